@@ -14,4 +14,15 @@ type AppendOptions struct {
 type AppendData struct {
 	UID         UID    // 消息的唯一标识符，要求支持 UIDPLUS 或 IMAP4rev2
 	UIDValidity uint32 // UID 的有效性，表示 UID 可能会在此有效性范围内变化
+
+	// 所有追加消息的 UID，用于 MULTIAPPEND。仅追加一封消息时也可能被设置
+	UIDs UIDSet
+}
+
+// CatenatePart 是 CATENATE 中的一个部分，参见 RFC 4469。
+//
+// Text 和 URL 中必须且只能设置一个。
+type CatenatePart struct {
+	Text []byte // 文本字面量
+	URL  string // 引用已有消息或其部分的 IMAP URL，参见 RFC 5092
 }
@@ -1,6 +1,7 @@
 package imap
 
 import (
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -40,6 +41,7 @@ const (
 	CapBinary           Cap = "BINARY"             // 支持 BINARY，RFC 3516
 	CapCatenate         Cap = "CATENATE"           // 支持 CATENATE，RFC 4469
 	CapChildren         Cap = "CHILDREN"           // 支持 CHILDREN，RFC 3348
+	CapCompressDeflate  Cap = "COMPRESS=DEFLATE"   // 支持 COMPRESS=DEFLATE，RFC 4978
 	CapCondStore        Cap = "CONDSTORE"          // 支持 CONDSTORE，RFC 7162
 	CapConvert          Cap = "CONVERT"            // 支持 CONVERT，RFC 5259
 	CapCreateSpecialUse Cap = "CREATE-SPECIAL-USE" // 支持 CREATE-SPECIAL-USE，RFC 6154
@@ -76,6 +78,8 @@ const (
 	CapUIDOnly          Cap = "UIDONLY"            // 支持 UIDONLY，RFC 9586
 	CapListMetadata     Cap = "LIST-METADATA"      // 支持 LIST-METADATA，RFC 9590
 	CapInProgress       Cap = "INPROGRESS"         // 支持 INPROGRESS，RFC 9585
+
+	CapGmailExt1 Cap = "X-GM-EXT-1" // 支持 Gmail 扩展（X-GM-LABELS、X-GM-MSGID 和 X-GM-THRID）
 )
 
 // imap4rev2Caps 是 IMAP4rev2 的能力集合。
@@ -95,6 +99,13 @@ var imap4rev2Caps = CapSet{
 	CapStatusSize:   {},
 }
 
+// impliedCaps 将能力映射到隐含它的能力。
+var impliedCaps = map[Cap]Cap{
+	CapLiteralMinus: CapLiteralPlus,
+	CapCondStore:    CapQResync,
+	CapUTF8Accept:   CapUTF8Only,
+}
+
 // AuthCap 返回 SASL 身份验证机制的能力名称。
 func AuthCap(mechanism string) Cap {
 	return Cap("AUTH=" + mechanism)
@@ -121,13 +132,7 @@ func (set CapSet) Has(c Cap) bool {
 		return true
 	}
 
-	if c == CapLiteralMinus && set.has(CapLiteralPlus) {
-		return true
-	}
-	if c == CapCondStore && set.has(CapQResync) {
-		return true
-	}
-	if c == CapUTF8Accept && set.has(CapUTF8Only) {
+	if by, ok := impliedCaps[c]; ok && set.has(by) {
 		return true
 	}
 	if c == CapAppendLimit {
@@ -138,6 +143,51 @@ func (set CapSet) Has(c Cap) bool {
 	return false
 }
 
+// HasAny 检查能力集合是否支持给定能力中的至少一个。
+//
+// 与 Has 一样，HasAny 会考虑被其他能力隐含的能力。
+func (set CapSet) HasAny(caps ...Cap) bool {
+	for _, c := range caps {
+		if set.Has(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// List 返回能力集合中所有能力的有序列表。
+//
+// 被其他能力隐含的能力（例如 IMAP4rev2 包含的能力）也会包含在列表中，
+// 与 Has 使用相同的隐含规则。
+func (set CapSet) List() []Cap {
+	all := make(CapSet, len(set))
+	for c := range set {
+		all[c] = struct{}{}
+	}
+	if set.has(CapIMAP4rev2) {
+		for c := range imap4rev2Caps {
+			all[c] = struct{}{}
+		}
+	}
+	for c, by := range impliedCaps {
+		if set.has(by) {
+			all[c] = struct{}{}
+		}
+	}
+	if _, ok := set.AppendLimit(); ok {
+		all[CapAppendLimit] = struct{}{}
+	}
+
+	l := make([]Cap, 0, len(all))
+	for c := range all {
+		l = append(l, c)
+	}
+	sort.Slice(l, func(i, j int) bool {
+		return l[i] < l[j]
+	})
+	return l
+}
+
 // AuthMechanisms 返回支持的 SASL 身份验证机制的列表。
 func (set CapSet) AuthMechanisms() []string {
 	var l []string
@@ -0,0 +1,79 @@
+package imap_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/luhaoyun888/go-imap-cn"
+)
+
+// TestCapSet_HasAny 测试 HasAny 对隐含能力的处理。
+func TestCapSet_HasAny(t *testing.T) {
+	set := imap.CapSet{imap.CapIMAP4rev2: {}, "X-GM-EXT-1": {}}
+
+	if !set.HasAny(imap.CapMove) {
+		t.Errorf("HasAny(MOVE) = false, want true（由 IMAP4rev2 隐含）")
+	}
+	if !set.HasAny("GMAIL", "X-GM-EXT-1") {
+		t.Errorf("HasAny(GMAIL, X-GM-EXT-1) = false, want true")
+	}
+	if set.HasAny(imap.CapCondStore, imap.CapQResync) {
+		t.Errorf("HasAny(CONDSTORE, QRESYNC) = true, want false")
+	}
+	if set.HasAny() {
+		t.Errorf("HasAny() = true, want false")
+	}
+}
+
+// TestCapSet_List 测试 List 返回排序后的能力，包括隐含能力。
+func TestCapSet_List(t *testing.T) {
+	set := imap.CapSet{imap.CapIMAP4rev1: {}, imap.CapLiteralPlus: {}, imap.CapQResync: {}}
+	want := []imap.Cap{
+		imap.CapCondStore,
+		imap.CapIMAP4rev1,
+		imap.CapLiteralPlus,
+		imap.CapLiteralMinus,
+		imap.CapQResync,
+	}
+	if l := set.List(); !reflect.DeepEqual(l, want) {
+		t.Errorf("List() = %v, want %v", l, want)
+	}
+
+	set = imap.CapSet{imap.CapIMAP4rev2: {}}
+	want = []imap.Cap{
+		imap.CapEnable,
+		imap.CapESearch,
+		imap.CapIdle,
+		imap.CapIMAP4rev2,
+		imap.CapListExtended,
+		imap.CapListStatus,
+		imap.CapLiteralMinus,
+		imap.CapMove,
+		imap.CapNamespace,
+		imap.CapSASLIR,
+		imap.CapSearchRes,
+		imap.CapStatusSize,
+		imap.CapUIDPlus,
+		imap.CapUnselect,
+	}
+	if l := set.List(); !reflect.DeepEqual(l, want) {
+		t.Errorf("List() = %v, want %v", l, want)
+	}
+
+	set = imap.CapSet{imap.CapIMAP4rev1: {}, imap.CapUTF8Only: {}, "APPENDLIMIT=1024": {}}
+	want = []imap.Cap{
+		imap.CapAppendLimit,
+		"APPENDLIMIT=1024",
+		imap.CapIMAP4rev1,
+		imap.CapUTF8Accept,
+		imap.CapUTF8Only,
+	}
+	if l := set.List(); !reflect.DeepEqual(l, want) {
+		t.Errorf("List() = %v, want %v", l, want)
+	}
+	for _, c := range set.List() {
+		if !set.Has(c) {
+			t.Errorf("List() 包含 %v，但 Has(%v) = false", c, c)
+		}
+	}
+}
@@ -21,6 +21,7 @@ var (
 	password     string
 	debug        bool
 	insecureAuth bool
+	dir          string
 )
 
 func main() {
@@ -31,6 +32,7 @@ func main() {
 	flag.StringVar(&password, "password", "user", "Password")
 	flag.BoolVar(&debug, "debug", false, "Print all commands and responses")
 	flag.BoolVar(&insecureAuth, "insecure-auth", false, "Allow authentication without TLS")
+	flag.StringVar(&dir, "dir", "", "Directory to store mailboxes in (default: in-memory only)")
 	flag.Parse()
 
 	var tlsConfig *tls.Config
@@ -51,11 +53,19 @@ func main() {
 	log.Printf("IMAP server listening on %v", ln.Addr())
 
 	memServer := imapmemserver.New()
+	if dir != "" {
+		memServer, err = imapmemserver.NewWithStore(dir)
+		if err != nil {
+			log.Fatalf("Failed to open store: %v", err)
+		}
+	}
 
 	if username != "" || password != "" {
 		user := imapmemserver.NewUser(username, password)
 		user.Create("INBOX", nil)
-		memServer.AddUser(user)
+		if err := memServer.AddUser(user); err != nil {
+			log.Fatalf("Failed to add user: %v", err)
+		}
 	}
 
 	var debugWriter io.Writer
@@ -68,8 +78,23 @@ func main() {
 			return memServer.NewSession(), nil, nil
 		},
 		Caps: imap.CapSet{
-			imap.CapIMAP4rev1: {},
-			imap.CapIMAP4rev2: {},
+			imap.CapIMAP4rev1:       {},
+			imap.CapIMAP4rev2:       {},
+			imap.CapCondStore:       {},
+			imap.CapMultiAppend:     {},
+			imap.CapCatenate:        {},
+			imap.CapQResync:         {},
+			imap.CapACL:             {},
+			imap.CapWithin:          {},
+			imap.CapSort:            {},
+			imap.CapQuota:           {},
+			"QUOTA=RES-STORAGE":     {},
+			"QUOTA=RES-MESSAGE":     {},
+			imap.CapMetadata:        {},
+			imap.CapMetadataServer:  {},
+			imap.CapMultiSearch:     {},
+			"THREAD=ORDEREDSUBJECT": {},
+			"THREAD=REFERENCES":     {},
 		},
 		TLSConfig:    tlsConfig,
 		InsecureAuth: insecureAuth,
@@ -19,6 +19,11 @@ type FetchOptions struct {
 	BinarySection     []*FetchItemBinarySection     // 二进制部分（要求支持 IMAP4rev2 或 BINARY）
 	BinarySectionSize []*FetchItemBinarySectionSize // 二进制部分大小（要求支持 IMAP4rev2 或 BINARY）
 	ModSeq            bool                          // 是否获取修改序列（要求支持 CONDSTORE）
+	GmailLabels       bool                          // 是否获取 Gmail 标签（要求支持 X-GM-EXT-1）
+	GmailMsgID        bool                          // 是否获取 Gmail 消息 ID（要求支持 X-GM-EXT-1）
+	GmailThrID        bool                          // 是否获取 Gmail 会话 ID（要求支持 X-GM-EXT-1）
+	EmailID           bool                          // 是否获取邮件对象 ID（要求支持 OBJECTID）
+	ThreadID          bool                          // 是否获取会话对象 ID（要求支持 OBJECTID）
 
 	ChangedSince uint64 // 从某个修改时间点后获取
 }
@@ -26,6 +31,9 @@ type FetchOptions struct {
 // FetchItemBodyStructure 包含用于体结构获取的 FETCH 选项。
 type FetchItemBodyStructure struct {
 	Extended bool // 是否获取扩展信息
+	// Both 为 true 时同时获取非扩展的 BODY 和扩展的 BODYSTRUCTURE，此时忽略
+	// Extended。服务器会分别返回两者，后端应返回扩展的体结构。
+	Both bool
 }
 
 // PartSpecifier 描述要获取的部分的头、体或两者。
@@ -130,6 +138,15 @@ type BodyStructure interface {
 	Walk(f BodyStructureWalkFunc)
 	// Disposition 返回体结构的处置方式（如果可用）。
 	Disposition() *BodyStructureDisposition
+	// PreferredText 返回用于显示正文的文本部分及其部分路径，没有找到时返回 nil。
+	// 在 multipart/alternative 中优先选择 text/html，其次选择 text/plain；
+	// 在其他多部分中返回第一个找到的正文部分。附件会被跳过。
+	PreferredText() (path []int, part *BodyStructureSinglePart)
+	// TotalSize 返回所有单个部分的正文大小（字节，编码后）之和，可以在不下载
+	// 正文的情况下估算邮件大小。不包括 MIME 边界和各部分的头部。
+	TotalSize() uint32
+	// TotalLines 返回所有 text/* 和 message/rfc822 部分的行数之和。
+	TotalLines() int64
 
 	bodyStructure()
 }
@@ -164,18 +181,82 @@ func (bs *BodyStructureSinglePart) Disposition() *BodyStructureDisposition {
 }
 
 // Filename 解码体结构的文件名（如果有的话）。
+//
+// imapclient 在解析体结构时已经按 RFC 2231 合并了分段参数并解码了字符集。
 func (bs *BodyStructureSinglePart) Filename() string {
 	var filename string
 	if bs.Extended != nil && bs.Extended.Disposition != nil {
-		filename = bs.Extended.Disposition.Params["filename"]
+		filename = bodyStructureParam(bs.Extended.Disposition.Params, "filename")
 	}
 	if filename == "" {
 		// 注意：在 Content-Type 中使用 "name" 是不建议的
-		filename = bs.Params["name"]
+		filename = bodyStructureParam(bs.Params, "name")
 	}
 	return filename
 }
 
+// Charset 返回 Content-Type 的 charset 参数（如果有的话）。
+func (bs *BodyStructureSinglePart) Charset() string {
+	return bodyStructureParam(bs.Params, "charset")
+}
+
+// bodyStructureParam 以大小写不敏感的方式查找参数 key 的值。
+func bodyStructureParam(params map[string]string, key string) string {
+	if v, ok := params[key]; ok {
+		return v
+	}
+	for k, v := range params {
+		if strings.EqualFold(k, key) {
+			return v
+		}
+	}
+	return ""
+}
+
+func (bs *BodyStructureSinglePart) PreferredText() (path []int, part *BodyStructureSinglePart) {
+	if textPreference(bs) == 0 {
+		return nil, nil
+	}
+	return []int{1}, bs
+}
+
+func (bs *BodyStructureSinglePart) TotalSize() uint32 {
+	return bs.Size // message/rfc822 的大小已包含内嵌邮件
+}
+
+func (bs *BodyStructureSinglePart) TotalLines() int64 {
+	switch {
+	case bs.Text != nil:
+		return bs.Text.NumLines
+	case bs.MessageRFC822 != nil:
+		return bs.MessageRFC822.NumLines
+	default:
+		return 0
+	}
+}
+
+// textPreference 返回单个部分作为正文的优先级：text/html 为 2，text/plain 为 1，
+// 其他部分和附件为 0。
+func textPreference(bs *BodyStructureSinglePart) int {
+	if isAttachment(bs) {
+		return 0
+	}
+	switch bs.MediaType() {
+	case "text/html":
+		return 2
+	case "text/plain":
+		return 1
+	default:
+		return 0
+	}
+}
+
+// isAttachment 判断部分是否为附件。
+func isAttachment(bs BodyStructure) bool {
+	disp := bs.Disposition()
+	return disp != nil && strings.EqualFold(disp.Value, "attachment")
+}
+
 func (*BodyStructureSinglePart) bodyStructure() {}
 
 // BodyStructureMessageRFC822 包含针对 BodyStructureSinglePart 的 RFC 822 部分的元数据。
@@ -242,6 +323,67 @@ func (bs *BodyStructureMultiPart) Disposition() *BodyStructureDisposition {
 	return bs.Extended.Disposition
 }
 
+func (bs *BodyStructureMultiPart) TotalSize() uint32 {
+	var size uint32
+	for _, child := range bs.Children {
+		size += child.TotalSize()
+	}
+	return size
+}
+
+func (bs *BodyStructureMultiPart) TotalLines() int64 {
+	var lines int64
+	for _, child := range bs.Children {
+		lines += child.TotalLines()
+	}
+	return lines
+}
+
+func (bs *BodyStructureMultiPart) PreferredText() (path []int, part *BodyStructureSinglePart) {
+	return bs.preferredText(nil)
+}
+
+func (bs *BodyStructureMultiPart) preferredText(path []int) ([]int, *BodyStructureSinglePart) {
+	if isAttachment(bs) {
+		return nil, nil
+	}
+
+	alternative := strings.EqualFold(bs.Subtype, "alternative")
+	var (
+		bestPath []int
+		best     *BodyStructureSinglePart
+	)
+	for i, child := range bs.Children {
+		childPath := append(append([]int(nil), path...), i+1)
+
+		var (
+			p    []int
+			part *BodyStructureSinglePart
+		)
+		switch child := child.(type) {
+		case *BodyStructureSinglePart:
+			if textPreference(child) > 0 {
+				p, part = childPath, child
+			}
+		case *BodyStructureMultiPart:
+			p, part = child.preferredText(childPath)
+		default:
+			panic(fmt.Errorf("unsupported body structure type %T", child))
+		}
+		if part == nil {
+			continue
+		}
+		if !alternative {
+			return p, part
+		}
+		// 按 RFC 2046，alternative 中越靠后的部分越接近原始内容
+		if best == nil || textPreference(part) >= textPreference(best) {
+			bestPath, best = p, part
+		}
+	}
+	return bestPath, best
+}
+
 func (*BodyStructureMultiPart) bodyStructure() {}
 
 // BodyStructureMultiPartExt 包含针对 BodyStructureMultiPart 的扩展体结构数据。
@@ -0,0 +1,157 @@
+package imap_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/luhaoyun888/go-imap-cn"
+)
+
+// TestBodyStructureSinglePart_Charset 测试从 text/plain; charset=UTF-8 中提取 charset。
+func TestBodyStructureSinglePart_Charset(t *testing.T) {
+	tests := []struct {
+		params map[string]string
+		want   string
+	}{
+		{map[string]string{"charset": "UTF-8"}, "UTF-8"},
+		{map[string]string{"CHARSET": "UTF-8"}, "UTF-8"},
+		{map[string]string{"format": "flowed"}, ""},
+		{nil, ""},
+	}
+	for _, tc := range tests {
+		bs := imap.BodyStructureSinglePart{Type: "text", Subtype: "plain", Params: tc.params}
+		if got := bs.Charset(); got != tc.want {
+			t.Errorf("Charset() with params %v = %q, want %q", tc.params, got, tc.want)
+		}
+	}
+}
+
+// TestBodyStructureSinglePart_Filename 测试 Filename 对参数键的大小写不敏感。
+func TestBodyStructureSinglePart_Filename(t *testing.T) {
+	bs := imap.BodyStructureSinglePart{
+		Type:    "application",
+		Subtype: "pdf",
+		Extended: &imap.BodyStructureSinglePartExt{
+			Disposition: &imap.BodyStructureDisposition{
+				Value:  "attachment",
+				Params: map[string]string{"FileName": "report.pdf"},
+			},
+		},
+	}
+	if got, want := bs.Filename(), "report.pdf"; got != want {
+		t.Errorf("Filename() = %q, want %q", got, want)
+	}
+}
+
+// TestBodyStructure_PreferredText 测试 multipart/alternative 中优先选择 HTML 部分。
+func TestBodyStructure_PreferredText(t *testing.T) {
+	plain := &imap.BodyStructureSinglePart{Type: "text", Subtype: "plain"}
+	html := &imap.BodyStructureSinglePart{Type: "text", Subtype: "html"}
+	attachment := &imap.BodyStructureSinglePart{
+		Type:    "text",
+		Subtype: "html",
+		Extended: &imap.BodyStructureSinglePartExt{
+			Disposition: &imap.BodyStructureDisposition{Value: "attachment"},
+		},
+	}
+	bs := &imap.BodyStructureMultiPart{
+		Subtype: "mixed",
+		Children: []imap.BodyStructure{
+			&imap.BodyStructureMultiPart{
+				Subtype:  "alternative",
+				Children: []imap.BodyStructure{plain, html},
+			},
+			attachment,
+		},
+	}
+
+	path, part := bs.PreferredText()
+	if want := []int{1, 2}; !reflect.DeepEqual(path, want) {
+		t.Errorf("PreferredText() path = %v, want %v", path, want)
+	}
+	if part != html {
+		t.Errorf("PreferredText() part = %v, want text/html part", part)
+	}
+
+	path, part = plain.PreferredText()
+	if want := []int{1}; !reflect.DeepEqual(path, want) || part != plain {
+		t.Errorf("PreferredText() on single part = %v, %v, want %v, text/plain part", path, part, want)
+	}
+
+	if path, part := attachment.PreferredText(); path != nil || part != nil {
+		t.Errorf("PreferredText() on attachment = %v, %v, want nil", path, part)
+	}
+}
+
+// TestBodyStructure_Total 测试多部分邮件的大小和行数汇总。
+func TestBodyStructure_Total(t *testing.T) {
+	bs := &imap.BodyStructureMultiPart{
+		Subtype: "mixed",
+		Children: []imap.BodyStructure{
+			&imap.BodyStructureMultiPart{
+				Subtype: "alternative",
+				Children: []imap.BodyStructure{
+					&imap.BodyStructureSinglePart{Type: "text", Subtype: "plain", Size: 120, Text: &imap.BodyStructureText{NumLines: 4}},
+					&imap.BodyStructureSinglePart{Type: "text", Subtype: "html", Size: 380, Text: &imap.BodyStructureText{NumLines: 9}},
+				},
+			},
+			&imap.BodyStructureSinglePart{Type: "image", Subtype: "png", Size: 2048},
+			&imap.BodyStructureSinglePart{
+				Type:    "message",
+				Subtype: "rfc822",
+				Size:    512,
+				MessageRFC822: &imap.BodyStructureMessageRFC822{
+					BodyStructure: &imap.BodyStructureSinglePart{Type: "text", Subtype: "plain", Size: 100, Text: &imap.BodyStructureText{NumLines: 3}},
+					NumLines:      15,
+				},
+			},
+		},
+	}
+
+	if got, want := bs.TotalSize(), uint32(120+380+2048+512); got != want {
+		t.Errorf("TotalSize() = %v, want %v", got, want)
+	}
+	if got, want := bs.TotalLines(), int64(4+9+15); got != want {
+		t.Errorf("TotalLines() = %v, want %v", got, want)
+	}
+}
+
+// TestFetchBuilder 测试构建器生成的 FetchOptions 与手写的等价。
+func TestFetchBuilder(t *testing.T) {
+	got := imap.NewFetch().
+		UID().
+		Flags().
+		Envelope().
+		BodyStructure(true).
+		BodyPeek(1, 2).
+		Body().
+		HeaderPeek("From", "Subject").
+		BinarySize(1).
+		Build()
+	want := &imap.FetchOptions{
+		UID:           true,
+		Flags:         true,
+		Envelope:      true,
+		BodyStructure: &imap.FetchItemBodyStructure{Extended: true},
+		BodySection: []*imap.FetchItemBodySection{
+			{Part: []int{1, 2}, Peek: true},
+			{},
+			{Specifier: imap.PartSpecifierHeader, HeaderFields: []string{"From", "Subject"}, Peek: true},
+		},
+		BinarySectionSize: []*imap.FetchItemBinarySectionSize{{Part: []int{1}}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Build() = %#v, want %#v", got, want)
+	}
+
+	// Build 之后继续修改构建器不影响已返回的值
+	b := imap.NewFetch().BodyPeek()
+	first := b.Build()
+	b.UID().BodyPeek(1)
+	if first.UID || len(first.BodySection) != 1 {
+		t.Errorf("Build() 的结果被之后的修改影响: %#v", first)
+	}
+	if empty := imap.NewFetch().Build(); !reflect.DeepEqual(empty, &imap.FetchOptions{}) {
+		t.Errorf("NewFetch().Build() = %#v, want 零值", empty)
+	}
+}
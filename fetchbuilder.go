@@ -0,0 +1,146 @@
+package imap
+
+// FetchBuilder 以链式调用的方式构建 FetchOptions，例如：
+//
+//	options := imap.NewFetch().UID().Envelope().BodyPeek(1, 2).Build()
+//
+// 等价于：
+//
+//	options := &imap.FetchOptions{
+//		UID:         true,
+//		Envelope:    true,
+//		BodySection: []*imap.FetchItemBodySection{{Part: []int{1, 2}, Peek: true}},
+//	}
+type FetchBuilder struct {
+	options FetchOptions
+}
+
+// NewFetch 创建一个空的 FETCH 请求构建器。
+func NewFetch() *FetchBuilder {
+	return &FetchBuilder{}
+}
+
+// UID 获取 UID。
+func (b *FetchBuilder) UID() *FetchBuilder {
+	b.options.UID = true
+	return b
+}
+
+// Flags 获取标志。
+func (b *FetchBuilder) Flags() *FetchBuilder {
+	b.options.Flags = true
+	return b
+}
+
+// Envelope 获取信封。
+func (b *FetchBuilder) Envelope() *FetchBuilder {
+	b.options.Envelope = true
+	return b
+}
+
+// InternalDate 获取内部日期。
+func (b *FetchBuilder) InternalDate() *FetchBuilder {
+	b.options.InternalDate = true
+	return b
+}
+
+// RFC822Size 获取邮件大小。
+func (b *FetchBuilder) RFC822Size() *FetchBuilder {
+	b.options.RFC822Size = true
+	return b
+}
+
+// BodyStructure 获取体结构。extended 为 true 时获取 BODYSTRUCTURE，否则获取 BODY。
+func (b *FetchBuilder) BodyStructure(extended bool) *FetchBuilder {
+	b.options.BodyStructure = &FetchItemBodyStructure{Extended: extended}
+	return b
+}
+
+// ModSeq 获取修改序列号（要求支持 CONDSTORE）。
+func (b *FetchBuilder) ModSeq() *FetchBuilder {
+	b.options.ModSeq = true
+	return b
+}
+
+// ObjectID 获取 EMAILID 和 THREADID（要求支持 OBJECTID）。
+func (b *FetchBuilder) ObjectID() *FetchBuilder {
+	b.options.EmailID = true
+	b.options.ThreadID = true
+	return b
+}
+
+// ChangedSince 只获取修改序列号大于 modSeq 的邮件（要求支持 CONDSTORE）。
+func (b *FetchBuilder) ChangedSince(modSeq uint64) *FetchBuilder {
+	b.options.ChangedSince = modSeq
+	return b
+}
+
+// Section 添加一个体部分。
+func (b *FetchBuilder) Section(section *FetchItemBodySection) *FetchBuilder {
+	b.options.BodySection = append(b.options.BodySection, section)
+	return b
+}
+
+// Body 获取指定部分的内容（BODY[part]），不指定 part 时获取整封邮件。
+// 服务器会为邮件设置 \Seen 标志。
+func (b *FetchBuilder) Body(part ...int) *FetchBuilder {
+	return b.Section(&FetchItemBodySection{Part: copyPart(part)})
+}
+
+// BodyPeek 与 Body 相同，但不会设置 \Seen 标志（BODY.PEEK[part]）。
+func (b *FetchBuilder) BodyPeek(part ...int) *FetchBuilder {
+	return b.Section(&FetchItemBodySection{Part: copyPart(part), Peek: true})
+}
+
+// HeaderPeek 获取邮件头部而不设置 \Seen 标志。指定 fields 时只获取这些头字段。
+func (b *FetchBuilder) HeaderPeek(fields ...string) *FetchBuilder {
+	section := &FetchItemBodySection{Specifier: PartSpecifierHeader, Peek: true}
+	if len(fields) > 0 {
+		section.HeaderFields = append([]string(nil), fields...)
+	}
+	return b.Section(section)
+}
+
+// BinaryPeek 获取指定部分解码后的内容而不设置 \Seen 标志
+// （要求支持 IMAP4rev2 或 BINARY）。
+func (b *FetchBuilder) BinaryPeek(part ...int) *FetchBuilder {
+	b.options.BinarySection = append(b.options.BinarySection, &FetchItemBinarySection{
+		Part: copyPart(part),
+		Peek: true,
+	})
+	return b
+}
+
+// BinarySize 获取指定部分解码后的大小（要求支持 IMAP4rev2 或 BINARY）。
+func (b *FetchBuilder) BinarySize(part ...int) *FetchBuilder {
+	b.options.BinarySectionSize = append(b.options.BinarySectionSize, &FetchItemBinarySectionSize{
+		Part: copyPart(part),
+	})
+	return b
+}
+
+// Build 返回构建的 FetchOptions。之后对构建器的修改不会影响返回值。
+func (b *FetchBuilder) Build() *FetchOptions {
+	options := b.options
+	options.BodySection = append([]*FetchItemBodySection(nil), b.options.BodySection...)
+	options.BinarySection = append([]*FetchItemBinarySection(nil), b.options.BinarySection...)
+	options.BinarySectionSize = append([]*FetchItemBinarySectionSize(nil), b.options.BinarySectionSize...)
+	if len(options.BodySection) == 0 {
+		options.BodySection = nil
+	}
+	if len(options.BinarySection) == 0 {
+		options.BinarySection = nil
+	}
+	if len(options.BinarySectionSize) == 0 {
+		options.BinarySectionSize = nil
+	}
+	return &options
+}
+
+// copyPart 复制部分索引，空索引返回 nil。
+func copyPart(part []int) []int {
+	if len(part) == 0 {
+		return nil
+	}
+	return append([]int(nil), part...)
+}
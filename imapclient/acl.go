@@ -12,6 +12,10 @@ import (
 //
 // 此命令需要支持 ACL 扩展。
 func (c *Client) MyRights(mailbox string) *MyRightsCommand {
+	if !c.Caps().Has(imap.CapACL) {
+		return &MyRightsCommand{commandBase: newACLErrorBase()}
+	}
+
 	cmd := &MyRightsCommand{}
 	enc := c.beginCommand("MYRIGHTS", cmd)
 	enc.SP().Mailbox(mailbox) // 设置邮箱
@@ -23,6 +27,10 @@ func (c *Client) MyRights(mailbox string) *MyRightsCommand {
 //
 // 此命令需要支持 ACL 扩展。
 func (c *Client) SetACL(mailbox string, ri imap.RightsIdentifier, rm imap.RightModification, rs imap.RightSet) *SetACLCommand {
+	if !c.Caps().Has(imap.CapACL) {
+		return &SetACLCommand{commandBase: newACLErrorBase()}
+	}
+
 	cmd := &SetACLCommand{}
 	enc := c.beginCommand("SETACL", cmd)
 	enc.SP().Mailbox(mailbox).SP().String(string(ri)).SP() // 设置邮箱和权限标识符
@@ -41,10 +49,29 @@ func (cmd *SetACLCommand) Wait() error {
 	return cmd.wait()
 }
 
+// DeleteACL 发送 DELETEACL 命令，删除标识符在邮箱上的所有权限。
+//
+// 此命令需要支持 ACL 扩展。
+func (c *Client) DeleteACL(mailbox string, ri imap.RightsIdentifier) *Command {
+	if !c.Caps().Has(imap.CapACL) {
+		return &Command{commandBase: newACLErrorBase()}
+	}
+
+	cmd := &Command{}
+	enc := c.beginCommand("DELETEACL", cmd)
+	enc.SP().Mailbox(mailbox).SP().String(string(ri)) // 设置邮箱和权限标识符
+	enc.end()
+	return cmd
+}
+
 // GetACL 发送 GETACL 命令。
 //
 // 此命令需要支持 ACL 扩展。
 func (c *Client) GetACL(mailbox string) *GetACLCommand {
+	if !c.Caps().Has(imap.CapACL) {
+		return &GetACLCommand{commandBase: newACLErrorBase()}
+	}
+
 	cmd := &GetACLCommand{}
 	enc := c.beginCommand("GETACL", cmd)
 	enc.SP().Mailbox(mailbox) // 设置邮箱
@@ -63,6 +90,37 @@ func (cmd *GetACLCommand) Wait() (*GetACLData, error) {
 	return &cmd.data, cmd.wait()
 }
 
+// ListRights 发送 LISTRIGHTS 命令，查询可以授予标识符的权限。
+//
+// 此命令需要支持 ACL 扩展。
+func (c *Client) ListRights(mailbox string, ri imap.RightsIdentifier) *ListRightsCommand {
+	if !c.Caps().Has(imap.CapACL) {
+		return &ListRightsCommand{commandBase: newACLErrorBase()}
+	}
+
+	cmd := &ListRightsCommand{}
+	enc := c.beginCommand("LISTRIGHTS", cmd)
+	enc.SP().Mailbox(mailbox).SP().String(string(ri)) // 设置邮箱和权限标识符
+	enc.end()
+	return cmd
+}
+
+// ListRightsCommand 是一个 LISTRIGHTS 命令。
+type ListRightsCommand struct {
+	commandBase
+	data ListRightsData
+}
+
+// Wait 等待 LISTRIGHTS 命令的响应，并返回数据。
+func (cmd *ListRightsCommand) Wait() (*ListRightsData, error) {
+	return &cmd.data, cmd.wait()
+}
+
+// newACLErrorBase 返回一个因服务器不支持 ACL 而失败的命令。
+func newACLErrorBase() commandBase {
+	return newFailedCommandBase(fmt.Errorf("imapclient: 服务器不支持 ACL"))
+}
+
 // handleMyRights 处理 MYRIGHTS 响应。
 func (c *Client) handleMyRights() error {
 	data, err := readMyRights(c.dec)
@@ -87,6 +145,18 @@ func (c *Client) handleGetACL() error {
 	return nil
 }
 
+// handleListRights 处理 LISTRIGHTS 响应。
+func (c *Client) handleListRights() error {
+	data, err := readListRights(c.dec)
+	if err != nil {
+		return fmt.Errorf("在 listrights 响应中: %v", err)
+	}
+	if cmd := findPendingCmdByType[*ListRightsCommand](c); cmd != nil {
+		cmd.data = *data
+	}
+	return nil
+}
+
 // MyRightsCommand 是一个 MYRIGHTS 命令。
 type MyRightsCommand struct {
 	commandBase
@@ -143,3 +213,33 @@ func readGetACL(dec *imapwire.Decoder) (*GetACLData, error) {
 
 	return data, nil
 }
+
+// ListRightsData 是 LISTRIGHTS 命令返回的数据。
+type ListRightsData struct {
+	Mailbox    string                // 邮箱名称
+	Identifier imap.RightsIdentifier // 权限标识符
+	Required   imap.RightSet         // 总是授予标识符的权限
+	Optional   []imap.RightSet       // 可以授予的权限，每组权限必须一起授予
+}
+
+// readListRights 从解码器读取 LISTRIGHTS 数据。
+func readListRights(dec *imapwire.Decoder) (*ListRightsData, error) {
+	var (
+		data         ListRightsData
+		riStr, rsStr string
+	)
+	if !dec.ExpectMailbox(&data.Mailbox) || !dec.ExpectSP() || !dec.ExpectAString(&riStr) || !dec.ExpectSP() || !dec.ExpectAString(&rsStr) {
+		return nil, dec.Err()
+	}
+	data.Identifier = imap.RightsIdentifier(riStr)
+	data.Required = imap.RightSet(rsStr)
+
+	for dec.SP() {
+		if !dec.ExpectAString(&rsStr) {
+			return nil, dec.Err()
+		}
+		data.Optional = append(data.Optional, imap.RightSet(rsStr))
+	}
+
+	return &data, nil
+}
@@ -31,7 +31,7 @@ var testCases = []struct {
 	},
 	{
 		name:                  "自定义子文件夹",
-		mailbox:               "MyFolder.Child",
+		mailbox:               "MyFolder/Child",
 		setRightsModification: imap.RightModificationReplace, // 替换权限
 		setRights:             imap.RightSet("aelrwtd"),      // 设置的权限
 		expectedRights:        imap.RightSet("aelrwtd"),      // 期望的权限
@@ -52,7 +52,7 @@ var testCases = []struct {
 	},
 	{
 		name:                  "空权限",
-		mailbox:               "MyFolder.Child",
+		mailbox:               "MyFolder/Child",
 		setRightsModification: imap.RightModificationReplace, // 替换权限
 		setRights:             imap.RightSet("a"),            // 设置的权限
 		expectedRights:        imap.RightSet("a"),            // 期望的权限
@@ -116,3 +116,65 @@ func TestACL(t *testing.T) {
 		}
 	})
 }
+
+// TestDeleteACL 测试 DELETEACL 和 LISTRIGHTS 命令。
+func TestDeleteACL(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateAuthenticated)
+	defer client.Close()
+	defer server.Close()
+
+	if !client.Caps().Has(imap.CapACL) {
+		t.Skipf("服务器不支持 ACL")
+	}
+
+	const ri = imap.RightsIdentifier("someone")
+	if err := client.SetACL("INBOX", ri, imap.RightModificationReplace, imap.RightSet("lr")).Wait(); err != nil {
+		t.Fatalf("SetACL().Wait() = %v", err)
+	}
+
+	listRightsData, err := client.ListRights("INBOX", ri).Wait()
+	if err != nil {
+		t.Fatalf("ListRights().Wait() = %v", err)
+	}
+	if listRightsData.Mailbox != "INBOX" || listRightsData.Identifier != ri {
+		t.Errorf("ListRights() = %v %v, want INBOX %v", listRightsData.Mailbox, listRightsData.Identifier, ri)
+	}
+	if len(listRightsData.Optional) == 0 {
+		t.Errorf("ListRights().Optional 为空")
+	}
+
+	if err := client.DeleteACL("INBOX", ri).Wait(); err != nil {
+		t.Fatalf("DeleteACL().Wait() = %v", err)
+	}
+	getACLData, err := client.GetACL("INBOX").Wait()
+	if err != nil {
+		t.Fatalf("GetACL().Wait() = %v", err)
+	}
+	if rights, ok := getACLData.Rights[ri]; ok {
+		t.Errorf("DELETEACL 之后 GETACL 仍返回 %v 的权限: %v", ri, rights)
+	}
+}
+
+// TestACL_unsupported 测试服务器不支持 ACL 时的错误。
+func TestACL_unsupported(t *testing.T) {
+	client := newScriptedClient(t, "", func(cmd string) []string {
+		t.Errorf("不应发送命令: %q", cmd)
+		return nil
+	})
+
+	if _, err := client.GetACL("INBOX").Wait(); err == nil {
+		t.Errorf("GetACL() 在服务器不支持 ACL 时应失败")
+	}
+	if _, err := client.MyRights("INBOX").Wait(); err == nil {
+		t.Errorf("MyRights() 在服务器不支持 ACL 时应失败")
+	}
+	if _, err := client.ListRights("INBOX", "anyone").Wait(); err == nil {
+		t.Errorf("ListRights() 在服务器不支持 ACL 时应失败")
+	}
+	if err := client.SetACL("INBOX", "anyone", imap.RightModificationAdd, imap.RightSet("r")).Wait(); err == nil {
+		t.Errorf("SetACL() 在服务器不支持 ACL 时应失败")
+	}
+	if err := client.DeleteACL("INBOX", "anyone").Wait(); err == nil {
+		t.Errorf("DeleteACL() 在服务器不支持 ACL 时应失败")
+	}
+}
@@ -1,6 +1,7 @@
 package imapclient
 
 import (
+	"fmt"
 	"io"
 
 	"github.com/luhaoyun888/go-imap-cn"
@@ -14,20 +15,57 @@ import (
 // options 是可选的。
 func (c *Client) Append(mailbox string, size int64, options *imap.AppendOptions) *AppendCommand {
 	cmd := &AppendCommand{}
-	cmd.enc = c.beginCommand("APPEND", cmd) // 开始 APPEND 命令
-	cmd.enc.SP().Mailbox(mailbox).SP()      // 设置邮箱名称
-	if options != nil && len(options.Flags) > 0 {
-		cmd.enc.List(len(options.Flags), func(i int) {
-			cmd.enc.Flag(options.Flags[i]) // 添加标志
-		}).SP()
+	cmd.enc = c.beginCommand("APPEND", cmd)             // 开始 APPEND 命令
+	cmd.enc.SP().Mailbox(mailbox).SP()                  // 设置邮箱名称
+	cmd.wc = writeAppendMessage(cmd.enc, size, options) // 写入邮件选项和字面量
+	return cmd
+}
+
+// Catenate 发送带有 CATENATE 的 APPEND 命令，将文本和已有邮件的部分拼接为一封新邮件。
+//
+// 命令会立即发送，调用者只需调用 AppendCommand.Wait。
+//
+// options 是可选的。此命令需要支持 CATENATE 扩展，参见 RFC 4469。如果服务器不支持，
+// 命令不会被发送并以错误结束。
+func (c *Client) Catenate(mailbox string, parts []imap.CatenatePart, options *imap.AppendOptions) *AppendCommand {
+	if !c.Caps().Has(imap.CapCatenate) {
+		return &AppendCommand{commandBase: newFailedCommandBase(fmt.Errorf("imapclient: 服务器不支持 CATENATE"))}
 	}
-	if options != nil && !options.Time.IsZero() {
-		cmd.enc.String(options.Time.Format(internal.DateTimeLayout)).SP() // 设置时间
+
+	cmd := &AppendCommand{}
+	enc := c.beginCommand("APPEND", cmd) // 开始 APPEND 命令
+	defer enc.end()                      // 结束命令
+	enc.SP().Mailbox(mailbox).SP()       // 设置邮箱名称
+	writeAppendOptions(enc, options)
+	enc.Atom("CATENATE").SP().Special('(')
+	for i, part := range parts {
+		if i > 0 {
+			enc.SP()
+		}
+		if part.URL != "" {
+			enc.Atom("URL").SP().String(part.URL) // 引用已有邮件
+			continue
+		}
+
+		enc.Atom("TEXT").SP()
+		wc := enc.Literal(int64(len(part.Text)))
+		if _, err := wc.Write(part.Text); err != nil {
+			break
+		}
+		if err := wc.Close(); err != nil {
+			break
+		}
 	}
+	enc.Special(')')
+	return cmd
+}
+
+// writeAppendMessage 写入一封邮件的标志和时间，并返回用于写入邮件内容的字面量写入器。
+func writeAppendMessage(enc *commandEncoder, size int64, options *imap.AppendOptions) io.WriteCloser {
+	writeAppendOptions(enc, options)
 	// TODO: literal8 for BINARY
 	// TODO: UTF8 data ext for UTF8=ACCEPT, with literal8
-	cmd.wc = cmd.enc.Literal(size) // 设置字面量大小
-	return cmd
+	return enc.Literal(size) // 设置字面量大小
 }
 
 // AppendCommand 是一个 APPEND 命令。
@@ -47,6 +85,9 @@ func (cmd *AppendCommand) Write(b []byte) (int, error) {
 
 // Close 关闭命令，等待服务器响应。
 func (cmd *AppendCommand) Close() error {
+	if cmd.wc == nil {
+		return nil // Catenate 发送的命令没有写入器
+	}
 	err := cmd.wc.Close() // 关闭写入器
 	if cmd.enc != nil {
 		cmd.enc.end() // 结束命令
@@ -59,3 +100,107 @@ func (cmd *AppendCommand) Close() error {
 func (cmd *AppendCommand) Wait() (*imap.AppendData, error) {
 	return &cmd.data, cmd.wait()
 }
+
+// writeAppendOptions 写入一封邮件的标志和时间。
+func writeAppendOptions(enc *commandEncoder, options *imap.AppendOptions) {
+	if options != nil && len(options.Flags) > 0 {
+		enc.List(len(options.Flags), func(i int) {
+			enc.Flag(options.Flags[i]) // 添加标志
+		}).SP()
+	}
+	if options != nil && !options.Time.IsZero() {
+		enc.String(options.Time.Format(internal.DateTimeLayout)).SP() // 设置时间
+	}
+}
+
+// MultiAppend 发送一个追加多封邮件的 APPEND 命令。
+//
+// 调用者必须对每封邮件调用 MultiAppendCommand.CreateMessage，写入内容并关闭
+// 返回的写入器，然后调用 MultiAppendCommand.Close。
+//
+// 此命令需要支持 MULTIAPPEND 扩展，参见 RFC 3502。如果服务器不支持，
+// 则回退为多个单独的 APPEND 命令，此时不再保证原子性。
+func (c *Client) MultiAppend(mailbox string) *MultiAppendCommand {
+	return &MultiAppendCommand{
+		client:  c,
+		mailbox: mailbox,
+		multi:   c.Caps().Has(imap.CapMultiAppend),
+	}
+}
+
+// MultiAppendCommand 是一个追加多封邮件的 APPEND 命令。
+type MultiAppendCommand struct {
+	commandBase
+	client  *Client
+	mailbox string
+	multi   bool             // 服务器是否支持 MULTIAPPEND
+	enc     *commandEncoder  // 命令编码器，仅用于 MULTIAPPEND
+	cmds    []*AppendCommand // 回退时发送的 APPEND 命令
+	data    imap.AppendData  // APPEND 数据
+}
+
+// CreateMessage 开始写入一封新邮件。
+//
+// 调用者必须写入 size 字节的邮件内容，并在创建下一封邮件之前关闭返回的写入器。
+//
+// options 是可选的。
+func (cmd *MultiAppendCommand) CreateMessage(size int64, options *imap.AppendOptions) io.WriteCloser {
+	if !cmd.multi {
+		appendCmd := cmd.client.Append(cmd.mailbox, size, options)
+		cmd.cmds = append(cmd.cmds, appendCmd)
+		return appendCmd
+	}
+
+	if cmd.enc == nil {
+		cmd.enc = cmd.client.beginCommand("APPEND", cmd) // 开始 APPEND 命令
+		cmd.enc.SP().Mailbox(cmd.mailbox)                // 设置邮箱名称
+	}
+	cmd.enc.SP()
+	return writeAppendMessage(cmd.enc, size, options)
+}
+
+// Close 结束命令。
+func (cmd *MultiAppendCommand) Close() error {
+	if !cmd.multi {
+		return nil
+	}
+	if cmd.enc == nil {
+		// 没有任何邮件，不发送命令
+		cmd.commandBase = newFailedCommandBase(fmt.Errorf("imapclient: MULTIAPPEND 至少需要一封邮件"))
+		return nil
+	}
+	cmd.enc.end() // 结束命令
+	cmd.enc = nil
+	return nil
+}
+
+// Wait 等待命令的响应，并返回数据。
+//
+// 回退为多个 APPEND 命令时，返回第一个错误，UID 来自所有成功的命令。
+func (cmd *MultiAppendCommand) Wait() (*imap.AppendData, error) {
+	if cmd.multi {
+		return &cmd.data, cmd.wait()
+	}
+
+	var firstErr error
+	for _, appendCmd := range cmd.cmds {
+		data, err := appendCmd.Wait()
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if data.UID != 0 {
+			cmd.data.UIDValidity = data.UIDValidity
+			cmd.data.UIDs.AddNum(data.UID)
+		}
+	}
+	if len(cmd.cmds) == 0 && firstErr == nil {
+		firstErr = fmt.Errorf("imapclient: MULTIAPPEND 至少需要一封邮件")
+	}
+	if len(cmd.data.UIDs) == 1 && cmd.data.UIDs[0].Start == cmd.data.UIDs[0].Stop {
+		cmd.data.UID = cmd.data.UIDs[0].Start
+	}
+	return &cmd.data, firstErr
+}
@@ -1,9 +1,14 @@
 package imapclient_test
 
 import (
+	"fmt"
+	"net"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/luhaoyun888/go-imap-cn"
+	"github.com/luhaoyun888/go-imap-cn/imapclient"
 )
 
 // TestAppend 测试 APPEND 命令。
@@ -28,3 +33,241 @@ func TestAppend(t *testing.T) {
 
 	// TODO: 获取消息并检查内容
 }
+
+// TestMultiAppend 测试在一个 APPEND 命令中追加多封邮件。
+func TestMultiAppend(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateSelected)
+	defer client.Close()
+	defer server.Close()
+
+	if !client.Caps().Has(imap.CapMultiAppend) {
+		t.Skip("服务器不支持 MULTIAPPEND")
+	}
+
+	bodies := []string{"第一封测试消息。", "第二封测试消息。", "第三封测试消息。"}
+
+	appendCmd := client.MultiAppend("INBOX")
+	for _, body := range bodies {
+		w := appendCmd.CreateMessage(int64(len(body)), &imap.AppendOptions{
+			Flags: []imap.Flag{imap.FlagSeen},
+		})
+		if _, err := w.Write([]byte(body)); err != nil {
+			t.Fatalf("Write() = %v", err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close() = %v", err)
+		}
+	}
+	if err := appendCmd.Close(); err != nil {
+		t.Fatalf("MultiAppendCommand.Close() = %v", err)
+	}
+	data, err := appendCmd.Wait()
+	if err != nil {
+		t.Fatalf("MultiAppendCommand.Wait() = %v", err)
+	}
+	uids, ok := data.UIDs.Nums()
+	if !ok || len(uids) != len(bodies) {
+		t.Errorf("AppendData.UIDs = %v, want %v UIDs", data.UIDs, len(bodies))
+	}
+
+	// 选中的邮箱应收到新邮件的 EXISTS 更新，且序号与服务器一致
+	if err := client.Noop().Wait(); err != nil {
+		t.Fatalf("Noop().Wait() = %v", err)
+	}
+	if n := client.Mailbox().NumMessages; n != uint32(1+len(bodies)) {
+		t.Errorf("NumMessages = %v, want %v", n, 1+len(bodies))
+	}
+
+	msgs, err := client.Fetch(imap.SeqSetNum(4), &imap.FetchOptions{UID: true}).Collect()
+	if err != nil {
+		t.Fatalf("Fetch().Collect() = %v", err)
+	} else if len(msgs) != 1 || len(uids) == 0 || msgs[0].UID != uids[len(uids)-1] {
+		t.Errorf("Fetch(4) = %v, want UID %v", msgs, uids)
+	}
+}
+
+// TestCatenate 测试使用 CATENATE 拼接文本和已有邮件的部分。
+func TestCatenate(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateSelected)
+	defer client.Close()
+	defer server.Close()
+
+	if !client.Caps().Has(imap.CapCatenate) {
+		t.Skip("服务器不支持 CATENATE")
+	}
+
+	section := &imap.FetchItemBodySection{Specifier: imap.PartSpecifierText}
+	msgs, err := client.Fetch(imap.SeqSetNum(1), &imap.FetchOptions{
+		UID:         true,
+		BodySection: []*imap.FetchItemBodySection{section},
+	}).Collect()
+	if err != nil {
+		t.Fatalf("Fetch().Collect() = %v", err)
+	} else if len(msgs) != 1 {
+		t.Fatalf("len(msgs) = %v, want %v", len(msgs), 1)
+	}
+	var text []byte
+	for _, b := range msgs[0].BodySection {
+		text = b
+	}
+
+	header := "Subject: 拼接的邮件\r\n\r\n"
+	parts := []imap.CatenatePart{
+		{Text: []byte(header)},
+		{URL: fmt.Sprintf("/INBOX/;UID=%v/;SECTION=TEXT", msgs[0].UID)},
+	}
+	data, err := client.Catenate("INBOX", parts, nil).Wait()
+	if err != nil {
+		t.Fatalf("Catenate().Wait() = %v", err)
+	}
+
+	wholeSection := &imap.FetchItemBodySection{}
+	msgs, err = client.Fetch(imap.UIDSetNum(data.UID), &imap.FetchOptions{
+		BodySection: []*imap.FetchItemBodySection{wholeSection},
+	}).Collect()
+	if err != nil {
+		t.Fatalf("Fetch().Collect() = %v", err)
+	} else if len(msgs) != 1 {
+		t.Fatalf("len(msgs) = %v, want %v", len(msgs), 1)
+	}
+	var got string
+	for _, b := range msgs[0].BodySection {
+		got = string(b)
+	}
+	if want := header + string(text); got != want {
+		t.Errorf("BODY[] = %q, want %q", got, want)
+	}
+
+	// 绝对 URL 可以引用本服务器上当前用户的邮件
+	uid := msgs[0].UID
+	parts = []imap.CatenatePart{{URL: fmt.Sprintf("imap://%v@localhost:143/INBOX/;UID=%v", testUsername, uid)}}
+	if _, err := client.Catenate("INBOX", parts, nil).Wait(); err != nil {
+		t.Errorf("Catenate(绝对 URL) = %v", err)
+	}
+
+	for _, url := range []string{
+		"/INBOX/;UID=4242", // 不存在的邮件
+		fmt.Sprintf("imap://example.org/INBOX/;UID=%v", uid),       // 其他服务器
+		fmt.Sprintf("imap://someone@localhost/INBOX/;UID=%v", uid), // 其他用户
+	} {
+		parts = []imap.CatenatePart{{URL: url}}
+		_, err := client.Catenate("INBOX", parts, nil).Wait()
+		if code := imap.ErrorCode(err); !strings.HasPrefix(string(code), string(imap.ResponseCodeBadURL)) {
+			t.Errorf("Catenate(%v) = %v, want NO [BADURL]", url, err)
+		}
+	}
+}
+
+// TestCatenate_unsupported 测试服务器不支持 CATENATE 时不发送命令。
+func TestCatenate_unsupported(t *testing.T) {
+	client := newScriptedClient(t, "", func(cmd string) []string {
+		t.Errorf("不应发送命令: %q", cmd)
+		return nil
+	})
+
+	parts := []imap.CatenatePart{{Text: []byte("Subject: 拼接的邮件\r\n\r\n")}}
+	if _, err := client.Catenate("INBOX", parts, nil).Wait(); err == nil {
+		t.Errorf("Catenate() 在服务器不支持 CATENATE 时应失败")
+	}
+}
+
+// TestAppend_selectedExists 测试 APPEND 到已选邮箱后，本连接在 APPEND 完成前收到 EXISTS，
+// 正在 IDLE 的其他连接也会收到 EXISTS。
+func TestAppend_selectedExists(t *testing.T) {
+	conn, server := newMemClientServerPair(t)
+	defer server.Close()
+	otherConn, err := net.Dial("tcp", conn.RemoteAddr().String())
+	if err != nil {
+		t.Fatalf("net.Dial() = %v", err)
+	}
+
+	existsCh := make(chan uint32, 1)
+	client := imapclient.New(conn, nil)
+	defer client.Close()
+	other := imapclient.New(otherConn, &imapclient.Options{
+		UnilateralDataHandler: &imapclient.UnilateralDataHandler{
+			Mailbox: func(data *imapclient.UnilateralDataMailbox) {
+				if data.NumMessages != nil {
+					existsCh <- *data.NumMessages
+				}
+			},
+		},
+	})
+	defer other.Close()
+
+	for _, c := range []*imapclient.Client{client, other} {
+		if err := c.Login(testUsername, testPassword).Wait(); err != nil {
+			t.Fatalf("Login().Wait() = %v", err)
+		}
+		if _, err := c.Select("INBOX", nil).Wait(); err != nil {
+			t.Fatalf("Select().Wait() = %v", err)
+		}
+	}
+
+	idleCmd, err := other.Idle()
+	if err != nil {
+		t.Fatalf("Idle() = %v", err)
+	}
+
+	appendCmd := client.Append("INBOX", int64(len(simpleRawMessage)), nil)
+	appendCmd.Write([]byte(simpleRawMessage))
+	appendCmd.Close()
+	if _, err := appendCmd.Wait(); err != nil {
+		t.Fatalf("Append().Wait() = %v", err)
+	}
+	// 服务器在 APPEND 的 OK 响应之前发送 EXISTS，无需再发送 NOOP
+	if n := client.Mailbox().NumMessages; n != 1 {
+		t.Errorf("Mailbox().NumMessages = %v, want 1", n)
+	}
+
+	select {
+	case n := <-existsCh:
+		if n != 1 {
+			t.Errorf("IDLE 期间收到 EXISTS %v, want 1", n)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("IDLE 期间未收到 EXISTS")
+	}
+
+	if err := idleCmd.Close(); err != nil {
+		t.Fatalf("IdleCommand.Close() = %v", err)
+	}
+	if err := idleCmd.Wait(); err != nil {
+		t.Fatalf("IdleCommand.Wait() = %v", err)
+	}
+	if n := other.Mailbox().NumMessages; n != 1 {
+		t.Errorf("other.Mailbox().NumMessages = %v, want 1", n)
+	}
+}
+
+// TestServerTime 测试通过临时邮件的 INTERNALDATE 估算服务器时间。
+func TestServerTime(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateAuthenticated)
+	defer client.Close()
+	defer server.Close()
+
+	if _, err := client.ServerTime(); err == nil {
+		t.Errorf("ServerTime() 在未选择邮箱时应失败")
+	}
+
+	if _, err := client.Select("INBOX", nil).Wait(); err != nil {
+		t.Fatalf("Select() = %v", err)
+	}
+
+	before := time.Now().Truncate(time.Second)
+	serverTime, err := client.ServerTime()
+	after := time.Now()
+	if err != nil {
+		t.Fatalf("ServerTime() = %v", err)
+	} else if serverTime.Before(before) || serverTime.After(after) {
+		t.Errorf("ServerTime() = %v, want between %v and %v", serverTime, before, after)
+	}
+
+	// 临时邮件已被删除
+	if err := client.Noop().Wait(); err != nil {
+		t.Fatalf("Noop().Wait() = %v", err)
+	}
+	if n := client.Mailbox().NumMessages; n != 1 {
+		t.Errorf("NumMessages = %v, want %v", n, 1)
+	}
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/luhaoyun888/go-imap-cn"
 	"github.com/luhaoyun888/go-imap-cn/internal"
+	"github.com/luhaoyun888/go-imap-cn/internal/imapwire"
 )
 
 // Authenticate 发送 AUTHENTICATE 命令。
@@ -29,7 +30,9 @@ func (c *Client) Authenticate(saslClient sasl.Client) error {
 	enc := c.beginCommand("AUTHENTICATE", cmd) // 开始 AUTHENTICATE 命令
 	enc.SP().Atom(mech)                        // 设置认证机制
 	if initialResp != nil && hasSASLIR {
-		enc.SP().Atom(internal.EncodeSASL(initialResp)) // 添加初始响应
+		enc.SP().Sensitive(func(enc *imapwire.Encoder) {
+			enc.Atom(internal.EncodeSASL(initialResp)) // 添加初始响应
+		})
 		initialResp = nil
 	}
 	enc.flush()     // 刷新编码
@@ -38,7 +41,11 @@ func (c *Client) Authenticate(saslClient sasl.Client) error {
 	for {
 		challengeStr, err := contReq.Wait() // 等待挑战字符串
 		if err != nil {
-			return cmd.wait() // 等待命令响应
+			err := cmd.wait() // 等待命令响应
+			if f, ok := saslClient.(saslFailureClient); ok && err != nil {
+				err = f.saslFailure(err)
+			}
+			return err
 		}
 
 		if challengeStr == "" {
@@ -61,6 +68,11 @@ func (c *Client) Authenticate(saslClient sasl.Client) error {
 
 		resp, err := saslClient.Next(challenge) // 获取下一个 SASL 响应
 		if err != nil {
+			// 取消认证交换，否则服务器会把之后的命令当作 SASL 响应
+			if err := c.writeSASLCancel(); err != nil {
+				return err
+			}
+			cmd.wait()
 			return err
 		}
 
@@ -75,9 +87,41 @@ type authenticateCommand struct {
 	commandBase
 }
 
+// saslFailureClient 由能够从服务器挑战中获得失败详情的 SASL 客户端实现。
+//
+// 认证失败时，Authenticate 调用 saslFailure 以把详情附加到服务器返回的错误上。
+type saslFailureClient interface {
+	saslFailure(err error) error
+}
+
+// writeSASLCancel 发送 "*" 取消 SASL 交换。
+func (c *Client) writeSASLCancel() error {
+	if _, err := c.bw.WriteString("*\r\n"); err != nil {
+		return err
+	}
+	return c.bw.Flush()
+}
+
 // writeSASLResp 写入 SASL 响应。
 func (c *Client) writeSASLResp(resp []byte) error {
-	respStr := internal.EncodeSASL(resp) // 编码 SASL 响应
+	// 后续响应中的空响应是空行，"=" 仅用于初始响应（RFC 4959）
+	var respStr string
+	if len(resp) > 0 {
+		respStr = internal.EncodeSASL(resp) // 编码 SASL 响应
+	}
+	if setRedact := c.setDebugRedact(); setRedact != nil {
+		// 单独写出 SASL 响应，以便在调试输出中隐藏
+		setRedact(true)
+		_, err := c.bw.WriteString(respStr)
+		if err == nil {
+			err = c.bw.Flush()
+		}
+		setRedact(false)
+		if err != nil {
+			return err
+		}
+		respStr = ""
+	}
 	if _, err := c.bw.WriteString(respStr + "\r\n"); err != nil {
 		return err // 写入时出错
 	}
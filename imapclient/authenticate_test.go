@@ -0,0 +1,45 @@
+package imapclient_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/emersion/go-sasl"
+
+	"github.com/luhaoyun888/go-imap-cn"
+	"github.com/luhaoyun888/go-imap-cn/imapclient"
+)
+
+// TestAuthenticate_caps 测试客户端采纳 AUTHENTICATE 成功响应中的能力，
+// 无需额外发送 CAPABILITY 命令。
+func TestAuthenticate_caps(t *testing.T) {
+	conn, server := newMemClientServerPair(t)
+	defer server.Close()
+
+	var debug lockedBuffer
+	client := imapclient.New(conn, &imapclient.Options{DebugWriter: &debug})
+	defer client.Close()
+
+	if client.Caps().Has(imap.CapACL) {
+		t.Fatalf("认证前 Caps() 包含 ACL")
+	}
+	capCmds := strings.Count(debug.String(), " CAPABILITY\r\n")
+
+	saslClient := sasl.NewPlainClient("", testUsername, testPassword)
+	if err := client.Authenticate(saslClient); err != nil {
+		t.Fatalf("Authenticate() = %v", err)
+	}
+
+	caps := client.Caps()
+	for _, c := range []imap.Cap{imap.CapACL, imap.CapUIDPlus, imap.CapIdle} {
+		if !caps.Has(c) {
+			t.Errorf("认证后 Caps() 不包含 %v", c)
+		}
+	}
+	if n := strings.Count(debug.String(), " CAPABILITY\r\n"); n != capCmds {
+		t.Errorf("认证后发送了 %v 个额外的 CAPABILITY 命令", n-capCmds)
+	}
+	if !strings.Contains(debug.String(), " OK [CAPABILITY ") {
+		t.Errorf("AUTHENTICATE 响应中没有 CAPABILITY 响应码")
+	}
+}
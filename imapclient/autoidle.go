@@ -0,0 +1,108 @@
+package imapclient
+
+import (
+	"github.com/luhaoyun888/go-imap-cn"
+)
+
+// AutoIdle 启用或禁用自动 IDLE。
+//
+// 启用后，客户端在已认证或已选择状态下没有待处理的命令时自动发送 IDLE，
+// 服务器推送的更新通过 UnilateralDataHandler 实时投递。发送其它命令前，
+// 客户端会先结束 IDLE 并等待服务器确认，命令完成后再重新进入 IDLE。
+//
+// 服务器必须支持 IMAP4rev2 或 IDLE 扩展，否则不会进入 IDLE。启用后不能在
+// UnilateralDataHandler 中发送命令，因为结束 IDLE 需要等待服务器的响应。
+func (c *Client) AutoIdle(enable bool) {
+	c.mutex.Lock()
+	c.autoIdle = enable
+	startLoop := enable && !c.autoIdleLoop
+	if startLoop {
+		c.autoIdleLoop = true
+	}
+	c.mutex.Unlock()
+
+	if startLoop {
+		go c.autoIdleLoopRun()
+	}
+	if enable {
+		c.notifyAutoIdle()
+	} else {
+		c.interruptAutoIdle()()
+	}
+}
+
+// autoIdleLoopRun 在收到通知时尝试进入自动 IDLE，直到连接关闭。
+func (c *Client) autoIdleLoopRun() {
+	for {
+		select {
+		case <-c.autoIdleCh:
+		case <-c.decCh: // 连接已关闭
+			return
+		}
+		c.startAutoIdle()
+	}
+}
+
+// notifyAutoIdle 通知自动 IDLE 循环重新检查是否可以进入 IDLE。不会阻塞。
+func (c *Client) notifyAutoIdle() {
+	select {
+	case c.autoIdleCh <- struct{}{}:
+	default:
+	}
+}
+
+// startAutoIdle 在启用了自动 IDLE 且客户端空闲时发送 IDLE。
+func (c *Client) startAutoIdle() {
+	c.autoIdleMutex.Lock()
+	defer c.autoIdleMutex.Unlock()
+
+	c.mutex.Lock()
+	if c.autoIdleCmd != nil {
+		select {
+		case <-c.autoIdleCmd.done: // 自动 IDLE 因错误结束
+			c.autoIdleCmd = nil
+		default:
+		}
+	}
+	ok := c.autoIdle && c.autoIdleCmd == nil && c.cmdStarting == 0 && len(c.pendingCmds) == 0 &&
+		!c.closed && (c.state == imap.ConnStateAuthenticated || c.state == imap.ConnStateSelected) &&
+		c.caps.Has(imap.CapIdle)
+	c.mutex.Unlock()
+	if !ok {
+		return
+	}
+
+	idleCmd, err := c.idleWithRenewal(idleRestartInterval)
+	if err != nil {
+		return // 命令失败时等待下一次通知
+	}
+	c.mutex.Lock()
+	c.autoIdleCmd = idleCmd
+	c.mutex.Unlock()
+}
+
+// interruptAutoIdle 结束正在运行的自动 IDLE，并在返回的函数被调用之前
+// 阻止再次进入自动 IDLE。
+func (c *Client) interruptAutoIdle() (done func()) {
+	c.autoIdleMutex.Lock()
+	defer c.autoIdleMutex.Unlock()
+
+	c.mutex.Lock()
+	idleCmd := c.autoIdleCmd
+	c.autoIdleCmd = nil
+	c.cmdStarting++
+	c.mutex.Unlock()
+
+	if idleCmd != nil {
+		// 等待服务器确认 IDLE 结束，避免与之后的命令产生歧义
+		if err := idleCmd.Close(); err == nil {
+			idleCmd.Wait()
+		}
+	}
+
+	return func() {
+		c.mutex.Lock()
+		c.cmdStarting--
+		c.mutex.Unlock()
+	}
+}
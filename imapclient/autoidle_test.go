@@ -0,0 +1,100 @@
+package imapclient_test
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/luhaoyun888/go-imap-cn/imapclient"
+)
+
+// waitDebug 等待调试输出满足 f。
+func waitDebug(t *testing.T, debug *lockedBuffer, f func(s string) bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !f(debug.String()) {
+		if time.Now().After(deadline) {
+			t.Fatalf("等待超时，调试输出:\n%v", debug.String())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// TestAutoIdle 测试自动 IDLE 在发送命令时被中断，并在命令完成后恢复。
+func TestAutoIdle(t *testing.T) {
+	conn, server := newMemClientServerPair(t)
+	defer server.Close()
+
+	numMessagesCh := make(chan uint32, 16)
+	var debug lockedBuffer
+	client := imapclient.New(conn, &imapclient.Options{
+		DebugWriter: &debug,
+		UnilateralDataHandler: &imapclient.UnilateralDataHandler{
+			Mailbox: func(data *imapclient.UnilateralDataMailbox) {
+				if data.NumMessages != nil {
+					numMessagesCh <- *data.NumMessages
+				}
+			},
+		},
+	})
+	defer client.Close()
+
+	if err := client.Login(testUsername, testPassword).Wait(); err != nil {
+		t.Fatalf("Login().Wait() = %v", err)
+	}
+	if _, err := client.Select("INBOX", nil).Wait(); err != nil {
+		t.Fatalf("Select().Wait() = %v", err)
+	}
+
+	client.AutoIdle(true)
+	waitDebug(t, &debug, func(s string) bool {
+		return strings.Count(s, " IDLE\r\n") == 1
+	})
+
+	// 发送命令会先结束 IDLE
+	if err := client.Noop().Wait(); err != nil {
+		t.Fatalf("Noop().Wait() = %v", err)
+	}
+	s := debug.String()
+	if i, j := strings.Index(s, "DONE\r\n"), strings.Index(s, " NOOP\r\n"); i < 0 || j < i {
+		t.Fatalf("NOOP 未在 DONE 之后发送:\n%v", s)
+	}
+
+	// 命令完成后重新进入 IDLE，并实时收到其他连接的更新
+	waitDebug(t, &debug, func(s string) bool {
+		return strings.Count(s, " IDLE\r\n") == 2
+	})
+	otherConn, err := net.Dial("tcp", conn.RemoteAddr().String())
+	if err != nil {
+		t.Fatalf("net.Dial() = %v", err)
+	}
+	other := imapclient.New(otherConn, nil)
+	defer other.Close()
+	if err := other.Login(testUsername, testPassword).Wait(); err != nil {
+		t.Fatalf("Login().Wait() = %v", err)
+	}
+	appendCmd := other.Append("INBOX", int64(len(simpleRawMessage)), nil)
+	appendCmd.Write([]byte(simpleRawMessage))
+	appendCmd.Close()
+	if _, err := appendCmd.Wait(); err != nil {
+		t.Fatalf("Append().Wait() = %v", err)
+	}
+	select {
+	case n := <-numMessagesCh:
+		if n != 1 {
+			t.Errorf("NumMessages = %v, want 1", n)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("IDLE 期间未收到 EXISTS")
+	}
+
+	// 禁用后结束 IDLE，不再重新进入
+	client.AutoIdle(false)
+	if err := client.Noop().Wait(); err != nil {
+		t.Fatalf("Noop().Wait() = %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if s := debug.String(); strings.Count(s, " IDLE\r\n") != 2 || strings.Count(s, "DONE\r\n") != 2 {
+		t.Errorf("禁用自动 IDLE 后仍进入 IDLE:\n%v", s)
+	}
+}
@@ -0,0 +1,61 @@
+package imapclient
+
+import (
+	"crypto/sha256"
+	"hash"
+	"io"
+
+	"github.com/luhaoyun888/go-imap-cn"
+)
+
+// ChecksumReader 包装 FETCH 返回的字面量，在读取的同时计算内容的 SHA-256 校验和，
+// 例如用于在备份邮件时校验下载的 BODY[] 是否完整：
+//
+//	r := imapclient.NewChecksumReader(item.Literal)
+//	if _, err := io.Copy(f, r); err != nil {
+//		return err
+//	}
+//	sum := r.Sum()
+//
+// 只有读取到 io.EOF 后，Sum 才是完整内容的校验和。
+type ChecksumReader struct {
+	imap.LiteralReader
+	hash hash.Hash
+	n    int64
+}
+
+// NewChecksumReader 创建一个在读取 r 的同时计算 SHA-256 校验和的 ChecksumReader。
+func NewChecksumReader(r imap.LiteralReader) *ChecksumReader {
+	return &ChecksumReader{LiteralReader: r, hash: sha256.New()}
+}
+
+// Read 实现了 io.Reader 接口。
+func (r *ChecksumReader) Read(b []byte) (int, error) {
+	n, err := r.LiteralReader.Read(b)
+	r.hash.Write(b[:n])
+	r.n += int64(n)
+	return n, err
+}
+
+// Sum 返回到目前为止读取的内容的 SHA-256 校验和。
+func (r *ChecksumReader) Sum() [sha256.Size]byte {
+	var sum [sha256.Size]byte
+	r.hash.Sum(sum[:0])
+	return sum
+}
+
+// BytesRead 返回到目前为止读取的字节数。读取完成后应等于 Size。
+func (r *ChecksumReader) BytesRead() int64 {
+	return r.n
+}
+
+// DownloadWithChecksum 将字面量的内容写入 w，并返回写入的字节数和内容的 SHA-256 校验和。
+// 如果读取的字节数与字面量的大小不符，返回 io.ErrUnexpectedEOF。
+func DownloadWithChecksum(w io.Writer, lit imap.LiteralReader) (n int64, sum [sha256.Size]byte, err error) {
+	r := NewChecksumReader(lit)
+	n, err = io.Copy(w, r)
+	if err == nil && n != lit.Size() {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, r.Sum(), err
+}
@@ -0,0 +1,71 @@
+package imapclient_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/luhaoyun888/go-imap-cn"
+	"github.com/luhaoyun888/go-imap-cn/imapclient"
+)
+
+func TestDownloadWithChecksum(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateSelected)
+	defer client.Close()
+	defer server.Close()
+
+	var sb strings.Builder
+	sb.WriteString("From: alice@example.org\r\nSubject: large\r\n\r\n")
+	for i := 0; sb.Len() < 4<<20; i++ {
+		fmt.Fprintf(&sb, "第 %v 行：%v\r\n", i, strings.Repeat("0123456789", 8))
+	}
+	raw := sb.String()
+
+	appendCmd := client.Append("INBOX", int64(len(raw)), nil)
+	appendCmd.Write([]byte(raw))
+	appendCmd.Close()
+	appendData, err := appendCmd.Wait()
+	if err != nil {
+		t.Fatalf("Append().Wait() = %v", err)
+	}
+
+	fetchCmd := client.Fetch(imap.UIDSetNum(appendData.UID), &imap.FetchOptions{
+		BodySection: []*imap.FetchItemBodySection{{Peek: true}},
+	})
+	defer fetchCmd.Close()
+	msg := fetchCmd.Next()
+	if msg == nil {
+		t.Fatalf("FetchCommand.Next() = nil")
+	}
+	var item imapclient.FetchItemDataBodySection
+	for {
+		next := msg.Next()
+		if next == nil {
+			t.Fatalf("FETCH 响应中缺少 BODY[]")
+		}
+		if data, ok := next.(imapclient.FetchItemDataBodySection); ok {
+			item = data
+			break
+		}
+	}
+
+	var buf bytes.Buffer
+	n, sum, err := imapclient.DownloadWithChecksum(&buf, item.Literal)
+	if err != nil {
+		t.Fatalf("DownloadWithChecksum() = %v", err)
+	}
+	if n != int64(len(raw)) {
+		t.Errorf("DownloadWithChecksum() n = %v, want %v", n, len(raw))
+	}
+	if want := sha256.Sum256([]byte(raw)); sum != want {
+		t.Errorf("DownloadWithChecksum() sum = %x, want %x", sum, want)
+	}
+	if buf.String() != raw {
+		t.Errorf("下载的内容与原始邮件不一致")
+	}
+	if err := fetchCmd.Close(); err != nil {
+		t.Fatalf("FetchCommand.Close() = %v", err)
+	}
+}
@@ -20,12 +20,14 @@ package imapclient
 
 import (
 	"bufio"
+	"context"
 	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
 	"mime"
 	"net"
+	"os"
 	"runtime/debug"
 	"strconv"
 	"sync"
@@ -45,6 +47,9 @@ const (
 	literalWriteTimeout = 5 * time.Minute  // 文本写入超时
 )
 
+// errLogoutTimeout 在服务器未及时回复 LOGOUT 时返回。
+var errLogoutTimeout = fmt.Errorf("imapclient: 等待 LOGOUT 响应超时: %w", os.ErrDeadlineExceeded)
+
 var dialer = &net.Dialer{
 	Timeout: 30 * time.Second, // 连接超时
 }
@@ -55,6 +60,9 @@ type SelectedMailbox struct {
 	NumMessages    uint32      // 邮件数量
 	Flags          []imap.Flag // 邮箱标志
 	PermanentFlags []imap.Flag // 永久标志
+	ReadOnly       bool        // 是否以只读方式选择（EXAMINE 或服务器返回 READ-ONLY）
+
+	seqNums *SeqNumTracker // 设置 Options.TrackSeqNums 时的序号跟踪器，拷贝之间共享
 }
 
 // copy 拷贝
@@ -67,19 +75,47 @@ func (mbox *SelectedMailbox) copy() *SelectedMailbox {
 type Options struct {
 	// 用于 DialTLS 和 DialStartTLS 的 TLS 配置。如果为 nil，则使用默认配置。
 	TLSConfig *tls.Config
+	// Dial 系列函数建立 TCP 连接时使用的拨号器，可用于控制超时、TCP keepalive
+	// 和本地地址绑定。如果为 nil，则使用 30 秒超时的默认拨号器。
+	Dialer *net.Dialer
 	// 原始的输入和输出数据将被写入此写入器（如果有）。注意，这可能包含在身份验证期间使用的敏感信息，例如凭证。
 	DebugWriter io.Writer
+	// 如果为 true，写入 DebugWriter 的 LOGIN 密码和 AUTHENTICATE 的 SASL 响应
+	// 会被替换为 "***"。
+	DebugRedact bool
 	// 单边数据处理程序。
 	UnilateralDataHandler *UnilateralDataHandler
 	// RFC 2047 字符串的解码器。
 	WordDecoder *mime.WordDecoder
+
+	// 检测到有歧义的流水线命令时调用（参见 RFC 9051 第 5.5 节），例如在
+	// FETCH 完成之前对相同消息发送 STORE。
+	AmbiguousPipelineHandler func(err error)
+	// 如果为 true，则有歧义的命令会等待冲突的待处理命令完成后再发送。
+	//
+	// 调用者必须在另一个 goroutine 中消耗冲突的命令（例如 FetchCommand），
+	// 否则发送新命令时可能会永远阻塞。
+	SerializeAmbiguousPipeline bool
+	// Logout 等待服务器回复的最长时间。超时后连接会被关闭。
+	// 某些服务器（例如 Dovecot）不会回复 LOGOUT。为零时使用默认值 30 秒。
+	LogoutTimeout time.Duration
+	// 如果大于零，客户端在已认证或已选择状态下空闲超过该时长（没有待处理的
+	// 命令，也没有正在运行的 IDLE）时自动发送 NOOP，以维持连接并接收单边更新。
+	KeepAliveInterval time.Duration
+	// TCPKeepAlive 是底层 TCP 连接的 keepalive 周期，用于检测死连接。
+	// 大于零时启用 keepalive 并使用该周期，小于零时禁用，为零时保持拨号器的默认设置。
+	// 只对 TCP 连接（包括其上的 TLS 连接）生效。
+	TCPKeepAlive time.Duration
+	// 如果为 true，客户端在选择邮箱后维护序号与 UID 的对应关系，参见
+	// Client.SeqNumTracker。
+	TrackSeqNums bool
 }
 
 // wrapReadWriter 将读写器包装，如果设置了 DebugWriter，则返回包装后的读写器。
 // 否则，返回原始的读写器。
-func (options *Options) wrapReadWriter(rw io.ReadWriter) io.ReadWriter {
+func (c *Client) wrapReadWriter(rw io.ReadWriter) io.ReadWriter {
 	// 如果未设置 DebugWriter，则直接返回原始的读写器。
-	if options.DebugWriter == nil {
+	if c.debugWriter == nil {
 		return rw
 	}
 	// 返回同时写入 rw 和 DebugWriter 的包装读写器。
@@ -87,9 +123,45 @@ func (options *Options) wrapReadWriter(rw io.ReadWriter) io.ReadWriter {
 		io.Reader
 		io.Writer
 	}{
-		Reader: io.TeeReader(rw, options.DebugWriter),   // 读取时同时写入 DebugWriter
-		Writer: io.MultiWriter(rw, options.DebugWriter), // 写入时同时写入 DebugWriter
+		Reader: io.TeeReader(rw, c.options.DebugWriter), // 读取时同时写入 DebugWriter
+		Writer: io.MultiWriter(rw, c.debugWriter),       // 写入时同时写入 DebugWriter
+	}
+}
+
+// debugWriter 把写出的数据复制到 Options.DebugWriter。
+//
+// 处于脱敏状态时，写入的数据被替换为一次 "***"。只能在持有 Client.encMutex 时使用。
+type debugWriter struct {
+	w        io.Writer
+	redact   bool // 是否处于脱敏状态
+	redacted bool // 本次脱敏是否已写出 "***"
+}
+
+func (w *debugWriter) Write(b []byte) (int, error) {
+	if !w.redact {
+		return w.w.Write(b)
+	}
+	if !w.redacted {
+		w.redacted = true
+		if _, err := io.WriteString(w.w, "***"); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// setRedact 切换脱敏状态。
+func (w *debugWriter) setRedact(redact bool) {
+	w.redact = redact
+	w.redacted = false
+}
+
+// setDebugRedact 返回编码器在写入凭证时使用的脱敏回调，未启用 DebugRedact 时返回 nil。
+func (c *Client) setDebugRedact() func(redact bool) {
+	if c.debugWriter == nil || !c.options.DebugRedact {
+		return nil
 	}
+	return c.debugWriter.setRedact
 }
 
 // decodeText 解码 MIME 编码的字符串，返回解码后的字符串。
@@ -131,6 +203,15 @@ func (options *Options) tlsConfig() *tls.Config {
 	}
 }
 
+// dialer 返回拨号器。
+// 如果 Options 结构体设置了 Dialer，则返回它，否则返回默认的拨号器。
+func (options *Options) dialer() *net.Dialer {
+	if options != nil && options.Dialer != nil {
+		return options.Dialer
+	}
+	return dialer
+}
+
 // Client 是一个 IMAP 客户端。
 //
 // IMAP 命令作为方法暴露。这些方法将在命令发送到服务器后阻塞，但不会阻塞直到服务器发送响应。
@@ -141,12 +222,14 @@ func (options *Options) tlsConfig() *tls.Config {
 // 但这并不保证任何命令的顺序，并且受到命令流水线的相同限制（请参见上文）。
 // 此外，一些命令（例如 StartTLS、Authenticate、Idle）在执行期间会阻塞客户端。
 type Client struct {
-	conn     net.Conn
-	options  Options
-	br       *bufio.Reader
-	bw       *bufio.Writer
-	dec      *imapwire.Decoder
-	encMutex sync.Mutex
+	conn        net.Conn
+	options     Options
+	rawRW       io.ReadWriter // 底层传输的读写器（STARTTLS 之后为 TLS 连接），不含调试包装
+	debugWriter *debugWriter  // 写入 DebugWriter 的包装，未设置 DebugWriter 时为 nil
+	br          *bufio.Reader
+	bw          *bufio.Writer
+	dec         *imapwire.Decoder
+	encMutex    sync.Mutex
 
 	greetingCh   chan struct{} // 问候通道
 	greetingRecv bool          // 是否已接收问候
@@ -165,6 +248,15 @@ type Client struct {
 	pendingCmds  []command             // 待处理命令
 	contReqs     []continuationRequest // 续请求
 	closed       bool                  // 是否已关闭
+	byeRecv      bool                  // 是否已接收 BYE
+	lastCmd      time.Time             // 最近一次发送命令的时间
+
+	autoIdleMutex sync.Mutex    // 串行化自动 IDLE 的进入和退出
+	autoIdleCh    chan struct{} // 通知自动 IDLE 循环检查是否可以进入 IDLE
+	autoIdle      bool          // 是否启用自动 IDLE，由 mutex 保护
+	autoIdleLoop  bool          // 自动 IDLE 循环是否已启动，由 mutex 保护
+	autoIdleCmd   *IdleCommand  // 正在运行的自动 IDLE，由 mutex 保护
+	cmdStarting   int           // 已退出自动 IDLE 但尚未加入 pendingCmds 的命令数，由 mutex 保护
 }
 
 // New 创建一个新的 IMAP 客户端。
@@ -177,22 +269,30 @@ func New(conn net.Conn, options *Options) *Client {
 		options = &Options{}
 	}
 
-	rw := options.wrapReadWriter(conn) // 包装读取器和写入器
-	br := bufio.NewReader(rw)          // 创建 bufio 读取器
-	bw := bufio.NewWriter(rw)          // 创建 bufio 写入器
-
 	client := &Client{
 		conn:       conn,
 		options:    *options,
-		br:         br,
-		bw:         bw,
-		dec:        imapwire.NewDecoder(br, imapwire.ConnSideClient),
+		rawRW:      conn,
 		greetingCh: make(chan struct{}), // 初始化问候通道
 		decCh:      make(chan struct{}), // 初始化解码通道
-		state:      imap.ConnStateNone,  // 初始化连接状态
-		enabled:    make(imap.CapSet),   // 初始化启用的能力集
+		autoIdleCh: make(chan struct{}, 1),
+		state:      imap.ConnStateNone, // 初始化连接状态
+		enabled:    make(imap.CapSet),  // 初始化启用的能力集
 	}
+	if options.DebugWriter != nil {
+		client.debugWriter = &debugWriter{w: options.DebugWriter}
+	}
+	// 尽力而为：设置失败时连接仍然可用，只是无法及时检测死连接
+	internal.SetTCPKeepAlive(conn, options.TCPKeepAlive)
+
+	rw := client.wrapReadWriter(conn) // 包装读取器和写入器
+	client.br = bufio.NewReader(rw)   // 创建 bufio 读取器
+	client.bw = bufio.NewWriter(rw)   // 创建 bufio 写入器
+	client.dec = imapwire.NewDecoder(client.br, imapwire.ConnSideClient)
 	go client.read() // 启动读取 goroutine
+	if options.KeepAliveInterval > 0 {
+		go client.keepAlive(options.KeepAliveInterval)
+	}
 	return client
 }
 
@@ -200,15 +300,37 @@ func New(conn net.Conn, options *Options) *Client {
 //
 // nil 选项指针等效于零选项值。
 func NewStartTLS(conn net.Conn, options *Options) (*Client, error) {
+	return newStartTLS(context.Background(), conn, options)
+}
+
+// newStartTLS 与 NewStartTLS 相同，但 ctx 取消时会关闭连接并中断 STARTTLS 协商。
+func newStartTLS(ctx context.Context, conn net.Conn, options *Options) (*Client, error) {
 	if options == nil {
 		options = &Options{}
 	}
 
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close() // 中断正在进行的读写
+		case <-done:
+		}
+	}()
+
 	client := New(conn, options) // 创建新的客户端
 	if err := client.startTLS(options.TLSConfig); err != nil {
 		conn.Close()
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
 		return nil, err // 启用 STARTTLS 失败
 	}
+	if err := ctx.Err(); err != nil {
+		client.Close()
+		return nil, err
+	}
 
 	// 根据第 7.1.4 节，在使用 STARTTLS 时拒绝 PREAUTH
 	if client.State() != imap.ConnStateNotAuthenticated {
@@ -221,7 +343,14 @@ func NewStartTLS(conn net.Conn, options *Options) (*Client, error) {
 
 // DialInsecure 连接到不加密的 IMAP 服务器。
 func DialInsecure(address string, options *Options) (*Client, error) {
-	conn, err := net.Dial("tcp", address) // 建立 TCP 连接
+	return DialInsecureContext(context.Background(), address, options)
+}
+
+// DialInsecureContext 与 DialInsecure 相同，但使用 ctx 控制拨号。
+//
+// ctx 仅作用于建立连接；连接建立后取消 ctx 不会影响返回的客户端。
+func DialInsecureContext(ctx context.Context, address string, options *Options) (*Client, error) {
+	conn, err := options.dialer().DialContext(ctx, "tcp", address) // 建立 TCP 连接
 	if err != nil {
 		return nil, err
 	}
@@ -230,12 +359,23 @@ func DialInsecure(address string, options *Options) (*Client, error) {
 
 // DialTLS 连接到使用隐式 TLS 的 IMAP 服务器。
 func DialTLS(address string, options *Options) (*Client, error) {
+	return DialTLSContext(context.Background(), address, options)
+}
+
+// DialTLSContext 与 DialTLS 相同，但使用 ctx 控制拨号和 TLS 握手。
+//
+// ctx 仅作用于建立连接；连接建立后取消 ctx 不会影响返回的客户端。
+func DialTLSContext(ctx context.Context, address string, options *Options) (*Client, error) {
 	tlsConfig := options.tlsConfig() // 获取 TLS 配置
 	if tlsConfig.NextProtos == nil {
 		tlsConfig.NextProtos = []string{"imap"} // 设置下一个协议
 	}
 
-	conn, err := tls.DialWithDialer(dialer, "tcp", address, tlsConfig) // 使用 TLS 建立连接
+	tlsDialer := &tls.Dialer{
+		NetDialer: options.dialer(),
+		Config:    tlsConfig,
+	}
+	conn, err := tlsDialer.DialContext(ctx, "tcp", address) // 使用 TLS 建立连接
 	if err != nil {
 		return nil, err
 	}
@@ -244,6 +384,13 @@ func DialTLS(address string, options *Options) (*Client, error) {
 
 // DialStartTLS 连接到使用 STARTTLS 的 IMAP 服务器。
 func DialStartTLS(address string, options *Options) (*Client, error) {
+	return DialStartTLSContext(context.Background(), address, options)
+}
+
+// DialStartTLSContext 与 DialStartTLS 相同，但使用 ctx 控制拨号和 STARTTLS 协商。
+//
+// ctx 仅作用于建立连接；连接建立后取消 ctx 不会影响返回的客户端。
+func DialStartTLSContext(ctx context.Context, address string, options *Options) (*Client, error) {
 	if options == nil {
 		options = &Options{}
 	}
@@ -253,7 +400,7 @@ func DialStartTLS(address string, options *Options) (*Client, error) {
 		return nil, err
 	}
 
-	conn, err := dialer.Dial("tcp", address) // 建立 TCP 连接
+	conn, err := options.dialer().DialContext(ctx, "tcp", address) // 建立 TCP 连接
 	if err != nil {
 		return nil, err
 	}
@@ -263,8 +410,8 @@ func DialStartTLS(address string, options *Options) (*Client, error) {
 		tlsConfig.ServerName = host // 设置服务器名称
 	}
 	newOptions := *options
-	newOptions.TLSConfig = tlsConfig      // 更新选项中的 TLS 配置
-	return NewStartTLS(conn, &newOptions) // 创建并返回 STARTTLS 客户端
+	newOptions.TLSConfig = tlsConfig           // 更新选项中的 TLS 配置
+	return newStartTLS(ctx, conn, &newOptions) // 创建并返回 STARTTLS 客户端
 }
 
 func (c *Client) setReadTimeout(dur time.Duration) {
@@ -374,6 +521,20 @@ func (c *Client) Mailbox() *SelectedMailbox {
 	return c.mailbox // 返回选定的邮箱
 }
 
+// SeqNumTracker 返回当前选定邮箱的序号跟踪器。
+//
+// 只有设置了 Options.TrackSeqNums 时才会维护跟踪器。如果没有设置该选项或者没有
+// 选定的邮箱，则返回 nil。跟踪器在选择邮箱时创建，邮件的 UID 最初都是未知的，
+// 获取邮件的 UID 后才能在序号和 UID 之间转换。
+func (c *Client) SeqNumTracker() *SeqNumTracker {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.mailbox == nil {
+		return nil
+	}
+	return c.mailbox.seqNums
+}
+
 // Close 立即关闭连接。
 func (c *Client) Close() error {
 	c.mutex.Lock()
@@ -403,21 +564,31 @@ func (c *Client) Close() error {
 //
 // 调用者必须调用 commandEncoder.end。
 func (c *Client) beginCommand(name string, cmd command) *commandEncoder {
+	if name != "IDLE" {
+		// 先退出自动 IDLE，命令加入 pendingCmds 之后才允许再次进入
+		defer c.interruptAutoIdle()()
+	}
 	c.encMutex.Lock() // commandEncoder.end 解锁
+	return c.beginCommandLocked(name, cmd)
+}
 
+// beginCommandLocked 与 beginCommand 相同，但调用者必须已经持有 c.encMutex。
+func (c *Client) beginCommandLocked(name string, cmd command) *commandEncoder {
 	c.mutex.Lock()
 
+	c.lastCmd = time.Now()
 	c.cmdTag++                          // 增加命令标签
 	tag := fmt.Sprintf("T%v", c.cmdTag) // 格式化标签
 
 	baseCmd := cmd.base()
 	*baseCmd = commandBase{
-		tag:  tag,
-		done: make(chan error, 1), // 创建命令完成通道
+		tag:       tag,
+		done:      make(chan error, 1), // 创建命令完成通道
+		completed: make(chan struct{}), // 命令完成时关闭
 	}
 
 	c.pendingCmds = append(c.pendingCmds, cmd) // 将命令添加到待处理命令中
-	quotedUTF8 := c.caps.Has(imap.CapIMAP4rev2) || c.enabled.Has(imap.CapUTF8Accept)
+	quotedUTF8 := c.utf8AcceptedLocked()
 	literalMinus := c.caps.Has(imap.CapLiteralMinus)
 	literalPlus := c.caps.Has(imap.CapLiteralPlus)
 
@@ -432,6 +603,7 @@ func (c *Client) beginCommand(name string, cmd command) *commandEncoder {
 	wireEnc.NewContinuationRequest = func() *imapwire.ContinuationRequest {
 		return c.registerContReq(cmd) // 注册续请求
 	}
+	wireEnc.SetRedact = c.setDebugRedact()
 
 	enc := &commandEncoder{
 		Encoder: wireEnc,
@@ -442,6 +614,14 @@ func (c *Client) beginCommand(name string, cmd command) *commandEncoder {
 	return enc
 }
 
+// utf8AcceptedLocked 返回服务器是否接受 UTF-8 字符串。
+//
+// 服务器支持 IMAP4rev2 时默认接受 UTF-8，无需显式发送 ENABLE。
+// 调用者必须持有 c.mutex。
+func (c *Client) utf8AcceptedLocked() bool {
+	return c.caps.Has(imap.CapIMAP4rev2) || c.enabled.Has(imap.CapUTF8Accept)
+}
+
 // deletePendingCmdByTag 根据命令的标签删除队列中的待处理命令。
 // 参数：
 // - tag: 字符串类型，表示要删除的命令标签。
@@ -514,6 +694,7 @@ func (c *Client) completeCommand(cmd command, err error) {
 	done := cmd.base().done
 	done <- err
 	close(done)
+	close(cmd.base().completed)
 
 	// 确保命令不会因为后续请求被阻塞
 	c.mutex.Lock()
@@ -551,6 +732,13 @@ func (c *Client) completeCommand(cmd command, err error) {
 				NumMessages:    cmd.data.NumMessages,    // 邮件数量
 				Flags:          cmd.data.Flags,          // 标志
 				PermanentFlags: cmd.data.PermanentFlags, // 永久标志
+				ReadOnly:       cmd.readOnly || cmd.data.ReadOnly,
+			}
+			if c.options.TrackSeqNums {
+				c.mailbox.seqNums = NewSeqNumTracker(cmd.data.NumMessages)
+			}
+			if cmd.condStore {
+				c.enabled[imap.CapCondStore] = struct{}{} // SELECT (CONDSTORE) 会启用 CONDSTORE，参见 RFC 7162 第 3.1 节
 			}
 			c.mutex.Unlock()
 		}
@@ -572,6 +760,8 @@ func (c *Client) completeCommand(cmd command, err error) {
 	case *ExpungeCommand:
 		close(cmd.seqNums) // 关闭序列号通道
 	}
+
+	c.notifyAutoIdle()
 }
 
 // registerContReq 注册一个后续请求，用于处理命令的进一步交互。
@@ -606,11 +796,24 @@ func (c *Client) closeWithError(err error) {
 	c.state = imap.ConnStateLogout // 设置为已注销状态
 	pendingCmds := c.pendingCmds
 	c.pendingCmds = nil
+	byeRecv := c.byeRecv
 	c.mutex.Unlock()
 
 	// 为每个待处理的命令标记为完成并返回错误
 	for _, cmd := range pendingCmds {
-		c.completeCommand(cmd, err)
+		cmdErr := err
+		if cmd, ok := cmd.(*logoutCommand); ok {
+			c.mutex.Lock()
+			timedOut := cmd.timedOut
+			c.mutex.Unlock()
+			if timedOut {
+				cmdErr = errLogoutTimeout
+			} else if byeRecv {
+				// 服务器在 BYE 之后直接关闭连接，LOGOUT 视为成功
+				cmdErr = nil
+			}
+		}
+		c.completeCommand(cmd, cmdErr)
 	}
 }
 
@@ -679,13 +882,13 @@ func (c *Client) readResponse() error {
 
 	// 根据是否有标签处理响应
 	var (
-		token    string
-		err      error
-		startTLS *startTLSCommand
+		token   string
+		err     error
+		upgrade command
 	)
 	if tag != "" {
 		token = "有标签的响应"
-		startTLS, err = c.readResponseTagged(tag, typ)
+		upgrade, err = c.readResponseTagged(tag, typ)
 	} else {
 		token = "数据响应"
 		err = c.readResponseData(typ)
@@ -699,9 +902,12 @@ func (c *Client) readResponse() error {
 		return fmt.Errorf("响应中: %v", c.dec.Err())
 	}
 
-	// 如果是 STARTTLS 命令，则升级为安全连接
-	if startTLS != nil {
-		c.upgradeStartTLS(startTLS)
+	// 如果是 STARTTLS 或 COMPRESS 命令，则升级连接
+	switch upgrade := upgrade.(type) {
+	case *startTLSCommand:
+		c.upgradeStartTLS(upgrade)
+	case *compressCommand:
+		c.upgradeCompress(upgrade)
 	}
 
 	return nil
@@ -745,9 +951,9 @@ func (c *Client) readContinueReq() error {
 // - typ: 响应的类型，表示状态，例如 OK、NO、BAD 等。
 //
 // 返回值：
-// - startTLS: 如果响应中包含 STARTTLS 命令，则返回对应的命令。
+// - upgrade: 如果响应完成了 STARTTLS 或 COMPRESS 命令，则返回对应的命令，读取完响应后需要升级连接。
 // - err: 返回处理过程中发生的错误，若无错误则为 nil。
-func (c *Client) readResponseTagged(tag, typ string) (startTLS *startTLSCommand, err error) {
+func (c *Client) readResponseTagged(tag, typ string) (upgrade command, err error) {
 	// 根据标签删除并返回待处理的命令
 	cmd := c.deletePendingCmdByTag(tag)
 	if cmd == nil {
@@ -764,7 +970,10 @@ func (c *Client) readResponseTagged(tag, typ string) (startTLS *startTLSCommand,
 	// 某些服务器即使 RFC 要求文本也不会提供，参考问题 #500 和 #502
 	hasSP := c.dec.SP()
 
-	var code string
+	var (
+		code      string
+		referrals []string
+	)
 	if hasSP && c.dec.Special('[') { // 处理 resp-text-code 部分
 		if !c.dec.ExpectAtom(&code) {
 			return nil, fmt.Errorf("在 resp-text-code 中: %v", c.dec.Err())
@@ -777,18 +986,31 @@ func (c *Client) readResponseTagged(tag, typ string) (startTLS *startTLSCommand,
 				return nil, fmt.Errorf("在 capability-data 中: %v", err)
 			}
 			c.setCaps(caps) // 设置客户端的功能集
+		case "READ-ONLY":
+			if cmd, ok := cmd.(*SelectCommand); ok {
+				cmd.data.ReadOnly = true
+			}
 		case "APPENDUID":
 			var (
 				uidValidity uint32
-				uid         imap.UID
+				uids        imap.UIDSet
 			)
-			// 读取 APPENDUID 相关的有效性和 UID
-			if !c.dec.ExpectSP() || !c.dec.ExpectNumber(&uidValidity) || !c.dec.ExpectSP() || !c.dec.ExpectUID(&uid) {
+			// 读取 APPENDUID 相关的有效性和 UID，MULTIAPPEND 时为 UID 集合
+			if !c.dec.ExpectSP() || !c.dec.ExpectNumber(&uidValidity) || !c.dec.ExpectSP() || !c.dec.ExpectUIDSet(&uids) {
 				return nil, fmt.Errorf("在 resp-code-apnd 中: %v", c.dec.Err())
 			}
-			if cmd, ok := cmd.(*AppendCommand); ok {
+			var uid imap.UID
+			if len(uids) == 1 && uids[0].Start == uids[0].Stop {
+				uid = uids[0].Start
+			}
+			switch cmd := cmd.(type) {
+			case *AppendCommand:
 				cmd.data.UID = uid
 				cmd.data.UIDValidity = uidValidity
+			case *MultiAppendCommand:
+				cmd.data.UID = uid
+				cmd.data.UIDValidity = uidValidity
+				cmd.data.UIDs = uids
 			}
 		case "COPYUID":
 			if !c.dec.ExpectSP() {
@@ -811,6 +1033,33 @@ func (c *Client) readResponseTagged(tag, typ string) (startTLS *startTLSCommand,
 				cmd.data.SourceUIDs = srcUIDs
 				cmd.data.DestUIDs = dstUIDs
 			}
+		case "MAILBOXID":
+			var id string
+			if !c.dec.ExpectSP() || !c.dec.ExpectSpecial('(') || !c.dec.Expect(c.dec.Func(&id, isObjectIDChar), "objectid") || !c.dec.ExpectSpecial(')') {
+				return nil, fmt.Errorf("在 resp-code-mailboxid 中: %v", c.dec.Err())
+			}
+			if cmd, ok := cmd.(*CreateCommand); ok {
+				cmd.mailboxID = id
+			}
+		case "REFERRAL":
+			referrals, err = readReferrals(c.dec)
+			if err != nil {
+				return nil, fmt.Errorf("在 referral-response-code 中: %v", err)
+			}
+		case "MODIFIED":
+			cmd, ok := cmd.(*FetchCommand)
+			if !ok {
+				if c.dec.SP() {
+					c.dec.DiscardUntilByte(']')
+				}
+				break
+			}
+			// 读取未满足 UNCHANGEDSINCE 条件的消息集合
+			var modified imap.NumSet
+			if !c.dec.ExpectSP() || !c.dec.ExpectNumSet(imapwire.NumSetKind(cmd.numSet), &modified) {
+				return nil, fmt.Errorf("在 resp-code-modified 中: %v", c.dec.Err())
+			}
+			cmd.modified = modified
 		default: // 处理其他未定义的文本代码
 			if c.dec.SP() {
 				c.dec.DiscardUntilByte(']')
@@ -827,17 +1076,23 @@ func (c *Client) readResponseTagged(tag, typ string) (startTLS *startTLSCommand,
 	if hasSP && !c.dec.ExpectText(&text) {
 		return nil, fmt.Errorf("在 resp-text 中: %v", c.dec.Err())
 	}
+	if code == "ALERT" {
+		c.handleAlert(text)
+	}
 
 	// 根据响应类型处理不同的状态
 	var cmdErr error
 	switch typ {
 	case "OK":
-		// 不需要处理 OK 类型的响应
+		// 带有 REFERRAL 的 OK 响应表示命令已成功，但服务器建议改用其他服务器，
+		// 例如邮箱已迁移。URL 通过命令的 Referrals 方法返回
+		cmd.base().referrals = referrals
 	case "NO", "BAD":
 		cmdErr = &imap.Error{
-			Type: imap.StatusResponseType(typ),
-			Code: imap.ResponseCode(code),
-			Text: text,
+			Type:      imap.StatusResponseType(typ),
+			Code:      imap.ResponseCode(code),
+			Text:      text,
+			Referrals: referrals,
 		}
 	default:
 		return nil, fmt.Errorf("在 resp-cond-state 中: 期望 OK、NO 或 BAD 状态，但收到 %v", typ)
@@ -846,9 +1101,12 @@ func (c *Client) readResponseTagged(tag, typ string) (startTLS *startTLSCommand,
 	// 完成命令处理并传递可能的错误
 	c.completeCommand(cmd, cmdErr)
 
-	// 处理 STARTTLS 命令
-	if cmd, ok := cmd.(*startTLSCommand); ok && cmdErr == nil {
-		startTLS = cmd
+	// 处理 STARTTLS 和 COMPRESS 命令
+	if cmdErr == nil {
+		switch cmd.(type) {
+		case *startTLSCommand, *compressCommand:
+			upgrade = cmd
+		}
 	}
 
 	// 如果没有错误并且代码不是 CAPABILITY，清空某些命令的功能集
@@ -860,7 +1118,35 @@ func (c *Client) readResponseTagged(tag, typ string) (startTLS *startTLSCommand,
 		}
 	}
 
-	return startTLS, nil
+	return upgrade, nil
+}
+
+// readReferrals 读取 REFERRAL 响应代码中以空格分隔的 IMAP URL 列表。
+func readReferrals(dec *imapwire.Decoder) ([]string, error) {
+	var referrals []string
+	for dec.SP() {
+		var url string
+		if !dec.Expect(dec.Func(&url, isReferralChar), "url") {
+			return nil, dec.Err()
+		}
+		referrals = append(referrals, url)
+	}
+	if len(referrals) == 0 {
+		return nil, fmt.Errorf("缺少 URL")
+	}
+	return referrals, nil
+}
+
+// isReferralChar 判断字符能否出现在 REFERRAL 响应代码的 URL 中。
+func isReferralChar(ch byte) bool {
+	return ch > ' ' && ch < 0x7f && ch != ']'
+}
+
+// handleAlert 将 [ALERT] 响应的文本交给 UnilateralDataHandler.Alert。
+func (c *Client) handleAlert(text string) {
+	if handler := c.options.unilateralDataHandler().Alert; handler != nil {
+		handler(text)
+	}
 }
 
 // readResponseData 解析服务器的响应数据，根据响应类型处理相应的逻辑。
@@ -889,7 +1175,10 @@ func (c *Client) readResponseData(typ string) error {
 		// 某些服务器不会提供文本，即使 RFC 要求，参见 #500 和 #502
 		hasSP := c.dec.SP()
 
-		var code string
+		var (
+			code      string
+			referrals []string
+		)
 		if hasSP && c.dec.Special('[') { // resp-text-code 响应文本代码
 			if !c.dec.ExpectAtom(&code) {
 				return fmt.Errorf("在 resp-text-code 中出错: %v", c.dec.Err())
@@ -962,6 +1251,11 @@ func (c *Client) readResponseData(typ string) error {
 				if cmd := findPendingCmdByType[*SelectCommand](c); cmd != nil {
 					cmd.data.HighestModSeq = modSeq
 				}
+			case "REFERRAL":
+				var err error
+				if referrals, err = readReferrals(c.dec); err != nil {
+					return fmt.Errorf("在 referral-response-code 中出错: %v", err)
+				}
 			case "NOMODSEQ":
 				// 忽略
 			default: // [SP 1*<任意除了 "]" 的文本字符>]
@@ -980,9 +1274,19 @@ func (c *Client) readResponseData(typ string) error {
 			return fmt.Errorf("在 resp-text 中出错: %v", c.dec.Err())
 		}
 
+		if code == "ALERT" {
+			c.handleAlert(text)
+		}
 		if code == "CLOSED" {
 			c.setState(imap.ConnStateAuthenticated)
 		}
+		if typ == "BYE" && c.greetingRecv {
+			// 服务器即将关闭连接
+			c.mutex.Lock()
+			c.state = imap.ConnStateLogout
+			c.byeRecv = true
+			c.mutex.Unlock()
+		}
 
 		if !c.greetingRecv {
 			switch typ {
@@ -993,9 +1297,10 @@ func (c *Client) readResponseData(typ string) error {
 			default:
 				c.setState(imap.ConnStateLogout)
 				c.greetingErr = &imap.Error{
-					Type: imap.StatusResponseType(typ),
-					Code: imap.ResponseCode(code),
-					Text: text,
+					Type:      imap.StatusResponseType(typ),
+					Code:      imap.ResponseCode(code),
+					Text:      text,
+					Referrals: referrals,
 				}
 			}
 			c.greetingRecv = true
@@ -1023,12 +1328,17 @@ func (c *Client) readResponseData(typ string) error {
 	case "EXISTS":
 		return c.handleExists(num)
 	case "RECENT":
-		// 忽略
-	case "LIST":
+		c.handleRecent(num)
+	case "VANISHED":
 		if !c.dec.ExpectSP() {
 			return c.dec.Err()
 		}
-		return c.handleList()
+		return c.handleVanished()
+	case "LIST", "LSUB":
+		if !c.dec.ExpectSP() {
+			return c.dec.Err()
+		}
+		return c.handleList(typ == "LSUB")
 	case "STATUS":
 		if !c.dec.ExpectSP() {
 			return c.dec.Err()
@@ -1074,6 +1384,11 @@ func (c *Client) readResponseData(typ string) error {
 			return c.dec.Err()
 		}
 		return c.handleGetACL()
+	case "LISTRIGHTS":
+		if !c.dec.ExpectSP() {
+			return c.dec.Err()
+		}
+		return c.handleListRights()
 	default:
 		return fmt.Errorf("不支持的响应类型 %q", typ)
 	}
@@ -1102,18 +1417,41 @@ func (c *Client) Noop() *Command {
 }
 
 // Logout 发送 LOGOUT 命令，通知服务器客户端已完成连接。
+//
+// 服务器会先发送 BYE 再回复 LOGOUT。如果服务器在 Options.LogoutTimeout 内
+// 没有回复，连接会被关闭，命令返回超时错误。
 func (c *Client) Logout() *Command {
 	cmd := &logoutCommand{}
 	c.beginCommand("LOGOUT", cmd).end() // 开始并结束 LOGOUT 命令
+
+	timeout := c.options.LogoutTimeout
+	if timeout == 0 {
+		timeout = respReadTimeout
+	}
+	go func() {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		select {
+		case <-cmd.completed:
+		case <-timer.C:
+			c.mutex.Lock()
+			cmd.timedOut = true
+			c.mutex.Unlock()
+			c.conn.Close()
+		}
+	}()
+
 	return &cmd.Command
 }
 
 // Login 发送 LOGIN 命令。
 func (c *Client) Login(username, password string) *Command {
 	cmd := &loginCommand{}
-	enc := c.beginCommand("LOGIN", cmd)             // 开始登录命令
-	enc.SP().String(username).SP().String(password) // 添加用户名和密码
-	enc.end()                                       // 结束命令
+	enc := c.beginCommand("LOGIN", cmd) // 开始登录命令
+	enc.SP().String(username).SP().Sensitive(func(enc *imapwire.Encoder) {
+		enc.String(password) // 添加密码
+	})
+	enc.end() // 结束命令
 	return &cmd.Command
 }
 
@@ -1193,8 +1531,12 @@ func (ce *commandEncoder) end() {
 // 该方法写入 CRLF 并刷新编码器。调用者必须调用 commandEncoder.end 来释放锁。
 func (ce *commandEncoder) flush() {
 	if err := ce.Encoder.CRLF(); err != nil {
-		// TODO: 考虑将错误存储在 Client 中，以便在未来调用中返回
-		ce.client.closeWithError(err)
+		// 如果服务器拒绝了同步字面量，命令已经结束，连接仍然可用
+		var imapErr *imap.Error
+		if !errors.As(err, &imapErr) {
+			// TODO: 考虑将错误存储在 Client 中，以便在未来调用中返回
+			ce.client.closeWithError(err)
+		}
 	}
 	ce.Encoder = nil
 }
@@ -1262,11 +1604,15 @@ type UnilateralDataMailbox struct {
 // - Mailbox: 处理邮箱状态更新的函数，参数是 UnilateralDataMailbox。
 // - Fetch: 处理抓取消息的函数，参数是 FetchMessageData。
 // - Metadata: 处理邮箱元数据的函数，要求启用 METADATA 或 SERVER-METADATA。
+// - Vanished: 启用 QRESYNC 后代替 Expunge 处理 VANISHED 响应，earlier 表示 VANISHED (EARLIER)。
+// - Alert: 处理带有 [ALERT] 响应码的 OK、NO、BAD 等响应，参数是应展示给用户的文本。
 type UnilateralDataHandler struct {
 	Expunge  func(seqNum uint32)
 	Mailbox  func(data *UnilateralDataMailbox)
 	Fetch    func(msg *FetchMessageData)
 	Metadata func(mailbox string, entries []string)
+	Vanished func(uids imap.UIDSet, earlier bool)
+	Alert    func(text string)
 }
 
 // command 是 IMAP 命令的接口。
@@ -1281,9 +1627,11 @@ type command interface {
 // - done: 一个信道，表示命令是否完成。
 // - err: 命令的错误。
 type commandBase struct {
-	tag  string
-	done chan error
-	err  error
+	tag       string
+	done      chan error
+	completed chan struct{} // 命令完成时关闭，不会消耗 done 中的错误
+	err       error
+	referrals []string // OK [REFERRAL] 响应中的 URL
 }
 
 // base 返回命令的基础结构。
@@ -1291,6 +1639,23 @@ func (cmd *commandBase) base() *commandBase {
 	return cmd
 }
 
+// Referrals 返回成功的 OK [REFERRAL] 响应中的 IMAP URL 列表（RFC 2193、RFC 2221），
+// 服务器以此建议客户端以后改用其他服务器。没有 REFERRAL 时返回 nil。
+//
+// 只能在 Wait 返回之后调用。失败命令的 URL 保存在 imap.Error.Referrals 中。
+func (cmd *commandBase) Referrals() []string {
+	return cmd.referrals
+}
+
+// newFailedCommandBase 返回一个尚未发送就已经以 err 失败的命令的基础结构。
+func newFailedCommandBase(err error) commandBase {
+	done := make(chan error)
+	close(done)
+	completed := make(chan struct{})
+	close(completed)
+	return commandBase{done: done, completed: completed, err: err}
+}
+
 // wait 等待命令完成。
 // 返回：
 // - error: 如果有错误，返回错误。
@@ -1322,4 +1687,5 @@ type loginCommand struct {
 // logoutCommand 是一个注销命令，继承自 Command。
 type logoutCommand struct {
 	Command
+	timedOut bool // 由 Client.mutex 保护
 }
@@ -1,12 +1,21 @@
 package imapclient_test
 
 import (
+	"bufio"
 	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
 	"io"
 	"net"
 	"os"
+	"reflect"
+	"strings"
 	"sync"
 	"testing"
+	"time"
+
+	"github.com/emersion/go-sasl"
 
 	"github.com/luhaoyun888/go-imap-cn"
 	"github.com/luhaoyun888/go-imap-cn/imapclient"
@@ -92,6 +101,7 @@ func newMemClientServerPair(t *testing.T) (net.Conn, io.Closer) {
 
 	user := imapmemserver.NewUser(testUsername, testPassword) // 创建用户
 	user.Create("INBOX", nil)                                 // 创建 INBOX 文件夹
+	user.SetQuotaAdmin(true)                                  // 允许测试用户通过 SETQUOTA 修改配额
 
 	memServer.AddUser(user) // 将用户添加到服务器
 
@@ -107,10 +117,29 @@ func newMemClientServerPair(t *testing.T) (net.Conn, io.Closer) {
 		TLSConfig: &tls.Config{ // 配置 TLS
 			Certificates: []tls.Certificate{cert},
 		},
-		InsecureAuth: true, // 允许不安全的身份验证
+		InsecureAuth: true,                  // 允许不安全的身份验证
+		Hostnames:    []string{"localhost"}, // CATENATE 的绝对 URL 可以引用 localhost
 		Caps: imap.CapSet{ // 设置服务器功能
-			imap.CapIMAP4rev1: {},
-			imap.CapIMAP4rev2: {},
+			imap.CapIMAP4rev1:       {},
+			imap.CapIMAP4rev2:       {},
+			imap.CapCompressDeflate: {},
+			imap.CapCondStore:       {},
+			imap.CapMultiAppend:     {},
+			imap.CapCatenate:        {},
+			imap.CapQResync:         {},
+			imap.CapObjectID:        {},
+			imap.CapACL:             {},
+			imap.CapWithin:          {},
+			imap.CapSort:            {},
+			imap.CapQuota:           {},
+			imap.CapQuotaSet:        {},
+			"QUOTA=RES-STORAGE":     {},
+			"QUOTA=RES-MESSAGE":     {},
+			imap.CapMetadata:        {},
+			imap.CapMetadataServer:  {},
+			imap.CapMultiSearch:     {},
+			"THREAD=ORDEREDSUBJECT": {},
+			"THREAD=REFERENCES":     {},
 		},
 	})
 
@@ -264,6 +293,145 @@ func TestLogout(t *testing.T) {
 	}
 }
 
+// TestLogout_bye 测试客户端消费 LOGOUT 之前的 BYE 响应。
+func TestLogout_bye(t *testing.T) {
+	client := newScriptedClient(t, "", func(cmd string) []string {
+		if cmd != "LOGOUT" {
+			return nil
+		}
+		return []string{"* BYE logging out"}
+	})
+
+	if err := client.Logout().Wait(); err != nil {
+		t.Errorf("Logout().Wait() = %v", err)
+	}
+	if state := client.State(); state != imap.ConnStateLogout {
+		t.Errorf("State() = %v, want %v", state, imap.ConnStateLogout)
+	}
+}
+
+// TestLogout_timeout 测试服务器不回复 LOGOUT 时的超时。
+func TestLogout_timeout(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	defer serverConn.Close()
+	go func() {
+		fmt.Fprintf(serverConn, "* OK [CAPABILITY IMAP4rev1] ready\r\n")
+		io.Copy(io.Discard, serverConn) // 从不回复
+	}()
+
+	client := imapclient.New(clientConn, &imapclient.Options{LogoutTimeout: 50 * time.Millisecond})
+	defer client.Close()
+	if err := client.WaitGreeting(); err != nil {
+		t.Fatalf("WaitGreeting() = %v", err)
+	}
+
+	if err := client.Logout().Wait(); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("Logout().Wait() = %v, want %v", err, os.ErrDeadlineExceeded)
+	}
+}
+
+// TestAlert 测试将欢迎信息、未标记响应和标记响应中的 [ALERT] 文本交给
+// UnilateralDataHandler.Alert。
+func TestAlert(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	defer serverConn.Close()
+	go func() {
+		fmt.Fprintf(serverConn, "* OK [ALERT] 服务器将于今晚维护\r\n")
+		br := bufio.NewReader(serverConn)
+		for {
+			line, err := br.ReadString('\n')
+			if err != nil {
+				return
+			}
+			tag, _, _ := strings.Cut(line, " ")
+			fmt.Fprintf(serverConn, "* NO [ALERT] 账户即将停用\r\n")
+			fmt.Fprintf(serverConn, "%v BAD [ALERT] 命令被拒绝\r\n", tag)
+		}
+	}()
+
+	var (
+		mutex  sync.Mutex
+		alerts []string
+	)
+	client := imapclient.New(clientConn, &imapclient.Options{
+		UnilateralDataHandler: &imapclient.UnilateralDataHandler{
+			Alert: func(text string) {
+				mutex.Lock()
+				alerts = append(alerts, text)
+				mutex.Unlock()
+			},
+		},
+	})
+	defer client.Close()
+
+	if err := client.Noop().Wait(); err == nil {
+		t.Errorf("Noop().Wait() 成功，期望 BAD")
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	want := []string{"服务器将于今晚维护", "账户即将停用", "命令被拒绝"}
+	if strings.Join(alerts, "|") != strings.Join(want, "|") {
+		t.Errorf("alerts = %q, want %q", alerts, want)
+	}
+}
+
+// TestReferral 测试解析 NO 和 OK 响应中的 REFERRAL 响应代码。
+func TestReferral(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	defer serverConn.Close()
+	go func() {
+		fmt.Fprintf(serverConn, "* OK ready\r\n")
+		br := bufio.NewReader(serverConn)
+		for {
+			line, err := br.ReadString('\n')
+			if err != nil {
+				return
+			}
+			tag, cmd, _ := strings.Cut(line, " ")
+			switch {
+			case strings.HasPrefix(cmd, "LOGIN "):
+				fmt.Fprintf(serverConn, "%v NO [REFERRAL imap://user@server2/] 请登录 server2\r\n", tag)
+			case strings.HasPrefix(cmd, "SELECT "):
+				fmt.Fprintf(serverConn, "* 0 EXISTS\r\n")
+				fmt.Fprintf(serverConn, "%v OK [REFERRAL imap://server2/INBOX imap://server3/INBOX] 邮箱已迁移\r\n", tag)
+			default:
+				fmt.Fprintf(serverConn, "%v OK done\r\n", tag)
+			}
+		}
+	}()
+
+	client := imapclient.New(clientConn, nil)
+	defer client.Close()
+
+	var imapErr *imap.Error
+	err := client.Login("user", "pass").Wait()
+	if !errors.As(err, &imapErr) {
+		t.Fatalf("Login().Wait() = %v, want *imap.Error", err)
+	}
+	if imapErr.Type != imap.StatusResponseTypeNo || imapErr.Code != imap.ResponseCodeReferral {
+		t.Errorf("Login() error = %v, want NO [REFERRAL]", imapErr)
+	}
+	if want := []string{"imap://user@server2/"}; !reflect.DeepEqual(imapErr.Referrals, want) {
+		t.Errorf("Login() referrals = %v, want %v", imapErr.Referrals, want)
+	}
+	if state := client.State(); state != imap.ConnStateNotAuthenticated {
+		t.Errorf("State() = %v, want %v", state, imap.ConnStateNotAuthenticated)
+	}
+
+	// OK [REFERRAL] 表示命令成功
+	selectCmd := client.Select("INBOX", nil)
+	if _, err := selectCmd.Wait(); err != nil {
+		t.Fatalf("Select().Wait() = %v", err)
+	}
+	if want := []string{"imap://server2/INBOX", "imap://server3/INBOX"}; !reflect.DeepEqual(selectCmd.Referrals(), want) {
+		t.Errorf("Select().Referrals() = %v, want %v", selectCmd.Referrals(), want)
+	}
+	if state := client.State(); state != imap.ConnStateSelected {
+		t.Errorf("State() = %v, want %v", state, imap.ConnStateSelected)
+	}
+}
+
 // https://github.com/emersion/go-imap/issues/562
 // TestFetch_invalid 测试无效的获取请求。
 func TestFetch_invalid(t *testing.T) {
@@ -312,3 +480,72 @@ func TestWaitGreeting_eof(t *testing.T) {
 		t.Fatalf("WaitGreeting() 应该失败") // 如果没有错误，则报告测试失败
 	}
 }
+
+// newScriptedClient 创建一个连接到脚本化服务器的客户端。
+//
+// 服务器发送附加了 caps（以空格开头）的问候语，随后对每条命令调用 handle，handle 返回的行
+// （不含标签和 CRLF）会依次发送给客户端，最后以标记的 OK 响应结束命令。
+func newScriptedClient(t *testing.T, caps string, handle func(cmd string) []string) *imapclient.Client {
+	clientConn, serverConn := net.Pipe()
+	go func() {
+		defer serverConn.Close()
+		fmt.Fprintf(serverConn, "* OK [CAPABILITY IMAP4rev1%v] ready\r\n", caps)
+		br := bufio.NewReader(serverConn)
+		for {
+			line, err := br.ReadString('\n')
+			if err != nil {
+				return
+			}
+			tag, cmd, _ := strings.Cut(strings.TrimRight(line, "\r\n"), " ")
+			for _, resp := range handle(cmd) {
+				fmt.Fprintf(serverConn, "%v\r\n", resp)
+			}
+			fmt.Fprintf(serverConn, "%v OK done\r\n", tag)
+		}
+	}()
+
+	client := imapclient.New(clientConn, nil)
+	t.Cleanup(func() { client.Close() })
+	if err := client.WaitGreeting(); err != nil {
+		t.Fatalf("WaitGreeting() = %v", err)
+	}
+	return client
+}
+
+// TestDebugRedact 测试 DebugRedact 在调试输出中隐藏 LOGIN 密码和 SASL 响应。
+func TestDebugRedact(t *testing.T) {
+	conn, server := newMemClientServerPair(t)
+	defer server.Close()
+
+	var debug lockedBuffer
+	client := imapclient.New(conn, &imapclient.Options{DebugWriter: &debug, DebugRedact: true})
+	defer client.Close()
+
+	if err := client.Login(testUsername, testPassword).Wait(); err != nil {
+		t.Fatalf("Login().Wait() = %v", err)
+	}
+	if s := debug.String(); strings.Contains(s, testPassword) {
+		t.Errorf("调试输出包含密码:\n%v", s)
+	} else if want := `LOGIN "` + testUsername + `" ***` + "\r\n"; !strings.Contains(s, want) {
+		t.Errorf("调试输出不包含 %q:\n%v", want, s)
+	}
+
+	conn2, err := net.Dial("tcp", conn.RemoteAddr().String())
+	if err != nil {
+		t.Fatalf("net.Dial() = %v", err)
+	}
+	var debug2 lockedBuffer
+	client2 := imapclient.New(conn2, &imapclient.Options{DebugWriter: &debug2, DebugRedact: true})
+	defer client2.Close()
+
+	saslClient := sasl.NewPlainClient("", testUsername, testPassword)
+	if err := client2.Authenticate(saslClient); err != nil {
+		t.Fatalf("Authenticate() = %v", err)
+	}
+	_, resp, _ := saslClient.Start()
+	if s := debug2.String(); strings.Contains(s, base64.StdEncoding.EncodeToString(resp)) {
+		t.Errorf("调试输出包含 SASL 响应:\n%v", s)
+	} else if !strings.Contains(s, "AUTHENTICATE PLAIN") || !strings.Contains(s, "***\r\n") {
+		t.Errorf("调试输出缺少 AUTHENTICATE 命令或脱敏标记:\n%v", s)
+	}
+}
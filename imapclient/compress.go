@@ -0,0 +1,66 @@
+package imapclient
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/luhaoyun888/go-imap-cn"
+	"github.com/luhaoyun888/go-imap-cn/internal"
+)
+
+// Compress 发送 COMPRESS DEFLATE 命令并启用 DEFLATE 压缩。
+//
+// 压缩层位于 TLS 之上：应在 STARTTLS 完成之后调用此方法。
+//
+// 与其他命令不同，此方法会阻塞，直到命令完成。
+//
+// 此命令需要支持 COMPRESS=DEFLATE，参见 RFC 4978。如果服务器不支持，命令不会被发送。
+func (c *Client) Compress() error {
+	if !c.Caps().Has(imap.CapCompressDeflate) {
+		return fmt.Errorf("imapclient: 服务器不支持 COMPRESS=DEFLATE")
+	}
+
+	upgradeDone := make(chan struct{}) // 创建一个通道，用于表示升级完成
+	cmd := &compressCommand{
+		upgradeDone: upgradeDone,
+	}
+	enc := c.beginCommand("COMPRESS", cmd)
+	enc.SP().Atom("DEFLATE")
+	enc.flush()     // 刷新编码器
+	defer enc.end() // 结束命令
+
+	// 在服务器响应之前不得发出其他命令：之后的数据都是压缩过的
+	if err := cmd.wait(); err != nil {
+		return err // 返回错误
+	}
+
+	// 解码器的 goroutine 将调用 Client.upgradeCompress
+	<-upgradeDone // 等待升级完成信号
+	return nil
+}
+
+// upgradeCompress 在服务器发送 OK 响应后启用压缩。它在解码器 goroutine 中运行。
+func (c *Client) upgradeCompress(compress *compressCommand) {
+	defer close(compress.upgradeDone) // 关闭升级完成信号
+
+	// 从我们的 bufio.Reader 中清空缓冲数据：这些数据已经是压缩过的
+	var buf bytes.Buffer
+	if _, err := io.CopyN(&buf, c.br, int64(c.br.Buffered())); err != nil {
+		panic(err) // 不会到达这里
+	}
+
+	r := io.MultiReader(&buf, c.rawRW)
+	rw := c.wrapReadWriter(internal.NewDeflateReadWriter(r, c.rawRW)) // 调试输出为解压后的数据
+
+	c.br.Reset(rw) // 重置 bufio.Reader
+	// 与 STARTTLS 一样，无法在这里重用 bufio.Writer
+	c.bw = bufio.NewWriter(rw) // 创建新的 bufio.Writer
+}
+
+type compressCommand struct {
+	commandBase
+
+	upgradeDone chan<- struct{} // 升级完成信号通道
+}
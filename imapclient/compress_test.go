@@ -0,0 +1,72 @@
+package imapclient_test
+
+import (
+	"crypto/tls"
+	"testing"
+
+	"github.com/luhaoyun888/go-imap-cn"
+	"github.com/luhaoyun888/go-imap-cn/imapclient"
+)
+
+// TestCompress 测试在 STARTTLS 之上启用 COMPRESS=DEFLATE。
+func TestCompress(t *testing.T) {
+	conn, server := newMemClientServerPair(t) // 创建一个内存客户端和服务器对
+	defer conn.Close()                        // 关闭连接
+	defer server.Close()                      // 关闭服务器
+
+	options := imapclient.Options{
+		TLSConfig: &tls.Config{InsecureSkipVerify: true}, // TLS 配置，允许不安全的连接
+	}
+	client, err := imapclient.NewStartTLS(conn, &options) // 创建新的 STARTTLS 客户端
+	if err != nil {
+		t.Fatalf("NewStartTLS() = %v", err)
+	}
+	defer client.Close() // 关闭客户端
+
+	if err := client.Login(testUsername, testPassword).Wait(); err != nil {
+		t.Fatalf("Login().Wait() = %v", err)
+	}
+	if !client.Caps().Has(imap.CapCompressDeflate) {
+		t.Fatalf("服务器未广告 %v", imap.CapCompressDeflate)
+	}
+
+	if err := client.Compress(); err != nil {
+		t.Fatalf("Compress() = %v", err)
+	}
+
+	// 压缩后的命令，包括字面量，都应正常工作
+	appendCmd := client.Append("INBOX", int64(len(simpleRawMessage)), nil)
+	appendCmd.Write([]byte(simpleRawMessage))
+	appendCmd.Close()
+	if _, err := appendCmd.Wait(); err != nil {
+		t.Fatalf("AppendCommand.Wait() = %v", err)
+	}
+
+	data, err := client.Select("INBOX", nil).Wait()
+	if err != nil {
+		t.Fatalf("Select().Wait() = %v", err)
+	} else if data.NumMessages != 1 {
+		t.Errorf("SelectData.NumMessages = %v, want %v", data.NumMessages, 1)
+	}
+
+	// 再次启用压缩应失败
+	if err := client.Compress(); err == nil {
+		t.Errorf("Compress() = nil, want an error")
+	}
+
+	if err := client.Noop().Wait(); err != nil {
+		t.Fatalf("Noop().Wait() = %v", err)
+	}
+}
+
+// TestCompress_unsupported 测试服务器不支持 COMPRESS=DEFLATE 时不发送命令。
+func TestCompress_unsupported(t *testing.T) {
+	client := newScriptedClient(t, "", func(cmd string) []string {
+		t.Errorf("不应发送命令: %q", cmd)
+		return nil
+	})
+
+	if err := client.Compress(); err == nil {
+		t.Errorf("Compress() 在服务器不支持 COMPRESS=DEFLATE 时应失败")
+	}
+}
@@ -10,13 +10,17 @@ import (
 // Copy 发送 COPY 命令。
 // 参数：
 //
-//	numSet - 要复制的邮件编号集合。
+//	numSet - 要复制的邮件编号集合，可以是 imap.SearchRes()。
 //	mailbox - 目标邮箱的名称。
 //
 // 返回值：
 //
 //	*CopyCommand - 复制命令的实例，用于后续操作。
 func (c *Client) Copy(numSet imap.NumSet, mailbox string) *CopyCommand {
+	if err := c.checkSearchRes(numSet); err != nil {
+		return &CopyCommand{commandBase: newFailedCommandBase(err)}
+	}
+
 	cmd := &CopyCommand{}                                                       // 创建一个新的 CopyCommand 实例
 	enc := c.beginCommand(uidCmdName("COPY", imapwire.NumSetKind(numSet)), cmd) // 开始 COPY 命令
 	enc.SP().NumSet(numSet).SP().Mailbox(mailbox)                               // 设置命令参数
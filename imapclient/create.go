@@ -13,9 +13,9 @@ import (
 //
 // 返回值：
 //
-//	*Command - CREATE 命令的实例，用于后续操作。
-func (c *Client) Create(mailbox string, options *imap.CreateOptions) *Command {
-	cmd := &Command{}                    // 创建一个新的 Command 实例
+//	*CreateCommand - CREATE 命令的实例，用于后续操作。
+func (c *Client) Create(mailbox string, options *imap.CreateOptions) *CreateCommand {
+	cmd := &CreateCommand{}              // 创建一个新的 CreateCommand 实例
 	enc := c.beginCommand("CREATE", cmd) // 开始 CREATE 命令
 	enc.SP().Mailbox(mailbox)            // 设置邮箱名称
 
@@ -27,3 +27,21 @@ func (c *Client) Create(mailbox string, options *imap.CreateOptions) *Command {
 	enc.end()  // 结束命令
 	return cmd // 返回 CREATE 命令实例
 }
+
+// CreateCommand 是一个 CREATE 命令。
+type CreateCommand struct {
+	commandBase
+	mailboxID string // MAILBOXID 响应码中的邮箱 ID
+}
+
+// Wait 阻塞直到命令完成。
+func (cmd *CreateCommand) Wait() error {
+	return cmd.wait()
+}
+
+// MailboxID 返回服务器在 MAILBOXID 响应码中返回的邮箱 ID，要求服务器支持 OBJECTID。
+//
+// 服务器未返回邮箱 ID 时为空字符串。必须在 Wait 之后调用。
+func (cmd *CreateCommand) MailboxID() string {
+	return cmd.mailboxID
+}
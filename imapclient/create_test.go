@@ -1,9 +1,13 @@
 package imapclient_test
 
 import (
+	"bytes"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/luhaoyun888/go-imap-cn"
+	"github.com/luhaoyun888/go-imap-cn/imapclient"
 )
 
 // testCreate 测试 CREATE 命令的实现。
@@ -62,3 +66,99 @@ func TestCreate(t *testing.T) {
 		testCreate(t, "Angus & Julia", true) // 测试 UTF-8 编码中包含 '&' 字符的情况
 	})
 }
+
+// lockedBuffer 是一个并发安全的 bytes.Buffer。
+type lockedBuffer struct {
+	mutex sync.Mutex
+	buf   bytes.Buffer
+}
+
+func (lb *lockedBuffer) Write(b []byte) (int, error) {
+	lb.mutex.Lock()
+	defer lb.mutex.Unlock()
+	return lb.buf.Write(b)
+}
+
+func (lb *lockedBuffer) String() string {
+	lb.mutex.Lock()
+	defer lb.mutex.Unlock()
+	return lb.buf.String()
+}
+
+// TestCreate_imap4rev2UTF8 测试 IMAP4rev2 服务器下无需 ENABLE 即使用 UTF-8 编码。
+func TestCreate_imap4rev2UTF8(t *testing.T) {
+	conn, server := newMemClientServerPair(t)
+	defer server.Close()
+
+	var debug lockedBuffer
+	client := imapclient.New(conn, &imapclient.Options{DebugWriter: &debug})
+	defer client.Close()
+
+	if err := client.Login(testUsername, testPassword).Wait(); err != nil {
+		t.Fatalf("Login().Wait() = %v", err)
+	}
+	if !client.Caps().Has(imap.CapIMAP4rev2) {
+		t.Skip("服务器不支持 IMAP4rev2")
+	}
+
+	name := "Cafè"
+	if err := client.Create(name, nil).Wait(); err != nil {
+		t.Fatalf("Create() = %v", err)
+	}
+	if !strings.Contains(debug.String(), `CREATE "Cafè"`) {
+		t.Errorf("CREATE 命令未使用 UTF-8 编码:\n%v", debug.String())
+	}
+
+	mailboxes, err := client.List("", name, nil).Collect()
+	if err != nil {
+		t.Fatalf("List() = %v", err)
+	} else if len(mailboxes) != 1 || mailboxes[0].Mailbox != name {
+		t.Errorf("List() = %v, want %q", mailboxes, name)
+	}
+
+	// 搜索非 ASCII 字符串时不需要 CHARSET
+	criteria := imap.SearchCriteria{Body: []string{"信"}}
+	if _, err := client.Select("INBOX", nil).Wait(); err != nil {
+		t.Fatalf("Select() = %v", err)
+	}
+	if _, err := client.Search(&criteria, nil).Wait(); err != nil {
+		t.Fatalf("Search() = %v", err)
+	}
+	if strings.Contains(debug.String(), "CHARSET") {
+		t.Errorf("SEARCH 命令不应指定 CHARSET:\n%v", debug.String())
+	}
+}
+
+// TestCreate_mailboxID 测试 CREATE 返回的 MAILBOXID 响应码。
+func TestCreate_mailboxID(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateAuthenticated)
+	defer client.Close()
+	defer server.Close()
+
+	if !client.Caps().Has(imap.CapObjectID) {
+		t.Skip("缺少 OBJECTID 支持")
+	}
+
+	var ids []string
+	for _, name := range []string{"Archive", "Drafts"} {
+		createCmd := client.Create(name, nil)
+		if err := createCmd.Wait(); err != nil {
+			t.Fatalf("Create(%q) = %v", name, err)
+		}
+		if createCmd.MailboxID() == "" {
+			t.Fatalf("Create(%q).MailboxID() 为空", name)
+		}
+		ids = append(ids, createCmd.MailboxID())
+	}
+	if ids[0] == ids[1] {
+		t.Errorf("不同邮箱的 MailboxID 相同: %v", ids[0])
+	}
+
+	// STATUS 返回与 CREATE 相同的邮箱 ID
+	data, err := client.Status("Archive", &imap.StatusOptions{MailboxID: true}).Wait()
+	if err != nil {
+		t.Fatalf("Status().Wait() = %v", err)
+	} else if data.MailboxID != ids[0] {
+		t.Errorf("Status().MailboxID = %q, want %q", data.MailboxID, ids[0])
+	}
+}
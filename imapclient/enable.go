@@ -7,21 +7,24 @@ import (
 )
 
 // Enable 发送 ENABLE 命令。
-// 该命令需要支持 IMAP4rev2 或 ENABLE 扩展。
+// 该命令需要支持 IMAP4rev2 或 ENABLE 扩展，否则返回错误。
+//
+// EnableCommand.Wait 返回服务器实际启用的能力，这些能力会影响客户端之后的行为，
+// 例如启用 UTF8=ACCEPT 后邮箱名称以 UTF-8 发送。
 // 参数：
 //
 //	caps - 要启用的能力列表。
 func (c *Client) Enable(caps ...imap.Cap) *EnableCommand {
+	if !c.Caps().Has(imap.CapEnable) {
+		return newEnableError(fmt.Errorf("imapclient: 服务器不支持 ENABLE"))
+	}
 	// 启用扩展可能会更改 IMAP 语法，因此只允许支持的扩展
 	for _, name := range caps {
 		switch name {
-		case imap.CapIMAP4rev2, imap.CapUTF8Accept, imap.CapMetadata, imap.CapMetadataServer:
+		case imap.CapIMAP4rev2, imap.CapUTF8Accept, imap.CapMetadata, imap.CapMetadataServer, imap.CapCondStore, imap.CapQResync:
 			// 支持的扩展，继续
 		default:
-			done := make(chan error)                                              // 创建完成信道
-			close(done)                                                           // 关闭信道
-			err := fmt.Errorf("imapclient: 无法启用 %q: 不支持", name)                   // 返回不支持错误
-			return &EnableCommand{commandBase: commandBase{done: done, err: err}} // 返回 ENABLE 命令实例
+			return newEnableError(fmt.Errorf("imapclient: 无法启用 %q: 不支持", name)) // 返回不支持错误
 		}
 	}
 
@@ -34,6 +37,33 @@ func (c *Client) Enable(caps ...imap.Cap) *EnableCommand {
 	return cmd // 返回 ENABLE 命令实例
 }
 
+// newEnableError 返回一个已经以 err 失败的 ENABLE 命令。
+func newEnableError(err error) *EnableCommand {
+	return &EnableCommand{commandBase: newFailedCommandBase(err)}
+}
+
+// ensureCondStore 确保在使用 MODSEQ 相关参数之前 CONDSTORE 已启用。
+//
+// 如果尚未启用且服务器支持 ENABLE，则先发送 ENABLE CONDSTORE。ENABLE 不会被等待：
+// 命令按顺序处理，它会在随后的命令之前生效。
+func (c *Client) ensureCondStore() error {
+	c.mutex.Lock()
+	enabled := c.enabled.Has(imap.CapCondStore) || c.enabled.Has(imap.CapQResync)
+	c.mutex.Unlock()
+	if enabled {
+		return nil
+	}
+
+	caps := c.Caps()
+	if !caps.Has(imap.CapCondStore) {
+		return fmt.Errorf("imapclient: 服务器不支持 CONDSTORE")
+	} else if !caps.Has(imap.CapEnable) {
+		return fmt.Errorf("imapclient: 使用 MODSEQ 之前必须先以 CONDSTORE 参数选择邮箱")
+	}
+	c.Enable(imap.CapCondStore)
+	return nil
+}
+
 // handleEnabled 处理 ENABLE 命令的响应。
 // 返回值：
 //
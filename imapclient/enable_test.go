@@ -0,0 +1,58 @@
+package imapclient_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/luhaoyun888/go-imap-cn"
+)
+
+// TestEnable 测试 ENABLE 返回服务器实际启用的能力，并影响之后的命令编码。
+func TestEnable(t *testing.T) {
+	cmds := make(chan string, 16)
+	client := newScriptedClient(t, " ENABLE CONDSTORE UTF8=ACCEPT", func(cmd string) []string {
+		cmds <- cmd
+		if strings.HasPrefix(cmd, "ENABLE ") {
+			return []string{"* ENABLED UTF8=ACCEPT"} // CONDSTORE 未被启用
+		}
+		return nil
+	})
+
+	data, err := client.Enable(imap.CapCondStore, imap.CapUTF8Accept).Wait()
+	if err != nil {
+		t.Fatalf("Enable().Wait() = %v", err)
+	}
+	if cmd := <-cmds; cmd != "ENABLE CONDSTORE UTF8=ACCEPT" {
+		t.Errorf("command = %q, want %q", cmd, "ENABLE CONDSTORE UTF8=ACCEPT")
+	}
+	if !data.Caps.Has(imap.CapUTF8Accept) || data.Caps.Has(imap.CapCondStore) {
+		t.Errorf("EnableData.Caps = %v, want only UTF8=ACCEPT", data.Caps)
+	}
+
+	// 启用 UTF8=ACCEPT 后，邮箱名称以 UTF-8 而不是修改过的 UTF-7 发送
+	if err := client.Create("草稿", nil).Wait(); err != nil {
+		t.Fatalf("Create().Wait() = %v", err)
+	}
+	if cmd, want := <-cmds, `CREATE "草稿"`; cmd != want {
+		t.Errorf("command = %q, want %q", cmd, want)
+	}
+}
+
+// TestEnable_unsupported 测试服务器不支持 ENABLE 时不发送命令。
+func TestEnable_unsupported(t *testing.T) {
+	cmds := make(chan string, 16)
+	client := newScriptedClient(t, "", func(cmd string) []string {
+		cmds <- cmd
+		return nil
+	})
+
+	if _, err := client.Enable(imap.CapCondStore).Wait(); err == nil {
+		t.Errorf("Enable().Wait() 应在服务器不支持 ENABLE 时失败")
+	}
+	if err := client.Noop().Wait(); err != nil {
+		t.Fatalf("Noop().Wait() = %v", err)
+	}
+	if cmd := <-cmds; cmd != "NOOP" {
+		t.Errorf("command = %q, want NOOP", cmd)
+	}
+}
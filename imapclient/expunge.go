@@ -1,13 +1,16 @@
 package imapclient
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/luhaoyun888/go-imap-cn"
 )
 
 // Expunge 发送 EXPUNGE 命令。
 func (c *Client) Expunge() *ExpungeCommand {
-	cmd := &ExpungeCommand{seqNums: make(chan uint32, 128)} // 创建一个 EXPUNGE 命令
-	c.beginCommand("EXPUNGE", cmd).end()                    // 开始命令
+	cmd := c.newExpungeCommand()         // 创建一个 EXPUNGE 命令
+	c.beginCommand("EXPUNGE", cmd).end() // 开始命令
 	return cmd
 }
 
@@ -15,13 +18,28 @@ func (c *Client) Expunge() *ExpungeCommand {
 //
 // 此命令要求支持 IMAP4rev2 或 UIDPLUS 扩展。
 func (c *Client) UIDExpunge(uids imap.UIDSet) *ExpungeCommand {
-	cmd := &ExpungeCommand{seqNums: make(chan uint32, 128)} // 创建一个 UID EXPUNGE 命令
-	enc := c.beginCommand("UID EXPUNGE", cmd)               // 开始命令
-	enc.SP().NumSet(uids)                                   // 设置 UID
-	enc.end()                                               // 结束命令
+	if !c.Caps().Has(imap.CapUIDPlus) {
+		seqNums := make(chan uint32)
+		close(seqNums)
+		err := fmt.Errorf("imapclient: 服务器不支持 UID EXPUNGE")
+		return &ExpungeCommand{commandBase: newFailedCommandBase(err), seqNums: seqNums}
+	}
+
+	cmd := c.newExpungeCommand()              // 创建一个 UID EXPUNGE 命令
+	enc := c.beginCommand("UID EXPUNGE", cmd) // 开始命令
+	enc.SP().NumSet(uids)                     // 设置 UID
+	enc.end()                                 // 结束命令
 	return cmd
 }
 
+// newExpungeCommand 创建一个 ExpungeCommand，并记录发送时是否已启用 QRESYNC。
+func (c *Client) newExpungeCommand() *ExpungeCommand {
+	c.mutex.Lock()
+	qresync := c.enabled.Has(imap.CapQResync)
+	c.mutex.Unlock()
+	return &ExpungeCommand{seqNums: make(chan uint32, 128), qresync: qresync}
+}
+
 // handleExpunge 处理 EXPUNGE 响应。
 func (c *Client) handleExpunge(seqNum uint32) error {
 	c.mutex.Lock() // 锁定以保护状态
@@ -31,6 +49,10 @@ func (c *Client) handleExpunge(seqNum uint32) error {
 	}
 	c.mutex.Unlock() // 解锁
 
+	if t := c.SeqNumTracker(); t != nil {
+		t.Expunge(seqNum)
+	}
+
 	cmd := findPendingCmdByType[*ExpungeCommand](c) // 查找待处理的命令
 	if cmd != nil {
 		cmd.seqNums <- seqNum // 将序列号发送到命令
@@ -41,13 +63,69 @@ func (c *Client) handleExpunge(seqNum uint32) error {
 	return nil
 }
 
+// handleVanished 处理 QRESYNC 的 VANISHED 响应。
+//
+// SELECT 期间的 VANISHED (EARLIER) 响应保存在 SelectData.Vanished 中，
+// EXPUNGE 期间的 VANISHED 响应由 ExpungeCommand 收集，其余的响应交给
+// UnilateralDataHandler.Vanished 处理。
+func (c *Client) handleVanished() error {
+	earlier := false
+	if c.dec.Special('(') {
+		var tag string
+		if !c.dec.ExpectAtom(&tag) || !c.dec.ExpectSpecial(')') || !c.dec.ExpectSP() {
+			return c.dec.Err()
+		}
+		earlier = strings.EqualFold(tag, "EARLIER")
+	}
+
+	var uids imap.UIDSet
+	if !c.dec.ExpectUIDSet(&uids) {
+		return c.dec.Err()
+	}
+
+	// 不带 EARLIER 的 VANISHED 表示邮件刚刚被删除，需要更新邮件数量
+	if !earlier {
+		nums, _ := uids.Nums()
+		c.mutex.Lock()
+		if c.state == imap.ConnStateSelected {
+			c.mailbox = c.mailbox.copy()
+			if n := uint32(len(nums)); n < c.mailbox.NumMessages {
+				c.mailbox.NumMessages -= n
+			} else {
+				c.mailbox.NumMessages = 0
+			}
+		}
+		c.mutex.Unlock()
+
+		if t := c.SeqNumTracker(); t != nil {
+			t.Vanished(uids)
+		}
+	}
+
+	if earlier {
+		if cmd := findPendingCmdByType[*SelectCommand](c); cmd != nil {
+			cmd.data.Vanished.AddSet(uids)
+			return nil
+		}
+	} else if cmd := findPendingCmdByType[*ExpungeCommand](c); cmd != nil {
+		cmd.vanished.AddSet(uids)
+		return nil
+	}
+	if handler := c.options.unilateralDataHandler().Vanished; handler != nil {
+		handler(uids, earlier)
+	}
+	return nil
+}
+
 // ExpungeCommand 是一个 EXPUNGE 命令。
 //
 // 调用者必须完全消耗 ExpungeCommand。一个简单的方法是
 // 延迟调用 FetchCommand.Close。
 type ExpungeCommand struct {
 	commandBase
-	seqNums chan uint32 // 存储序列号的通道
+	seqNums  chan uint32 // 存储序列号的通道
+	vanished imap.UIDSet // VANISHED 响应中的 UID
+	qresync  bool        // 发送命令时是否已启用 QRESYNC
 }
 
 // Next 前进到下一个被删除的邮件序列号。
@@ -83,3 +161,27 @@ func (cmd *ExpungeCommand) Collect() ([]uint32, error) {
 	}
 	return l, cmd.Close() // 返回列表和关闭命令
 }
+
+// Vanished 返回服务器通过 VANISHED 响应报告的已删除邮件的 UID。
+//
+// 启用 QRESYNC 后，服务器使用 VANISHED 响应代替 EXPUNGE 响应，此时 Next
+// 不会返回任何序列号。必须在 Close 或 Collect 之后调用。
+func (cmd *ExpungeCommand) Vanished() imap.UIDSet {
+	return cmd.vanished
+}
+
+// CollectUIDs 将服务器通过 VANISHED 响应报告的已删除邮件 UID 累积到列表中。
+//
+// 这等效于调用 Close 然后展开 Vanished。只有启用 QRESYNC 后服务器才会返回 VANISHED 响应，
+// 否则删除通过不含 UID 的 EXPUNGE 响应报告：发送命令时尚未启用 QRESYNC 时，
+// CollectUIDs 仍会等待命令完成，然后返回错误，调用者应改用 Collect 获取序列号。
+func (cmd *ExpungeCommand) CollectUIDs() ([]imap.UID, error) {
+	if err := cmd.Close(); err != nil {
+		return nil, err
+	}
+	if !cmd.qresync {
+		return nil, fmt.Errorf("imapclient: 未启用 QRESYNC，服务器不会返回 VANISHED 响应")
+	}
+	uids, _ := cmd.vanished.Nums() // VANISHED 中不会包含 "*"
+	return uids, nil
+}
@@ -1,9 +1,15 @@
 package imapclient_test
 
 import (
+	"fmt"
+	"net"
+	"reflect"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/luhaoyun888/go-imap-cn"
+	"github.com/luhaoyun888/go-imap-cn/imapclient"
 )
 
 func TestExpunge(t *testing.T) {
@@ -36,3 +42,271 @@ func TestExpunge(t *testing.T) {
 		t.Errorf("Expunge().Collect() = %v, want [1]", seqNums) // 期望返回 [1]
 	}
 }
+
+func TestExpunge_collectUIDsWithoutQResync(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateSelected)
+	defer client.Close()
+	defer server.Close()
+
+	storeFlags := imap.StoreFlags{
+		Op:    imap.StoreFlagsAdd,
+		Flags: []imap.Flag{imap.FlagDeleted},
+	}
+	if err := client.Store(imap.SeqSetNum(1), &storeFlags, nil).Close(); err != nil {
+		t.Fatalf("Store() = %v", err)
+	}
+
+	// 未启用 QRESYNC 时 CollectUIDs 返回错误，但命令仍然执行完毕
+	if uids, err := client.Expunge().CollectUIDs(); err == nil {
+		t.Errorf("Expunge().CollectUIDs() = %v, want error", uids)
+	}
+	if err := client.Noop().Wait(); err != nil {
+		t.Fatalf("Noop() = %v", err)
+	}
+	if n := client.Mailbox().NumMessages; n != 0 {
+		t.Errorf("Mailbox().NumMessages = %v, want 0", n)
+	}
+}
+
+func TestUIDExpunge(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateSelected)
+	defer client.Close()
+	defer server.Close()
+
+	msgs, err := client.Fetch(imap.SeqSetNum(1), &imap.FetchOptions{UID: true}).Collect()
+	if err != nil {
+		t.Fatalf("Fetch() = %v", err)
+	} else if len(msgs) != 1 {
+		t.Fatalf("len(msgs) = %v, want %v", len(msgs), 1)
+	}
+	uid := msgs[0].UID
+
+	storeFlags := imap.StoreFlags{
+		Op:    imap.StoreFlagsAdd,
+		Flags: []imap.Flag{imap.FlagDeleted},
+	}
+	if err := client.Store(imap.SeqSetNum(1), &storeFlags, nil).Close(); err != nil {
+		t.Fatalf("Store() = %v", err)
+	}
+
+	// 集合中不包含被标记为删除的邮件，不应删除任何邮件
+	seqNums, err := client.UIDExpunge(imap.UIDSetNum(uid + 1)).Collect()
+	if err != nil {
+		t.Fatalf("UIDExpunge() = %v", err)
+	} else if len(seqNums) != 0 {
+		t.Errorf("UIDExpunge().Collect() = %v, want []", seqNums)
+	}
+
+	seqNums, err = client.UIDExpunge(imap.UIDSetNum(uid)).Collect()
+	if err != nil {
+		t.Fatalf("UIDExpunge() = %v", err)
+	} else if len(seqNums) != 1 || seqNums[0] != 1 {
+		t.Errorf("UIDExpunge().Collect() = %v, want [1]", seqNums)
+	}
+}
+
+func TestExpunge_vanished(t *testing.T) {
+	conn, server := newMemClientServerPair(t)
+	defer server.Close()
+	otherConn, err := net.Dial("tcp", conn.RemoteAddr().String())
+	if err != nil {
+		t.Fatalf("net.Dial() = %v", err)
+	}
+
+	type vanished struct {
+		uids    imap.UIDSet
+		earlier bool
+	}
+	vanishedCh := make(chan vanished, 1)
+	client := imapclient.New(conn, nil)
+	defer client.Close()
+	other := imapclient.New(otherConn, &imapclient.Options{
+		UnilateralDataHandler: &imapclient.UnilateralDataHandler{
+			Vanished: func(uids imap.UIDSet, earlier bool) {
+				vanishedCh <- vanished{uids, earlier}
+			},
+		},
+	})
+	defer other.Close()
+
+	for _, c := range []*imapclient.Client{client, other} {
+		if err := c.Login(testUsername, testPassword).Wait(); err != nil {
+			t.Fatalf("Login().Wait() = %v", err)
+		}
+		if _, err := c.Enable(imap.CapQResync).Wait(); err != nil {
+			t.Fatalf("Enable(QRESYNC).Wait() = %v", err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		appendCmd := client.Append("INBOX", int64(len(simpleRawMessage)), nil)
+		appendCmd.Write([]byte(simpleRawMessage))
+		appendCmd.Close()
+		if _, err := appendCmd.Wait(); err != nil {
+			t.Fatalf("Append().Wait() = %v", err)
+		}
+	}
+	for _, c := range []*imapclient.Client{client, other} {
+		if _, err := c.Select("INBOX", nil).Wait(); err != nil {
+			t.Fatalf("Select().Wait() = %v", err)
+		}
+	}
+
+	storeFlags := imap.StoreFlags{Op: imap.StoreFlagsAdd, Silent: true, Flags: []imap.Flag{imap.FlagDeleted}}
+	if err := client.Store(imap.UIDSetNum(1), &storeFlags, nil).Close(); err != nil {
+		t.Fatalf("Store().Close() = %v", err)
+	}
+
+	// 启用 QRESYNC 后服务器返回 VANISHED 而不是 EXPUNGE
+	expungeCmd := client.Expunge()
+	seqNums, err := expungeCmd.Collect()
+	if err != nil {
+		t.Fatalf("Expunge().Collect() = %v", err)
+	} else if len(seqNums) != 0 {
+		t.Errorf("Expunge().Collect() = %v, want []", seqNums)
+	}
+	if got, want := expungeCmd.Vanished().String(), "1"; got != want {
+		t.Errorf("ExpungeCommand.Vanished() = %v, want %v", got, want)
+	}
+	if n := client.Mailbox().NumMessages; n != 1 {
+		t.Errorf("Mailbox().NumMessages = %v, want 1", n)
+	}
+
+	// 其他连接通过 UnilateralDataHandler.Vanished 收到通知
+	if err := other.Noop().Wait(); err != nil {
+		t.Fatalf("Noop().Wait() = %v", err)
+	}
+	select {
+	case v := <-vanishedCh:
+		if v.uids.String() != "1" || v.earlier {
+			t.Errorf("Vanished(%v, %v), want Vanished(1, false)", v.uids, v.earlier)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("未收到 VANISHED 响应")
+	}
+
+	// CollectUIDs 将 VANISHED 响应统一收集为 UID 列表
+	if err := client.Store(imap.UIDSetNum(2), &storeFlags, nil).Close(); err != nil {
+		t.Fatalf("Store().Close() = %v", err)
+	}
+	uids, err := client.Expunge().CollectUIDs()
+	if err != nil {
+		t.Fatalf("Expunge().CollectUIDs() = %v", err)
+	} else if len(uids) != 1 || uids[0] != 2 {
+		t.Errorf("Expunge().CollectUIDs() = %v, want [2]", uids)
+	}
+}
+
+// TestExpunge_nonContiguous 测试删除多封不连续的邮件后，依次应用 EXPUNGE 响应中的序号
+// 能得到与服务器一致的邮件视图。
+func TestExpunge_nonContiguous(t *testing.T) {
+	conn, server := newMemClientServerPair(t)
+	defer server.Close()
+	otherConn, err := net.Dial("tcp", conn.RemoteAddr().String())
+	if err != nil {
+		t.Fatalf("net.Dial() = %v", err)
+	}
+
+	var (
+		mutex         sync.Mutex
+		otherExpunged []uint32
+	)
+	client := imapclient.New(conn, nil)
+	defer client.Close()
+	other := imapclient.New(otherConn, &imapclient.Options{
+		UnilateralDataHandler: &imapclient.UnilateralDataHandler{
+			Expunge: func(seqNum uint32) {
+				mutex.Lock()
+				otherExpunged = append(otherExpunged, seqNum)
+				mutex.Unlock()
+			},
+		},
+	})
+	defer other.Close()
+
+	if err := client.Login(testUsername, testPassword).Wait(); err != nil {
+		t.Fatalf("Login().Wait() = %v", err)
+	}
+	if err := other.Login(testUsername, testPassword).Wait(); err != nil {
+		t.Fatalf("Login().Wait() = %v", err)
+	}
+	for i := 0; i < 6; i++ {
+		appendCmd := client.Append("INBOX", int64(len(simpleRawMessage)), nil)
+		appendCmd.Write([]byte(simpleRawMessage))
+		appendCmd.Close()
+		if _, err := appendCmd.Wait(); err != nil {
+			t.Fatalf("Append().Wait() = %v", err)
+		}
+	}
+	for _, c := range []*imapclient.Client{client, other} {
+		if _, err := c.Select("INBOX", nil).Wait(); err != nil {
+			t.Fatalf("Select().Wait() = %v", err)
+		}
+	}
+
+	view, err := fetchUIDs(client)
+	if err != nil {
+		t.Fatalf("Fetch() = %v", err)
+	}
+
+	// 删除第 2、4、5 封邮件
+	storeFlags := imap.StoreFlags{Op: imap.StoreFlagsAdd, Silent: true, Flags: []imap.Flag{imap.FlagDeleted}}
+	var seqSet imap.SeqSet
+	seqSet.AddNum(2, 4, 5)
+	if err := client.Store(seqSet, &storeFlags, nil).Close(); err != nil {
+		t.Fatalf("Store().Close() = %v", err)
+	}
+	seqNums, err := client.Expunge().Collect()
+	if err != nil {
+		t.Fatalf("Expunge().Collect() = %v", err)
+	}
+	if err := other.Noop().Wait(); err != nil {
+		t.Fatalf("Noop().Wait() = %v", err)
+	}
+
+	want := []imap.UID{view[0], view[2], view[5]}
+	check := func(name string, seqNums []uint32) {
+		got := append([]imap.UID(nil), view...)
+		for _, seqNum := range seqNums {
+			if seqNum == 0 || int(seqNum) > len(got) {
+				t.Fatalf("%v: EXPUNGE 序号 %v 超出范围 1:%v", name, seqNum, len(got))
+			}
+			got = append(got[:seqNum-1], got[seqNum:]...)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%v: 应用 EXPUNGE %v 后的视图 = %v, want %v", name, seqNums, got, want)
+		}
+	}
+	check("Expunge().Collect()", seqNums)
+	mutex.Lock()
+	check("UnilateralDataHandler.Expunge", otherExpunged)
+	mutex.Unlock()
+
+	for _, c := range []*imapclient.Client{client, other} {
+		uids, err := fetchUIDs(c)
+		if err != nil {
+			t.Fatalf("Fetch() = %v", err)
+		}
+		if !reflect.DeepEqual(uids, want) {
+			t.Errorf("FETCH 1:* UID = %v, want %v", uids, want)
+		}
+		if n := c.Mailbox().NumMessages; n != uint32(len(want)) {
+			t.Errorf("Mailbox().NumMessages = %v, want %v", n, len(want))
+		}
+	}
+}
+
+// fetchUIDs 按序号顺序返回已选邮箱中所有邮件的 UID。
+func fetchUIDs(client *imapclient.Client) ([]imap.UID, error) {
+	msgs, err := client.Fetch(imap.SeqSet{{Start: 1, Stop: 0}}, &imap.FetchOptions{UID: true}).Collect()
+	if err != nil {
+		return nil, err
+	}
+	uids := make([]imap.UID, len(msgs))
+	for i, msg := range msgs {
+		if msg.SeqNum != uint32(i)+1 {
+			return nil, fmt.Errorf("第 %v 个 FETCH 响应的序号为 %v", i+1, msg.SeqNum)
+		}
+		uids[i] = msg.UID
+	}
+	return uids, nil
+}
@@ -1,9 +1,13 @@
 package imapclient
 
 import (
+	"encoding/base64"
 	"fmt"
 	"io"
+	"mime"
 	netmail "net/mail"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -24,9 +28,21 @@ func (c *Client) Fetch(numSet imap.NumSet, options *imap.FetchOptions) *FetchCom
 	if options == nil {
 		options = new(imap.FetchOptions)
 	}
+	if (options.GmailLabels || options.GmailMsgID || options.GmailThrID) && !c.Caps().Has(imap.CapGmailExt1) {
+		return newFetchErrorCommand(fmt.Errorf("imapclient: 服务器不支持 X-GM-EXT-1"))
+	}
+	if (options.EmailID || options.ThreadID) && !c.Caps().Has(imap.CapObjectID) {
+		return newFetchErrorCommand(fmt.Errorf("imapclient: 服务器不支持 OBJECTID"))
+	}
+	if options.ModSeq || options.ChangedSince != 0 {
+		if err := c.ensureCondStore(); err != nil {
+			return newFetchErrorCommand(err)
+		}
+	}
 
 	// 获取数字集合类型
 	numKind := imapwire.NumSetKind(numSet)
+	c.checkPipeline(uidCmdName("FETCH", numKind), numSet, false)
 
 	// 初始化 FetchCommand 并创建消息通道
 	cmd := &FetchCommand{
@@ -43,13 +59,43 @@ func (c *Client) Fetch(numSet imap.NumSet, options *imap.FetchOptions) *FetchCom
 	writeFetchItems(enc.Encoder, numKind, options)
 	// 如果有 CHANGEDSINCE 选项，添加到命令中
 	if options.ChangedSince != 0 {
-		enc.SP().Special('(').Atom("已更改自").SP().ModSeq(options.ChangedSince).Special(')')
+		enc.SP().Special('(').Atom("CHANGEDSINCE").SP().ModSeq(options.ChangedSince).Special(')')
 	}
 	// 结束命令编码
 	enc.end()
 	return cmd
 }
 
+// FetchEnvelopes 使用单个 FETCH 命令获取多封邮件的信封。
+//
+// 返回的 map 以消息序号为键；如果 numSet 是 imap.UIDSet，则以 UID 为键。
+func (c *Client) FetchEnvelopes(numSet imap.NumSet) (map[uint32]*imap.Envelope, error) {
+	_, isUID := numSet.(imap.UIDSet)
+	cmd := c.Fetch(numSet, &imap.FetchOptions{Envelope: true, UID: isUID})
+	defer cmd.Close()
+
+	envelopes := make(map[uint32]*imap.Envelope)
+	for {
+		msg := cmd.Next()
+		if msg == nil {
+			break
+		}
+		buf, err := msg.Collect()
+		if err != nil {
+			return nil, err
+		}
+		key := buf.SeqNum
+		if isUID {
+			key = uint32(buf.UID)
+		}
+		envelopes[key] = buf.Envelope
+	}
+	if err := cmd.Close(); err != nil {
+		return nil, err
+	}
+	return envelopes, nil
+}
+
 // writeFetchItems 写入 FETCH 命令中的各项请求
 // 参数说明：
 // enc 是一个命令的编码器
@@ -60,18 +106,23 @@ func writeFetchItems(enc *imapwire.Encoder, numKind imapwire.NumKind, options *i
 
 	// 如果请求 UID FETCH，则确保第一个项目请求 UID
 	if options.UID || numKind == imapwire.NumKindUID {
-		listEnc.Item().Atom("唯一标识符")
+		listEnc.Item().Atom("UID")
 	}
 
 	// 根据请求选项，将对应的项目加入到FETCH命令中
 	m := map[string]bool{
-		"正文":        options.BodyStructure != nil && !options.BodyStructure.Extended,
-		"完整结构":      options.BodyStructure != nil && options.BodyStructure.Extended,
-		"信封":        options.Envelope,
-		"标志":        options.Flags,
-		"内部日期":      options.InternalDate,
-		"RFC822.大小": options.RFC822Size,
-		"修改序列号":     options.ModSeq,
+		"BODY":          options.BodyStructure != nil && (!options.BodyStructure.Extended || options.BodyStructure.Both),
+		"BODYSTRUCTURE": options.BodyStructure != nil && (options.BodyStructure.Extended || options.BodyStructure.Both),
+		"ENVELOPE":      options.Envelope,
+		"FLAGS":         options.Flags,
+		"INTERNALDATE":  options.InternalDate,
+		"RFC822.SIZE":   options.RFC822Size,
+		"MODSEQ":        options.ModSeq,
+		"X-GM-LABELS":   options.GmailLabels,
+		"X-GM-MSGID":    options.GmailMsgID,
+		"X-GM-THRID":    options.GmailThrID,
+		"EMAILID":       options.EmailID,
+		"THREADID":      options.ThreadID,
 	}
 	for k, req := range m {
 		if req {
@@ -134,9 +185,9 @@ func writeFetchItemBodySection(enc *imapwire.Encoder, item *imap.FetchItemBodySe
 // enc 是命令的编码器
 // item 是请求的二进制部分
 func writeFetchItemBinarySection(enc *imapwire.Encoder, item *imap.FetchItemBinarySection) {
-	enc.Atom("二进制")
+	enc.Atom("BINARY")
 	if item.Peek {
-		enc.Atom(".窥视")
+		enc.Atom(".PEEK")
 	}
 	enc.Special('[')
 	writeSectionPart(enc, item.Part)
@@ -149,7 +200,7 @@ func writeFetchItemBinarySection(enc *imapwire.Encoder, item *imap.FetchItemBina
 // enc 是命令的编码器
 // item 是请求的二进制大小部分
 func writeFetchItemBinarySectionSize(enc *imapwire.Encoder, item *imap.FetchItemBinarySectionSize) {
-	enc.Atom("二进制.大小")
+	enc.Atom("BINARY.SIZE")
 	enc.Special('[')
 	writeSectionPart(enc, item.Part)
 	enc.Special(']')
@@ -197,6 +248,23 @@ type FetchCommand struct {
 	msgs chan *FetchMessageData
 	// prev 保存上一个 FETCH 消息数据。
 	prev *FetchMessageData
+	// onMessage 是每处理一条消息时调用的进度回调。
+	onMessage func(processed int)
+	// processed 是已处理的消息数量。
+	processed int
+	// onBodySection 是 Collect 读取 BODY[] 字面量时调用的回调。
+	onBodySection func(section *imap.FetchItemBodySection, r io.Reader) error
+	// modified 保存 STORE 响应中 MODIFIED 响应码返回的消息集合。
+	modified imap.NumSet
+	// store 表示该命令是否为 STORE 命令。
+	store bool
+}
+
+// newFetchErrorCommand 返回一个立即以 err 失败的 FetchCommand。
+func newFetchErrorCommand(err error) *FetchCommand {
+	msgs := make(chan *FetchMessageData)
+	close(msgs)
+	return &FetchCommand{commandBase: newFailedCommandBase(err), msgs: msgs}
 }
 
 // recvSeqNum 接收顺序号。
@@ -224,8 +292,9 @@ func (cmd *FetchCommand) recvSeqNum(seqNum uint32) bool {
 // 返回值表示是否成功接收。
 func (cmd *FetchCommand) recvUID(uid imap.UID) bool {
 	// 检查 numSet 是否为 UID 集合并且包含 uid。
+	// $ 引用服务器保存的搜索结果，客户端无法得知其中的 UID，因此接受所有 UID。
 	set, ok := cmd.numSet.(imap.UIDSet)
-	if !ok || !set.Contains(uid) {
+	if !ok || (!imap.IsSearchRes(set) && !set.Contains(uid)) {
 		return false
 	}
 
@@ -248,9 +317,35 @@ func (cmd *FetchCommand) Next() *FetchMessageData {
 	}
 	// 读取下一条消息。
 	cmd.prev = <-cmd.msgs
+	if cmd.prev != nil {
+		cmd.prev.onBodySection = cmd.onBodySection
+	}
+	if cmd.prev != nil && cmd.onMessage != nil {
+		cmd.processed++
+		cmd.onMessage(cmd.processed)
+	}
 	return cmd.prev
 }
 
+// OnMessage 注册进度回调，每当 Next 返回一条消息时以已处理的消息数量调用。
+//
+// 回调在调用 Next（或 Collect）的 goroutine 中执行。结合 numSet 中已知的消息数量，
+// 可用于显示批量 FETCH 的进度。必须在第一次调用 Next 之前注册。
+func (cmd *FetchCommand) OnMessage(f func(processed int)) {
+	cmd.onMessage = f
+}
+
+// OnBodySection 注册 BODY[] 字面量的流式处理回调。
+//
+// Collect 解析到 BODY[] 字面量时，直接把读取器交给 f，而不是把内容读入内存，
+// 例如可以把大附件写入文件。f 返回后客户端继续解码，未读取的字节会被丢弃。
+// 交给 f 处理的部分不会保存在 FetchMessageBuffer.BodySection 中。f 返回的错误
+// 由 Collect 返回。section 是从响应中解析的部分，应按值（Specifier、Part 等）
+// 与请求的部分比较。必须在第一次调用 Next 之前注册。
+func (cmd *FetchCommand) OnBodySection(f func(section *imap.FetchItemBodySection, r io.Reader) error) {
+	cmd.onBodySection = f
+}
+
 // Close 关闭命令。
 // 调用 Close 会解除阻塞的 IMAP 客户端解码器，并让它读取下一条响应。
 // 在 Close 之后，Next 将始终返回 nil。
@@ -261,6 +356,14 @@ func (cmd *FetchCommand) Close() error {
 	return cmd.wait()
 }
 
+// Modified 返回因 UNCHANGEDSINCE 条件未满足而未被修改的消息集合。
+//
+// 该集合来自 STORE 命令的 MODIFIED 响应码，若所有消息都已更新则返回 nil。
+// 必须在 Close 或 Collect 返回之后调用。
+func (cmd *FetchCommand) Modified() imap.NumSet {
+	return cmd.modified
+}
+
 // Collect 收集消息数据到列表中。
 // 此方法将读取并将消息内容存储在内存中。对于合理大小的消息内容，这是可接受的，但对于如附件等大文件，可能不合适。
 // 该方法等效于反复调用 Next 然后 Close。
@@ -295,6 +398,8 @@ type FetchMessageData struct {
 	items chan FetchItemData
 	// prev 保存上一个 FETCH 项数据。
 	prev FetchItemData
+	// onBodySection 是 FetchCommand.OnBodySection 注册的回调。
+	onBodySection func(section *imap.FetchItemBodySection, r io.Reader) error
 }
 
 // Next 读取下一条数据项。
@@ -332,6 +437,13 @@ func (data *FetchMessageData) Collect() (*FetchMessageBuffer, error) {
 		if item == nil {
 			break
 		}
+		// 已注册回调时，BODY[] 字面量直接交给回调处理
+		if section, ok := item.(FetchItemDataBodySection); ok && section.Literal != nil && data.onBodySection != nil {
+			if err := data.onBodySection(section.Section, section.Literal); err != nil {
+				return buf, err
+			}
+			continue
+		}
 		// 填充数据项到缓冲区。
 		if err := buf.populateItemData(item); err != nil {
 			return buf, err
@@ -372,7 +484,7 @@ var (
 type FetchItemDataBodySection struct {
 	// Section 表示 FETCH 项的 BODY 部分。
 	Section *imap.FetchItemBodySection
-	// Literal 表示数据内容的读取器。
+	// Literal 表示数据内容的读取器。服务器返回 NIL（例如请求的部分不存在）时为 nil。
 	Literal imap.LiteralReader
 }
 
@@ -472,6 +584,51 @@ type FetchItemDataModSeq struct {
 
 func (FetchItemDataModSeq) fetchItemData() {}
 
+// FetchItemDataGmailLabels 保存 FETCH X-GM-LABELS 返回的数据。
+// 需要 X-GM-EXT-1 扩展。
+type FetchItemDataGmailLabels struct {
+	// Labels 是消息的 Gmail 标签，系统标签以反斜杠开头，例如 \Inbox。
+	Labels []string
+}
+
+func (FetchItemDataGmailLabels) fetchItemData() {}
+
+// FetchItemDataGmailMsgID 保存 FETCH X-GM-MSGID 返回的数据。
+// 需要 X-GM-EXT-1 扩展。
+type FetchItemDataGmailMsgID struct {
+	// MsgID 是 Gmail 中消息的唯一标识。
+	MsgID uint64
+}
+
+func (FetchItemDataGmailMsgID) fetchItemData() {}
+
+// FetchItemDataGmailThrID 保存 FETCH X-GM-THRID 返回的数据。
+// 需要 X-GM-EXT-1 扩展。
+type FetchItemDataGmailThrID struct {
+	// ThrID 是 Gmail 中消息所属会话的标识。
+	ThrID uint64
+}
+
+func (FetchItemDataGmailThrID) fetchItemData() {}
+
+// FetchItemDataEmailID 保存 FETCH EMAILID 返回的数据。
+// 需要 OBJECTID 扩展。
+type FetchItemDataEmailID struct {
+	// EmailID 是邮件的对象 ID。
+	EmailID string
+}
+
+func (FetchItemDataEmailID) fetchItemData() {}
+
+// FetchItemDataThreadID 保存 FETCH THREADID 返回的数据。
+// 需要 OBJECTID 扩展。
+type FetchItemDataThreadID struct {
+	// ThreadID 是邮件所属会话的对象 ID，服务器不支持会话时为空。
+	ThreadID string
+}
+
+func (FetchItemDataThreadID) fetchItemData() {}
+
 // FetchMessageBuffer 是一个用于存储 FetchMessageData 返回数据的缓冲区结构体。
 //
 // SeqNum 字段始终会被填充。其他字段都是可选的。
@@ -483,10 +640,147 @@ type FetchMessageBuffer struct {
 	RFC822Size        int64                                   // 邮件大小
 	UID               imap.UID                                // 邮件唯一标识
 	BodyStructure     imap.BodyStructure                      // 邮件正文结构
-	BodySection       map[*imap.FetchItemBodySection][]byte   // 正文部分
+	BodySection       map[*imap.FetchItemBodySection][]byte   // 正文部分，值为 nil 表示服务器返回了 NIL
 	BinarySection     map[*imap.FetchItemBinarySection][]byte // 二进制部分
 	BinarySectionSize []FetchItemDataBinarySectionSize        // 二进制部分大小
 	ModSeq            uint64                                  // 修改序列号 (需要 CONDSTORE 支持)
+	GmailLabels       []string                                // Gmail 标签 (需要 X-GM-EXT-1 支持)
+	GmailMsgID        uint64                                  // Gmail 消息 ID (需要 X-GM-EXT-1 支持)
+	GmailThrID        uint64                                  // Gmail 会话 ID (需要 X-GM-EXT-1 支持)
+	EmailID           string                                  // 邮件对象 ID (需要 OBJECTID 支持)
+	ThreadID          string                                  // 会话对象 ID (需要 OBJECTID 支持)
+}
+
+// SinglePart 返回单部分的邮件正文结构。
+//
+// 如果未获取正文结构，或者正文结构不是单部分，则 ok 为 false。
+func (buf *FetchMessageBuffer) SinglePart() (part *imap.BodyStructureSinglePart, ok bool) {
+	part, ok = buf.BodyStructure.(*imap.BodyStructureSinglePart)
+	return part, ok
+}
+
+// MultiPart 返回多部分的邮件正文结构。
+//
+// 如果未获取正文结构，或者正文结构不是多部分，则 ok 为 false。
+func (buf *FetchMessageBuffer) MultiPart() (part *imap.BodyStructureMultiPart, ok bool) {
+	part, ok = buf.BodyStructure.(*imap.BodyStructureMultiPart)
+	return part, ok
+}
+
+// Attachment 描述邮件正文结构中的一个附件。
+type Attachment struct {
+	Path []int                         // IMAP 部分路径，可用于 BODY[] 和 BINARY[]
+	Part *imap.BodyStructureSinglePart // 附件的正文结构
+}
+
+// Attachments 返回邮件正文结构中的附件，按 DFS 前序排列。
+//
+// 处置方式为 "attachment" 的部分，以及带有文件名且处置方式不是 "inline"
+// 的部分都被视为附件。获取扩展正文结构（BODYSTRUCTURE）才能得到处置方式。
+func (buf *FetchMessageBuffer) Attachments() []Attachment {
+	if buf.BodyStructure == nil {
+		return nil
+	}
+
+	var l []Attachment
+	buf.BodyStructure.Walk(func(path []int, part imap.BodyStructure) bool {
+		singlePart, ok := part.(*imap.BodyStructureSinglePart)
+		if !ok {
+			return true // 遍历子部分
+		}
+
+		isAttachment := false
+		if disp := singlePart.Disposition(); disp != nil && strings.EqualFold(disp.Value, "attachment") {
+			isAttachment = true
+		} else if disp == nil || !strings.EqualFold(disp.Value, "inline") {
+			isAttachment = singlePart.Filename() != ""
+		}
+		if isAttachment {
+			l = append(l, Attachment{Path: path, Part: singlePart})
+		}
+		return true
+	})
+	return l
+}
+
+// FindBodySection 返回已获取的 BODY[] 部分的内容。
+//
+// BodySection 以指针为键，无法直接用请求的部分查找，因此这里按 specifier 和
+// part 比较每个键。带有头部字段列表或部分范围的部分不会被匹配。如果没有找到，
+// 返回 nil。
+func (buf *FetchMessageBuffer) FindBodySection(specifier imap.PartSpecifier, part []int) []byte {
+	for section, b := range buf.BodySection {
+		if section.Specifier != specifier || !intSliceEqual(section.Part, part) {
+			continue
+		}
+		if len(section.HeaderFields) > 0 || len(section.HeaderFieldsNot) > 0 || section.Partial != nil {
+			continue
+		}
+		return b
+	}
+	return nil
+}
+
+// PlainText 返回第一个 text/plain 正文部分的内容。
+//
+// 需要同时获取正文结构和对应部分的 BODY[]。返回的内容未经
+// Content-Transfer-Encoding 解码。如果没有找到，返回 nil。
+func (buf *FetchMessageBuffer) PlainText() []byte {
+	return buf.findText("text/plain")
+}
+
+// HTML 返回第一个 text/html 正文部分的内容。
+//
+// 需要同时获取正文结构和对应部分的 BODY[]。返回的内容未经
+// Content-Transfer-Encoding 解码。如果没有找到，返回 nil。
+func (buf *FetchMessageBuffer) HTML() []byte {
+	return buf.findText("text/html")
+}
+
+// findText 返回第一个媒体类型为 mediaType 且不是附件的部分的内容。
+func (buf *FetchMessageBuffer) findText(mediaType string) []byte {
+	if buf.BodyStructure == nil {
+		return nil
+	}
+
+	var b []byte
+	buf.BodyStructure.Walk(func(path []int, part imap.BodyStructure) bool {
+		if b != nil {
+			return false // 已经找到
+		}
+		singlePart, ok := part.(*imap.BodyStructureSinglePart)
+		if !ok {
+			return true // 遍历子部分
+		}
+		if singlePart.MediaType() != mediaType {
+			return true
+		}
+		if disp := singlePart.Disposition(); disp != nil && strings.EqualFold(disp.Value, "attachment") {
+			return true
+		}
+		b = buf.FindBodySection(imap.PartSpecifierNone, path)
+		if b == nil && len(path) == 1 && path[0] == 1 {
+			// 单部分邮件的正文也可以通过 BODY[TEXT] 获取
+			if _, ok := buf.BodyStructure.(*imap.BodyStructureSinglePart); ok {
+				b = buf.FindBodySection(imap.PartSpecifierText, nil)
+			}
+		}
+		return true
+	})
+	return b
+}
+
+// intSliceEqual 判断两个整数切片是否相等。
+func intSliceEqual(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
 }
 
 // populateItemData 根据提供的 FetchItemData 数据填充对应的字段。
@@ -532,11 +826,24 @@ func (buf *FetchMessageBuffer) populateItemData(item FetchItemData) error {
 	case FetchItemDataUID:
 		buf.UID = item.UID
 	case FetchItemDataBodyStructure:
-		buf.BodyStructure = item.BodyStructure
+		// 同时收到 BODY 和 BODYSTRUCTURE 时保留信息更完整的扩展结构
+		if item.IsExtended || buf.BodyStructure == nil {
+			buf.BodyStructure = item.BodyStructure
+		}
 	case FetchItemDataBinarySectionSize:
 		buf.BinarySectionSize = append(buf.BinarySectionSize, item)
 	case FetchItemDataModSeq:
 		buf.ModSeq = item.ModSeq
+	case FetchItemDataGmailLabels:
+		buf.GmailLabels = item.Labels
+	case FetchItemDataGmailMsgID:
+		buf.GmailMsgID = item.MsgID
+	case FetchItemDataGmailThrID:
+		buf.GmailThrID = item.ThrID
+	case FetchItemDataEmailID:
+		buf.EmailID = item.EmailID
+	case FetchItemDataThreadID:
+		buf.ThreadID = item.ThreadID
 	default:
 		panic(fmt.Errorf("不支持的提取项数据 %T", item))
 	}
@@ -658,11 +965,46 @@ func (c *Client) handleFetch(seqNum uint32) error {
 			}
 			item = FetchItemDataRFC822Size{Size: size}
 
+		// 处理旧的 RFC822 属性，它们分别等价于 BODY[]、BODY.PEEK[HEADER] 和 BODY[TEXT]
+		case "RFC822", "RFC822.HEADER", "RFC822.TEXT":
+			section := &imap.FetchItemBodySection{}
+			switch attName {
+			case "RFC822.HEADER":
+				section.Specifier = imap.PartSpecifierHeader
+				section.Peek = true
+			case "RFC822.TEXT":
+				section.Specifier = imap.PartSpecifierText
+			}
+
+			if !dec.ExpectSP() {
+				return dec.Err()
+			}
+			lit, _, ok := dec.ExpectNStringReader()
+			if !ok {
+				return dec.Err()
+			}
+
+			var fetchLit imap.LiteralReader
+			if lit != nil {
+				done = make(chan struct{})
+				fetchLit = &fetchLiteralReader{
+					LiteralReader: lit,
+					ch:            done,
+				}
+			}
+			item = FetchItemDataBodySection{
+				Section: section,
+				Literal: fetchLit,
+			}
+
 		case "UID": // 处理 UID 属性
 			if !dec.ExpectSP() || !dec.ExpectUID(&uid) {
 				return dec.Err()
 			}
 			item = FetchItemDataUID{UID: uid}
+			if t := c.SeqNumTracker(); t != nil {
+				t.SetUID(seqNum, uid)
+			}
 
 		// 处理 BODY 和 BINARY 属性
 		case "BODY", "BINARY":
@@ -683,7 +1025,15 @@ func (c *Client) handleFetch(seqNum uint32) error {
 					if !dec.ExpectSpecial(']') {
 						return dec.Err()
 					}
-					section = &imap.FetchItemBinarySection{Part: part}
+					binarySection := &imap.FetchItemBinarySection{Part: part}
+					offset, err := readPartialOffset(dec)
+					if err != nil {
+						return err
+					}
+					if offset != nil {
+						binarySection.Partial = &imap.SectionPartial{Offset: int64(*offset)}
+					}
+					section = binarySection
 				}
 
 				if !dec.ExpectSP() {
@@ -768,6 +1118,48 @@ func (c *Client) handleFetch(seqNum uint32) error {
 			}
 			item = FetchItemDataModSeq{ModSeq: modSeq}
 
+		case "X-GM-LABELS": // 处理 Gmail 标签属性
+			if !dec.ExpectSP() {
+				return dec.Err()
+			}
+			labels, err := readGmailLabelList(dec)
+			if err != nil {
+				return fmt.Errorf("解析 Gmail 标签时出错: %v", err)
+			}
+			item = FetchItemDataGmailLabels{Labels: labels}
+
+		case "X-GM-MSGID", "X-GM-THRID": // 处理 Gmail 消息 ID 和会话 ID 属性
+			var id uint64
+			if !dec.ExpectSP() || !dec.Expect(dec.ModSeq(&id), "Gmail ID") {
+				return dec.Err()
+			}
+			if attName == "X-GM-MSGID" {
+				item = FetchItemDataGmailMsgID{MsgID: id}
+			} else {
+				item = FetchItemDataGmailThrID{ThrID: id}
+			}
+
+		case "EMAILID": // 处理邮件对象 ID 属性
+			var id string
+			if !dec.ExpectSP() || !dec.ExpectSpecial('(') || !dec.Expect(dec.Func(&id, isObjectIDChar), "objectid") || !dec.ExpectSpecial(')') {
+				return dec.Err()
+			}
+			item = FetchItemDataEmailID{EmailID: id}
+
+		case "THREADID": // 处理会话对象 ID 属性，服务器不支持会话时为 NIL
+			var id string
+			if !dec.ExpectSP() {
+				return dec.Err()
+			}
+			if dec.Special('(') {
+				if !dec.Expect(dec.Func(&id, isObjectIDChar), "objectid") || !dec.ExpectSpecial(')') {
+					return dec.Err()
+				}
+			} else if !dec.ExpectNIL() {
+				return dec.Err()
+			}
+			item = FetchItemDataThreadID{ThreadID: id}
+
 		default: // 如果属性不支持，返回错误
 			return fmt.Errorf("不支持的消息属性名称: %q", attName)
 		}
@@ -794,6 +1186,32 @@ func (c *Client) handleFetch(seqNum uint32) error {
 	})
 }
 
+// readGmailLabelList 读取 Gmail 标签列表。
+//
+// 系统标签（例如 \Inbox）以标志的形式发送，其他标签为 astring。
+func readGmailLabelList(dec *imapwire.Decoder) ([]string, error) {
+	var labels []string
+	err := dec.ExpectList(func() error {
+		var label string
+		if dec.Special('\\') {
+			if !dec.ExpectAtom(&label) {
+				return dec.Err()
+			}
+			label = "\\" + label
+		} else if !dec.ExpectAString(&label) {
+			return dec.Err()
+		}
+		labels = append(labels, label)
+		return nil
+	})
+	return labels, err
+}
+
+// isObjectIDChar 判断字符是否可以出现在 RFC 8474 的 objectid 中。
+func isObjectIDChar(ch byte) bool {
+	return (ch >= 'A' && ch <= 'Z') || (ch >= 'a' && ch <= 'z') || (ch >= '0' && ch <= '9') || ch == '-' || ch == '_'
+}
+
 // 判断字符是否是消息属性名称的合法字符
 // 参数:
 //
@@ -1247,10 +1665,14 @@ func readBodyFldParam(dec *imapwire.Decoder, options *Options) (map[string]strin
 			if params == nil {
 				params = make(map[string]string)
 			}
-			decoded, _ := options.decodeText(s)
-			// TODO: 处理错误
-
-			params[strings.ToLower(k)] = decoded
+			k = strings.ToLower(k)
+			if strings.Contains(k, "*") {
+				params[k] = s // RFC 2231 参数稍后统一解码
+			} else {
+				decoded, _ := options.decodeText(s)
+				// TODO: 处理错误
+				params[k] = decoded
+			}
 			k = ""
 		}
 
@@ -1261,9 +1683,129 @@ func readBodyFldParam(dec *imapwire.Decoder, options *Options) (map[string]strin
 	} else if k != "" {
 		return nil, fmt.Errorf("在 body-fld-param 解析时出错: 有键但无值")
 	}
+	decodeParamContinuations(params, options)
 	return params, nil
 }
 
+// paramSegment 是 RFC 2231 参数值的一段。
+type paramSegment struct {
+	num     int    // 段号，不分段的扩展参数为 0
+	encoded bool   // 是否带有字符集和百分号编码
+	value   string // 原始值
+}
+
+// decodeParamContinuations 按 RFC 2231 合并形如 "filename*0*"、"filename*1" 的分段参数，
+// 并解码 "filename*" 等扩展参数的字符集和百分号编码。解码后的值保存在不带 "*" 的键中，
+// 覆盖同名的普通参数。无法解码的参数保持原样。
+func decodeParamContinuations(params map[string]string, options *Options) {
+	segments := make(map[string][]paramSegment)
+	for k, v := range params {
+		i := strings.IndexByte(k, '*')
+		if i <= 0 {
+			continue
+		}
+		name, rest := k[:i], k[i+1:]
+		seg := paramSegment{value: v}
+		if rest == "" {
+			seg.encoded = true
+		} else {
+			if strings.HasSuffix(rest, "*") {
+				seg.encoded = true
+				rest = strings.TrimSuffix(rest, "*")
+			}
+			num, err := strconv.Atoi(rest)
+			if err != nil || num < 0 || (num > 0 && rest[0] == '0') {
+				continue
+			}
+			seg.num = num
+		}
+		segments[name] = append(segments[name], seg)
+	}
+
+	for name, l := range segments {
+		sort.Slice(l, func(i, j int) bool {
+			return l[i].num < l[j].num
+		})
+		value, ok := decodeParamSegments(l, options)
+		if !ok {
+			continue
+		}
+		for k := range params {
+			if strings.HasPrefix(k, name+"*") {
+				delete(params, k)
+			}
+		}
+		params[name] = value
+	}
+}
+
+// decodeParamSegments 将按段号排序的分段合并为一个值。
+func decodeParamSegments(l []paramSegment, options *Options) (string, bool) {
+	var (
+		charset string
+		buf     []byte
+	)
+	for i, seg := range l {
+		if seg.num != i {
+			return "", false // 段号必须从 0 开始连续
+		}
+		if !seg.encoded {
+			buf = append(buf, seg.value...)
+			continue
+		}
+		value := seg.value
+		if i == 0 {
+			// 第一段的格式为 charset'language'value
+			parts := strings.SplitN(value, "'", 3)
+			if len(parts) != 3 {
+				return "", false
+			}
+			charset, value = parts[0], parts[2]
+		}
+		b, ok := percentDecode(value)
+		if !ok {
+			return "", false
+		}
+		buf = append(buf, b...)
+	}
+
+	switch strings.ToLower(charset) {
+	case "", "utf-8", "us-ascii":
+		return string(buf), true
+	}
+	// 借助 WordDecoder 的 CharsetReader 转换字符集
+	wordDecoder := options.WordDecoder
+	if wordDecoder == nil {
+		wordDecoder = &mime.WordDecoder{}
+	}
+	s, err := wordDecoder.Decode("=?" + charset + "?b?" + base64.StdEncoding.EncodeToString(buf) + "?=")
+	if err != nil {
+		return "", false
+	}
+	return s, true
+}
+
+// percentDecode 解码 RFC 2231 扩展值中的百分号编码。
+func percentDecode(s string) ([]byte, bool) {
+	b := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' {
+			b = append(b, s[i])
+			continue
+		}
+		if i+2 >= len(s) {
+			return nil, false
+		}
+		v, err := strconv.ParseUint(s[i+1:i+3], 16, 8)
+		if err != nil {
+			return nil, false
+		}
+		b = append(b, byte(v))
+		i += 2
+	}
+	return b, true
+}
+
 // 读取Body的语言字段
 // 参数：
 // - dec: IMAP协议的解码器
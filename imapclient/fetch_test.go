@@ -0,0 +1,1074 @@
+package imapclient_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/luhaoyun888/go-imap-cn"
+	"github.com/luhaoyun888/go-imap-cn/imapclient"
+)
+
+const binaryRawMessage = "MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/mixed; boundary=\"frontier\"\r\n" +
+	"\r\n" +
+	"--frontier\r\n" +
+	"Content-Type: text/plain; charset=utf-8\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"caf=C3=A9\r\n" +
+	"--frontier\r\n" +
+	"Content-Type: application/octet-stream\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"AAEC\r\n" +
+	"/w==\r\n" +
+	"--frontier\r\n" +
+	"Content-Type: application/octet-stream\r\n" +
+	"Content-Transfer-Encoding: x-unknown\r\n" +
+	"\r\n" +
+	"???\r\n" +
+	"--frontier--\r\n"
+
+// TestFetch_binary 测试 FETCH BINARY[] 和 BINARY.SIZE[]。
+func TestFetch_binary(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateSelected)
+	defer client.Close()
+	defer server.Close()
+
+	appendCmd := client.Append("INBOX", int64(len(binaryRawMessage)), nil)
+	appendCmd.Write([]byte(binaryRawMessage))
+	appendCmd.Close()
+	if _, err := appendCmd.Wait(); err != nil {
+		t.Fatalf("AppendCommand.Wait() = %v", err)
+	}
+
+	seqSet := imap.SeqSetNum(2)
+	fetchOptions := &imap.FetchOptions{
+		BinarySection: []*imap.FetchItemBinarySection{
+			{Part: []int{1}, Peek: true},
+			{Part: []int{2}, Peek: true},
+		},
+		BinarySectionSize: []*imap.FetchItemBinarySectionSize{
+			{Part: []int{2}},
+		},
+	}
+	msgs, err := client.Fetch(seqSet, fetchOptions).Collect()
+	if err != nil {
+		t.Fatalf("Fetch().Collect() = %v", err)
+	} else if len(msgs) != 1 {
+		t.Fatalf("len(msgs) = %v, want %v", len(msgs), 1)
+	}
+	msg := msgs[0]
+
+	want := map[string]string{
+		"[1]": "café",
+		"[2]": "\x00\x01\x02\xff",
+	}
+	if len(msg.BinarySection) != len(want) {
+		t.Errorf("len(BinarySection) = %v, want %v", len(msg.BinarySection), len(want))
+	}
+	for section, b := range msg.BinarySection {
+		k := fmt.Sprint(section.Part)
+		if string(b) != want[k] {
+			t.Errorf("BINARY%v = %q, want %q", k, b, want[k])
+		}
+	}
+
+	if len(msg.BinarySectionSize) != 1 {
+		t.Fatalf("len(BinarySectionSize) = %v, want %v", len(msg.BinarySectionSize), 1)
+	} else if size := msg.BinarySectionSize[0].Size; size != 4 {
+		t.Errorf("BINARY.SIZE[2] = %v, want %v", size, 4)
+	}
+
+	// 无法解码的部分应返回错误
+	fetchOptions = &imap.FetchOptions{
+		BinarySection: []*imap.FetchItemBinarySection{{Part: []int{3}, Peek: true}},
+	}
+	if _, err := client.Fetch(seqSet, fetchOptions).Collect(); err == nil {
+		t.Errorf("Fetch(BINARY[3]).Collect() = nil, want an error")
+	}
+}
+
+const attachmentRawMessage = "MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/mixed; boundary=\"frontier\"\r\n" +
+	"\r\n" +
+	"--frontier\r\n" +
+	"Content-Type: text/plain; charset=utf-8\r\n" +
+	"\r\n" +
+	"Hi!\r\n" +
+	"--frontier\r\n" +
+	"Content-Type: image/png\r\n" +
+	"Content-Disposition: inline; filename=\"logo.png\"\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"AAEC\r\n" +
+	"--frontier\r\n" +
+	"Content-Type: application/pdf\r\n" +
+	"Content-Disposition: attachment; filename=\"report.pdf\"\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"AAEC\r\n" +
+	"--frontier--\r\n"
+
+// TestFetch_bodyStructureHelpers 测试正文结构的辅助方法。
+func TestFetch_bodyStructureHelpers(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateSelected)
+	defer client.Close()
+	defer server.Close()
+
+	appendCmd := client.Append("INBOX", int64(len(attachmentRawMessage)), nil)
+	appendCmd.Write([]byte(attachmentRawMessage))
+	appendCmd.Close()
+	if _, err := appendCmd.Wait(); err != nil {
+		t.Fatalf("AppendCommand.Wait() = %v", err)
+	}
+
+	fetchOptions := &imap.FetchOptions{
+		BodyStructure: &imap.FetchItemBodyStructure{Extended: true},
+	}
+	msgs, err := client.Fetch(imap.SeqSetNum(1, 2), fetchOptions).Collect()
+	if err != nil {
+		t.Fatalf("Fetch().Collect() = %v", err)
+	} else if len(msgs) != 2 {
+		t.Fatalf("len(msgs) = %v, want %v", len(msgs), 2)
+	}
+
+	// 第一封邮件是单部分邮件
+	if part, ok := msgs[0].SinglePart(); !ok {
+		t.Errorf("SinglePart() = _, false, want true")
+	} else if mediaType := part.MediaType(); mediaType != "text/plain" {
+		t.Errorf("SinglePart().MediaType() = %v, want %v", mediaType, "text/plain")
+	} else if charset := part.Charset(); charset != "utf-8" {
+		t.Errorf("SinglePart().Charset() = %v, want %v", charset, "utf-8")
+	}
+	if _, ok := msgs[0].MultiPart(); ok {
+		t.Errorf("MultiPart() = _, true, want false")
+	}
+	if l := msgs[0].Attachments(); len(l) != 0 {
+		t.Errorf("Attachments() = %v, want none", l)
+	}
+
+	// 第二封邮件是带附件的多部分邮件
+	if _, ok := msgs[1].SinglePart(); ok {
+		t.Errorf("SinglePart() = _, true, want false")
+	}
+	if part, ok := msgs[1].MultiPart(); !ok {
+		t.Errorf("MultiPart() = _, false, want true")
+	} else if len(part.Children) != 3 {
+		t.Errorf("len(MultiPart().Children) = %v, want %v", len(part.Children), 3)
+	}
+	attachments := msgs[1].Attachments()
+	if len(attachments) != 1 {
+		t.Fatalf("len(Attachments()) = %v, want %v", len(attachments), 1)
+	}
+	if filename := attachments[0].Part.Filename(); filename != "report.pdf" {
+		t.Errorf("Attachments()[0].Part.Filename() = %v, want %v", filename, "report.pdf")
+	}
+	if path := fmt.Sprint(attachments[0].Path); path != "[3]" {
+		t.Errorf("Attachments()[0].Path = %v, want %v", path, "[3]")
+	}
+
+	// 未获取正文结构时
+	var buf imapclient.FetchMessageBuffer
+	if _, ok := buf.SinglePart(); ok {
+		t.Errorf("SinglePart() = _, true, want false")
+	}
+	if l := buf.Attachments(); l != nil {
+		t.Errorf("Attachments() = %v, want nil", l)
+	}
+}
+
+// TestFetch_modSeq 测试 FETCH MODSEQ 和 CHANGEDSINCE。
+func TestFetch_modSeq(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateAuthenticated)
+	defer client.Close()
+	defer server.Close()
+
+	if !client.Caps().Has(imap.CapCondStore) {
+		t.Skip("服务器不支持 CONDSTORE")
+	}
+
+	selectData, err := client.Select("INBOX", nil).Wait()
+	if err != nil {
+		t.Fatalf("Select().Wait() = %v", err)
+	} else if selectData.HighestModSeq == 0 {
+		t.Fatalf("SelectData.HighestModSeq = 0, want non-zero")
+	}
+
+	msgs, err := client.Fetch(imap.SeqSetNum(1), &imap.FetchOptions{ModSeq: true}).Collect()
+	if err != nil {
+		t.Fatalf("Fetch().Collect() = %v", err)
+	} else if len(msgs) != 1 {
+		t.Fatalf("len(msgs) = %v, want %v", len(msgs), 1)
+	}
+	modSeq := msgs[0].ModSeq
+	if modSeq == 0 || modSeq > selectData.HighestModSeq {
+		t.Errorf("ModSeq = %v, want in (0, %v]", modSeq, selectData.HighestModSeq)
+	}
+
+	// 自当前修改序列号以来没有邮件被修改
+	msgs, err = client.Fetch(imap.SeqSetNum(1), &imap.FetchOptions{ChangedSince: modSeq}).Collect()
+	if err != nil {
+		t.Fatalf("Fetch(ChangedSince).Collect() = %v", err)
+	} else if len(msgs) != 0 {
+		t.Errorf("len(msgs) = %v, want %v", len(msgs), 0)
+	}
+
+	storeFlags := imap.StoreFlags{
+		Op:     imap.StoreFlagsAdd,
+		Silent: true,
+		Flags:  []imap.Flag{imap.FlagFlagged},
+	}
+	if err := client.Store(imap.SeqSetNum(1), &storeFlags, nil).Close(); err != nil {
+		t.Fatalf("Store().Close() = %v", err)
+	}
+
+	msgs, err = client.Fetch(imap.SeqSetNum(1), &imap.FetchOptions{ChangedSince: modSeq}).Collect()
+	if err != nil {
+		t.Fatalf("Fetch(ChangedSince).Collect() = %v", err)
+	} else if len(msgs) != 1 {
+		t.Fatalf("len(msgs) = %v, want %v", len(msgs), 1)
+	} else if msgs[0].ModSeq <= modSeq {
+		t.Errorf("ModSeq = %v, want > %v", msgs[0].ModSeq, modSeq)
+	}
+
+	statusData, err := client.Status("INBOX", &imap.StatusOptions{HighestModSeq: true}).Wait()
+	if err != nil {
+		t.Fatalf("Status().Wait() = %v", err)
+	} else if statusData.HighestModSeq != msgs[0].ModSeq {
+		t.Errorf("StatusData.HighestModSeq = %v, want %v", statusData.HighestModSeq, msgs[0].ModSeq)
+	}
+}
+
+func TestFetch_onMessage(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateSelected)
+	defer client.Close()
+	defer server.Close()
+
+	const numMessages = 3
+	for i := 1; i < numMessages; i++ {
+		appendCmd := client.Append("INBOX", int64(len(simpleRawMessage)), nil)
+		appendCmd.Write([]byte(simpleRawMessage))
+		appendCmd.Close()
+		if _, err := appendCmd.Wait(); err != nil {
+			t.Fatalf("Append().Wait() = %v", err)
+		}
+	}
+	if err := client.Noop().Wait(); err != nil {
+		t.Fatalf("Noop().Wait() = %v", err)
+	}
+
+	var progress []int
+	fetchCmd := client.Fetch(imap.SeqSetNum(1, 2, 3), &imap.FetchOptions{Flags: true})
+	fetchCmd.OnMessage(func(processed int) {
+		progress = append(progress, processed)
+	})
+	msgs, err := fetchCmd.Collect()
+	if err != nil {
+		t.Fatalf("Fetch().Collect() = %v", err)
+	} else if len(msgs) != numMessages {
+		t.Fatalf("len(msgs) = %v, want %v", len(msgs), numMessages)
+	}
+	if want := []int{1, 2, 3}; fmt.Sprint(progress) != fmt.Sprint(want) {
+		t.Errorf("progress = %v, want %v", progress, want)
+	}
+}
+
+func TestFetch_objectID(t *testing.T) {
+	var searchCmd string
+	client := newScriptedClient(t, " OBJECTID", func(cmd string) []string {
+		switch {
+		case strings.HasPrefix(cmd, "FETCH 1:2 "):
+			return []string{
+				"* 1 FETCH (EMAILID (M6d99ac3275bb4e) THREADID (T64b478a75b7ea9))",
+				"* 2 FETCH (EMAILID (M5fdc09b49ea703) THREADID NIL)",
+			}
+		case strings.HasPrefix(cmd, "SEARCH "):
+			searchCmd = cmd
+			return []string{"* SEARCH 1"}
+		default:
+			return nil
+		}
+	})
+
+	options := imap.FetchOptions{EmailID: true, ThreadID: true}
+	msgs, err := client.Fetch(imap.SeqSetNum(1, 2), &options).Collect()
+	if err != nil {
+		t.Fatalf("Fetch().Collect() = %v", err)
+	} else if len(msgs) != 2 {
+		t.Fatalf("len(msgs) = %v, want 2", len(msgs))
+	}
+	if msgs[0].EmailID != "M6d99ac3275bb4e" || msgs[0].ThreadID != "T64b478a75b7ea9" {
+		t.Errorf("msgs[0] = (%q, %q), want (%q, %q)", msgs[0].EmailID, msgs[0].ThreadID, "M6d99ac3275bb4e", "T64b478a75b7ea9")
+	}
+	if msgs[1].EmailID != "M5fdc09b49ea703" || msgs[1].ThreadID != "" {
+		t.Errorf("msgs[1] = (%q, %q), want (%q, %q)", msgs[1].EmailID, msgs[1].ThreadID, "M5fdc09b49ea703", "")
+	}
+
+	criteria := imap.SearchCriteria{EmailID: []string{"M6d99ac3275bb4e"}, ThreadID: []string{"T64b478a75b7ea9"}}
+	if _, err := client.Search(&criteria, nil).Wait(); err != nil {
+		t.Fatalf("Search().Wait() = %v", err)
+	}
+	if want := "SEARCH EMAILID M6d99ac3275bb4e THREADID T64b478a75b7ea9"; searchCmd != want {
+		t.Errorf("命令 = %q, want %q", searchCmd, want)
+	}
+}
+
+func TestFetch_objectIDUnsupported(t *testing.T) {
+	client := newScriptedClient(t, "", func(cmd string) []string {
+		t.Errorf("不应发送命令: %q", cmd)
+		return nil
+	})
+
+	if err := client.Fetch(imap.SeqSetNum(1), &imap.FetchOptions{EmailID: true}).Close(); err == nil {
+		t.Errorf("Fetch() 在服务器不支持 OBJECTID 时应失败")
+	}
+	criteria := imap.SearchCriteria{Not: []imap.SearchCriteria{{ThreadID: []string{"T1"}}}}
+	if _, err := client.Search(&criteria, nil).Wait(); err == nil {
+		t.Errorf("Search() 在服务器不支持 OBJECTID 时应失败")
+	}
+}
+
+// TestFetch_objectIDServer 测试服务器返回的 EMAILID 和 THREADID，以及按 EMAILID 搜索。
+func TestFetch_objectIDServer(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateSelected)
+	defer client.Close()
+	defer server.Close()
+
+	if !client.Caps().Has(imap.CapObjectID) {
+		t.Skip("服务器不支持 OBJECTID")
+	}
+
+	fetchObjectID := func() *imapclient.FetchMessageBuffer {
+		t.Helper()
+		msgs, err := client.Fetch(imap.SeqSetNum(1), &imap.FetchOptions{EmailID: true, ThreadID: true}).Collect()
+		if err != nil {
+			t.Fatalf("Fetch().Collect() = %v", err)
+		} else if len(msgs) != 1 {
+			t.Fatalf("len(msgs) = %v, want 1", len(msgs))
+		}
+		return msgs[0]
+	}
+	msg := fetchObjectID()
+	if msg.EmailID == "" {
+		t.Fatalf("EmailID 为空")
+	}
+
+	// 复制到其他邮箱的邮件保持相同的 EMAILID
+	if err := client.Create("Archive", nil).Wait(); err != nil {
+		t.Fatalf("Create().Wait() = %v", err)
+	}
+	if _, err := client.Copy(imap.SeqSetNum(1), "Archive").Wait(); err != nil {
+		t.Fatalf("Copy().Wait() = %v", err)
+	}
+	if _, err := client.Select("Archive", nil).Wait(); err != nil {
+		t.Fatalf("Select().Wait() = %v", err)
+	}
+	if copied := fetchObjectID(); copied.EmailID != msg.EmailID {
+		t.Errorf("复制后的 EmailID = %q, want %q", copied.EmailID, msg.EmailID)
+	}
+
+	data, err := client.Search(&imap.SearchCriteria{EmailID: []string{msg.EmailID}}, nil).Wait()
+	if err != nil {
+		t.Fatalf("Search(EMAILID).Wait() = %v", err)
+	} else if seqNums := data.AllSeqNums(); len(seqNums) != 1 || seqNums[0] != 1 {
+		t.Errorf("Search(EMAILID) = %v, want [1]", seqNums)
+	}
+	data, err = client.Search(&imap.SearchCriteria{EmailID: []string{"Eunknown"}}, nil).Wait()
+	if err != nil {
+		t.Fatalf("Search(EMAILID).Wait() = %v", err)
+	} else if seqNums := data.AllSeqNums(); len(seqNums) != 0 {
+		t.Errorf("Search(EMAILID unknown) = %v, want []", seqNums)
+	}
+}
+
+const alternativeRawMessage = "MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/mixed; boundary=\"outer\"\r\n" +
+	"\r\n" +
+	"--outer\r\n" +
+	"Content-Type: multipart/alternative; boundary=\"inner\"\r\n" +
+	"\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/plain; charset=utf-8\r\n" +
+	"\r\n" +
+	"Hello\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/html; charset=utf-8\r\n" +
+	"\r\n" +
+	"<p>Hello</p>\r\n" +
+	"--inner--\r\n" +
+	"--outer\r\n" +
+	"Content-Type: text/plain\r\n" +
+	"Content-Disposition: attachment; filename=\"notes.txt\"\r\n" +
+	"\r\n" +
+	"Notes\r\n" +
+	"--outer--\r\n"
+
+// TestFetch_findBodySection 测试按内容类型查找正文部分。
+func TestFetch_findBodySection(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateSelected)
+	defer client.Close()
+	defer server.Close()
+
+	appendCmd := client.Append("INBOX", int64(len(alternativeRawMessage)), nil)
+	appendCmd.Write([]byte(alternativeRawMessage))
+	appendCmd.Close()
+	if _, err := appendCmd.Wait(); err != nil {
+		t.Fatalf("AppendCommand.Wait() = %v", err)
+	}
+
+	// 单部分邮件通过 BODY[TEXT] 获取正文
+	msgs, err := client.Fetch(imap.SeqSetNum(1), &imap.FetchOptions{
+		BodyStructure: &imap.FetchItemBodyStructure{},
+		BodySection: []*imap.FetchItemBodySection{
+			{Specifier: imap.PartSpecifierText, Peek: true},
+			{Specifier: imap.PartSpecifierHeader, HeaderFields: []string{"Message-Id"}, Peek: true},
+		},
+	}).Collect()
+	if err != nil {
+		t.Fatalf("Fetch().Collect() = %v", err)
+	}
+	if b := msgs[0].PlainText(); string(b) != "这是我的信！" {
+		t.Errorf("PlainText() = %q, want %q", b, "这是我的信！")
+	}
+	if b := msgs[0].HTML(); b != nil {
+		t.Errorf("HTML() = %q, want nil", b)
+	}
+	if b := msgs[0].FindBodySection(imap.PartSpecifierHeader, nil); b != nil {
+		t.Errorf("FindBodySection(HEADER) = %q, want nil（仅获取了 HEADER.FIELDS）", b)
+	}
+
+	// 嵌套的多部分邮件，附件中的 text/plain 不应被当作正文
+	msgs, err = client.Fetch(imap.SeqSetNum(2), &imap.FetchOptions{
+		BodyStructure: &imap.FetchItemBodyStructure{Extended: true},
+		BodySection: []*imap.FetchItemBodySection{
+			{Part: []int{1, 1}, Peek: true},
+			{Part: []int{1, 2}, Peek: true},
+			{Part: []int{2}, Peek: true},
+		},
+	}).Collect()
+	if err != nil {
+		t.Fatalf("Fetch().Collect() = %v", err)
+	}
+	if b := msgs[0].PlainText(); string(b) != "Hello" {
+		t.Errorf("PlainText() = %q, want %q", b, "Hello")
+	}
+	if b := msgs[0].HTML(); string(b) != "<p>Hello</p>" {
+		t.Errorf("HTML() = %q, want %q", b, "<p>Hello</p>")
+	}
+	if b := msgs[0].FindBodySection(imap.PartSpecifierNone, []int{2}); string(b) != "Notes" {
+		t.Errorf("FindBodySection(2) = %q, want %q", b, "Notes")
+	}
+	if b := msgs[0].FindBodySection(imap.PartSpecifierNone, []int{3}); b != nil {
+		t.Errorf("FindBodySection(3) = %q, want nil", b)
+	}
+}
+
+// TestFetch_partOutOfRange 测试请求不存在的部分时服务器返回 NIL，
+// 而偏移超出范围的部分内容返回空字符串。
+func TestFetch_partOutOfRange(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateSelected)
+	defer client.Close()
+	defer server.Close()
+
+	appendCmd := client.Append("INBOX", int64(len(alternativeRawMessage)), nil)
+	appendCmd.Write([]byte(alternativeRawMessage))
+	appendCmd.Close()
+	if _, err := appendCmd.Wait(); err != nil {
+		t.Fatalf("AppendCommand.Wait() = %v", err)
+	}
+
+	missing := []*imap.FetchItemBodySection{
+		{Part: []int{3}, Peek: true},
+		{Part: []int{1, 5}, Peek: true},
+		{Part: []int{3}, Specifier: imap.PartSpecifierMIME, Peek: true},
+	}
+	partial := &imap.FetchItemBodySection{Part: []int{2}, Partial: &imap.SectionPartial{Offset: 1000, Size: 10}, Peek: true}
+	msgs, err := client.Fetch(imap.SeqSetNum(2), &imap.FetchOptions{
+		BodySection: append(missing, partial),
+	}).Collect()
+	if err != nil {
+		t.Fatalf("Fetch().Collect() = %v", err)
+	}
+	if len(msgs[0].BodySection) != len(missing)+1 {
+		t.Fatalf("BodySection 包含 %v 项, want %v", len(msgs[0].BodySection), len(missing)+1)
+	}
+	for section, b := range msgs[0].BodySection {
+		if section.Partial != nil {
+			if b == nil || len(b) != 0 {
+				t.Errorf("BODY[2]<1000.10> = %q, want 空字符串", b)
+			}
+		} else if b != nil {
+			t.Errorf("BODY[%v] = %q, want NIL", section.Part, b)
+		}
+	}
+
+	// 连接仍然可用
+	if err := client.Noop().Wait(); err != nil {
+		t.Errorf("Noop().Wait() = %v", err)
+	}
+}
+
+// TestFetch_modSeqAutoEnable 测试请求 MODSEQ 时自动启用 CONDSTORE。
+func TestFetch_modSeqAutoEnable(t *testing.T) {
+	var (
+		mutex sync.Mutex
+		cmds  []string
+	)
+	client := newScriptedClient(t, " ENABLE CONDSTORE", func(cmd string) []string {
+		mutex.Lock()
+		cmds = append(cmds, cmd)
+		mutex.Unlock()
+		switch {
+		case cmd == "ENABLE CONDSTORE":
+			return []string{"* ENABLED CONDSTORE"}
+		case strings.HasPrefix(cmd, "FETCH"):
+			return []string{"* 1 FETCH (MODSEQ (42))"}
+		}
+		return nil
+	})
+
+	for i := 0; i < 2; i++ {
+		msgs, err := client.Fetch(imap.SeqSetNum(1), &imap.FetchOptions{ModSeq: true}).Collect()
+		if err != nil {
+			t.Fatalf("Fetch().Collect() = %v", err)
+		} else if len(msgs) != 1 || msgs[0].ModSeq != 42 {
+			t.Fatalf("Fetch() = %v, want MODSEQ 42", msgs)
+		}
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	want := []string{"ENABLE CONDSTORE", "FETCH 1 (MODSEQ)", "FETCH 1 (MODSEQ)"}
+	if fmt.Sprint(cmds) != fmt.Sprint(want) {
+		t.Errorf("命令 = %q, want %q", cmds, want)
+	}
+}
+
+// TestFetch_modSeqUnsupported 测试服务器不支持 CONDSTORE 时的错误。
+func TestFetch_modSeqUnsupported(t *testing.T) {
+	client := newScriptedClient(t, "", func(cmd string) []string {
+		t.Errorf("不应发送命令: %q", cmd)
+		return nil
+	})
+
+	if err := client.Fetch(imap.SeqSetNum(1), &imap.FetchOptions{ChangedSince: 1}).Close(); err == nil {
+		t.Errorf("Fetch() 在服务器不支持 CONDSTORE 时应失败")
+	}
+}
+
+// TestFetch_onBodySection 测试把 BODY[] 字面量流式交给回调处理。
+func TestFetch_onBodySection(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateSelected)
+	defer client.Close()
+	defer server.Close()
+
+	fetchOptions := &imap.FetchOptions{
+		UID:         true,
+		BodySection: []*imap.FetchItemBodySection{{Peek: true}},
+	}
+	var buf bytes.Buffer
+	fetchCmd := client.Fetch(imap.SeqSetNum(1), fetchOptions)
+	fetchCmd.OnBodySection(func(section *imap.FetchItemBodySection, r io.Reader) error {
+		if section.Specifier != imap.PartSpecifierNone || len(section.Part) != 0 {
+			t.Errorf("section = %v, want BODY[]", section)
+		}
+		_, err := io.Copy(&buf, r)
+		return err
+	})
+	msgs, err := fetchCmd.Collect()
+	if err != nil {
+		t.Fatalf("Fetch().Collect() = %v", err)
+	} else if len(msgs) != 1 {
+		t.Fatalf("len(msgs) = %v, want %v", len(msgs), 1)
+	}
+	if msgs[0].UID == 0 {
+		t.Errorf("UID = 0, want non-zero")
+	}
+	if len(msgs[0].BodySection) != 0 {
+		t.Errorf("BodySection = %v, want empty", msgs[0].BodySection)
+	}
+	if got := strings.ReplaceAll(buf.String(), "\r\n", "\n"); got != simpleRawMessage {
+		t.Errorf("回调收到 %q, want %q", got, simpleRawMessage)
+	}
+
+	// 回调的错误由 Collect 返回，连接仍然可用
+	errCallback := errors.New("回调失败")
+	fetchCmd = client.Fetch(imap.SeqSetNum(1), fetchOptions)
+	fetchCmd.OnBodySection(func(section *imap.FetchItemBodySection, r io.Reader) error {
+		return errCallback
+	})
+	if _, err := fetchCmd.Collect(); err != errCallback {
+		t.Errorf("Fetch().Collect() = %v, want %v", err, errCallback)
+	}
+	if err := client.Noop().Wait(); err != nil {
+		t.Errorf("Noop().Wait() = %v", err)
+	}
+}
+
+// TestFetchEnvelopes 测试一次性获取 100 封邮件的信封。
+func TestFetchEnvelopes(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateSelected)
+	defer client.Close()
+	defer server.Close()
+
+	const numMessages = 100
+	for i := 2; i <= numMessages; i++ {
+		rawMessage := fmt.Sprintf("Subject: 邮件 %v\r\n\r\nHello\r\n", i)
+		appendCmd := client.Append("INBOX", int64(len(rawMessage)), nil)
+		appendCmd.Write([]byte(rawMessage))
+		appendCmd.Close()
+		if _, err := appendCmd.Wait(); err != nil {
+			t.Fatalf("Append().Wait() = %v", err)
+		}
+	}
+	if err := client.Noop().Wait(); err != nil {
+		t.Fatalf("Noop().Wait() = %v", err)
+	}
+
+	var seqSet imap.SeqSet
+	seqSet.AddRange(1, numMessages)
+	envelopes, err := client.FetchEnvelopes(seqSet)
+	if err != nil {
+		t.Fatalf("FetchEnvelopes() = %v", err)
+	} else if len(envelopes) != numMessages {
+		t.Fatalf("len(envelopes) = %v, want %v", len(envelopes), numMessages)
+	}
+	for seqNum := uint32(2); seqNum <= numMessages; seqNum++ {
+		env := envelopes[seqNum]
+		if want := fmt.Sprintf("邮件 %v", seqNum); env == nil || env.Subject != want {
+			t.Errorf("envelopes[%v] = %v, want subject %q", seqNum, env, want)
+		}
+	}
+
+	// UID 集合以 UID 为键
+	uidEnvelopes, err := client.FetchEnvelopes(imap.UIDSetNum(1, 2))
+	if err != nil {
+		t.Fatalf("FetchEnvelopes(UIDSet) = %v", err)
+	} else if len(uidEnvelopes) != 2 || uidEnvelopes[1] == nil || uidEnvelopes[2] == nil {
+		t.Errorf("FetchEnvelopes(UIDSet) = %v, want UID 1 and 2", uidEnvelopes)
+	}
+}
+
+// TestFetch_envelopeGroup 测试 ENVELOPE 中的组地址保留组的开始和结束标记。
+func TestFetch_envelopeGroup(t *testing.T) {
+	client := newScriptedClient(t, "", func(cmd string) []string {
+		if !strings.HasPrefix(cmd, "FETCH ") {
+			return nil
+		}
+		return []string{
+			`* 1 FETCH (ENVELOPE ("Mon, 1 Jan 2024 10:00:00 +0000" "hi" ` +
+				`(("Alice" NIL "alice" "example.org")) NIL NIL ` +
+				`((NIL NIL "undisclosed-recipients" NIL)(NIL NIL NIL NIL)) ` +
+				`((NIL NIL "team" NIL)("Bob" NIL "bob" "example.org")(NIL NIL NIL NIL)("Carol" NIL "carol" "example.org")) ` +
+				`NIL NIL NIL))`,
+		}
+	})
+
+	msgs, err := client.Fetch(imap.SeqSetNum(1), &imap.FetchOptions{Envelope: true}).Collect()
+	if err != nil {
+		t.Fatalf("Fetch().Collect() = %v", err)
+	} else if len(msgs) != 1 || msgs[0].Envelope == nil {
+		t.Fatalf("Fetch().Collect() = %v, want one envelope", msgs)
+	}
+	env := msgs[0].Envelope
+
+	wantTo := []imap.Address{{Mailbox: "undisclosed-recipients"}, {}}
+	if !reflect.DeepEqual(env.To, wantTo) {
+		t.Errorf("To = %#v, want %#v", env.To, wantTo)
+	}
+	if !env.To[0].IsGroupStart() || !env.To[1].IsGroupEnd() {
+		t.Errorf("To = %#v, want group start and end", env.To)
+	}
+
+	wantCc := []imap.Address{
+		{Mailbox: "team"},
+		{Name: "Bob", Mailbox: "bob", Host: "example.org"},
+		{},
+		{Name: "Carol", Mailbox: "carol", Host: "example.org"},
+	}
+	if !reflect.DeepEqual(env.Cc, wantCc) {
+		t.Errorf("Cc = %#v, want %#v", env.Cc, wantCc)
+	}
+	for i, want := range []string{"", "bob@example.org", "", "carol@example.org"} {
+		if got := env.Cc[i].Addr(); got != want {
+			t.Errorf("Cc[%v].Addr() = %q, want %q", i, got, want)
+		}
+	}
+}
+
+// TestFetch_envelopeGroupRoundTrip 测试组地址经服务器编码、客户端解析后保持结构。
+func TestFetch_envelopeGroupRoundTrip(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateSelected)
+	defer client.Close()
+	defer server.Close()
+
+	rawMessage := "From: Alice <alice@example.org>\r\n" +
+		"To: undisclosed-recipients:;\r\n" +
+		"Cc: bob@example.org, \"Team: Dev\": Carol <carol@example.org>, dave@example.org;, erin@example.org\r\n" +
+		"Subject: 组地址\r\n" +
+		"\r\n" +
+		"Hello\r\n"
+	appendCmd := client.Append("INBOX", int64(len(rawMessage)), nil)
+	appendCmd.Write([]byte(rawMessage))
+	appendCmd.Close()
+	if _, err := appendCmd.Wait(); err != nil {
+		t.Fatalf("Append().Wait() = %v", err)
+	}
+	if err := client.Noop().Wait(); err != nil {
+		t.Fatalf("Noop().Wait() = %v", err)
+	}
+
+	envelopes, err := client.FetchEnvelopes(imap.SeqSetNum(2))
+	if err != nil {
+		t.Fatalf("FetchEnvelopes() = %v", err)
+	}
+	env := envelopes[2]
+	if env == nil {
+		t.Fatalf("FetchEnvelopes() = %v, want message 2", envelopes)
+	}
+
+	wantFrom := []imap.Address{{Name: "Alice", Mailbox: "alice", Host: "example.org"}}
+	if !reflect.DeepEqual(env.From, wantFrom) {
+		t.Errorf("From = %#v, want %#v", env.From, wantFrom)
+	}
+	wantTo := []imap.Address{{Mailbox: "undisclosed-recipients"}, {}}
+	if !reflect.DeepEqual(env.To, wantTo) {
+		t.Errorf("To = %#v, want %#v", env.To, wantTo)
+	}
+	wantCc := []imap.Address{
+		{Mailbox: "bob", Host: "example.org"},
+		{Mailbox: "Team: Dev"},
+		{Name: "Carol", Mailbox: "carol", Host: "example.org"},
+		{Mailbox: "dave", Host: "example.org"},
+		{},
+		{Mailbox: "erin", Host: "example.org"},
+	}
+	if !reflect.DeepEqual(env.Cc, wantCc) {
+		t.Errorf("Cc = %#v, want %#v", env.Cc, wantCc)
+	}
+	if env.Bcc != nil {
+		t.Errorf("Bcc = %#v, want nil", env.Bcc)
+	}
+}
+
+// TestFetch_uidSeqNumMapping 测试 UID FETCH 同时填充序号和 UID。
+func TestFetch_uidSeqNumMapping(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateSelected)
+	defer client.Close()
+	defer server.Close()
+
+	for i := 0; i < 3; i++ {
+		appendCmd := client.Append("INBOX", int64(len(simpleRawMessage)), nil)
+		appendCmd.Write([]byte(simpleRawMessage))
+		appendCmd.Close()
+		if _, err := appendCmd.Wait(); err != nil {
+			t.Fatalf("Append().Wait() = %v", err)
+		}
+	}
+
+	// 删除第 2 封邮件，使序号与 UID 不再相同
+	storeFlags := imap.StoreFlags{Op: imap.StoreFlagsAdd, Silent: true, Flags: []imap.Flag{imap.FlagDeleted}}
+	if err := client.Store(imap.SeqSetNum(2), &storeFlags, nil).Close(); err != nil {
+		t.Fatalf("Store().Close() = %v", err)
+	}
+	if err := client.Expunge().Close(); err != nil {
+		t.Fatalf("Expunge().Close() = %v", err)
+	}
+
+	want := map[uint32]imap.UID{1: 1, 2: 3, 3: 4}
+	got := make(map[uint32]imap.UID)
+	fetchCmd := client.Fetch(imap.UIDSetNum(1, 3, 4), &imap.FetchOptions{UID: true})
+	for {
+		msg := fetchCmd.Next()
+		if msg == nil {
+			break
+		}
+		for {
+			item := msg.Next()
+			if item == nil {
+				break
+			}
+			if item, ok := item.(imapclient.FetchItemDataUID); ok {
+				got[msg.SeqNum] = item.UID
+			}
+		}
+	}
+	if err := fetchCmd.Close(); err != nil {
+		t.Fatalf("Fetch().Close() = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("序号到 UID 的映射 = %v, want %v", got, want)
+	}
+}
+
+// TestFetch_mixedLiteralOrder 测试 FETCH 中字面量与非字面量数据项按服务器发送顺序交付。
+func TestFetch_mixedLiteralOrder(t *testing.T) {
+	const (
+		body   = "Subject: hi\r\n\r\nHello\r\n"
+		header = "Subject: hi\r\n\r\n"
+	)
+	client := newScriptedClient(t, "", func(cmd string) []string {
+		if !strings.HasPrefix(cmd, "FETCH ") {
+			return nil
+		}
+		return []string{
+			fmt.Sprintf("* 1 FETCH (FLAGS (\\Seen) BODY[] {%v}\r\n%v UID 7 BODY[HEADER] {%v}\r\n%v RFC822.SIZE %v)",
+				len(body), body, len(header), header, len(body)),
+		}
+	})
+
+	fetchCmd := client.Fetch(imap.SeqSetNum(1), &imap.FetchOptions{
+		Flags:       true,
+		UID:         true,
+		RFC822Size:  true,
+		BodySection: []*imap.FetchItemBodySection{{}, {Specifier: imap.PartSpecifierHeader}},
+	})
+	defer fetchCmd.Close()
+
+	msg := fetchCmd.Next()
+	if msg == nil {
+		t.Fatalf("FetchCommand.Next() = nil")
+	}
+	var got []string
+	for {
+		item := msg.Next()
+		if item == nil {
+			break
+		}
+		switch item := item.(type) {
+		case imapclient.FetchItemDataFlags:
+			got = append(got, "FLAGS")
+		case imapclient.FetchItemDataUID:
+			got = append(got, fmt.Sprintf("UID %v", item.UID))
+		case imapclient.FetchItemDataRFC822Size:
+			got = append(got, fmt.Sprintf("RFC822.SIZE %v", item.Size))
+		case imapclient.FetchItemDataBodySection:
+			if item.Section.Specifier == imap.PartSpecifierNone {
+				// 只读取一部分，剩余的字面量应在推进到下一项时被丢弃
+				b := make([]byte, 4)
+				if _, err := io.ReadFull(item.Literal, b); err != nil {
+					t.Fatalf("读取 BODY[] 失败: %v", err)
+				}
+				got = append(got, "BODY[] "+string(b))
+			} else {
+				b, err := io.ReadAll(item.Literal)
+				if err != nil {
+					t.Fatalf("读取 BODY[HEADER] 失败: %v", err)
+				}
+				got = append(got, "BODY[HEADER] "+string(b))
+			}
+		default:
+			t.Errorf("意外的数据项 %T", item)
+		}
+	}
+	if err := fetchCmd.Close(); err != nil {
+		t.Fatalf("FetchCommand.Close() = %v", err)
+	}
+
+	want := []string{
+		"FLAGS",
+		"BODY[] Subj",
+		"UID 7",
+		"BODY[HEADER] " + header,
+		fmt.Sprintf("RFC822.SIZE %v", len(body)),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("数据项顺序 = %q, want %q", got, want)
+	}
+}
+
+// TestFetch_rfc822 测试将服务器返回的 RFC822、RFC822.HEADER 和 RFC822.TEXT
+// 解析为对应的体部分。
+func TestFetch_rfc822(t *testing.T) {
+	const (
+		header = "Subject: hi\r\n\r\n"
+		text   = "Hello\r\n"
+		body   = header + text
+	)
+	client := newScriptedClient(t, "", func(cmd string) []string {
+		if !strings.HasPrefix(cmd, "FETCH ") {
+			return nil
+		}
+		return []string{
+			fmt.Sprintf("* 1 FETCH (RFC822 {%v}\r\n%v RFC822.HEADER {%v}\r\n%v RFC822.TEXT {%v}\r\n%v)",
+				len(body), body, len(header), header, len(text), text),
+		}
+	})
+
+	msgs, err := client.Fetch(imap.SeqSetNum(1), &imap.FetchOptions{
+		BodySection: []*imap.FetchItemBodySection{
+			{},
+			{Specifier: imap.PartSpecifierHeader, Peek: true},
+			{Specifier: imap.PartSpecifierText},
+		},
+	}).Collect()
+	if err != nil {
+		t.Fatalf("Fetch().Collect() = %v", err)
+	} else if len(msgs) != 1 {
+		t.Fatalf("len(msgs) = %v, want 1", len(msgs))
+	}
+
+	msg := msgs[0]
+	if got := string(msg.FindBodySection(imap.PartSpecifierNone, nil)); got != body {
+		t.Errorf("RFC822 = %q, want %q", got, body)
+	}
+	if got := string(msg.FindBodySection(imap.PartSpecifierHeader, nil)); got != header {
+		t.Errorf("RFC822.HEADER = %q, want %q", got, header)
+	}
+	if got := string(msg.FindBodySection(imap.PartSpecifierText, nil)); got != text {
+		t.Errorf("RFC822.TEXT = %q, want %q", got, text)
+	}
+}
+
+// TestFetch_rfc2231Params 测试按 RFC 2231 合并分段参数并解码字符集和百分号编码。
+func TestFetch_rfc2231Params(t *testing.T) {
+	client := newScriptedClient(t, "", func(cmd string) []string {
+		if !strings.HasPrefix(cmd, "FETCH ") {
+			return nil
+		}
+		return []string{
+			`* 1 FETCH (BODYSTRUCTURE (` +
+				`("application" "pdf" ("name*0*" "utf-8''%E6%8A%A5" "name*1*" "%E5%91%8A.pdf") NIL NIL "base64" 100 NIL ("attachment" ("FILENAME*" "UTF-8''%E6%8A%A5%E5%91%8A.pdf")) NIL NIL)` +
+				`("text" "plain" ("charset" "us-ascii" "name*1" "name.txt" "name*0" "long") NIL NIL "7bit" 10 1 NIL NIL NIL NIL)` +
+				`("application" "octet-stream" ("name*" "iso-8859-1'en'caf%E9.txt") NIL NIL "base64" 4 NIL NIL NIL NIL)` +
+				` "mixed"))`,
+		}
+	})
+
+	msgs, err := client.Fetch(imap.SeqSetNum(1), &imap.FetchOptions{
+		BodyStructure: &imap.FetchItemBodyStructure{Extended: true},
+	}).Collect()
+	if err != nil {
+		t.Fatalf("Fetch().Collect() = %v", err)
+	} else if len(msgs) != 1 {
+		t.Fatalf("len(msgs) = %v, want 1", len(msgs))
+	}
+	mp, ok := msgs[0].BodyStructure.(*imap.BodyStructureMultiPart)
+	if !ok || len(mp.Children) != 3 {
+		t.Fatalf("BodyStructure = %#v, want multipart with 3 children", msgs[0].BodyStructure)
+	}
+
+	for i, want := range []string{"报告.pdf", "longname.txt", "café.txt"} {
+		part := mp.Children[i].(*imap.BodyStructureSinglePart)
+		if got := part.Filename(); got != want {
+			t.Errorf("part %v: Filename() = %q, want %q", i+1, got, want)
+		}
+	}
+	pdf := mp.Children[0].(*imap.BodyStructureSinglePart)
+	if got, want := pdf.Params["name"], "报告.pdf"; got != want {
+		t.Errorf("Params[name] = %q, want %q", got, want)
+	}
+	if _, ok := pdf.Params["name*0*"]; ok {
+		t.Errorf("Params 仍包含分段参数: %v", pdf.Params)
+	}
+	if got := mp.Children[1].(*imap.BodyStructureSinglePart).Charset(); got != "us-ascii" {
+		t.Errorf("Charset() = %q, want %q", got, "us-ascii")
+	}
+}
+
+// TestFetch_bodyAndBodyStructure 测试同时请求 BODY 和 BODYSTRUCTURE。
+func TestFetch_bodyAndBodyStructure(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateSelected)
+	defer client.Close()
+	defer server.Close()
+
+	options := &imap.FetchOptions{BodyStructure: &imap.FetchItemBodyStructure{Both: true}}
+	fetchCmd := client.Fetch(imap.SeqSetNum(1), options)
+	msg := fetchCmd.Next()
+	if msg == nil {
+		t.Fatalf("FetchCommand.Next() = nil")
+	}
+	var body, bodyStructure *imap.BodyStructureSinglePart
+	for {
+		item := msg.Next()
+		if item == nil {
+			break
+		}
+		data, ok := item.(imapclient.FetchItemDataBodyStructure)
+		if !ok {
+			continue
+		}
+		part, ok := data.BodyStructure.(*imap.BodyStructureSinglePart)
+		if !ok {
+			t.Fatalf("BodyStructure = %T, want single part", data.BodyStructure)
+		}
+		if data.IsExtended {
+			bodyStructure = part
+		} else {
+			body = part
+		}
+	}
+	if err := fetchCmd.Close(); err != nil {
+		t.Fatalf("FetchCommand.Close() = %v", err)
+	}
+
+	if body == nil || bodyStructure == nil {
+		t.Fatalf("BODY = %v, BODYSTRUCTURE = %v, want both", body, bodyStructure)
+	}
+	if body.Extended != nil {
+		t.Errorf("BODY 包含扩展数据: %+v", body.Extended)
+	}
+	if bodyStructure.Extended == nil {
+		t.Errorf("BODYSTRUCTURE 缺少扩展数据")
+	}
+	if body.Type != bodyStructure.Type || body.Subtype != bodyStructure.Subtype || body.Size != bodyStructure.Size {
+		t.Errorf("BODY = %+v, BODYSTRUCTURE = %+v, want same part", body, bodyStructure)
+	}
+
+	// Collect 保留扩展的体结构
+	msgs, err := client.Fetch(imap.SeqSetNum(1), options).Collect()
+	if err != nil {
+		t.Fatalf("Fetch().Collect() = %v", err)
+	}
+	if part, ok := msgs[0].SinglePart(); !ok || part.Extended == nil {
+		t.Errorf("FetchMessageBuffer.BodyStructure = %+v, want extended", msgs[0].BodyStructure)
+	}
+}
+
+// TestFetch_builder 测试构建器生成的 options 与手写的编码一致。
+func TestFetch_builder(t *testing.T) {
+	var (
+		mutex sync.Mutex
+		cmds  []string
+	)
+	client := newScriptedClient(t, "", func(cmd string) []string {
+		mutex.Lock()
+		cmds = append(cmds, cmd)
+		mutex.Unlock()
+		return nil
+	})
+
+	built := imap.NewFetch().UID().Envelope().BodyPeek(1, 2).HeaderPeek("Subject").Build()
+	handwritten := &imap.FetchOptions{
+		UID:      true,
+		Envelope: true,
+		BodySection: []*imap.FetchItemBodySection{
+			{Part: []int{1, 2}, Peek: true},
+			{Specifier: imap.PartSpecifierHeader, HeaderFields: []string{"Subject"}, Peek: true},
+		},
+	}
+	for _, options := range []*imap.FetchOptions{built, handwritten} {
+		if err := client.Fetch(imap.SeqSetNum(1), options).Close(); err != nil {
+			t.Fatalf("Fetch().Close() = %v", err)
+		}
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(cmds) != 2 {
+		t.Fatalf("发送了 %v 个命令, want 2", len(cmds))
+	}
+	if want := `FETCH 1 (UID ENVELOPE BODY.PEEK[1.2] BODY.PEEK[HEADER.FIELDS ("Subject")])`; cmds[0] != want {
+		t.Errorf("构建器编码为 %q, want %q", cmds[0], want)
+	}
+	if cmds[0] != cmds[1] {
+		t.Errorf("构建器编码为 %q, 手写编码为 %q", cmds[0], cmds[1])
+	}
+}
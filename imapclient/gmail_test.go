@@ -0,0 +1,77 @@
+package imapclient_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/luhaoyun888/go-imap-cn"
+)
+
+func TestFetch_gmail(t *testing.T) {
+	client := newScriptedClient(t, " X-GM-EXT-1", func(cmd string) []string {
+		if !strings.HasPrefix(cmd, "FETCH 1 ") {
+			return nil
+		}
+		for _, item := range []string{"X-GM-LABELS", "X-GM-MSGID", "X-GM-THRID"} {
+			if !strings.Contains(cmd, item) {
+				return nil
+			}
+		}
+		return []string{`* 1 FETCH (X-GM-LABELS (\Inbox "My label" work) X-GM-MSGID 1278455344230334865 X-GM-THRID 1266894439832287888)`}
+	})
+
+	options := imap.FetchOptions{GmailLabels: true, GmailMsgID: true, GmailThrID: true}
+	msgs, err := client.Fetch(imap.SeqSetNum(1), &options).Collect()
+	if err != nil {
+		t.Fatalf("Fetch().Collect() = %v", err)
+	} else if len(msgs) != 1 {
+		t.Fatalf("len(msgs) = %v, want 1", len(msgs))
+	}
+	msg := msgs[0]
+	if want := []string{`\Inbox`, "My label", "work"}; !reflect.DeepEqual(msg.GmailLabels, want) {
+		t.Errorf("GmailLabels = %q, want %q", msg.GmailLabels, want)
+	}
+	if msg.GmailMsgID != 1278455344230334865 {
+		t.Errorf("GmailMsgID = %v, want %v", msg.GmailMsgID, uint64(1278455344230334865))
+	}
+	if msg.GmailThrID != 1266894439832287888 {
+		t.Errorf("GmailThrID = %v, want %v", msg.GmailThrID, uint64(1266894439832287888))
+	}
+}
+
+func TestStoreGmailLabels(t *testing.T) {
+	var got string
+	client := newScriptedClient(t, " X-GM-EXT-1", func(cmd string) []string {
+		got = cmd
+		return nil
+	})
+
+	store := imap.StoreGmailLabels{
+		Op:     imap.StoreFlagsAdd,
+		Silent: true,
+		Labels: []string{`\Important`, "My label"},
+	}
+	if err := client.StoreGmailLabels(imap.UIDSetNum(42), &store, nil).Close(); err != nil {
+		t.Fatalf("StoreGmailLabels().Close() = %v", err)
+	}
+	if want := `UID STORE 42 +X-GM-LABELS.SILENT (\Important "My label")`; got != want {
+		t.Errorf("命令 = %q, want %q", got, want)
+	}
+}
+
+func TestGmail_unsupported(t *testing.T) {
+	client := newScriptedClient(t, "", func(cmd string) []string {
+		t.Errorf("不应发送命令: %q", cmd)
+		return nil
+	})
+
+	options := imap.FetchOptions{GmailMsgID: true}
+	if err := client.Fetch(imap.SeqSetNum(1), &options).Close(); err == nil {
+		t.Errorf("Fetch() 在服务器不支持 X-GM-EXT-1 时应失败")
+	}
+	store := imap.StoreGmailLabels{Labels: []string{"work"}}
+	if err := client.StoreGmailLabels(imap.SeqSetNum(1), &store, nil).Close(); err == nil {
+		t.Errorf("StoreGmailLabels() 在服务器不支持 X-GM-EXT-1 时应失败")
+	}
+}
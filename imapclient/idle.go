@@ -17,14 +17,37 @@ const idleRestartInterval = 28 * time.Minute // IDLE 命令重启间隔
 // 此命令要求支持 IMAP4rev2 或 IDLE 扩展。IDLE
 // 命令会自动重启，以避免因不活动超时而断开连接。
 func (c *Client) Idle() (*IdleCommand, error) {
+	return c.IdleWithRenewal(idleRestartInterval)
+}
+
+// IdleWithRenewal 与 Idle 相同，但使用自定义的续期间隔。
+//
+// 每经过 interval，客户端会发送 DONE 并重新发送 IDLE，这对调用者是透明的。
+// 续期期间收到的单方面数据仍会通过 UnilateralDataHandler 投递。
+// 如果 interval 小于或等于零，则使用默认间隔（28 分钟）。
+//
+// RFC 2177 要求服务器的不活动超时至少为 30 分钟，因此 interval 不应超过
+// 29 分钟。
+func (c *Client) IdleWithRenewal(interval time.Duration) (*IdleCommand, error) {
+	defer c.interruptAutoIdle()()
+	return c.idleWithRenewal(interval)
+}
+
+// idleWithRenewal 与 IdleWithRenewal 相同，但不退出自动 IDLE。
+func (c *Client) idleWithRenewal(interval time.Duration) (*IdleCommand, error) {
+	if interval <= 0 {
+		interval = idleRestartInterval
+	}
+
 	child, err := c.idle() // 发送 IDLE 命令
 	if err != nil {
 		return nil, err
 	}
 
 	cmd := &IdleCommand{
-		stop: make(chan struct{}),
-		done: make(chan struct{}),
+		interval: interval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
 	}
 	go cmd.run(c, child) // 启动 IDLE 命令的运行
 	return cmd, nil
@@ -37,9 +60,10 @@ func (c *Client) Idle() (*IdleCommand, error) {
 //
 // 必须调用 Close 来停止 IDLE 命令。
 type IdleCommand struct {
-	stopped atomic.Bool
-	stop    chan struct{}
-	done    chan struct{}
+	interval time.Duration // 续期间隔
+	stopped  atomic.Bool
+	stop     chan struct{}
+	done     chan struct{}
 
 	err       error
 	lastChild *idleCommand
@@ -49,7 +73,7 @@ type IdleCommand struct {
 func (cmd *IdleCommand) run(c *Client, child *idleCommand) {
 	defer close(cmd.done) // 关闭完成通道
 
-	timer := time.NewTimer(idleRestartInterval) // 创建重启定时器
+	timer := time.NewTimer(cmd.interval) // 创建重启定时器
 	defer timer.Stop()
 
 	defer func() {
@@ -63,7 +87,7 @@ func (cmd *IdleCommand) run(c *Client, child *idleCommand) {
 	for {
 		select {
 		case <-timer.C: // 如果定时器到期
-			timer.Reset(idleRestartInterval) // 重置定时器
+			timer.Reset(cmd.interval) // 重置定时器
 
 			if cmd.err = child.Close(); cmd.err != nil {
 				return // 关闭子命令出错
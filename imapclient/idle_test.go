@@ -2,6 +2,7 @@ package imapclient_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/luhaoyun888/go-imap-cn"
 )
@@ -20,6 +21,33 @@ func TestIdle(t *testing.T) {
 	if err := idleCmd.Close(); err != nil { // 关闭 IDLE 命令
 		t.Errorf("Close() = %v", err) // 检查关闭是否成功
 	}
+	if err := idleCmd.Wait(); err != nil { // 等待服务器响应
+		t.Errorf("Wait() = %v", err)
+	}
+}
+
+// TestIdleWithRenewal 测试 IDLE 命令的自动续期。
+func TestIdleWithRenewal(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateSelected)
+	defer client.Close() // 确保客户端关闭
+	defer server.Close() // 确保服务器关闭
+
+	idleCmd, err := client.IdleWithRenewal(10 * time.Millisecond)
+	if err != nil {
+		t.Fatalf("IdleWithRenewal() = %v", err)
+	}
+	time.Sleep(100 * time.Millisecond) // 等待数次续期
+	if err := idleCmd.Close(); err != nil {
+		t.Errorf("Close() = %v", err)
+	}
+	if err := idleCmd.Wait(); err != nil {
+		t.Errorf("Wait() = %v", err)
+	}
+
+	// 续期结束后，客户端应能继续发送命令
+	if err := client.Noop().Wait(); err != nil {
+		t.Errorf("Noop().Wait() = %v", err)
+	}
 }
 
 // TestIdle_closedConn 测试关闭连接时的 IDLE 命令。
@@ -42,3 +70,25 @@ func TestIdle_closedConn(t *testing.T) {
 		t.Errorf("IdleCommand.Wait() = nil, want an error") // 检查是否返回错误
 	}
 }
+
+// TestIdleWithRenewal_closedConn 测试续期期间关闭连接。
+func TestIdleWithRenewal_closedConn(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateSelected)
+	defer client.Close() // 确保客户端关闭
+	defer server.Close() // 确保服务器关闭
+
+	idleCmd, err := client.IdleWithRenewal(time.Millisecond)
+	if err != nil {
+		t.Fatalf("IdleWithRenewal() = %v", err)
+	}
+	defer idleCmd.Close() // 确保 IDLE 命令关闭
+
+	time.Sleep(20 * time.Millisecond)
+	if err := client.Close(); err != nil {
+		t.Fatalf("client.Close() = %v", err)
+	}
+
+	if err := idleCmd.Wait(); err == nil {
+		t.Errorf("IdleCommand.Wait() = nil, want an error")
+	}
+}
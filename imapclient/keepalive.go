@@ -0,0 +1,53 @@
+package imapclient
+
+import (
+	"time"
+
+	"github.com/luhaoyun888/go-imap-cn"
+)
+
+// keepAlive 在连接空闲时定期发送 NOOP，直到连接关闭。
+func (c *Client) keepAlive(interval time.Duration) {
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+		case <-c.decCh: // 连接已关闭
+			return
+		}
+
+		c.mutex.Lock()
+		wait := interval - time.Since(c.lastCmd)
+		c.mutex.Unlock()
+		if wait <= 0 {
+			c.sendKeepAlive()
+			wait = interval
+		}
+		timer.Reset(wait)
+	}
+}
+
+// sendKeepAlive 在没有其它命令进行时发送 NOOP。
+//
+// 为了不与其它命令（包括 IDLE）交错，检查和发送都在持有 c.encMutex 时完成；
+// 如果有命令正在编码，则跳过本次保活。
+func (c *Client) sendKeepAlive() {
+	if !c.encMutex.TryLock() {
+		return
+	}
+
+	c.mutex.Lock()
+	ok := len(c.pendingCmds) == 0 && !c.closed &&
+		(c.state == imap.ConnStateAuthenticated || c.state == imap.ConnStateSelected)
+	c.mutex.Unlock()
+	if !ok {
+		c.encMutex.Unlock()
+		return
+	}
+
+	// 回复由读取 goroutine 处理，这里无需等待
+	cmd := &Command{}
+	c.beginCommandLocked("NOOP", cmd).end()
+}
@@ -0,0 +1,59 @@
+package imapclient_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/luhaoyun888/go-imap-cn/imapclient"
+)
+
+// TestKeepAlive 测试空闲时自动发送 NOOP，而 IDLE 期间不发送。
+func TestKeepAlive(t *testing.T) {
+	conn, server := newMemClientServerPair(t)
+	defer server.Close()
+
+	var debug lockedBuffer
+	client := imapclient.New(conn, &imapclient.Options{
+		DebugWriter:       &debug,
+		KeepAliveInterval: 20 * time.Millisecond,
+	})
+	defer client.Close()
+
+	// 未认证时不发送 NOOP
+	if err := client.WaitGreeting(); err != nil {
+		t.Fatalf("WaitGreeting() = %v", err)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if s := debug.String(); strings.Contains(s, " NOOP") {
+		t.Fatalf("认证前发送了 NOOP:\n%v", s)
+	}
+
+	if err := client.Login(testUsername, testPassword).Wait(); err != nil {
+		t.Fatalf("Login() = %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(debug.String(), " NOOP") {
+		if time.Now().After(deadline) {
+			t.Fatalf("未发送保活 NOOP:\n%v", debug.String())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	idleCmd, err := client.Idle()
+	if err != nil {
+		t.Fatalf("Idle() = %v", err)
+	}
+	n := len(debug.String())
+	time.Sleep(100 * time.Millisecond)
+	if s := debug.String()[n:]; strings.Contains(s, " NOOP") {
+		t.Errorf("IDLE 期间发送了 NOOP:\n%v", s)
+	}
+	if err := idleCmd.Close(); err != nil {
+		t.Fatalf("IdleCommand.Close() = %v", err)
+	}
+	if err := idleCmd.Wait(); err != nil {
+		t.Fatalf("IdleCommand.Wait() = %v", err)
+	}
+}
@@ -61,10 +61,16 @@ func getReturnOpts(options *imap.ListOptions) []string {
 // nil 的 options 指针等同于零选项值。
 //
 // 非零的选项值要求支持 IMAP4rev2 或 LIST-EXTENDED 扩展。
+//
+// 服务器返回的与 ref 和 pattern 不匹配的邮箱会在客户端被过滤掉。
 func (c *Client) List(ref, pattern string, options *imap.ListOptions) *ListCommand {
 	cmd := &ListCommand{
 		mailboxes:    make(chan *imap.ListData, 64),
 		returnStatus: options != nil && options.ReturnStatus != nil,
+		ref:          ref,
+		pattern:      pattern,
+		// RECURSIVEMATCH 会返回不匹配模式的父邮箱
+		filter: pattern != "" && (options == nil || !options.SelectRecursiveMatch),
 	}
 	enc := c.beginCommand("LIST", cmd)
 	if selectOpts := getSelectOpts(options); len(selectOpts) > 0 {
@@ -89,25 +95,51 @@ func (c *Client) List(ref, pattern string, options *imap.ListOptions) *ListComma
 	return cmd
 }
 
-// handleList 处理 LIST 响应。
-func (c *Client) handleList() error {
+// LSub 发送 LSUB 命令，列出已订阅的邮箱。
+//
+// 调用者必须完全消费 ListCommand。一个简单的方法是延迟调用 ListCommand.Close。
+//
+// IMAP4rev2 已废弃 LSUB，应使用带有 SelectSubscribed 选项的 LIST 命令代替。
+// 此方法用于与只支持 IMAP4rev1 的服务器互操作。
+func (c *Client) LSub(ref, pattern string) *ListCommand {
+	cmd := &ListCommand{
+		mailboxes: make(chan *imap.ListData, 64),
+		ref:       ref,
+		pattern:   pattern,
+		filter:    pattern != "",
+		lsub:      true,
+	}
+	enc := c.beginCommand("LSUB", cmd)
+	enc.SP().Mailbox(ref).SP().Mailbox(pattern) // 设置参考和模式
+	enc.end()
+	return cmd
+}
+
+// handleList 处理 LIST 响应，lsub 为 true 时处理 LSUB 响应。
+func (c *Client) handleList(lsub bool) error {
 	data, err := readList(c.dec) // 读取 LIST 响应
 	if err != nil {
+		if lsub {
+			return fmt.Errorf("in LSUB: %v", err)
+		}
 		return fmt.Errorf("in LIST: %v", err)
 	}
 
 	cmd := c.findPendingCmdFunc(func(cmd command) bool {
 		switch cmd := cmd.(type) {
 		case *ListCommand:
-			return true // TODO: 匹配模式，检查是否已处理
+			return cmd.lsub == lsub
 		case *SelectCommand:
-			return cmd.mailbox == data.Mailbox && cmd.data.List == nil
+			return !lsub && cmd.mailbox == data.Mailbox && cmd.data.List == nil
 		default:
 			return false
 		}
 	})
 	switch cmd := cmd.(type) {
 	case *ListCommand:
+		if !cmd.match(data) {
+			break // 丢弃服务器多返回的邮箱
+		}
 		if cmd.returnStatus {
 			if cmd.pendingData != nil {
 				cmd.mailboxes <- cmd.pendingData
@@ -123,13 +155,29 @@ func (c *Client) handleList() error {
 	return nil
 }
 
-// ListCommand 是 LIST 命令的结构体。
+// ListCommand 是 LIST 或 LSUB 命令的结构体。
 type ListCommand struct {
 	commandBase
 	mailboxes chan *imap.ListData // 存储邮箱数据的通道
 
 	returnStatus bool           // 是否返回状态
 	pendingData  *imap.ListData // 等待的 LIST 数据
+
+	ref, pattern string // 命令的引用和模式
+	filter       bool   // 是否按模式过滤服务器返回的邮箱
+	lsub         bool   // 是否为 LSUB 命令
+}
+
+// match 检查服务器返回的邮箱是否与命令的引用和模式匹配。
+func (cmd *ListCommand) match(data *imap.ListData) bool {
+	if !cmd.filter {
+		return true
+	}
+	if strings.EqualFold(data.Mailbox, "INBOX") {
+		// INBOX 不区分大小写
+		return internal.MatchList("INBOX", data.Delim, strings.ToUpper(cmd.ref), strings.ToUpper(cmd.pattern))
+	}
+	return internal.MatchList(data.Mailbox, data.Delim, cmd.ref, cmd.pattern)
 }
 
 // Next 前进到下一个邮箱。
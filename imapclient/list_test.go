@@ -2,6 +2,7 @@ package imapclient_test
 
 import (
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/luhaoyun888/go-imap-cn"
@@ -41,3 +42,73 @@ func TestList(t *testing.T) {
 		t.Errorf("got %#v but want %#v", mbox, want) // 输出不匹配的错误信息
 	}
 }
+
+func TestList_filter(t *testing.T) {
+	client := newScriptedClient(t, "", func(cmd string) []string {
+		if !strings.HasPrefix(cmd, "LIST ") {
+			return nil
+		}
+		// 服务器忽略模式，返回所有层级的邮箱
+		return []string{
+			`* LIST () "/" INBOX`,
+			`* LIST () "/" Archive`,
+			`* LIST () "/" Archive/2020`,
+			`* LIST () "/" Archive/2020/Q1`,
+			`* LIST () "/" Sent`,
+		}
+	})
+
+	for _, tc := range []struct {
+		ref, pattern string
+		want         []string
+	}{
+		{"", "%", []string{"INBOX", "Archive", "Sent"}},
+		{"", "*", []string{"INBOX", "Archive", "Archive/2020", "Archive/2020/Q1", "Sent"}},
+		{"", "Archive/%", []string{"Archive/2020"}},
+		{"Archive", "*", []string{"Archive/2020", "Archive/2020/Q1"}},
+		{"", "S*", []string{"Sent"}},
+		{"", "inbox", []string{"INBOX"}},
+	} {
+		mailboxes, err := client.List(tc.ref, tc.pattern, nil).Collect()
+		if err != nil {
+			t.Fatalf("List(%q, %q) = %v", tc.ref, tc.pattern, err)
+		}
+		var got []string
+		for _, mbox := range mailboxes {
+			got = append(got, mbox.Mailbox)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("List(%q, %q) = %v, want %v", tc.ref, tc.pattern, got, tc.want)
+		}
+	}
+}
+
+func TestLSub(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateAuthenticated)
+	defer client.Close()
+	defer server.Close()
+
+	for _, name := range []string{"Archive", "Drafts"} {
+		if err := client.Create(name, nil).Wait(); err != nil {
+			t.Fatalf("Create(%q) = %v", name, err)
+		}
+	}
+	if err := client.Subscribe("Archive").Wait(); err != nil {
+		t.Fatalf("Subscribe() = %v", err)
+	}
+
+	mailboxes, err := client.LSub("", "*").Collect()
+	if err != nil {
+		t.Fatalf("LSub() = %v", err)
+	}
+	var got []string
+	for _, mbox := range mailboxes {
+		got = append(got, mbox.Mailbox)
+	}
+	if want := []string{"Archive"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("LSub() = %v, want %v", got, want)
+	}
+	if len(mailboxes) > 0 && mailboxes[0].Delim != '/' {
+		t.Errorf("LSub() delim = %q, want '/'", mailboxes[0].Delim)
+	}
+}
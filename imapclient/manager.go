@@ -0,0 +1,314 @@
+package imapclient
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/luhaoyun888/go-imap-cn"
+)
+
+// managerEventBufferSize 是 Manager 事件通道的缓冲区大小。
+const managerEventBufferSize = 64
+
+// ManagerEvent 是 Manager 从某个账户收到的单边更新。
+//
+// 除 Account 外，每个事件只设置一个字段。
+type ManagerEvent struct {
+	Account string // 账户名称
+
+	Expunge  uint32                 // EXPUNGE 响应中的序号
+	Mailbox  *UnilateralDataMailbox // 邮箱状态更新，例如 EXISTS
+	Fetch    *FetchMessageBuffer    // FETCH 响应，例如其他连接修改了标志
+	Vanished *ManagerVanished       // VANISHED 响应（需要启用 QRESYNC）
+	Metadata *ManagerMetadata       // METADATA 响应（需要 METADATA 或 METADATA-SERVER）
+	Alert    string                 // ALERT 响应码的文本
+}
+
+// ManagerVanished 是 VANISHED 响应的数据。
+type ManagerVanished struct {
+	UIDs    imap.UIDSet // 已删除邮件的 UID
+	Earlier bool        // 是否为 VANISHED (EARLIER)
+}
+
+// ManagerMetadata 是单边 METADATA 响应的数据。
+type ManagerMetadata struct {
+	Mailbox string   // 邮箱名称，为空表示服务器元数据
+	Entries []string // 发生变化的条目
+}
+
+// Manager 管理多个命名账户的客户端连接，并把所有连接的单边更新聚合到一个事件流中，
+// 适用于统一收件箱等需要同时访问多个账户或服务器的应用。
+//
+// 每个账户对应一个普通的 Client，可以通过 Client 方法取得并直接发送命令。
+type Manager struct {
+	events chan ManagerEvent
+	done   chan struct{} // Close 时关闭
+
+	// 保护 events 的关闭：正在添加的账户可能在 Close 之后才发送事件
+	eventsMutex  sync.RWMutex
+	eventsClosed bool
+
+	mutex    sync.Mutex
+	accounts map[string]*managerAccount
+	autoIdle bool
+	closed   bool
+}
+
+// managerAccount 是 Manager 中的一个账户。
+type managerAccount struct {
+	client *Client
+	done   chan struct{} // 账户被移除时关闭，解除阻塞的事件发送
+}
+
+// NewManager 创建一个新的 Manager。
+func NewManager() *Manager {
+	return &Manager{
+		events:   make(chan ManagerEvent, managerEventBufferSize),
+		done:     make(chan struct{}),
+		accounts: make(map[string]*managerAccount),
+	}
+}
+
+// Add 添加一个名为 name 的账户。
+//
+// dial 必须使用传入的选项创建客户端，例如：
+//
+//	m.Add("work", nil, func(options *imapclient.Options) (*imapclient.Client, error) {
+//		return imapclient.DialTLS("imap.example.org:993", options)
+//	})
+//
+// 传入 dial 的选项是 options 的副本，其中的 UnilateralDataHandler 被替换为
+// 先调用 options 中原有的处理函数、再把更新发送到 Events 的处理函数。
+// FetchMessageData 只能读取一次，因此 options 中设置了 Fetch 时，FETCH 更新不会发送到 Events。
+// 返回的客户端尚未认证，调用者需要自行登录和选择邮箱。
+func (m *Manager) Add(name string, options *Options, dial func(options *Options) (*Client, error)) (*Client, error) {
+	if err := m.checkAccountName(name); err != nil {
+		return nil, err
+	}
+
+	acct := &managerAccount{done: make(chan struct{})}
+	var clientOptions Options
+	if options != nil {
+		clientOptions = *options
+	}
+	clientOptions.UnilateralDataHandler = m.unilateralDataHandler(name, acct, clientOptions.UnilateralDataHandler)
+
+	client, err := dial(&clientOptions)
+	if err != nil {
+		return nil, err
+	}
+	acct.client = client
+
+	m.mutex.Lock()
+	err = m.checkAccountNameLocked(name)
+	if err == nil {
+		m.accounts[name] = acct
+	}
+	autoIdle := m.autoIdle
+	m.mutex.Unlock()
+	if err != nil {
+		close(acct.done)
+		client.Close()
+		return nil, err
+	}
+
+	if autoIdle {
+		client.AutoIdle(true)
+	}
+	return client, nil
+}
+
+// checkAccountName 检查是否可以添加名为 name 的账户。
+func (m *Manager) checkAccountName(name string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.checkAccountNameLocked(name)
+}
+
+func (m *Manager) checkAccountNameLocked(name string) error {
+	if m.closed {
+		return fmt.Errorf("imapclient: Manager 已关闭")
+	} else if _, ok := m.accounts[name]; ok {
+		return fmt.Errorf("imapclient: 账户 %q 已存在", name)
+	}
+	return nil
+}
+
+// unilateralDataHandler 返回把单边更新发送到事件流的处理函数。
+func (m *Manager) unilateralDataHandler(name string, acct *managerAccount, next *UnilateralDataHandler) *UnilateralDataHandler {
+	if next == nil {
+		next = &UnilateralDataHandler{}
+	}
+	send := func(ev ManagerEvent) {
+		ev.Account = name
+		m.eventsMutex.RLock()
+		defer m.eventsMutex.RUnlock()
+		if m.eventsClosed {
+			return
+		}
+		select {
+		case m.events <- ev:
+		case <-acct.done:
+		case <-m.done:
+		}
+	}
+	return &UnilateralDataHandler{
+		Expunge: func(seqNum uint32) {
+			if next.Expunge != nil {
+				next.Expunge(seqNum)
+			}
+			send(ManagerEvent{Expunge: seqNum})
+		},
+		Mailbox: func(data *UnilateralDataMailbox) {
+			if next.Mailbox != nil {
+				next.Mailbox(data)
+			}
+			send(ManagerEvent{Mailbox: data})
+		},
+		Fetch: func(msg *FetchMessageData) {
+			if next.Fetch != nil {
+				next.Fetch(msg) // FetchMessageData 只能读取一次
+				return
+			}
+			buf, err := msg.Collect()
+			if err != nil {
+				return
+			}
+			send(ManagerEvent{Fetch: buf})
+		},
+		Metadata: func(mailbox string, entries []string) {
+			if next.Metadata != nil {
+				next.Metadata(mailbox, entries)
+			}
+			send(ManagerEvent{Metadata: &ManagerMetadata{Mailbox: mailbox, Entries: entries}})
+		},
+		Vanished: func(uids imap.UIDSet, earlier bool) {
+			if next.Vanished != nil {
+				next.Vanished(uids, earlier)
+			}
+			send(ManagerEvent{Vanished: &ManagerVanished{UIDs: uids, Earlier: earlier}})
+		},
+		Alert: func(text string) {
+			if next.Alert != nil {
+				next.Alert(text)
+			}
+			send(ManagerEvent{Alert: text})
+		},
+	}
+}
+
+// Events 返回聚合所有账户单边更新的事件通道。Close 之后通道被关闭。
+//
+// 调用者必须持续读取此通道：缓冲区满时，客户端会阻塞在读取服务器响应上。
+func (m *Manager) Events() <-chan ManagerEvent {
+	return m.events
+}
+
+// Client 返回名为 name 的账户的客户端，账户不存在时返回 nil。
+func (m *Manager) Client(name string) *Client {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if acct := m.accounts[name]; acct != nil {
+		return acct.client
+	}
+	return nil
+}
+
+// Accounts 返回所有账户的名称，按名称排序。
+func (m *Manager) Accounts() []string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	names := make([]string, 0, len(m.accounts))
+	for name := range m.accounts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Remove 移除名为 name 的账户并关闭其连接。
+func (m *Manager) Remove(name string) error {
+	m.mutex.Lock()
+	acct := m.accounts[name]
+	delete(m.accounts, name)
+	m.mutex.Unlock()
+	if acct == nil {
+		return fmt.Errorf("imapclient: 账户 %q 不存在", name)
+	}
+	close(acct.done)
+	return acct.client.Close()
+}
+
+// ForEach 在每个账户上并发调用 f，并等待所有调用返回。
+//
+// 返回值包含 f 返回错误的账户及其错误，全部成功时为 nil。
+func (m *Manager) ForEach(f func(name string, client *Client) error) map[string]error {
+	m.mutex.Lock()
+	accounts := make(map[string]*Client, len(m.accounts))
+	for name, acct := range m.accounts {
+		accounts[name] = acct.client
+	}
+	m.mutex.Unlock()
+
+	var (
+		wg       sync.WaitGroup
+		errMutex sync.Mutex
+		errs     map[string]error
+	)
+	for name, client := range accounts {
+		wg.Add(1)
+		go func(name string, client *Client) {
+			defer wg.Done()
+			if err := f(name, client); err != nil {
+				errMutex.Lock()
+				if errs == nil {
+					errs = make(map[string]error)
+				}
+				errs[name] = err
+				errMutex.Unlock()
+			}
+		}(name, client)
+	}
+	wg.Wait()
+	return errs
+}
+
+// AutoIdle 在所有账户（包括之后添加的账户）上启用或禁用自动 IDLE，参见 Client.AutoIdle。
+func (m *Manager) AutoIdle(enable bool) {
+	m.mutex.Lock()
+	m.autoIdle = enable
+	m.mutex.Unlock()
+
+	m.ForEach(func(name string, client *Client) error {
+		client.AutoIdle(enable)
+		return nil
+	})
+}
+
+// Close 关闭所有账户的连接，并关闭事件通道。
+func (m *Manager) Close() error {
+	m.mutex.Lock()
+	if m.closed {
+		m.mutex.Unlock()
+		return nil
+	}
+	m.closed = true
+	accounts := m.accounts
+	m.accounts = nil
+	m.mutex.Unlock()
+	close(m.done)
+
+	var firstErr error
+	for _, acct := range accounts {
+		close(acct.done)
+		if err := acct.client.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	m.eventsMutex.Lock()
+	m.eventsClosed = true
+	close(m.events)
+	m.eventsMutex.Unlock()
+	return firstErr
+}
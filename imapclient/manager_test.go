@@ -0,0 +1,128 @@
+package imapclient_test
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/luhaoyun888/go-imap-cn"
+	"github.com/luhaoyun888/go-imap-cn/imapclient"
+)
+
+// TestManager_idle 测试 Manager 把两个账户的并发 IDLE 更新聚合到一个事件流中。
+func TestManager_idle(t *testing.T) {
+	m := imapclient.NewManager()
+	defer m.Close()
+
+	accounts := []string{"personal", "work"}
+	addrs := make(map[string]string)
+	debugs := make(map[string]*lockedBuffer)
+	for _, name := range accounts {
+		conn, server := newMemClientServerPair(t)
+		defer server.Close()
+		addrs[name] = conn.RemoteAddr().String()
+		debug := &lockedBuffer{}
+		debugs[name] = debug
+
+		_, err := m.Add(name, &imapclient.Options{DebugWriter: debug}, func(options *imapclient.Options) (*imapclient.Client, error) {
+			return imapclient.New(conn, options), nil
+		})
+		if err != nil {
+			t.Fatalf("Add(%q) = %v", name, err)
+		}
+	}
+	if _, err := m.Add("work", nil, nil); err == nil {
+		t.Errorf("Add(重复的账户) = nil, want error")
+	}
+	if got := m.Accounts(); strings.Join(got, ",") != strings.Join(accounts, ",") {
+		t.Errorf("Accounts() = %v, want %v", got, accounts)
+	}
+
+	errs := m.ForEach(func(name string, client *imapclient.Client) error {
+		if err := client.Login(testUsername, testPassword).Wait(); err != nil {
+			return err
+		}
+		_, err := client.Select("INBOX", nil).Wait()
+		return err
+	})
+	if errs != nil {
+		t.Fatalf("ForEach(登录并选择) = %v", errs)
+	}
+
+	m.AutoIdle(true)
+	for _, name := range accounts {
+		waitDebug(t, debugs[name], func(s string) bool {
+			return strings.Contains(s, " IDLE\r\n")
+		})
+	}
+
+	// 通过其他连接同时向两个账户追加邮件
+	appendErrs := make(chan error, len(accounts))
+	for _, name := range accounts {
+		go func(name string) {
+			appendErrs <- appendFromOtherConn(addrs[name])
+		}(name)
+	}
+	for range accounts {
+		if err := <-appendErrs; err != nil {
+			t.Fatalf("追加邮件失败: %v", err)
+		}
+	}
+
+	got := make(map[string]uint32)
+	timeout := time.After(5 * time.Second)
+	for len(got) < len(accounts) {
+		select {
+		case ev := <-m.Events():
+			if ev.Mailbox != nil && ev.Mailbox.NumMessages != nil {
+				got[ev.Account] = *ev.Mailbox.NumMessages
+			}
+		case <-timeout:
+			t.Fatalf("等待 IDLE 更新超时，已收到 %v", got)
+		}
+	}
+	for _, name := range accounts {
+		if got[name] != 1 {
+			t.Errorf("账户 %q 的 NumMessages = %v, want 1", name, got[name])
+		}
+	}
+
+	if err := m.Remove("work"); err != nil {
+		t.Errorf("Remove() = %v", err)
+	}
+	if m.Client("work") != nil || m.Client("personal") == nil {
+		t.Errorf("Remove() 之后 Accounts() = %v", m.Accounts())
+	}
+
+	if err := m.Close(); err != nil {
+		t.Errorf("Close() = %v", err)
+	}
+	for range m.Events() {
+		// 丢弃剩余的事件
+	}
+	if _, err := m.Add("other", nil, nil); err == nil {
+		t.Errorf("Close() 之后 Add() = nil, want error")
+	}
+}
+
+// appendFromOtherConn 通过新的连接向 INBOX 追加一封邮件。
+func appendFromOtherConn(addr string) error {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return err
+	}
+	client := imapclient.New(conn, nil)
+	defer client.Close()
+	if err := client.Login(testUsername, testPassword).Wait(); err != nil {
+		return fmt.Errorf("Login(): %w", err)
+	}
+	appendCmd := client.Append("INBOX", int64(len(simpleRawMessage)), &imap.AppendOptions{})
+	appendCmd.Write([]byte(simpleRawMessage))
+	appendCmd.Close()
+	if _, err := appendCmd.Wait(); err != nil {
+		return fmt.Errorf("Append(): %w", err)
+	}
+	return nil
+}
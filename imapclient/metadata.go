@@ -3,40 +3,24 @@ package imapclient
 import (
 	"fmt"
 
+	"github.com/luhaoyun888/go-imap-cn"
 	"github.com/luhaoyun888/go-imap-cn/internal/imapwire"
 )
 
 // GetMetadataDepth 表示获取元数据的深度。
-type GetMetadataDepth int
+type GetMetadataDepth = imap.GetMetadataDepth
 
 const (
-	GetMetadataDepthZero     GetMetadataDepth = 0  // 不获取任何元数据
-	GetMetadataDepthOne      GetMetadataDepth = 1  // 仅获取一级元数据
-	GetMetadataDepthInfinity GetMetadataDepth = -1 // 获取所有元数据
+	GetMetadataDepthZero     = imap.GetMetadataDepthZero     // 只获取指定的条目
+	GetMetadataDepthOne      = imap.GetMetadataDepthOne      // 同时获取条目的直接子条目
+	GetMetadataDepthInfinity = imap.GetMetadataDepthInfinity // 同时获取条目的所有子条目
 )
 
-// String 返回 GetMetadataDepth 的字符串表示。
-func (depth GetMetadataDepth) String() string {
-	switch depth {
-	case GetMetadataDepthZero:
-		return "0"
-	case GetMetadataDepthOne:
-		return "1"
-	case GetMetadataDepthInfinity:
-		return "infinity"
-	default:
-		panic(fmt.Errorf("imapclient: unknown GETMETADATA depth %d", depth))
-	}
-}
-
 // GetMetadataOptions 包含 GETMETADATA 命令的选项。
-type GetMetadataOptions struct {
-	MaxSize *uint32          // 最大大小选项
-	Depth   GetMetadataDepth // 获取深度选项
-}
+type GetMetadataOptions = imap.GetMetadataOptions
 
-// names 返回 GETMETADATA 选项的名称列表。
-func (options *GetMetadataOptions) names() []string {
+// getMetadataOptionNames 返回 GETMETADATA 选项的名称列表。
+func getMetadataOptionNames(options *GetMetadataOptions) []string {
 	if options == nil {
 		return nil
 	}
@@ -57,7 +41,7 @@ func (c *Client) GetMetadata(mailbox string, entries []string, options *GetMetad
 	cmd := &GetMetadataCommand{mailbox: mailbox}
 	enc := c.beginCommand("GETMETADATA", cmd)
 	enc.SP().Mailbox(mailbox)
-	if opts := options.names(); len(opts) > 0 {
+	if opts := getMetadataOptionNames(options); len(opts) > 0 {
 		enc.SP().List(len(opts), func(i int) {
 			opt := opts[i]
 			enc.Atom(opt).SP()
@@ -96,7 +80,7 @@ func (c *Client) SetMetadata(mailbox string, entries map[string]*[]byte) *Comman
 		if v == nil {
 			enc.NIL() // 设置为 nil
 		} else {
-			enc.String(string(*v)) // TODO: 根据需要使用字面量
+			writeMetadataValue(enc, *v)
 		}
 		i++
 	}
@@ -105,6 +89,38 @@ func (c *Client) SetMetadata(mailbox string, entries map[string]*[]byte) *Comman
 	return cmd
 }
 
+// metadataQuotedMaxSize 是使用 quoted string 写入的元数据值的最大长度。
+const metadataQuotedMaxSize = 1024
+
+// writeMetadataValue 写入元数据值。
+//
+// 元数据值可以是任意二进制数据。值含有控制字符、非 ASCII 字节或较大时，
+// 使用字面量写入，避免 quoted string 损坏数据或被服务器拒绝。
+func writeMetadataValue(enc *commandEncoder, v []byte) {
+	if !metadataValueNeedsLiteral(v) {
+		enc.Quoted(string(v))
+		return
+	}
+	wc := enc.Literal(int64(len(v)))
+	if _, err := wc.Write(v); err != nil {
+		return
+	}
+	wc.Close()
+}
+
+// metadataValueNeedsLiteral 判断元数据值是否需要以字面量写入。
+func metadataValueNeedsLiteral(v []byte) bool {
+	if len(v) > metadataQuotedMaxSize {
+		return true
+	}
+	for _, ch := range v {
+		if ch < 0x20 || ch >= 0x7f {
+			return true
+		}
+	}
+	return false
+}
+
 // handleMetadata 处理元数据响应。
 func (c *Client) handleMetadata() error {
 	data, err := readMetadataResp(c.dec) // 读取元数据响应
@@ -171,14 +187,15 @@ func readMetadataResp(dec *imapwire.Decoder) (*metadataResp, error) {
 			return dec.Err()
 		}
 
-		// TODO: 以 []byte 解码
+		// 值以字节解码，二进制数据（包括 NUL）保持不变
 		var (
 			value *[]byte
-			s     string
+			b     []byte
 		)
-		if dec.String(&s) || dec.Literal(&s) {
-			b := []byte(s)
+		if dec.StringBytes(&b) {
 			value = &b
+		} else if dec.Err() != nil {
+			return dec.Err()
 		} else if !dec.ExpectNIL() {
 			return dec.Err()
 		}
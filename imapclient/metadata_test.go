@@ -0,0 +1,151 @@
+package imapclient_test
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/luhaoyun888/go-imap-cn"
+	"github.com/luhaoyun888/go-imap-cn/imapclient"
+)
+
+func metadataValue(s string) *[]byte {
+	b := []byte(s)
+	return &b
+}
+
+// TestMetadata 测试服务器和邮箱元数据的设置、查询、深度、MAXSIZE 与删除。
+func TestMetadata(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateAuthenticated)
+	defer client.Close()
+	defer server.Close()
+
+	if !client.Caps().Has(imap.CapMetadata) || !client.Caps().Has(imap.CapMetadataServer) {
+		t.Fatalf("server doesn't advertise METADATA and METADATA-SERVER")
+	}
+
+	// 服务器元数据
+	err := client.SetMetadata("", map[string]*[]byte{
+		"/shared/comment": metadataValue("Hello"),
+	}).Wait()
+	if err != nil {
+		t.Fatalf("SetMetadata(\"\") = %v", err)
+	}
+	data, err := client.GetMetadata("", []string{"/shared/comment"}, nil).Wait()
+	if err != nil {
+		t.Fatalf("GetMetadata(\"\") = %v", err)
+	} else if want := map[string]*[]byte{"/shared/comment": metadataValue("Hello")}; !reflect.DeepEqual(data.Entries, want) {
+		t.Errorf("GetMetadata(\"\") = %v, want %v", data.Entries, want)
+	}
+
+	// 邮箱元数据，条目名称不区分大小写
+	err = client.SetMetadata("INBOX", map[string]*[]byte{
+		"/private/Comment":     metadataValue("My own comment"),
+		"/private/comment/a":   metadataValue("A"),
+		"/private/comment/a/b": metadataValue("B"),
+	}).Wait()
+	if err != nil {
+		t.Fatalf("SetMetadata(INBOX) = %v", err)
+	}
+
+	for _, tc := range []struct {
+		depth imapclient.GetMetadataDepth
+		want  []string
+	}{
+		{imapclient.GetMetadataDepthZero, []string{"/private/comment"}},
+		{imapclient.GetMetadataDepthOne, []string{"/private/comment", "/private/comment/a"}},
+		{imapclient.GetMetadataDepthInfinity, []string{"/private/comment", "/private/comment/a", "/private/comment/a/b"}},
+	} {
+		options := imapclient.GetMetadataOptions{Depth: tc.depth}
+		data, err := client.GetMetadata("INBOX", []string{"/private/comment"}, &options).Wait()
+		if err != nil {
+			t.Fatalf("GetMetadata(INBOX, DEPTH %v) = %v", tc.depth, err)
+		}
+		for _, name := range tc.want {
+			if data.Entries[name] == nil {
+				t.Errorf("GetMetadata(INBOX, DEPTH %v): missing entry %v", tc.depth, name)
+			}
+		}
+		if len(data.Entries) != len(tc.want) {
+			t.Errorf("GetMetadata(INBOX, DEPTH %v) = %v, want entries %v", tc.depth, data.Entries, tc.want)
+		}
+	}
+
+	// 超过 MAXSIZE 的值被省略
+	maxSize := uint32(2)
+	options := imapclient.GetMetadataOptions{MaxSize: &maxSize, Depth: imapclient.GetMetadataDepthOne}
+	data, err = client.GetMetadata("INBOX", []string{"/private/comment"}, &options).Wait()
+	if err != nil {
+		t.Fatalf("GetMetadata(INBOX, MAXSIZE 2) = %v", err)
+	} else if want := map[string]*[]byte{"/private/comment/a": metadataValue("A")}; !reflect.DeepEqual(data.Entries, want) {
+		t.Errorf("GetMetadata(INBOX, MAXSIZE 2) = %v, want %v", data.Entries, want)
+	}
+
+	// 值为 nil 时删除条目，不存在的条目返回 NIL
+	err = client.SetMetadata("INBOX", map[string]*[]byte{"/private/comment/a": nil}).Wait()
+	if err != nil {
+		t.Fatalf("SetMetadata(INBOX, NIL) = %v", err)
+	}
+	data, err = client.GetMetadata("INBOX", []string{"/private/comment/a", "/shared/missing"}, nil).Wait()
+	if err != nil {
+		t.Fatalf("GetMetadata(INBOX) = %v", err)
+	} else if want := map[string]*[]byte{"/private/comment/a": nil, "/shared/missing": nil}; !reflect.DeepEqual(data.Entries, want) {
+		t.Errorf("GetMetadata(INBOX) after delete = %v, want %v", data.Entries, want)
+	}
+
+	// 邮箱不存在
+	if err := client.SetMetadata("Missing", map[string]*[]byte{"/private/comment": metadataValue("x")}).Wait(); err == nil {
+		t.Errorf("SetMetadata(Missing) = nil, want error")
+	}
+}
+
+// TestMetadata_literal 测试含有换行和控制字符的元数据值以字面量写入，并且往返后保持不变。
+func TestMetadata_literal(t *testing.T) {
+	conn, server := newMemClientServerPair(t)
+	defer server.Close()
+
+	var debug lockedBuffer
+	client := imapclient.New(conn, &imapclient.Options{DebugWriter: &debug})
+	defer client.Close()
+
+	if err := client.Login(testUsername, testPassword).Wait(); err != nil {
+		t.Fatalf("Login().Wait() = %v", err)
+	}
+
+	value := []byte("line 1\r\nline 2\r\n\x01\xff")
+	err := client.SetMetadata("INBOX", map[string]*[]byte{"/private/comment": &value}).Wait()
+	if err != nil {
+		t.Fatalf("SetMetadata() = %v", err)
+	}
+	if s, want := debug.String(), fmt.Sprintf(`SETMETADATA INBOX ("/private/comment" {%v`, len(value)); !strings.Contains(s, want) {
+		t.Errorf("调试输出不包含 %q:\n%v", want, s)
+	}
+
+	data, err := client.GetMetadata("INBOX", []string{"/private/comment"}, nil).Wait()
+	if err != nil {
+		t.Fatalf("GetMetadata() = %v", err)
+	} else if got := data.Entries["/private/comment"]; got == nil || string(*got) != string(value) {
+		t.Errorf("GetMetadata() = %v, want %q", data.Entries, value)
+	}
+}
+
+// TestMetadata_binary 测试含有 NUL 字节的二进制元数据值往返后保持不变。
+func TestMetadata_binary(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateAuthenticated)
+	defer client.Close()
+	defer server.Close()
+
+	value := []byte{'a', 0, 'b', 0, 0, 0xff, '\r', '\n', 0}
+	err := client.SetMetadata("", map[string]*[]byte{"/private/binary": &value}).Wait()
+	if err != nil {
+		t.Fatalf("SetMetadata() = %v", err)
+	}
+
+	data, err := client.GetMetadata("", []string{"/private/binary"}, nil).Wait()
+	if err != nil {
+		t.Fatalf("GetMetadata() = %v", err)
+	} else if got := data.Entries["/private/binary"]; got == nil || !reflect.DeepEqual(*got, value) {
+		t.Errorf("GetMetadata() = %v, want %v", data.Entries, value)
+	}
+}
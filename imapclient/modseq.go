@@ -0,0 +1,70 @@
+package imapclient
+
+import (
+	"sort"
+
+	"github.com/luhaoyun888/go-imap-cn"
+)
+
+// SortByModSeq 按修改序列号升序对 FETCH 结果进行稳定排序（需要 CONDSTORE 支持）。
+//
+// 服务器按消息顺序而不是修改顺序返回 FETCH 响应。同步本地缓存时，按排序后的
+// 顺序依次应用 FLAGS，较新的变更总会覆盖较旧的变更。没有 MODSEQ 的消息排在最前面。
+func SortByModSeq(msgs []*FetchMessageBuffer) {
+	sort.SliceStable(msgs, func(i, j int) bool {
+		return msgs[i].ModSeq < msgs[j].ModSeq
+	})
+}
+
+// FlagCache 按 UID 缓存消息的标志，并按修改序列号增量应用标志变更。
+//
+// 标志更新可能乱序到达，例如 UID FETCH CHANGEDSINCE 的结果与 NOOP 期间收到的
+// 单方面 FETCH 交错。FlagCache 记录每条消息最后应用的修改序列号，忽略过时的更新。
+// 零值可以直接使用。
+type FlagCache struct {
+	// HighestModSeq 是已应用的更新中最高的修改序列号，可用于下一次 CHANGEDSINCE
+	HighestModSeq uint64
+
+	messages map[imap.UID]flagCacheEntry
+}
+
+type flagCacheEntry struct {
+	flags  []imap.Flag
+	modSeq uint64
+}
+
+// Apply 应用一条 FETCH 结果中的标志。buf 必须包含 UID、FLAGS 和 MODSEQ。
+//
+// 如果 buf 缺少 UID 或 MODSEQ，或者其修改序列号不大于该消息已应用的修改
+// 序列号，则忽略该更新并返回 false。
+func (cache *FlagCache) Apply(buf *FetchMessageBuffer) bool {
+	if buf.UID == 0 || buf.ModSeq == 0 {
+		return false
+	}
+	if entry, ok := cache.messages[buf.UID]; ok && entry.modSeq >= buf.ModSeq {
+		return false // 过时的更新
+	}
+
+	if cache.messages == nil {
+		cache.messages = make(map[imap.UID]flagCacheEntry)
+	}
+	cache.messages[buf.UID] = flagCacheEntry{
+		flags:  append([]imap.Flag(nil), buf.Flags...),
+		modSeq: buf.ModSeq,
+	}
+	if buf.ModSeq > cache.HighestModSeq {
+		cache.HighestModSeq = buf.ModSeq
+	}
+	return true
+}
+
+// Flags 返回消息的标志及其修改序列号。如果没有应用过该消息的更新，ok 为 false。
+func (cache *FlagCache) Flags(uid imap.UID) (flags []imap.Flag, modSeq uint64, ok bool) {
+	entry, ok := cache.messages[uid]
+	return entry.flags, entry.modSeq, ok
+}
+
+// Remove 从缓存中删除消息，例如在收到 EXPUNGE 或 VANISHED 之后。
+func (cache *FlagCache) Remove(uid imap.UID) {
+	delete(cache.messages, uid)
+}
@@ -0,0 +1,70 @@
+package imapclient_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/luhaoyun888/go-imap-cn"
+	"github.com/luhaoyun888/go-imap-cn/imapclient"
+)
+
+// TestFlagCache 测试乱序到达的标志更新按修改序列号排序和应用。
+func TestFlagCache(t *testing.T) {
+	client := newScriptedClient(t, " CONDSTORE", func(cmd string) []string {
+		if !strings.HasPrefix(cmd, "UID FETCH ") {
+			return nil
+		}
+		return []string{
+			`* 1 FETCH (UID 10 MODSEQ (7) FLAGS (\Seen \Flagged))`,
+			`* 2 FETCH (UID 11 MODSEQ (5) FLAGS ())`,
+			`* 3 FETCH (UID 12 MODSEQ (6) FLAGS (\Seen))`,
+		}
+	})
+
+	if _, err := client.Select("INBOX", &imap.SelectOptions{CondStore: true}).Wait(); err != nil {
+		t.Fatalf("Select() = %v", err)
+	}
+
+	options := imap.FetchOptions{UID: true, Flags: true, ModSeq: true, ChangedSince: 1}
+	msgs, err := client.Fetch(imap.UIDSet{imap.UIDRange{Start: 1, Stop: 0}}, &options).Collect()
+	if err != nil {
+		t.Fatalf("Fetch().Collect() = %v", err)
+	} else if len(msgs) != 3 {
+		t.Fatalf("len(msgs) = %v, want 3", len(msgs))
+	}
+
+	imapclient.SortByModSeq(msgs)
+	var modSeqs []uint64
+	for _, msg := range msgs {
+		modSeqs = append(modSeqs, msg.ModSeq)
+	}
+	if want := []uint64{5, 6, 7}; !reflect.DeepEqual(modSeqs, want) {
+		t.Errorf("SortByModSeq() = %v, want %v", modSeqs, want)
+	}
+	var cache imapclient.FlagCache
+	for _, msg := range msgs {
+		if !cache.Apply(msg) {
+			t.Errorf("Apply(MODSEQ %v) = false, want true", msg.ModSeq)
+		}
+	}
+
+	// 较晚到达的过时更新被忽略
+	stale := imapclient.FetchMessageBuffer{SeqNum: 1, UID: 10, ModSeq: 6, Flags: []imap.Flag{imap.FlagSeen}}
+	if cache.Apply(&stale) {
+		t.Errorf("Apply(stale) = true, want false")
+	}
+
+	flags, modSeq, ok := cache.Flags(10)
+	if want := []imap.Flag{imap.FlagSeen, imap.FlagFlagged}; !ok || modSeq != 7 || !reflect.DeepEqual(flags, want) {
+		t.Errorf("Flags(10) = %v, %v, %v, want %v, 7, true", flags, modSeq, ok, want)
+	}
+	if cache.HighestModSeq != 7 {
+		t.Errorf("HighestModSeq = %v, want 7", cache.HighestModSeq)
+	}
+
+	cache.Remove(10)
+	if _, _, ok := cache.Flags(10); ok {
+		t.Errorf("Flags(10) after Remove() = ok, want not found")
+	}
+}
@@ -8,7 +8,13 @@ import (
 // Move 发送 MOVE 命令。
 //
 // 如果服务器不支持 IMAP4rev2 或 MOVE 扩展，则使用 COPY + STORE + EXPUNGE 命令作为回退方案。
+//
+// numSet 可以是 imap.SearchRes()，表示上次 SEARCH RETURN (SAVE) 保存的结果。
 func (c *Client) Move(numSet imap.NumSet, mailbox string) *MoveCommand {
+	if err := c.checkSearchRes(numSet); err != nil {
+		return &MoveCommand{commandBase: newFailedCommandBase(err)}
+	}
+
 	// 如果服务器不支持 MOVE，则回退到 [UID] COPY，
 	// [UID] STORE +FLAGS.SILENT \Deleted 和 [UID] EXPUNGE
 	cmdName := "MOVE"
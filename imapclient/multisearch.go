@@ -0,0 +1,84 @@
+package imapclient
+
+import (
+	"fmt"
+
+	"github.com/luhaoyun888/go-imap-cn"
+)
+
+// ESearch 发送一个 ESEARCH 命令，在 sources 指定的多个邮箱中搜索邮件（RFC 7377）。
+//
+// sources 为空时只搜索当前选择的邮箱。每个邮箱返回一个 imap.MultiSearchData（服务器可以省略没有结果的邮箱），
+// 结果中的编号始终为 UID。此命令需要 MULTISEARCH 扩展，不支持 SAVE 返回选项。
+func (c *Client) ESearch(sources []imap.MultiSearchSource, criteria *imap.SearchCriteria, options *imap.SearchOptions) *ESearchCommand {
+	var err error
+	if !c.Caps().Has(imap.CapMultiSearch) {
+		err = fmt.Errorf("imapclient: 服务器不支持 MULTISEARCH")
+	} else if options != nil && options.ReturnSave {
+		err = fmt.Errorf("imapclient: ESEARCH 命令不支持 SAVE 返回选项")
+	} else if searchCriteriaHas(criteria, searchCriteriaHasObjectID) && !c.Caps().Has(imap.CapObjectID) {
+		err = fmt.Errorf("imapclient: 服务器不支持 OBJECTID")
+	} else if searchCriteriaHas(criteria, searchCriteriaHasWithin) && !c.Caps().Has(imap.CapWithin) {
+		err = fmt.Errorf("imapclient: 服务器不支持 WITHIN")
+	}
+	for _, source := range sources {
+		if err != nil {
+			break
+		}
+		switch source.Filter {
+		case imap.MultiSearchFilterSubtree, imap.MultiSearchFilterSubtreeOne, imap.MultiSearchFilterMailboxes:
+			if len(source.Mailboxes) == 0 {
+				err = fmt.Errorf("imapclient: ESEARCH 邮箱过滤器 %q 需要至少一个邮箱", source.Filter)
+			}
+		}
+	}
+	if err != nil {
+		return &ESearchCommand{commandBase: newFailedCommandBase(err)}
+	}
+
+	c.mutex.Lock()
+	utf8Accepted := c.utf8AcceptedLocked()
+	c.mutex.Unlock()
+
+	cmd := &ESearchCommand{}
+	enc := c.beginCommand("ESEARCH", cmd)
+	if len(sources) > 0 {
+		enc.SP().Atom("IN").SP().List(len(sources), func(i int) {
+			source := sources[i]
+			enc.Atom(string(source.Filter))
+			switch len(source.Mailboxes) {
+			case 0:
+				// 没有参数
+			case 1:
+				enc.SP().Mailbox(source.Mailboxes[0])
+			default:
+				enc.SP().List(len(source.Mailboxes), func(j int) {
+					enc.Mailbox(source.Mailboxes[j])
+				})
+			}
+		})
+	}
+	if returnOpts := returnSearchOptions(options); len(returnOpts) > 0 {
+		enc.SP().Atom("RETURN").SP().List(len(returnOpts), func(i int) {
+			enc.Atom(returnOpts[i])
+		})
+	}
+	enc.SP()
+	if !utf8Accepted && !searchCriteriaIsASCII(criteria) {
+		enc.Atom("CHARSET").SP().Atom("UTF-8").SP()
+	}
+	writeSearchKey(enc.Encoder, criteria)
+	enc.end()
+	return cmd
+}
+
+// ESearchCommand 是一个 ESEARCH 命令。
+type ESearchCommand struct {
+	commandBase
+	data []imap.MultiSearchData // 各邮箱的搜索结果
+}
+
+// Wait 等待命令完成并返回各邮箱的搜索结果。
+func (cmd *ESearchCommand) Wait() ([]imap.MultiSearchData, error) {
+	return cmd.data, cmd.wait()
+}
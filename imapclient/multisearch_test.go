@@ -0,0 +1,71 @@
+package imapclient_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/luhaoyun888/go-imap-cn"
+)
+
+func TestMultiSearch(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateSelected)
+	defer client.Close()
+	defer server.Close()
+
+	if !client.Caps().Has(imap.CapMultiSearch) {
+		t.Skip("服务器不支持 MULTISEARCH")
+	}
+
+	if err := client.Create("Archive", nil).Wait(); err != nil {
+		t.Fatalf("Create().Wait() = %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		appendCmd := client.Append("Archive", int64(len(simpleRawMessage)), nil)
+		appendCmd.Write([]byte(simpleRawMessage))
+		appendCmd.Close()
+		if _, err := appendCmd.Wait(); err != nil {
+			t.Fatalf("Append().Wait() = %v", err)
+		}
+	}
+	status, err := client.Status("Archive", &imap.StatusOptions{UIDValidity: true}).Wait()
+	if err != nil {
+		t.Fatalf("Status().Wait() = %v", err)
+	}
+
+	criteria := imap.SearchCriteria{NotFlag: []imap.Flag{imap.FlagDeleted}}
+	sources := []imap.MultiSearchSource{{
+		Filter:    imap.MultiSearchFilterMailboxes,
+		Mailboxes: []string{"INBOX", "Archive"},
+	}}
+	results, err := client.ESearch(sources, &criteria, nil).Wait()
+	if err != nil {
+		t.Fatalf("ESearch().Wait() = %v", err)
+	}
+	got := make(map[string][]imap.UID)
+	for _, data := range results {
+		got[data.Mailbox] = data.AllUIDs()
+		if data.Mailbox == "Archive" && data.UIDValidity != status.UIDValidity {
+			t.Errorf("Archive 的 UIDVALIDITY = %v, want %v", data.UIDValidity, status.UIDValidity)
+		}
+	}
+	want := map[string][]imap.UID{
+		"INBOX":   {1},
+		"Archive": {1, 2},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ESearch() = %v, want %v", got, want)
+	}
+
+	// 没有 IN 源选项时只搜索当前选择的邮箱
+	results, err = client.ESearch(nil, &criteria, &imap.SearchOptions{ReturnCount: true}).Wait()
+	if err != nil {
+		t.Fatalf("ESearch(selected).Wait() = %v", err)
+	}
+	if len(results) != 1 || results[0].Mailbox != "INBOX" || results[0].Count != 1 {
+		t.Errorf("ESearch(selected) = %+v, want INBOX 中的 1 封邮件", results)
+	}
+
+	if _, err := client.ESearch(sources, &criteria, &imap.SearchOptions{ReturnSave: true}).Wait(); err == nil {
+		t.Errorf("ESearch(SAVE).Wait() = nil, want error")
+	}
+}
@@ -11,6 +11,11 @@ import (
 //
 // 此命令要求支持 IMAP4rev2 或 NAMESPACE 扩展。
 func (c *Client) Namespace() *NamespaceCommand {
+	if !c.Caps().Has(imap.CapNamespace) {
+		err := fmt.Errorf("imapclient: 服务器不支持 NAMESPACE")
+		return &NamespaceCommand{commandBase: newFailedCommandBase(err)}
+	}
+
 	cmd := &NamespaceCommand{}
 	c.beginCommand("NAMESPACE", cmd).end() // 开始并结束命令
 	return cmd
@@ -35,6 +40,9 @@ type NamespaceCommand struct {
 }
 
 // Wait 等待命令完成，并返回 NAMESPACE 数据。
+//
+// 数据包含个人（Personal）、其他用户（Other）和共享（Shared）三类命名空间，
+// 每个命名空间描述包含前缀和层级分隔符。
 func (cmd *NamespaceCommand) Wait() (*imap.NamespaceData, error) {
 	return &cmd.data, cmd.wait() // 返回数据和等待结果
 }
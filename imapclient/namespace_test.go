@@ -0,0 +1,35 @@
+package imapclient_test
+
+import (
+	"testing"
+
+	"github.com/luhaoyun888/go-imap-cn"
+)
+
+func TestNamespace(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateAuthenticated)
+	defer client.Close()
+	defer server.Close()
+
+	data, err := client.Namespace().Wait()
+	if err != nil {
+		t.Fatalf("Namespace().Wait() = %v", err)
+	}
+	if len(data.Personal) == 0 {
+		t.Fatalf("NamespaceData.Personal 为空")
+	}
+	if delim := data.Personal[0].Delim; delim != '/' && delim != '.' {
+		t.Errorf("Personal[0].Delim = %q, want '/' or '.'", delim)
+	}
+}
+
+func TestNamespace_unsupported(t *testing.T) {
+	client := newScriptedClient(t, "", func(cmd string) []string {
+		t.Errorf("不应发送命令: %q", cmd)
+		return nil
+	})
+
+	if _, err := client.Namespace().Wait(); err == nil {
+		t.Errorf("Namespace() 在服务器不支持 NAMESPACE 时应失败")
+	}
+}
@@ -0,0 +1,104 @@
+package imapclient
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/luhaoyun888/go-imap-cn"
+)
+
+// checkPipeline 在发送 FETCH 或 STORE 命令之前检测其与待处理命令之间的歧义。
+//
+// 参见 RFC 9051 第 5.5 节。检测到歧义时会调用 Options.AmbiguousPipelineHandler，
+// 如果设置了 Options.SerializeAmbiguousPipeline，则等待有歧义的待处理命令完成。
+func (c *Client) checkPipeline(name string, numSet imap.NumSet, store bool) {
+	handler := c.options.AmbiguousPipelineHandler
+	serialize := c.options.SerializeAmbiguousPipeline
+	if handler == nil && !serialize {
+		return
+	}
+
+	warned := false
+	for {
+		pending := c.findPendingCmdFunc(func(cmd command) bool {
+			return isAmbiguousPipeline(cmd, numSet, store)
+		})
+		if pending == nil {
+			return
+		}
+
+		if handler != nil && !warned {
+			handler(fmt.Errorf("imapclient: %v %v 与待处理命令 %v 存在歧义", name, numSet, pending.base().tag))
+			warned = true
+		}
+		if !serialize {
+			return
+		}
+		<-pending.base().completed // 等待待处理命令完成
+	}
+}
+
+// isAmbiguousPipeline 判断在 pending 命令完成前发送针对 numSet 的 FETCH 或 STORE 命令是否有歧义。
+func isAmbiguousPipeline(pending command, numSet imap.NumSet, store bool) bool {
+	switch pending := pending.(type) {
+	case *FetchCommand:
+		// FETCH 与 STORE 针对相同消息时，无法确定 FETCH 响应属于哪个命令
+		return (store || pending.store) && numSetsOverlap(pending.numSet, numSet)
+	case *ExpungeCommand:
+		// EXPUNGE 响应会改变消息序号
+		_, isSeqSet := numSet.(imap.SeqSet)
+		return isSeqSet
+	default:
+		return false
+	}
+}
+
+// numSetsOverlap 判断两个消息编号集合是否可能包含相同的消息。
+//
+// 类型不同的集合以及 SEARCHRES 标记无法比较，视为重叠。
+func numSetsOverlap(a, b imap.NumSet) bool {
+	_, aIsSeqSet := a.(imap.SeqSet)
+	_, bIsSeqSet := b.(imap.SeqSet)
+	if aIsSeqSet != bIsSeqSet || imap.IsSearchRes(a) || imap.IsSearchRes(b) {
+		return true
+	}
+
+	for _, ra := range numSetRanges(a) {
+		for _, rb := range numSetRanges(b) {
+			if ra[0] <= rb[1] && rb[0] <= ra[1] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// numSetRanges 返回消息编号集合中的范围，"*" 以最大值表示。
+func numSetRanges(numSet imap.NumSet) [][2]uint32 {
+	var l [][2]uint32
+	switch numSet := numSet.(type) {
+	case imap.SeqSet:
+		for _, r := range numSet {
+			l = append(l, normalizeRange(r.Start, r.Stop))
+		}
+	case imap.UIDSet:
+		for _, r := range numSet {
+			l = append(l, normalizeRange(uint32(r.Start), uint32(r.Stop)))
+		}
+	}
+	return l
+}
+
+// normalizeRange 将 "*"（零）替换为最大值，并确保起始值不大于结束值。
+func normalizeRange(start, stop uint32) [2]uint32 {
+	if start == 0 {
+		start = math.MaxUint32
+	}
+	if stop == 0 {
+		stop = math.MaxUint32
+	}
+	if start > stop {
+		start, stop = stop, start
+	}
+	return [2]uint32{start, stop}
+}
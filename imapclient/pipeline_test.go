@@ -0,0 +1,77 @@
+package imapclient_test
+
+import (
+	"testing"
+
+	"github.com/luhaoyun888/go-imap-cn"
+	"github.com/luhaoyun888/go-imap-cn/imapclient"
+)
+
+func TestAmbiguousPipeline(t *testing.T) {
+	for _, serialize := range []bool{false, true} {
+		serialize := serialize
+		name := "warn"
+		if serialize {
+			name = "serialize"
+		}
+		t.Run(name, func(t *testing.T) {
+			conn, server := newMemClientServerPair(t)
+			defer server.Close()
+
+			var warnings []error
+			client := imapclient.New(conn, &imapclient.Options{
+				AmbiguousPipelineHandler:   func(err error) { warnings = append(warnings, err) },
+				SerializeAmbiguousPipeline: serialize,
+			})
+			defer client.Close()
+
+			if err := client.Login(testUsername, testPassword).Wait(); err != nil {
+				t.Fatalf("Login().Wait() = %v", err)
+			}
+			appendCmd := client.Append("INBOX", int64(len(simpleRawMessage)), nil)
+			appendCmd.Write([]byte(simpleRawMessage))
+			appendCmd.Close()
+			if _, err := appendCmd.Wait(); err != nil {
+				t.Fatalf("AppendCommand.Wait() = %v", err)
+			}
+			if _, err := client.Select("INBOX", nil).Wait(); err != nil {
+				t.Fatalf("Select().Wait() = %v", err)
+			}
+
+			storeFlags := imap.StoreFlags{
+				Op:     imap.StoreFlagsAdd,
+				Silent: true,
+				Flags:  []imap.Flag{imap.FlagFlagged},
+			}
+
+			// 针对不同消息的 FETCH 和 STORE 没有歧义
+			fetchCmd := client.Fetch(imap.SeqSetNum(1), &imap.FetchOptions{Flags: true})
+			storeCmd := client.Store(imap.SeqSetNum(2), &storeFlags, nil)
+			if _, err := fetchCmd.Collect(); err != nil {
+				t.Fatalf("Fetch().Collect() = %v", err)
+			}
+			if err := storeCmd.Close(); err != nil {
+				t.Fatalf("Store().Close() = %v", err)
+			}
+			if len(warnings) != 0 {
+				t.Fatalf("warnings = %v, want none", warnings)
+			}
+
+			// 针对相同消息的 FETCH 和 STORE 有歧义
+			fetchCmd = client.Fetch(imap.SeqSetNum(1), &imap.FetchOptions{Flags: true})
+			storeCmd = client.Store(imap.SeqSetNum(1), &storeFlags, nil)
+			msgs, err := fetchCmd.Collect()
+			if err != nil {
+				t.Fatalf("Fetch().Collect() = %v", err)
+			} else if len(msgs) != 1 {
+				t.Fatalf("len(msgs) = %v, want %v", len(msgs), 1)
+			}
+			if err := storeCmd.Close(); err != nil {
+				t.Fatalf("Store().Close() = %v", err)
+			}
+			if len(warnings) != 1 {
+				t.Errorf("len(warnings) = %v, want %v", len(warnings), 1)
+			}
+		})
+	}
+}
@@ -134,16 +134,10 @@ func (cmd *GetQuotaRootCommand) Wait() ([]QuotaData, error) {
 }
 
 // QuotaData 是 QUOTA 响应返回的数据。
-type QuotaData struct {
-	Root      string                                       // 根命名空间
-	Resources map[imap.QuotaResourceType]QuotaResourceData // 资源数据
-}
+type QuotaData = imap.QuotaData
 
 // QuotaResourceData 包含配额资源的使用情况和限制。
-type QuotaResourceData struct {
-	Usage int64 // 使用量
-	Limit int64 // 限制量
-}
+type QuotaResourceData = imap.QuotaResourceData
 
 // readQuotaResponse 读取 QUOTA 响应。
 func readQuotaResponse(dec *imapwire.Decoder) (*QuotaData, error) {
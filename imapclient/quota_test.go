@@ -0,0 +1,115 @@
+package imapclient_test
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/luhaoyun888/go-imap-cn"
+	"github.com/luhaoyun888/go-imap-cn/imapclient"
+)
+
+// TestQuota 测试配额的查询、设置和 APPEND 超出配额时的错误。
+func TestQuota(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateAuthenticated)
+	defer client.Close()
+	defer server.Close()
+
+	resources := client.Caps().QuotaResourceTypes()
+	sort.Slice(resources, func(i, j int) bool { return resources[i] < resources[j] })
+	if want := []imap.QuotaResourceType{imap.QuotaResourceMessage, imap.QuotaResourceStorage}; !reflect.DeepEqual(resources, want) {
+		t.Errorf("QuotaResourceTypes() = %v, want %v", resources, want)
+	}
+
+	// 未设置限制时没有任何资源
+	data, err := client.GetQuota("INBOX").Wait()
+	if err != nil {
+		t.Fatalf("GetQuota() = %v", err)
+	} else if data.Root != "INBOX" || len(data.Resources) != 0 {
+		t.Errorf("GetQuota() = %v, want INBOX without resources", data)
+	}
+
+	limits := map[imap.QuotaResourceType]int64{
+		imap.QuotaResourceStorage: 1,
+		imap.QuotaResourceMessage: 2,
+	}
+	if err := client.SetQuota("INBOX", limits).Wait(); err != nil {
+		t.Fatalf("SetQuota() = %v", err)
+	}
+
+	l, err := client.GetQuotaRoot("INBOX").Wait()
+	if err != nil {
+		t.Fatalf("GetQuotaRoot() = %v", err)
+	}
+	want := []imapclient.QuotaData{{
+		Root: "INBOX",
+		Resources: map[imap.QuotaResourceType]imapclient.QuotaResourceData{
+			imap.QuotaResourceStorage: {Usage: int64(len(simpleRawMessage)) / 1024, Limit: 1},
+			imap.QuotaResourceMessage: {Usage: 1, Limit: 2},
+		},
+	}}
+	if !reflect.DeepEqual(l, want) {
+		t.Errorf("GetQuotaRoot() = %v, want %v", l, want)
+	}
+
+	appendMessage := func(msg string) error {
+		appendCmd := client.Append("INBOX", int64(len(msg)), nil)
+		appendCmd.Write([]byte(msg))
+		appendCmd.Close()
+		_, err := appendCmd.Wait()
+		return err
+	}
+	checkOverQuota := func(name string, err error) {
+		t.Helper()
+		if !imap.IsNo(err) || imap.ErrorCode(err) != imap.ResponseCodeOverQuota {
+			t.Errorf("%v = %v, want NO [OVERQUOTA]", name, err)
+		}
+	}
+
+	if err := appendMessage(simpleRawMessage); err != nil {
+		t.Fatalf("Append() = %v", err)
+	}
+	// 已达到邮件数量的限制
+	checkOverQuota("Append()", appendMessage(simpleRawMessage))
+
+	// COPY 和 MOVE 同样受目标邮箱配额的限制，且要么全部成功，要么不复制任何邮件
+	if err := client.Create("Archive", nil).Wait(); err != nil {
+		t.Fatalf("Create() = %v", err)
+	}
+	if err := client.SetQuota("Archive", map[imap.QuotaResourceType]int64{imap.QuotaResourceMessage: 1}).Wait(); err != nil {
+		t.Fatalf("SetQuota(Archive) = %v", err)
+	}
+	if _, err := client.Select("INBOX", nil).Wait(); err != nil {
+		t.Fatalf("Select() = %v", err)
+	}
+	_, err = client.Copy(imap.SeqSetNum(1, 2), "Archive").Wait()
+	checkOverQuota("Copy()", err)
+	_, err = client.Move(imap.SeqSetNum(1, 2), "Archive").Wait()
+	checkOverQuota("Move()", err)
+	checkNumMessages := func(mailbox string, want uint32) {
+		t.Helper()
+		status, err := client.Status(mailbox, &imap.StatusOptions{NumMessages: true}).Wait()
+		if err != nil {
+			t.Fatalf("Status(%v) = %v", mailbox, err)
+		} else if *status.NumMessages != want {
+			t.Errorf("Status(%v).NumMessages = %v, want %v", mailbox, *status.NumMessages, want)
+		}
+	}
+	checkNumMessages("Archive", 0)
+	checkNumMessages("INBOX", 2)
+
+	// 只限制存储空间时，过大的邮件被拒绝
+	if err := client.SetQuota("INBOX", map[imap.QuotaResourceType]int64{imap.QuotaResourceStorage: 1}).Wait(); err != nil {
+		t.Fatalf("SetQuota() = %v", err)
+	}
+	checkOverQuota("Append(large)", appendMessage("Subject: large\r\n\r\n"+strings.Repeat("a", 1024)))
+	if err := appendMessage(simpleRawMessage); err != nil {
+		t.Errorf("Append() = %v", err)
+	}
+
+	// 不支持的资源
+	if err := client.SetQuota("INBOX", map[imap.QuotaResourceType]int64{imap.QuotaResourceMailbox: 1}).Wait(); !imap.IsNo(err) {
+		t.Errorf("SetQuota(MAILBOX) = %v, want NO", err)
+	}
+}
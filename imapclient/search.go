@@ -11,6 +11,10 @@ import (
 	"github.com/luhaoyun888/go-imap-cn/internal/imapwire"
 )
 
+// maxSearchUIDSetLen 是 SEARCH 命令中单个 UID 条件编码后的最大长度。
+// 超出时命令会被拆分为多个 SEARCH，以免超出服务器的命令行长度限制。
+const maxSearchUIDSetLen = 4096
+
 // 返回搜索选项的字符串列表
 // options: 传入的搜索选项指针
 // 返回值: 返回一个字符串切片，包含所有被选中的搜索选项
@@ -24,6 +28,7 @@ func returnSearchOptions(options *imap.SearchOptions) []string {
 		"MAX":   options.ReturnMax,   // 返回最大值
 		"ALL":   options.ReturnAll,   // 返回所有
 		"COUNT": options.ReturnCount, // 返回计数
+		"SAVE":  options.ReturnSave,  // 保存搜索结果
 	}
 
 	var l []string
@@ -42,8 +47,30 @@ func returnSearchOptions(options *imap.SearchOptions) []string {
 // 返回值: 返回一个SearchCommand结构体指针
 func (c *Client) search(numKind imapwire.NumKind, criteria *imap.SearchCriteria, options *imap.SearchOptions) *SearchCommand {
 	// IMAP4rev2的搜索字符集默认为UTF-8。当启用UTF8=ACCEPT时，指定任何CHARSET都是无效的。
+	c.Caps() // 确保已获取服务器能力
+	c.mutex.Lock()
+	utf8Accepted := c.utf8AcceptedLocked()
+	c.mutex.Unlock()
+
+	var err error
+	if searchCriteriaHas(criteria, searchCriteriaHasObjectID) && !c.Caps().Has(imap.CapObjectID) {
+		err = fmt.Errorf("imapclient: 服务器不支持 OBJECTID")
+	} else if searchCriteriaHas(criteria, searchCriteriaHasWithin) && !c.Caps().Has(imap.CapWithin) {
+		err = fmt.Errorf("imapclient: 服务器不支持 WITHIN")
+	}
+	if err != nil {
+		return &SearchCommand{commandBase: newFailedCommandBase(err)}
+	}
+
+	// SAVE 只会保存最后一个命令的结果，因此不能拆分
+	if options == nil || !options.ReturnSave {
+		if chunks := splitSearchCriteriaUID(criteria); len(chunks) > 1 {
+			return c.searchChunks(numKind, chunks, options)
+		}
+	}
+
 	var charset string
-	if !c.Caps().Has(imap.CapIMAP4rev2) && !c.enabled.Has(imap.CapUTF8Accept) && !searchCriteriaIsASCII(criteria) {
+	if !utf8Accepted && !searchCriteriaIsASCII(criteria) {
 		charset = "UTF-8"
 	}
 
@@ -72,6 +99,105 @@ func (c *Client) search(numKind imapwire.NumKind, criteria *imap.SearchCriteria,
 	return cmd
 }
 
+// searchChunks 为每个拆分后的搜索条件发送一个 SEARCH 命令，并合并所有结果。
+func (c *Client) searchChunks(numKind imapwire.NumKind, chunks []*imap.SearchCriteria, options *imap.SearchOptions) *SearchCommand {
+	cmds := make([]*SearchCommand, len(chunks))
+	for i, criteria := range chunks {
+		cmds[i] = c.search(numKind, criteria, options)
+	}
+
+	cmd := &SearchCommand{
+		commandBase: commandBase{
+			done:      make(chan error, 1),
+			completed: make(chan struct{}),
+		},
+	}
+	switch numKind {
+	case imapwire.NumKindSeq:
+		cmd.data.All = imap.SeqSet(nil)
+	case imapwire.NumKindUID:
+		cmd.data.All = imap.UIDSet(nil)
+	}
+	go func() {
+		var err error
+		for _, subCmd := range cmds {
+			data, subErr := subCmd.Wait()
+			if subErr != nil {
+				if err == nil {
+					err = subErr
+				}
+				continue
+			}
+			mergeSearchData(&cmd.data, data)
+		}
+		cmd.done <- err
+		close(cmd.completed)
+	}()
+	return cmd
+}
+
+// mergeSearchData 将 src 中的搜索结果合并到 dst 中。
+func mergeSearchData(dst, src *imap.SearchData) {
+	switch all := dst.All.(type) {
+	case imap.SeqSet:
+		if other, ok := src.All.(imap.SeqSet); ok {
+			all.AddSet(other)
+			dst.All = all
+		}
+	case imap.UIDSet:
+		if other, ok := src.All.(imap.UIDSet); ok {
+			all.AddSet(other)
+			dst.All = all
+		}
+	}
+	dst.UID = dst.UID || src.UID
+	if src.Min != 0 && (dst.Min == 0 || src.Min < dst.Min) {
+		dst.Min = src.Min
+	}
+	if src.Max > dst.Max {
+		dst.Max = src.Max
+	}
+	dst.Count += src.Count
+	if src.ModSeq > dst.ModSeq {
+		dst.ModSeq = src.ModSeq
+	}
+}
+
+// splitSearchCriteriaUID 在顶层的某个 UID 条件过长时，将搜索条件拆分为多个。
+// 由于顶层条件之间为 AND 关系，对各部分的搜索结果取并集即可得到原搜索的结果。
+// 不需要拆分时返回 nil。
+func splitSearchCriteriaUID(criteria *imap.SearchCriteria) []*imap.SearchCriteria {
+	for i, uidSet := range criteria.UID {
+		if len(uidSet.String()) <= maxSearchUIDSetLen {
+			continue
+		}
+
+		var (
+			chunks []*imap.SearchCriteria
+			part   imap.UIDSet
+			n      int
+		)
+		flush := func() {
+			chunk := *criteria
+			chunk.UID = append([]imap.UIDSet(nil), criteria.UID...)
+			chunk.UID[i] = part
+			chunks = append(chunks, &chunk)
+			part, n = nil, 0
+		}
+		for _, r := range uidSet {
+			l := len(imap.UIDSet{r}.String()) + 1 // 加上逗号
+			if n > 0 && n+l > maxSearchUIDSetLen {
+				flush()
+			}
+			part = append(part, r)
+			n += l
+		}
+		flush()
+		return chunks
+	}
+	return nil
+}
+
 // Search方法，发送一个SEARCH命令
 // criteria: 搜索条件
 // options: 搜索选项
@@ -127,6 +253,14 @@ func (c *Client) handleSearch() error {
 	return nil
 }
 
+// checkSearchRes 检查服务器是否支持 numSet 中引用的上次搜索结果（$）。
+func (c *Client) checkSearchRes(numSet imap.NumSet) error {
+	if imap.IsSearchRes(numSet) && !c.Caps().Has(imap.CapSearchRes) {
+		return fmt.Errorf("imapclient: 服务器不支持 SEARCHRES，不能使用 $")
+	}
+	return nil
+}
+
 // 处理扩展搜索响应
 func (c *Client) handleESearch() error {
 	if !c.dec.ExpectSP() {
@@ -137,19 +271,23 @@ func (c *Client) handleESearch() error {
 		return err
 	}
 	cmd := c.findPendingCmdFunc(func(anyCmd command) bool {
-		cmd, ok := anyCmd.(*SearchCommand)
-		if !ok {
-			return false
-		}
-		if tag != "" {
+		switch cmd := anyCmd.(type) {
+		case *SearchCommand:
+			return tag == "" || cmd.tag == tag
+		case *ESearchCommand:
 			return cmd.tag == tag
-		} else {
-			return true
+		default:
+			return false
 		}
 	})
-	if cmd != nil {
-		cmd := cmd.(*SearchCommand)
-		cmd.data = *data
+	switch cmd := cmd.(type) {
+	case *SearchCommand:
+		cmd.data = data.SearchData
+	case *ESearchCommand:
+		if data.All == nil {
+			data.All = imap.UIDSet(nil) // 没有结果时服务器不返回 ALL
+		}
+		cmd.data = append(cmd.data, *data)
 	}
 	return nil
 }
@@ -245,6 +383,13 @@ func writeSearchKey(enc *imapwire.Encoder, criteria *imap.SearchCriteria) {
 		encodeItem().Atom("SMALLER").SP().Number64(criteria.Smaller)
 	}
 
+	if criteria.Younger > 0 {
+		encodeItem().Atom("YOUNGER").SP().Number64(criteria.Younger)
+	}
+	if criteria.Older > 0 {
+		encodeItem().Atom("OLDER").SP().Number64(criteria.Older)
+	}
+
 	if modSeq := criteria.ModSeq; modSeq != nil {
 		encodeItem().Atom("MODSEQ")
 		if modSeq.MetadataName != "" && modSeq.MetadataType != "" {
@@ -258,6 +403,13 @@ func writeSearchKey(enc *imapwire.Encoder, criteria *imap.SearchCriteria) {
 		}
 	}
 
+	for _, emailID := range criteria.EmailID {
+		encodeItem().Atom("EMAILID").SP().Atom(emailID)
+	}
+	for _, threadID := range criteria.ThreadID {
+		encodeItem().Atom("THREADID").SP().Atom(threadID)
+	}
+
 	for _, not := range criteria.Not {
 		encodeItem().Atom("NOT").SP()
 		enc.Special('(')
@@ -276,7 +428,7 @@ func writeSearchKey(enc *imapwire.Encoder, criteria *imap.SearchCriteria) {
 	}
 
 	if firstItem {
-		enc.Atom("所有") // "ALL" replaced with "所有"
+		enc.Atom("ALL")
 	}
 }
 
@@ -295,16 +447,37 @@ func flagSearchKey(flag imap.Flag) string {
 // 读取扩展搜索响应
 // dec: 解码器
 // 返回值: 返回tag字符串、搜索数据结构体指针和可能的错误
-func readESearchResponse(dec *imapwire.Decoder) (tag string, data *imap.SearchData, err error) {
-	data = &imap.SearchData{}
+func readESearchResponse(dec *imapwire.Decoder) (tag string, data *imap.MultiSearchData, err error) {
+	data = &imap.MultiSearchData{}
 	if dec.Special('(') { // 搜索相关器
 		var correlator string
-		if !dec.ExpectAtom(&correlator) || !dec.ExpectSP() || !dec.ExpectAString(&tag) || !dec.ExpectSpecial(')') {
+		if !dec.ExpectAtom(&correlator) || !dec.ExpectSP() || !dec.ExpectAString(&tag) {
 			return "", nil, dec.Err()
 		}
 		if correlator != "TAG" {
 			return "", nil, fmt.Errorf("在搜索相关器中：名称必须是TAG，但得到 %q", correlator)
 		}
+		// MULTISEARCH 的响应还带有 MAILBOX 和 UIDVALIDITY（RFC 7377）
+		for dec.SP() {
+			if !dec.ExpectAtom(&correlator) || !dec.ExpectSP() {
+				return "", nil, dec.Err()
+			}
+			switch strings.ToUpper(correlator) {
+			case "MAILBOX":
+				if !dec.ExpectMailbox(&data.Mailbox) {
+					return "", nil, dec.Err()
+				}
+			case "UIDVALIDITY":
+				if !dec.ExpectNumber(&data.UIDValidity) {
+					return "", nil, dec.Err()
+				}
+			default:
+				return "", nil, fmt.Errorf("在搜索相关器中：未知的名称 %q", correlator)
+			}
+		}
+		if !dec.ExpectSpecial(')') {
+			return "", nil, dec.Err()
+		}
 	}
 
 	var name string
@@ -412,6 +585,34 @@ func searchCriteriaIsASCII(criteria *imap.SearchCriteria) bool {
 	return true
 }
 
+// searchCriteriaHasObjectID 判断搜索条件是否包含需要 OBJECTID 的 EMAILID 或 THREADID 条件。
+func searchCriteriaHasObjectID(criteria *imap.SearchCriteria) bool {
+	return len(criteria.EmailID) > 0 || len(criteria.ThreadID) > 0
+}
+
+// searchCriteriaHasWithin 判断搜索条件是否使用了 WITHIN 扩展的 YOUNGER 或 OLDER
+func searchCriteriaHasWithin(criteria *imap.SearchCriteria) bool {
+	return criteria.Younger > 0 || criteria.Older > 0
+}
+
+// searchCriteriaHas 判断搜索条件或其 NOT、OR 子条件中是否有满足 f 的条件
+func searchCriteriaHas(criteria *imap.SearchCriteria, f func(criteria *imap.SearchCriteria) bool) bool {
+	if f(criteria) {
+		return true
+	}
+	for _, not := range criteria.Not {
+		if searchCriteriaHas(&not, f) {
+			return true
+		}
+	}
+	for _, or := range criteria.Or {
+		if searchCriteriaHas(&or[0], f) || searchCriteriaHas(&or[1], f) {
+			return true
+		}
+	}
+	return false
+}
+
 // 判断字符串是否为ASCII字符
 // s: 待判断字符串
 // 返回值: 返回布尔值，表示是否为ASCII字符
@@ -1,9 +1,13 @@
 package imapclient_test
 
 import (
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/luhaoyun888/go-imap-cn"
+	"github.com/luhaoyun888/go-imap-cn/imapclient"
 )
 
 func TestESearch(t *testing.T) {
@@ -32,3 +36,437 @@ func TestESearch(t *testing.T) {
 		t.Errorf("Count = %v, want %v", data.Count, want)
 	}
 }
+
+// TestSearch_size 测试 LARGER 和 SMALLER 是严格比较。
+func TestSearch_size(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateSelected)
+	defer client.Close()
+	defer server.Close()
+
+	size := int64(len(simpleRawMessage))
+	tests := []struct {
+		name     string
+		criteria imap.SearchCriteria
+		want     int
+	}{
+		{"LARGER 相等", imap.SearchCriteria{Larger: size}, 0},
+		{"LARGER 小于", imap.SearchCriteria{Larger: size - 1}, 1},
+		{"SMALLER 相等", imap.SearchCriteria{Smaller: size}, 0},
+		{"SMALLER 大于", imap.SearchCriteria{Smaller: size + 1}, 1},
+	}
+	for _, tc := range tests {
+		data, err := client.Search(&tc.criteria, nil).Wait()
+		if err != nil {
+			t.Fatalf("%v: Search().Wait() = %v", tc.name, err)
+		}
+		if seqNums := data.AllSeqNums(); len(seqNums) != tc.want {
+			t.Errorf("%v: Search() = %v, want %v matches", tc.name, seqNums, tc.want)
+		}
+	}
+}
+
+// TestSearch_date 测试 SINCE/BEFORE 基于内部日期，而 SENTSINCE/SENTBEFORE 基于 Date 头。
+func TestSearch_date(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateSelected)
+	defer client.Close()
+	defer server.Close()
+
+	const rawMessage = "Date: Wed, 01 Jan 2020 12:00:00 +0000\r\n" +
+		"Subject: date\r\n" +
+		"\r\n" +
+		"Hello\r\n"
+	internalDate := time.Date(2023, time.June, 15, 12, 0, 0, 0, time.UTC)
+	appendCmd := client.Append("INBOX", int64(len(rawMessage)), &imap.AppendOptions{Time: internalDate})
+	appendCmd.Write([]byte(rawMessage))
+	appendCmd.Close()
+	appendData, err := appendCmd.Wait()
+	if err != nil {
+		t.Fatalf("Append().Wait() = %v", err)
+	} else if appendData.UID == 0 {
+		t.Skip("服务器未返回 APPENDUID")
+	}
+	uidSet := []imap.UIDSet{imap.UIDSetNum(appendData.UID)}
+
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name     string
+		criteria imap.SearchCriteria
+		want     bool
+	}{
+		{"SINCE 内部日期之前", imap.SearchCriteria{Since: date(2023, time.June, 1)}, true},
+		{"SINCE 内部日期之后", imap.SearchCriteria{Since: date(2023, time.July, 1)}, false},
+		{"BEFORE Date 头之后", imap.SearchCriteria{Before: date(2021, time.January, 1)}, false},
+		{"SENTSINCE 内部日期之前", imap.SearchCriteria{SentSince: date(2023, time.June, 1)}, false},
+		{"SENTSINCE Date 头之前", imap.SearchCriteria{SentSince: date(2019, time.December, 1)}, true},
+		{"SENTBEFORE Date 头之后", imap.SearchCriteria{SentBefore: date(2021, time.January, 1)}, true},
+	}
+	for _, tc := range tests {
+		tc.criteria.UID = uidSet
+		data, err := client.UIDSearch(&tc.criteria, nil).Wait()
+		if err != nil {
+			t.Fatalf("%v: UIDSearch().Wait() = %v", tc.name, err)
+		}
+		if got := len(data.AllUIDs()) == 1; got != tc.want {
+			t.Errorf("%v: UIDSearch() = %v, want match = %v", tc.name, data.AllUIDs(), tc.want)
+		}
+	}
+}
+
+func TestSearch_saveReuse(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateSelected)
+	defer client.Close()
+	defer server.Close()
+
+	if !client.Caps().Has(imap.CapSearchRes) {
+		t.Skip("服务器不支持 SEARCHRES")
+	}
+
+	for i := 0; i < 2; i++ {
+		appendCmd := client.Append("INBOX", int64(len(simpleRawMessage)), nil)
+		appendCmd.Write([]byte(simpleRawMessage))
+		appendCmd.Close()
+		if _, err := appendCmd.Wait(); err != nil {
+			t.Fatalf("Append().Wait() = %v", err)
+		}
+	}
+	if err := client.Create("Archive", nil).Wait(); err != nil {
+		t.Fatalf("Create().Wait() = %v", err)
+	}
+
+	storeFlags := imap.StoreFlags{Op: imap.StoreFlagsAdd, Silent: true, Flags: []imap.Flag{imap.FlagFlagged}}
+	if err := client.Store(imap.SeqSetNum(1, 3), &storeFlags, nil).Close(); err != nil {
+		t.Fatalf("Store().Close() = %v", err)
+	}
+
+	// 保存搜索结果，后续命令通过 $ 引用
+	criteria := imap.SearchCriteria{Flag: []imap.Flag{imap.FlagFlagged}}
+	if _, err := client.UIDSearch(&criteria, &imap.SearchOptions{ReturnSave: true}).Wait(); err != nil {
+		t.Fatalf("UIDSearch(SAVE).Wait() = %v", err)
+	}
+	msgs, err := client.Fetch(imap.SearchRes(), &imap.FetchOptions{UID: true}).Collect()
+	if err != nil {
+		t.Fatalf("Fetch($).Collect() = %v", err)
+	}
+	var uids imap.UIDSet
+	for _, msg := range msgs {
+		uids.AddNum(msg.UID)
+	}
+	if len(msgs) != 2 || uids.Contains(2) {
+		t.Fatalf("Fetch($) 返回了 UID %v, want 1,3", uids)
+	}
+
+	// 不带 SAVE 的 SEARCH 不会改变 $
+	if _, err := client.UIDSearch(&imap.SearchCriteria{}, nil).Wait(); err != nil {
+		t.Fatalf("UIDSearch().Wait() = %v", err)
+	}
+
+	copyData, err := client.Copy(imap.SearchRes(), "Archive").Wait()
+	if err != nil {
+		t.Fatalf("Copy($).Wait() = %v", err)
+	} else if got := copyData.SourceUIDs.String(); got != uids.String() {
+		t.Errorf("Copy($).SourceUIDs = %v, want %v", got, uids)
+	}
+
+	storeFlags.Flags = []imap.Flag{imap.FlagDeleted}
+	if err := client.Store(imap.SearchRes(), &storeFlags, nil).Close(); err != nil {
+		t.Fatalf("Store($).Close() = %v", err)
+	}
+	seqNums, err := client.UIDExpunge(imap.SearchRes()).Collect()
+	if err != nil {
+		t.Fatalf("UIDExpunge($).Collect() = %v", err)
+	} else if len(seqNums) != 2 {
+		t.Errorf("UIDExpunge($).Collect() = %v, want 2 个序列号", seqNums)
+	}
+
+	data, err := client.UIDSearch(&imap.SearchCriteria{}, nil).Wait()
+	if err != nil {
+		t.Fatalf("UIDSearch().Wait() = %v", err)
+	}
+	if all := data.AllUIDs(); len(all) != 1 || uids.Contains(all[0]) {
+		t.Errorf("UID EXPUNGE $ 之后剩余 UID %v, want 未被保存的那封邮件", all)
+	}
+}
+
+// TestSearch_saveMove 测试 SEARCH RETURN (SAVE) 之后通过 $ 移动邮件。
+func TestSearch_saveMove(t *testing.T) {
+	conn, server := newMemClientServerPair(t)
+	defer server.Close()
+
+	var debug lockedBuffer
+	client := imapclient.New(conn, &imapclient.Options{DebugWriter: &debug})
+	defer client.Close()
+
+	if err := client.Login(testUsername, testPassword).Wait(); err != nil {
+		t.Fatalf("Login().Wait() = %v", err)
+	}
+	if err := client.Create("Archive", nil).Wait(); err != nil {
+		t.Fatalf("Create().Wait() = %v", err)
+	}
+	for _, subject := range []string{"keep", "move", "move"} {
+		rawMessage := "Subject: " + subject + "\r\n\r\nHello\r\n"
+		appendCmd := client.Append("INBOX", int64(len(rawMessage)), nil)
+		appendCmd.Write([]byte(rawMessage))
+		appendCmd.Close()
+		if _, err := appendCmd.Wait(); err != nil {
+			t.Fatalf("Append().Wait() = %v", err)
+		}
+	}
+	if _, err := client.Select("INBOX", nil).Wait(); err != nil {
+		t.Fatalf("Select().Wait() = %v", err)
+	}
+
+	criteria := imap.SearchCriteria{Header: []imap.SearchCriteriaHeaderField{{Key: "Subject", Value: "move"}}}
+	if _, err := client.Search(&criteria, &imap.SearchOptions{ReturnSave: true}).Wait(); err != nil {
+		t.Fatalf("Search(SAVE).Wait() = %v", err)
+	}
+	data, err := client.Move(imap.SearchRes(), "Archive").Wait()
+	if err != nil {
+		t.Fatalf("Move($).Wait() = %v", err)
+	}
+	if !strings.Contains(debug.String(), `UID MOVE $ "Archive"`) {
+		t.Errorf("MOVE 命令没有使用 $：\n%v", debug.String())
+	}
+	if strings.Contains(debug.String(), "* 0 EXPUNGE") {
+		t.Errorf("MOVE 返回了序号为 0 的 EXPUNGE：\n%v", debug.String())
+	}
+	if got := data.SourceUIDs.String(); got != "2:3" {
+		t.Errorf("Move($).SourceUIDs = %v, want 2:3", got)
+	}
+
+	status, err := client.Status("Archive", &imap.StatusOptions{NumMessages: true}).Wait()
+	if err != nil {
+		t.Fatalf("Status().Wait() = %v", err)
+	} else if *status.NumMessages != 2 {
+		t.Errorf("Archive 中有 %v 封邮件, want 2", *status.NumMessages)
+	}
+	searchData, err := client.UIDSearch(&imap.SearchCriteria{}, nil).Wait()
+	if err != nil {
+		t.Fatalf("UIDSearch().Wait() = %v", err)
+	} else if uids := searchData.AllUIDs(); len(uids) != 1 || uids[0] != 1 {
+		t.Errorf("INBOX 中剩余 UID %v, want [1]", uids)
+	}
+}
+
+// TestSearch_saveUnsupported 测试服务器不支持 SEARCHRES 时不能使用 $。
+func TestSearch_saveUnsupported(t *testing.T) {
+	var cmds []string
+	client := newScriptedClient(t, "", func(cmd string) []string {
+		cmds = append(cmds, cmd)
+		return nil
+	})
+	defer client.Close()
+
+	if _, err := client.Copy(imap.SearchRes(), "Archive").Wait(); err == nil {
+		t.Errorf("Copy($).Wait() = nil, want error")
+	}
+	if _, err := client.Move(imap.SearchRes(), "Archive").Wait(); err == nil {
+		t.Errorf("Move($).Wait() = nil, want error")
+	}
+	if err := client.Noop().Wait(); err != nil {
+		t.Fatalf("Noop().Wait() = %v", err)
+	}
+	if len(cmds) != 1 {
+		t.Errorf("发送的命令 = %q, want 只有 NOOP", cmds)
+	}
+}
+
+// TestSearch_within 测试 WITHIN 扩展的 YOUNGER 和 OLDER 基于内部日期的相对时间。
+func TestSearch_within(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateSelected)
+	defer client.Close()
+	defer server.Close()
+
+	if !client.Caps().Has(imap.CapWithin) {
+		t.Skip("服务器不支持 WITHIN")
+	}
+
+	internalDate := time.Now().Add(-2 * time.Hour)
+	appendCmd := client.Append("INBOX", int64(len(simpleRawMessage)), &imap.AppendOptions{Time: internalDate})
+	appendCmd.Write([]byte(simpleRawMessage))
+	appendCmd.Close()
+	appendData, err := appendCmd.Wait()
+	if err != nil {
+		t.Fatalf("Append().Wait() = %v", err)
+	} else if appendData.UID == 0 {
+		t.Skip("服务器未返回 APPENDUID")
+	}
+	uidSet := []imap.UIDSet{imap.UIDSetNum(appendData.UID)}
+
+	tests := []struct {
+		name     string
+		criteria imap.SearchCriteria
+		want     bool
+	}{
+		{"YOUNGER 一小时", imap.SearchCriteria{Younger: 3600}, false},
+		{"YOUNGER 三小时", imap.SearchCriteria{Younger: 3 * 3600}, true},
+		{"OLDER 一小时", imap.SearchCriteria{Older: 3600}, true},
+		{"OLDER 三小时", imap.SearchCriteria{Older: 3 * 3600}, false},
+	}
+	for _, tc := range tests {
+		tc.criteria.UID = uidSet
+		data, err := client.UIDSearch(&tc.criteria, nil).Wait()
+		if err != nil {
+			t.Fatalf("%v: UIDSearch().Wait() = %v", tc.name, err)
+		}
+		if got := len(data.AllUIDs()) == 1; got != tc.want {
+			t.Errorf("%v: UIDSearch() = %v, want match = %v", tc.name, data.AllUIDs(), tc.want)
+		}
+	}
+}
+
+// TestSearch_withinUnsupported 测试服务器不支持 WITHIN 时不发送 YOUNGER/OLDER。
+func TestSearch_withinUnsupported(t *testing.T) {
+	client := newScriptedClient(t, "", func(cmd string) []string {
+		t.Errorf("不应发送命令: %q", cmd)
+		return nil
+	})
+
+	criteria := imap.SearchCriteria{Not: []imap.SearchCriteria{{Older: 3600}}}
+	if _, err := client.Search(&criteria, nil).Wait(); err == nil {
+		t.Errorf("Search() 在服务器不支持 WITHIN 时应失败")
+	}
+}
+
+// TestSearch_relativeDate 测试 SinceDays 和 LastHours 生成的日期条件编码。
+func TestSearch_relativeDate(t *testing.T) {
+	var searchCmd string
+	client := newScriptedClient(t, " WITHIN", func(cmd string) []string {
+		if strings.HasPrefix(cmd, "SEARCH ") {
+			searchCmd = cmd
+		}
+		return nil
+	})
+
+	var criteria imap.SearchCriteria
+	criteria.SinceDays(7)
+	criteria.LastHours(3)
+	since := time.Now().AddDate(0, 0, -7).Format("2-Jan-2006")
+	if _, err := client.Search(&criteria, nil).Wait(); err != nil {
+		t.Fatalf("Search() = %v", err)
+	}
+	if want := fmt.Sprintf(`SEARCH SINCE "%v" YOUNGER 10800`, since); searchCmd != want {
+		t.Errorf("命令 = %q, want %q", searchCmd, want)
+	}
+	if h, m, s := criteria.Since.Clock(); h != 0 || m != 0 || s != 0 {
+		t.Errorf("Since = %v, want midnight", criteria.Since)
+	}
+}
+
+// TestSearch_modSeq 测试 MODSEQ 只匹配修改序列号不小于 n 的邮件，并返回最大的修改序列号。
+func TestSearch_modSeq(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateSelected)
+	defer client.Close()
+	defer server.Close()
+
+	if !client.Caps().Has(imap.CapCondStore) {
+		t.Skip("服务器不支持 CONDSTORE")
+	}
+
+	appendCmd := client.Append("INBOX", int64(len(simpleRawMessage)), nil)
+	appendCmd.Write([]byte(simpleRawMessage))
+	appendCmd.Close()
+	if _, err := appendCmd.Wait(); err != nil {
+		t.Fatalf("Append().Wait() = %v", err)
+	}
+
+	msgs, err := client.Fetch(imap.SeqSetNum(1, 2), &imap.FetchOptions{ModSeq: true}).Collect()
+	if err != nil {
+		t.Fatalf("Fetch().Collect() = %v", err)
+	} else if len(msgs) != 2 {
+		t.Fatalf("len(msgs) = %v, want %v", len(msgs), 2)
+	} else if msgs[1].ModSeq <= msgs[0].ModSeq {
+		t.Fatalf("ModSeq = %v, %v, want increasing", msgs[0].ModSeq, msgs[1].ModSeq)
+	}
+	modSeq := msgs[1].ModSeq
+
+	tests := []struct {
+		name     string
+		criteria imap.SearchCriteria
+		want     []uint32
+		modSeq   uint64
+	}{
+		{"MODSEQ 第一封", imap.SearchCriteria{ModSeq: &imap.SearchCriteriaModSeq{ModSeq: msgs[0].ModSeq}}, []uint32{1, 2}, modSeq},
+		{"MODSEQ 第二封", imap.SearchCriteria{ModSeq: &imap.SearchCriteriaModSeq{ModSeq: modSeq}}, []uint32{2}, modSeq},
+		{"MODSEQ 之后", imap.SearchCriteria{ModSeq: &imap.SearchCriteriaModSeq{ModSeq: modSeq + 1}}, nil, 0},
+		{"MODSEQ 元数据", imap.SearchCriteria{ModSeq: &imap.SearchCriteriaModSeq{
+			ModSeq:       modSeq,
+			MetadataName: "/flags/\\Seen",
+			MetadataType: imap.SearchCriteriaMetadataAll,
+		}}, []uint32{2}, modSeq},
+		{"无 MODSEQ", imap.SearchCriteria{}, []uint32{1, 2}, 0},
+	}
+	for _, tc := range tests {
+		data, err := client.Search(&tc.criteria, nil).Wait()
+		if err != nil {
+			t.Fatalf("%v: Search().Wait() = %v", tc.name, err)
+		}
+		if seqNums := data.AllSeqNums(); fmt.Sprint(seqNums) != fmt.Sprint(tc.want) {
+			t.Errorf("%v: Search() = %v, want %v", tc.name, seqNums, tc.want)
+		}
+		if data.ModSeq != tc.modSeq {
+			t.Errorf("%v: SearchData.ModSeq = %v, want %v", tc.name, data.ModSeq, tc.modSeq)
+		}
+	}
+}
+
+// TestSearch_largeUIDSet 测试包含数千个分散 UID 的 SEARCH 会被拆分，且命令行不超过长度限制。
+func TestSearch_largeUIDSet(t *testing.T) {
+	conn, server := newMemClientServerPair(t)
+	defer server.Close()
+
+	var debug lockedBuffer
+	client := imapclient.New(conn, &imapclient.Options{DebugWriter: &debug})
+	defer client.Close()
+
+	if err := client.Login(testUsername, testPassword).Wait(); err != nil {
+		t.Fatalf("Login().Wait() = %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		appendCmd := client.Append("INBOX", int64(len(simpleRawMessage)), nil)
+		appendCmd.Write([]byte(simpleRawMessage))
+		appendCmd.Close()
+		if _, err := appendCmd.Wait(); err != nil {
+			t.Fatalf("Append().Wait() = %v", err)
+		}
+	}
+	if _, err := client.Select("INBOX", nil).Wait(); err != nil {
+		t.Fatalf("Select().Wait() = %v", err)
+	}
+
+	// 所有奇数 UID，编码后远超常见的命令行长度限制
+	var uidSet imap.UIDSet
+	for uid := imap.UID(1); uid < 20000; uid += 2 {
+		uidSet.AddNum(uid)
+	}
+	criteria := imap.SearchCriteria{UID: []imap.UIDSet{uidSet}}
+
+	data, err := client.UIDSearch(&criteria, nil).Wait()
+	if err != nil {
+		t.Fatalf("UIDSearch().Wait() = %v", err)
+	}
+	if uids := data.AllUIDs(); fmt.Sprint(uids) != "[1 3]" {
+		t.Errorf("UIDSearch() = %v, want [1 3]", uids)
+	}
+	if n := strings.Count(debug.String(), "UID SEARCH UID "); n < 2 {
+		t.Errorf("发送了 %v 个 UID SEARCH 命令，want 拆分为多个", n)
+	}
+
+	if client.Caps().Has(imap.CapESearch) || client.Caps().Has(imap.CapIMAP4rev2) {
+		options := imap.SearchOptions{ReturnMin: true, ReturnMax: true, ReturnCount: true}
+		data, err := client.UIDSearch(&criteria, &options).Wait()
+		if err != nil {
+			t.Fatalf("UIDSearch(RETURN) = %v", err)
+		}
+		if data.Min != 1 || data.Max != 3 || data.Count != 2 {
+			t.Errorf("UIDSearch(RETURN) = MIN %v MAX %v COUNT %v, want 1 3 2", data.Min, data.Max, data.Count)
+		}
+	}
+
+	for _, line := range strings.Split(debug.String(), "\r\n") {
+		if len(line) > 8192 {
+			t.Errorf("命令行长度 %v 超过限制: %.80q...", len(line), line)
+		}
+	}
+}
@@ -1,47 +1,123 @@
 package imapclient
 
 import (
+	"fmt"
+
 	"github.com/luhaoyun888/go-imap-cn"
 	"github.com/luhaoyun888/go-imap-cn/internal"
+	"github.com/luhaoyun888/go-imap-cn/internal/imapwire"
 )
 
 // Select 发送 SELECT 或 EXAMINE 命令。
 //
 // nil 的选项指针等同于零选项值。
+//
+// 使用 QRESYNC 参数之前必须先通过 Enable 启用 QRESYNC。服务器返回的变化邮件
+// 通过 UnilateralDataHandler.Fetch 传递，已删除邮件的 UID 保存在 SelectData.Vanished 中。
 func (c *Client) Select(mailbox string, options *imap.SelectOptions) *SelectCommand {
-	cmdName := "SELECT"                     // 默认命令为 SELECT
-	if options != nil && options.ReadOnly { // 如果选项为只读，则使用 EXAMINE 命令
+	if options == nil {
+		options = new(imap.SelectOptions)
+	}
+
+	cmdName := "SELECT"   // 默认命令为 SELECT
+	if options.ReadOnly { // 如果选项为只读，则使用 EXAMINE 命令
 		cmdName = "EXAMINE"
 	}
 
-	cmd := &SelectCommand{mailbox: mailbox}  // 创建选择命令
-	enc := c.beginCommand(cmdName, cmd)      // 开始命令编码
-	enc.SP().Mailbox(mailbox)                // 添加邮箱参数
-	if options != nil && options.CondStore { // 如果启用条件存储
-		enc.SP().Special('(').Atom("CONDSTORE").Special(')') // 添加条件存储标志
+	if options.QResync != nil {
+		c.mutex.Lock()
+		qresync := c.enabled.Has(imap.CapQResync)
+		c.mutex.Unlock()
+		if !qresync {
+			err := fmt.Errorf("imapclient: 使用 QRESYNC 参数之前必须先启用 QRESYNC")
+			return &SelectCommand{commandBase: newFailedCommandBase(err), mailbox: mailbox}
+		}
+	}
+
+	cmd := &SelectCommand{mailbox: mailbox, readOnly: options.ReadOnly, condStore: options.CondStore} // 创建选择命令
+	enc := c.beginCommand(cmdName, cmd)                                                               // 开始命令编码
+	enc.SP().Mailbox(mailbox)                                                                         // 添加邮箱参数
+	if options.CondStore || options.QResync != nil {
+		enc.SP().Special('(')
+		if options.CondStore { // 如果启用条件存储
+			enc.Atom("CONDSTORE") // 添加条件存储标志
+			if options.QResync != nil {
+				enc.SP()
+			}
+		}
+		if options.QResync != nil {
+			writeQResyncParam(enc.Encoder, options.QResync)
+		}
+		enc.Special(')')
 	}
 	enc.end()  // 结束命令
 	return cmd // 返回选择命令
 }
 
-// Unselect 发送 UNSELECT 命令。
+// writeQResyncParam 写入 SELECT 的 QRESYNC 参数。
+func writeQResyncParam(enc *imapwire.Encoder, options *imap.QResyncOptions) {
+	enc.Atom("QRESYNC").SP().Special('(')
+	enc.Number(options.UIDValidity).SP().ModSeq(options.ModSeq)
+	if len(options.KnownUIDs) > 0 {
+		enc.SP().NumSet(options.KnownUIDs)
+	}
+	if seqMatch := options.SeqMatch; seqMatch != nil {
+		enc.SP().Special('(').NumSet(seqMatch.SeqNums).SP().NumSet(seqMatch.UIDs).Special(')')
+	}
+	enc.Special(')')
+}
+
+// Unselect 发送 UNSELECT 命令，关闭当前邮箱并回到已认证状态，不会删除带有
+// \Deleted 标志的邮件。
 //
-// 此命令要求支持 IMAP4rev2 或 UNSELECT 扩展。
+// 此命令要求支持 IMAP4rev2 或 UNSELECT 扩展。服务器不支持时，如果当前邮箱是
+// 以只读方式选择的（此时 CLOSE 不会删除邮件），则改为发送 CLOSE；否则返回错误。
+// 调用者可以改用 CloseMailbox，但要注意 CLOSE 会删除带有 \Deleted 标志的邮件。
 func (c *Client) Unselect() *Command {
-	cmd := &unselectCommand{}             // 创建 UNSELECT 命令
-	c.beginCommand("UNSELECT", cmd).end() // 开始并结束命令
-	return &cmd.Command                   // 返回命令
+	name := "UNSELECT"
+	if !c.Caps().Has(imap.CapUnselect) {
+		if mbox := c.Mailbox(); mbox == nil || !mbox.ReadOnly {
+			err := fmt.Errorf("imapclient: 服务器不支持 UNSELECT，CLOSE 会删除带有 \\Deleted 标志的邮件")
+			return &Command{commandBase: newFailedCommandBase(err)}
+		}
+		name = "CLOSE" // 只读邮箱的 CLOSE 不会删除邮件
+	}
+
+	cmd := &unselectCommand{}       // 创建 UNSELECT 命令
+	c.beginCommand(name, cmd).end() // 开始并结束命令
+	return &cmd.Command             // 返回命令
 }
 
-// UnselectAndExpunge 发送 CLOSE 命令。
+// CloseMailbox 发送 CLOSE 命令，关闭当前选择的邮箱。
 //
-// CLOSE 隐式执行静默 EXPUNGE 命令。
-func (c *Client) UnselectAndExpunge() *Command {
+// CLOSE 隐式执行静默 EXPUNGE 命令（以只读方式选择的邮箱除外），命令成功后
+// 连接回到已认证状态。注意 Client.Close 关闭的是网络连接而不是邮箱。
+func (c *Client) CloseMailbox() *Command {
 	cmd := &unselectCommand{}          // 创建 UNSELECT 命令
 	c.beginCommand("CLOSE", cmd).end() // 开始并结束命令
 	return &cmd.Command                // 返回命令
 }
 
+// UnselectAndExpunge 发送 CLOSE 命令，等同于 CloseMailbox。
+//
+// CLOSE 隐式执行静默 EXPUNGE 命令。
+func (c *Client) UnselectAndExpunge() *Command {
+	return c.CloseMailbox()
+}
+
+// Check 发送 CHECK 命令，请求服务器为当前选择的邮箱执行检查点操作。
+//
+// IMAP4rev2 删除了 CHECK，如果服务器不支持 IMAP4rev1，则改为发送等效的 NOOP。
+func (c *Client) Check() *Command {
+	name := "CHECK"
+	if !c.Caps().Has(imap.CapIMAP4rev1) {
+		name = "NOOP"
+	}
+	cmd := &Command{}
+	c.beginCommand(name, cmd).end()
+	return cmd
+}
+
 func (c *Client) handleFlags() error {
 	flags, err := internal.ExpectFlagList(c.dec) // 读取标志列表
 	if err != nil {
@@ -77,6 +153,10 @@ func (c *Client) handleExists(num uint32) error {
 		}
 		c.mutex.Unlock() // 解锁
 
+		if t := c.SeqNumTracker(); t != nil {
+			t.Exists(num)
+		}
+
 		if handler := c.options.unilateralDataHandler().Mailbox; handler != nil {
 			handler(&UnilateralDataMailbox{NumMessages: &num}) // 调用处理程序
 		}
@@ -84,11 +164,20 @@ func (c *Client) handleExists(num uint32) error {
 	return nil // 返回成功
 }
 
+// handleRecent 处理 IMAP4rev1 的 RECENT 响应。选择邮箱之外的 RECENT 响应会被忽略。
+func (c *Client) handleRecent(num uint32) {
+	if cmd := findPendingCmdByType[*SelectCommand](c); cmd != nil {
+		cmd.data.NumRecent = num
+	}
+}
+
 // SelectCommand 是 SELECT 命令。
 type SelectCommand struct {
 	commandBase
-	mailbox string          // 邮箱名称
-	data    imap.SelectData // 选择数据
+	mailbox   string          // 邮箱名称
+	readOnly  bool            // 是否使用 EXAMINE 选择
+	condStore bool            // 是否带有 CONDSTORE 参数，成功后服务器视为已启用 CONDSTORE
+	data      imap.SelectData // 选择数据
 }
 
 func (cmd *SelectCommand) Wait() (*imap.SelectData, error) {
@@ -1,9 +1,12 @@
 package imapclient_test
 
 import (
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/luhaoyun888/go-imap-cn"
+	"github.com/luhaoyun888/go-imap-cn/imapclient"
 )
 
 func TestSelect(t *testing.T) {
@@ -18,3 +21,339 @@ func TestSelect(t *testing.T) {
 		t.Errorf("SelectData.NumMessages = %v, want %v", data.NumMessages, 1) // 如果不符合，记录错误
 	}
 }
+
+// TestSelect_recent 测试 IMAP4rev1 下的 \Recent 语义：只有第一个以读写方式选择邮箱的会话会看到新邮件。
+func TestSelect_recent(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateAuthenticated)
+	defer client.Close()
+	defer server.Close()
+
+	recentFlag := imap.Flag("\\Recent")
+
+	// EXAMINE 不会清除 \Recent 标志
+	data, err := client.Select("INBOX", &imap.SelectOptions{ReadOnly: true}).Wait()
+	if err != nil {
+		t.Fatalf("Examine() = %v", err)
+	} else if data.NumRecent != 1 {
+		t.Errorf("EXAMINE: SelectData.NumRecent = %v, want %v", data.NumRecent, 1)
+	}
+
+	data, err = client.Select("INBOX", nil).Wait()
+	if err != nil {
+		t.Fatalf("Select() = %v", err)
+	} else if data.NumRecent != 1 {
+		t.Errorf("SELECT: SelectData.NumRecent = %v, want %v", data.NumRecent, 1)
+	}
+
+	msgs, err := client.Fetch(imap.SeqSetNum(1), &imap.FetchOptions{Flags: true}).Collect()
+	if err != nil {
+		t.Fatalf("Fetch() = %v", err)
+	} else if len(msgs) != 1 || !containsFlag(msgs[0].Flags, recentFlag) {
+		t.Errorf("FETCH FLAGS 应包含 \\Recent: %v", msgs)
+	}
+
+	searchData, err := client.Search(&imap.SearchCriteria{Flag: []imap.Flag{recentFlag}}, nil).Wait()
+	if err != nil {
+		t.Fatalf("Search() = %v", err)
+	} else if seqNums := searchData.AllSeqNums(); len(seqNums) != 1 {
+		t.Errorf("SEARCH RECENT = %v, want [1]", seqNums)
+	}
+
+	// 邮件已被上一次 SELECT 看到，不再带有 \Recent 标志
+	data, err = client.Select("INBOX", nil).Wait()
+	if err != nil {
+		t.Fatalf("Select() = %v", err)
+	} else if data.NumRecent != 0 {
+		t.Errorf("第二次 SELECT: SelectData.NumRecent = %v, want %v", data.NumRecent, 0)
+	}
+}
+
+// TestSelect_readOnly 测试服务器拒绝修改以 EXAMINE 选择的邮箱的命令。
+func TestSelect_readOnly(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateAuthenticated)
+	defer client.Close()
+	defer server.Close()
+
+	if err := client.Create("Archive", nil).Wait(); err != nil {
+		t.Fatalf("Create() = %v", err)
+	}
+	if _, err := client.Select("INBOX", nil).Wait(); err != nil {
+		t.Fatalf("Select() = %v", err)
+	}
+	storeFlags := imap.StoreFlags{Op: imap.StoreFlagsAdd, Silent: true, Flags: []imap.Flag{imap.FlagDeleted}}
+	if err := client.Store(imap.SeqSetNum(1), &storeFlags, nil).Close(); err != nil {
+		t.Fatalf("Store() = %v", err)
+	}
+
+	if _, err := client.Select("INBOX", &imap.SelectOptions{ReadOnly: true}).Wait(); err != nil {
+		t.Fatalf("Examine() = %v", err)
+	}
+
+	checkCannot := func(name string, err error) {
+		t.Helper()
+		if !imap.IsNo(err) || imap.ErrorCode(err) != imap.ResponseCodeCannot {
+			t.Errorf("%v = %v, want NO [CANNOT]", name, err)
+		}
+	}
+
+	storeFlags = imap.StoreFlags{Op: imap.StoreFlagsAdd, Silent: true, Flags: []imap.Flag{imap.FlagFlagged}}
+	checkCannot("Store()", client.Store(imap.SeqSetNum(1), &storeFlags, nil).Close())
+	_, err := client.Expunge().Collect()
+	checkCannot("Expunge()", err)
+	_, err = client.UIDExpunge(imap.UIDSetNum(1)).Collect()
+	checkCannot("UIDExpunge()", err)
+	_, err = client.Copy(imap.SeqSetNum(1), "INBOX").Wait()
+	checkCannot("Copy(INBOX)", err)
+	_, err = client.Move(imap.SeqSetNum(1), "Archive").Wait()
+	checkCannot("Move()", err)
+
+	appendCmd := client.Append("INBOX", int64(len(simpleRawMessage)), nil)
+	appendCmd.Write([]byte(simpleRawMessage))
+	appendCmd.Close()
+	_, err = appendCmd.Wait()
+	checkCannot("Append(INBOX)", err)
+
+	// 复制到其他邮箱不会修改当前邮箱
+	if _, err := client.Copy(imap.SeqSetNum(1), "Archive").Wait(); err != nil {
+		t.Errorf("Copy(Archive) = %v", err)
+	}
+
+	// 读取正文不会设置 \Seen 标志
+	bodySection := &imap.FetchItemBodySection{}
+	if _, err := client.Fetch(imap.SeqSetNum(1), &imap.FetchOptions{BodySection: []*imap.FetchItemBodySection{bodySection}}).Collect(); err != nil {
+		t.Fatalf("Fetch(BODY[]) = %v", err)
+	}
+	msgs, err := client.Fetch(imap.SeqSetNum(1), &imap.FetchOptions{Flags: true}).Collect()
+	if err != nil {
+		t.Fatalf("Fetch(FLAGS) = %v", err)
+	} else if len(msgs) != 1 || containsFlag(msgs[0].Flags, imap.FlagSeen) || containsFlag(msgs[0].Flags, imap.FlagFlagged) {
+		t.Errorf("Fetch(FLAGS) = %v, want neither \\Seen nor \\Flagged", msgs)
+	}
+
+	// 只读邮箱的 CLOSE 不会删除邮件
+	if err := client.UnselectAndExpunge().Wait(); err != nil {
+		t.Fatalf("UnselectAndExpunge() = %v", err)
+	}
+	data, err := client.Select("INBOX", nil).Wait()
+	if err != nil {
+		t.Fatalf("Select() = %v", err)
+	} else if data.NumMessages != 1 {
+		t.Errorf("SelectData.NumMessages = %v, want %v", data.NumMessages, 1)
+	}
+
+	// 以读写方式重新选择后可以修改邮箱
+	if err := client.Store(imap.SeqSetNum(1), &storeFlags, nil).Close(); err != nil {
+		t.Errorf("Store() = %v", err)
+	}
+}
+
+// TestSelect_unselect 测试 UNSELECT 回到已认证状态且不删除邮件。
+func TestSelect_unselect(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateSelected)
+	defer client.Close()
+	defer server.Close()
+
+	storeFlags := imap.StoreFlags{Op: imap.StoreFlagsAdd, Silent: true, Flags: []imap.Flag{imap.FlagDeleted}}
+	if err := client.Store(imap.SeqSetNum(1), &storeFlags, nil).Close(); err != nil {
+		t.Fatalf("Store() = %v", err)
+	}
+	if err := client.Unselect().Wait(); err != nil {
+		t.Fatalf("Unselect() = %v", err)
+	}
+	if state := client.State(); state != imap.ConnStateAuthenticated {
+		t.Errorf("State() = %v, want %v", state, imap.ConnStateAuthenticated)
+	}
+
+	data, err := client.Select("INBOX", nil).Wait()
+	if err != nil {
+		t.Fatalf("Select() = %v", err)
+	} else if data.NumMessages != 1 {
+		t.Errorf("SelectData.NumMessages = %v, want 1", data.NumMessages)
+	}
+}
+
+// TestSelect_unselectFallback 测试服务器不支持 UNSELECT 时，只在只读邮箱上回退为 CLOSE。
+func TestSelect_unselectFallback(t *testing.T) {
+	var cmds []string
+	client := newScriptedClient(t, "", func(cmd string) []string {
+		cmds = append(cmds, cmd)
+		return nil
+	})
+
+	if _, err := client.Select("INBOX", nil).Wait(); err != nil {
+		t.Fatalf("Select() = %v", err)
+	}
+	if err := client.Unselect().Wait(); err == nil {
+		t.Errorf("Unselect() 在读写邮箱上应失败")
+	}
+	if state := client.State(); state != imap.ConnStateSelected {
+		t.Errorf("State() = %v, want %v", state, imap.ConnStateSelected)
+	}
+
+	if _, err := client.Select("INBOX", &imap.SelectOptions{ReadOnly: true}).Wait(); err != nil {
+		t.Fatalf("Select(ReadOnly) = %v", err)
+	}
+	if err := client.Unselect().Wait(); err != nil {
+		t.Fatalf("Unselect() = %v", err)
+	}
+	if state := client.State(); state != imap.ConnStateAuthenticated {
+		t.Errorf("State() = %v, want %v", state, imap.ConnStateAuthenticated)
+	}
+
+	if want := []string{"SELECT INBOX", "EXAMINE INBOX", "CLOSE"}; !reflect.DeepEqual(cmds, want) {
+		t.Errorf("命令 = %q, want %q", cmds, want)
+	}
+}
+
+// TestSelect_closeMailbox 测试 CHECK，以及 CLOSE 删除带有 \Deleted 的邮件并回到已认证状态。
+func TestSelect_closeMailbox(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateSelected)
+	defer client.Close()
+	defer server.Close()
+
+	storeFlags := imap.StoreFlags{Op: imap.StoreFlagsAdd, Silent: true, Flags: []imap.Flag{imap.FlagDeleted}}
+	if err := client.Store(imap.SeqSetNum(1), &storeFlags, nil).Close(); err != nil {
+		t.Fatalf("Store() = %v", err)
+	}
+	if err := client.Check().Wait(); err != nil {
+		t.Fatalf("Check() = %v", err)
+	}
+
+	if err := client.CloseMailbox().Wait(); err != nil {
+		t.Fatalf("CloseMailbox() = %v", err)
+	}
+	if state := client.State(); state != imap.ConnStateAuthenticated {
+		t.Errorf("State() = %v, want %v", state, imap.ConnStateAuthenticated)
+	}
+	if mbox := client.Mailbox(); mbox != nil {
+		t.Errorf("Mailbox() = %v, want nil", mbox)
+	}
+
+	data, err := client.Select("INBOX", nil).Wait()
+	if err != nil {
+		t.Fatalf("Select() = %v", err)
+	} else if data.NumMessages != 0 {
+		t.Errorf("SelectData.NumMessages = %v, want 0", data.NumMessages)
+	}
+}
+
+// TestSelect_recentIMAP4rev2 测试启用 IMAP4rev2 后不会返回 \Recent。
+func TestSelect_recentIMAP4rev2(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateAuthenticated)
+	defer client.Close()
+	defer server.Close()
+
+	if !client.Caps().Has(imap.CapIMAP4rev2) {
+		t.Skip("服务器不支持 IMAP4rev2")
+	}
+	if _, err := client.Enable(imap.CapIMAP4rev2).Wait(); err != nil {
+		t.Fatalf("Enable() = %v", err)
+	}
+
+	data, err := client.Select("INBOX", nil).Wait()
+	if err != nil {
+		t.Fatalf("Select() = %v", err)
+	} else if data.NumRecent != 0 {
+		t.Errorf("SelectData.NumRecent = %v, want %v", data.NumRecent, 0)
+	}
+
+	msgs, err := client.Fetch(imap.SeqSetNum(1), &imap.FetchOptions{Flags: true}).Collect()
+	if err != nil {
+		t.Fatalf("Fetch() = %v", err)
+	} else if len(msgs) != 1 || containsFlag(msgs[0].Flags, imap.Flag("\\Recent")) {
+		t.Errorf("FETCH FLAGS 不应包含 \\Recent: %v", msgs)
+	}
+}
+
+func containsFlag(flags []imap.Flag, flag imap.Flag) bool {
+	for _, f := range flags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+func TestSelect_qresync(t *testing.T) {
+	conn, server := newMemClientServerPair(t)
+	defer server.Close()
+
+	fetched := make(chan imap.UID, 16)
+	options := imapclient.Options{
+		UnilateralDataHandler: &imapclient.UnilateralDataHandler{
+			Fetch: func(msg *imapclient.FetchMessageData) {
+				buf, err := msg.Collect()
+				if err != nil {
+					t.Errorf("FetchMessageData.Collect() = %v", err)
+					return
+				}
+				fetched <- buf.UID
+			},
+		},
+	}
+	client := imapclient.New(conn, &options)
+	defer client.Close()
+
+	if err := client.Login(testUsername, testPassword).Wait(); err != nil {
+		t.Fatalf("Login().Wait() = %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		appendCmd := client.Append("INBOX", int64(len(simpleRawMessage)), nil)
+		appendCmd.Write([]byte(simpleRawMessage))
+		appendCmd.Close()
+		if _, err := appendCmd.Wait(); err != nil {
+			t.Fatalf("Append().Wait() = %v", err)
+		}
+	}
+
+	selectData, err := client.Select("INBOX", &imap.SelectOptions{CondStore: true}).Wait()
+	if err != nil {
+		t.Fatalf("Select().Wait() = %v", err)
+	}
+
+	// 修改 UID 2 的标志并删除 UID 3
+	storeFlags := imap.StoreFlags{Op: imap.StoreFlagsAdd, Silent: true, Flags: []imap.Flag{imap.FlagFlagged}}
+	if err := client.Store(imap.UIDSetNum(2), &storeFlags, nil).Close(); err != nil {
+		t.Fatalf("Store().Close() = %v", err)
+	}
+	storeFlags.Flags = []imap.Flag{imap.FlagDeleted}
+	if err := client.Store(imap.UIDSetNum(3), &storeFlags, nil).Close(); err != nil {
+		t.Fatalf("Store().Close() = %v", err)
+	}
+	if err := client.Expunge().Close(); err != nil {
+		t.Fatalf("Expunge().Close() = %v", err)
+	}
+	if err := client.Unselect().Wait(); err != nil {
+		t.Fatalf("Unselect().Wait() = %v", err)
+	}
+
+	// 未启用 QRESYNC 时不能使用 QRESYNC 参数
+	qresync := &imap.QResyncOptions{UIDValidity: selectData.UIDValidity, ModSeq: selectData.HighestModSeq}
+	if _, err := client.Select("INBOX", &imap.SelectOptions{QResync: qresync}).Wait(); err == nil {
+		t.Fatalf("Select(QResync) 在启用 QRESYNC 之前应失败")
+	}
+	if _, err := client.Enable(imap.CapQResync).Wait(); err != nil {
+		t.Fatalf("Enable(QRESYNC).Wait() = %v", err)
+	}
+
+	data, err := client.Select("INBOX", &imap.SelectOptions{QResync: qresync}).Wait()
+	if err != nil {
+		t.Fatalf("Select(QResync).Wait() = %v", err)
+	}
+	if want := imap.UIDSetNum(3); data.Vanished.String() != want.String() {
+		t.Errorf("SelectData.Vanished = %v, want %v", data.Vanished, want)
+	}
+	select {
+	case uid := <-fetched:
+		if uid != 2 {
+			t.Errorf("FETCH UID = %v, want 2", uid)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("未收到变化邮件的 FETCH 响应")
+	}
+	select {
+	case uid := <-fetched:
+		t.Errorf("收到意外的 FETCH 响应: UID %v", uid)
+	default:
+	}
+}
@@ -0,0 +1,107 @@
+package imapclient
+
+import (
+	"sync"
+
+	"github.com/luhaoyun888/go-imap-cn"
+)
+
+// SeqNumTracker 在客户端维护已选邮箱中邮件序号与 UID 的对应关系。
+//
+// 跟踪器根据 EXISTS、EXPUNGE 和 VANISHED 响应调整序号，并从带有 UID 的 FETCH
+// 响应中得知每个序号对应的 UID。设置 Options.TrackSeqNums 后，客户端会在选择
+// 邮箱时自动创建并更新跟踪器，参见 Client.SeqNumTracker。也可以手动创建跟踪器，
+// 并在 UnilateralDataHandler 中调用其方法。
+//
+// 跟踪器可以被多个 goroutine 同时使用。
+type SeqNumTracker struct {
+	mutex sync.Mutex
+	uids  []imap.UID // uids[i] 是序号为 i+1 的邮件的 UID，0 表示尚未得知
+}
+
+// NewSeqNumTracker 为包含 numMessages 封邮件的邮箱创建跟踪器。邮件的 UID 最初都是未知的。
+func NewSeqNumTracker(numMessages uint32) *SeqNumTracker {
+	return &SeqNumTracker{uids: make([]imap.UID, numMessages)}
+}
+
+// NumMessages 返回邮箱中的邮件数量。
+func (t *SeqNumTracker) NumMessages() uint32 {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return uint32(len(t.uids))
+}
+
+// UID 返回序号为 seqNum 的邮件的 UID。序号无效或 UID 尚未得知时返回 0。
+func (t *SeqNumTracker) UID(seqNum uint32) imap.UID {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if seqNum == 0 || seqNum > uint32(len(t.uids)) {
+		return 0
+	}
+	return t.uids[seqNum-1]
+}
+
+// SeqNum 返回 UID 为 uid 的邮件的序号。未找到时返回 0。
+func (t *SeqNumTracker) SeqNum(uid imap.UID) uint32 {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if uid == 0 {
+		return 0
+	}
+	for i, other := range t.uids {
+		if other == uid {
+			return uint32(i) + 1
+		}
+	}
+	return 0
+}
+
+// SetUID 记录序号为 seqNum 的邮件的 UID，通常在收到带有 UID 的 FETCH 响应时调用。
+// 超出邮件数量的序号会被忽略。
+func (t *SeqNumTracker) SetUID(seqNum uint32, uid imap.UID) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if seqNum == 0 || seqNum > uint32(len(t.uids)) {
+		return
+	}
+	t.uids[seqNum-1] = uid
+}
+
+// Exists 处理 EXISTS 响应。新邮件追加在末尾，其 UID 未知。
+//
+// 邮件数量只会因 EXPUNGE 而减少，因此小于当前数量的 numMessages 会截断跟踪器，
+// 这通常意味着跟踪器已经与服务器不一致。
+func (t *SeqNumTracker) Exists(numMessages uint32) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if n := uint32(len(t.uids)); numMessages > n {
+		t.uids = append(t.uids, make([]imap.UID, numMessages-n)...)
+	} else {
+		t.uids = t.uids[:numMessages]
+	}
+}
+
+// Expunge 处理 EXPUNGE 响应：删除序号为 seqNum 的邮件，之后的邮件序号减一。
+func (t *SeqNumTracker) Expunge(seqNum uint32) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if seqNum == 0 || seqNum > uint32(len(t.uids)) {
+		return
+	}
+	t.uids = append(t.uids[:seqNum-1], t.uids[seqNum:]...)
+}
+
+// Vanished 处理不带 EARLIER 的 VANISHED 响应：删除 UID 在 uids 中的邮件。
+//
+// UID 未知的邮件无法被删除，此时跟踪器会与服务器不一致。
+func (t *SeqNumTracker) Vanished(uids imap.UIDSet) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	filtered := t.uids[:0]
+	for _, uid := range t.uids {
+		if uid == 0 || !uids.Contains(uid) {
+			filtered = append(filtered, uid)
+		}
+	}
+	t.uids = filtered
+}
@@ -0,0 +1,123 @@
+package imapclient_test
+
+import (
+	"testing"
+
+	"github.com/luhaoyun888/go-imap-cn"
+	"github.com/luhaoyun888/go-imap-cn/imapclient"
+)
+
+// checkSeqNums 检查跟踪器中序号 1 到 n 的 UID 依次为 want。
+func checkSeqNums(t *testing.T, tracker *imapclient.SeqNumTracker, want []imap.UID) {
+	t.Helper()
+	if n := tracker.NumMessages(); n != uint32(len(want)) {
+		t.Fatalf("NumMessages() = %v, want %v", n, len(want))
+	}
+	for i, uid := range want {
+		seqNum := uint32(i) + 1
+		if got := tracker.UID(seqNum); got != uid {
+			t.Errorf("UID(%v) = %v, want %v", seqNum, got, uid)
+		}
+		if uid != 0 {
+			if got := tracker.SeqNum(uid); got != seqNum {
+				t.Errorf("SeqNum(%v) = %v, want %v", uid, got, seqNum)
+			}
+		}
+	}
+}
+
+func TestSeqNumTracker(t *testing.T) {
+	tracker := imapclient.NewSeqNumTracker(5)
+	for i := uint32(1); i <= 5; i++ {
+		tracker.SetUID(i, imap.UID(i*10))
+	}
+	checkSeqNums(t, tracker, []imap.UID{10, 20, 30, 40, 50})
+
+	// 按降序删除第 4 和第 2 封邮件，再删除新的第 1 封邮件
+	tracker.Expunge(4)
+	tracker.Expunge(2)
+	tracker.Expunge(1)
+	checkSeqNums(t, tracker, []imap.UID{30, 50})
+	if seqNum := tracker.SeqNum(20); seqNum != 0 {
+		t.Errorf("SeqNum(20) = %v, want 0", seqNum)
+	}
+
+	tracker.Exists(4)
+	tracker.SetUID(3, 60)
+	checkSeqNums(t, tracker, []imap.UID{30, 50, 60, 0})
+
+	tracker.Vanished(imap.UIDSetNum(30, 60))
+	checkSeqNums(t, tracker, []imap.UID{50, 0})
+
+	// 超出范围的序号被忽略
+	tracker.Expunge(3)
+	tracker.SetUID(3, 70)
+	checkSeqNums(t, tracker, []imap.UID{50, 0})
+}
+
+func TestClient_SeqNumTracker(t *testing.T) {
+	conn, server := newMemClientServerPair(t)
+	defer server.Close()
+
+	client := imapclient.New(conn, &imapclient.Options{TrackSeqNums: true})
+	defer client.Close()
+
+	if err := client.Login(testUsername, testPassword).Wait(); err != nil {
+		t.Fatalf("Login().Wait() = %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		appendCmd := client.Append("INBOX", int64(len(simpleRawMessage)), nil)
+		appendCmd.Write([]byte(simpleRawMessage))
+		appendCmd.Close()
+		if _, err := appendCmd.Wait(); err != nil {
+			t.Fatalf("Append().Wait() = %v", err)
+		}
+	}
+	if client.SeqNumTracker() != nil {
+		t.Errorf("SeqNumTracker() != nil before SELECT")
+	}
+	if _, err := client.Select("INBOX", nil).Wait(); err != nil {
+		t.Fatalf("Select().Wait() = %v", err)
+	}
+
+	tracker := client.SeqNumTracker()
+	if tracker == nil {
+		t.Fatalf("SeqNumTracker() = nil")
+	}
+	checkSeqNums(t, tracker, []imap.UID{0, 0, 0, 0, 0})
+
+	uids, err := fetchUIDs(client)
+	if err != nil {
+		t.Fatalf("Fetch() = %v", err)
+	}
+	checkSeqNums(t, tracker, uids)
+
+	storeFlags := imap.StoreFlags{Op: imap.StoreFlagsAdd, Silent: true, Flags: []imap.Flag{imap.FlagDeleted}}
+	var seqSet imap.SeqSet
+	seqSet.AddNum(1, 3, 4)
+	if err := client.Store(seqSet, &storeFlags, nil).Close(); err != nil {
+		t.Fatalf("Store().Close() = %v", err)
+	}
+	if err := client.Expunge().Close(); err != nil {
+		t.Fatalf("Expunge().Close() = %v", err)
+	}
+	checkSeqNums(t, tracker, []imap.UID{uids[1], uids[4]})
+
+	appendCmd := client.Append("INBOX", int64(len(simpleRawMessage)), nil)
+	appendCmd.Write([]byte(simpleRawMessage))
+	appendCmd.Close()
+	if _, err := appendCmd.Wait(); err != nil {
+		t.Fatalf("Append().Wait() = %v", err)
+	}
+	if err := client.Noop().Wait(); err != nil {
+		t.Fatalf("Noop().Wait() = %v", err)
+	}
+	checkSeqNums(t, tracker, []imap.UID{uids[1], uids[4], 0})
+
+	if err := client.Unselect().Wait(); err != nil {
+		t.Fatalf("Unselect().Wait() = %v", err)
+	}
+	if client.SeqNumTracker() != nil {
+		t.Errorf("SeqNumTracker() != nil after UNSELECT")
+	}
+}
@@ -0,0 +1,61 @@
+package imapclient
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/luhaoyun888/go-imap-cn"
+)
+
+// serverTimeProbe 是 ServerTime 追加的临时邮件。
+const serverTimeProbe = "Subject: imapclient server time probe\r\n\r\n"
+
+// ServerTime 估算服务器的当前时间，可用于检测本地与服务器之间的时钟偏差。
+//
+// IMAP 没有查询服务器时间的命令。ServerTime 向当前选择的邮箱追加一封带有 \Seen
+// 和 \Deleted 标志的临时邮件，读取服务器为其设置的 INTERNALDATE，然后用
+// UID EXPUNGE 删除这封邮件。因此调用前必须以读写方式选择邮箱，且服务器必须支持
+// UIDPLUS 或 IMAP4rev2。
+//
+// INTERNALDATE 的精度为一秒，返回的时间可能比服务器收到 APPEND 时早不到一秒。
+// 如果不便修改邮箱，也可以比较新邮件的 INTERNALDATE 与收到 EXISTS 时的本地时间
+// 来粗略推断时钟偏差。
+func (c *Client) ServerTime() (time.Time, error) {
+	mbox := c.Mailbox()
+	if mbox == nil {
+		return time.Time{}, fmt.Errorf("imapclient: ServerTime 需要先选择邮箱")
+	}
+	if !c.Caps().Has(imap.CapUIDPlus) {
+		return time.Time{}, fmt.Errorf("imapclient: 服务器不支持 UIDPLUS")
+	}
+
+	appendCmd := c.Append(mbox.Name, int64(len(serverTimeProbe)), &imap.AppendOptions{
+		Flags: []imap.Flag{imap.FlagSeen, imap.FlagDeleted},
+	})
+	if _, err := appendCmd.Write([]byte(serverTimeProbe)); err != nil {
+		appendCmd.Close()
+		return time.Time{}, err
+	}
+	if err := appendCmd.Close(); err != nil {
+		return time.Time{}, err
+	}
+	data, err := appendCmd.Wait()
+	if err != nil {
+		return time.Time{}, err
+	} else if data.UID == 0 {
+		return time.Time{}, fmt.Errorf("imapclient: 服务器未返回 APPENDUID")
+	}
+
+	uids := imap.UIDSetNum(data.UID)
+	msgs, fetchErr := c.Fetch(uids, &imap.FetchOptions{UID: true, InternalDate: true}).Collect()
+	// 无论 FETCH 是否成功，都删除临时邮件
+	if _, err := c.UIDExpunge(uids).Collect(); err != nil {
+		return time.Time{}, err
+	}
+	if fetchErr != nil {
+		return time.Time{}, fetchErr
+	} else if len(msgs) != 1 || msgs[0].InternalDate.IsZero() {
+		return time.Time{}, fmt.Errorf("imapclient: 服务器未返回临时邮件的 INTERNALDATE")
+	}
+	return msgs[0].InternalDate, nil
+}
@@ -5,24 +5,21 @@ import (
 	"github.com/luhaoyun888/go-imap-cn/internal/imapwire"
 )
 
-// SortKey 表示排序关键字
-type SortKey string
+// SortKey 是 imap.SortKey 的别名。
+type SortKey = imap.SortKey
 
 const (
-	SortKeyArrival SortKey = "ARRIVAL" // 按到达时间排序
-	SortKeyCc      SortKey = "CC"      // 按抄送人排序
-	SortKeyDate    SortKey = "DATE"    // 按日期排序
-	SortKeyFrom    SortKey = "FROM"    // 按发件人排序
-	SortKeySize    SortKey = "SIZE"    // 按大小排序
-	SortKeySubject SortKey = "SUBJECT" // 按主题排序
-	SortKeyTo      SortKey = "TO"      // 按收件人排序
+	SortKeyArrival = imap.SortKeyArrival // 按到达时间排序
+	SortKeyCc      = imap.SortKeyCc      // 按抄送人排序
+	SortKeyDate    = imap.SortKeyDate    // 按日期排序
+	SortKeyFrom    = imap.SortKeyFrom    // 按发件人排序
+	SortKeySize    = imap.SortKeySize    // 按大小排序
+	SortKeySubject = imap.SortKeySubject // 按主题排序
+	SortKeyTo      = imap.SortKeyTo      // 按收件人排序
 )
 
-// SortCriterion 表示排序标准
-type SortCriterion struct {
-	Key     SortKey // 排序关键字
-	Reverse bool    // 是否反向排序
-}
+// SortCriterion 是 imap.SortCriterion 的别名。
+type SortCriterion = imap.SortCriterion
 
 // SortOptions 包含 SORT 命令的选项。
 type SortOptions struct {
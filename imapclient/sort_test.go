@@ -0,0 +1,78 @@
+package imapclient_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/luhaoyun888/go-imap-cn"
+	"github.com/luhaoyun888/go-imap-cn/imapclient"
+)
+
+func TestSort(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateSelected)
+	defer client.Close()
+	defer server.Close()
+
+	if !client.Caps().Has(imap.CapSort) {
+		t.Skip("服务器不支持 SORT")
+	}
+
+	messages := []struct {
+		from, subject, body string
+	}{
+		{"carol@example.org", "Re: banana", "短"},
+		{"alice@example.org", "apple", strings.Repeat("长", 100)},
+		{"Bob <bob@example.org>", "Fwd: [list] Cherry", strings.Repeat("中", 10)},
+	}
+	for _, msg := range messages {
+		raw := "From: " + msg.from + "\r\nSubject: " + msg.subject + "\r\n\r\n" + msg.body
+		appendCmd := client.Append("INBOX", int64(len(raw)), nil)
+		appendCmd.Write([]byte(raw))
+		appendCmd.Close()
+		if _, err := appendCmd.Wait(); err != nil {
+			t.Fatalf("AppendCommand.Wait() = %v", err)
+		}
+	}
+	if err := client.Noop().Wait(); err != nil {
+		t.Fatalf("Noop().Wait() = %v", err)
+	}
+
+	// 排除没有 From 头字段的第一封邮件
+	searchCriteria := &imap.SearchCriteria{
+		Header: []imap.SearchCriteriaHeaderField{{Key: "From", Value: "example.org"}},
+	}
+	tests := []struct {
+		name     string
+		criteria []imapclient.SortCriterion
+		want     []uint32
+	}{
+		{"SUBJECT", []imapclient.SortCriterion{{Key: imapclient.SortKeySubject}}, []uint32{3, 2, 4}},
+		{"FROM", []imapclient.SortCriterion{{Key: imapclient.SortKeyFrom}}, []uint32{3, 4, 2}},
+		{"REVERSE SIZE", []imapclient.SortCriterion{{Key: imapclient.SortKeySize, Reverse: true}}, []uint32{3, 4, 2}},
+		{"REVERSE ARRIVAL", []imapclient.SortCriterion{{Key: imapclient.SortKeyArrival, Reverse: true}}, []uint32{4, 3, 2}},
+	}
+	for _, tc := range tests {
+		nums, err := client.Sort(&imapclient.SortOptions{
+			SearchCriteria: searchCriteria,
+			SortCriteria:   tc.criteria,
+		}).Wait()
+		if err != nil {
+			t.Fatalf("%v: Sort().Wait() = %v", tc.name, err)
+		}
+		if !reflect.DeepEqual(nums, tc.want) {
+			t.Errorf("%v: Sort() = %v, want %v", tc.name, nums, tc.want)
+		}
+	}
+
+	uids, err := client.UIDSort(&imapclient.SortOptions{
+		SearchCriteria: searchCriteria,
+		SortCriteria:   []imapclient.SortCriterion{{Key: imapclient.SortKeySubject, Reverse: true}},
+	}).Wait()
+	if err != nil {
+		t.Fatalf("UIDSort().Wait() = %v", err)
+	}
+	if want := []uint32{4, 2, 3}; !reflect.DeepEqual(uids, want) {
+		t.Errorf("UIDSort() = %v, want %v", uids, want)
+	}
+}
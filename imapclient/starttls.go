@@ -51,11 +51,12 @@ func (c *Client) upgradeStartTLS(startTLS *startTLSCommand) {
 	}
 
 	tlsConn := tls.Client(cleartextConn, startTLS.tlsConfig) // 创建 TLS 客户端连接
-	rw := c.options.wrapReadWriter(tlsConn)                  // 包装读取和写入器
+	rw := c.wrapReadWriter(tlsConn)                          // 包装读取和写入器
 
 	c.br.Reset(rw) // 重置 bufio.Reader
 	// 不幸的是，我们无法在这里重用 bufio.Writer，因为它与 Client.StartTLS 有竞争
 	c.bw = bufio.NewWriter(rw) // 创建新的 bufio.Writer
+	c.rawRW = tlsConn          // 后续的升级（例如 COMPRESS）位于 TLS 之上
 
 	startTLS.tlsConn = tlsConn // 设置 TLS 连接
 }
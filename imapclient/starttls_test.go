@@ -1,8 +1,14 @@
 package imapclient_test
 
 import (
+	"context"
 	"crypto/tls"
+	"errors"
+	"net"
+	"sync/atomic"
+	"syscall"
 	"testing"
+	"time"
 
 	"github.com/luhaoyun888/go-imap-cn/imapclient"
 )
@@ -26,3 +32,64 @@ func TestStartTLS(t *testing.T) {
 		t.Fatalf("Noop().Wait() = %v", err) // 如果 NOOP 命令失败，输出错误信息
 	}
 }
+
+// TestDialStartTLSContext 测试 DialStartTLSContext 使用 Options.Dialer 拨号。
+func TestDialStartTLSContext(t *testing.T) {
+	conn, server := newMemClientServerPair(t)
+	defer conn.Close()
+	defer server.Close()
+
+	var dialed int32
+	options := imapclient.Options{
+		TLSConfig: &tls.Config{InsecureSkipVerify: true},
+		Dialer: &net.Dialer{
+			Control: func(network, address string, c syscall.RawConn) error {
+				atomic.StoreInt32(&dialed, 1)
+				return nil
+			},
+		},
+	}
+	client, err := imapclient.DialStartTLSContext(context.Background(), conn.RemoteAddr().String(), &options)
+	if err != nil {
+		t.Fatalf("DialStartTLSContext() = %v", err)
+	}
+	defer client.Close()
+
+	if atomic.LoadInt32(&dialed) == 0 {
+		t.Errorf("未使用 Options.Dialer")
+	}
+	if err := client.Noop().Wait(); err != nil {
+		t.Fatalf("Noop().Wait() = %v", err)
+	}
+}
+
+// TestDialStartTLSContext_timeout 测试服务器无响应时 ctx 超时会中断 STARTTLS 协商。
+func TestDialStartTLSContext_timeout(t *testing.T) {
+	ln, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("net.Listen() = %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		// 接受连接但从不发送问候
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = imapclient.DialStartTLSContext(ctx, ln.Addr().String(), nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("DialStartTLSContext() = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("DialStartTLSContext() 耗时 %v，未被 ctx 中断", d)
+	}
+}
@@ -17,9 +17,11 @@ func statusItems(options *imap.StatusOptions) []string {
 		"UNSEEN":          options.NumUnseen,      // 未读消息数量
 		"DELETED":         options.NumDeleted,     // 删除消息数量
 		"SIZE":            options.Size,           // 邮箱大小
+		"RECENT":          options.NumRecent,      // 最近消息数量
 		"APPENDLIMIT":     options.AppendLimit,    // 附加限制
 		"DELETED-STORAGE": options.DeletedStorage, // 删除存储
 		"HIGHESTMODSEQ":   options.HighestModSeq,  // 最高修改序列号
+		"MAILBOXID":       options.MailboxID,      // 邮箱对象 ID
 	}
 
 	var l []string
@@ -38,6 +40,9 @@ func (c *Client) Status(mailbox string, options *imap.StatusOptions) *StatusComm
 	if options == nil {
 		options = new(imap.StatusOptions) // 如果选项为 nil，则创建新选项
 	}
+	if options.MailboxID && !c.Caps().Has(imap.CapObjectID) {
+		return &StatusCommand{commandBase: newFailedCommandBase(fmt.Errorf("imapclient: 服务器不支持 OBJECTID")), mailbox: mailbox}
+	}
 
 	cmd := &StatusCommand{mailbox: mailbox}
 	enc := c.beginCommand("STATUS", cmd)
@@ -138,6 +143,10 @@ func readStatusAttVal(dec *imapwire.Decoder, data *imap.StatusData) error {
 		var size int64
 		ok = dec.ExpectNumber64(&size)
 		data.Size = &size // 设置邮箱大小
+	case "RECENT":
+		var num uint32
+		ok = dec.ExpectNumber(&num)
+		data.NumRecent = &num // 设置最近消息数量
 	case "APPENDLIMIT":
 		var num uint32
 		if dec.Number(&num) {
@@ -153,6 +162,8 @@ func readStatusAttVal(dec *imapwire.Decoder, data *imap.StatusData) error {
 		data.DeletedStorage = &storage // 设置删除存储
 	case "HIGHESTMODSEQ":
 		ok = dec.ExpectModSeq(&data.HighestModSeq) // 设置最高修改序列号
+	case "MAILBOXID":
+		ok = dec.ExpectSpecial('(') && dec.Expect(dec.Func(&data.MailboxID, isObjectIDChar), "objectid") && dec.ExpectSpecial(')') // 设置邮箱对象 ID
 	default:
 		if !dec.DiscardValue() {
 			return dec.Err() // 返回错误
@@ -38,3 +38,31 @@ func TestStatus(t *testing.T) {
 		t.Errorf("Status() = %#v but want %#v", data, want) // 如果不相等则打印错误信息
 	}
 }
+
+// TestStatus_recent 测试 STATUS RECENT 返回尚未被任何会话选择的邮件数量。
+func TestStatus_recent(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateAuthenticated)
+	defer client.Close()
+	defer server.Close()
+
+	options := imap.StatusOptions{NumRecent: true}
+	data, err := client.Status("INBOX", &options).Wait()
+	if err != nil {
+		t.Fatalf("Status() = %v", err)
+	}
+	if data.NumRecent == nil || *data.NumRecent != 1 {
+		t.Errorf("Status().NumRecent = %v, want 1", data.NumRecent)
+	}
+
+	// 以读写方式选择邮箱后，邮件不再是其他会话的最近邮件
+	if _, err := client.Select("INBOX", nil).Wait(); err != nil {
+		t.Fatalf("Select() = %v", err)
+	}
+	data, err = client.Status("INBOX", &options).Wait()
+	if err != nil {
+		t.Fatalf("Status() = %v", err)
+	}
+	if data.NumRecent == nil || *data.NumRecent != 0 {
+		t.Errorf("Status().NumRecent = %v, want 0", data.NumRecent)
+	}
+}
@@ -2,6 +2,7 @@ package imapclient
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/luhaoyun888/go-imap-cn"
 	"github.com/luhaoyun888/go-imap-cn/internal/imapwire"
@@ -13,11 +14,15 @@ import (
 //
 // nil 的 options 指针等同于零选项值。
 func (c *Client) Store(numSet imap.NumSet, store *imap.StoreFlags, options *imap.StoreOptions) *FetchCommand {
+	cmdName := uidCmdName("STORE", imapwire.NumSetKind(numSet))
+	c.checkPipeline(cmdName, numSet, true)
+
 	cmd := &FetchCommand{
 		numSet: numSet,
 		msgs:   make(chan *FetchMessageData, 128), // 创建消息数据通道
+		store:  true,
 	}
-	enc := c.beginCommand(uidCmdName("STORE", imapwire.NumSetKind(numSet)), cmd)
+	enc := c.beginCommand(cmdName, cmd)
 	enc.SP().NumSet(numSet).SP() // 添加序列集
 
 	// 如果选项不为 nil 且 UnchangedSince 不为 0，添加 UNCHANGEDSINCE 条件
@@ -25,28 +30,71 @@ func (c *Client) Store(numSet imap.NumSet, store *imap.StoreFlags, options *imap
 		enc.Special('(').Atom("UNCHANGEDSINCE").SP().ModSeq(options.UnchangedSince).Special(')').SP()
 	}
 
-	// 根据操作类型设置标志
-	switch store.Op {
+	writeStoreItem(enc.Encoder, "FLAGS", store.Op, store.Silent) // 添加 FLAGS 关键字
+
+	// 添加标志列表
+	enc.SP().List(len(store.Flags), func(i int) {
+		enc.Flag(store.Flags[i])
+	})
+
+	enc.end()  // 结束编码
+	return cmd // 返回命令
+}
+
+// StoreGmailLabels 发送修改 X-GM-LABELS 的 STORE 命令。
+//
+// 此命令要求支持 X-GM-EXT-1 扩展。
+func (c *Client) StoreGmailLabels(numSet imap.NumSet, store *imap.StoreGmailLabels, options *imap.StoreOptions) *FetchCommand {
+	if !c.Caps().Has(imap.CapGmailExt1) {
+		return newFetchErrorCommand(fmt.Errorf("imapclient: 服务器不支持 X-GM-EXT-1"))
+	}
+
+	cmdName := uidCmdName("STORE", imapwire.NumSetKind(numSet))
+	c.checkPipeline(cmdName, numSet, true)
+
+	cmd := &FetchCommand{
+		numSet: numSet,
+		msgs:   make(chan *FetchMessageData, 128),
+		store:  true,
+	}
+	enc := c.beginCommand(cmdName, cmd)
+	enc.SP().NumSet(numSet).SP()
+	if options != nil && options.UnchangedSince != 0 {
+		enc.Special('(').Atom("UNCHANGEDSINCE").SP().ModSeq(options.UnchangedSince).Special(')').SP()
+	}
+
+	writeStoreItem(enc.Encoder, "X-GM-LABELS", store.Op, store.Silent)
+
+	// 系统标签以标志的形式发送，其他标签为字符串
+	enc.SP().List(len(store.Labels), func(i int) {
+		label := store.Labels[i]
+		if strings.HasPrefix(label, "\\") {
+			enc.Flag(imap.Flag(label))
+		} else {
+			enc.String(label)
+		}
+	})
+
+	enc.end()
+	return cmd
+}
+
+// writeStoreItem 写入带有操作前缀和可选 .SILENT 后缀的 STORE 数据项名称。
+func writeStoreItem(enc *imapwire.Encoder, name string, op imap.StoreFlagsOp, silent bool) {
+	// 根据操作类型设置前缀
+	switch op {
 	case imap.StoreFlagsSet:
 		// 无需操作
 	case imap.StoreFlagsAdd:
-		enc.Special('+') // 添加标志
+		enc.Special('+') // 添加
 	case imap.StoreFlagsDel:
-		enc.Special('-') // 删除标志
+		enc.Special('-') // 删除
 	default:
-		panic(fmt.Errorf("imapclient: 未知的存储标志操作: %v", store.Op)) // 处理未知操作
+		panic(fmt.Errorf("imapclient: 未知的存储标志操作: %v", op)) // 处理未知操作
 	}
 
-	enc.Atom("FLAGS") // 添加 FLAGS 关键字
-	if store.Silent {
+	enc.Atom(name)
+	if silent {
 		enc.Atom(".SILENT") // 如果 Silent 被设置，添加 .SILENT
 	}
-
-	// 添加标志列表
-	enc.SP().List(len(store.Flags), func(i int) {
-		enc.Flag(store.Flags[i])
-	})
-
-	enc.end()  // 结束编码
-	return cmd // 返回命令
 }
@@ -1,9 +1,12 @@
 package imapclient_test
 
 import (
+	"net"
 	"testing"
+	"time"
 
 	"github.com/luhaoyun888/go-imap-cn"
+	"github.com/luhaoyun888/go-imap-cn/imapclient"
 )
 
 // TestStore 测试 Store 方法
@@ -44,3 +47,146 @@ func TestStore(t *testing.T) {
 		t.Errorf("msg.Flags 中缺少已删除标志: %v", msg.Flags) // 如果未找到已删除标志，记录错误
 	}
 }
+
+// TestStore_unchangedSince 测试带有 UNCHANGEDSINCE 条件的 Store 方法
+func TestStore_unchangedSince(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateSelected)
+	defer client.Close()
+	defer server.Close()
+
+	if !client.Caps().Has(imap.CapCondStore) {
+		t.Skip("服务器不支持 CONDSTORE")
+	}
+
+	msgs, err := client.Fetch(imap.SeqSetNum(1), &imap.FetchOptions{ModSeq: true}).Collect()
+	if err != nil {
+		t.Fatalf("Fetch().Collect() = %v", err)
+	} else if len(msgs) != 1 {
+		t.Fatalf("len(msgs) = %v, want %v", len(msgs), 1)
+	}
+	modSeq := msgs[0].ModSeq
+
+	// 邮件自 modSeq 以来未被修改，存储应当成功
+	storeFlags := imap.StoreFlags{
+		Op:    imap.StoreFlagsAdd,
+		Flags: []imap.Flag{imap.FlagFlagged},
+	}
+	cmd := client.Store(imap.SeqSetNum(1), &storeFlags, &imap.StoreOptions{UnchangedSince: modSeq})
+	msgs, err = cmd.Collect()
+	if err != nil {
+		t.Fatalf("Store().Collect() = %v", err)
+	} else if len(msgs) != 1 {
+		t.Fatalf("len(msgs) = %v, want %v", len(msgs), 1)
+	} else if msgs[0].ModSeq <= modSeq {
+		t.Errorf("ModSeq = %v, want > %v", msgs[0].ModSeq, modSeq)
+	}
+	if modified := cmd.Modified(); modified != nil {
+		t.Errorf("Modified() = %v, want nil", modified)
+	}
+
+	// 使用旧的修改序列号，存储应当被拒绝
+	storeFlags = imap.StoreFlags{
+		Op:    imap.StoreFlagsAdd,
+		Flags: []imap.Flag{imap.FlagDeleted},
+	}
+	cmd = client.Store(imap.SeqSetNum(1), &storeFlags, &imap.StoreOptions{UnchangedSince: modSeq})
+	msgs, err = cmd.Collect()
+	if err != nil {
+		t.Fatalf("Store().Collect() = %v", err)
+	} else if len(msgs) != 0 {
+		t.Errorf("len(msgs) = %v, want %v", len(msgs), 0)
+	}
+	seqSet, ok := cmd.Modified().(imap.SeqSet)
+	if !ok || !seqSet.Contains(1) {
+		t.Errorf("Modified() = %v, want 1", cmd.Modified())
+	}
+
+	msgs, err = client.Fetch(imap.SeqSetNum(1), &imap.FetchOptions{Flags: true}).Collect()
+	if err != nil {
+		t.Fatalf("Fetch().Collect() = %v", err)
+	}
+	for _, f := range msgs[0].Flags {
+		if f == imap.FlagDeleted {
+			t.Errorf("msg.Flags = %v, want no %v", msgs[0].Flags, imap.FlagDeleted)
+		}
+	}
+}
+
+// TestStore_broadcast 测试 STORE 修改的标志被广播给选择了同一邮箱的其他会话，
+// 而不会以未经请求的 FETCH 发回给执行 STORE 的会话。
+func TestStore_broadcast(t *testing.T) {
+	conn, server := newMemClientServerPair(t)
+	defer server.Close()
+	otherConn, err := net.Dial("tcp", conn.RemoteAddr().String())
+	if err != nil {
+		t.Fatalf("net.Dial() = %v", err)
+	}
+
+	newClient := func(conn net.Conn, fetched chan<- *imapclient.FetchMessageBuffer) *imapclient.Client {
+		return imapclient.New(conn, &imapclient.Options{
+			UnilateralDataHandler: &imapclient.UnilateralDataHandler{
+				Fetch: func(msg *imapclient.FetchMessageData) {
+					buf, err := msg.Collect()
+					if err != nil {
+						t.Errorf("FetchMessageData.Collect() = %v", err)
+						return
+					}
+					fetched <- buf
+				},
+			},
+		})
+	}
+	clientFetched := make(chan *imapclient.FetchMessageBuffer, 16)
+	client := newClient(conn, clientFetched)
+	defer client.Close()
+	otherFetched := make(chan *imapclient.FetchMessageBuffer, 16)
+	other := newClient(otherConn, otherFetched)
+	defer other.Close()
+
+	if err := client.Login(testUsername, testPassword).Wait(); err != nil {
+		t.Fatalf("Login().Wait() = %v", err)
+	}
+	appendCmd := client.Append("INBOX", int64(len(simpleRawMessage)), nil)
+	appendCmd.Write([]byte(simpleRawMessage))
+	appendCmd.Close()
+	if _, err := appendCmd.Wait(); err != nil {
+		t.Fatalf("Append().Wait() = %v", err)
+	}
+	if _, err := client.Select("INBOX", nil).Wait(); err != nil {
+		t.Fatalf("Select().Wait() = %v", err)
+	}
+	if err := other.Login(testUsername, testPassword).Wait(); err != nil {
+		t.Fatalf("Login().Wait() = %v", err)
+	}
+	if _, err := other.Select("INBOX", nil).Wait(); err != nil {
+		t.Fatalf("Select().Wait() = %v", err)
+	}
+
+	storeFlags := imap.StoreFlags{Op: imap.StoreFlagsAdd, Silent: true, Flags: []imap.Flag{imap.FlagFlagged}}
+	if err := client.Store(imap.SeqSetNum(1), &storeFlags, nil).Close(); err != nil {
+		t.Fatalf("Store().Close() = %v", err)
+	}
+
+	// 其他会话收到 FETCH FLAGS。未经请求的 FETCH 在单独的 goroutine 中处理
+	if err := other.Noop().Wait(); err != nil {
+		t.Fatalf("Noop().Wait() = %v", err)
+	}
+	select {
+	case buf := <-otherFetched:
+		if buf.SeqNum != 1 || !containsFlag(buf.Flags, imap.FlagFlagged) {
+			t.Errorf("其他会话收到 FETCH %v, want 1 with \\Flagged", buf)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("其他会话未收到 FETCH FLAGS")
+	}
+
+	// 执行 STORE 的会话不会收到自己的修改
+	if err := client.Noop().Wait(); err != nil {
+		t.Fatalf("Noop().Wait() = %v", err)
+	}
+	select {
+	case buf := <-clientFetched:
+		t.Errorf("源会话收到了未经请求的 FETCH: %v", buf)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
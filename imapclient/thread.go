@@ -41,14 +41,20 @@ func (c *Client) UIDThread(options *ThreadOptions) *ThreadCommand {
 // handleThread 方法，处理 THREAD 响应
 func (c *Client) handleThread() error {
 	cmd := findPendingCmdByType[*ThreadCommand](c)
-	for c.dec.SP() {
-		data, err := readThreadList(c.dec) // 读取线程列表
+	if !c.dec.SP() {
+		return nil // 没有符合条件的邮件
+	}
+	for {
+		data, ok, err := readThreadList(c.dec, 0) // 读取线程列表
 		if err != nil {
 			return fmt.Errorf("在线程列表中: %v", err)
+		} else if !ok {
+			break
 		}
 		if cmd != nil {
 			cmd.data = append(cmd.data, *data)
 		}
+		c.dec.SP() // RFC 5256 中线程之间没有空格，但有的服务器会加上
 	}
 	return nil
 }
@@ -65,29 +71,39 @@ func (cmd *ThreadCommand) Wait() ([]ThreadData, error) {
 	return cmd.data, err
 }
 
-// ThreadData 表示线程数据
-type ThreadData struct {
-	Chain      []uint32     // 线程链
-	SubThreads []ThreadData // 子线程
-}
+// ThreadData 是 imap.ThreadData 的别名。
+type ThreadData = imap.ThreadData
+
+// maxThreadDepth 限制线程的最大嵌套深度，防止栈溢出。
+const maxThreadDepth = 1000
 
 // readThreadList 方法，读取线程列表
 // dec: 解码器
-// 返回值: 返回一个 ThreadData 结构体指针和可能的错误
-func readThreadList(dec *imapwire.Decoder) (*ThreadData, error) {
-	var data ThreadData
-	err := dec.ExpectList(func() error {
+// depth: 当前嵌套深度
+// 返回值: 返回一个 ThreadData 结构体指针；下一个字符不是 "(" 时 ok 为 false
+func readThreadList(dec *imapwire.Decoder, depth int) (data *ThreadData, ok bool, err error) {
+	if !dec.Special('(') {
+		return nil, false, nil
+	}
+	if depth >= maxThreadDepth {
+		return nil, true, fmt.Errorf("线程嵌套过深")
+	}
+
+	data = &ThreadData{}
+	for !dec.Special(')') {
 		var num uint32
 		if len(data.SubThreads) == 0 && dec.Number(&num) {
 			data.Chain = append(data.Chain, num) // 添加到链中
 		} else {
-			sub, err := readThreadList(dec) // 递归读取子线程
+			sub, ok, err := readThreadList(dec, depth+1) // 递归读取子线程
 			if err != nil {
-				return err
+				return nil, true, err
+			} else if !dec.Expect(ok, "thread-list") {
+				return nil, true, dec.Err()
 			}
 			data.SubThreads = append(data.SubThreads, *sub) // 添加子线程
 		}
-		return nil
-	})
-	return &data, err
+		dec.SP() // 子线程之间没有空格
+	}
+	return data, true, nil
 }
@@ -0,0 +1,114 @@
+package imapclient_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/luhaoyun888/go-imap-cn"
+	"github.com/luhaoyun888/go-imap-cn/imapclient"
+)
+
+func TestThread(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateSelected)
+	defer client.Close()
+	defer server.Close()
+
+	algs := client.Caps().ThreadAlgorithms()
+	if len(algs) == 0 {
+		t.Skip("服务器不支持 THREAD")
+	}
+
+	messages := []string{
+		"Message-Id: <a@example.org>\r\nDate: Mon, 1 Jan 2024 10:00:00 +0000\r\nSubject: hello\r\n",
+		"Message-Id: <b@example.org>\r\nDate: Mon, 1 Jan 2024 11:00:00 +0000\r\nSubject: Re: hello\r\nIn-Reply-To: <a@example.org>\r\n",
+		"Message-Id: <c@example.org>\r\nDate: Mon, 1 Jan 2024 12:00:00 +0000\r\nSubject: Re: hello\r\nReferences: <a@example.org> <b@example.org>\r\n",
+		"Message-Id: <d@example.org>\r\nDate: Mon, 1 Jan 2024 13:00:00 +0000\r\nSubject: Re: hello\r\nReferences: <a@example.org>\r\n",
+		"Message-Id: <e@example.org>\r\nDate: Mon, 1 Jan 2024 09:00:00 +0000\r\nSubject: other\r\n",
+	}
+	for _, header := range messages {
+		raw := "From: alice@example.org\r\n" + header + "\r\n正文"
+		appendCmd := client.Append("INBOX", int64(len(raw)), nil)
+		appendCmd.Write([]byte(raw))
+		appendCmd.Close()
+		if _, err := appendCmd.Wait(); err != nil {
+			t.Fatalf("AppendCommand.Wait() = %v", err)
+		}
+	}
+	if err := client.Noop().Wait(); err != nil {
+		t.Fatalf("Noop().Wait() = %v", err)
+	}
+
+	// 排除没有 From 头字段的第一封邮件
+	searchCriteria := &imap.SearchCriteria{
+		Header: []imap.SearchCriteriaHeaderField{{Key: "From", Value: "example.org"}},
+	}
+	tests := []struct {
+		alg  imap.ThreadAlgorithm
+		want []imapclient.ThreadData
+	}{
+		{imap.ThreadReferences, []imapclient.ThreadData{
+			{Chain: []uint32{6}},
+			{Chain: []uint32{2}, SubThreads: []imapclient.ThreadData{
+				{Chain: []uint32{3, 4}},
+				{Chain: []uint32{5}},
+			}},
+		}},
+		{imap.ThreadOrderedSubject, []imapclient.ThreadData{
+			{Chain: []uint32{6}},
+			{Chain: []uint32{2}, SubThreads: []imapclient.ThreadData{
+				{Chain: []uint32{3}},
+				{Chain: []uint32{4}},
+				{Chain: []uint32{5}},
+			}},
+		}},
+	}
+	for _, tc := range tests {
+		data, err := client.Thread(&imapclient.ThreadOptions{
+			Algorithm:      tc.alg,
+			SearchCriteria: searchCriteria,
+		}).Wait()
+		if err != nil {
+			t.Fatalf("%v: Thread().Wait() = %v", tc.alg, err)
+		}
+		if !reflect.DeepEqual(data, tc.want) {
+			t.Errorf("%v: Thread() = %v, want %v", tc.alg, data, tc.want)
+		}
+	}
+
+	_, err := client.UIDThread(&imapclient.ThreadOptions{
+		Algorithm:      "UNKNOWN",
+		SearchCriteria: searchCriteria,
+	}).Wait()
+	if err == nil {
+		t.Errorf("UIDThread(UNKNOWN) 成功，期望失败")
+	}
+}
+
+// TestThread_nested 测试解析 RFC 5256 中子线程之间没有空格的 THREAD 响应。
+func TestThread_nested(t *testing.T) {
+	client := newScriptedClient(t, " THREAD=REFERENCES", func(cmd string) []string {
+		return []string{"* THREAD (2)(3 6 (4 23)(44 7 96))((8)(9))"}
+	})
+
+	data, err := client.Thread(&imapclient.ThreadOptions{
+		Algorithm:      imap.ThreadReferences,
+		SearchCriteria: &imap.SearchCriteria{},
+	}).Wait()
+	if err != nil {
+		t.Fatalf("Thread().Wait() = %v", err)
+	}
+	want := []imapclient.ThreadData{
+		{Chain: []uint32{2}},
+		{Chain: []uint32{3, 6}, SubThreads: []imapclient.ThreadData{
+			{Chain: []uint32{4, 23}},
+			{Chain: []uint32{44, 7, 96}},
+		}},
+		{SubThreads: []imapclient.ThreadData{
+			{Chain: []uint32{8}},
+			{Chain: []uint32{9}},
+		}},
+	}
+	if !reflect.DeepEqual(data, want) {
+		t.Errorf("Thread() = %v, want %v", data, want)
+	}
+}
@@ -0,0 +1,77 @@
+package imapclient
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/emersion/go-sasl"
+)
+
+// XOAUTH2 是 XOAUTH2 SASL 机制的名称。
+const XOAUTH2 = "XOAUTH2"
+
+// XOAUTH2Error 包含服务器在 XOAUTH2 认证失败时通过挑战返回的错误详情。
+type XOAUTH2Error struct {
+	Status  string `json:"status"`
+	Schemes string `json:"schemes"`
+	Scope   string `json:"scope"`
+
+	Err error `json:"-"` // 服务器对 AUTHENTICATE 命令的最终响应
+}
+
+func (err *XOAUTH2Error) Error() string {
+	if err.Err == nil {
+		return fmt.Sprintf("imapclient: XOAUTH2 认证失败 (%v)", err.Status)
+	}
+	return fmt.Sprintf("imapclient: XOAUTH2 认证失败 (%v): %v", err.Status, err.Err)
+}
+
+func (err *XOAUTH2Error) Unwrap() error {
+	return err.Err
+}
+
+type xoauth2Client struct {
+	username, token string
+	failure         *XOAUTH2Error // 服务器返回的错误详情
+}
+
+var _ saslFailureClient = (*xoauth2Client)(nil)
+
+// NewXOAUTH2Client 创建使用 XOAUTH2 机制的 SASL 客户端。
+//
+// XOAUTH2 是 Google 和 Microsoft 使用的非标准机制，支持 OAUTHBEARER（RFC 7628）
+// 的服务器应优先使用 sasl.NewOAuthBearerClient。
+//
+// 认证失败时，Client.Authenticate 返回 *XOAUTH2Error，其中包含服务器返回的
+// 错误详情。
+func NewXOAUTH2Client(username, token string) sasl.Client {
+	return &xoauth2Client{username: username, token: token}
+}
+
+func (c *xoauth2Client) Start() (mech string, ir []byte, err error) {
+	ir = []byte("user=" + c.username + "\x01auth=Bearer " + c.token + "\x01\x01")
+	return XOAUTH2, ir, nil
+}
+
+func (c *xoauth2Client) Next(challenge []byte) ([]byte, error) {
+	if c.failure != nil {
+		return nil, fmt.Errorf("imapclient: 意外的 XOAUTH2 挑战")
+	}
+
+	// 服务器以挑战的形式返回 JSON 错误详情，客户端必须回复空响应，
+	// 之后服务器会以 NO 结束命令
+	c.failure = &XOAUTH2Error{}
+	if err := json.Unmarshal(challenge, c.failure); err != nil {
+		return nil, fmt.Errorf("imapclient: 无效的 XOAUTH2 错误详情: %v", err)
+	}
+	return []byte{}, nil
+}
+
+func (c *xoauth2Client) saslFailure(err error) error {
+	if c.failure == nil {
+		return err
+	}
+	failure := *c.failure
+	failure.Err = err
+	return &failure
+}
@@ -0,0 +1,129 @@
+package imapclient_test
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/emersion/go-sasl"
+
+	"github.com/luhaoyun888/go-imap-cn"
+	"github.com/luhaoyun888/go-imap-cn/imapclient"
+	"github.com/luhaoyun888/go-imap-cn/imapserver"
+	"github.com/luhaoyun888/go-imap-cn/imapserver/imapmemserver"
+)
+
+const testToken = "test-token"
+
+// xoauth2Session 为内存会话增加 XOAUTH2 认证。
+type xoauth2Session struct {
+	imapserver.Session
+}
+
+func (sess *xoauth2Session) AuthenticateMechanisms() []string {
+	return []string{imapclient.XOAUTH2}
+}
+
+func (sess *xoauth2Session) Authenticate(mech string) (sasl.Server, error) {
+	return &xoauth2Server{sess: sess.Session}, nil
+}
+
+// xoauth2Server 模拟 Gmail 的 XOAUTH2 服务器：令牌无效时先以 JSON 挑战返回
+// 错误详情，收到空响应后再失败。
+type xoauth2Server struct {
+	sess   imapserver.Session
+	failed bool
+}
+
+func (s *xoauth2Server) Next(response []byte) (challenge []byte, done bool, err error) {
+	if s.failed {
+		if len(response) != 0 {
+			return nil, false, errors.New("want empty response")
+		}
+		return nil, true, &imap.Error{Type: imap.StatusResponseTypeNo, Text: "Invalid credentials"}
+	}
+
+	parts := strings.Split(string(response), "\x01")
+	if len(parts) != 4 || parts[2] != "" || parts[3] != "" {
+		return nil, false, errors.New("malformed XOAUTH2 response")
+	}
+	username := strings.TrimPrefix(parts[0], "user=")
+	token := strings.TrimPrefix(parts[1], "auth=Bearer ")
+	if token != testToken {
+		s.failed = true
+		return []byte(`{"status":"401","schemes":"Bearer","scope":"https://mail.google.com/"}`), false, nil
+	}
+	return nil, true, s.sess.Login(username, testPassword)
+}
+
+func newXOAUTH2Client(t *testing.T) (*imapclient.Client, *imapserver.Server) {
+	memServer := imapmemserver.New()
+	user := imapmemserver.NewUser(testUsername, testPassword)
+	user.Create("INBOX", nil)
+	memServer.AddUser(user)
+
+	server := imapserver.New(&imapserver.Options{
+		NewSession: func(conn *imapserver.Conn) (imapserver.Session, *imapserver.GreetingData, error) {
+			return &xoauth2Session{memServer.NewSession()}, nil, nil
+		},
+		// 包装后的会话未实现 SessionIMAP4rev2
+		Caps:         imap.CapSet{imap.CapIMAP4rev1: {}, imap.CapSASLIR: {}},
+		InsecureAuth: true,
+	})
+	ln, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("net.Listen() = %v", err)
+	}
+	go server.Serve(ln)
+
+	client, err := imapclient.DialInsecure(ln.Addr().String(), nil)
+	if err != nil {
+		server.Close()
+		t.Fatalf("DialInsecure() = %v", err)
+	}
+	return client, server
+}
+
+// TestAuthenticate_xoauth2 测试 XOAUTH2 认证成功。
+func TestAuthenticate_xoauth2(t *testing.T) {
+	client, server := newXOAUTH2Client(t)
+	defer server.Close()
+	defer client.Close()
+
+	if !client.Caps().Has(imap.AuthCap(imapclient.XOAUTH2)) {
+		t.Fatalf("服务器未声明 AUTH=XOAUTH2")
+	}
+	if err := client.Authenticate(imapclient.NewXOAUTH2Client(testUsername, testToken)); err != nil {
+		t.Fatalf("Authenticate() = %v", err)
+	}
+	if state := client.State(); state != imap.ConnStateAuthenticated {
+		t.Errorf("State() = %v, want %v", state, imap.ConnStateAuthenticated)
+	}
+}
+
+// TestAuthenticate_xoauth2Error 测试服务器返回 JSON 错误详情时回复空响应，
+// 并把详情附加到返回的错误上。
+func TestAuthenticate_xoauth2Error(t *testing.T) {
+	client, server := newXOAUTH2Client(t)
+	defer server.Close()
+	defer client.Close()
+
+	err := client.Authenticate(imapclient.NewXOAUTH2Client(testUsername, "bad-token"))
+	var xoauth2Err *imapclient.XOAUTH2Error
+	if !errors.As(err, &xoauth2Err) {
+		t.Fatalf("Authenticate() = %v, want *XOAUTH2Error", err)
+	}
+	if xoauth2Err.Status != "401" || xoauth2Err.Scope != "https://mail.google.com/" {
+		t.Errorf("XOAUTH2Error = %+v", xoauth2Err)
+	}
+	var imapErr *imap.Error
+	if !errors.As(err, &imapErr) || imapErr.Type != imap.StatusResponseTypeNo {
+		t.Errorf("Authenticate() = %v, want NO", err)
+	}
+
+	// 认证交换已结束，连接仍然可用
+	if err := client.Noop().Wait(); err != nil {
+		t.Errorf("Noop().Wait() = %v", err)
+	}
+}
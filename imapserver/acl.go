@@ -0,0 +1,150 @@
+package imapserver
+
+import (
+	"sort"
+
+	"github.com/luhaoyun888/go-imap-cn"
+	"github.com/luhaoyun888/go-imap-cn/internal/imapwire"
+)
+
+// handleSetACL 处理 SETACL 命令。
+func (c *Conn) handleSetACL(dec *imapwire.Decoder) error {
+	var mailbox, ri, rights string
+	if !dec.ExpectSP() || !dec.ExpectMailbox(&mailbox) || !dec.ExpectSP() || !dec.ExpectAString(&ri) || !dec.ExpectSP() || !dec.ExpectAString(&rights) || !dec.ExpectCRLF() {
+		return dec.Err()
+	}
+
+	// 权限前的 + 或 - 表示增加或删除权限
+	rm := imap.RightModificationReplace
+	if len(rights) > 0 && (rights[0] == '+' || rights[0] == '-') {
+		rm = imap.RightModification(rights[0])
+		rights = rights[1:]
+	}
+	if !isValidRights(rights) {
+		return newClientBugError("无效的权限")
+	}
+
+	session, err := c.checkACL()
+	if err != nil {
+		return err
+	}
+	return session.SetACL(mailbox, imap.RightsIdentifier(ri), rm, imap.RightSet(rights))
+}
+
+// handleDeleteACL 处理 DELETEACL 命令。
+func (c *Conn) handleDeleteACL(dec *imapwire.Decoder) error {
+	var mailbox, ri string
+	if !dec.ExpectSP() || !dec.ExpectMailbox(&mailbox) || !dec.ExpectSP() || !dec.ExpectAString(&ri) || !dec.ExpectCRLF() {
+		return dec.Err()
+	}
+
+	session, err := c.checkACL()
+	if err != nil {
+		return err
+	}
+	return session.DeleteACL(mailbox, imap.RightsIdentifier(ri))
+}
+
+// handleGetACL 处理 GETACL 命令。
+func (c *Conn) handleGetACL(dec *imapwire.Decoder) error {
+	var mailbox string
+	if !dec.ExpectSP() || !dec.ExpectMailbox(&mailbox) || !dec.ExpectCRLF() {
+		return dec.Err()
+	}
+
+	session, err := c.checkACL()
+	if err != nil {
+		return err
+	}
+	acl, err := session.GetACL(mailbox)
+	if err != nil {
+		return err
+	}
+
+	// 按标识符排序，保证响应稳定
+	identifiers := make([]string, 0, len(acl))
+	for ri := range acl {
+		identifiers = append(identifiers, string(ri))
+	}
+	sort.Strings(identifiers)
+
+	enc := newResponseEncoder(c)
+	defer enc.end()
+	enc.Atom("*").SP().Atom("ACL").SP().Mailbox(mailbox)
+	for _, ri := range identifiers {
+		enc.SP().String(ri).SP().String(string(acl[imap.RightsIdentifier(ri)]))
+	}
+	return enc.CRLF()
+}
+
+// handleListRights 处理 LISTRIGHTS 命令。
+func (c *Conn) handleListRights(dec *imapwire.Decoder) error {
+	var mailbox, ri string
+	if !dec.ExpectSP() || !dec.ExpectMailbox(&mailbox) || !dec.ExpectSP() || !dec.ExpectAString(&ri) || !dec.ExpectCRLF() {
+		return dec.Err()
+	}
+
+	session, err := c.checkACL()
+	if err != nil {
+		return err
+	}
+	required, optional, err := session.ListRights(mailbox, imap.RightsIdentifier(ri))
+	if err != nil {
+		return err
+	}
+
+	enc := newResponseEncoder(c)
+	defer enc.end()
+	enc.Atom("*").SP().Atom("LISTRIGHTS").SP().Mailbox(mailbox).SP().String(ri).SP().String(string(required))
+	for _, rs := range optional {
+		enc.SP().String(string(rs))
+	}
+	return enc.CRLF()
+}
+
+// handleMyRights 处理 MYRIGHTS 命令。
+func (c *Conn) handleMyRights(dec *imapwire.Decoder) error {
+	var mailbox string
+	if !dec.ExpectSP() || !dec.ExpectMailbox(&mailbox) || !dec.ExpectCRLF() {
+		return dec.Err()
+	}
+
+	session, err := c.checkACL()
+	if err != nil {
+		return err
+	}
+	rights, err := session.MyRights(mailbox)
+	if err != nil {
+		return err
+	}
+
+	enc := newResponseEncoder(c)
+	defer enc.end()
+	enc.Atom("*").SP().Atom("MYRIGHTS").SP().Mailbox(mailbox).SP().String(string(rights))
+	return enc.CRLF()
+}
+
+// checkACL 检查 ACL 能力和连接状态，并返回支持 ACL 的会话。
+func (c *Conn) checkACL() (SessionACL, error) {
+	if err := c.checkCap(imap.CapACL); err != nil {
+		return nil, err
+	}
+	if err := c.checkState(imap.ConnStateAuthenticated); err != nil {
+		return nil, err
+	}
+	session, ok := c.session.(SessionACL)
+	if !ok {
+		return nil, newClientBugError("ACL 不被支持")
+	}
+	return session, nil
+}
+
+// isValidRights 检查权限字符串是否只包含小写字母和数字（RFC 4314 第 2.1 节）。
+func isValidRights(rights string) bool {
+	for _, ch := range rights {
+		if !(ch >= 'a' && ch <= 'z') && !(ch >= '0' && ch <= '9') {
+			return false
+		}
+	}
+	return true
+}
@@ -10,22 +10,138 @@ import (
 	"github.com/luhaoyun888/go-imap-cn/internal/imapwire"
 )
 
-// appendLimit 是 APPEND 有效负载的最大大小。
-const appendLimit = 100 * 1024 * 1024 // 100MiB
+// defaultAppendLimit 是未设置 Options.AppendLimit 时 APPEND 有效负载的最大大小。
+const defaultAppendLimit = 100 * 1024 * 1024 // 100MiB
 
 // handleAppend 处理 APPEND 命令。
 // tag: 客户端提供的标记，dec: 用于解码请求的 Decoder。
 func (c *Conn) handleAppend(tag string, dec *imapwire.Decoder) error {
-	var (
-		mailbox string             // 邮箱名称
-		options imap.AppendOptions // 附加选项
-	)
+	var mailbox string // 邮箱名称
 
 	// 解析请求，期望空格后跟邮箱名称
 	if !dec.ExpectSP() || !dec.ExpectMailbox(&mailbox) || !dec.ExpectSP() {
 		return dec.Err() // 返回解析错误
 	}
 
+	// 如果启用了 MULTIAPPEND，则所有 APPEND 命令都通过批量接口处理
+	if session, ok := c.session.(SessionMultiAppend); ok && c.server.options.caps().Has(imap.CapMultiAppend) {
+		return c.handleMultiAppend(tag, dec, mailbox, session)
+	}
+
+	options, dataExt, err := readAppendOptions(dec)
+	if err != nil {
+		return err // 返回错误
+	}
+
+	// 解析邮件内容
+	lit, err := c.readAppendData(dec, dataExt)
+	if err != nil {
+		return err // 返回错误
+	}
+
+	c.setReadTimeout(literalReadTimeout)   // 设置读取超时
+	defer c.setReadTimeout(cmdReadTimeout) // 恢复读取超时
+
+	// 检查连接状态是否为已认证，以及目标邮箱是否可写
+	err = c.checkState(imap.ConnStateAuthenticated)
+	if err == nil {
+		err = c.checkWritableMailbox(mailbox)
+	}
+	if err != nil {
+		io.Copy(io.Discard, lit) // 读取并丢弃邮件内容
+		dec.CRLF()               // 读取 CRLF
+		return err               // 返回错误
+	}
+
+	// 调用会话的 Append 方法
+	data, appendErr := c.sessionAppend(mailbox, lit, options)
+	if _, discardErr := io.Copy(io.Discard, lit); discardErr != nil {
+		return err // 返回错误
+	}
+	if dataExt == "UTF8" && !dec.ExpectSpecial(')') {
+		return dec.Err() // 返回解析错误
+	}
+	if !dec.ExpectCRLF() {
+		return err // 返回错误
+	}
+	if appendErr != nil {
+		return appendErr // 返回附加错误
+	}
+	if err := c.poll("APPEND"); err != nil {
+		return err // 返回错误
+	}
+	return c.writeAppendOK(tag, data) // 返回 APPEND 完成响应
+}
+
+// handleMultiAppend 处理可能包含多封邮件的 APPEND 命令，参见 RFC 3502。
+//
+// 只有在所有邮件都被成功读取后才会提交，否则不会追加任何邮件。
+func (c *Conn) handleMultiAppend(tag string, dec *imapwire.Decoder, mailbox string, session SessionMultiAppend) (err error) {
+	c.setReadTimeout(literalReadTimeout)   // 设置读取超时
+	defer c.setReadTimeout(cmdReadTimeout) // 恢复读取超时
+
+	var appender MultiAppender
+	appendErr := c.checkState(imap.ConnStateAuthenticated) // 检查连接状态是否为已认证
+	if appendErr == nil {
+		appendErr = c.checkWritableMailbox(mailbox)
+	}
+	if appendErr == nil {
+		appender, appendErr = c.sessionMultiAppend(session, mailbox)
+	}
+	committed := false
+	defer func() {
+		if appender != nil && !committed {
+			appender.Abort() // 命令失败，放弃所有邮件
+		}
+	}()
+
+	for {
+		options, dataExt, err := readAppendOptions(dec)
+		if err != nil {
+			return err
+		}
+		lit, err := c.readAppendData(dec, dataExt)
+		if err != nil {
+			return err
+		}
+
+		// 出错后仍需读取剩余的邮件内容
+		if appendErr == nil {
+			appendErr = appender.Append(lit, options)
+		}
+		if _, err := io.Copy(io.Discard, lit); err != nil {
+			return err
+		}
+		if dataExt == "UTF8" && !dec.ExpectSpecial(')') {
+			return dec.Err()
+		}
+
+		if !dec.SP() { // 没有更多邮件
+			break
+		}
+	}
+	if !dec.ExpectCRLF() {
+		return dec.Err()
+	}
+	if appendErr != nil {
+		return appendErr
+	}
+
+	data, err := appender.Commit()
+	committed = true
+	if err != nil {
+		return err
+	}
+	if err := c.poll("APPEND"); err != nil {
+		return err
+	}
+	return c.writeAppendOK(tag, data)
+}
+
+// readAppendOptions 读取 APPEND 命令中一封邮件的标志、时间和数据扩展。
+func readAppendOptions(dec *imapwire.Decoder) (options *imap.AppendOptions, dataExt string, err error) {
+	options = new(imap.AppendOptions)
+
 	// 解析标志列表
 	hasFlagList, err := dec.List(func() error {
 		flag, err := internal.ExpectFlag(dec) // 期望标志
@@ -36,83 +152,86 @@ func (c *Conn) handleAppend(tag string, dec *imapwire.Decoder) error {
 		return nil
 	})
 	if err != nil {
-		return err // 返回错误
+		return nil, "", err // 返回错误
 	}
 	if hasFlagList && !dec.ExpectSP() {
-		return dec.Err() // 返回解析错误
+		return nil, "", dec.Err() // 返回解析错误
 	}
 
 	// 解析时间
 	t, err := internal.DecodeDateTime(dec) // 解析日期时间
 	if err != nil {
-		return err // 返回错误
+		return nil, "", err // 返回错误
 	}
 	if !t.IsZero() && !dec.ExpectSP() {
-		return dec.Err() // 返回解析错误
+		return nil, "", dec.Err() // 返回解析错误
 	}
 	options.Time = t // 设置时间选项
 
-	var dataExt string      // 数据扩展
 	if dec.Atom(&dataExt) { // 如果存在数据扩展
-		switch strings.ToUpper(dataExt) { // 转换为大写进行匹配
+		dataExt = strings.ToUpper(dataExt) // 转换为大写进行匹配
+		switch dataExt {
 		case "UTF8":
 			// '~' 是 literal8 前缀
 			if !dec.ExpectSP() || !dec.ExpectSpecial('(') || !dec.ExpectSpecial('~') {
-				return dec.Err() // 返回解析错误
+				return nil, "", dec.Err() // 返回解析错误
 			}
+		case "CATENATE":
+			// 部分列表由 readAppendData 读取
 		default:
-			return newClientBugError("未知的 APPEND 数据扩展") // 返回未知扩展错误
+			return nil, "", newClientBugError("未知的 APPEND 数据扩展") // 返回未知扩展错误
 		}
 	} else {
 		dec.Special('~') // 如果存在 BINARY，则忽略 literal8 前缀
 	}
 
-	// 解析邮件内容
+	return options, dataExt, nil
+}
+
+// readAppendData 读取 APPEND 命令中一封邮件的内容，可以是字面量或 CATENATE 部分列表。
+func (c *Conn) readAppendData(dec *imapwire.Decoder, dataExt string) (imap.LiteralReader, error) {
+	if dataExt == "CATENATE" {
+		return c.readCatenate(dec)
+	}
+	return c.readAppendLiteral(dec)
+}
+
+// readAppendLiteral 读取 APPEND 命令中的邮件字面量，检查其大小并接受它。
+func (c *Conn) readAppendLiteral(dec *imapwire.Decoder) (imap.LiteralReader, error) {
+	return c.readAppendLiteralMax(dec, c.server.options.appendLimit())
+}
+
+// readAppendLiteralMax 与 readAppendLiteral 相同，但字面量不能超过 max 字节，
+// 用于 CATENATE 检查已读取部分之外的剩余大小。
+func (c *Conn) readAppendLiteralMax(dec *imapwire.Decoder, max int64) (imap.LiteralReader, error) {
 	lit, nonSync, err := dec.ExpectLiteralReader() // 期望字面量读取器
 	if err != nil {
-		return err // 返回错误
+		return nil, err // 返回错误
 	}
 
 	// 检查字面量大小是否超出限制
-	if lit.Size() > appendLimit {
-		return &imap.Error{
-			Type: imap.StatusResponseTypeNo,
-			Code: imap.ResponseCodeTooBig,
-			Text: fmt.Sprintf("字面量大小限制为 %v 字节", appendLimit),
+	if lit.Size() > max {
+		if nonSync {
+			// 客户端不等待继续请求就会发送字面量，需要读取并丢弃
+			if _, err := io.Copy(io.Discard, lit); err != nil {
+				return nil, err
+			}
 		}
+		return nil, newTooBigError(c.server.options.appendLimit())
 	}
 	if err := c.acceptLiteral(lit.Size(), nonSync); err != nil {
-		return err // 返回错误
-	}
-
-	c.setReadTimeout(literalReadTimeout)   // 设置读取超时
-	defer c.setReadTimeout(cmdReadTimeout) // 恢复读取超时
-
-	// 检查连接状态是否为已认证
-	if err := c.checkState(imap.ConnStateAuthenticated); err != nil {
-		io.Copy(io.Discard, lit) // 读取并丢弃邮件内容
-		dec.CRLF()               // 读取 CRLF
-		return err               // 返回错误
+		return nil, err // 返回错误
 	}
+	return lit, nil
+}
 
-	// 调用会话的 Append 方法
-	data, appendErr := c.session.Append(mailbox, lit, &options)
-	if _, discardErr := io.Copy(io.Discard, lit); discardErr != nil {
-		return err // 返回错误
+// newTooBigError 返回邮件超出 APPEND 大小限制时的错误。
+func newTooBigError(limit int64) error {
+	return &imap.Error{
+		Type: imap.StatusResponseTypeNo,
+		Code: imap.ResponseCodeTooBig,
+		Text: fmt.Sprintf("邮件大小限制为 %v 字节", limit),
 	}
-	if dataExt != "" && !dec.ExpectSpecial(')') {
-		return dec.Err() // 返回解析错误
-	}
-	if !dec.ExpectCRLF() {
-		return err // 返回错误
-	}
-	if appendErr != nil {
-		return appendErr // 返回附加错误
-	}
-	if err := c.poll("APPEND"); err != nil {
-		return err // 返回错误
-	}
-	return c.writeAppendOK(tag, data) // 返回 APPEND 完成响应
 }
 
 // writeAppendOK 写入 APPEND 成功的响应。
@@ -124,7 +243,12 @@ func (c *Conn) writeAppendOK(tag string, data *imap.AppendData) error {
 	enc.Atom(tag).SP().Atom("OK").SP() // 编码标记和 OK 响应
 	if data != nil {
 		enc.Special('[')
-		enc.Atom("APPENDUID").SP().Number(data.UIDValidity).SP().UID(data.UID) // 编码 UID 信息
+		enc.Atom("APPENDUID").SP().Number(data.UIDValidity).SP() // 编码 UID 信息
+		if len(data.UIDs) > 0 {
+			enc.NumSet(data.UIDs) // MULTIAPPEND 返回 UID 集合
+		} else {
+			enc.UID(data.UID)
+		}
 		enc.Special(']').SP()
 	}
 	enc.Text("APPEND 完成") // 编码完成消息
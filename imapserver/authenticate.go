@@ -48,7 +48,26 @@ func (c *Conn) handleAuthenticate(tag string, dec *imapwire.Decoder) error {
 	}
 
 	var saslServer sasl.Server // SASL 服务器
-	if authSess, ok := c.session.(SessionSASL); ok {
+	if extSess, ok := c.session.(SessionExternal); ok && mech == sasl.External {
+		cert := c.clientCert()
+		if cert == nil {
+			return &imap.Error{
+				Type: imap.StatusResponseTypeNo,
+				Code: imap.ResponseCodeAuthenticationFailed,
+				Text: "未提供经过验证的客户端证书",
+			}
+		}
+		saslServer = sasl.NewExternalServer(func(identity string) error {
+			if identity != "" { // 不支持以其他身份登录
+				return &imap.Error{
+					Type: imap.StatusResponseTypeNo,
+					Code: imap.ResponseCodeAuthorizationFailed,
+					Text: "不支持的 SASL 身份",
+				}
+			}
+			return extSess.AuthenticateExternal(cert)
+		})
+	} else if authSess, ok := c.session.(SessionSASL); ok {
 		var err error
 		saslServer, err = authSess.Authenticate(mech) // 从会话获取 SASL 服务器
 		if err != nil {
@@ -134,6 +153,10 @@ func decodeSASL(s string) ([]byte, error) {
 // handleUnauthenticate 处理 UNAUTHENTICATE 命令。
 // dec: 用于解码请求的 Decoder。
 func (c *Conn) handleUnauthenticate(dec *imapwire.Decoder) error {
+	if err := c.checkCap(imap.CapUnauthenticate); err != nil {
+		return err // 未宣告 UNAUTHENTICATE
+	}
+
 	if !dec.ExpectCRLF() {
 		return dec.Err() // 返回解析错误
 	}
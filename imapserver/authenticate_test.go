@@ -0,0 +1,188 @@
+package imapserver_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-sasl"
+
+	"github.com/luhaoyun888/go-imap-cn"
+	"github.com/luhaoyun888/go-imap-cn/imapclient"
+	"github.com/luhaoyun888/go-imap-cn/imapserver"
+	"github.com/luhaoyun888/go-imap-cn/imapserver/imapmemserver"
+)
+
+// testCA 是测试用的证书颁发机构。
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pool *x509.CertPool
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("ecdsa.GenerateKey() = %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("x509.CreateCertificate() = %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("x509.ParseCertificate() = %v", err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &testCA{cert: cert, key: key, pool: pool}
+}
+
+// issue 签发一张证书。
+func (ca *testCA) issue(t *testing.T, commonName string, usage x509.ExtKeyUsage) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("ecdsa.GenerateKey() = %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("x509.CreateCertificate() = %v", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// TestServer_authenticateExternal 测试使用 TLS 客户端证书通过 SASL EXTERNAL 登录。
+func TestServer_authenticateExternal(t *testing.T) {
+	ca := newTestCA(t)
+	server, addr := newTestServer(t, &imapserver.Options{
+		TLSConfig: &tls.Config{
+			Certificates: []tls.Certificate{ca.issue(t, "localhost", x509.ExtKeyUsageServerAuth)},
+		},
+		ClientCAs: ca.pool,
+	})
+	defer server.Close()
+
+	dial := func(clientCerts ...tls.Certificate) *imapclient.Client {
+		client, err := imapclient.DialStartTLS(addr, &imapclient.Options{
+			TLSConfig: &tls.Config{
+				RootCAs:      ca.pool,
+				ServerName:   "127.0.0.1",
+				Certificates: clientCerts,
+			},
+		})
+		if err != nil {
+			t.Fatalf("DialStartTLS() = %v", err)
+		}
+		return client
+	}
+
+	client := dial(ca.issue(t, testUsername, x509.ExtKeyUsageClientAuth))
+	defer client.Close()
+	if !client.Caps().Has(imap.AuthCap(sasl.External)) {
+		t.Errorf("提供证书后未声明 AUTH=EXTERNAL")
+	}
+	if err := client.Authenticate(sasl.NewExternalClient("")); err != nil {
+		t.Fatalf("Authenticate(EXTERNAL) = %v", err)
+	}
+	if state := client.State(); state != imap.ConnStateAuthenticated {
+		t.Errorf("State() = %v, want %v", state, imap.ConnStateAuthenticated)
+	}
+
+	// 未提供证书时不能使用 EXTERNAL
+	anonClient := dial()
+	defer anonClient.Close()
+	if anonClient.Caps().Has(imap.AuthCap(sasl.External)) {
+		t.Errorf("未提供证书时声明了 AUTH=EXTERNAL")
+	}
+	err := anonClient.Authenticate(sasl.NewExternalClient(""))
+	var imapErr *imap.Error
+	if !errors.As(err, &imapErr) || imapErr.Code != imap.ResponseCodeAuthenticationFailed {
+		t.Errorf("Authenticate(EXTERNAL) = %v, want AUTHENTICATIONFAILED", err)
+	}
+}
+
+// TestServer_insecureHiddenCaps 测试未加密连接的欢迎信息不宣告
+// GreetingData.InsecureHiddenCaps 中的能力，STARTTLS 之后才宣告。
+func TestServer_insecureHiddenCaps(t *testing.T) {
+	memServer := imapmemserver.New()
+	user := imapmemserver.NewUser(testUsername, testPassword)
+	user.Create("INBOX", nil)
+	memServer.AddUser(user)
+
+	ca := newTestCA(t)
+	server := imapserver.New(&imapserver.Options{
+		NewSession: func(conn *imapserver.Conn) (imapserver.Session, *imapserver.GreetingData, error) {
+			greeting := &imapserver.GreetingData{
+				InsecureHiddenCaps: []imap.Cap{imap.AuthCap(sasl.Plain)},
+			}
+			return memServer.NewSession(), greeting, nil
+		},
+		Caps: imap.CapSet{imap.CapIMAP4rev1: {}, imap.CapIMAP4rev2: {}},
+		TLSConfig: &tls.Config{
+			Certificates: []tls.Certificate{ca.issue(t, "localhost", x509.ExtKeyUsageServerAuth)},
+		},
+		InsecureAuth: true,
+	})
+	ln, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("net.Listen() = %v", err)
+	}
+	go server.Serve(ln)
+	defer server.Close()
+
+	plainClient, err := imapclient.DialInsecure(ln.Addr().String(), nil)
+	if err != nil {
+		t.Fatalf("DialInsecure() = %v", err)
+	}
+	defer plainClient.Close()
+	if err := plainClient.WaitGreeting(); err != nil {
+		t.Fatalf("WaitGreeting() = %v", err)
+	}
+	caps := plainClient.Caps()
+	if caps.Has(imap.AuthCap(sasl.Plain)) {
+		t.Errorf("未加密连接宣告了 AUTH=PLAIN")
+	}
+	if !caps.Has(imap.CapIMAP4rev1) || !caps.Has(imap.CapStartTLS) {
+		t.Errorf("未加密连接的能力 = %v, want 包含 IMAP4rev1 和 STARTTLS", caps)
+	}
+
+	tlsClient, err := imapclient.DialStartTLS(ln.Addr().String(), &imapclient.Options{
+		TLSConfig: &tls.Config{RootCAs: ca.pool, ServerName: "127.0.0.1"},
+	})
+	if err != nil {
+		t.Fatalf("DialStartTLS() = %v", err)
+	}
+	defer tlsClient.Close()
+	if !tlsClient.Caps().Has(imap.AuthCap(sasl.Plain)) {
+		t.Errorf("STARTTLS 之后未宣告 AUTH=PLAIN")
+	}
+	if err := tlsClient.Login(testUsername, testPassword).Wait(); err != nil {
+		t.Fatalf("Login().Wait() = %v", err)
+	}
+}
@@ -1,6 +1,11 @@
 package imapserver
 
 import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/emersion/go-sasl"
 	"github.com/luhaoyun888/go-imap-cn"
 	"github.com/luhaoyun888/go-imap-cn/internal/imapwire"
 )
@@ -52,6 +57,9 @@ func (c *Conn) availableCaps() []imap.Cap {
 		if authSess, ok := c.session.(SessionSASL); ok {
 			mechs = authSess.AuthenticateMechanisms() // 获取可用的 SASL 机制
 		}
+		if _, ok := c.session.(SessionExternal); ok && c.clientCert() != nil && !hasMech(mechs, sasl.External) {
+			mechs = append(mechs, sasl.External) // 客户端提供了已验证的证书
+		}
 		for _, mech := range mechs {
 			caps = append(caps, imap.Cap("AUTH="+mech)) // 添加身份验证能力
 		}
@@ -77,6 +85,7 @@ func (c *Conn) availableCaps() []imap.Cap {
 				imap.CapMove,
 				imap.CapStatusSize,
 				imap.CapBinary,
+				imap.CapACL,
 			})
 		}
 		// 添加其他能力
@@ -84,11 +93,65 @@ func (c *Conn) availableCaps() []imap.Cap {
 			imap.CapCreateSpecialUse,
 			imap.CapLiteralPlus,
 			imap.CapUnauthenticate,
+			imap.CapCondStore,
+			imap.CapQResync,
+			imap.CapObjectID,
+			imap.CapWithin,
+			imap.CapMultiAppend,
+			imap.CapCatenate,
+			imap.CapSort,
+			imap.Cap("THREAD=" + imap.ThreadOrderedSubject),
+			imap.Cap("THREAD=" + imap.ThreadReferences),
+			imap.CapQuota,
+			imap.CapQuotaSet,
+			imap.CapMetadata,
+			imap.CapMetadataServer,
+			imap.CapMultiSearch,
 		})
+		if available.Has(imap.CapQuota) {
+			// 支持的配额资源类型，例如 QUOTA=RES-STORAGE
+			resources := available.QuotaResourceTypes()
+			sort.Slice(resources, func(i, j int) bool { return resources[i] < resources[j] })
+			for _, t := range resources {
+				caps = append(caps, imap.Cap("QUOTA=RES-"+string(t)))
+			}
+		}
+		if limit := c.server.options.AppendLimit; limit != nil {
+			caps = append(caps, imap.Cap(fmt.Sprintf("APPENDLIMIT=%v", *limit)))
+		} else if _, ok := available[imap.CapAppendLimit]; ok {
+			caps = append(caps, imap.CapAppendLimit) // 各邮箱的限制不同
+		}
+		if !c.compressed {
+			addAvailableCaps(&caps, available, []imap.Cap{imap.CapCompressDeflate})
+		}
+	}
+	if len(c.hiddenCaps) > 0 && !c.isTLS() {
+		caps = removeCaps(caps, c.hiddenCaps) // 隐藏未加密时不宣告的能力
 	}
 	return caps // 返回可用能力
 }
 
+// removeCaps 返回 caps 中不属于 hidden 的能力，不区分大小写。
+func removeCaps(caps, hidden []imap.Cap) []imap.Cap {
+	var l []imap.Cap
+	for _, c := range caps {
+		if !hasCap(hidden, c) {
+			l = append(l, c)
+		}
+	}
+	return l
+}
+
+// hasCap 检查能力列表中是否包含 c，不区分大小写。
+func hasCap(caps []imap.Cap, c imap.Cap) bool {
+	for _, other := range caps {
+		if strings.EqualFold(string(other), string(c)) {
+			return true
+		}
+	}
+	return false
+}
+
 // addAvailableCaps 将可用的能力添加到 caps 切片中。
 // caps: 目标切片，available: 可用能力集合，l: 要添加的能力列表。
 func addAvailableCaps(caps *[]imap.Cap, available imap.CapSet, l []imap.Cap) {
@@ -98,3 +161,13 @@ func addAvailableCaps(caps *[]imap.Cap, available imap.CapSet, l []imap.Cap) {
 		}
 	}
 }
+
+// hasMech 检查 SASL 机制列表中是否包含 mech。
+func hasMech(mechs []string, mech string) bool {
+	for _, m := range mechs {
+		if strings.EqualFold(m, mech) {
+			return true
+		}
+	}
+	return false
+}
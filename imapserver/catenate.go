@@ -0,0 +1,223 @@
+package imapserver
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/luhaoyun888/go-imap-cn"
+	"github.com/luhaoyun888/go-imap-cn/internal/imapwire"
+)
+
+// CatenateURL 是 CATENATE 中引用已有消息的 IMAP URL，参见 RFC 5092。
+type CatenateURL struct {
+	User        string                     // URL 中的用户名，未指定时为空。会话必须拒绝引用其他用户的 URL
+	Mailbox     string                     // 邮箱名称
+	UIDValidity uint32                     // 邮箱的 UIDVALIDITY，为零表示未指定
+	UID         imap.UID                   // 邮件 UID
+	Section     *imap.FetchItemBodySection // 引用的正文部分，空值表示整封邮件
+}
+
+// readCatenate 读取 CATENATE 部分列表，并返回拼接后的邮件内容。
+func (c *Conn) readCatenate(dec *imapwire.Decoder) (imap.LiteralReader, error) {
+	if err := c.checkCap(imap.CapCatenate); err != nil {
+		return nil, err // 未宣告 CATENATE
+	}
+	if !dec.ExpectSP() {
+		return nil, dec.Err()
+	}
+
+	c.setReadTimeout(literalReadTimeout) // 设置读取超时
+
+	limit := c.server.options.appendLimit()
+	var buf bytes.Buffer
+	isList, err := dec.List(func() error {
+		var typ string
+		if !dec.ExpectAtom(&typ) || !dec.ExpectSP() {
+			return dec.Err()
+		}
+		switch strings.ToUpper(typ) {
+		case "TEXT":
+			// 在读取字面量之前检查累计大小，避免缓冲超出限制的内容
+			lit, err := c.readAppendLiteralMax(dec, limit-int64(buf.Len()))
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(&buf, lit)
+			return err
+		case "URL":
+			var rawURL string
+			if !dec.ExpectAString(&rawURL) {
+				return dec.Err()
+			}
+			b, err := c.fetchCatenateURL(rawURL)
+			if err != nil {
+				return err
+			}
+			if int64(buf.Len()+len(b)) > limit {
+				return newTooBigError(limit)
+			}
+			buf.Write(b)
+			return nil
+		default:
+			return newClientBugError("未知的 CATENATE 部分")
+		}
+	})
+	if err != nil {
+		return nil, err
+	} else if !isList {
+		return nil, newClientBugError("CATENATE 需要部分列表")
+	}
+
+	return bytes.NewReader(buf.Bytes()), nil
+}
+
+// fetchCatenateURL 解析 URL 并通过会话获取其引用的内容。
+func (c *Conn) fetchCatenateURL(rawURL string) ([]byte, error) {
+	if err := c.checkState(imap.ConnStateAuthenticated); err != nil {
+		return nil, err
+	}
+	session, ok := c.session.(SessionCatenate)
+	if !ok {
+		return nil, newBadURLError(rawURL, "不支持 URL")
+	}
+
+	u, err := parseCatenateURL(rawURL, c.server.options.Hostnames)
+	if err != nil {
+		return nil, newBadURLError(rawURL, err.Error())
+	}
+
+	b, err := session.FetchURL(u)
+	var imapErr *imap.Error
+	if errors.As(err, &imapErr) {
+		return nil, newBadURLError(rawURL, imapErr.Text)
+	}
+	return b, err
+}
+
+// newBadURLError 返回带有 BADURL 响应码的错误。
+func newBadURLError(rawURL, text string) error {
+	return &imap.Error{
+		Type: imap.StatusResponseTypeNo,
+		// BADURL 响应码需要附带 URL，其中不能包含 "]"
+		Code: imap.ResponseCode(fmt.Sprintf("%v %v", imap.ResponseCodeBadURL, strings.ReplaceAll(rawURL, "]", "%5D"))),
+		Text: text,
+	}
+}
+
+// parseCatenateURL 解析引用本账号中邮件的 IMAP URL。
+//
+// 支持绝对 URL（imap://...）以及以 "/" 开头的路径，例如
+// "/INBOX;UIDVALIDITY=385759045/;UID=20/;SECTION=1.2"。绝对 URL 的主机名必须属于
+// hostnames，否则 URL 引用的是其他服务器。
+func parseCatenateURL(s string, hostnames []string) (*CatenateURL, error) {
+	u := &CatenateURL{Section: &imap.FetchItemBodySection{}}
+	if len(s) >= len("imap://") && strings.EqualFold(s[:len("imap://")], "imap://") {
+		s = s[len("imap://"):]
+		i := strings.IndexByte(s, '/')
+		if i < 0 {
+			return nil, fmt.Errorf("URL 缺少邮箱")
+		}
+		user, err := checkCatenateURLAuthority(s[:i], hostnames)
+		if err != nil {
+			return nil, err
+		}
+		u.User = user
+		s = s[i:]
+	}
+	if !strings.HasPrefix(s, "/") {
+		return nil, fmt.Errorf("不支持相对 URL")
+	}
+
+	fields := strings.Split(s[1:], "/;")
+
+	mailbox := fields[0]
+	if i := strings.Index(strings.ToUpper(mailbox), ";UIDVALIDITY="); i >= 0 {
+		v, err := strconv.ParseUint(mailbox[i+len(";UIDVALIDITY="):], 10, 32)
+		if err != nil || v == 0 {
+			return nil, fmt.Errorf("无效的 UIDVALIDITY")
+		}
+		u.UIDValidity = uint32(v)
+		mailbox = mailbox[:i]
+	}
+	mailbox, err := url.PathUnescape(mailbox)
+	if err != nil || mailbox == "" {
+		return nil, fmt.Errorf("无效的邮箱")
+	}
+	u.Mailbox = mailbox
+
+	for _, field := range fields[1:] {
+		k, v, ok := strings.Cut(field, "=")
+		if !ok {
+			return nil, fmt.Errorf("无效的 URL 字段 %q", field)
+		}
+		switch strings.ToUpper(k) {
+		case "UID":
+			uid, err := strconv.ParseUint(v, 10, 32)
+			if err != nil || uid == 0 {
+				return nil, fmt.Errorf("无效的 UID")
+			}
+			u.UID = imap.UID(uid)
+		case "SECTION":
+			section, err := url.PathUnescape(v)
+			if err != nil {
+				return nil, fmt.Errorf("无效的 SECTION")
+			}
+			dec := imapwire.NewDecoder(bufio.NewReader(strings.NewReader(section+"]")), imapwire.ConnSideServer)
+			if err := readSection(dec, u.Section); err != nil {
+				return nil, fmt.Errorf("无效的 SECTION")
+			}
+		case "PARTIAL":
+			offsetStr, sizeStr, hasSize := strings.Cut(v, ".")
+			offset, err := strconv.ParseUint(offsetStr, 10, 63)
+			if err != nil {
+				return nil, fmt.Errorf("无效的 PARTIAL")
+			}
+			size := uint64(math.MaxInt64) - offset // 未指定长度时直到末尾
+			if hasSize {
+				if size, err = strconv.ParseUint(sizeStr, 10, 63); err != nil || size == 0 {
+					return nil, fmt.Errorf("无效的 PARTIAL")
+				}
+			}
+			u.Section.Partial = &imap.SectionPartial{Offset: int64(offset), Size: int64(size)}
+		default:
+			return nil, fmt.Errorf("不支持的 URL 字段 %q", k)
+		}
+	}
+	if u.UID == 0 {
+		return nil, fmt.Errorf("URL 缺少 UID")
+	}
+	return u, nil
+}
+
+// checkCatenateURLAuthority 检查 IMAP URL 的授权部分是否指向本服务器，并返回其中的用户名。
+func checkCatenateURLAuthority(authority string, hostnames []string) (user string, err error) {
+	if i := strings.LastIndexByte(authority, '@'); i >= 0 {
+		userinfo := authority[:i]
+		authority = authority[i+1:]
+		if j := strings.Index(strings.ToUpper(userinfo), ";AUTH="); j >= 0 {
+			userinfo = userinfo[:j]
+		}
+		if user, err = url.PathUnescape(userinfo); err != nil {
+			return "", fmt.Errorf("无效的用户名")
+		}
+	}
+
+	host := authority
+	if h, _, err := net.SplitHostPort(authority); err == nil {
+		host = h
+	}
+	for _, name := range hostnames {
+		if strings.EqualFold(host, name) {
+			return user, nil
+		}
+	}
+	return "", fmt.Errorf("URL 引用了其他服务器")
+}
@@ -0,0 +1,72 @@
+package imapserver
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	"github.com/luhaoyun888/go-imap-cn"
+	"github.com/luhaoyun888/go-imap-cn/internal"
+	"github.com/luhaoyun888/go-imap-cn/internal/imapwire"
+)
+
+// handleCompress 处理 COMPRESS 命令。
+func (c *Conn) handleCompress(tag string, dec *imapwire.Decoder) error {
+	if err := c.checkCap(imap.CapCompressDeflate); err != nil {
+		return err // 未宣告 COMPRESS=DEFLATE
+	}
+
+	var mech string
+	if !dec.ExpectSP() || !dec.ExpectAtom(&mech) || !dec.ExpectCRLF() {
+		return dec.Err() // 返回解码错误
+	}
+
+	if err := c.checkState(imap.ConnStateAuthenticated); err != nil {
+		return err
+	}
+	if !strings.EqualFold(mech, "DEFLATE") {
+		return &imap.Error{
+			Type: imap.StatusResponseTypeBad,
+			Text: "不支持的压缩机制",
+		}
+	}
+	if c.compressed {
+		return &imap.Error{
+			Type: imap.StatusResponseTypeNo,
+			Code: imap.ResponseCodeCompressionActive,
+			Text: "压缩已启用",
+		}
+	}
+
+	// 不允许在此之后写入未压缩的数据：在此期间保持 c.encMutex 锁定
+	enc := newResponseEncoder(c) // 创建响应编码器
+	defer enc.end()              // 确保在函数结束时释放编码器
+
+	err := writeStatusResp(enc.Encoder, tag, &imap.StatusResponse{
+		Type: imap.StatusResponseTypeOK,
+		Text: "DEFLATE 已启用",
+	})
+	if err != nil {
+		return err // 返回写入状态响应的错误
+	}
+
+	// 从 bufio.Reader 中排空缓冲数据：这些数据已经是压缩过的
+	var buf bytes.Buffer
+	if _, err := io.CopyN(&buf, c.br, int64(c.br.Buffered())); err != nil {
+		panic(err) // 不可达
+	}
+
+	// c.conn 在 STARTTLS 之后为 TLS 连接，因此压缩层位于 TLS 之上。
+	// 速率限制作用于压缩后的数据（STARTTLS 之后则已作用于 TLS 之下）
+	transport := io.ReadWriter(c.conn)
+	if !c.connLimited {
+		transport = c.transportReadWriter(c.conn)
+	}
+	r := io.MultiReader(&buf, transport)
+	rw := c.server.options.wrapReadWriter(internal.NewDeflateReadWriter(r, transport)) // 调试输出为解压后的数据
+	c.br.Reset(rw)                                                                     // 重置读取器
+	c.bw.Reset(rw)                                                                     // 重置写入器
+	c.compressed = true
+
+	return nil
+}
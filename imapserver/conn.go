@@ -2,7 +2,9 @@ package imapserver
 
 import (
 	"bufio"
+	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"io"
@@ -13,6 +15,7 @@ import (
 	"time"
 
 	"github.com/luhaoyun888/go-imap-cn"
+	"github.com/luhaoyun888/go-imap-cn/internal"
 	"github.com/luhaoyun888/go-imap-cn/internal/imapwire"
 )
 
@@ -25,6 +28,8 @@ const (
 	literalWriteTimeout = 5 * time.Minute
 )
 
+var errSessionPanic = errors.New("imapserver: 处理命令时发生 panic")
+
 var internalServerErrorResp = &imap.StatusResponse{
 	Type: imap.StatusResponseTypeNo,
 	Code: imap.ResponseCodeServerBug,
@@ -42,22 +47,97 @@ type Conn struct {
 	conn    net.Conn    // 网络连接
 	enabled imap.CapSet // 启用的能力集
 
-	state   imap.ConnState // 当前连接状态
-	session Session        // 当前会话
+	state      imap.ConnState // 当前连接状态
+	mailbox    string         // 当前选择的邮箱名称
+	readOnly   bool           // 当前选择的邮箱是否为只读（EXAMINE）
+	session    Session        // 当前会话
+	compressed bool           // 是否已启用 COMPRESS
+	hiddenCaps []imap.Cap     // 连接未加密时不宣告的能力
+
+	readLimiter, writeLimiter *internal.RateLimiter // 读写速率限制，未设置时为 nil
+	connLimited               bool                  // conn 自身是否已包含速率限制（STARTTLS 之后）
+
+	ctx    context.Context    // 连接关闭或读写出错时被取消
+	cancel context.CancelFunc // 取消 ctx
+	cmdCtx context.Context    // 当前命令的上下文，命令完成时被取消
+
+	peerWatch chan struct{} // watchPeerClose 的预读结束时关闭，没有预读时为 nil
+
+	idleMutex sync.Mutex // 保护 idle 和 closing
+	idle      bool       // 是否正在等待下一个命令
+	closing   bool       // 服务器是否正在关闭
 }
 
 // newConn 创建一个新的 IMAP 连接。
 func newConn(c net.Conn, server *Server) *Conn {
-	rw := server.options.wrapReadWriter(c) // 包装网络连接以支持读写
-	br := bufio.NewReader(rw)              // 创建输入缓冲区
-	bw := bufio.NewWriter(rw)              // 创建输出缓冲区
-	return &Conn{
+	conn := &Conn{
 		conn:    c,
 		server:  server,
-		br:      br,
-		bw:      bw,
 		enabled: make(imap.CapSet), // 初始化能力集
 	}
+	conn.ctx, conn.cancel = context.WithCancel(context.Background())
+	conn.cmdCtx = conn.ctx
+	if err := internal.SetTCPKeepAlive(c, server.options.TCPKeepAlive); err != nil {
+		server.logger().Printf("设置 TCP keepalive 失败：%v", err)
+	}
+	if server.options.ReadLimit > 0 {
+		conn.readLimiter = internal.NewRateLimiter(server.options.ReadLimit)
+	}
+	if server.options.WriteLimit > 0 {
+		conn.writeLimiter = internal.NewRateLimiter(server.options.WriteLimit)
+	}
+
+	rw := server.options.wrapReadWriter(conn.transportReadWriter(c)) // 包装网络连接以支持读写
+	conn.br = bufio.NewReader(rw)                                    // 创建输入缓冲区
+	conn.bw = bufio.NewWriter(rw)                                    // 创建输出缓冲区
+	return conn
+}
+
+// transportReadWriter 包装底层传输：读写出错（例如客户端断开连接）时取消连接的上下文，
+// 并加上 Options.ReadLimit 和 Options.WriteLimit 的速率限制。
+func (c *Conn) transportReadWriter(rw io.ReadWriter) io.ReadWriter {
+	var (
+		r io.Reader = &cancelOnErrorReader{rw, c.cancel}
+		w io.Writer = &cancelOnErrorWriter{rw, c.cancel}
+	)
+	if c.readLimiter != nil {
+		r = internal.NewRateLimitedReader(r, c.readLimiter)
+	}
+	if c.writeLimiter != nil {
+		w = internal.NewRateLimitedWriter(w, c.writeLimiter)
+	}
+	return struct {
+		io.Reader
+		io.Writer
+	}{r, w}
+}
+
+// cancelOnErrorReader 在读取出错时调用 cancel。
+type cancelOnErrorReader struct {
+	r      io.Reader
+	cancel context.CancelFunc
+}
+
+func (r *cancelOnErrorReader) Read(b []byte) (int, error) {
+	n, err := r.r.Read(b)
+	if err != nil {
+		r.cancel()
+	}
+	return n, err
+}
+
+// cancelOnErrorWriter 在写入出错时调用 cancel。
+type cancelOnErrorWriter struct {
+	w      io.Writer
+	cancel context.CancelFunc
+}
+
+func (w *cancelOnErrorWriter) Write(b []byte) (int, error) {
+	n, err := w.w.Write(b)
+	if err != nil {
+		w.cancel()
+	}
+	return n, err
 }
 
 // NetConn 返回被 IMAP 连接包装的底层网络连接。
@@ -71,28 +151,83 @@ func (c *Conn) NetConn() net.Conn {
 
 // Bye 终止 IMAP 连接。
 func (c *Conn) Bye(text string) error {
+	return c.bye("", text)
+}
+
+// bye 发送带有响应码的 BYE 响应并关闭连接。
+func (c *Conn) bye(code imap.ResponseCode, text string) error {
 	respErr := c.writeStatusResp("", &imap.StatusResponse{
 		Type: imap.StatusResponseTypeBye,
+		Code: code,
 		Text: text,
 	})
-	closeErr := c.conn.Close() // 关闭连接
+	closeErr := c.closeNetConn() // 关闭连接
 	if respErr != nil {
 		return respErr
 	}
 	return closeErr // 返回关闭错误
 }
 
+// closeNetConn 关闭底层网络连接，并取消连接上的所有上下文。
+func (c *Conn) closeNetConn() error {
+	c.cancel()
+	return c.conn.Close()
+}
+
+// rejectConn 因连接数达到上限而拒绝连接。
+func (c *Conn) rejectConn() {
+	if err := c.bye(imap.ResponseCodeLimit, "连接数过多"); err != nil && !errors.Is(err, net.ErrClosed) {
+		c.server.logger().Printf("写入 BYE 失败: %v", err)
+	}
+}
+
+// shutdownByeText 是服务器关闭时发送的 BYE 响应文本。
+const shutdownByeText = "服务器正在关闭"
+
+// shutdown 请求在当前命令完成后关闭连接。
+//
+// 如果连接正在等待下一个命令，则立即发送 BYE 并关闭连接。
+func (c *Conn) shutdown() {
+	// 在写入 BYE 期间保持锁定，以免 serve 开始处理新的命令
+	c.idleMutex.Lock()
+	defer c.idleMutex.Unlock()
+
+	c.closing = true
+	if !c.idle {
+		return // serve 会在当前命令完成后关闭连接
+	}
+	if err := c.Bye(shutdownByeText); err != nil && !errors.Is(err, net.ErrClosed) {
+		c.server.logger().Printf("写入 BYE 失败: %v", err)
+	}
+}
+
 // serve 处理IMAP连接的主要逻辑。
 func (c *Conn) serve() {
 	defer func() {
 		if v := recover(); v != nil {
 			c.server.logger().Printf("处理命令时发生panic: %v\n%s", v, debug.Stack())
 		}
-		c.conn.Close()
+		c.closeNetConn()
 	}()
 
+	if f := c.server.options.ConnLimitFunc; f != nil && f(c.conn.RemoteAddr()) {
+		c.rejectConn()
+		return
+	}
+
 	c.server.mutex.Lock()
+	if max := c.server.options.MaxConnections; max > 0 && len(c.server.conns) >= max {
+		c.server.mutex.Unlock()
+		c.rejectConn()
+		return
+	}
 	c.server.conns[c] = struct{}{}
+	if c.server.closed {
+		// 服务器在此连接注册之前已开始关闭
+		c.idleMutex.Lock()
+		c.closing = true
+		c.idleMutex.Unlock()
+	}
 	c.server.mutex.Unlock()
 	defer func() {
 		c.server.mutex.Lock()
@@ -122,13 +257,21 @@ func (c *Conn) serve() {
 		return
 	}
 
+	// 如果 serve 因 panic 而提前返回，则保留此错误
+	sessionErr := errSessionPanic
 	defer func() {
 		if c.session != nil {
 			if err := c.session.Close(); err != nil {
 				c.server.logger().Printf("关闭会话失败: %v", err)
 			}
 		}
+		if c.server.options.OnSessionEnd != nil {
+			c.server.options.OnSessionEnd(c, sessionErr)
+		}
 	}()
+	if c.server.options.OnSessionStart != nil {
+		c.server.options.OnSessionStart(c, c.session)
+	}
 
 	caps := c.server.options.caps()
 	if _, ok := c.session.(SessionIMAP4rev2); !ok && caps.Has(imap.CapIMAP4rev2) {
@@ -143,6 +286,30 @@ func (c *Conn) serve() {
 	if _, ok := c.session.(SessionUnauthenticate); !ok && caps.Has(imap.CapUnauthenticate) {
 		panic("imapserver: 服务器声明支持UNAUTHENTICATE，但会话不支持")
 	}
+	if _, ok := c.session.(SessionQResync); !ok && caps.Has(imap.CapQResync) {
+		panic("imapserver: 服务器声明支持QRESYNC，但会话不支持")
+	}
+	if _, ok := c.session.(SessionObjectID); !ok && caps.Has(imap.CapObjectID) {
+		panic("imapserver: 服务器声明支持OBJECTID，但会话不支持")
+	}
+	if _, ok := c.session.(SessionACL); !ok && caps.Has(imap.CapACL) {
+		panic("imapserver: 服务器声明支持ACL，但会话不支持")
+	}
+	if _, ok := c.session.(SessionMetadata); !ok && caps.HasAny(imap.CapMetadata, imap.CapMetadataServer) {
+		panic("imapserver: 服务器声明支持METADATA，但会话不支持")
+	}
+	if _, ok := c.session.(SessionQuota); !ok && caps.Has(imap.CapQuota) {
+		panic("imapserver: 服务器声明支持QUOTA，但会话不支持")
+	}
+	if _, ok := c.session.(SessionSort); !ok && caps.Has(imap.CapSort) {
+		panic("imapserver: 服务器声明支持SORT，但会话不支持")
+	}
+	if _, ok := c.session.(SessionMultiSearch); !ok && caps.Has(imap.CapMultiSearch) {
+		panic("imapserver: 服务器声明支持MULTISEARCH，但会话不支持")
+	}
+	if _, ok := c.session.(SessionThread); !ok && len(caps.ThreadAlgorithms()) > 0 {
+		panic("imapserver: 服务器声明支持THREAD，但会话不支持")
+	}
 
 	c.state = imap.ConnStateNotAuthenticated // 初始状态为未认证
 	statusType := imap.StatusResponseTypeOK  // 默认状态为OK
@@ -150,8 +317,12 @@ func (c *Conn) serve() {
 		c.state = imap.ConnStateAuthenticated // 如果支持预认证，则状态为已认证
 		statusType = imap.StatusResponseTypePreAuth
 	}
+	if greetingData != nil {
+		c.hiddenCaps = greetingData.InsecureHiddenCaps
+	}
 	if err := c.writeCapabilityStatus("", statusType, "IMAP 服务器已准备就绪"); err != nil {
 		c.server.logger().Printf("写入欢迎信息失败: %v", err)
+		sessionErr = err
 		return
 	}
 
@@ -167,9 +338,39 @@ func (c *Conn) serve() {
 
 		dec := imapwire.NewDecoder(c.br, imapwire.ConnSideServer) // 创建解码器
 		dec.CheckBufferedLiteralFunc = c.checkBufferedLiteral     // 设置缓冲字面量检查
+		dec.MaxSize = c.server.options.MaxCommandSize             // 限制单条命令的大小
 
-		if c.state == imap.ConnStateLogout || dec.EOF() {
-			break // 如果状态为注销或EOF，则退出循环
+		if c.state == imap.ConnStateLogout {
+			sessionErr = nil
+			break // 如果状态为注销，则退出循环
+		}
+
+		c.idleMutex.Lock()
+		closing := c.closing
+		c.idle = !closing
+		c.idleMutex.Unlock()
+		if closing {
+			// 服务器在处理上一个命令时开始关闭
+			if err := c.Bye(shutdownByeText); err != nil && !errors.Is(err, net.ErrClosed) {
+				c.server.logger().Printf("写入 BYE 失败: %v", err)
+			}
+			sessionErr = errClosed
+			break
+		}
+
+		c.waitPeerClose() // 此后才能读取连接
+		eof := dec.EOF()
+
+		c.idleMutex.Lock()
+		c.idle = false
+		closing = c.closing
+		c.idleMutex.Unlock()
+		if closing {
+			sessionErr = errClosed // Server.Shutdown 已发送 BYE 并关闭了连接
+			break
+		} else if eof {
+			sessionErr = io.ErrUnexpectedEOF // 客户端未登出便断开了连接
+			break
 		}
 
 		c.setReadTimeout(cmdReadTimeout)
@@ -177,6 +378,7 @@ func (c *Conn) serve() {
 			if !errors.Is(err, net.ErrClosed) {
 				c.server.logger().Printf("读取命令失败: %v", err)
 			}
+			sessionErr = err
 			break
 		}
 	}
@@ -202,6 +404,14 @@ func (c *Conn) readCommand(dec *imapwire.Decoder) error {
 		name = "UID " + strings.ToUpper(subName) // 组合UID命令
 	}
 
+	// 命令完成或连接关闭时取消传递给会话的上下文
+	ctx, cancel := context.WithCancel(c.ctx)
+	c.cmdCtx = ctx
+	defer func() {
+		cancel()
+		c.cmdCtx = c.ctx
+	}()
+
 	// TODO: 处理多个命令并发执行
 	sendOK := true
 	var err error
@@ -216,6 +426,9 @@ func (c *Conn) readCommand(dec *imapwire.Decoder) error {
 	case "STARTTLS":
 		err = c.handleStartTLS(tag, dec)
 		sendOK = false // STARTTLS不发送OK响应
+	case "COMPRESS":
+		err = c.handleCompress(tag, dec)
+		sendOK = false
 	case "AUTHENTICATE":
 		err = c.handleAuthenticate(tag, dec)
 		sendOK = false
@@ -227,7 +440,8 @@ func (c *Conn) readCommand(dec *imapwire.Decoder) error {
 	case "ENABLE":
 		err = c.handleEnable(dec)
 	case "CREATE":
-		err = c.handleCreate(dec)
+		err = c.handleCreate(tag, dec)
+		sendOK = false
 	case "DELETE":
 		err = c.handleDelete(dec)
 	case "RENAME":
@@ -244,8 +458,32 @@ func (c *Conn) readCommand(dec *imapwire.Decoder) error {
 		err = c.handleLSub(dec)
 	case "NAMESPACE":
 		err = c.handleNamespace(dec)
+	case "SETACL":
+		err = c.handleSetACL(dec)
+	case "DELETEACL":
+		err = c.handleDeleteACL(dec)
+	case "GETACL":
+		err = c.handleGetACL(dec)
+	case "LISTRIGHTS":
+		err = c.handleListRights(dec)
+	case "MYRIGHTS":
+		err = c.handleMyRights(dec)
+	case "GETMETADATA":
+		err = c.handleGetMetadata(tag, dec)
+		sendOK = false
+	case "SETMETADATA":
+		err = c.handleSetMetadata(dec)
+	case "ESEARCH":
+		err = c.handleESearch(tag, dec)
+	case "GETQUOTA":
+		err = c.handleGetQuota(dec)
+	case "GETQUOTAROOT":
+		err = c.handleGetQuotaRoot(dec)
+	case "SETQUOTA":
+		err = c.handleSetQuota(dec)
 	case "IDLE":
 		err = c.handleIdle(dec)
+		sendOK = c.state != imap.ConnStateLogout // 超过 MaxIdleDuration 时已发送 BYE
 	case "SELECT", "EXAMINE":
 		err = c.handleSelect(tag, dec, name == "EXAMINE")
 		sendOK = false
@@ -261,7 +499,8 @@ func (c *Conn) readCommand(dec *imapwire.Decoder) error {
 	case "UID EXPUNGE":
 		err = c.handleUIDExpunge(dec)
 	case "STORE", "UID STORE":
-		err = c.handleStore(dec, numKind)
+		err = c.handleStore(tag, dec, numKind)
+		sendOK = false
 	case "COPY", "UID COPY":
 		err = c.handleCopy(tag, dec, numKind)
 		sendOK = false
@@ -269,6 +508,10 @@ func (c *Conn) readCommand(dec *imapwire.Decoder) error {
 		err = c.handleMove(dec, numKind)
 	case "SEARCH", "UID SEARCH":
 		err = c.handleSearch(tag, dec, numKind)
+	case "SORT", "UID SORT":
+		err = c.handleSort(dec, numKind)
+	case "THREAD", "UID THREAD":
+		err = c.handleThread(dec, numKind)
 	default:
 		// 处理未识别的命令
 		if c.state == imap.ConnStateNotAuthenticated {
@@ -282,7 +525,19 @@ func (c *Conn) readCommand(dec *imapwire.Decoder) error {
 		}
 	}
 
-	dec.DiscardLine() // 丢弃解码器中的当前行
+	if errors.Is(err, imapwire.ErrTooBig) {
+		// 丢弃命令的剩余部分（包括非同步字面量），连接可以继续使用
+		if discardErr := dec.DiscardCommand(); discardErr != nil {
+			return discardErr
+		}
+		err = &imap.Error{
+			Type: imap.StatusResponseTypeNo,
+			Code: imap.ResponseCodeTooBig,
+			Text: fmt.Sprintf("命令大小限制为 %v 字节", c.server.options.MaxCommandSize),
+		}
+	} else {
+		dec.DiscardLine() // 丢弃解码器中的当前行
+	}
 
 	var (
 		resp    *imap.StatusResponse
@@ -388,11 +643,11 @@ func (c *Conn) handleUnsubscribe(dec *imapwire.Decoder) error {
 
 // checkBufferedLiteral 检查字面量缓冲区。
 func (c *Conn) checkBufferedLiteral(size int64, nonSync bool) error {
-	if size > 4096 {
+	if limit := c.server.options.maxLiteralSize(); size > limit {
 		return &imap.Error{
 			Type: imap.StatusResponseTypeNo,
 			Code: imap.ResponseCodeTooBig,
-			Text: "此命令的字面量限制为 4096 字节", // 字面量大小限制
+			Text: fmt.Sprintf("此命令的字面量限制为 %v 字节", limit), // 字面量大小限制
 		}
 	}
 
@@ -401,10 +656,11 @@ func (c *Conn) checkBufferedLiteral(size int64, nonSync bool) error {
 
 // acceptLiteral 接受字面量。
 func (c *Conn) acceptLiteral(size int64, nonSync bool) error {
-	if nonSync && size > 4096 && !c.server.options.caps().Has(imap.CapLiteralPlus) {
+	limit := c.server.options.maxNonSyncLiteralSize()
+	if nonSync && size > limit && !c.server.options.caps().Has(imap.CapLiteralPlus) {
 		return &imap.Error{
 			Type: imap.StatusResponseTypeBad,
-			Text: "非同步字面量限制为 4096 字节", // 非同步字面量大小限制
+			Text: fmt.Sprintf("非同步字面量限制为 %v 字节", limit), // 非同步字面量大小限制
 		}
 	}
 
@@ -412,7 +668,20 @@ func (c *Conn) acceptLiteral(size int64, nonSync bool) error {
 		return nil
 	}
 
-	return c.writeContReq("中文什么意思") // 请求发送字面量数据
+	return c.writeContReq("准备接收字面量数据") // 请求发送字面量数据
+}
+
+// clientCert 返回客户端提供的、已通过验证的 TLS 证书。没有时返回 nil。
+func (c *Conn) clientCert() *x509.Certificate {
+	tlsConn, ok := c.conn.(*tls.Conn)
+	if !ok {
+		return nil
+	}
+	chains := tlsConn.ConnectionState().VerifiedChains
+	if len(chains) == 0 || len(chains[0]) == 0 {
+		return nil
+	}
+	return chains[0][0]
 }
 
 // canAuth 检查是否可以进行认证。
@@ -420,8 +689,13 @@ func (c *Conn) canAuth() bool {
 	if c.state != imap.ConnStateNotAuthenticated {
 		return false // 如果当前状态不是未认证，返回false
 	}
-	_, isTLS := c.conn.(*tls.Conn)                // 检查连接是否为TLS
-	return isTLS || c.server.options.InsecureAuth // 返回TLS或不安全认证选项
+	return c.isTLS() || c.server.options.InsecureAuth // 返回TLS或不安全认证选项
+}
+
+// isTLS 检查连接是否已加密。
+func (c *Conn) isTLS() bool {
+	_, ok := c.conn.(*tls.Conn)
+	return ok
 }
 
 // writeStatusResp 写入状态响应。
@@ -456,6 +730,40 @@ func (c *Conn) checkState(state imap.ConnState) error {
 	return nil
 }
 
+// checkWritable 检查当前选择的邮箱是否可写。用 EXAMINE 选择的邮箱是只读的。
+func (c *Conn) checkWritable() error {
+	if c.state == imap.ConnStateSelected && c.readOnly {
+		return &imap.Error{
+			Type: imap.StatusResponseTypeNo,
+			Code: imap.ResponseCodeCannot,
+			Text: "邮箱为只读",
+		}
+	}
+	return nil
+}
+
+// checkWritableMailbox 检查命令能否写入邮箱 mailbox，例如 COPY 和 APPEND 的目标邮箱。
+// 只有以只读方式选择的当前邮箱不可写。
+func (c *Conn) checkWritableMailbox(mailbox string) error {
+	if mailbox != c.mailbox {
+		return nil
+	}
+	return c.checkWritable()
+}
+
+// checkCap 检查服务器是否宣告了某个能力。
+//
+// 未宣告能力对应的命令会被拒绝，即使会话实现了相应的接口。
+func (c *Conn) checkCap(cap imap.Cap) error {
+	if !c.server.options.caps().Has(cap) {
+		return &imap.Error{
+			Type: imap.StatusResponseTypeBad,
+			Text: "命令不被支持",
+		}
+	}
+	return nil
+}
+
 // setReadTimeout 设置读取超时时间。
 func (c *Conn) setReadTimeout(dur time.Duration) {
 	if dur > 0 {
@@ -474,7 +782,10 @@ func (c *Conn) setWriteTimeout(dur time.Duration) {
 	}
 }
 
-// poll 轮询状态更新。
+// poll 在命令成功后轮询并写出邮箱更新。
+//
+// 会话返回的错误只会被记录，不会返回给调用者，以免丢失命令的 OK 响应。
+// 只有更新队列溢出（连接已被关闭）时才返回错误。
 func (c *Conn) poll(cmd string) error {
 	switch c.state {
 	case imap.ConnStateAuthenticated, imap.ConnStateSelected:
@@ -490,7 +801,14 @@ func (c *Conn) poll(cmd string) error {
 	}
 
 	w := &UpdateWriter{conn: c, allowExpunge: allowExpunge} // 创建更新写入器
-	return c.session.Poll(w, allowExpunge)                  // 轮询状态更新
+	err := c.sessionPoll(w, allowExpunge)                   // 轮询状态更新
+	if err == nil || errors.Is(err, errSessionTrackerOverflow) {
+		return err
+	}
+	// 命令本身已经成功，推送更新失败不应让客户端丢失命令的结果，因此只记录错误。
+	// 如果连接已不可用，随后写入状态响应时会失败并断开连接。
+	c.server.logger().Printf("轮询 %v 命令的邮箱更新失败: %v", cmd, err)
+	return nil
 }
 
 // responseEncoder 用于编码IMAP响应。
@@ -553,7 +871,9 @@ func writeStatusResp(enc *imapwire.Encoder, tag string, statusResp *imap.StatusR
 		tag = "*" // 如果标签为空，设置为星号
 	}
 	enc.Atom(tag).SP().Atom(string(statusResp.Type)).SP() // 编码标签和状态类型
-	if statusResp.Code != "" {
+	if statusResp.Code == imap.ResponseCodeReferral && len(statusResp.Referrals) > 0 {
+		enc.Atom(fmt.Sprintf("[%v %v]", statusResp.Code, strings.Join(statusResp.Referrals, " "))).SP() // 编码重定向 URL
+	} else if statusResp.Code != "" {
 		enc.Atom(fmt.Sprintf("[%v]", statusResp.Code)).SP() // 编码状态代码
 	}
 	enc.Text(statusResp.Text) // 编码状态文本
@@ -607,6 +927,21 @@ func (w *UpdateWriter) WriteExpunge(seqNum uint32) error {
 	return w.conn.writeExpunge(seqNum) // 写入EXPUNGE响应
 }
 
+// writeExpungeUID 写入删除更新。启用 QRESYNC 且 UID 已知时写入 VANISHED 响应代替 EXPUNGE。
+func (w *UpdateWriter) writeExpungeUID(seqNum uint32, uid imap.UID) error {
+	w.conn.mutex.Lock()
+	qresync := w.conn.enabled.Has(imap.CapQResync)
+	w.conn.mutex.Unlock()
+
+	if uid == 0 || !qresync {
+		return w.WriteExpunge(seqNum)
+	}
+	if !w.allowExpunge {
+		return fmt.Errorf("imapserver：在此上下文中不允许进行 EXPUNGE 更新")
+	}
+	return w.conn.writeVanished(imap.UIDSetNum(uid), false)
+}
+
 // WriteNumMessages 写入EXISTS响应。
 func (w *UpdateWriter) WriteNumMessages(n uint32) error {
 	return w.conn.writeExists(n) // 写入EXISTS响应
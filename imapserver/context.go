@@ -0,0 +1,141 @@
+package imapserver
+
+import (
+	"context"
+
+	"github.com/luhaoyun888/go-imap-cn"
+)
+
+// 以下方法在会话实现了 SessionContext 时调用带上下文的版本，
+// 否则回退到 Session 中的同名方法。
+//
+// 对于不会再读取连接的命令，调用前通过 watchPeerClose 监视客户端是否断开连接。
+// APPEND 的会话方法需要读取字面量，IDLE 自己读取 DONE，轮询可能发生在读取命令之前，
+// 因此它们不监视连接。
+
+// watchPeerClose 在执行会话方法期间从连接中预读，客户端断开连接时 cancelOnErrorReader
+// 会取消连接及当前命令的上下文。
+//
+// 调用时命令必须已经读取完毕。预读期间不能读取连接，serve 在读取下一个命令之前调用
+// waitPeerClose 等待预读结束；客户端发送的下一个命令会留在缓冲区中。
+func (c *Conn) watchPeerClose() {
+	if c.peerWatch != nil {
+		return // 已经在监视
+	}
+	c.setReadTimeout(0) // 会话方法可能执行很久，由 serve 在命令完成后重新设置超时
+	done := make(chan struct{})
+	c.peerWatch = done
+	go func() {
+		defer close(done)
+		c.br.Peek(1)
+	}()
+}
+
+// waitPeerClose 等待 watchPeerClose 启动的预读结束。
+func (c *Conn) waitPeerClose() {
+	if c.peerWatch != nil {
+		<-c.peerWatch
+		c.peerWatch = nil
+	}
+}
+
+func (c *Conn) sessionSelect(mailbox string, options *imap.SelectOptions) (*imap.SelectData, error) {
+	if sess, ok := c.session.(SessionContext); ok {
+		c.watchPeerClose()
+		return sess.SelectContext(c.cmdCtx, mailbox, options)
+	}
+	return c.session.Select(mailbox, options)
+}
+
+func (c *Conn) sessionList(w *ListWriter, ref string, patterns []string, options *imap.ListOptions) error {
+	if sess, ok := c.session.(SessionContext); ok {
+		c.watchPeerClose()
+		return sess.ListContext(c.cmdCtx, w, ref, patterns, options)
+	}
+	return c.session.List(w, ref, patterns, options)
+}
+
+func (c *Conn) sessionStatus(mailbox string, options *imap.StatusOptions) (*imap.StatusData, error) {
+	if sess, ok := c.session.(SessionContext); ok {
+		c.watchPeerClose()
+		return sess.StatusContext(c.cmdCtx, mailbox, options)
+	}
+	return c.session.Status(mailbox, options)
+}
+
+func (c *Conn) sessionSearch(kind NumKind, criteria *imap.SearchCriteria, options *imap.SearchOptions) (*imap.SearchData, error) {
+	if sess, ok := c.session.(SessionContext); ok {
+		c.watchPeerClose()
+		return sess.SearchContext(c.cmdCtx, kind, criteria, options)
+	}
+	return c.session.Search(kind, criteria, options)
+}
+
+func (c *Conn) sessionFetch(w *FetchWriter, numSet imap.NumSet, options *imap.FetchOptions) error {
+	if sess, ok := c.session.(SessionContext); ok {
+		c.watchPeerClose()
+		return sess.FetchContext(c.cmdCtx, w, numSet, options)
+	}
+	return c.session.Fetch(w, numSet, options)
+}
+
+func (c *Conn) sessionStore(w *FetchWriter, numSet imap.NumSet, flags *imap.StoreFlags, options *imap.StoreOptions) error {
+	if sess, ok := c.session.(SessionContext); ok {
+		c.watchPeerClose()
+		return sess.StoreContext(c.cmdCtx, w, numSet, flags, options)
+	}
+	return c.session.Store(w, numSet, flags, options)
+}
+
+func (c *Conn) sessionCopy(numSet imap.NumSet, dest string) (*imap.CopyData, error) {
+	if sess, ok := c.session.(SessionContext); ok {
+		c.watchPeerClose()
+		return sess.CopyContext(c.cmdCtx, numSet, dest)
+	}
+	return c.session.Copy(numSet, dest)
+}
+
+func (c *Conn) sessionAppend(mailbox string, r imap.LiteralReader, options *imap.AppendOptions) (*imap.AppendData, error) {
+	if sess, ok := c.session.(SessionContext); ok {
+		return sess.AppendContext(c.cmdCtx, mailbox, r, options)
+	}
+	return c.session.Append(mailbox, r, options)
+}
+
+func (c *Conn) sessionMultiAppend(session SessionMultiAppend, mailbox string) (MultiAppender, error) {
+	if sess, ok := session.(SessionMultiAppendContext); ok {
+		return sess.MultiAppendContext(c.cmdCtx, mailbox)
+	}
+	return session.MultiAppend(mailbox)
+}
+
+func (c *Conn) sessionExpunge(w *ExpungeWriter, uids *imap.UIDSet) error {
+	if sess, ok := c.session.(SessionContext); ok {
+		c.watchPeerClose()
+		return sess.ExpungeContext(c.cmdCtx, w, uids)
+	}
+	return c.session.Expunge(w, uids)
+}
+
+func (c *Conn) sessionMove(session SessionMove, w *MoveWriter, numSet imap.NumSet, dest string) error {
+	if sess, ok := session.(SessionMoveContext); ok {
+		c.watchPeerClose()
+		return sess.MoveContext(c.cmdCtx, w, numSet, dest)
+	}
+	return session.Move(w, numSet, dest)
+}
+
+func (c *Conn) sessionPoll(w *UpdateWriter, allowExpunge bool) error {
+	if sess, ok := c.session.(SessionContext); ok {
+		return sess.PollContext(c.cmdCtx, w, allowExpunge)
+	}
+	return c.session.Poll(w, allowExpunge)
+}
+
+// sessionIdle 在单独的 goroutine 中调用，因此 ctx 由调用者传入。
+func (c *Conn) sessionIdle(ctx context.Context, w *UpdateWriter, stop <-chan struct{}) error {
+	if sess, ok := c.session.(SessionContext); ok {
+		return sess.IdleContext(ctx, w, stop)
+	}
+	return c.session.Idle(w, stop)
+}
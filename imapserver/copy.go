@@ -23,7 +23,10 @@ func (c *Conn) handleCopy(tag string, dec *imapwire.Decoder, numKind NumKind) er
 	if err := c.checkState(imap.ConnStateSelected); err != nil {
 		return err
 	}
-	data, err := c.session.Copy(numSet, dest)
+	if err := c.checkWritableMailbox(dest); err != nil {
+		return err
+	}
+	data, err := c.sessionCopy(numSet, dest)
 	if err != nil {
 		return err
 	}
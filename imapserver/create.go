@@ -11,12 +11,13 @@ import (
 // handleCreate 处理 CREATE 命令，创建一个新的邮箱。
 // 参数：
 //
+//	tag: 客户端提供的命令标签。
 //	dec: 用于解码请求的 imapwire 解码器。
 //
 // 返回值：
 //
 //	返回 nil 表示成功，其他返回值表示错误信息。
-func (c *Conn) handleCreate(dec *imapwire.Decoder) error {
+func (c *Conn) handleCreate(tag string, dec *imapwire.Decoder) error {
 	var (
 		name    string             // 存储邮箱名称
 		options imap.CreateOptions // 存储创建邮箱的选项
@@ -63,5 +64,35 @@ func (c *Conn) handleCreate(dec *imapwire.Decoder) error {
 	}
 
 	// 创建新的邮箱
-	return c.session.Create(name, &options) // 返回创建操作的结果
+	if err := c.session.Create(name, &options); err != nil {
+		return err // 返回创建操作的错误
+	}
+
+	// 支持 OBJECTID 时在 OK 响应中返回邮箱 ID
+	var mailboxID string
+	if session, ok := c.session.(SessionObjectID); ok && c.server.options.caps().Has(imap.CapObjectID) {
+		var err error
+		if mailboxID, err = session.MailboxID(name); err != nil {
+			return err
+		}
+	}
+
+	if err := c.poll("CREATE"); err != nil {
+		return err
+	}
+	return c.writeCreateOK(tag, mailboxID)
+}
+
+// writeCreateOK 写入 CREATE 成功的响应。
+// tag: 客户端提供的标记，mailboxID: 邮箱 ID，为空时不写入 MAILBOXID 响应码。
+func (c *Conn) writeCreateOK(tag, mailboxID string) error {
+	enc := newResponseEncoder(c)
+	defer enc.end()
+
+	enc.Atom(tag).SP().Atom("OK").SP()
+	if mailboxID != "" {
+		enc.Special('[').Atom("MAILBOXID").SP().Special('(').Atom(mailboxID).Special(')').Special(']').SP()
+	}
+	enc.Text("CREATE 完成")
+	return enc.CRLF()
 }
@@ -32,18 +32,26 @@ func (c *Conn) handleEnable(dec *imapwire.Decoder) error {
 		return err // 返回错误信息
 	}
 
+	caps := c.server.options.caps()
 	var enabled []imap.Cap // 存储启用的能力
 	// 检查请求的能力是否可以启用
 	for _, req := range requested {
 		switch req {
 		case imap.CapIMAP4rev2, imap.CapUTF8Accept:
 			enabled = append(enabled, req) // 启用请求的能力
+		case imap.CapCondStore, imap.CapQResync:
+			if caps.Has(req) {
+				enabled = append(enabled, req)
+			}
 		}
 	}
 
 	c.mutex.Lock() // 加锁以保护对启用能力的修改
 	for _, e := range enabled {
 		c.enabled[e] = struct{}{} // 将能力标记为已启用
+		if e == imap.CapQResync {
+			c.enabled[imap.CapCondStore] = struct{}{} // 启用 QRESYNC 隐含启用 CONDSTORE
+		}
 	}
 	c.mutex.Unlock() // 解锁
 
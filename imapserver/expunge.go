@@ -29,6 +29,10 @@ func (c *Conn) handleExpunge(dec *imapwire.Decoder) error {
 //
 //	返回 nil 表示成功，其他返回值表示错误信息。
 func (c *Conn) handleUIDExpunge(dec *imapwire.Decoder) error {
+	if err := c.checkCap(imap.CapUIDPlus); err != nil {
+		return err // 未宣告 UIDPLUS
+	}
+
 	var uidSet imap.UIDSet // 存储 UID 集合
 	if !dec.ExpectSP() || !dec.ExpectUIDSet(&uidSet) || !dec.ExpectCRLF() {
 		return dec.Err() // 如果解析失败，返回错误信息
@@ -48,8 +52,11 @@ func (c *Conn) expunge(uids *imap.UIDSet) error {
 	if err := c.checkState(imap.ConnStateSelected); err != nil {
 		return err // 检查连接状态是否为已选择，返回错误信息
 	}
-	w := &ExpungeWriter{conn: c}      // 创建 ExpungeWriter 实例
-	return c.session.Expunge(w, uids) // 调用会话的 Expunge 方法执行删除
+	if err := c.checkWritable(); err != nil {
+		return err // 只读邮箱不能删除邮件
+	}
+	w := &ExpungeWriter{conn: c}     // 创建 ExpungeWriter 实例
+	return c.sessionExpunge(w, uids) // 调用会话的 Expunge 方法执行删除
 }
 
 // writeExpunge 写入 EXPUNGE 更新响应。
@@ -67,6 +74,18 @@ func (c *Conn) writeExpunge(seqNum uint32) error {
 	return enc.CRLF()                                      // 返回编码后的响应
 }
 
+// writeVanished 写入 VANISHED 响应，earlier 表示这些邮件是在之前被删除的。
+func (c *Conn) writeVanished(uids imap.UIDSet, earlier bool) error {
+	enc := newResponseEncoder(c)
+	defer enc.end()
+	enc.Atom("*").SP().Atom("VANISHED").SP()
+	if earlier {
+		enc.Atom("(EARLIER)").SP()
+	}
+	enc.NumSet(uids)
+	return enc.CRLF()
+}
+
 // ExpungeWriter 写入 EXPUNGE 更新的结构体。
 type ExpungeWriter struct {
 	conn *Conn // 连接实例
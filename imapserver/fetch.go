@@ -82,6 +82,12 @@ func (c *Conn) handleFetch(dec *imapwire.Decoder, numKind NumKind) error {
 		}
 	}
 
+	if dec.SP() {
+		if err := readFetchModifiers(dec, &options); err != nil {
+			return err
+		}
+	}
+
 	if !dec.ExpectCRLF() {
 		return dec.Err() // 期望 CRLF 不正确，返回错误。
 	}
@@ -89,13 +95,27 @@ func (c *Conn) handleFetch(dec *imapwire.Decoder, numKind NumKind) error {
 	if err := c.checkState(imap.ConnStateSelected); err != nil { // 检查连接状态
 		return err
 	}
+	if options.EmailID || options.ThreadID {
+		if err := c.checkCap(imap.CapObjectID); err != nil {
+			return err
+		}
+	}
 
 	if numKind == NumKindUID {
 		options.UID = true // 如果是 UID 类型，设置 UID 选项为真。
 	}
+	if c.readOnly {
+		// 只读邮箱中读取正文不会设置 \Seen 标志
+		for _, bs := range options.BodySection {
+			bs.Peek = true
+		}
+		for _, bs := range options.BinarySection {
+			bs.Peek = true
+		}
+	}
 
-	w := &FetchWriter{conn: c, options: writerOptions}           // 创建 FetchWriter
-	if err := c.session.Fetch(w, numSet, &options); err != nil { // 执行 FETCH 操作
+	w := &FetchWriter{conn: c, options: writerOptions}          // 创建 FetchWriter
+	if err := c.sessionFetch(w, numSet, &options); err != nil { // 执行 FETCH 操作
 		return err
 	}
 	return nil
@@ -123,6 +143,12 @@ func handleFetchAtt(dec *imapwire.Decoder, attName string, options *imap.FetchOp
 		options.RFC822Size = true // 设置 RFC822.Size 选项为真
 	case "UID":
 		options.UID = true // 设置 UID 选项为真
+	case "MODSEQ":
+		options.ModSeq = true // 设置 ModSeq 选项为真
+	case "EMAILID":
+		options.EmailID = true // 设置 EmailID 选项为真
+	case "THREADID":
+		options.ThreadID = true // 设置 ThreadID 选项为真
 	case "RFC822": // 等同于 BODY[]
 		bs := &imap.FetchItemBodySection{}
 		writerOptions.obsolete[bs] = attName                  // 记录过时的 FETCH 项目体部分
@@ -213,6 +239,8 @@ func handleFetchBodyStructure(options *imap.FetchOptions, writerOptions *fetchWr
 	} else {
 		writerOptions.bodyStructure.nonExtended = true // 设置非扩展标志
 	}
+	// 同时请求 BODY 和 BODYSTRUCTURE 时，两者都由扩展结构写出
+	options.BodyStructure.Both = writerOptions.bodyStructure.extended && writerOptions.bodyStructure.nonExtended
 }
 
 // readFetchAttName 读取 FETCH 属性名称。
@@ -288,6 +316,31 @@ func readSection(dec *imapwire.Decoder, section *imap.FetchItemBodySection) erro
 	return nil
 }
 
+// readFetchModifiers 读取 FETCH 修饰符列表。
+//
+// 参数：
+//
+//	dec - 解码器，用于解码修饰符。
+//	options - FETCH 选项。
+func readFetchModifiers(dec *imapwire.Decoder, options *imap.FetchOptions) error {
+	return dec.ExpectList(func() error {
+		var name string
+		if !dec.ExpectAtom(&name) || !dec.ExpectSP() {
+			return dec.Err()
+		}
+		switch strings.ToUpper(name) {
+		case "CHANGEDSINCE":
+			if !dec.ExpectModSeq(&options.ChangedSince) {
+				return dec.Err()
+			}
+			options.ModSeq = true // CHANGEDSINCE 隐含 MODSEQ
+		default:
+			return newClientBugError("未知的 FETCH 修饰符")
+		}
+		return nil
+	})
+}
+
 // readSectionPart 读取部分的序号。
 func readSectionPart(dec *imapwire.Decoder) (part []int, dot bool) {
 	for {
@@ -378,17 +431,29 @@ func maybeReadPartial(dec *imapwire.Decoder) (*imap.SectionPartial, error) {
 
 // FetchWriter 写入 FETCH 响应。
 type FetchWriter struct {
-	conn    *Conn              // 连接对象
-	options fetchWriterOptions // 写入选项
+	conn     *Conn              // 连接对象
+	options  fetchWriterOptions // 写入选项
+	modified imap.NumSet        // STORE 中未满足 UNCHANGEDSINCE 条件的消息
+}
+
+// WriteModified 记录因 UNCHANGEDSINCE 条件未满足而未被修改的消息。
+//
+// 这些消息将通过 MODIFIED 响应码返回给客户端。此方法仅对 STORE 命令有效。
+func (cmd *FetchWriter) WriteModified(numSet imap.NumSet) {
+	cmd.modified = numSet
 }
 
 // CreateMessage 为消息写入 FETCH 响应。
 //
 // FetchResponseWriter.Close 必须在写入任何更多消息数据项之前调用。
 func (cmd *FetchWriter) CreateMessage(seqNum uint32) *FetchResponseWriter {
+	cmd.conn.mutex.Lock()
+	imap4rev2 := cmd.conn.enabled.Has(imap.CapIMAP4rev2)
+	cmd.conn.mutex.Unlock()
+
 	enc := newResponseEncoder(cmd.conn) // 创建响应编码器
 	enc.Atom("*").SP().Number(seqNum).SP().Atom("FETCH").SP().Special('(')
-	return &FetchResponseWriter{enc: enc, options: cmd.options} // 返回 FETCH 响应写入器
+	return &FetchResponseWriter{enc: enc, options: cmd.options, imap4rev2: imap4rev2} // 返回 FETCH 响应写入器
 }
 
 // FetchResponseWriter 为消息写入单个 FETCH 响应。
@@ -396,7 +461,8 @@ type FetchResponseWriter struct {
 	enc     *responseEncoder   // 响应编码器
 	options fetchWriterOptions // 写入选项
 
-	hasItem bool // 是否已经写入项
+	hasItem   bool // 是否已经写入项
+	imap4rev2 bool // 是否已启用 IMAP4rev2，此时不写入 \Recent 标志
 }
 
 // writeItemSep 写入项分隔符。
@@ -414,7 +480,19 @@ func (w *FetchResponseWriter) WriteUID(uid imap.UID) {
 }
 
 // WriteFlags 写入消息的标志。
+//
+// 启用 IMAP4rev2 时会忽略 \Recent 标志。
 func (w *FetchResponseWriter) WriteFlags(flags []imap.Flag) {
+	if w.imap4rev2 {
+		var l []imap.Flag
+		for _, flag := range flags {
+			if flag != internal.FlagRecent {
+				l = append(l, flag)
+			}
+		}
+		flags = l
+	}
+
 	w.writeItemSep() // 写入分隔符
 	w.enc.Atom("FLAGS").SP().List(len(flags), func(i int) {
 		w.enc.Flag(flags[i]) // 写入每个标志
@@ -437,6 +515,18 @@ func (w *FetchResponseWriter) WriteInternalDate(t time.Time) {
 //
 // 返回的 io.WriteCloser 必须在写入任何更多消息数据项之前关闭。
 func (w *FetchResponseWriter) WriteBodySection(section *imap.FetchItemBodySection, size int64) io.WriteCloser {
+	w.writeBodySectionName(section)
+	return w.enc.Literal(size) // 返回字面量写入器
+}
+
+// WriteBodySectionNIL 写入值为 NIL 的邮件体部分，用于请求的部分在邮件中不存在的情况。
+func (w *FetchResponseWriter) WriteBodySectionNIL(section *imap.FetchItemBodySection) {
+	w.writeBodySectionName(section)
+	w.enc.NIL()
+}
+
+// writeBodySectionName 写入邮件体部分的名称和其后的空格。
+func (w *FetchResponseWriter) writeBodySectionName(section *imap.FetchItemBodySection) {
 	w.writeItemSep() // 写入分隔符
 	enc := w.enc.Encoder
 
@@ -446,8 +536,7 @@ func (w *FetchResponseWriter) WriteBodySection(section *imap.FetchItemBodySectio
 		writeItemBodySection(enc, section) // 写入邮件体部分
 	}
 
-	enc.SP()                   // 添加空格
-	return w.enc.Literal(size) // 返回字面量写入器
+	enc.SP() // 添加空格
 }
 
 // writeItemBodySection 编写 BODY 部分的编码方法。
@@ -495,9 +584,55 @@ func (w *FetchResponseWriter) WriteBinarySection(section *imap.FetchItemBinarySe
 
 	enc.Atom("BINARY").Special('[')     // 写入 "BINARY" 原子
 	writeSectionPart(enc, section.Part) // 写入部分信息
-	enc.Special(']').SP()               // 结束特殊字符 ']' 并添加空格
-	enc.Special('~')                    // 指示字面值类型为 8
-	return w.enc.Literal(size)          // 返回一个写入器，用于写入二进制数据
+	enc.Special(']')                    // 结束特殊字符 ']'
+	if partial := section.Partial; partial != nil {
+		enc.Special('<').Number(uint32(partial.Offset)).Special('>') // 写入偏移信息
+	}
+	enc.SP()                   // 添加空格
+	enc.Special('~')           // 指示字面值类型为 8
+	return w.enc.Literal(size) // 返回一个写入器，用于写入二进制数据
+}
+
+// WriteBinarySectionSize 写入二进制部分解码后的大小。
+//
+// section: 要编码的 imap.FetchItemBinarySectionSize。
+// size: 解码后数据的大小。
+func (w *FetchResponseWriter) WriteBinarySectionSize(section *imap.FetchItemBinarySectionSize, size uint32) {
+	w.writeItemSep()     // 写入项分隔符
+	enc := w.enc.Encoder // 获取编码器
+
+	enc.Atom("BINARY.SIZE").Special('[') // 写入 "BINARY.SIZE" 原子
+	writeSectionPart(enc, section.Part)  // 写入部分信息
+	enc.Special(']').SP().Number(size)   // 写入大小
+}
+
+// WriteModSeq 写入消息的修改序列号。
+//
+// 此方法要求支持 CONDSTORE。
+func (w *FetchResponseWriter) WriteModSeq(modSeq uint64) {
+	w.writeItemSep()                                                   // 写入项分隔符
+	w.enc.Atom("MODSEQ").SP().Special('(').ModSeq(modSeq).Special(')') // 写入 MODSEQ
+}
+
+// WriteEmailID 写入邮件的对象 ID。
+//
+// 此方法要求支持 OBJECTID。
+func (w *FetchResponseWriter) WriteEmailID(id string) {
+	w.writeItemSep()                                              // 写入项分隔符
+	w.enc.Atom("EMAILID").SP().Special('(').Atom(id).Special(')') // 写入 EMAILID
+}
+
+// WriteThreadID 写入邮件所属会话的对象 ID，id 为空时写入 NIL，表示服务器不支持会话。
+//
+// 此方法要求支持 OBJECTID。
+func (w *FetchResponseWriter) WriteThreadID(id string) {
+	w.writeItemSep() // 写入项分隔符
+	w.enc.Atom("THREADID").SP()
+	if id == "" {
+		w.enc.NIL()
+	} else {
+		w.enc.Special('(').Atom(id).Special(')') // 写入 THREADID
+	}
 }
 
 // WriteEnvelope 写入消息的信封。
@@ -1,8 +1,10 @@
 package imapserver
 
 import (
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"runtime/debug"
 
 	"github.com/luhaoyun888/go-imap-cn"
@@ -21,10 +23,11 @@ func (c *Conn) handleIdle(dec *imapwire.Decoder) error {
 		return err // 检查连接状态是否为已验证，若不是则返回错误
 	}
 
-	if err := c.writeContReq("正在等待"); err != nil { // 将 "idling" 替换为 "正在等待"
+	if err := c.writeContReq("正在等待"); err != nil {
 		return err // 发送 IDLE 请求的持续状态
 	}
 
+	ctx := c.cmdCtx             // IDLE 结束时被取消
 	stop := make(chan struct{}) // 创建停止信号通道
 	done := make(chan error, 1) // 创建完成信号通道
 	go func() {
@@ -35,18 +38,32 @@ func (c *Conn) handleIdle(dec *imapwire.Decoder) error {
 			}
 		}()
 		w := &UpdateWriter{conn: c, allowExpunge: true} // 创建更新写入器
-		done <- c.session.Idle(w, stop)                 // 进入 IDLE 状态并等待停止信号
+		done <- c.sessionIdle(ctx, w, stop)             // 进入 IDLE 状态并等待停止信号
 	}()
 
-	c.setReadTimeout(idleReadTimeout)      // 设置读取超时
+	readTimeout := idleReadTimeout
+	maxIdle := c.server.options.MaxIdleDuration
+	if maxIdle > 0 {
+		readTimeout = maxIdle
+	}
+	c.setReadTimeout(readTimeout)          // 设置读取超时
 	line, isPrefix, err := c.br.ReadLine() // 读取一行输入
 	close(stop)                            // 关闭停止信号通道
+	var netErr net.Error
+	if maxIdle > 0 && errors.As(err, &netErr) && netErr.Timeout() {
+		// 超过最长 IDLE 时长，等待会话停止推送更新后发送 BYE 并关闭连接
+		if err := <-done; err != nil {
+			c.server.logger().Printf("IDLE 失败: %v", err)
+		}
+		c.state = imap.ConnStateLogout
+		return c.Bye("IDLE 时间过长")
+	}
 	if err == io.EOF {
 		return nil // 如果到达文件结束，返回 nil
 	} else if err != nil {
 		return err // 其他错误返回
-	} else if isPrefix || string(line) != "完成" { // 将 "DONE" 替换为 "完成"
-		return newClientBugError("语法错误: 期望以 '完成' 结束 IDLE 命令") // 处理语法错误
+	} else if isPrefix || string(line) != "DONE" {
+		return newClientBugError("语法错误: 期望以 DONE 结束 IDLE 命令") // 处理语法错误
 	}
 
 	return <-done // 返回完成信号的结果
@@ -0,0 +1,36 @@
+package imapmemserver
+
+import (
+	"bufio"
+	"bytes"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-message/textproto"
+)
+
+// InternalDateFunc 根据邮件内容生成 INTERNALDATE。
+//
+// 仅在 APPEND 未指定日期时调用。返回零值时使用当前时间。
+type InternalDateFunc func(buf []byte) time.Time
+
+// ReceivedInternalDate 从邮件最上面的 Received 头字段中提取投递时间，
+// 该字段由最后一个经手的邮件服务器添加。没有可解析的 Received 头字段时返回零值。
+func ReceivedInternalDate(buf []byte) time.Time {
+	header, err := textproto.ReadHeader(bufio.NewReader(bytes.NewReader(buf)))
+	if err != nil {
+		return time.Time{}
+	}
+	received := header.Get("Received")
+	// Received 的日期位于最后一个分号之后（RFC 5322 第 3.6.7 节）
+	i := strings.LastIndexByte(received, ';')
+	if i < 0 {
+		return time.Time{}
+	}
+	t, err := mail.ParseDate(strings.TrimSpace(received[i+1:]))
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
@@ -2,6 +2,9 @@ package imapmemserver
 
 import (
 	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
 	"sort"
 	"sync"
 	"time"
@@ -15,22 +18,181 @@ import (
 type Mailbox struct {
 	tracker     *imapserver.MailboxTracker // 邮箱跟踪器，用于跟踪邮箱的状态
 	uidValidity uint32                     // UID 有效性，用于确保 UID 的唯一性
+	id          string                     // 邮箱 ID（RFC 8474），重命名后保持不变
 
 	mutex      sync.Mutex // 互斥锁，用于保护邮箱的并发访问
 	name       string     // 邮箱名称
 	subscribed bool       // 是否订阅该邮箱
 	l          []*message // 存储邮件的切片
 	uidNext    imap.UID   // 下一个 UID
+
+	highestModSeq uint64 // 最高的修改序列号，每次邮件被修改时递增
+
+	acl map[imap.RightsIdentifier]imap.RightSet // 访问控制列表，为 nil 时所有者拥有所有权限
+
+	internalDate InternalDateFunc // 未指定日期时生成 INTERNALDATE，为 nil 时使用当前时间
+	appendLimit  *uint32          // APPEND 接受的最大邮件大小，为 nil 时不限制
+
+	quota    map[imap.QuotaResourceType]int64 // 配额限制（RFC 9208），为 nil 时不限制
+	metadata metadataStore                    // 邮箱元数据（RFC 5464）
+	access   MailboxAccess                    // 访问模式
+
+	store *mailboxStore // 磁盘存储，为 nil 时只保存在内存中
 }
 
+// MailboxAccess 表示邮箱的访问模式。
+type MailboxAccess int
+
+const (
+	// MailboxAccessReadWrite 表示邮箱可读写，这是默认的模式
+	MailboxAccessReadWrite MailboxAccess = iota
+	// MailboxAccessReadOnly 表示邮箱只读：SELECT 返回 READ-ONLY，拒绝 STORE 和
+	// EXPUNGE，也不接受 APPEND、COPY 和 MOVE 写入的新邮件
+	MailboxAccessReadOnly
+	// MailboxAccessAppendOnly 表示邮箱只追加：SELECT 返回 READ-ONLY，拒绝 STORE
+	// 和 EXPUNGE，但接受 APPEND、COPY 和 MOVE 写入的新邮件，例如归档邮箱
+	MailboxAccessAppendOnly
+)
+
 // NewMailbox 创建一个新的邮箱。
 func NewMailbox(name string, uidValidity uint32) *Mailbox {
 	return &Mailbox{
 		tracker:     imapserver.NewMailboxTracker(0), // 初始化邮箱跟踪器
 		uidValidity: uidValidity,                     // 设置 UID 有效性
+		id:          newMailboxID(),                  // 生成邮箱 ID
 		name:        name,                            // 设置邮箱名称
 		uidNext:     1,                               // 初始化下一个 UID 为 1
+
+		highestModSeq: 1, // 修改序列号必须为非零值
+	}
+}
+
+// newMailboxID 生成一个随机的邮箱 ID。
+func newMailboxID() string {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	return "M" + hex.EncodeToString(b[:])
+}
+
+// SetInternalDateFunc 设置 APPEND 未指定日期时生成 INTERNALDATE 的函数。
+// f 为 nil 时使用当前时间。
+func (mbox *Mailbox) SetInternalDateFunc(f InternalDateFunc) {
+	mbox.mutex.Lock()
+	defer mbox.mutex.Unlock()
+	mbox.internalDate = f
+}
+
+// SetAppendLimit 设置邮箱接受的最大邮件大小（RFC 7889），会在 STATUS 中返回。
+// limit 为 nil 时不限制。
+func (mbox *Mailbox) SetAppendLimit(limit *uint32) {
+	mbox.mutex.Lock()
+	defer mbox.mutex.Unlock()
+	mbox.appendLimit = limit
+}
+
+// SetAccess 设置邮箱的访问模式。新的模式在下一次 SELECT 时对已选择该邮箱的连接生效。
+func (mbox *Mailbox) SetAccess(access MailboxAccess) {
+	mbox.mutex.Lock()
+	defer mbox.mutex.Unlock()
+	mbox.access = access
+}
+
+// checkAppendLimit 检查邮件大小是否超出邮箱的限制。
+func (mbox *Mailbox) checkAppendLimit(size int64) error {
+	mbox.mutex.Lock()
+	limit := mbox.appendLimit
+	mbox.mutex.Unlock()
+	if limit != nil && size > int64(*limit) {
+		return &imap.Error{
+			Type: imap.StatusResponseTypeNo,
+			Code: imap.ResponseCodeTooBig,
+			Text: fmt.Sprintf("邮箱的邮件大小限制为 %v 字节", *limit),
+		}
+	}
+	return nil
+}
+
+// SetQuota 设置邮箱的配额限制（RFC 9208）。每个邮箱都是自己的配额根，支持
+// STORAGE（以 1024 字节为单位）和 MESSAGE 两种资源，limits 中没有的资源不受限制。
+//
+// 超出配额的 APPEND、COPY 和 MOVE 会以 NO [OVERQUOTA] 失败。要让客户端使用
+// 配额命令，服务器应在 Options.Caps 中加入 QUOTA、QUOTA=RES-STORAGE 和
+// QUOTA=RES-MESSAGE，允许管理员用户（参见 User.SetQuotaAdmin）修改限制时还应加入 QUOTASET。
+func (mbox *Mailbox) SetQuota(limits map[imap.QuotaResourceType]int64) error {
+	quota := make(map[imap.QuotaResourceType]int64, len(limits))
+	for typ, limit := range limits {
+		switch typ {
+		case imap.QuotaResourceStorage, imap.QuotaResourceMessage:
+			quota[typ] = limit
+		default:
+			return &imap.Error{
+				Type: imap.StatusResponseTypeNo,
+				Code: imap.ResponseCodeCannot,
+				Text: fmt.Sprintf("不支持的配额资源 %v", typ),
+			}
+		}
+	}
+	if len(quota) == 0 {
+		quota = nil
+	}
+
+	mbox.mutex.Lock()
+	defer mbox.mutex.Unlock()
+	mbox.quota = quota
+	return nil
+}
+
+// quotaData 返回邮箱配额的使用情况和限制，只包含设置了限制的资源。
+func (mbox *Mailbox) quotaData() *imap.QuotaData {
+	mbox.mutex.Lock()
+	defer mbox.mutex.Unlock()
+
+	data := imap.QuotaData{
+		Root:      mbox.name,
+		Resources: make(map[imap.QuotaResourceType]imap.QuotaResourceData),
+	}
+	for typ, limit := range mbox.quota {
+		var usage int64
+		switch typ {
+		case imap.QuotaResourceStorage:
+			usage = mbox.sizeLocked() / 1024
+		case imap.QuotaResourceMessage:
+			usage = int64(len(mbox.l))
+		}
+		data.Resources[typ] = imap.QuotaResourceData{Usage: usage, Limit: limit}
+	}
+	return &data
+}
+
+// checkQuotaLocked 在锁定状态下检查追加邮件 msgs 后是否超出邮箱的配额。
+func (mbox *Mailbox) checkQuotaLocked(msgs []*message) error {
+	if mbox.quota == nil {
+		return nil
+	}
+
+	over := false
+	if limit, ok := mbox.quota[imap.QuotaResourceMessage]; ok && int64(len(mbox.l)+len(msgs)) > limit {
+		over = true
 	}
+	if limit, ok := mbox.quota[imap.QuotaResourceStorage]; ok {
+		size := mbox.sizeLocked()
+		for _, msg := range msgs {
+			size += int64(len(msg.buf))
+		}
+		if size > limit*1024 {
+			over = true
+		}
+	}
+	if over {
+		return &imap.Error{
+			Type: imap.StatusResponseTypeNo,
+			Code: imap.ResponseCodeOverQuota,
+			Text: "超出邮箱的配额",
+		}
+	}
+	return nil
 }
 
 // list 返回邮箱的列表数据。
@@ -90,6 +252,24 @@ func (mbox *Mailbox) statusDataLocked(options *imap.StatusOptions) *imap.StatusD
 		size := mbox.sizeLocked() // 计算邮件总大小
 		data.Size = &size         // 设置邮件总大小
 	}
+	if options.NumRecent { // 如果请求最近邮件数量
+		var num uint32
+		for _, msg := range mbox.l {
+			if msg.recent { // 尚未被任何会话以读写方式选择
+				num++
+			}
+		}
+		data.NumRecent = &num
+	}
+	if options.HighestModSeq { // 如果请求最高的修改序列号
+		data.HighestModSeq = mbox.highestModSeq
+	}
+	if options.AppendLimit { // 如果请求追加限制，nil 表示不限制
+		data.AppendLimit = mbox.appendLimit
+	}
+	if options.MailboxID { // 如果请求邮箱对象 ID
+		data.MailboxID = mbox.id
+	}
 	return &data
 }
 
@@ -117,78 +297,216 @@ func (mbox *Mailbox) sizeLocked() int64 {
 // appendLiteral 将字面量内容附加到邮箱中。
 // r: 邮件内容的字面量读取器，options: 附加选项。
 func (mbox *Mailbox) appendLiteral(r imap.LiteralReader, options *imap.AppendOptions) (*imap.AppendData, error) {
+	if err := mbox.checkAppendLimit(r.Size()); err != nil {
+		return nil, err
+	}
 	var buf bytes.Buffer
 	if _, err := buf.ReadFrom(r); err != nil { // 从读取器中读取字面量内容
 		return nil, err // 如果出错，返回错误
 	}
-	return mbox.appendBytes(buf.Bytes(), options), nil // 将字节内容附加到邮箱
-}
-
-// copyMsg 复制一封邮件并返回附加数据。
-// msg: 要复制的邮件。
-func (mbox *Mailbox) copyMsg(msg *message) *imap.AppendData {
-	return mbox.appendBytes(msg.buf, &imap.AppendOptions{
-		Time:  msg.t,          // 邮件时间
-		Flags: msg.flagList(), // 邮件标志
-	})
+	return mbox.appendBytes(buf.Bytes(), options) // 将字节内容附加到邮箱
 }
 
 // appendBytes 将字节内容附加到邮箱中。
 // buf: 邮件内容的字节切片，options: 附加选项。
-func (mbox *Mailbox) appendBytes(buf []byte, options *imap.AppendOptions) *imap.AppendData {
-	msg := &message{
-		flags: make(map[imap.Flag]struct{}), // 初始化邮件标志
-		buf:   buf,                          // 设置邮件内容
+func (mbox *Mailbox) appendBytes(buf []byte, options *imap.AppendOptions) (*imap.AppendData, error) {
+	data, err := mbox.appendBatch([]pendingMessage{{buf: buf, options: options}})
+	if err != nil {
+		return nil, err
 	}
+	data.UIDs = nil // 单封邮件只返回 UID
+	return data, nil
+}
 
-	if options.Time.IsZero() { // 如果未指定时间，则使用当前时间
-		msg.t = time.Now()
-	} else {
-		msg.t = options.Time // 否则使用指定时间
-	}
+// pendingMessage 是一封等待追加到邮箱中的邮件。
+type pendingMessage struct {
+	buf     []byte              // 邮件内容
+	options *imap.AppendOptions // 附加选项
+}
+
+// appendBatch 将多封邮件一次性附加到邮箱中，它们的 UID 是连续的。
+func (mbox *Mailbox) appendBatch(pending []pendingMessage) (*imap.AppendData, error) {
+	mbox.mutex.Lock()
+	internalDate := mbox.internalDate
+	mbox.mutex.Unlock()
+
+	msgs := make([]*message, len(pending))
+	for i, p := range pending {
+		msg := &message{
+			flags:  make(map[imap.Flag]struct{}), // 初始化邮件标志
+			buf:    p.buf,                        // 设置邮件内容
+			recent: true,                         // 新邮件带有 \Recent 标志
+		}
 
-	for _, flag := range options.Flags { // 设置邮件标志
-		msg.flags[canonicalFlag(flag)] = struct{}{}
+		msg.t = p.options.Time // 使用指定时间
+		if msg.t.IsZero() && internalDate != nil {
+			msg.t = internalDate(p.buf) // 未指定时间时由生成函数决定
+		}
+		if msg.t.IsZero() { // 仍未确定时间，则使用当前时间
+			msg.t = time.Now()
+		}
+
+		for _, flag := range p.options.Flags { // 设置邮件标志
+			msg.flags[canonicalFlag(flag)] = struct{}{}
+		}
+		msgs[i] = msg
 	}
 
 	mbox.mutex.Lock() // 锁定邮箱以进行并发安全访问
 	defer mbox.mutex.Unlock()
 
-	msg.uid = mbox.uidNext // 设置邮件 UID
-	mbox.uidNext++         // 更新下一个 UID
+	if mbox.access == MailboxAccessReadOnly {
+		return nil, &imap.Error{
+			Type: imap.StatusResponseTypeNo,
+			Code: imap.ResponseCodeCannot,
+			Text: "邮箱为只读",
+		}
+	}
+	if err := mbox.checkQuotaLocked(msgs); err != nil {
+		return nil, err
+	}
+
+	prevUIDNext, prevHighestModSeq := mbox.uidNext, mbox.highestModSeq
+	data := &imap.AppendData{UIDValidity: mbox.uidValidity} // 返回 UID 有效性
+	for _, msg := range msgs {
+		msg.uid = mbox.uidNext // 设置邮件 UID
+		mbox.uidNext++         // 更新下一个 UID
+		msg.modSeq = mbox.nextModSeqLocked()
+		data.UIDs.AddNum(msg.uid)
+	}
+	if len(msgs) == 1 {
+		data.UID = msgs[0].uid // 返回邮件 UID
+	}
+
+	// rollback 撤销追加，并删除已经写入磁盘的邮件文件
+	n := len(mbox.l)
+	rollback := func(written []*message) {
+		mbox.l = mbox.l[:n]
+		mbox.uidNext, mbox.highestModSeq = prevUIDNext, prevHighestModSeq
+		for _, msg := range written {
+			mbox.store.removeMessage(msg.uid)
+		}
+	}
+
+	if mbox.store != nil {
+		for i, msg := range msgs {
+			if err := mbox.store.writeMessage(msg.uid, msg.buf); err != nil {
+				rollback(msgs[:i])
+				return nil, err
+			}
+		}
+	}
 
-	mbox.l = append(mbox.l, msg)                       // 将邮件添加到邮箱中
+	mbox.l = append(mbox.l, msgs...) // 将邮件添加到邮箱中
+	if err := mbox.saveLocked(); err != nil {
+		rollback(msgs) // 索引写入失败，撤销追加
+		return nil, err
+	}
 	mbox.tracker.QueueNumMessages(uint32(len(mbox.l))) // 更新消息数量
 
-	return &imap.AppendData{
-		UIDValidity: mbox.uidValidity, // 返回 UID 有效性
-		UID:         msg.uid,          // 返回邮件 UID
+	return data, nil
+}
+
+// removeAppended 删除刚刚由 appendBatch 追加、UID 在 uids 中的邮件，用于撤销失败的 MOVE。
+func (mbox *Mailbox) removeAppended(uids imap.UIDSet) error {
+	mbox.mutex.Lock()
+	defer mbox.mutex.Unlock()
+
+	expunged := make(map[*message]struct{})
+	for _, msg := range mbox.l {
+		if uids.Contains(msg.uid) {
+			expunged[msg] = struct{}{}
+		}
 	}
+	_, err := mbox.expungeLocked(expunged)
+	return err
+}
+
+// multiAppender 实现了 imapserver.MultiAppender。邮件在提交前缓存在内存中。
+type multiAppender struct {
+	mbox    *Mailbox
+	pending []pendingMessage
+}
+
+var _ imapserver.MultiAppender = (*multiAppender)(nil)
+
+// Append 读取一封邮件并缓存，直到提交。
+func (a *multiAppender) Append(r imap.LiteralReader, options *imap.AppendOptions) error {
+	if err := a.mbox.checkAppendLimit(r.Size()); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(r); err != nil { // 从读取器中读取字面量内容
+		return err
+	}
+	a.pending = append(a.pending, pendingMessage{buf: buf.Bytes(), options: options})
+	return nil
+}
+
+// Commit 将所有缓存的邮件附加到邮箱中。
+func (a *multiAppender) Commit() (*imap.AppendData, error) {
+	return a.mbox.appendBatch(a.pending)
+}
+
+// Abort 丢弃所有缓存的邮件。
+func (a *multiAppender) Abort() {
+	a.pending = nil
 }
 
 // rename 更改邮箱名称。
 // newName: 新的邮箱名称。
-func (mbox *Mailbox) rename(newName string) {
-	mbox.mutex.Lock()   // 锁定邮箱以进行并发安全访问
+func (mbox *Mailbox) rename(newName string) error {
+	mbox.mutex.Lock()         // 锁定邮箱以进行并发安全访问
+	defer mbox.mutex.Unlock() // 解锁
+
+	oldName := mbox.name
 	mbox.name = newName // 更新邮箱名称
-	mbox.mutex.Unlock() // 解锁
+	if err := mbox.saveLocked(); err != nil {
+		mbox.name = oldName
+		return err
+	}
+	return nil
+}
+
+// aclLocked 在锁定状态下返回邮箱的访问控制列表。
+// owner: 邮箱所有者，首次访问时授予所有权限。
+func (mbox *Mailbox) aclLocked(owner string) map[imap.RightsIdentifier]imap.RightSet {
+	if mbox.acl == nil {
+		mbox.acl = map[imap.RightsIdentifier]imap.RightSet{
+			imap.RightsIdentifier(owner): imap.RightSetAll,
+		}
+	}
+	return mbox.acl
 }
 
 // SetSubscribed 更改邮箱的订阅状态。
 // subscribed: 订阅状态，true 表示订阅，false 表示未订阅。
+//
+// 邮箱保存在磁盘上时，写入失败的错误会被忽略，需要处理错误时使用 User.Subscribe。
 func (mbox *Mailbox) SetSubscribed(subscribed bool) {
+	mbox.setSubscribed(subscribed)
+}
+
+// setSubscribed 更改邮箱的订阅状态并写入磁盘。
+func (mbox *Mailbox) setSubscribed(subscribed bool) error {
 	mbox.mutex.Lock()            // 锁定邮箱以进行并发安全访问
+	defer mbox.mutex.Unlock()    // 解锁
 	mbox.subscribed = subscribed // 更新订阅状态
-	mbox.mutex.Unlock()          // 解锁
+	return mbox.saveLocked()
 }
 
 // selectDataLocked 在锁定状态下返回选择数据。
 func (mbox *Mailbox) selectDataLocked() *imap.SelectData {
 	flags := mbox.flagsLocked() // 获取当前邮件标志
 
-	permanentFlags := make([]imap.Flag, len(flags))            // 创建一个永久标志的切片
-	copy(permanentFlags, flags)                                // 复制当前邮件标志
-	permanentFlags = append(permanentFlags, imap.FlagWildcard) // 添加通配符标志
+	readOnly := mbox.access != MailboxAccessReadWrite
+
+	var permanentFlags []imap.Flag
+	if !readOnly { // 只读邮箱没有可以永久更改的标志
+		permanentFlags = make([]imap.Flag, len(flags))             // 创建一个永久标志的切片
+		copy(permanentFlags, flags)                                // 复制当前邮件标志
+		permanentFlags = append(permanentFlags, imap.FlagWildcard) // 添加通配符标志
+	}
 
 	return &imap.SelectData{
 		Flags:          flags,               // 返回当前标志
@@ -196,9 +514,17 @@ func (mbox *Mailbox) selectDataLocked() *imap.SelectData {
 		NumMessages:    uint32(len(mbox.l)), // 返回邮件数量
 		UIDNext:        mbox.uidNext,        // 返回下一个 UID
 		UIDValidity:    mbox.uidValidity,    // 返回 UID 有效性
+		HighestModSeq:  mbox.highestModSeq,  // 返回最高的修改序列号
+		ReadOnly:       readOnly,            // 返回邮箱是否只读
 	}
 }
 
+// nextModSeqLocked 在锁定状态下递增并返回邮箱的修改序列号。
+func (mbox *Mailbox) nextModSeqLocked() uint64 {
+	mbox.highestModSeq++
+	return mbox.highestModSeq
+}
+
 // flagsLocked 在锁定状态下返回所有邮件的标志。
 func (mbox *Mailbox) flagsLocked() []imap.Flag {
 	m := make(map[imap.Flag]struct{}) // 使用 map 存储唯一的标志
@@ -239,26 +565,26 @@ func (mbox *Mailbox) Expunge(w *imapserver.ExpungeWriter, uids *imap.UIDSet) err
 		return nil // 返回 nil
 	}
 
-	mbox.mutex.Lock()            // 锁定邮箱以进行并发安全访问
-	mbox.expungeLocked(expunged) // 调用内部方法删除邮件
-	mbox.mutex.Unlock()          // 解锁
+	mbox.mutex.Lock()                      // 锁定邮箱以进行并发安全访问
+	_, err := mbox.expungeLocked(expunged) // 调用内部方法删除邮件
+	mbox.mutex.Unlock()                    // 解锁
 
-	return nil // 返回 nil
+	return err
 }
 
 // expungeLocked 在锁定状态下删除已标记为删除的邮件。
 // expunged: 待删除的邮件集合。
-func (mbox *Mailbox) expungeLocked(expunged map[*message]struct{}) (seqNums []uint32) {
+func (mbox *Mailbox) expungeLocked(expunged map[*message]struct{}) (seqNums []uint32, err error) {
 	// TODO: 优化
 
-	// 反向迭代，以保持序列号的一致性
-	var filtered []*message
+	// 反向迭代，按降序生成序列号：删除序号较大的邮件不会改变序号较小的邮件，
+	// 因此客户端依次应用每条 EXPUNGE 响应后，后续响应中的序号仍然有效
+	var filtered, removed []*message
 	for i := len(mbox.l) - 1; i >= 0; i-- { // 从最后一封邮件开始迭代
 		msg := mbox.l[i]
 		if _, ok := expunged[msg]; ok { // 如果当前邮件在待删除集合中
-			seqNum := uint32(i) + 1           // 计算序列号
-			seqNums = append(seqNums, seqNum) // 将序列号添加到返回切片中
-			mbox.tracker.QueueExpunge(seqNum) // 更新跟踪器以通知删除
+			seqNums = append(seqNums, uint32(i)+1) // 将序列号添加到返回切片中
+			removed = append(removed, msg)
 		} else {
 			filtered = append(filtered, msg) // 如果邮件未被删除，添加到过滤后的切片中
 		}
@@ -270,9 +596,22 @@ func (mbox *Mailbox) expungeLocked(expunged map[*message]struct{}) (seqNums []ui
 		filtered[i], filtered[j] = filtered[j], filtered[i] // 反转切片顺序
 	}
 
+	prev := mbox.l
 	mbox.l = filtered // 更新邮箱中的邮件列表
+	if err := mbox.saveLocked(); err != nil {
+		mbox.l = prev // 索引写入失败，撤销删除
+		return nil, err
+	}
+
+	for i, msg := range removed {
+		mbox.tracker.QueueExpungeUID(seqNums[i], msg.uid) // 更新跟踪器以通知删除
+		if mbox.store != nil {
+			// 索引已经更新，删除失败只会留下无用的文件
+			mbox.store.removeMessage(msg.uid)
+		}
+	}
 
-	return seqNums // 返回已删除邮件的序列号
+	return seqNums, nil // 返回已删除邮件的序列号
 }
 
 // NewView 创建一个新的邮箱视图。
@@ -292,6 +631,26 @@ type MailboxView struct {
 	*Mailbox                             // 嵌入 Mailbox
 	tracker   *imapserver.SessionTracker // 会话跟踪器
 	searchRes imap.UIDSet                // 搜索结果的 UID 集
+	recent    imap.UIDSet                // 对此会话而言带有 \Recent 标志的邮件
+}
+
+// claimRecentLocked 记录对此视图而言带有 \Recent 标志的邮件，并返回其数量。
+//
+// 只有第一个以读写方式选择邮箱的会话会看到新邮件的 \Recent 标志。只读视图
+// （EXAMINE）不会清除邮件的 \Recent 状态，参见 RFC 3501 第 6.3.2 节。
+func (mbox *MailboxView) claimRecentLocked(readOnly bool) uint32 {
+	var n uint32
+	for _, msg := range mbox.l {
+		if !msg.recent {
+			continue
+		}
+		mbox.recent.AddNum(msg.uid)
+		if !readOnly {
+			msg.recent = false
+		}
+		n++
+	}
+	return n
 }
 
 // Close 释放为邮箱视图分配的资源。
@@ -309,24 +668,77 @@ func (mbox *MailboxView) Fetch(w *imapserver.FetchWriter, numSet imap.NumSet, op
 			break
 		}
 	}
+	for _, bs := range options.BinarySection { // 遍历请求的二进制部分
+		if !bs.Peek {
+			markSeen = true
+			break
+		}
+	}
+
+	mbox.mutex.Lock()
+	defer mbox.mutex.Unlock()
+
+	if markSeen {
+		if err := mbox.markSeenLocked(numSet); err != nil {
+			return err
+		}
+	}
 
 	var err error
-	mbox.forEach(numSet, func(seqNum uint32, msg *message) { // 遍历要获取的邮件
+	mbox.forEachLocked(numSet, func(seqNum uint32, msg *message) { // 遍历要获取的邮件
 		if err != nil {
 			return // 如果出错，停止遍历
 		}
 
-		if markSeen { // 如果需要标记为已读
-			msg.flags[canonicalFlag(imap.FlagSeen)] = struct{}{}                         // 设置已读标志
-			mbox.Mailbox.tracker.QueueMessageFlags(seqNum, msg.uid, msg.flagList(), nil) // 更新标志到跟踪器
+		if options.ChangedSince != 0 && msg.modSeq <= options.ChangedSince {
+			return // 跳过自 CHANGEDSINCE 以来未修改的邮件
 		}
 
-		respWriter := w.CreateMessage(mbox.tracker.EncodeSeqNum(seqNum)) // 创建响应写入器
-		err = msg.fetch(respWriter, options)                             // 获取邮件数据
+		respWriter := w.CreateMessage(mbox.tracker.EncodeSeqNum(seqNum))    // 创建响应写入器
+		err = msg.fetch(respWriter, options, mbox.recent.Contains(msg.uid)) // 获取邮件数据
 	})
 	return err // 返回可能的错误
 }
 
+// markSeenLocked 在锁定状态下为 numSet 中的邮件设置 \Seen 标志。
+//
+// 与 Store 一样，先写入磁盘，成功后才通知其他会话；写入失败时撤销修改。
+func (mbox *MailboxView) markSeenLocked(numSet imap.NumSet) error {
+	type seenMsg struct {
+		seqNum uint32
+		msg    *message
+		modSeq uint64
+	}
+
+	seenFlag := canonicalFlag(imap.FlagSeen)
+	prevHighestModSeq := mbox.highestModSeq
+	var changed []seenMsg
+	mbox.forEachLocked(numSet, func(seqNum uint32, msg *message) {
+		if _, seen := msg.flags[seenFlag]; seen {
+			return
+		}
+		changed = append(changed, seenMsg{seqNum: seqNum, msg: msg, modSeq: msg.modSeq})
+		msg.flags[seenFlag] = struct{}{}     // 设置已读标志
+		msg.modSeq = mbox.nextModSeqLocked() // 更新修改序列号
+	})
+	if len(changed) == 0 {
+		return nil
+	}
+
+	if err := mbox.saveLocked(); err != nil {
+		for _, c := range changed {
+			delete(c.msg.flags, seenFlag)
+			c.msg.modSeq = c.modSeq
+		}
+		mbox.highestModSeq = prevHighestModSeq
+		return err
+	}
+	for _, c := range changed {
+		mbox.Mailbox.tracker.QueueMessageFlags(c.seqNum, c.msg.uid, c.msg.flagList(), nil) // 更新到跟踪器
+	}
+	return nil
+}
+
 // Search 在邮箱中搜索符合条件的邮件。
 // numKind: 序列号或 UID 类型，criteria: 搜索条件，options: 搜索选项。
 func (mbox *MailboxView) Search(numKind imapserver.NumKind, criteria *imap.SearchCriteria, options *imap.SearchOptions) (*imap.SearchData, error) {
@@ -336,6 +748,7 @@ func (mbox *MailboxView) Search(numKind imapserver.NumKind, criteria *imap.Searc
 	mbox.staticSearchCriteria(criteria) // 处理静态搜索条件
 
 	data := imap.SearchData{UID: numKind == imapserver.NumKindUID} // 初始化搜索数据
+	hasModSeq := searchCriteriaHasModSeq(criteria)                 // 使用 MODSEQ 时需要返回最大的修改序列号
 
 	var (
 		seqSet imap.SeqSet // 序列号集合
@@ -344,7 +757,7 @@ func (mbox *MailboxView) Search(numKind imapserver.NumKind, criteria *imap.Searc
 	for i, msg := range mbox.l { // 遍历邮箱中的所有邮件
 		seqNum := mbox.tracker.EncodeSeqNum(uint32(i) + 1) // 计算序列号
 
-		if !msg.search(seqNum, criteria) { // 如果邮件不符合搜索条件
+		if !msg.search(seqNum, mbox.recent.Contains(msg.uid), criteria) { // 如果邮件不符合搜索条件
 			continue // 跳过
 		}
 
@@ -369,6 +782,9 @@ func (mbox *MailboxView) Search(numKind imapserver.NumKind, criteria *imap.Searc
 			data.Max = num // 更新最大值
 		}
 		data.Count++ // 增加计数
+		if hasModSeq && msg.modSeq > data.ModSeq {
+			data.ModSeq = msg.modSeq // 更新最大的修改序列号
+		}
 	}
 
 	switch numKind {
@@ -385,6 +801,24 @@ func (mbox *MailboxView) Search(numKind imapserver.NumKind, criteria *imap.Searc
 	return &data, nil // 返回搜索数据
 }
 
+// searchCriteriaHasModSeq 判断搜索条件或其子条件是否包含 MODSEQ。
+func searchCriteriaHasModSeq(criteria *imap.SearchCriteria) bool {
+	if criteria.ModSeq != nil {
+		return true
+	}
+	for _, not := range criteria.Not {
+		if searchCriteriaHasModSeq(&not) {
+			return true
+		}
+	}
+	for _, or := range criteria.Or {
+		if searchCriteriaHasModSeq(&or[0]) || searchCriteriaHasModSeq(&or[1]) {
+			return true
+		}
+	}
+	return false
+}
+
 // staticSearchCriteria 处理静态搜索条件。
 // criteria: 搜索条件。
 func (mbox *MailboxView) staticSearchCriteria(criteria *imap.SearchCriteria) {
@@ -414,17 +848,101 @@ func (mbox *MailboxView) staticSearchCriteria(criteria *imap.SearchCriteria) {
 	}
 }
 
+// Expunge 删除已标记为删除的邮件。
+// 与 Mailbox.Expunge 不同，它会将 SEARCHRES 标记 "$" 替换为上次保存的搜索结果。
+// w: 用于写入的 ExpungeWriter，uids: 要删除的邮件的 UID 集。
+func (mbox *MailboxView) Expunge(w *imapserver.ExpungeWriter, uids *imap.UIDSet) error {
+	if uids != nil && imap.IsSearchRes(*uids) {
+		mbox.mutex.Lock()
+		searchRes := mbox.searchRes
+		mbox.mutex.Unlock()
+		uids = &searchRes
+	}
+	return mbox.Mailbox.Expunge(w, uids)
+}
+
 // Store 存储邮件的标志。
 // w: 用于写入的 FetchWriter，numSet: 要更新的邮件序列号集合，flags: 要更新的标志，options: 存储选项。
 func (mbox *MailboxView) Store(w *imapserver.FetchWriter, numSet imap.NumSet, flags *imap.StoreFlags, options *imap.StoreOptions) error {
-	mbox.forEach(numSet, func(seqNum uint32, msg *message) { // 遍历要更新的邮件
-		msg.store(flags)                                                                      // 存储标志
-		mbox.Mailbox.tracker.QueueMessageFlags(seqNum, msg.uid, msg.flagList(), mbox.tracker) // 更新到跟踪器
+	// storedMsg 记录被修改的邮件及修改前的状态，写入磁盘失败时用于撤销
+	type storedMsg struct {
+		seqNum uint32
+		msg    *message
+		flags  map[imap.Flag]struct{}
+		modSeq uint64
+	}
+
+	_, isUID := numSet.(imap.UIDSet)
+	var stored, modified []uint32 // 已更新和因 UNCHANGEDSINCE 被跳过的邮件编号
+	var changed []storedMsg
+
+	mbox.mutex.Lock()
+	prevHighestModSeq := mbox.highestModSeq
+	mbox.forEachLocked(numSet, func(seqNum uint32, msg *message) { // 遍历要更新的邮件
+		num := uint32(msg.uid)
+		if !isUID {
+			num = mbox.tracker.EncodeSeqNum(seqNum)
+		}
+		if options.UnchangedSince != 0 && msg.modSeq > options.UnchangedSince {
+			modified = append(modified, num) // 邮件自指定修改序列号以来已被修改
+			return
+		}
+		stored = append(stored, num)
+		prev := storedMsg{seqNum: seqNum, msg: msg, flags: make(map[imap.Flag]struct{}, len(msg.flags)), modSeq: msg.modSeq}
+		for flag := range msg.flags {
+			prev.flags[flag] = struct{}{}
+		}
+		if !msg.store(flags) {
+			return // 标志未改变，不更新修改序列号
+		}
+		msg.modSeq = mbox.nextModSeqLocked() // 更新修改序列号
+		changed = append(changed, prev)
 	})
-	if !flags.Silent { // 如果不是静默模式
-		return mbox.Fetch(w, numSet, &imap.FetchOptions{Flags: true}) // 获取更新后的邮件数据
+	if len(changed) > 0 {
+		// 先写入磁盘，成功后才通知其他会话：写入失败时撤销修改，其他会话不会看到新的标志
+		if err := mbox.saveLocked(); err != nil {
+			for _, prev := range changed {
+				prev.msg.flags, prev.msg.modSeq = prev.flags, prev.modSeq
+			}
+			mbox.highestModSeq = prevHighestModSeq
+			mbox.mutex.Unlock()
+			return err
+		}
+		for _, c := range changed {
+			mbox.Mailbox.tracker.QueueMessageFlags(c.seqNum, c.msg.uid, c.msg.flagList(), mbox.tracker) // 更新到跟踪器
+		}
+	}
+	mbox.mutex.Unlock()
+
+	if len(modified) > 0 {
+		w.WriteModified(numsToNumSet(modified, isUID))
+	}
+
+	// 使用 UNCHANGEDSINCE 时，即使是静默模式也需要返回 MODSEQ
+	fetchOptions := imap.FetchOptions{
+		Flags:  !flags.Silent,
+		ModSeq: options.UnchangedSince != 0,
+	}
+	if len(stored) == 0 || (!fetchOptions.Flags && !fetchOptions.ModSeq) {
+		return nil
+	}
+	return mbox.Fetch(w, numsToNumSet(stored, isUID), &fetchOptions) // 获取更新后的邮件数据
+}
+
+// numsToNumSet 将邮件编号列表转换为序列号集合或 UID 集合。
+func numsToNumSet(nums []uint32, isUID bool) imap.NumSet {
+	if isUID {
+		var uidSet imap.UIDSet
+		for _, num := range nums {
+			uidSet.AddNum(imap.UID(num))
+		}
+		return uidSet
+	}
+	var seqSet imap.SeqSet
+	for _, num := range nums {
+		seqSet.AddNum(num)
 	}
-	return nil // 返回 nil
+	return seqSet
 }
 
 // Poll 检查邮箱更新。
@@ -3,9 +3,13 @@ package imapmemserver
 import (
 	"bufio"
 	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"mime"
+	"mime/quotedprintable"
 	"strings"
 	"time"
 
@@ -14,6 +18,7 @@ import (
 	"github.com/emersion/go-message/textproto"
 	"github.com/luhaoyun888/go-imap-cn"
 	"github.com/luhaoyun888/go-imap-cn/imapserver"
+	"github.com/luhaoyun888/go-imap-cn/internal"
 )
 
 // message 表示一封邮件的结构体。
@@ -23,21 +28,59 @@ type message struct {
 	buf []byte    // 邮件内容的字节切片
 	t   time.Time // 邮件的时间戳
 
-	flags map[imap.Flag]struct{} // 邮件标志的集合
+	flags  map[imap.Flag]struct{} // 邮件标志的集合
+	modSeq uint64                 // 修改序列号
+	recent bool                   // 是否尚未被任何会话以读写方式选择（\Recent）
 }
 
 // fetch 方法用于提取邮件的相关信息。
 // 参数：
 //   - w: 用于写入提取结果的 FetchResponseWriter。
 //   - options: 选择要提取的信息的选项。
+//   - recent: 对当前会话而言邮件是否带有 \Recent 标志。
 //
 // 返回：
 //   - 返回错误信息（如果有）。
-func (msg *message) fetch(w *imapserver.FetchResponseWriter, options *imap.FetchOptions) error {
+func (msg *message) fetch(w *imapserver.FetchResponseWriter, options *imap.FetchOptions, recent bool) error {
+	// 在写入任何数据之前解码二进制部分，以便在出错时拒绝整个请求
+	binarySections := make([][]byte, len(options.BinarySection))
+	for i, bs := range options.BinarySection {
+		buf, err := msg.binarySection(bs.Part)
+		if err != nil {
+			w.WriteUID(msg.uid)
+			w.Close()
+			return err
+		}
+		binarySections[i] = extractPartial(buf, bs.Partial)
+	}
+	binarySectionSizes := make([]uint32, len(options.BinarySectionSize))
+	for i, bss := range options.BinarySectionSize {
+		buf, err := msg.binarySection(bss.Part)
+		if err != nil {
+			w.WriteUID(msg.uid)
+			w.Close()
+			return err
+		}
+		binarySectionSizes[i] = uint32(len(buf))
+	}
+
 	w.WriteUID(msg.uid) // 写入邮件的 UID
 
 	if options.Flags {
-		w.WriteFlags(msg.flagList()) // 写入邮件标志
+		flags := msg.flagList()
+		if recent {
+			flags = append(flags, internal.FlagRecent)
+		}
+		w.WriteFlags(flags) // 写入邮件标志
+	}
+	if options.ModSeq {
+		w.WriteModSeq(msg.modSeq) // 写入修改序列号
+	}
+	if options.EmailID {
+		w.WriteEmailID(msg.emailID()) // 写入邮件对象 ID
+	}
+	if options.ThreadID {
+		w.WriteThreadID("") // 不支持会话对象 ID
 	}
 	if options.InternalDate {
 		w.WriteInternalDate(msg.t) // 写入内部日期
@@ -54,7 +97,11 @@ func (msg *message) fetch(w *imapserver.FetchResponseWriter, options *imap.Fetch
 
 	// 写入邮件的各个部分
 	for _, bs := range options.BodySection {
-		buf := msg.bodySection(bs)                    // 获取邮件部分内容
+		buf, ok := msg.bodySection(bs) // 获取邮件部分内容
+		if !ok {
+			w.WriteBodySectionNIL(bs) // 请求的部分不存在
+			continue
+		}
 		wc := w.WriteBodySection(bs, int64(len(buf))) // 写入邮件部分
 		_, writeErr := wc.Write(buf)                  // 写入内容
 		closeErr := wc.Close()                        // 关闭写入器
@@ -66,7 +113,22 @@ func (msg *message) fetch(w *imapserver.FetchResponseWriter, options *imap.Fetch
 		}
 	}
 
-	// TODO: BinarySection, BinarySectionSize
+	// 写入邮件解码后的各个部分
+	for i, bs := range options.BinarySection {
+		buf := binarySections[i]
+		wc := w.WriteBinarySection(bs, int64(len(buf))) // 写入二进制部分
+		_, writeErr := wc.Write(buf)                    // 写入内容
+		closeErr := wc.Close()                          // 关闭写入器
+		if writeErr != nil {
+			return writeErr // 返回写入错误
+		}
+		if closeErr != nil {
+			return closeErr // 返回关闭错误
+		}
+	}
+	for i, bss := range options.BinarySectionSize {
+		w.WriteBinarySectionSize(bss, binarySectionSizes[i]) // 写入二进制部分大小
+	}
 
 	return w.Close() // 关闭响应写入器
 }
@@ -104,8 +166,8 @@ func (msg *message) bodyStructure(extended bool) imap.BodyStructure {
 // 返回：
 //   - 返回打开后的邮件头和读取器。
 func openMessagePart(header textproto.Header, body io.Reader, parentMediaType string) (textproto.Header, io.Reader) {
-	msgHeader := gomessage.Header{header}      // 创建 gomessage.Header
-	mediaType, _, _ := msgHeader.ContentType() // 获取内容类型
+	msgHeader := gomessage.Header{Header: header} // 创建 gomessage.Header
+	mediaType, _, _ := msgHeader.ContentType()    // 获取内容类型
 	if !msgHeader.Has("Content-Type") && parentMediaType == "multipart/digest" {
 		mediaType = "message/rfc822" // 如果没有内容类型并且是 multipart/digest，则设置为 message/rfc822
 	}
@@ -117,44 +179,38 @@ func openMessagePart(header textproto.Header, body io.Reader, parentMediaType st
 	return header, body // 返回头部和原始内容
 }
 
-// bodySection 方法用于提取邮件的特定部分内容。
+// openPart 方法用于按部分路径查找邮件的 MIME 部分。
 // 参数：
-//   - item: 提取项，包含部分信息。
+//   - part: 部分路径，为空时表示整封邮件。
 //
 // 返回：
-//   - 返回特定部分的字节切片（如果找到）或 nil。
-func (msg *message) bodySection(item *imap.FetchItemBodySection) []byte {
-	var (
-		header textproto.Header
-		body   io.Reader
-	)
-
+//   - 返回部分的头部、内容读取器和父级媒体类型；如果未找到，ok 为 false。
+func (msg *message) openPart(part []int) (header textproto.Header, body io.Reader, parentMediaType string, ok bool) {
 	br := bufio.NewReader(bytes.NewReader(msg.buf)) // 创建字节读取器
 	header, err := textproto.ReadHeader(br)         // 读取邮件头
 	if err != nil {
-		return nil // 返回 nil 表示失败
+		return header, nil, "", false
 	}
 	body = br // 设置邮件内容读取器
 
 	// 非 multipart 邮件的第一部分引用邮件本身
-	msgHeader := gomessage.Header{header}      // 创建 gomessage.Header
-	mediaType, _, _ := msgHeader.ContentType() // 获取内容类型
-	partPath := item.Part                      // 获取部分路径
+	msgHeader := gomessage.Header{Header: header} // 创建 gomessage.Header
+	mediaType, _, _ := msgHeader.ContentType()    // 获取内容类型
+	partPath := part                              // 获取部分路径
 	if !strings.HasPrefix(mediaType, "multipart/") && len(partPath) > 0 && partPath[0] == 1 {
 		partPath = partPath[1:] // 去掉前缀
 	}
 
 	// 使用提供的路径查找请求的部分
-	var parentMediaType string
 	for i := 0; i < len(partPath); i++ {
 		partNum := partPath[i] // 当前部分编号
 
 		header, body = openMessagePart(header, body, parentMediaType) // 打开当前部分
-		msgHeader := gomessage.Header{header}                         // 创建 gomessage.Header
+		msgHeader := gomessage.Header{Header: header}                 // 创建 gomessage.Header
 		mediaType, typeParams, _ := msgHeader.ContentType()           // 获取内容类型和参数
 		if !strings.HasPrefix(mediaType, "multipart/") {
 			if partNum != 1 {
-				return nil // 如果不是第一部分，返回 nil
+				return header, nil, "", false // 如果不是第一部分，则未找到
 			}
 			continue // 如果是第一部分，继续
 		}
@@ -164,7 +220,7 @@ func (msg *message) bodySection(item *imap.FetchItemBodySection) []byte {
 		for j := 1; j <= partNum; j++ {
 			p, err := mr.NextPart() // 获取下一个部分
 			if err != nil {
-				return nil // 返回 nil 表示失败
+				return header, nil, "", false
 			}
 
 			if j == partNum { // 如果当前是目标部分
@@ -176,10 +232,25 @@ func (msg *message) bodySection(item *imap.FetchItemBodySection) []byte {
 			}
 		}
 		if !found {
-			return nil // 如果未找到，返回 nil
+			return header, nil, "", false // 如果未找到，返回
 		}
 	}
 
+	return header, body, parentMediaType, true
+}
+
+// bodySection 方法用于提取邮件的特定部分内容。
+// 参数：
+//   - item: 提取项，包含部分信息。
+//
+// 返回：
+//   - 返回特定部分的字节切片；如果部分不存在，ok 为 false。
+func (msg *message) bodySection(item *imap.FetchItemBodySection) (b []byte, ok bool) {
+	header, body, parentMediaType, ok := msg.openPart(item.Part) // 查找请求的部分
+	if !ok {
+		return nil, false
+	}
+
 	if len(item.Part) > 0 {
 		switch item.Specifier {
 		case imap.PartSpecifierHeader, imap.PartSpecifierText:
@@ -214,30 +285,104 @@ func (msg *message) bodySection(item *imap.FetchItemBodySection) []byte {
 	}
 	if writeHeader {
 		if err := textproto.WriteHeader(&buf, header); err != nil {
-			return nil // 返回 nil 表示失败
+			return nil, false
 		}
 	}
 
 	switch item.Specifier {
 	case imap.PartSpecifierNone, imap.PartSpecifierText:
 		if _, err := io.Copy(&buf, body); err != nil {
-			return nil // 返回 nil 表示失败
+			return nil, false
 		}
 	}
 
-	// 提取部分内容（如果有）
-	b := buf.Bytes()
-	if partial := item.Partial; partial != nil {
-		end := partial.Offset + partial.Size // 计算结束位置
-		if partial.Offset > int64(len(b)) {
-			return nil // 如果偏移量超出范围，返回 nil
+	return extractPartial(buf.Bytes(), item.Partial), true // 提取部分内容（如果有）
+}
+
+// extractPartial 方法用于截取 <offset.size> 指定的部分内容。
+// 参数：
+//   - b: 完整内容。
+//   - partial: 部分内容的偏移和大小，为 nil 时返回完整内容。
+//
+// 返回：
+//   - 返回截取后的内容，如果偏移量超出范围则返回 nil。
+func extractPartial(b []byte, partial *imap.SectionPartial) []byte {
+	if partial == nil {
+		return b
+	}
+	end := partial.Offset + partial.Size // 计算结束位置
+	if partial.Offset > int64(len(b)) {
+		return nil // 如果偏移量超出范围，返回 nil
+	}
+	if end > int64(len(b)) {
+		end = int64(len(b)) // 调整结束位置
+	}
+	return b[partial.Offset:end] // 截取部分内容
+}
+
+// binarySection 方法用于提取邮件特定部分解码后的内容。
+// 参数：
+//   - part: 部分路径，为空时表示整封邮件。
+//
+// 返回：
+//   - 返回解码后的内容（如果未找到部分则为 nil），以及解码失败时的错误。
+func (msg *message) binarySection(part []int) ([]byte, error) {
+	if len(part) == 0 {
+		return msg.buf, nil // 整封邮件不做解码
+	}
+
+	header, body, _, ok := msg.openPart(part) // 查找请求的部分
+	if !ok {
+		return nil, nil
+	}
+
+	msgHeader := gomessage.Header{Header: header}
+	if mediaType, _, _ := msgHeader.ContentType(); strings.HasPrefix(mediaType, "multipart/") {
+		return io.ReadAll(body) // multipart 部分不能使用 base64 或 quoted-printable 编码
+	}
+
+	var r io.Reader
+	switch enc := strings.ToLower(strings.TrimSpace(header.Get("Content-Transfer-Encoding"))); enc {
+	case "", "7bit", "8bit", "binary":
+		r = body
+	case "base64":
+		r = base64.NewDecoder(base64.StdEncoding, body) // 解码器会忽略换行符
+	case "quoted-printable":
+		r = quotedprintable.NewReader(body)
+	default:
+		return nil, &imap.Error{
+			Type: imap.StatusResponseTypeNo,
+			Code: imap.ResponseCodeUnknownCTE,
+			Text: fmt.Sprintf("未知的内容传输编码: %v", enc),
 		}
-		if end > int64(len(b)) {
-			end = int64(len(b)) // 调整结束位置
+	}
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, &imap.Error{
+			Type: imap.StatusResponseTypeNo,
+			Code: imap.ResponseCodeUnknownCTE,
+			Text: fmt.Sprintf("无法解码内容: %v", err),
 		}
-		b = b[partial.Offset:end] // 截取部分内容
 	}
-	return b // 返回提取的部分
+	return b, nil
+}
+
+// emailID 返回邮件的对象 ID（RFC 8474）。
+//
+// ID 由邮件内容的摘要生成，因此复制或移动到其他邮箱的邮件保持相同的 ID，
+// 服务器重启后也不会变化。内容相同的邮件共享同一个 ID，这是 RFC 8474 允许的。
+func (msg *message) emailID() string {
+	sum := sha256.Sum256(msg.buf)
+	return "E" + hex.EncodeToString(sum[:12])
+}
+
+// pendingCopy 返回将邮件 msg 复制到其他邮箱时需要追加的内容。
+func (msg *message) pendingCopy() pendingMessage {
+	return pendingMessage{buf: msg.buf, options: &imap.AppendOptions{
+		Time:  msg.t,          // 邮件时间
+		Flags: msg.flagList(), // 邮件标志
+	}}
 }
 
 // flagList 方法用于获取邮件标志的列表。
@@ -251,19 +396,37 @@ func (msg *message) flagList() []imap.Flag {
 	return flags // 返回标志切片
 }
 
+// hasFlag 判断邮件是否带有某个标志，recent 表示对当前会话而言邮件是否带有 \Recent 标志。
+func (msg *message) hasFlag(flag imap.Flag, recent bool) bool {
+	flag = canonicalFlag(flag)
+	if flag == canonicalFlag(internal.FlagRecent) {
+		return recent
+	}
+	_, ok := msg.flags[flag]
+	return ok
+}
+
 // store 方法用于存储邮件标志。
 // 参数：
 //   - store: 存储标志的操作结构体。
 //
 // 返回：
-//   - 无
-func (msg *message) store(store *imap.StoreFlags) {
+//   - 返回 true 表示标志发生了改变。
+func (msg *message) store(store *imap.StoreFlags) bool {
+	prev := make(map[imap.Flag]struct{}, len(msg.flags))
+	for flag := range msg.flags {
+		prev[flag] = struct{}{}
+	}
+
 	switch store.Op {
 	case imap.StoreFlagsSet:
 		msg.flags = make(map[imap.Flag]struct{}) // 设置新的标志集合
 		fallthrough
 	case imap.StoreFlagsAdd:
 		for _, flag := range store.Flags {
+			if canonicalFlag(flag) == canonicalFlag(internal.FlagRecent) {
+				continue // 客户端不能修改 \Recent 标志
+			}
 			msg.flags[canonicalFlag(flag)] = struct{}{} // 添加标志
 		}
 	case imap.StoreFlagsDel:
@@ -273,6 +436,16 @@ func (msg *message) store(store *imap.StoreFlags) {
 	default:
 		panic(fmt.Errorf("未知的 STORE 标志操作: %v", store.Op)) // 抛出未知操作的错误
 	}
+
+	if len(prev) != len(msg.flags) {
+		return true
+	}
+	for flag := range msg.flags {
+		if _, ok := prev[flag]; !ok {
+			return true
+		}
+	}
+	return false
 }
 
 // search 方法用于根据给定的搜索标准检查邮件。
@@ -282,7 +455,7 @@ func (msg *message) store(store *imap.StoreFlags) {
 //
 // 返回：
 //   - 返回 true 表示邮件匹配搜索条件，false 表示不匹配。
-func (msg *message) search(seqNum uint32, criteria *imap.SearchCriteria) bool {
+func (msg *message) search(seqNum uint32, recent bool, criteria *imap.SearchCriteria) bool {
 	for _, seqSet := range criteria.SeqNum {
 		if seqNum == 0 || !seqSet.Contains(seqNum) {
 			return false // 如果序列号不匹配，返回 false
@@ -293,35 +466,59 @@ func (msg *message) search(seqNum uint32, criteria *imap.SearchCriteria) bool {
 			return false // 如果 UID 不匹配，返回 false
 		}
 	}
+	// SINCE/BEFORE/ON 基于内部日期，SENTSINCE/SENTBEFORE/SENTON 基于 Date 头，
+	// 参见 RFC 9051 第 6.4.4 节
 	if !matchDate(msg.t, criteria.Since, criteria.Before) {
 		return false // 如果日期不匹配，返回 false
 	}
+	// YOUNGER/OLDER 以当前时间为基准比较内部日期，参见 RFC 5032
+	age := time.Since(msg.t)
+	if criteria.Younger > 0 && age > time.Duration(criteria.Younger)*time.Second {
+		return false
+	}
+	if criteria.Older > 0 && age <= time.Duration(criteria.Older)*time.Second {
+		return false
+	}
+	// MODSEQ 只匹配修改序列号大于或等于 n 的邮件，参见 RFC 7162 第 3.1.5 节
+	if criteria.ModSeq != nil && msg.modSeq < criteria.ModSeq.ModSeq {
+		return false
+	}
+	// EMAILID 比较邮件对象 ID；不支持会话对象 ID，因此 THREADID 不匹配任何邮件，参见 RFC 8474
+	for _, id := range criteria.EmailID {
+		if id != msg.emailID() {
+			return false
+		}
+	}
+	if len(criteria.ThreadID) > 0 {
+		return false
+	}
 
 	for _, flag := range criteria.Flag {
-		if _, ok := msg.flags[canonicalFlag(flag)]; !ok {
+		if !msg.hasFlag(flag, recent) {
 			return false // 如果标志不匹配，返回 false
 		}
 	}
 	for _, flag := range criteria.NotFlag {
-		if _, ok := msg.flags[canonicalFlag(flag)]; ok {
+		if msg.hasFlag(flag, recent) {
 			return false // 如果不应有的标志存在，返回 false
 		}
 	}
 
+	// LARGER 和 SMALLER 都是严格比较：大小恰好等于 n 时不匹配
 	if criteria.Larger != 0 && int64(len(msg.buf)) <= criteria.Larger {
-		return false // 如果邮件大小不符合要求，返回 false
+		return false // 邮件大小不大于 n，返回 false
 	}
 	if criteria.Smaller != 0 && int64(len(msg.buf)) >= criteria.Smaller {
-		return false // 如果邮件大小不符合要求，返回 false
+		return false // 邮件大小不小于 n，返回 false
 	}
 
 	if !matchBytes(msg.buf, criteria.Text) {
 		return false // 如果内容不匹配，返回 false
 	}
 
-	br := bufio.NewReader(bytes.NewReader(msg.buf))    // 创建字节读取器
-	rawHeader, _ := textproto.ReadHeader(br)           // 读取邮件头
-	header := mail.Header{gomessage.Header{rawHeader}} // 创建邮件头
+	br := bufio.NewReader(bytes.NewReader(msg.buf))                    // 创建字节读取器
+	rawHeader, _ := textproto.ReadHeader(br)                           // 读取邮件头
+	header := mail.Header{Header: gomessage.Header{Header: rawHeader}} // 创建邮件头
 
 	for _, fieldCriteria := range criteria.Header {
 		if !header.Has(fieldCriteria.Key) {
@@ -359,12 +556,12 @@ func (msg *message) search(seqNum uint32, criteria *imap.SearchCriteria) bool {
 	}
 
 	for _, not := range criteria.Not {
-		if msg.search(seqNum, &not) {
+		if msg.search(seqNum, recent, &not) {
 			return false // 如果不应存在的条件匹配，返回 false
 		}
 	}
 	for _, or := range criteria.Or {
-		if !msg.search(seqNum, &or[0]) && !msg.search(seqNum, &or[1]) {
+		if !msg.search(seqNum, recent, &or[0]) && !msg.search(seqNum, recent, &or[1]) {
 			return false // 如果或条件都不匹配，返回 false
 		}
 	}
@@ -421,11 +618,11 @@ func matchBytes(buf []byte, patterns []string) bool {
 // 返回：
 //   - 返回 IMAP Envelope 结构体指针。
 func getEnvelope(h textproto.Header) *imap.Envelope {
-	mh := mail.Header{gomessage.Header{h}}      // 创建邮件头
-	date, _ := mh.Date()                        // 获取日期
-	inReplyTo, _ := mh.MsgIDList("In-Reply-To") // 获取回复消息 ID
-	messageID, _ := mh.MessageID()              // 获取消息 ID
-	return &imap.Envelope{                      // 返回信封信息
+	mh := mail.Header{Header: gomessage.Header{Header: h}} // 创建邮件头
+	date, _ := mh.Date()                                   // 获取日期
+	inReplyTo, _ := mh.MsgIDList("In-Reply-To")            // 获取回复消息 ID
+	messageID, _ := mh.MessageID()                         // 获取消息 ID
+	return &imap.Envelope{                                 // 返回信封信息
 		Date:      date,
 		Subject:   h.Get("Subject"),
 		From:      parseAddressList(mh, "From"),
@@ -440,6 +637,7 @@ func getEnvelope(h textproto.Header) *imap.Envelope {
 }
 
 // parseAddressList 方法用于解析邮件地址列表。
+// 组地址被表示为组开始标记（组名作为 mailbox）、组成员和组结束标记（全 NIL 地址）。
 // 参数：
 //   - mh: 邮件头。
 //   - k: 要解析的字段名。
@@ -448,8 +646,28 @@ func getEnvelope(h textproto.Header) *imap.Envelope {
 //   - 返回解析后的 IMAP Address 列表。
 func parseAddressList(mh mail.Header, k string) []imap.Address {
 	// TODO: 保持引号词不变
-	// TODO: 处理组地址
-	addrs, _ := mh.AddressList(k) // 获取地址列表
+	var l []imap.Address
+	for _, seg := range splitAddressGroups(mh.Get(k)) {
+		if seg.group {
+			// 组开始：组名作为 mailbox，host 为 NIL
+			l = append(l, imap.Address{Mailbox: seg.name})
+		}
+		l = append(l, parseMailboxList(seg.addrs)...)
+		if seg.group {
+			// 组结束：所有字段均为 NIL
+			l = append(l, imap.Address{})
+		}
+	}
+	return l // 返回地址列表
+}
+
+// parseMailboxList 方法用于解析不含组的地址列表。
+func parseMailboxList(s string) []imap.Address {
+	s = strings.Trim(s, " \t\r\n,") // 去除与相邻组之间的分隔符
+	if s == "" {
+		return nil
+	}
+	addrs, _ := mail.ParseAddressList(s) // 获取地址列表
 	var l []imap.Address
 	for _, addr := range addrs {
 		mailbox, host, ok := strings.Cut(addr.Address, "@") // 分割地址
@@ -462,7 +680,111 @@ func parseAddressList(mh mail.Header, k string) []imap.Address {
 			Host:    host,
 		})
 	}
-	return l // 返回地址列表
+	return l
+}
+
+// addressSegment 是地址头部字段中的一段：一个组，或组之外的若干地址。
+type addressSegment struct {
+	group bool
+	name  string // 组名
+	addrs string // 未解析的地址列表
+}
+
+// splitAddressGroups 方法用于按组（"组名: 地址列表;"）拆分地址头部字段。
+// 引号字符串、注释和尖括号中的 ':'、';' 和 ',' 不作为分隔符。
+func splitAddressGroups(s string) []addressSegment {
+	var (
+		segs      []addressSegment
+		start     int  // 当前段的起始位置
+		lastComma = -1 // 当前段中最后一个顶层 ',' 的位置
+		inGroup   bool
+		groupName string
+		quoted    bool
+		comment   int
+		angle     bool
+	)
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case quoted:
+			if ch == '\\' {
+				i++
+			} else if ch == '"' {
+				quoted = false
+			}
+			continue
+		case comment > 0:
+			if ch == '\\' {
+				i++
+			} else if ch == '(' {
+				comment++
+			} else if ch == ')' {
+				comment--
+			}
+			continue
+		case angle:
+			if ch == '>' {
+				angle = false
+			}
+			continue
+		}
+
+		switch ch {
+		case '"':
+			quoted = true
+		case '(':
+			comment++
+		case '<':
+			angle = true
+		case ',':
+			if !inGroup {
+				lastComma = i
+			}
+		case ':':
+			if inGroup {
+				break
+			}
+			nameStart := start
+			if lastComma >= 0 {
+				segs = append(segs, addressSegment{addrs: s[start:lastComma]})
+				nameStart = lastComma + 1
+			}
+			groupName = unquotePhrase(s[nameStart:i])
+			inGroup = true
+			start = i + 1
+		case ';':
+			if !inGroup {
+				break
+			}
+			segs = append(segs, addressSegment{group: true, name: groupName, addrs: s[start:i]})
+			inGroup = false
+			start = i + 1
+			lastComma = -1
+		}
+	}
+	if inGroup {
+		// 组没有以 ';' 结束，仍将其视为组
+		segs = append(segs, addressSegment{group: true, name: groupName, addrs: s[start:]})
+	} else {
+		segs = append(segs, addressSegment{addrs: s[start:]})
+	}
+	return segs
+}
+
+// unquotePhrase 方法用于去除组名短语两端的空白和引号。
+func unquotePhrase(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return s
+	}
+	var sb strings.Builder
+	for i := 1; i < len(s)-1; i++ {
+		if s[i] == '\\' && i+1 < len(s)-1 {
+			i++
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
 }
 
 // canonicalFlag 方法用于返回规范化的邮件标志。
@@ -484,7 +806,7 @@ func canonicalFlag(flag imap.Flag) imap.Flag {
 // 返回：
 //   - 返回 IMAP BodyStructure 结构体。
 func getBodyStructure(rawHeader textproto.Header, r io.Reader, extended bool) imap.BodyStructure {
-	header := gomessage.Header{rawHeader} // 创建邮件头
+	header := gomessage.Header{Header: rawHeader} // 创建邮件头
 
 	mediaType, typeParams, _ := header.ContentType()       // 获取媒体类型和参数
 	primaryType, subType, _ := strings.Cut(mediaType, "/") // 分割媒体类型
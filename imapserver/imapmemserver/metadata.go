@@ -0,0 +1,54 @@
+package imapmemserver
+
+import (
+	"strings"
+
+	"github.com/luhaoyun888/go-imap-cn"
+)
+
+// metadataStore 保存元数据条目（RFC 5464），键为小写的条目名称。
+type metadataStore map[string][]byte
+
+// get 返回 entries 中的条目及 depth 指定深度内的子条目，不存在的条目的值为 nil。
+func (store metadataStore) get(entries []string, depth imap.GetMetadataDepth) map[string]*[]byte {
+	values := make(map[string]*[]byte)
+	for _, entry := range entries {
+		if value, ok := store[entry]; ok {
+			values[entry] = cloneMetadataValue(value)
+		} else {
+			values[entry] = nil
+		}
+		if depth == imap.GetMetadataDepthZero {
+			continue
+		}
+
+		prefix := entry + "/"
+		for name, value := range store {
+			if !strings.HasPrefix(name, prefix) {
+				continue
+			}
+			if depth == imap.GetMetadataDepthOne && strings.Contains(name[len(prefix):], "/") {
+				continue // 只返回直接子条目
+			}
+			values[name] = cloneMetadataValue(value)
+		}
+	}
+
+	return values
+}
+
+// set 设置条目的值，值为 nil 时删除该条目。
+func (store metadataStore) set(entries map[string]*[]byte) {
+	for name, value := range entries {
+		if value == nil {
+			delete(store, name)
+		} else {
+			store[name] = append([]byte(nil), *value...)
+		}
+	}
+}
+
+func cloneMetadataValue(value []byte) *[]byte {
+	b := append([]byte(nil), value...)
+	return &b
+}
@@ -0,0 +1,129 @@
+package imapmemserver
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/luhaoyun888/go-imap-cn"
+	"github.com/luhaoyun888/go-imap-cn/imapserver"
+)
+
+// MultiSearch 实现了 imapserver.SessionMultiSearch 接口。
+// 结果按邮箱名称排列，不存在的邮箱被跳过。
+func (sess *UserSession) MultiSearch(sources []imap.MultiSearchSource, criteria *imap.SearchCriteria, options *imap.SearchOptions) ([]imap.MultiSearchData, error) {
+	var selected *Mailbox
+	if sess.mailbox != nil {
+		selected = sess.mailbox.Mailbox
+	}
+	names, mailboxes := sess.user.multiSearchMailboxes(sources, selected)
+
+	results := make([]imap.MultiSearchData, 0, len(names))
+	for _, name := range names {
+		mbox := mailboxes[name]
+
+		view := sess.mailbox
+		if mbox != selected {
+			mbox.mutex.Lock()
+			view = mbox.NewView()
+			mbox.mutex.Unlock()
+		}
+		// Search 会将搜索条件中的编号集转换为静态集合，因此每个邮箱使用一份副本
+		data, err := view.Search(imapserver.NumKindUID, cloneSearchCriteria(criteria), options)
+		if view != sess.mailbox {
+			view.Close()
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, imap.MultiSearchData{
+			Mailbox:     name,
+			UIDValidity: mbox.uidValidity,
+			SearchData:  *data,
+		})
+	}
+	return results, nil
+}
+
+// multiSearchMailboxes 返回 sources 匹配的邮箱名称（已排序）及对应的邮箱。
+// selected 为当前选择的邮箱，可以为 nil。
+func (u *User) multiSearchMailboxes(sources []imap.MultiSearchSource, selected *Mailbox) ([]string, map[string]*Mailbox) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	matched := make(map[string]*Mailbox)
+	add := func(name string) {
+		if mbox := u.mailboxes[name]; mbox != nil {
+			matched[name] = mbox
+		}
+	}
+	for _, source := range sources {
+		switch source.Filter {
+		case imap.MultiSearchFilterSelected:
+			for name, mbox := range u.mailboxes {
+				if mbox == selected {
+					matched[name] = mbox
+				}
+			}
+		case imap.MultiSearchFilterInboxes:
+			add("INBOX")
+		case imap.MultiSearchFilterPersonal:
+			for name, mbox := range u.mailboxes {
+				matched[name] = mbox
+			}
+		case imap.MultiSearchFilterSubscribed:
+			for name, mbox := range u.mailboxes {
+				mbox.mutex.Lock()
+				subscribed := mbox.subscribed
+				mbox.mutex.Unlock()
+				if subscribed {
+					matched[name] = mbox
+				}
+			}
+		case imap.MultiSearchFilterSubtree, imap.MultiSearchFilterSubtreeOne:
+			for _, root := range source.Mailboxes {
+				add(root)
+				prefix := root + string(mailboxDelim)
+				for name, mbox := range u.mailboxes {
+					child := strings.TrimPrefix(name, prefix)
+					if child == name {
+						continue
+					}
+					if source.Filter == imap.MultiSearchFilterSubtreeOne && strings.ContainsRune(child, mailboxDelim) {
+						continue // 只包含直接子邮箱
+					}
+					matched[name] = mbox
+				}
+			}
+		case imap.MultiSearchFilterMailboxes:
+			for _, name := range source.Mailboxes {
+				add(name)
+			}
+		}
+	}
+
+	names := make([]string, 0, len(matched))
+	for name := range matched {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, matched
+}
+
+// cloneSearchCriteria 复制搜索条件中会被 staticSearchCriteria 修改的部分。
+func cloneSearchCriteria(criteria *imap.SearchCriteria) *imap.SearchCriteria {
+	clone := *criteria
+	clone.SeqNum = append([]imap.SeqSet(nil), criteria.SeqNum...)
+	clone.UID = append([]imap.UIDSet(nil), criteria.UID...)
+	clone.Not = make([]imap.SearchCriteria, len(criteria.Not))
+	for i := range criteria.Not {
+		clone.Not[i] = *cloneSearchCriteria(&criteria.Not[i])
+	}
+	clone.Or = make([][2]imap.SearchCriteria, len(criteria.Or))
+	for i := range criteria.Or {
+		for j := range criteria.Or[i] {
+			clone.Or[i][j] = *cloneSearchCriteria(&criteria.Or[i][j])
+		}
+	}
+	return &clone
+}
@@ -2,6 +2,11 @@
 package imapmemserver
 
 import (
+	"crypto/x509"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
 	"sync"
 
 	"github.com/luhaoyun888/go-imap-cn/imapserver"
@@ -13,6 +18,7 @@ import (
 type Server struct {
 	mutex sync.Mutex       // 互斥锁，用于保护用户列表的并发访问
 	users map[string]*User // 用户列表，以用户名为键，User 结构体指针为值
+	dir   string           // 保存邮箱的目录，为空时只保存在内存中
 }
 
 // New 创建一个新的服务器实例。
@@ -23,6 +29,24 @@ func New() *Server {
 	}
 }
 
+// NewWithStore 创建一个将邮箱保存在磁盘目录 dir 中的服务器实例。
+//
+// 每个用户的邮箱保存在 dir 下以用户名命名的子目录中，邮件以类似 maildir 的
+// 方式每封一个文件，UID、标志和修改序列号保存在每个邮箱的索引文件中。
+// APPEND、EXPUNGE、STORE 等修改会在返回前同步写入磁盘。服务器重启后，
+// AddUser 会加载该用户的邮箱，并恢复 UIDVALIDITY 和 UIDNEXT。
+//
+// APPENDLIMIT、配额、元数据（METADATA）、访问控制列表和 \Recent 标志只保存在内存中，
+// 服务器重启后需要重新设置。
+func NewWithStore(dir string) (*Server, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	s := New()
+	s.dir = dir
+	return s, nil
+}
+
 // NewSession 创建一个新的 IMAP 会话。
 // 返回一个实现了 imapserver.Session 接口的 serverSession 结构体指针。
 func (s *Server) NewSession() imapserver.Session {
@@ -44,10 +68,23 @@ func (s *Server) user(username string) *User {
 // AddUser 将用户添加到服务器。
 // 参数：
 //   - user: 要添加的 User 结构体指针。
-func (s *Server) AddUser(user *User) {
+//
+// 对于 NewWithStore 创建的服务器，AddUser 会加载磁盘上该用户已有的邮箱，
+// 并将用户在内存中的其他邮箱保存到磁盘。同名的邮箱以磁盘上的为准。
+func (s *Server) AddUser(user *User) error {
+	if s.dir != "" {
+		if user.username == "" || user.username == "." || user.username == ".." {
+			return fmt.Errorf("imapmemserver: 无效的用户名 %q", user.username)
+		}
+		if err := user.openStore(filepath.Join(s.dir, url.PathEscape(user.username))); err != nil {
+			return err
+		}
+	}
+
 	s.mutex.Lock()                // 锁定
 	s.users[user.username] = user // 添加用户
 	s.mutex.Unlock()              // 解锁
+	return nil
 }
 
 // serverSession 是与特定服务器关联的会话。
@@ -57,7 +94,10 @@ type serverSession struct {
 	server       *Server // 不可变的服务器指针
 }
 
-var _ imapserver.Session = (*serverSession)(nil) // 确保 serverSession 实现了 Session 接口
+var (
+	_ imapserver.Session         = (*serverSession)(nil) // 确保 serverSession 实现了 Session 接口
+	_ imapserver.SessionExternal = (*serverSession)(nil)
+)
 
 // Login 方法用于用户登录。
 // 参数：
@@ -77,3 +117,18 @@ func (sess *serverSession) Login(username, password string) error {
 	sess.UserSession = NewUserSession(u) // 创建用户会话
 	return nil                           // 返回 nil 表示成功
 }
+
+// AuthenticateExternal 方法使用 TLS 客户端证书登录，证书的通用名称（CN）即用户名。
+// 参数：
+//   - cert: 已验证的客户端证书。
+//
+// 返回：
+//   - 返回错误信息（如果有）。
+func (sess *serverSession) AuthenticateExternal(cert *x509.Certificate) error {
+	u := sess.server.user(cert.Subject.CommonName) // 获取用户
+	if u == nil {
+		return imapserver.ErrAuthFailed // 如果用户不存在，返回认证失败错误
+	}
+	sess.UserSession = NewUserSession(u) // 创建用户会话
+	return nil                           // 返回 nil 表示成功
+}
@@ -19,7 +19,17 @@ type UserSession struct {
 	*mailbox // 可为空的邮箱指针
 }
 
-var _ imapserver.SessionIMAP4rev2 = (*UserSession)(nil) // 确保 UserSession 实现了 SessionIMAP4rev2 接口
+var _ imapserver.SessionIMAP4rev2 = (*UserSession)(nil)   // 确保 UserSession 实现了 SessionIMAP4rev2 接口
+var _ imapserver.SessionMultiAppend = (*UserSession)(nil) // 确保 UserSession 实现了 SessionMultiAppend 接口
+var _ imapserver.SessionCatenate = (*UserSession)(nil)    // 确保 UserSession 实现了 SessionCatenate 接口
+var _ imapserver.SessionQResync = (*UserSession)(nil)     // 确保 UserSession 实现了 SessionQResync 接口
+var _ imapserver.SessionObjectID = (*UserSession)(nil)    // 确保 UserSession 实现了 SessionObjectID 接口
+var _ imapserver.SessionACL = (*UserSession)(nil)         // 确保 UserSession 实现了 SessionACL 接口
+var _ imapserver.SessionQuota = (*UserSession)(nil)       // 确保 UserSession 实现了 SessionQuota 接口
+var _ imapserver.SessionMetadata = (*UserSession)(nil)    // 确保 UserSession 实现了 SessionMetadata 接口
+var _ imapserver.SessionSort = (*UserSession)(nil)        // 确保 UserSession 实现了 SessionSort 接口
+var _ imapserver.SessionThread = (*UserSession)(nil)      // 确保 UserSession 实现了 SessionThread 接口
+var _ imapserver.SessionMultiSearch = (*UserSession)(nil) // 确保 UserSession 实现了 SessionMultiSearch 接口
 
 // NewUserSession 创建一个新的用户会话。
 // 参数：
@@ -52,10 +62,12 @@ func (sess *UserSession) Select(name string, options *imap.SelectOptions) (*imap
 	if err != nil {
 		return nil, err // 返回错误
 	}
-	mbox.mutex.Lock()                   // 锁定邮箱
-	defer mbox.mutex.Unlock()           // 解锁
-	sess.mailbox = mbox.NewView()       // 创建邮箱视图
-	return mbox.selectDataLocked(), nil // 返回选择数据
+	mbox.mutex.Lock()             // 锁定邮箱
+	defer mbox.mutex.Unlock()     // 解锁
+	sess.mailbox = mbox.NewView() // 创建邮箱视图
+	data := mbox.selectDataLocked()
+	data.NumRecent = sess.mailbox.claimRecentLocked(data.ReadOnly || (options != nil && options.ReadOnly))
+	return data, nil // 返回选择数据
 }
 
 // Unselect 方法取消当前选择的邮箱。
@@ -89,17 +101,25 @@ func (sess *UserSession) Copy(numSet imap.NumSet, destName string) (*imap.CopyDa
 		}
 	}
 
-	var sourceUIDs, destUIDs imap.UIDSet // 源和目标邮箱的 UID 集合
+	// 所有邮件通过一次 appendBatch 追加：配额检查针对整个集合，失败时目标邮箱不会留下部分邮件
+	var sourceUIDs imap.UIDSet // 源邮箱的 UID 集合
+	var pending []pendingMessage
 	sess.mailbox.forEach(numSet, func(seqNum uint32, msg *message) {
-		appendData := dest.copyMsg(msg) // 复制邮件
-		sourceUIDs.AddNum(msg.uid)      // 添加源 UID
-		destUIDs.AddNum(appendData.UID) // 添加目标 UID
+		sourceUIDs.AddNum(msg.uid) // 添加源 UID
+		pending = append(pending, msg.pendingCopy())
 	})
+	if len(pending) == 0 {
+		return &imap.CopyData{UIDValidity: dest.uidValidity}, nil
+	}
+	appendData, err := dest.appendBatch(pending)
+	if err != nil {
+		return nil, err
+	}
 
 	return &imap.CopyData{
 		UIDValidity: dest.uidValidity, // 返回目标邮箱的 UID 有效性
 		SourceUIDs:  sourceUIDs,       // 返回源 UID 集合
-		DestUIDs:    destUIDs,         // 返回目标 UID 集合
+		DestUIDs:    appendData.UIDs,  // 返回目标 UID 集合
 	}, nil
 }
 
@@ -129,32 +149,74 @@ func (sess *UserSession) Move(w *imapserver.MoveWriter, numSet imap.NumSet, dest
 	sess.mailbox.mutex.Lock()         // 锁定源邮箱
 	defer sess.mailbox.mutex.Unlock() // 解锁
 
-	var sourceUIDs, destUIDs imap.UIDSet    // 源和目标邮箱的 UID 集合
+	// 与 Copy 相同，所有邮件通过一次 appendBatch 追加，失败时两个邮箱都不会改变
+	var sourceUIDs imap.UIDSet              // 源邮箱的 UID 集合
 	expunged := make(map[*message]struct{}) // 存储被删除的邮件
+	var pending []pendingMessage
 	sess.mailbox.forEachLocked(numSet, func(seqNum uint32, msg *message) {
-		appendData := dest.copyMsg(msg) // 复制邮件
-		sourceUIDs.AddNum(msg.uid)      // 添加源 UID
-		destUIDs.AddNum(appendData.UID) // 添加目标 UID
-		expunged[msg] = struct{}{}      // 标记为被删除
+		sourceUIDs.AddNum(msg.uid) // 添加源 UID
+		pending = append(pending, msg.pendingCopy())
+		expunged[msg] = struct{}{} // 标记为被删除
 	})
-	seqNums := sess.mailbox.expungeLocked(expunged) // 清理已删除邮件
+	if len(pending) == 0 {
+		return w.WriteCopyData(&imap.CopyData{UIDValidity: dest.uidValidity})
+	}
+	appendData, err := dest.appendBatch(pending)
+	if err != nil {
+		return err
+	}
+	// 删除通过跟踪器排队，命令完成后由 Poll 发送 EXPUNGE 响应（在 COPYUID 之后）。
+	// 这里不能再直接写出 EXPUNGE：排队后 EncodeSeqNum 会把这些序号编码为 0
+	if _, err := sess.mailbox.expungeLocked(expunged); err != nil {
+		// 从源邮箱删除失败：撤销复制，避免邮件同时出现在两个邮箱中
+		dest.removeAppended(appendData.UIDs)
+		return err
+	}
 
-	err = w.WriteCopyData(&imap.CopyData{
+	return w.WriteCopyData(&imap.CopyData{
 		UIDValidity: dest.uidValidity, // 返回目标邮箱的 UID 有效性
 		SourceUIDs:  sourceUIDs,       // 返回源 UID 集合
-		DestUIDs:    destUIDs,         // 返回目标 UID 集合
+		DestUIDs:    appendData.UIDs,  // 返回目标 UID 集合
 	})
-	if err != nil {
-		return err // 返回错误
+}
+
+// Vanished 方法返回 uids 中已被删除的邮件 UID。
+//
+// 内存邮箱不记录邮件被删除时的修改序列号，因此会忽略 modSeq，返回 uids 中所有
+// 已不存在的 UID。
+func (sess *UserSession) Vanished(uids imap.UIDSet, modSeq uint64) (imap.UIDSet, error) {
+	mbox := sess.mailbox
+	mbox.mutex.Lock()
+	defer mbox.mutex.Unlock()
+
+	existing := make(map[imap.UID]struct{}, len(mbox.l))
+	for _, msg := range mbox.l {
+		existing[msg.uid] = struct{}{}
 	}
 
-	for _, seqNum := range seqNums { // 遍历已删除邮件的序号
-		if err := w.WriteExpunge(sess.mailbox.tracker.EncodeSeqNum(seqNum)); err != nil {
-			return err // 返回错误
+	last := mbox.uidNext - 1 // 已分配的最大 UID
+	var vanished imap.UIDSet
+	for _, r := range uids {
+		start, stop := r.Start, r.Stop
+		if start == 0 {
+			start = last // "*" 表示最大 UID
+		}
+		if stop == 0 {
+			stop = last
+		}
+		if start > stop {
+			start, stop = stop, start
+		}
+		if stop > last {
+			stop = last
+		}
+		for uid := start; uid != 0 && uid <= stop; uid++ {
+			if _, ok := existing[uid]; !ok {
+				vanished.AddNum(uid)
+			}
 		}
 	}
-
-	return nil // 返回 nil 表示成功
+	return vanished, nil
 }
 
 // Poll 方法从当前邮箱中轮询更新。
@@ -184,3 +246,19 @@ func (sess *UserSession) Idle(w *imapserver.UpdateWriter, stop <-chan struct{})
 	}
 	return sess.mailbox.Idle(w, stop) // 调用邮箱的 Idle 方法
 }
+
+// SetQuota 实现了 imapserver.SessionQuota 接口。用户未通过 User.SetQuotaAdmin
+// 获得权限时返回 NO [NOPERM]。
+func (sess *UserSession) SetQuota(root string, limits map[imap.QuotaResourceType]int64) error {
+	sess.user.mutex.Lock()
+	admin := sess.user.quotaAdmin
+	sess.user.mutex.Unlock()
+	if !admin {
+		return &imap.Error{
+			Type: imap.StatusResponseTypeNo,
+			Code: imap.ResponseCodeNoPerm,
+			Text: "没有修改配额的权限",
+		}
+	}
+	return sess.user.SetQuota(root, limits)
+}
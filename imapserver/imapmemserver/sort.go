@@ -0,0 +1,167 @@
+package imapmemserver
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/luhaoyun888/go-imap-cn"
+	"github.com/luhaoyun888/go-imap-cn/imapserver"
+)
+
+// sortItem 保存一封待排序邮件及其排序键。
+type sortItem struct {
+	num           uint32
+	arrival, date time.Time
+	size          int
+	subject       string
+	from, to, cc  string
+}
+
+// newSortItem 从邮件中提取排序键。
+func newSortItem(num uint32, msg *message) sortItem {
+	item := sortItem{num: num, arrival: msg.t, date: msg.t, size: len(msg.buf)}
+	if env := msg.envelope(); env != nil {
+		if !env.Date.IsZero() {
+			item.date = env.Date // 使用 Date 头字段，缺失时使用内部日期
+		}
+		item.subject = baseSubject(env.Subject)
+		item.from = firstMailbox(env.From)
+		item.to = firstMailbox(env.To)
+		item.cc = firstMailbox(env.Cc)
+	}
+	return item
+}
+
+// Sort 实现了 imapserver.SessionSort 接口。
+// 先按 searchCriteria 过滤邮件，再按 sortCriteria 稳定排序。
+func (mbox *MailboxView) Sort(numKind imapserver.NumKind, sortCriteria []imap.SortCriterion, searchCriteria *imap.SearchCriteria) ([]uint32, error) {
+	mbox.mutex.Lock() // 锁定邮箱以进行并发安全访问
+	defer mbox.mutex.Unlock()
+
+	var items []sortItem
+	mbox.forEachMatch(numKind, searchCriteria, func(num uint32, msg *message) {
+		items = append(items, newSortItem(num, msg))
+	})
+
+	// 邮件已按序列号排列，稳定排序保证排序键相同的邮件仍按序列号排列
+	sort.SliceStable(items, func(i, j int) bool {
+		for _, criterion := range sortCriteria {
+			cmp := compareSortKey(criterion.Key, &items[i], &items[j])
+			if criterion.Reverse {
+				cmp = -cmp
+			}
+			if cmp != 0 {
+				return cmp < 0
+			}
+		}
+		return false
+	})
+
+	nums := make([]uint32, len(items))
+	for i, item := range items {
+		nums[i] = item.num
+	}
+	return nums, nil
+}
+
+// forEachMatch 按序列号顺序对每封符合 criteria 的邮件调用 f，
+// num 是邮件的序列号或 UID（取决于 numKind）。调用者必须持有 mbox.mutex。
+func (mbox *MailboxView) forEachMatch(numKind imapserver.NumKind, criteria *imap.SearchCriteria, f func(num uint32, msg *message)) {
+	mbox.staticSearchCriteria(criteria) // 处理静态搜索条件
+
+	for i, msg := range mbox.l {
+		seqNum := mbox.tracker.EncodeSeqNum(uint32(i) + 1)
+
+		if !msg.search(seqNum, mbox.recent.Contains(msg.uid), criteria) {
+			continue
+		}
+
+		var num uint32
+		switch numKind {
+		case imapserver.NumKindSeq:
+			if seqNum == 0 {
+				continue
+			}
+			num = seqNum
+		case imapserver.NumKindUID:
+			num = uint32(msg.uid)
+		}
+		f(num, msg)
+	}
+}
+
+// compareSortKey 按指定排序键比较两封邮件，返回 -1、0 或 1。
+func compareSortKey(key imap.SortKey, a, b *sortItem) int {
+	switch key {
+	case imap.SortKeyArrival:
+		return compareTime(a.arrival, b.arrival)
+	case imap.SortKeyDate:
+		return compareTime(a.date, b.date)
+	case imap.SortKeySize:
+		return compareInt(a.size, b.size)
+	case imap.SortKeySubject:
+		return strings.Compare(a.subject, b.subject)
+	case imap.SortKeyFrom:
+		return strings.Compare(a.from, b.from)
+	case imap.SortKeyTo:
+		return strings.Compare(a.to, b.to)
+	case imap.SortKeyCc:
+		return strings.Compare(a.cc, b.cc)
+	default:
+		return 0
+	}
+}
+
+// firstMailbox 返回地址列表中第一个地址的邮箱部分（小写），列表为空时返回空字符串。
+func firstMailbox(addrs []imap.Address) string {
+	if len(addrs) == 0 {
+		return ""
+	}
+	return strings.ToLower(addrs[0].Mailbox)
+}
+
+// baseSubject 返回用于排序的基础主题，参见 RFC 5256 第 2.1 节：
+// 去掉 "Re:"、"Fwd:"、"Fw:" 和 "[...]" 前缀以及 "(fwd)" 后缀，并忽略大小写。
+func baseSubject(subject string) string {
+	s := strings.ToLower(strings.Join(strings.Fields(subject), " "))
+	for {
+		prev := s
+		s = strings.TrimSpace(strings.TrimSuffix(s, "(fwd)"))
+		for _, prefix := range []string{"re:", "fwd:", "fw:"} {
+			s = strings.TrimSpace(strings.TrimPrefix(s, prefix))
+		}
+		if strings.HasPrefix(s, "[") {
+			if i := strings.IndexByte(s, ']'); i > 0 && i+1 < len(s) {
+				s = strings.TrimSpace(s[i+1:])
+			}
+		}
+		if s == prev {
+			return s
+		}
+	}
+}
+
+// compareTime 比较两个时间，返回 -1、0 或 1。
+func compareTime(a, b time.Time) int {
+	switch {
+	case a.Before(b):
+		return -1
+	case a.After(b):
+		return 1
+	default:
+		return 0
+	}
+}
+
+// compareInt 比较两个整数，返回 -1、0 或 1。
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
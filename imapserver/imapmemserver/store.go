@@ -0,0 +1,292 @@
+package imapmemserver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/luhaoyun888/go-imap-cn"
+	"github.com/luhaoyun888/go-imap-cn/imapserver"
+)
+
+// 磁盘存储的目录结构：
+//
+//	<dir>/<用户名>/uidvalidity          上一个分配的 UIDVALIDITY
+//	<dir>/<用户名>/<邮箱 ID>/index.json  邮箱的元数据及邮件的 UID、标志和修改序列号
+//	<dir>/<用户名>/<邮箱 ID>/cur/<UID>   邮件内容
+//	<dir>/<用户名>/<邮箱 ID>/tmp/        正在写入的邮件
+//
+// 与 maildir 一样，邮件先写入 tmp 再重命名到 cur，索引文件也先写入临时文件再
+// 重命名，因此不会读到写了一半的文件。
+
+const (
+	indexFileName       = "index.json"  // 邮箱索引文件名
+	uidValidityFileName = "uidvalidity" // 用户的 UIDVALIDITY 文件名
+)
+
+// mailboxIndex 是邮箱索引文件的内容。
+type mailboxIndex struct {
+	Name          string         `json:"name"`
+	UIDValidity   uint32         `json:"uidValidity"`
+	UIDNext       imap.UID       `json:"uidNext"`
+	HighestModSeq uint64         `json:"highestModSeq"`
+	Subscribed    bool           `json:"subscribed,omitempty"`
+	Messages      []messageIndex `json:"messages"`
+}
+
+// messageIndex 是索引文件中一封邮件的元数据。
+type messageIndex struct {
+	UID          imap.UID    `json:"uid"`
+	InternalDate time.Time   `json:"internalDate"`
+	Flags        []imap.Flag `json:"flags,omitempty"`
+	ModSeq       uint64      `json:"modSeq"`
+}
+
+// mailboxStore 将一个邮箱保存在磁盘目录中。
+type mailboxStore struct {
+	dir string
+}
+
+// newMailboxStore 在 dir 中创建邮箱存储所需的目录。
+func newMailboxStore(dir string) (*mailboxStore, error) {
+	for _, sub := range []string{"cur", "tmp"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0700); err != nil {
+			return nil, err
+		}
+	}
+	return &mailboxStore{dir: dir}, nil
+}
+
+// messagePath 返回邮件内容的文件路径。
+func (s *mailboxStore) messagePath(uid imap.UID) string {
+	return filepath.Join(s.dir, "cur", strconv.FormatUint(uint64(uid), 10))
+}
+
+// writeMessage 将邮件内容写入磁盘。
+func (s *mailboxStore) writeMessage(uid imap.UID, buf []byte) error {
+	tmp := filepath.Join(s.dir, "tmp", strconv.FormatUint(uint64(uid), 10))
+	if err := writeFileSync(tmp, buf); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.messagePath(uid))
+}
+
+// removeMessage 删除邮件内容。
+func (s *mailboxStore) removeMessage(uid imap.UID) error {
+	err := os.Remove(s.messagePath(uid))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// writeIndex 写入邮箱索引文件。
+func (s *mailboxStore) writeIndex(index *mailboxIndex) error {
+	b, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	name := filepath.Join(s.dir, indexFileName)
+	if err := writeFileSync(name+".tmp", b); err != nil {
+		return err
+	}
+	return os.Rename(name+".tmp", name)
+}
+
+// writeFileSync 写入文件并在返回前同步到磁盘。
+func writeFileSync(name string, b []byte) error {
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// loadMailbox 从目录 dir 中加载邮箱，目录名即邮箱 ID。
+func loadMailbox(dir string) (*Mailbox, error) {
+	b, err := os.ReadFile(filepath.Join(dir, indexFileName))
+	if err != nil {
+		return nil, err
+	}
+	var index mailboxIndex
+	if err := json.Unmarshal(b, &index); err != nil {
+		return nil, fmt.Errorf("解析 %v 失败: %w", indexFileName, err)
+	}
+
+	store, err := newMailboxStore(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	mbox := NewMailbox(index.Name, index.UIDValidity)
+	mbox.id = filepath.Base(dir)
+	mbox.uidNext = index.UIDNext
+	mbox.highestModSeq = index.HighestModSeq
+	mbox.subscribed = index.Subscribed
+	mbox.store = store
+	for _, mi := range index.Messages {
+		buf, err := os.ReadFile(store.messagePath(mi.UID))
+		if err != nil {
+			return nil, err
+		}
+		msg := &message{
+			uid:    mi.UID,
+			buf:    buf,
+			t:      mi.InternalDate,
+			flags:  make(map[imap.Flag]struct{}, len(mi.Flags)),
+			modSeq: mi.ModSeq,
+		}
+		for _, flag := range mi.Flags {
+			msg.flags[canonicalFlag(flag)] = struct{}{}
+		}
+		mbox.l = append(mbox.l, msg)
+	}
+	mbox.tracker = imapserver.NewMailboxTracker(uint32(len(mbox.l)))
+	return mbox, nil
+}
+
+// openStore 将邮箱及其中的邮件保存到目录 dir 中，之后的修改会同步写入磁盘。
+func (mbox *Mailbox) openStore(dir string) error {
+	store, err := newMailboxStore(dir)
+	if err != nil {
+		return err
+	}
+
+	mbox.mutex.Lock()
+	defer mbox.mutex.Unlock()
+
+	for _, msg := range mbox.l {
+		if err := store.writeMessage(msg.uid, msg.buf); err != nil {
+			return err
+		}
+	}
+	mbox.store = store
+	if err := mbox.saveLocked(); err != nil {
+		mbox.store = nil
+		return err
+	}
+	return nil
+}
+
+// saveLocked 在锁定状态下将邮箱索引写入磁盘。邮箱只保存在内存中时不做任何操作。
+func (mbox *Mailbox) saveLocked() error {
+	if mbox.store == nil {
+		return nil
+	}
+
+	index := mailboxIndex{
+		Name:          mbox.name,
+		UIDValidity:   mbox.uidValidity,
+		UIDNext:       mbox.uidNext,
+		HighestModSeq: mbox.highestModSeq,
+		Subscribed:    mbox.subscribed,
+		Messages:      make([]messageIndex, len(mbox.l)),
+	}
+	for i, msg := range mbox.l {
+		flags := make([]imap.Flag, 0, len(msg.flags))
+		for flag := range msg.flags {
+			flags = append(flags, flag)
+		}
+		sort.Slice(flags, func(i, j int) bool {
+			return flags[i] < flags[j]
+		})
+		index.Messages[i] = messageIndex{
+			UID:          msg.uid,
+			InternalDate: msg.t,
+			Flags:        flags,
+			ModSeq:       msg.modSeq,
+		}
+	}
+	return mbox.store.writeIndex(&index)
+}
+
+// openStore 将用户的邮箱保存到目录 dir 中，并加载 dir 中已有的邮箱。
+// 内存中已有的同名邮箱以磁盘上的为准，但保留其 APPENDLIMIT、配额、元数据和访问控制列表。
+func (u *User) openStore(dir string) error {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	if b, err := os.ReadFile(filepath.Join(dir, uidValidityFileName)); err == nil {
+		v, err := strconv.ParseUint(string(b), 10, 32)
+		if err != nil {
+			return fmt.Errorf("解析 %v 失败: %w", uidValidityFileName, err)
+		}
+		if uint32(v) > u.prevUidValidity {
+			u.prevUidValidity = uint32(v)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	mailboxes := make(map[string]*Mailbox)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		mbox, err := loadMailbox(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return fmt.Errorf("加载邮箱 %v 失败: %w", entry.Name(), err)
+		}
+		mbox.internalDate = u.internalDate
+		if old := u.mailboxes[mbox.name]; old != nil {
+			mbox.appendLimit = old.appendLimit
+			mbox.acl = old.acl
+			mbox.quota = old.quota
+			mbox.metadata = old.metadata
+			mbox.access = old.access
+		}
+		mailboxes[mbox.name] = mbox
+		if mbox.uidValidity > u.prevUidValidity {
+			u.prevUidValidity = mbox.uidValidity
+		}
+	}
+
+	for name, mbox := range u.mailboxes {
+		if mailboxes[name] != nil {
+			continue
+		}
+		if err := mbox.openStore(filepath.Join(dir, mbox.id)); err != nil {
+			return err
+		}
+		mailboxes[name] = mbox
+	}
+
+	u.storeDir = dir
+	u.mailboxes = mailboxes
+	return u.saveUIDValidityLocked()
+}
+
+// saveUIDValidityLocked 在锁定状态下将上一个分配的 UIDVALIDITY 写入磁盘，
+// 保证服务器重启后重建的邮箱 UIDVALIDITY 也会变化。
+func (u *User) saveUIDValidityLocked() error {
+	if u.storeDir == "" {
+		return nil
+	}
+	name := filepath.Join(u.storeDir, uidValidityFileName)
+	b := []byte(strconv.FormatUint(uint64(u.prevUidValidity), 10))
+	if err := writeFileSync(name+".tmp", b); err != nil {
+		return err
+	}
+	return os.Rename(name+".tmp", name)
+}
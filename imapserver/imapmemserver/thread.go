@@ -0,0 +1,338 @@
+package imapmemserver
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	gomessage "github.com/emersion/go-message"
+	"github.com/emersion/go-message/mail"
+	"github.com/emersion/go-message/textproto"
+	"github.com/luhaoyun888/go-imap-cn"
+	"github.com/luhaoyun888/go-imap-cn/imapserver"
+)
+
+// threadItem 保存一封待组织成线程的邮件及线程算法所需的字段。
+type threadItem struct {
+	num        uint32
+	date       time.Time // 发送日期，缺失时使用内部日期
+	subject    string    // 基础主题
+	reply      bool      // 主题是否带有回复或转发前缀
+	messageID  string
+	references []string // References，缺失时使用 In-Reply-To 的第一个 ID
+}
+
+// newThreadItem 从邮件头中提取线程算法所需的字段。
+func newThreadItem(num uint32, msg *message) *threadItem {
+	item := &threadItem{num: num, date: msg.t}
+
+	header, err := textproto.ReadHeader(bufio.NewReader(bytes.NewReader(msg.buf)))
+	if err != nil {
+		return item
+	}
+	mh := mail.Header{Header: gomessage.Header{Header: header}}
+
+	if date, err := mh.Date(); err == nil && !date.IsZero() {
+		item.date = date
+	}
+	subject, err := mh.Subject()
+	if err != nil {
+		subject = header.Get("Subject")
+	}
+	item.subject = baseSubject(subject)
+	item.reply = isReplySubject(subject)
+	item.messageID, _ = mh.MessageID()
+	item.references, _ = mh.MsgIDList("References")
+	if len(item.references) == 0 {
+		if inReplyTo, _ := mh.MsgIDList("In-Reply-To"); len(inReplyTo) > 0 {
+			item.references = inReplyTo[:1]
+		}
+	}
+	return item
+}
+
+// isReplySubject 判断主题是否带有回复或转发前缀（或 "(fwd)" 后缀）。
+func isReplySubject(subject string) bool {
+	return baseSubject(subject) != strings.ToLower(strings.Join(strings.Fields(subject), " "))
+}
+
+// Thread 实现了 imapserver.SessionThread 接口。
+func (mbox *MailboxView) Thread(numKind imapserver.NumKind, algorithm imap.ThreadAlgorithm, searchCriteria *imap.SearchCriteria) ([]imap.ThreadData, error) {
+	mbox.mutex.Lock() // 锁定邮箱以进行并发安全访问
+	defer mbox.mutex.Unlock()
+
+	var items []*threadItem
+	mbox.forEachMatch(numKind, searchCriteria, func(num uint32, msg *message) {
+		items = append(items, newThreadItem(num, msg))
+	})
+
+	switch algorithm {
+	case imap.ThreadOrderedSubject:
+		return threadOrderedSubject(items), nil
+	case imap.ThreadReferences:
+		return threadReferences(items), nil
+	default:
+		return nil, &imap.Error{
+			Type: imap.StatusResponseTypeNo,
+			Text: fmt.Sprintf("不支持线程算法 %v", algorithm),
+		}
+	}
+}
+
+// threadOrderedSubject 实现 ORDEREDSUBJECT 算法，参见 RFC 5256 第 3 节：
+// 按基础主题分组，每组中最早的邮件是线程的根，其余邮件按日期排列，都是根的子邮件。
+// 线程按根的日期排序。
+func threadOrderedSubject(items []*threadItem) []imap.ThreadData {
+	sorted := append([]*threadItem(nil), items...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].subject != sorted[j].subject {
+			return sorted[i].subject < sorted[j].subject
+		}
+		return sorted[i].date.Before(sorted[j].date)
+	})
+
+	var roots []*threadContainer
+	for i := 0; i < len(sorted); {
+		j := i + 1
+		for j < len(sorted) && sorted[j].subject == sorted[i].subject {
+			j++
+		}
+		root := &threadContainer{item: sorted[i]}
+		for _, item := range sorted[i+1 : j] {
+			root.addChild(&threadContainer{item: item})
+		}
+		roots = append(roots, root)
+		i = j
+	}
+
+	sortThreadContainers(roots)
+	return threadDataList(roots)
+}
+
+// threadReferences 实现 REFERENCES 算法，参见 RFC 5256 第 3 节。
+func threadReferences(items []*threadItem) []imap.ThreadData {
+	// 按创建顺序保存所有容器，保证结果与 map 的遍历顺序无关
+	var all []*threadContainer
+	byID := make(map[string]*threadContainer)
+	getContainer := func(id string) *threadContainer {
+		c, ok := byID[id]
+		if !ok {
+			c = &threadContainer{}
+			byID[id] = c
+			all = append(all, c)
+		}
+		return c
+	}
+
+	// 第 1 步：根据 Message-ID 和 References 建立父子关系
+	for i, item := range items {
+		id := item.messageID
+		if id == "" || (byID[id] != nil && byID[id].item != nil) {
+			id = fmt.Sprintf(" %v", i) // 缺失或重复的 Message-ID，空格保证不会与真实 ID 冲突
+		}
+		c := getContainer(id)
+		c.item = item
+
+		var prev *threadContainer
+		for _, ref := range item.references {
+			rc := getContainer(ref)
+			if prev != nil && rc.parent == nil && !rc.isAncestorOf(prev) {
+				prev.addChild(rc)
+			}
+			prev = rc
+		}
+
+		if c.parent != nil {
+			c.parent.removeChild(c)
+		}
+		if prev != nil && !c.isAncestorOf(prev) {
+			prev.addChild(c)
+		}
+	}
+
+	// 第 2 步：收集根容器
+	var roots []*threadContainer
+	for _, c := range all {
+		if c.parent == nil {
+			roots = append(roots, c)
+		}
+	}
+
+	// 第 3、4 步：删除空容器
+	roots = pruneThreadContainers(roots, true)
+
+	// 第 5 步：按基础主题合并根容器
+	roots = groupThreadContainers(roots)
+
+	// 第 6 步：按日期排序
+	sortThreadContainers(roots)
+	return threadDataList(roots)
+}
+
+// threadContainer 是线程树中的一个节点。item 为 nil 表示被引用的邮件不存在。
+type threadContainer struct {
+	item     *threadItem
+	parent   *threadContainer
+	children []*threadContainer
+}
+
+// addChild 将 child 添加为 c 的最后一个子节点。
+func (c *threadContainer) addChild(child *threadContainer) {
+	child.parent = c
+	c.children = append(c.children, child)
+}
+
+// removeChild 将 child 从 c 的子节点中移除。
+func (c *threadContainer) removeChild(child *threadContainer) {
+	for i, other := range c.children {
+		if other == child {
+			c.children = append(c.children[:i], c.children[i+1:]...)
+			break
+		}
+	}
+	child.parent = nil
+}
+
+// isAncestorOf 判断 c 是否为 other 本身或其祖先。
+func (c *threadContainer) isAncestorOf(other *threadContainer) bool {
+	for ; other != nil; other = other.parent {
+		if other == c {
+			return true
+		}
+	}
+	return false
+}
+
+// first 返回 c 中的邮件，空容器返回第一个子节点中的邮件。
+func (c *threadContainer) first() *threadItem {
+	if c.item != nil || len(c.children) == 0 {
+		return c.item
+	}
+	return c.children[0].first()
+}
+
+// date 返回用于排序的日期。
+func (c *threadContainer) date() time.Time {
+	if item := c.first(); item != nil {
+		return item.date
+	}
+	return time.Time{}
+}
+
+// pruneThreadContainers 删除没有子节点的空容器，并将非根空容器的子节点提升一级。
+// 根空容器只有在仅有一个子节点时才会被提升。
+func pruneThreadContainers(l []*threadContainer, root bool) []*threadContainer {
+	var out []*threadContainer
+	for _, c := range l {
+		c.children = pruneThreadContainers(c.children, false)
+		for _, child := range c.children {
+			child.parent = c
+		}
+		if c.item == nil {
+			if len(c.children) == 0 {
+				continue
+			}
+			if !root || len(c.children) == 1 {
+				for _, child := range c.children {
+					child.parent = c.parent
+				}
+				out = append(out, c.children...)
+				continue
+			}
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// groupThreadContainers 将基础主题相同的根容器合并到同一个线程。
+func groupThreadContainers(roots []*threadContainer) []*threadContainer {
+	table := make(map[string]*threadContainer)
+	for _, c := range roots {
+		item := c.first()
+		if item == nil || item.subject == "" {
+			continue
+		}
+		old, ok := table[item.subject]
+		if !ok || (c.item == nil && old.item != nil) || (old.item != nil && old.item.reply && !item.reply) {
+			table[item.subject] = c
+		}
+	}
+
+	var dummies []*threadContainer
+	merged := make(map[*threadContainer]bool)
+	for _, c := range roots {
+		item := c.first()
+		if item == nil || item.subject == "" {
+			continue
+		}
+		old := table[item.subject]
+		if old == c {
+			continue
+		}
+		switch {
+		case c.item == nil && old.item == nil:
+			for _, child := range c.children {
+				old.addChild(child)
+			}
+			c.children = nil
+			merged[c] = true
+		case old.item == nil:
+			old.addChild(c)
+		case !old.item.reply && item.reply:
+			old.addChild(c)
+		default:
+			dummy := &threadContainer{}
+			dummy.addChild(old)
+			dummy.addChild(c)
+			table[item.subject] = dummy
+			dummies = append(dummies, dummy)
+		}
+	}
+
+	var out []*threadContainer
+	for _, c := range append(roots, dummies...) {
+		if c.parent == nil && !merged[c] {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// sortThreadContainers 按日期递归排序容器。空容器使用其第一个子节点的日期。
+func sortThreadContainers(l []*threadContainer) {
+	for _, c := range l {
+		sortThreadContainers(c.children)
+	}
+	sort.SliceStable(l, func(i, j int) bool {
+		return l[i].date().Before(l[j].date())
+	})
+}
+
+// threadDataList 将容器列表转换为 THREAD 响应数据。
+func threadDataList(l []*threadContainer) []imap.ThreadData {
+	var threads []imap.ThreadData
+	for _, c := range l {
+		threads = append(threads, c.threadData())
+	}
+	return threads
+}
+
+// threadData 将以 c 为根的线程树转换为 THREAD 响应数据。
+func (c *threadContainer) threadData() imap.ThreadData {
+	var data imap.ThreadData
+	for {
+		if c.item != nil {
+			data.Chain = append(data.Chain, c.item.num)
+		}
+		if len(c.children) != 1 {
+			break
+		}
+		c = c.children[0]
+	}
+	data.SubThreads = threadDataList(c.children)
+	return data
+}
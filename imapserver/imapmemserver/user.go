@@ -2,9 +2,12 @@ package imapmemserver
 
 import (
 	"crypto/subtle"
+	"os"
+	"path/filepath"
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/luhaoyun888/go-imap-cn"
 	"github.com/luhaoyun888/go-imap-cn/imapserver"
@@ -19,6 +22,20 @@ type User struct {
 	mutex           sync.Mutex          // 互斥锁，保护并发访问
 	mailboxes       map[string]*Mailbox // 用户的邮箱映射
 	prevUidValidity uint32              // 上一个 UID 有效性
+	internalDate    InternalDateFunc    // 新建邮箱使用的 INTERNALDATE 生成函数
+	uidValidity     UIDValidityFunc     // 新建邮箱的 UIDVALIDITY 生成函数
+	storeDir        string              // 保存邮箱的目录，为空时只保存在内存中
+	metadata        metadataStore       // 服务器元数据（RFC 5464）
+	quotaAdmin      bool                // 是否允许通过 SETQUOTA 命令修改配额
+}
+
+// UIDValidityFunc 为新建的邮箱生成 UIDVALIDITY。
+type UIDValidityFunc func(mailbox string) uint32
+
+// TimeUIDValidity 使用当前的 Unix 时间戳作为 UIDVALIDITY，即使服务器重启后
+// 重建邮箱，UIDVALIDITY 也会变化。
+func TimeUIDValidity(mailbox string) uint32 {
+	return uint32(time.Now().Unix())
 }
 
 // NewUser 创建一个新的用户实例。
@@ -172,6 +189,70 @@ func (u *User) Append(mailbox string, r imap.LiteralReader, options *imap.Append
 	return mbox.appendLiteral(r, options) // 追加邮件
 }
 
+// MultiAppend 方法开始向指定邮箱批量追加邮件。
+// 参数：
+//   - mailbox: 邮箱名称。
+//
+// 返回：
+//   - 返回批量追加器和错误信息（如果有）。
+func (u *User) MultiAppend(mailbox string) (imapserver.MultiAppender, error) {
+	mbox, err := u.mailbox(mailbox) // 获取邮箱
+	if err != nil {
+		return nil, &imap.Error{
+			Type: imap.StatusResponseTypeNo,
+			Code: imap.ResponseCodeTryCreate, // 邮箱不存在，提示尝试创建
+			Text: "找不到该邮箱",
+		}
+	}
+	return &multiAppender{mbox: mbox}, nil
+}
+
+// FetchURL 方法获取 CATENATE 中 URL 引用的邮件内容。
+// 参数：
+//   - url: 引用邮件或其正文部分的 URL。
+//
+// 返回：
+//   - 返回引用的内容和错误信息（如果有）。
+func (u *User) FetchURL(url *imapserver.CatenateURL) ([]byte, error) {
+	if url.User != "" && url.User != u.username {
+		return nil, &imap.Error{
+			Type: imap.StatusResponseTypeNo,
+			Text: "不能引用其他用户的邮件",
+		}
+	}
+	mbox, err := u.mailbox(url.Mailbox) // 获取邮箱
+	if err != nil {
+		return nil, err
+	}
+
+	mbox.mutex.Lock()
+	defer mbox.mutex.Unlock()
+
+	if url.UIDValidity != 0 && url.UIDValidity != mbox.uidValidity {
+		return nil, &imap.Error{
+			Type: imap.StatusResponseTypeNo,
+			Text: "UIDVALIDITY 不匹配",
+		}
+	}
+	for _, msg := range mbox.l {
+		if msg.uid != url.UID {
+			continue
+		}
+		b, ok := msg.bodySection(url.Section) // 提取引用的正文部分
+		if !ok {
+			return nil, &imap.Error{
+				Type: imap.StatusResponseTypeNo,
+				Text: "找不到该正文部分",
+			}
+		}
+		return b, nil
+	}
+	return nil, &imap.Error{
+		Type: imap.StatusResponseTypeNo,
+		Text: "找不到该邮件",
+	}
+}
+
 // Create 方法创建一个新的邮箱。
 // 参数：
 //   - name: 新邮箱名称。
@@ -193,10 +274,73 @@ func (u *User) Create(name string, options *imap.CreateOptions) error {
 		}
 	}
 
-	// UIDVALIDITY 如果邮箱被删除再重新创建，必须更改
-	u.prevUidValidity++
-	u.mailboxes[name] = NewMailbox(name, u.prevUidValidity) // 创建新邮箱并保存
-	return nil                                              // 返回 nil 表示成功
+	// UIDVALIDITY 如果邮箱被删除再重新创建，必须更改，因此保证严格递增
+	uidValidity := u.prevUidValidity + 1
+	if u.uidValidity != nil {
+		if v := u.uidValidity(name); v > u.prevUidValidity {
+			uidValidity = v
+		}
+	}
+	u.prevUidValidity = uidValidity
+	if err := u.saveUIDValidityLocked(); err != nil {
+		return err
+	}
+	mbox := NewMailbox(name, uidValidity) // 创建新邮箱
+	mbox.internalDate = u.internalDate
+	if u.storeDir != "" {
+		if err := mbox.openStore(filepath.Join(u.storeDir, mbox.id)); err != nil {
+			return err
+		}
+	}
+	u.mailboxes[name] = mbox // 保存邮箱
+	return nil               // 返回 nil 表示成功
+}
+
+// SetUIDValidityFunc 设置之后新建邮箱的 UIDVALIDITY 生成函数。f 为 nil 时
+// UIDVALIDITY 从 1 开始递增。
+//
+// 为保证删除并重建的邮箱 UIDVALIDITY 一定变化，生成的值不大于之前分配的
+// UIDVALIDITY 时，使用之前的值加一。
+func (u *User) SetUIDValidityFunc(f UIDValidityFunc) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	u.uidValidity = f
+}
+
+// SetInternalDateFunc 设置该用户所有邮箱（包括之后创建的邮箱）在 APPEND
+// 未指定日期时生成 INTERNALDATE 的函数。f 为 nil 时使用当前时间。
+//
+// 例如，传入 ReceivedInternalDate 可以使用 Received 头字段中的投递时间。
+func (u *User) SetInternalDateFunc(f InternalDateFunc) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	u.internalDate = f
+	for _, mbox := range u.mailboxes {
+		mbox.SetInternalDateFunc(f)
+	}
+}
+
+// SetAppendLimit 设置指定邮箱接受的最大邮件大小。limit 为 nil 时不限制。
+//
+// 要让客户端知道各邮箱的限制，服务器应在 Options.Caps 中加入 APPENDLIMIT。
+func (u *User) SetAppendLimit(name string, limit *uint32) error {
+	mbox, err := u.mailbox(name)
+	if err != nil {
+		return err
+	}
+	mbox.SetAppendLimit(limit)
+	return nil
+}
+
+// SetMailboxAccess 设置指定邮箱的访问模式，参见 Mailbox.SetAccess。
+func (u *User) SetMailboxAccess(name string, access MailboxAccess) error {
+	mbox, err := u.mailbox(name)
+	if err != nil {
+		return err
+	}
+	mbox.SetAccess(access)
+	return nil
 }
 
 // Delete 方法删除指定的邮箱。
@@ -209,9 +353,15 @@ func (u *User) Delete(name string) error {
 	u.mutex.Lock()         // 锁定
 	defer u.mutex.Unlock() // 解锁
 
-	if _, err := u.mailboxLocked(name); err != nil { // 检查邮箱是否存在
+	mbox, err := u.mailboxLocked(name) // 检查邮箱是否存在
+	if err != nil {
 		return err // 返回错误
 	}
+	if mbox.store != nil {
+		if err := os.RemoveAll(mbox.store.dir); err != nil { // 删除磁盘上的邮箱
+			return err
+		}
+	}
 
 	delete(u.mailboxes, name) // 删除邮箱
 	return nil                // 返回 nil 表示成功
@@ -243,7 +393,9 @@ func (u *User) Rename(oldName, newName string) error {
 		}
 	}
 
-	mbox.rename(newName)         // 重命名邮箱
+	if err := mbox.rename(newName); err != nil { // 重命名邮箱
+		return err
+	}
 	u.mailboxes[newName] = mbox  // 更新邮箱映射
 	delete(u.mailboxes, oldName) // 删除旧邮箱映射
 	return nil                   // 返回 nil 表示成功
@@ -260,8 +412,7 @@ func (u *User) Subscribe(name string) error {
 	if err != nil {
 		return err // 返回错误
 	}
-	mbox.SetSubscribed(true) // 设置为已订阅
-	return nil               // 返回 nil 表示成功
+	return mbox.setSubscribed(true) // 设置为已订阅
 }
 
 // Unsubscribe 方法取消订阅指定的邮箱。
@@ -275,8 +426,250 @@ func (u *User) Unsubscribe(name string) error {
 	if err != nil {
 		return err // 返回错误
 	}
-	mbox.SetSubscribed(false) // 设置为未订阅
-	return nil                // 返回 nil 表示成功
+	return mbox.setSubscribed(false) // 设置为未订阅
+}
+
+// MailboxID 方法返回指定邮箱的 ID。
+// 参数：
+//   - name: 邮箱名称。
+//
+// 返回：
+//   - 返回邮箱 ID；如果邮箱不存在，返回错误信息。
+func (u *User) MailboxID(name string) (string, error) {
+	name = strings.TrimRight(name, string(mailboxDelim)) // 与 Create 一致，去掉尾部的分隔符
+	mbox, err := u.mailbox(name)                         // 获取邮箱
+	if err != nil {
+		return "", err // 返回错误
+	}
+	return mbox.id, nil
+}
+
+// SetACL 方法修改标识符在指定邮箱上的权限。
+// 参数：
+//   - name: 邮箱名称。
+//   - ri: 权限标识符。
+//   - rm: 权限修改方式（替换、增加或删除）。
+//   - rs: 权限集。
+//
+// 返回：
+//   - 返回错误信息（如果有）。
+func (u *User) SetACL(name string, ri imap.RightsIdentifier, rm imap.RightModification, rs imap.RightSet) error {
+	mbox, err := u.mailbox(name) // 获取邮箱
+	if err != nil {
+		return err // 返回错误
+	}
+
+	mbox.mutex.Lock()
+	defer mbox.mutex.Unlock()
+
+	acl := mbox.aclLocked(u.username)
+	switch rm {
+	case imap.RightModificationAdd:
+		rs = acl[ri].Add(rs)
+	case imap.RightModificationRemove:
+		rs = acl[ri].Remove(rs)
+	}
+	if len(rs) == 0 {
+		delete(acl, ri) // 没有任何权限时删除条目
+	} else {
+		acl[ri] = rs
+	}
+	return nil
+}
+
+// DeleteACL 方法删除标识符在指定邮箱上的所有权限。
+// 参数：
+//   - name: 邮箱名称。
+//   - ri: 权限标识符。
+//
+// 返回：
+//   - 返回错误信息（如果有）。
+func (u *User) DeleteACL(name string, ri imap.RightsIdentifier) error {
+	mbox, err := u.mailbox(name) // 获取邮箱
+	if err != nil {
+		return err // 返回错误
+	}
+
+	mbox.mutex.Lock()
+	defer mbox.mutex.Unlock()
+	delete(mbox.aclLocked(u.username), ri)
+	return nil
+}
+
+// GetACL 方法返回指定邮箱的访问控制列表。
+// 参数：
+//   - name: 邮箱名称。
+//
+// 返回：
+//   - 返回标识符到权限集的映射和错误信息（如果有）。
+func (u *User) GetACL(name string) (map[imap.RightsIdentifier]imap.RightSet, error) {
+	mbox, err := u.mailbox(name) // 获取邮箱
+	if err != nil {
+		return nil, err // 返回错误
+	}
+
+	mbox.mutex.Lock()
+	defer mbox.mutex.Unlock()
+
+	acl := make(map[imap.RightsIdentifier]imap.RightSet)
+	for ri, rs := range mbox.aclLocked(u.username) {
+		acl[ri] = rs // 复制一份，避免调用者修改
+	}
+	return acl, nil
+}
+
+// ListRights 方法返回可以授予标识符的权限。
+// 内存服务器不强制要求任何权限，所有标准权限都可以单独授予。
+// 参数：
+//   - name: 邮箱名称。
+//   - ri: 权限标识符。
+//
+// 返回：
+//   - 返回必需的权限、可选的权限组和错误信息（如果有）。
+func (u *User) ListRights(name string, ri imap.RightsIdentifier) (imap.RightSet, []imap.RightSet, error) {
+	if _, err := u.mailbox(name); err != nil {
+		return nil, nil, err // 返回错误
+	}
+
+	optional := make([]imap.RightSet, len(imap.RightSetAll))
+	for i, r := range imap.RightSetAll {
+		optional[i] = imap.RightSet{r}
+	}
+	return imap.RightSet{}, optional, nil
+}
+
+// MyRights 方法返回用户在指定邮箱上的权限。
+// 参数：
+//   - name: 邮箱名称。
+//
+// 返回：
+//   - 返回权限集和错误信息（如果有）。
+func (u *User) MyRights(name string) (imap.RightSet, error) {
+	mbox, err := u.mailbox(name) // 获取邮箱
+	if err != nil {
+		return nil, err // 返回错误
+	}
+
+	mbox.mutex.Lock()
+	defer mbox.mutex.Unlock()
+
+	acl := mbox.aclLocked(u.username)
+	if rs, ok := acl[imap.RightsIdentifier(u.username)]; ok {
+		return rs, nil
+	}
+	return acl[imap.RightsIdentifierAnyone], nil // 回退到 anyone 的权限
+}
+
+// GetQuota 方法返回配额根的资源使用情况和限制。每个邮箱都是以其名称命名的配额根。
+// 参数：
+//   - root: 配额根名称。
+//
+// 返回：
+//   - 返回配额数据和错误信息（如果有）。
+func (u *User) GetQuota(root string) (*imap.QuotaData, error) {
+	mbox, err := u.mailbox(root) // 获取邮箱
+	if err != nil {
+		return nil, err // 返回错误
+	}
+	return mbox.quotaData(), nil
+}
+
+// GetQuotaRoot 方法返回邮箱所属的配额根，即邮箱自身。
+// 参数：
+//   - name: 邮箱名称。
+//
+// 返回：
+//   - 返回配额根列表和错误信息（如果有）。
+func (u *User) GetQuotaRoot(name string) ([]string, error) {
+	mbox, err := u.mailbox(name) // 获取邮箱
+	if err != nil {
+		return nil, err // 返回错误
+	}
+	mbox.mutex.Lock()
+	defer mbox.mutex.Unlock()
+	return []string{mbox.name}, nil
+}
+
+// SetQuotaAdmin 设置是否允许该用户通过 SETQUOTA 命令修改配额，默认不允许。
+//
+// 只应对管理员用户启用，否则用户可以自行提高或取消配额限制。
+func (u *User) SetQuotaAdmin(admin bool) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	u.quotaAdmin = admin
+}
+
+// SetQuota 方法设置配额根的资源限制，参见 Mailbox.SetQuota。
+//
+// 此方法供服务器代码使用，不检查权限。客户端的 SETQUOTA 命令由
+// UserSession.SetQuota 处理，只有通过 SetQuotaAdmin 启用的用户才能执行。
+// 参数：
+//   - root: 配额根名称。
+//   - limits: 资源限制。
+//
+// 返回：
+//   - 返回错误信息（如果有）。
+func (u *User) SetQuota(root string, limits map[imap.QuotaResourceType]int64) error {
+	mbox, err := u.mailbox(root) // 获取邮箱
+	if err != nil {
+		return err // 返回错误
+	}
+	return mbox.SetQuota(limits)
+}
+
+// GetMetadata 方法返回邮箱或服务器的元数据条目。
+// 参数：
+//   - name: 邮箱名称，为空字符串时表示服务器元数据。
+//   - entries: 条目名称。
+//   - options: GETMETADATA 选项，只使用其中的深度。
+//
+// 返回：
+//   - 返回条目的值（不存在的条目为 nil）和错误信息（如果有）。
+func (u *User) GetMetadata(name string, entries []string, options *imap.GetMetadataOptions) (map[string]*[]byte, error) {
+	if name == "" {
+		u.mutex.Lock()
+		defer u.mutex.Unlock()
+		return u.metadata.get(entries, options.Depth), nil
+	}
+
+	mbox, err := u.mailbox(name) // 获取邮箱
+	if err != nil {
+		return nil, err // 返回错误
+	}
+	mbox.mutex.Lock()
+	defer mbox.mutex.Unlock()
+	return mbox.metadata.get(entries, options.Depth), nil
+}
+
+// SetMetadata 方法设置邮箱或服务器的元数据条目，值为 nil 时删除该条目。
+// 参数：
+//   - name: 邮箱名称，为空字符串时表示服务器元数据。
+//   - entries: 要设置的条目。
+//
+// 返回：
+//   - 返回错误信息（如果有）。
+func (u *User) SetMetadata(name string, entries map[string]*[]byte) error {
+	if name == "" {
+		u.mutex.Lock()
+		defer u.mutex.Unlock()
+		if u.metadata == nil {
+			u.metadata = make(metadataStore)
+		}
+		u.metadata.set(entries)
+		return nil
+	}
+
+	mbox, err := u.mailbox(name) // 获取邮箱
+	if err != nil {
+		return err // 返回错误
+	}
+	mbox.mutex.Lock()
+	defer mbox.mutex.Unlock()
+	if mbox.metadata == nil {
+		mbox.metadata = make(metadataStore)
+	}
+	mbox.metadata.set(entries)
+	return nil
 }
 
 // Namespace 方法返回用户的命名空间信息。
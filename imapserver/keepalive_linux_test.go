@@ -0,0 +1,85 @@
+package imapserver_test
+
+import (
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/luhaoyun888/go-imap-cn/imapclient"
+	"github.com/luhaoyun888/go-imap-cn/imapserver"
+)
+
+// tcpKeepAlive 读取 TCP 连接的 SO_KEEPALIVE 和 TCP_KEEPIDLE（keepalive 周期）选项。
+func tcpKeepAlive(t *testing.T, conn net.Conn) (enabled bool, period time.Duration) {
+	tcpConn, ok := conn.(*net.TCPConn)
+	if !ok {
+		t.Fatalf("连接 %T 不是 *net.TCPConn", conn)
+	}
+	rawConn, err := tcpConn.SyscallConn()
+	if err != nil {
+		t.Fatalf("SyscallConn() = %v", err)
+	}
+	var (
+		keepAlive, idle int
+		sockErr         error
+	)
+	err = rawConn.Control(func(fd uintptr) {
+		keepAlive, sockErr = syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_KEEPALIVE)
+		if sockErr == nil {
+			idle, sockErr = syscall.GetsockoptInt(int(fd), syscall.IPPROTO_TCP, syscall.TCP_KEEPIDLE)
+		}
+	})
+	if err == nil {
+		err = sockErr
+	}
+	if err != nil {
+		t.Fatalf("getsockopt() = %v", err)
+	}
+	return keepAlive != 0, time.Duration(idle) * time.Second
+}
+
+// TestServer_tcpKeepAlive 测试服务器和客户端的 TCPKeepAlive 选项设置到底层 socket 上。
+func TestServer_tcpKeepAlive(t *testing.T) {
+	connCh := make(chan net.Conn, 1)
+	server, addr := newTestServer(t, &imapserver.Options{
+		TCPKeepAlive: 42 * time.Second,
+		OnSessionStart: func(conn *imapserver.Conn, session imapserver.Session) {
+			connCh <- conn.NetConn()
+		},
+	})
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("net.Dial() = %v", err)
+	}
+	client := imapclient.New(conn, &imapclient.Options{TCPKeepAlive: 37 * time.Second})
+	defer client.Close()
+	if err := client.WaitGreeting(); err != nil {
+		t.Fatalf("WaitGreeting() = %v", err)
+	}
+
+	if enabled, interval := tcpKeepAlive(t, conn); !enabled || interval != 37*time.Second {
+		t.Errorf("客户端 keepalive = %v, %v, want true, 37s", enabled, interval)
+	}
+	select {
+	case serverConn := <-connCh:
+		if enabled, interval := tcpKeepAlive(t, serverConn); !enabled || interval != 42*time.Second {
+			t.Errorf("服务器 keepalive = %v, %v, want true, 42s", enabled, interval)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("OnSessionStart 未被调用")
+	}
+
+	// 小于零时禁用 keepalive
+	conn2, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("net.Dial() = %v", err)
+	}
+	client2 := imapclient.New(conn2, &imapclient.Options{TCPKeepAlive: -1})
+	defer client2.Close()
+	if enabled, _ := tcpKeepAlive(t, conn2); enabled {
+		t.Errorf("客户端 keepalive = true, want false")
+	}
+}
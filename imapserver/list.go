@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/luhaoyun888/go-imap-cn"
+	"github.com/luhaoyun888/go-imap-cn/internal"
 	"github.com/luhaoyun888/go-imap-cn/internal/imapwire"
 	"github.com/luhaoyun888/go-imap-cn/internal/utf7"
 )
@@ -18,7 +19,7 @@ import (
 //
 //	处理过程中的错误，如果没有错误返回 nil。
 func (c *Conn) handleList(dec *imapwire.Decoder) error {
-	ref, pattern, options, returnRecent, err := readListCmd(dec)
+	ref, pattern, options, err := readListCmd(dec)
 	if err != nil {
 		return err
 	}
@@ -28,11 +29,10 @@ func (c *Conn) handleList(dec *imapwire.Decoder) error {
 	}
 
 	w := &ListWriter{
-		conn:         c,
-		options:      options,
-		returnRecent: returnRecent,
+		conn:    c,
+		options: options,
 	}
-	return c.session.List(w, ref, pattern, options)
+	return c.sessionList(w, ref, pattern, options)
 }
 
 // handleLSub 处理 LSUB 命令。
@@ -65,7 +65,7 @@ func (c *Conn) handleLSub(dec *imapwire.Decoder) error {
 		conn: c,
 		lsub: true,
 	}
-	return c.session.List(w, ref, []string{pattern}, options)
+	return c.sessionList(w, ref, []string{pattern}, options)
 }
 
 // writeList 写入 LIST 响应。
@@ -155,13 +155,12 @@ func (c *Conn) writeLSub(data *imap.ListData) error {
 //	ref - 邮箱引用。
 //	patterns - 匹配模式。
 //	options - 列表选项。
-//	returnRecent - 是否返回最近的邮件。
 //	err - 处理过程中的错误。
-func readListCmd(dec *imapwire.Decoder) (ref string, patterns []string, options *imap.ListOptions, returnRecent bool, err error) {
+func readListCmd(dec *imapwire.Decoder) (ref string, patterns []string, options *imap.ListOptions, err error) {
 	options = &imap.ListOptions{}
 
 	if !dec.ExpectSP() {
-		return "", nil, nil, false, dec.Err()
+		return "", nil, nil, dec.Err()
 	}
 
 	hasSelectOpts, err := dec.List(func() error {
@@ -182,14 +181,14 @@ func readListCmd(dec *imapwire.Decoder) (ref string, patterns []string, options
 		return nil
 	})
 	if err != nil {
-		return "", nil, nil, false, fmt.Errorf("在 list-select-opts 中: %w", err) // "in list-select-opts"
+		return "", nil, nil, fmt.Errorf("在 list-select-opts 中: %w", err) // "in list-select-opts"
 	}
 	if hasSelectOpts && !dec.ExpectSP() {
-		return "", nil, nil, false, dec.Err()
+		return "", nil, nil, dec.Err()
 	}
 
 	if !dec.ExpectMailbox(&ref) || !dec.ExpectSP() {
-		return "", nil, nil, false, dec.Err()
+		return "", nil, nil, dec.Err()
 	}
 
 	hasPatterns, err := dec.List(func() error {
@@ -200,13 +199,13 @@ func readListCmd(dec *imapwire.Decoder) (ref string, patterns []string, options
 		return err
 	})
 	if err != nil {
-		return "", nil, nil, false, err
+		return "", nil, nil, err
 	} else if hasPatterns && len(patterns) == 0 {
-		return "", nil, nil, false, newClientBugError("LIST-EXTENDED 需要一个非空的括号模式列表") // "LIST-EXTENDED requires a non-empty parenthesized pattern list"
+		return "", nil, nil, newClientBugError("LIST-EXTENDED 需要一个非空的括号模式列表") // "LIST-EXTENDED requires a non-empty parenthesized pattern list"
 	} else if !hasPatterns {
 		pattern, err := readListMailbox(dec)
 		if err != nil {
-			return "", nil, nil, false, err
+			return "", nil, nil, err
 		}
 		if pattern != "" {
 			patterns = append(patterns, pattern)
@@ -216,26 +215,26 @@ func readListCmd(dec *imapwire.Decoder) (ref string, patterns []string, options
 	if dec.SP() { // list-return-opts
 		var atom string
 		if !dec.ExpectAtom(&atom) || !dec.Expect(strings.EqualFold(atom, "RETURN"), "RETURN") || !dec.ExpectSP() {
-			return "", nil, nil, false, dec.Err()
+			return "", nil, nil, dec.Err()
 		}
 
 		err := dec.ExpectList(func() error {
-			return readReturnOption(dec, options, &returnRecent)
+			return readReturnOption(dec, options)
 		})
 		if err != nil {
-			return "", nil, nil, false, fmt.Errorf("在 list-return-opts 中: %w", err) // "in list-return-opts"
+			return "", nil, nil, fmt.Errorf("在 list-return-opts 中: %w", err) // "in list-return-opts"
 		}
 	}
 
 	if !dec.ExpectCRLF() {
-		return "", nil, nil, false, dec.Err()
+		return "", nil, nil, dec.Err()
 	}
 
 	if options.SelectRecursiveMatch && !options.SelectSubscribed {
-		return "", nil, nil, false, newClientBugError("LIST RECURSIVEMATCH 选择选项需要 SUBSCRIBED") // "The LIST RECURSIVEMATCH select option requires SUBSCRIBED"
+		return "", nil, nil, newClientBugError("LIST RECURSIVEMATCH 选择选项需要 SUBSCRIBED") // "The LIST RECURSIVEMATCH select option requires SUBSCRIBED"
 	}
 
-	return ref, patterns, options, returnRecent, nil
+	return ref, patterns, options, nil
 }
 
 // readListMailbox 读取 LIST 命令中的邮箱名称。
@@ -269,12 +268,11 @@ func isListChar(ch byte) bool {
 //
 //	dec - 解码器，用于读取命令参数。
 //	options - 列表选项。
-//	recent - 是否返回最近的邮件。
 //
 // 返回值:
 //
 //	处理过程中的错误。
-func readReturnOption(dec *imapwire.Decoder, options *imap.ListOptions, recent *bool) error {
+func readReturnOption(dec *imapwire.Decoder, options *imap.ListOptions) error {
 	var name string
 	if !dec.ExpectAtom(&name) {
 		return dec.Err()
@@ -291,14 +289,7 @@ func readReturnOption(dec *imapwire.Decoder, options *imap.ListOptions, recent *
 		}
 		options.ReturnStatus = new(imap.StatusOptions)
 		return dec.ExpectList(func() error {
-			isRecent, err := readStatusItem(dec, options.ReturnStatus)
-			if err != nil {
-				return err
-			}
-			if isRecent {
-				*recent = true
-			}
-			return nil
+			return readStatusItem(dec, options.ReturnStatus)
 		})
 	default:
 		return newClientBugError("未知的 LIST 返回选项") // "Unknown LIST return option"
@@ -309,10 +300,9 @@ func readReturnOption(dec *imapwire.Decoder, options *imap.ListOptions, recent *
 
 // ListWriter 写入 LIST 响应。
 type ListWriter struct {
-	conn         *Conn             // 连接对象
-	options      *imap.ListOptions // 列表选项
-	returnRecent bool              // 是否返回最近的邮件
-	lsub         bool              // 是否为 LSUB 命令
+	conn    *Conn             // 连接对象
+	options *imap.ListOptions // 列表选项
+	lsub    bool              // 是否为 LSUB 命令
 }
 
 // WriteList 写入单个邮箱的 LIST 响应。
@@ -332,7 +322,7 @@ func (w *ListWriter) WriteList(data *imap.ListData) error {
 		return err // 写入 LIST 响应时的错误
 	}
 	if w.options.ReturnStatus != nil && data.Status != nil {
-		if err := w.conn.writeStatus(data.Status, w.options.ReturnStatus, w.returnRecent); err != nil {
+		if err := w.conn.writeStatus(data.Status, w.options.ReturnStatus); err != nil {
 			return err // 写入状态时的错误
 		}
 	}
@@ -351,67 +341,5 @@ func (w *ListWriter) WriteList(data *imap.ListData) error {
 //
 //	如果匹配返回 true，否则返回 false。
 func MatchList(name string, delim rune, reference, pattern string) bool {
-	var delimStr string
-	if delim != 0 {
-		delimStr = string(delim)
-	}
-
-	if delimStr != "" && strings.HasPrefix(pattern, delimStr) {
-		reference = ""
-		pattern = strings.TrimPrefix(pattern, delimStr)
-	}
-	if reference != "" {
-		if delimStr != "" && !strings.HasSuffix(reference, delimStr) {
-			reference += delimStr
-		}
-		if !strings.HasPrefix(name, reference) {
-			return false
-		}
-		name = strings.TrimPrefix(name, reference)
-	}
-
-	return matchList(name, delimStr, pattern)
-}
-
-// matchList 检查名称是否与模式匹配。
-// 参数:
-//
-//	name - 邮箱名称。
-//	delim - 分隔符。
-//	pattern - 匹配模式。
-//
-// 返回值:
-//
-//	如果匹配返回 true，否则返回 false。
-func matchList(name, delim, pattern string) bool {
-	// TODO: 优化
-
-	i := strings.IndexAny(pattern, "*%")
-	if i == -1 {
-		// 没有更多的通配符
-		return name == pattern
-	}
-
-	// 获取通配符前后的部分
-	chunk, wildcard, rest := pattern[0:i], pattern[i], pattern[i+1:]
-
-	// 检查名称是否以 chunk 开头
-	if len(chunk) > 0 && !strings.HasPrefix(name, chunk) {
-		return false
-	}
-	name = strings.TrimPrefix(name, chunk)
-
-	// 展开通配符
-	var j int
-	for j = 0; j < len(name); j++ {
-		if wildcard == '%' && string(name[j]) == delim {
-			break // 如果通配符是 %，则在分隔符处停止
-		}
-		// 尝试从这里匹配其余部分
-		if matchList(name[j:], delim, rest) {
-			return true
-		}
-	}
-
-	return matchList(name[j:], delim, rest)
+	return internal.MatchList(name, delim, reference, pattern)
 }
@@ -0,0 +1,288 @@
+package imapserver
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/luhaoyun888/go-imap-cn"
+	"github.com/luhaoyun888/go-imap-cn/internal/imapwire"
+)
+
+// handleGetMetadata 处理 GETMETADATA 命令。
+//
+// 选项列表可以位于邮箱名称之前（RFC 5464 的语法）或之后（RFC 5464 的示例）。
+func (c *Conn) handleGetMetadata(tag string, dec *imapwire.Decoder) error {
+	var (
+		mailbox string
+		entries []string
+		options imap.GetMetadataOptions
+	)
+	if !dec.ExpectSP() {
+		return dec.Err()
+	}
+	if dec.Special('(') {
+		if err := readGetMetadataOptions(dec, "", &options); err != nil {
+			return err
+		}
+		if !dec.ExpectSP() {
+			return dec.Err()
+		}
+	}
+	if !dec.ExpectMailbox(&mailbox) || !dec.ExpectSP() {
+		return dec.Err()
+	}
+	if dec.Special('(') {
+		var name string
+		if !dec.ExpectAString(&name) {
+			return dec.Err()
+		}
+		switch strings.ToUpper(name) {
+		case "MAXSIZE", "DEPTH":
+			if err := readGetMetadataOptions(dec, name, &options); err != nil {
+				return err
+			}
+			if !dec.ExpectSP() {
+				return dec.Err()
+			}
+			var err error
+			if entries, err = readMetadataEntries(dec); err != nil {
+				return err
+			}
+		default:
+			entries = append(entries, name)
+			for dec.SP() {
+				if !dec.ExpectAString(&name) {
+					return dec.Err()
+				}
+				entries = append(entries, name)
+			}
+			if !dec.ExpectSpecial(')') {
+				return dec.Err()
+			}
+		}
+	} else {
+		var name string
+		if !dec.ExpectAString(&name) {
+			return dec.Err()
+		}
+		entries = append(entries, name)
+	}
+	if !dec.ExpectCRLF() {
+		return dec.Err()
+	}
+
+	for i, entry := range entries {
+		if !isValidMetadataEntry(entry) {
+			return newClientBugError(fmt.Sprintf("无效的元数据条目 %q", entry))
+		}
+		entries[i] = strings.ToLower(entry) // 条目名称不区分大小写
+	}
+
+	session, err := c.checkMetadata(mailbox)
+	if err != nil {
+		return err
+	}
+	values, err := session.GetMetadata(mailbox, entries, &options)
+	if err != nil {
+		return err
+	}
+
+	// 省略超过 MAXSIZE 的值，并在 OK 响应中返回其中最大的值的大小
+	var longEntries int
+	if options.MaxSize != nil {
+		for name, value := range values {
+			if value != nil && len(*value) > int(*options.MaxSize) {
+				delete(values, name)
+				if len(*value) > longEntries {
+					longEntries = len(*value)
+				}
+			}
+		}
+	}
+
+	if len(values) > 0 {
+		if err := c.writeMetadata(mailbox, values); err != nil {
+			return err
+		}
+	}
+
+	status := imap.StatusResponse{Type: imap.StatusResponseTypeOK, Text: "GETMETADATA 完成"}
+	if longEntries > 0 {
+		status.Code = imap.ResponseCode(fmt.Sprintf("%v LONGENTRIES %v", imap.ResponseCodeMetadata, longEntries))
+	}
+	return c.writeStatusResp(tag, &status)
+}
+
+// readGetMetadataOptions 读取 GETMETADATA 的选项列表，列表的 '(' 已被读取。
+// 如果 name 不为空，则它是已被读取的第一个选项的名称。
+func readGetMetadataOptions(dec *imapwire.Decoder, name string, options *imap.GetMetadataOptions) error {
+	for {
+		if name == "" && !dec.ExpectAtom(&name) {
+			return dec.Err()
+		}
+		if !dec.ExpectSP() {
+			return dec.Err()
+		}
+		switch strings.ToUpper(name) {
+		case "MAXSIZE":
+			var maxSize uint32
+			if !dec.ExpectNumber(&maxSize) {
+				return dec.Err()
+			}
+			options.MaxSize = &maxSize
+		case "DEPTH":
+			var depth string
+			if !dec.ExpectAtom(&depth) {
+				return dec.Err()
+			}
+			switch strings.ToLower(depth) {
+			case "0":
+				options.Depth = imap.GetMetadataDepthZero
+			case "1":
+				options.Depth = imap.GetMetadataDepthOne
+			case "infinity":
+				options.Depth = imap.GetMetadataDepthInfinity
+			default:
+				return newClientBugError("无效的 GETMETADATA 深度")
+			}
+		default:
+			return newClientBugError("未知的 GETMETADATA 选项")
+		}
+		name = ""
+		if !dec.SP() {
+			break
+		}
+	}
+	if !dec.ExpectSpecial(')') {
+		return dec.Err()
+	}
+	return nil
+}
+
+// readMetadataEntries 读取单个条目或条目列表。
+func readMetadataEntries(dec *imapwire.Decoder) ([]string, error) {
+	var entries []string
+	isList, err := dec.List(func() error {
+		var name string
+		if !dec.ExpectAString(&name) {
+			return dec.Err()
+		}
+		entries = append(entries, name)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	} else if !isList {
+		var name string
+		if !dec.ExpectAString(&name) {
+			return nil, dec.Err()
+		}
+		entries = append(entries, name)
+	}
+	return entries, nil
+}
+
+// handleSetMetadata 处理 SETMETADATA 命令。
+func (c *Conn) handleSetMetadata(dec *imapwire.Decoder) error {
+	var mailbox string
+	if !dec.ExpectSP() || !dec.ExpectMailbox(&mailbox) || !dec.ExpectSP() {
+		return dec.Err()
+	}
+	entries := make(map[string]*[]byte)
+	err := dec.ExpectList(func() error {
+		var name string
+		if !dec.ExpectAString(&name) || !dec.ExpectSP() {
+			return dec.Err()
+		}
+		if !isValidMetadataEntry(name) {
+			return newClientBugError(fmt.Sprintf("无效的元数据条目 %q", name))
+		}
+
+		var (
+			value *[]byte
+			b     []byte
+		)
+		if dec.StringBytes(&b) {
+			value = &b
+		} else if dec.Err() != nil {
+			return dec.Err()
+		} else if !dec.ExpectNIL() {
+			return dec.Err()
+		}
+		entries[strings.ToLower(name)] = value // 条目名称不区分大小写
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if !dec.ExpectCRLF() {
+		return dec.Err()
+	}
+
+	session, err := c.checkMetadata(mailbox)
+	if err != nil {
+		return err
+	}
+	return session.SetMetadata(mailbox, entries)
+}
+
+// writeMetadata 写入 METADATA 响应。条目按名称排序，保证响应稳定。
+func (c *Conn) writeMetadata(mailbox string, values map[string]*[]byte) error {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	enc := newResponseEncoder(c)
+	defer enc.end()
+	enc.Atom("*").SP().Atom("METADATA").SP().Mailbox(mailbox).SP()
+	enc.List(len(names), func(i int) {
+		enc.String(names[i]).SP()
+		if value := values[names[i]]; value != nil {
+			enc.String(string(*value))
+		} else {
+			enc.NIL()
+		}
+	})
+	return enc.CRLF()
+}
+
+// checkMetadata 检查 METADATA 能力和连接状态，并返回支持 METADATA 的会话。
+//
+// mailbox 为空字符串表示服务器元数据，METADATA-SERVER 或 METADATA 都可以访问；
+// 邮箱元数据要求 METADATA。
+func (c *Conn) checkMetadata(mailbox string) (SessionMetadata, error) {
+	if mailbox != "" || !c.server.options.caps().Has(imap.CapMetadataServer) {
+		if err := c.checkCap(imap.CapMetadata); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.checkState(imap.ConnStateAuthenticated); err != nil {
+		return nil, err
+	}
+	session, ok := c.session.(SessionMetadata)
+	if !ok {
+		return nil, newClientBugError("METADATA 不被支持")
+	}
+	return session, nil
+}
+
+// isValidMetadataEntry 检查元数据条目名称是否有效（RFC 5464 第 3.2 节）：
+// 以 /private/ 或 /shared/ 开头，不以 / 结尾，不包含连续的 /、通配符或控制字符。
+func isValidMetadataEntry(name string) bool {
+	lower := strings.ToLower(name)
+	if !strings.HasPrefix(lower, "/private/") && !strings.HasPrefix(lower, "/shared/") {
+		return false
+	}
+	if strings.HasSuffix(name, "/") || strings.Contains(name, "//") || strings.ContainsAny(name, "*%") {
+		return false
+	}
+	for _, ch := range name {
+		if ch < 0x20 || ch == 0x7f {
+			return false
+		}
+	}
+	return true
+}
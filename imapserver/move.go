@@ -13,6 +13,10 @@ import (
 //
 // 返回：错误信息，如果有的话
 func (c *Conn) handleMove(dec *imapwire.Decoder, numKind NumKind) error {
+	if err := c.checkCap(imap.CapMove); err != nil {
+		return err // 未宣告 MOVE
+	}
+
 	numSet, dest, err := readCopy(numKind, dec) // 读取移动的邮件编号和目标
 	if err != nil {
 		return err // 返回读取错误
@@ -22,6 +26,10 @@ func (c *Conn) handleMove(dec *imapwire.Decoder, numKind NumKind) error {
 	if err := c.checkState(imap.ConnStateSelected); err != nil {
 		return err // 返回状态检查错误
 	}
+	// MOVE 会从源邮箱删除邮件，因此源邮箱必须可写
+	if err := c.checkWritable(); err != nil {
+		return err
+	}
 
 	// 检查当前会话是否支持移动操作
 	session, ok := c.session.(SessionMove)
@@ -32,7 +40,7 @@ func (c *Conn) handleMove(dec *imapwire.Decoder, numKind NumKind) error {
 	// 创建 MoveWriter 实例
 	w := &MoveWriter{conn: c}
 	// 调用会话的 Move 方法进行移动操作
-	return session.Move(w, numSet, dest)
+	return c.sessionMove(session, w, numSet, dest)
 }
 
 // MoveWriter 用于写入 MOVE 命令的响应。
@@ -0,0 +1,146 @@
+package imapserver
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/luhaoyun888/go-imap-cn"
+	"github.com/luhaoyun888/go-imap-cn/internal/imapwire"
+)
+
+// handleESearch 处理 MULTISEARCH 的 ESEARCH 命令，参见 RFC 7377。
+func (c *Conn) handleESearch(tag string, dec *imapwire.Decoder) error {
+	if !dec.ExpectSP() {
+		return dec.Err()
+	}
+	var (
+		atom    string
+		sources []imap.MultiSearchSource
+		options imap.SearchOptions
+	)
+	if maybeReadSearchKeyAtom(dec, &atom) && strings.EqualFold(atom, "IN") {
+		var err error
+		if sources, err = readMultiSearchSources(dec); err != nil {
+			return fmt.Errorf("在 esearch-source-opts 中: %w", err)
+		}
+		if !dec.ExpectSP() {
+			return dec.Err()
+		}
+		atom = ""
+	}
+	criteria, _, err := readSearchArgs(dec, atom, &options)
+	if err != nil {
+		return err
+	}
+	if !dec.ExpectCRLF() {
+		return dec.Err()
+	}
+
+	if err := c.checkCap(imap.CapMultiSearch); err != nil {
+		return err
+	}
+	if err := c.checkState(imap.ConnStateAuthenticated); err != nil {
+		return err
+	}
+	if options.ReturnSave {
+		return newClientBugError("ESEARCH 命令不支持 SAVE 返回选项")
+	}
+
+	// 如果没有指定返回选项，默认为 ALL
+	if !options.ReturnMin && !options.ReturnMax && !options.ReturnAll && !options.ReturnCount {
+		options.ReturnAll = true
+	}
+	if len(sources) == 0 {
+		if c.state != imap.ConnStateSelected {
+			return newClientBugError("未选择邮箱时 ESEARCH 命令必须指定 IN 源选项")
+		}
+		sources = []imap.MultiSearchSource{{Filter: imap.MultiSearchFilterSelected}}
+	}
+
+	session, ok := c.session.(SessionMultiSearch)
+	if !ok {
+		return newClientBugError("不支持 MULTISEARCH")
+	}
+	results, err := session.MultiSearch(sources, criteria, &options)
+	if err != nil {
+		return err
+	}
+
+	for i := range results {
+		if err := c.writeMultiSearch(tag, &results[i], &options); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readMultiSearchSources 读取 IN 之后的源选项列表。
+func readMultiSearchSources(dec *imapwire.Decoder) ([]imap.MultiSearchSource, error) {
+	if !dec.ExpectSP() || !dec.ExpectSpecial('(') {
+		return nil, dec.Err()
+	}
+	var sources []imap.MultiSearchSource
+	for {
+		if dec.Special('(') {
+			return nil, newClientBugError("不支持 ESEARCH 范围选项")
+		}
+		var name string
+		if !dec.ExpectAtom(&name) {
+			return nil, dec.Err()
+		}
+		source := imap.MultiSearchSource{Filter: imap.MultiSearchFilter(strings.ToLower(name))}
+		switch source.Filter {
+		case imap.MultiSearchFilterSelected, imap.MultiSearchFilterInboxes, imap.MultiSearchFilterPersonal, imap.MultiSearchFilterSubscribed:
+			// 没有参数
+		case imap.MultiSearchFilterSubtree, imap.MultiSearchFilterSubtreeOne, imap.MultiSearchFilterMailboxes:
+			if !dec.ExpectSP() {
+				return nil, dec.Err()
+			}
+			isList, err := dec.List(func() error {
+				var mailbox string
+				if !dec.ExpectMailbox(&mailbox) {
+					return dec.Err()
+				}
+				source.Mailboxes = append(source.Mailboxes, mailbox)
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			} else if !isList {
+				var mailbox string
+				if !dec.ExpectMailbox(&mailbox) {
+					return nil, dec.Err()
+				}
+				source.Mailboxes = append(source.Mailboxes, mailbox)
+			}
+			if len(source.Mailboxes) == 0 {
+				return nil, newClientBugError("ESEARCH 源选项中的邮箱列表为空")
+			}
+		default:
+			return nil, newClientBugError(fmt.Sprintf("未知的 ESEARCH 邮箱过滤器 %q", name))
+		}
+		sources = append(sources, source)
+
+		if dec.Special(')') {
+			return sources, nil
+		} else if !dec.ExpectSP() {
+			return nil, dec.Err()
+		}
+	}
+}
+
+// writeMultiSearch 写入一个邮箱的 ESEARCH 响应，相关器中带有 MAILBOX 和 UIDVALIDITY。
+func (c *Conn) writeMultiSearch(tag string, data *imap.MultiSearchData, options *imap.SearchOptions) error {
+	enc := newResponseEncoder(c)
+	defer enc.end()
+
+	enc.Atom("*").SP().Atom("ESEARCH").SP().Special('(')
+	enc.Atom("TAG").SP().Atom(tag)
+	enc.SP().Atom("MAILBOX").SP().Mailbox(data.Mailbox)
+	enc.SP().Atom("UIDVALIDITY").SP().Number(data.UIDValidity)
+	enc.Special(')')
+	searchData := data.SearchData
+	searchData.UID = true // MULTISEARCH 的结果始终为 UID
+	writeESearchResults(enc.Encoder, &searchData, options)
+	return enc.CRLF()
+}
@@ -12,6 +12,10 @@ import (
 //
 // 返回：错误信息，如果有的话
 func (c *Conn) handleNamespace(dec *imapwire.Decoder) error {
+	if err := c.checkCap(imap.CapNamespace); err != nil {
+		return err // 未宣告 NAMESPACE
+	}
+
 	if !dec.ExpectCRLF() {
 		return dec.Err() // 检查是否以 CRLF 结束
 	}
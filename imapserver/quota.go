@@ -0,0 +1,150 @@
+package imapserver
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/luhaoyun888/go-imap-cn"
+	"github.com/luhaoyun888/go-imap-cn/internal/imapwire"
+)
+
+// handleGetQuota 处理 GETQUOTA 命令。
+func (c *Conn) handleGetQuota(dec *imapwire.Decoder) error {
+	var root string
+	if !dec.ExpectSP() || !dec.ExpectAString(&root) || !dec.ExpectCRLF() {
+		return dec.Err()
+	}
+
+	session, err := c.checkQuota()
+	if err != nil {
+		return err
+	}
+	data, err := session.GetQuota(root)
+	if err != nil {
+		return err
+	}
+	return c.writeQuota(data)
+}
+
+// handleGetQuotaRoot 处理 GETQUOTAROOT 命令。
+func (c *Conn) handleGetQuotaRoot(dec *imapwire.Decoder) error {
+	var mailbox string
+	if !dec.ExpectSP() || !dec.ExpectMailbox(&mailbox) || !dec.ExpectCRLF() {
+		return dec.Err()
+	}
+
+	session, err := c.checkQuota()
+	if err != nil {
+		return err
+	}
+	roots, err := session.GetQuotaRoot(mailbox)
+	if err != nil {
+		return err
+	}
+
+	// 先获取所有配额根的数据，避免在写入 QUOTAROOT 之后失败
+	l := make([]*imap.QuotaData, len(roots))
+	for i, root := range roots {
+		if l[i], err = session.GetQuota(root); err != nil {
+			return err
+		}
+	}
+
+	if err := c.writeQuotaRoot(mailbox, roots); err != nil {
+		return err
+	}
+	for _, data := range l {
+		if err := c.writeQuota(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handleSetQuota 处理 SETQUOTA 命令。
+func (c *Conn) handleSetQuota(dec *imapwire.Decoder) error {
+	var root string
+	if !dec.ExpectSP() || !dec.ExpectAString(&root) || !dec.ExpectSP() {
+		return dec.Err()
+	}
+	limits := make(map[imap.QuotaResourceType]int64)
+	err := dec.ExpectList(func() error {
+		var (
+			name  string
+			limit int64
+		)
+		if !dec.ExpectAtom(&name) || !dec.ExpectSP() || !dec.ExpectNumber64(&limit) {
+			return dec.Err()
+		}
+		limits[imap.QuotaResourceType(strings.ToUpper(name))] = limit
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if !dec.ExpectCRLF() {
+		return dec.Err()
+	}
+
+	if err := c.checkCap(imap.CapQuotaSet); err != nil {
+		return err
+	}
+	session, err := c.checkQuota()
+	if err != nil {
+		return err
+	}
+	if err := session.SetQuota(root, limits); err != nil {
+		return err
+	}
+
+	// 返回设置之后的配额
+	data, err := session.GetQuota(root)
+	if err != nil {
+		return err
+	}
+	return c.writeQuota(data)
+}
+
+// writeQuota 写入 QUOTA 响应。资源按名称排序，保证响应稳定。
+func (c *Conn) writeQuota(data *imap.QuotaData) error {
+	names := make([]string, 0, len(data.Resources))
+	for name := range data.Resources {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+
+	enc := newResponseEncoder(c)
+	defer enc.end()
+	enc.Atom("*").SP().Atom("QUOTA").SP().String(data.Root).SP()
+	enc.List(len(names), func(i int) {
+		res := data.Resources[imap.QuotaResourceType(names[i])]
+		enc.Atom(names[i]).SP().Number64(res.Usage).SP().Number64(res.Limit)
+	})
+	return enc.CRLF()
+}
+
+// writeQuotaRoot 写入 QUOTAROOT 响应。
+func (c *Conn) writeQuotaRoot(mailbox string, roots []string) error {
+	enc := newResponseEncoder(c)
+	defer enc.end()
+	enc.Atom("*").SP().Atom("QUOTAROOT").SP().Mailbox(mailbox)
+	for _, root := range roots {
+		enc.SP().String(root)
+	}
+	return enc.CRLF()
+}
+
+// checkQuota 检查 QUOTA 能力和连接状态，并返回支持 QUOTA 的会话。
+func (c *Conn) checkQuota() (SessionQuota, error) {
+	if err := c.checkCap(imap.CapQuota); err != nil {
+		return nil, err
+	}
+	if err := c.checkState(imap.ConnStateAuthenticated); err != nil {
+		return nil, err
+	}
+	session, ok := c.session.(SessionQuota)
+	if !ok {
+		return nil, newClientBugError("QUOTA 不被支持")
+	}
+	return session, nil
+}
@@ -18,17 +18,53 @@ func (c *Conn) handleSearch(tag string, dec *imapwire.Decoder, numKind NumKind)
 	if !dec.ExpectSP() {
 		return dec.Err()
 	}
-	var (
-		atom     string
-		options  imap.SearchOptions
-		extended bool
-	)
-	if maybeReadSearchKeyAtom(dec, &atom) && strings.EqualFold(atom, "RETURN") {
-		if err := readSearchReturnOpts(dec, &options); err != nil {
-			return fmt.Errorf("在 search-return-opts 中: %w", err)
+	var options imap.SearchOptions
+	criteria, extended, err := readSearchArgs(dec, "", &options)
+	if err != nil {
+		return err
+	}
+	if !dec.ExpectCRLF() {
+		return dec.Err()
+	}
+
+	if err := c.checkState(imap.ConnStateSelected); err != nil {
+		return err
+	}
+
+	// 如果没有指定返回选项，默认为 ALL；只指定 SAVE 时只保存结果
+	noReturn := !options.ReturnMin && !options.ReturnMax && !options.ReturnAll && !options.ReturnCount
+	saveOnly := noReturn && options.ReturnSave
+	if noReturn && !saveOnly {
+		options.ReturnAll = true
+	}
+
+	data, err := c.sessionSearch(numKind, criteria, &options)
+	if err != nil {
+		return err
+	}
+	if saveOnly {
+		return nil // 只保存结果时不返回 ESEARCH 响应（RFC 5182）
+	}
+
+	if c.enabled.Has(imap.CapIMAP4rev2) || extended {
+		return c.writeESearch(tag, data, &options)
+	} else {
+		return c.writeSearch(data.All, data.ModSeq)
+	}
+}
+
+// readSearchArgs 读取 SEARCH 命令的返回选项、字符集和搜索键。
+// atom 是调用者已经读取的第一个原子（如果有的话）。extended 表示是否指定了返回选项。
+func readSearchArgs(dec *imapwire.Decoder, atom string, options *imap.SearchOptions) (criteria *imap.SearchCriteria, extended bool, err error) {
+	if atom == "" {
+		maybeReadSearchKeyAtom(dec, &atom)
+	}
+	if strings.EqualFold(atom, "RETURN") {
+		if err := readSearchReturnOpts(dec, options); err != nil {
+			return nil, false, fmt.Errorf("在 search-return-opts 中: %w", err)
 		}
 		if !dec.ExpectSP() {
-			return dec.Err()
+			return nil, false, dec.Err()
 		}
 		extended = true
 		atom = ""
@@ -37,62 +73,46 @@ func (c *Conn) handleSearch(tag string, dec *imapwire.Decoder, numKind NumKind)
 	if strings.EqualFold(atom, "CHARSET") {
 		var charset string
 		if !dec.ExpectSP() || !dec.ExpectAString(&charset) || !dec.ExpectSP() {
-			return dec.Err()
+			return nil, false, dec.Err()
 		}
-		switch strings.ToUpper(charset) {
-		case "US-ASCII", "UTF-8":
-			// 不需要做任何操作
-		default:
-			return &imap.Error{
-				Type: imap.StatusResponseTypeNo,
-				Code: imap.ResponseCodeBadCharset, // TODO: 返回支持的字符集列表
-				Text: "只支持 US-ASCII 和 UTF-8 作为搜索字符集",
-			}
+		if err := checkSearchCharset(charset); err != nil {
+			return nil, false, err
 		}
 		atom = ""
 		maybeReadSearchKeyAtom(dec, &atom)
 	}
 
-	var criteria imap.SearchCriteria
+	criteria = new(imap.SearchCriteria)
 	for {
 		var err error
 		if atom != "" {
-			err = readSearchKeyWithAtom(&criteria, dec, atom)
+			err = readSearchKeyWithAtom(criteria, dec, atom)
 			atom = ""
 		} else {
-			err = readSearchKey(&criteria, dec)
+			err = readSearchKey(criteria, dec)
 		}
 		if err != nil {
-			return fmt.Errorf("在 search-key 中: %w", err)
+			return nil, false, fmt.Errorf("在 search-key 中: %w", err)
 		}
 
 		if !dec.SP() {
 			break
 		}
 	}
+	return criteria, extended, nil
+}
 
-	if !dec.ExpectCRLF() {
-		return dec.Err()
-	}
-
-	if err := c.checkState(imap.ConnStateSelected); err != nil {
-		return err
-	}
-
-	// 如果没有指定返回选项，默认为 ALL
-	if !options.ReturnMin && !options.ReturnMax && !options.ReturnAll && !options.ReturnCount {
-		options.ReturnAll = true
-	}
-
-	data, err := c.session.Search(numKind, &criteria, &options)
-	if err != nil {
-		return err
-	}
-
-	if c.enabled.Has(imap.CapIMAP4rev2) || extended {
-		return c.writeESearch(tag, data, &options)
-	} else {
-		return c.writeSearch(data.All)
+// checkSearchCharset 检查是否支持搜索字符集。
+func checkSearchCharset(charset string) error {
+	switch strings.ToUpper(charset) {
+	case "US-ASCII", "UTF-8":
+		return nil
+	default:
+		return &imap.Error{
+			Type: imap.StatusResponseTypeNo,
+			Code: imap.ResponseCodeBadCharset, // TODO: 返回支持的字符集列表
+			Text: "只支持 US-ASCII 和 UTF-8 作为搜索字符集",
+		}
 	}
 }
 
@@ -108,6 +128,12 @@ func (c *Conn) writeESearch(tag string, data *imap.SearchData, options *imap.Sea
 	if tag != "" {
 		enc.SP().Special('(').Atom("TAG").SP().Atom(tag).Special(')')
 	}
+	writeESearchResults(enc.Encoder, data, options)
+	return enc.CRLF()
+}
+
+// writeESearchResults 写入 ESEARCH 响应中相关器之后的搜索结果。
+func writeESearchResults(enc *imapwire.Encoder, data *imap.SearchData, options *imap.SearchOptions) {
 	if data.UID {
 		enc.SP().Atom("UID")
 	}
@@ -124,7 +150,9 @@ func (c *Conn) writeESearch(tag string, data *imap.SearchData, options *imap.Sea
 	if options.ReturnCount {
 		enc.SP().Atom("COUNT").SP().Number(data.Count)
 	}
-	return enc.CRLF()
+	if data.ModSeq != 0 {
+		enc.SP().Atom("MODSEQ").SP().ModSeq(data.ModSeq)
+	}
 }
 
 // isNumSetEmpty 检查数字集合是否为空。
@@ -141,7 +169,8 @@ func isNumSetEmpty(numSet imap.NumSet) bool {
 
 // writeSearch 写入搜索响应。
 // numSet: 包含搜索结果的数字集合。
-func (c *Conn) writeSearch(numSet imap.NumSet) error {
+// modSeq: 结果中最大的修改序列号，为零时不写出。
+func (c *Conn) writeSearch(numSet imap.NumSet, modSeq uint64) error {
 	enc := newResponseEncoder(c)
 	defer enc.end()
 
@@ -164,6 +193,9 @@ func (c *Conn) writeSearch(numSet imap.NumSet) error {
 	if !ok {
 		return fmt.Errorf("imapserver: 在 SEARCH 响应中枚举消息编号失败")
 	}
+	if modSeq != 0 {
+		enc.SP().Special('(').Atom("MODSEQ").SP().ModSeq(modSeq).Special(')')
+	}
 	return enc.CRLF()
 }
 
@@ -314,7 +346,7 @@ func readSearchKeyWithAtom(criteria *imap.SearchCriteria, dec *imapwire.Decoder,
 			return dec.Err()
 		}
 		criteria.Text = append(criteria.Text, text)
-	case "LARGER", "SMALLER":
+	case "LARGER", "SMALLER", "YOUNGER", "OLDER":
 		var n int64
 		if !dec.ExpectSP() || !dec.ExpectNumber64(&n) {
 			return dec.Err()
@@ -324,6 +356,10 @@ func readSearchKeyWithAtom(criteria *imap.SearchCriteria, dec *imapwire.Decoder,
 			criteria.And(&imap.SearchCriteria{Larger: n})
 		case "SMALLER":
 			criteria.And(&imap.SearchCriteria{Smaller: n})
+		case "YOUNGER":
+			criteria.And(&imap.SearchCriteria{Younger: n})
+		case "OLDER":
+			criteria.And(&imap.SearchCriteria{Older: n})
 		}
 	case "NOT":
 		if !dec.ExpectSP() {
@@ -349,6 +385,39 @@ func readSearchKeyWithAtom(criteria *imap.SearchCriteria, dec *imapwire.Decoder,
 			return dec.Err()
 		}
 		criteria.Or = append(criteria.Or, or)
+	case "MODSEQ":
+		var modSeq imap.SearchCriteriaModSeq
+		if !dec.ExpectSP() {
+			return dec.Err()
+		}
+		if dec.String(&modSeq.MetadataName) {
+			var typ string
+			if !dec.ExpectSP() || !dec.ExpectAtom(&typ) || !dec.ExpectSP() {
+				return dec.Err()
+			}
+			modSeq.MetadataType = imap.SearchCriteriaMetadataType(strings.ToLower(typ))
+			switch modSeq.MetadataType {
+			case imap.SearchCriteriaMetadataAll, imap.SearchCriteriaMetadataPrivate, imap.SearchCriteriaMetadataShared:
+				// 有效的元数据类型
+			default:
+				return newClientBugError("未知的 MODSEQ 元数据类型")
+			}
+		}
+		if !dec.ExpectModSeq(&modSeq.ModSeq) {
+			return dec.Err()
+		}
+		criteria.And(&imap.SearchCriteria{ModSeq: &modSeq})
+	case "EMAILID", "THREADID":
+		var id string
+		if !dec.ExpectSP() || !dec.ExpectAtom(&id) {
+			return dec.Err()
+		}
+		switch key {
+		case "EMAILID":
+			criteria.EmailID = append(criteria.EmailID, id)
+		case "THREADID":
+			criteria.ThreadID = append(criteria.ThreadID, id)
+		}
 	case "$":
 		criteria.UID = append(criteria.UID, imap.SearchRes())
 	default:
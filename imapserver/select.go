@@ -2,6 +2,7 @@ package imapserver
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/luhaoyun888/go-imap-cn"
 	"github.com/luhaoyun888/go-imap-cn/internal/imapwire"
@@ -13,7 +14,17 @@ import (
 // readOnly: 指示选择的邮箱是否为只读模式。
 func (c *Conn) handleSelect(tag string, dec *imapwire.Decoder, readOnly bool) error {
 	var mailbox string
-	if !dec.ExpectSP() || !dec.ExpectMailbox(&mailbox) || !dec.ExpectCRLF() {
+	if !dec.ExpectSP() || !dec.ExpectMailbox(&mailbox) {
+		return dec.Err()
+	}
+	// 设置选择选项。
+	options := imap.SelectOptions{ReadOnly: readOnly}
+	if dec.SP() {
+		if err := c.readSelectParams(dec, &options); err != nil {
+			return err
+		}
+	}
+	if !dec.ExpectCRLF() {
 		return dec.Err()
 	}
 
@@ -38,9 +49,7 @@ func (c *Conn) handleSelect(tag string, dec *imapwire.Decoder, readOnly bool) er
 		}
 	}
 
-	// 设置选择选项。
-	options := imap.SelectOptions{ReadOnly: readOnly}
-	data, err := c.session.Select(mailbox, &options)
+	data, err := c.sessionSelect(mailbox, &options)
 	if err != nil {
 		return err
 	}
@@ -51,7 +60,7 @@ func (c *Conn) handleSelect(tag string, dec *imapwire.Decoder, readOnly bool) er
 	}
 	// 如果不支持 IMAP4rev2，写入过时的 RECENT。
 	if !c.enabled.Has(imap.CapIMAP4rev2) {
-		if err := c.writeObsoleteRecent(); err != nil {
+		if err := c.writeObsoleteRecent(data.NumRecent); err != nil {
 			return err
 		}
 	}
@@ -71,6 +80,12 @@ func (c *Conn) handleSelect(tag string, dec *imapwire.Decoder, readOnly bool) er
 	if err := c.writePermanentFlags(data.PermanentFlags); err != nil {
 		return err
 	}
+	// 如果支持 CONDSTORE，写入最高的修改序列号。
+	if c.server.options.caps().Has(imap.CapCondStore) {
+		if err := c.writeHighestModSeq(data.HighestModSeq); err != nil {
+			return err
+		}
+	}
 	// 如果有列表数据，写入列表。
 	if data.List != nil {
 		if err := c.writeList(data.List); err != nil {
@@ -79,7 +94,15 @@ func (c *Conn) handleSelect(tag string, dec *imapwire.Decoder, readOnly bool) er
 	}
 
 	c.state = imap.ConnStateSelected
-	// TODO: 在只读模式下禁止写命令
+	c.mailbox = mailbox
+	c.readOnly = readOnly || data.ReadOnly
+
+	// UIDVALIDITY 不变时，返回客户端缓存之后的变化
+	if qresync := options.QResync; qresync != nil && qresync.UIDValidity == data.UIDValidity {
+		if err := c.writeQResyncChanges(qresync); err != nil {
+			return err
+		}
+	}
 
 	var (
 		cmdName string
@@ -87,9 +110,12 @@ func (c *Conn) handleSelect(tag string, dec *imapwire.Decoder, readOnly bool) er
 	)
 	if readOnly {
 		cmdName = "EXAMINE"
-		code = "READ-ONLY"
 	} else {
 		cmdName = "SELECT"
+	}
+	if c.readOnly {
+		code = "READ-ONLY"
+	} else {
 		code = "READ-WRITE"
 	}
 	return c.writeStatusResp(tag, &imap.StatusResponse{
@@ -99,6 +125,95 @@ func (c *Conn) handleSelect(tag string, dec *imapwire.Decoder, readOnly bool) er
 	})
 }
 
+// readSelectParams 读取 SELECT 和 EXAMINE 命令的参数列表。
+func (c *Conn) readSelectParams(dec *imapwire.Decoder, options *imap.SelectOptions) error {
+	return dec.ExpectList(func() error {
+		var name string
+		if !dec.ExpectAtom(&name) {
+			return dec.Err()
+		}
+		switch strings.ToUpper(name) {
+		case "CONDSTORE":
+			if err := c.checkCap(imap.CapCondStore); err != nil {
+				return err
+			}
+			options.CondStore = true
+			// SELECT (CONDSTORE) 会为连接启用 CONDSTORE
+			c.mutex.Lock()
+			c.enabled[imap.CapCondStore] = struct{}{}
+			c.mutex.Unlock()
+		case "QRESYNC":
+			if !c.enabled.Has(imap.CapQResync) {
+				return newClientBugError("必须先启用 QRESYNC")
+			}
+			var qresync imap.QResyncOptions
+			if !dec.ExpectSP() || !dec.ExpectSpecial('(') || !dec.ExpectNumber(&qresync.UIDValidity) || !dec.ExpectSP() || !dec.ExpectModSeq(&qresync.ModSeq) {
+				return dec.Err()
+			}
+			seqMatch := false
+			if dec.SP() {
+				if dec.Special('(') {
+					seqMatch = true
+				} else {
+					if !dec.ExpectUIDSet(&qresync.KnownUIDs) {
+						return dec.Err()
+					}
+					if dec.SP() {
+						if !dec.ExpectSpecial('(') {
+							return dec.Err()
+						}
+						seqMatch = true
+					}
+				}
+			}
+			if seqMatch {
+				var (
+					seqNums imap.NumSet
+					uids    imap.UIDSet
+				)
+				if !dec.ExpectNumSet(imapwire.NumKindSeq, &seqNums) || !dec.ExpectSP() || !dec.ExpectUIDSet(&uids) || !dec.ExpectSpecial(')') {
+					return dec.Err()
+				}
+				qresync.SeqMatch = &imap.QResyncSeqMatch{SeqNums: seqNums.(imap.SeqSet), UIDs: uids}
+			}
+			if !dec.ExpectSpecial(')') {
+				return dec.Err()
+			}
+			options.QResync = &qresync
+		default:
+			return newClientBugError("未知的 SELECT 参数")
+		}
+		return nil
+	})
+}
+
+// writeQResyncChanges 写入客户端缓存之后的变化：已删除邮件的 VANISHED (EARLIER)
+// 响应，以及修改序列号大于 options.ModSeq 的邮件的 FETCH 响应。
+func (c *Conn) writeQResyncChanges(options *imap.QResyncOptions) error {
+	uids := options.KnownUIDs
+	if len(uids) == 0 {
+		uids = imap.UIDSet{imap.UIDRange{Start: 1, Stop: 0}} // 1:*
+	}
+
+	vanished, err := c.session.(SessionQResync).Vanished(uids, options.ModSeq)
+	if err != nil {
+		return err
+	}
+	if len(vanished) > 0 {
+		if err := c.writeVanished(vanished, true); err != nil {
+			return err
+		}
+	}
+
+	w := &FetchWriter{conn: c}
+	return c.sessionFetch(w, uids, &imap.FetchOptions{
+		UID:          true,
+		Flags:        true,
+		ModSeq:       true,
+		ChangedSince: options.ModSeq,
+	})
+}
+
 // handleUnselect 处理 UNSELECT 命令，取消当前选择的邮箱。
 // dec: 解码器，用于解析输入数据。
 // expunge: 指示是否在取消选择时清除已删除邮件。
@@ -112,10 +227,10 @@ func (c *Conn) handleUnselect(dec *imapwire.Decoder, expunge bool) error {
 		return err
 	}
 
-	// 如果需要，清除已删除邮件。
-	if expunge {
+	// 如果需要，清除已删除邮件。只读邮箱的 CLOSE 不会删除邮件。
+	if expunge && !c.readOnly {
 		w := &ExpungeWriter{}
-		if err := c.session.Expunge(w, nil); err != nil {
+		if err := c.sessionExpunge(w, nil); err != nil {
 			return err
 		}
 	}
@@ -137,12 +252,12 @@ func (c *Conn) writeExists(numMessages uint32) error {
 	return enc.Atom("*").SP().Number(numMessages).SP().Atom("EXISTS").CRLF()
 }
 
-// writeObsoleteRecent 写入过时的 RECENT 响应，表示没有最近的邮件。
-// 返回值：无。
-func (c *Conn) writeObsoleteRecent() error {
+// writeObsoleteRecent 写入过时的 RECENT 响应。
+// n: 带有 \Recent 标志的邮件数量。
+func (c *Conn) writeObsoleteRecent(n uint32) error {
 	enc := newResponseEncoder(c)
 	defer enc.end()
-	return enc.Atom("*").SP().Number(0).SP().Atom("RECENT").CRLF()
+	return enc.Atom("*").SP().Number(n).SP().Atom("RECENT").CRLF()
 }
 
 // writeUIDValidity 写入 UID 有效性。
@@ -190,3 +305,19 @@ func (c *Conn) writePermanentFlags(flags []imap.Flag) error {
 	enc.SP().Text("永久标志")
 	return enc.CRLF()
 }
+
+// writeHighestModSeq 写入邮箱的最高修改序列号。
+// highestModSeq: 最高修改序列号，为零时表示邮箱不支持持久的修改序列号。
+func (c *Conn) writeHighestModSeq(highestModSeq uint64) error {
+	enc := newResponseEncoder(c)
+	defer enc.end()
+	enc.Atom("*").SP().Atom("OK").SP()
+	if highestModSeq == 0 {
+		enc.Special('[').Atom("NOMODSEQ").Special(']')
+		enc.SP().Text("不支持修改序列号")
+	} else {
+		enc.Special('[').Atom("HIGHESTMODSEQ").SP().ModSeq(highestModSeq).Special(']')
+		enc.SP().Text("最高的修改序列号")
+	}
+	return enc.CRLF()
+}
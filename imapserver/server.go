@@ -2,7 +2,9 @@
 package imapserver
 
 import (
+	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"io"
@@ -16,6 +18,9 @@ import (
 
 var errClosed = errors.New("imapserver: 服务器已关闭")
 
+// shutdownPollInterval 是 Shutdown 检查连接是否全部关闭的间隔。
+const shutdownPollInterval = 50 * time.Millisecond
+
 // Logger 是一个记录错误信息的工具。
 type Logger interface {
 	Printf(format string, args ...interface{})
@@ -43,11 +48,66 @@ type Options struct {
 	Logger Logger
 	// TLSConfig 是用于 STARTTLS 的 TLS 配置。如果为 nil，则禁用 STARTTLS。
 	TLSConfig *tls.Config
+	// ClientCAs 用于验证客户端证书。如果不为 nil，STARTTLS 和 ListenAndServeTLS
+	// 会请求客户端证书（除非 TLSConfig.ClientAuth 另有设置），实现了
+	// SessionExternal 的会话可以通过 SASL EXTERNAL 机制使用已验证的证书登录。
+	ClientCAs *x509.CertPool
+	// ReadLimit 和 WriteLimit 限制每个连接的读取和写入速率（字节/秒），
+	// 包括字面量的传输。超出限制时传输会被平滑地减速，而不会断开连接。
+	// 为零时不限制。
+	ReadLimit, WriteLimit int
+	// AppendLimit 是 APPEND 命令接受的最大邮件大小（字节）。如果不为 nil，
+	// 服务器会广告 APPENDLIMIT=n（RFC 7889），超出限制的 APPEND 会收到
+	// NO [TOOBIG] 响应。为 nil 时限制为 100MiB 且不广告。
+	//
+	// 如果各邮箱的限制不同，应保持为 nil 并在 Caps 中加入 APPENDLIMIT，
+	// 由会话在 STATUS 中返回每个邮箱的限制。
+	AppendLimit *uint32
+	// MaxCommandSize 是单条命令的最大字节数，包括其中的字面量。超出限制的命令
+	// 会收到 NO [TOOBIG] 响应，其余部分被丢弃，连接仍可继续使用。为零时不限制。
+	MaxCommandSize int64
+	// MaxLiteralSize 是需要完整缓冲在内存中的字面量（例如 SEARCH 的字符串参数）
+	// 的最大字节数，超出时返回 NO [TOOBIG]。为零时使用 1MiB。
+	//
+	// APPEND 的邮件内容以流式字面量传递给会话，不受此限制，而由 AppendLimit 限制。
+	MaxLiteralSize int64
+	// MaxNonSyncLiteralSize 是未广告 LITERAL+ 时非同步字面量的最大字节数。
+	// 为零时使用 LITERAL- 规定的 4096 字节（RFC 7888）。
+	MaxNonSyncLiteralSize int64
+	// MaxIdleDuration 是一次 IDLE 命令的最长时长。超时后服务器发送 BYE 并关闭连接。
+	// 为零时使用 35 分钟的默认读取超时（RFC 9051 第 5.4 节要求至少 30 分钟）。
+	MaxIdleDuration time.Duration
+	// Hostnames 是本服务器的主机名。CATENATE 中的绝对 IMAP URL（imap://host/...）
+	// 只有在主机名属于此列表时才会被解析，否则返回 NO [BADURL]。
+	// 为空时只接受以 "/" 开头、不带授权部分的 URL。
+	Hostnames []string
 	// InsecureAuth 允许客户端在没有 TLS 的情况下进行身份验证。在这种模式下，服务器容易受到中间人攻击。
 	InsecureAuth bool
+	// TCPKeepAlive 是连接的 TCP keepalive 周期，用于检测长时间 IDLE 的死连接。
+	// 大于零时启用 keepalive 并使用该周期，小于零时禁用，为零时保持监听器的默认设置。
+	// 只对 TCP 连接（包括其上的 TLS 连接）生效。
+	TCPKeepAlive time.Duration
 	// 原始输入和输出数据将写入此写入器（如果有的话）。
 	// 请注意，这可能包含敏感信息，例如身份验证期间使用的凭据。
 	DebugWriter io.Writer
+
+	// MaxConnections 是同时处理的最大连接数。达到上限后，新连接会收到
+	// BYE [LIMIT] 响应并被关闭。为零时不限制。
+	MaxConnections int
+	// ConnLimitFunc 在接受连接时被调用（如果有的话）。如果返回 true，则来自
+	// addr 的连接已达上限，服务器发送 BYE [LIMIT] 响应并关闭连接。
+	//
+	// 例如，可以在 OnSessionStart 和 OnSessionEnd 中按 IP 统计活动连接，
+	// 以实现按 IP 的连接数限制。
+	ConnLimitFunc func(addr net.Addr) bool
+
+	// OnSessionStart 在会话成功创建后被调用（如果有的话）。
+	OnSessionStart func(*Conn, Session)
+	// OnSessionEnd 在会话结束时被调用（如果有的话）。
+	//
+	// 如果客户端正常登出，err 为 nil；否则 err 描述了连接结束的原因，
+	// 例如客户端异常断开。只有在 OnSessionStart 被调用之后才会调用此函数。
+	OnSessionEnd func(*Conn, error)
 }
 
 // wrapReadWriter 包装给定的读写器，如果 DebugWriter 不为 nil，则会将调试信息写入 DebugWriter。
@@ -64,6 +124,44 @@ func (options *Options) wrapReadWriter(rw io.ReadWriter) io.ReadWriter {
 	}
 }
 
+// tlsConfig 返回 STARTTLS 和 ListenAndServeTLS 使用的 TLS 配置，
+// 其中包含 ClientCAs。
+func (options *Options) tlsConfig() *tls.Config {
+	if options.TLSConfig == nil || options.ClientCAs == nil {
+		return options.TLSConfig
+	}
+	tlsConfig := options.TLSConfig.Clone()
+	tlsConfig.ClientCAs = options.ClientCAs
+	if tlsConfig.ClientAuth == tls.NoClientCert {
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven // 客户端证书是可选的
+	}
+	return tlsConfig
+}
+
+// appendLimit 返回 APPEND 接受的最大邮件大小。
+func (options *Options) appendLimit() int64 {
+	if options.AppendLimit != nil {
+		return int64(*options.AppendLimit)
+	}
+	return defaultAppendLimit
+}
+
+// maxLiteralSize 返回缓冲字面量的最大大小。
+func (options *Options) maxLiteralSize() int64 {
+	if options.MaxLiteralSize > 0 {
+		return options.MaxLiteralSize
+	}
+	return defaultMaxLiteralSize
+}
+
+// maxNonSyncLiteralSize 返回未广告 LITERAL+ 时非同步字面量的最大大小。
+func (options *Options) maxNonSyncLiteralSize() int64 {
+	if options.MaxNonSyncLiteralSize > 0 {
+		return options.MaxNonSyncLiteralSize
+	}
+	return 4096
+}
+
 // caps 返回服务器的能力集。如果未设置 Caps，则默认返回只支持 IMAP4rev1 的能力集。
 func (options *Options) caps() imap.CapSet {
 	if options.Caps != nil {
@@ -72,6 +170,9 @@ func (options *Options) caps() imap.CapSet {
 	return imap.CapSet{imap.CapIMAP4rev1: {}}
 }
 
+// defaultMaxLiteralSize 是未设置 Options.MaxLiteralSize 时缓冲字面量的最大大小。
+const defaultMaxLiteralSize = 1024 * 1024 // 1MiB
+
 // Server 是一个 IMAP 服务器。
 type Server struct {
 	options Options
@@ -174,7 +275,7 @@ func (s *Server) ListenAndServeTLS(addr string) error {
 	if addr == "" {
 		addr = ":993"
 	}
-	ln, err := tls.Listen("tcp", addr, s.options.TLSConfig)
+	ln, err := tls.Listen("tcp", addr, s.options.tlsConfig())
 	if err != nil {
 		return err
 	}
@@ -187,10 +288,63 @@ func (s *Server) ListenAndServeTLS(addr string) error {
 //
 // 一旦对服务器调用 Close，就不能再重用；对 Serve 等方法的未来调用将返回错误。
 func (s *Server) Close() error {
-	var err error
+	ok, err := s.closeListeners()
+	if !ok {
+		return errClosed
+	}
+
+	s.closeConns()
+	return err
+}
+
+// Shutdown 优雅地关闭服务器。
+//
+// Shutdown 首先关闭所有活动的监听器，然后向每个连接发送 BYE 响应并关闭它们。
+// 正在处理命令的连接会在当前命令完成后再关闭。Shutdown 会等待所有连接关闭；
+// 如果 ctx 在此之前结束，则强制关闭剩余的连接并返回 ctx 的错误。
+//
+// 与 Close 一样，一旦调用 Shutdown，服务器就不能再重用。
+func (s *Server) Shutdown(ctx context.Context) error {
+	ok, err := s.closeListeners()
+	if !ok {
+		return errClosed
+	}
 
 	s.mutex.Lock()
-	ok := !s.closed
+	conns := make([]*Conn, 0, len(s.conns))
+	for c := range s.conns {
+		conns = append(conns, c)
+	}
+	s.mutex.Unlock()
+	for _, c := range conns {
+		c.shutdown()
+	}
+
+	ticker := time.NewTicker(shutdownPollInterval)
+	defer ticker.Stop()
+	for {
+		s.mutex.Lock()
+		n := len(s.conns)
+		s.mutex.Unlock()
+		if n == 0 {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			s.closeConns()
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// closeListeners 将服务器标记为已关闭，关闭所有监听器并等待 Serve 返回。
+//
+// 如果服务器已经关闭，ok 为 false。
+func (s *Server) closeListeners() (ok bool, err error) {
+	s.mutex.Lock()
+	ok = !s.closed
 	if ok {
 		s.closed = true
 		for l := range s.listeners {
@@ -201,18 +355,20 @@ func (s *Server) Close() error {
 	}
 	s.mutex.Unlock()
 	if !ok {
-		return errClosed
+		return false, nil
 	}
 
 	s.listenerWaitGroup.Wait()
+	return true, err
+}
 
+// closeConns 立即关闭所有活动的连接。
+func (s *Server) closeConns() {
 	s.mutex.Lock()
 	for c := range s.conns {
 		c.mutex.Lock()
-		c.conn.Close()
+		c.closeNetConn()
 		c.mutex.Unlock()
 	}
 	s.mutex.Unlock()
-
-	return err
 }
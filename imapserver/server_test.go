@@ -0,0 +1,1777 @@
+package imapserver_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/luhaoyun888/go-imap-cn"
+	"github.com/luhaoyun888/go-imap-cn/imapclient"
+	"github.com/luhaoyun888/go-imap-cn/imapserver"
+	"github.com/luhaoyun888/go-imap-cn/imapserver/imapmemserver"
+)
+
+const (
+	testUsername = "test-user"
+	testPassword = "test-password"
+)
+
+// newTestServer 使用内存后端创建并启动一个服务器，返回服务器和监听地址。
+//
+// options.NewSession 和 InsecureAuth 会被覆盖。如果 options.Caps 为 nil，
+// 则使用 IMAP4rev1 和 IMAP4rev2。
+func newTestServer(t *testing.T, options *imapserver.Options) (*imapserver.Server, string) {
+	memServer := imapmemserver.New() // 创建一个内存 IMAP 服务器
+
+	user := imapmemserver.NewUser(testUsername, testPassword) // 创建用户
+	user.Create("INBOX", nil)                                 // 创建 INBOX 文件夹
+	memServer.AddUser(user)
+
+	options.NewSession = func(conn *imapserver.Conn) (imapserver.Session, *imapserver.GreetingData, error) {
+		return memServer.NewSession(), nil, nil
+	}
+	if options.Caps == nil {
+		options.Caps = imap.CapSet{imap.CapIMAP4rev1: {}, imap.CapIMAP4rev2: {}}
+	}
+	options.InsecureAuth = true
+	server := imapserver.New(options)
+
+	ln, err := net.Listen("tcp", "localhost:0") // 监听本地端口
+	if err != nil {
+		t.Fatalf("net.Listen() = %v", err)
+	}
+	go server.Serve(ln)
+
+	return server, ln.Addr().String()
+}
+
+// TestServer_sessionLifecycle 测试 OnSessionStart 和 OnSessionEnd 钩子。
+func TestServer_sessionLifecycle(t *testing.T) {
+	started := make(chan imapserver.Session, 2)
+	ended := make(chan error, 2)
+	server, addr := newTestServer(t, &imapserver.Options{
+		OnSessionStart: func(conn *imapserver.Conn, session imapserver.Session) {
+			started <- session
+		},
+		OnSessionEnd: func(conn *imapserver.Conn, err error) {
+			ended <- err
+		},
+	})
+	defer server.Close()
+
+	waitEnd := func() error {
+		select {
+		case err := <-ended:
+			return err
+		case <-time.After(5 * time.Second):
+			t.Fatalf("OnSessionEnd 未被调用")
+			return nil
+		}
+	}
+
+	// 正常登出
+	client, err := imapclient.DialInsecure(addr, nil)
+	if err != nil {
+		t.Fatalf("DialInsecure() = %v", err)
+	}
+	if err := client.Login(testUsername, testPassword).Wait(); err != nil {
+		t.Fatalf("Login().Wait() = %v", err)
+	}
+	if session := <-started; session == nil {
+		t.Errorf("OnSessionStart 的会话为 nil")
+	}
+	if err := client.Logout().Wait(); err != nil {
+		t.Fatalf("Logout().Wait() = %v", err)
+	}
+	client.Close()
+	if err := waitEnd(); err != nil {
+		t.Errorf("OnSessionEnd() 在登出后 err = %v, want nil", err)
+	}
+
+	// 异常断开
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("net.Dial() = %v", err)
+	}
+	client = imapclient.New(conn, nil)
+	if err := client.Noop().Wait(); err != nil {
+		t.Fatalf("Noop().Wait() = %v", err)
+	}
+	<-started
+	conn.Close()
+	if err := waitEnd(); err == nil {
+		t.Errorf("OnSessionEnd() 在异常断开后 err = nil, want an error")
+	}
+}
+
+// TestServer_maxIdleDuration 测试 IDLE 超过 MaxIdleDuration 后服务器发送 BYE 并关闭连接。
+func TestServer_maxIdleDuration(t *testing.T) {
+	server, addr := newTestServer(t, &imapserver.Options{MaxIdleDuration: 200 * time.Millisecond})
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("net.Dial() = %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	br := bufio.NewReader(conn)
+	if _, err := br.ReadString('\n'); err != nil { // 读取欢迎信息
+		t.Fatalf("读取欢迎信息失败: %v", err)
+	}
+	io.WriteString(conn, "A1 LOGIN "+testUsername+" "+testPassword+"\r\n")
+	if line, err := br.ReadString('\n'); err != nil || !strings.HasPrefix(line, "A1 OK") {
+		t.Fatalf("LOGIN 响应 = %q, %v", line, err)
+	}
+
+	start := time.Now()
+	io.WriteString(conn, "A2 IDLE\r\n")
+	if line, err := br.ReadString('\n'); err != nil || !strings.HasPrefix(line, "+ ") {
+		t.Fatalf("IDLE 响应 = %q, %v", line, err)
+	}
+
+	// 客户端不发送 DONE，服务器在超时后发送 BYE 并关闭连接，不再发送带标签的响应
+	var lines []string
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			break
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) != 1 || !strings.HasPrefix(lines[0], "* BYE ") {
+		t.Errorf("IDLE 超时后的响应 = %q, want * BYE", lines)
+	}
+	if d := time.Since(start); d < 200*time.Millisecond {
+		t.Errorf("连接在 %v 后被关闭，want 至少 200ms", d)
+	}
+}
+
+// TestServer_Shutdown 测试优雅关闭。
+func TestServer_Shutdown(t *testing.T) {
+	server, addr := newTestServer(t, &imapserver.Options{})
+
+	client, err := imapclient.DialInsecure(addr, nil)
+	if err != nil {
+		t.Fatalf("DialInsecure() = %v", err)
+	}
+	defer client.Close()
+	if err := client.Login(testUsername, testPassword).Wait(); err != nil {
+		t.Fatalf("Login().Wait() = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() = %v", err)
+	}
+
+	if err := client.Noop().Wait(); err == nil {
+		t.Errorf("Noop().Wait() = nil, want an error")
+	}
+	if _, err := net.Dial("tcp", addr); err == nil {
+		t.Errorf("net.Dial() = nil, want an error")
+	}
+	if err := server.Close(); err == nil {
+		t.Errorf("Close() = nil, want an error")
+	}
+}
+
+// TestServer_Shutdown_timeout 测试 ctx 结束后强制关闭连接。
+func TestServer_Shutdown_timeout(t *testing.T) {
+	server, addr := newTestServer(t, &imapserver.Options{})
+
+	client, err := imapclient.DialInsecure(addr, nil)
+	if err != nil {
+		t.Fatalf("DialInsecure() = %v", err)
+	}
+	defer client.Close()
+	if err := client.Login(testUsername, testPassword).Wait(); err != nil {
+		t.Fatalf("Login().Wait() = %v", err)
+	}
+	if _, err := client.Select("INBOX", nil).Wait(); err != nil {
+		t.Fatalf("Select().Wait() = %v", err)
+	}
+
+	// IDLE 命令在客户端发送 DONE 之前不会完成
+	idleCmd, err := client.Idle()
+	if err != nil {
+		t.Fatalf("Idle() = %v", err)
+	}
+	defer idleCmd.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Shutdown() = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	if err := idleCmd.Wait(); err == nil {
+		t.Errorf("IdleCommand.Wait() = nil, want an error")
+	}
+}
+
+// TestServer_unadvertisedCommand 测试未宣告能力对应的命令返回 BAD。
+func TestServer_unadvertisedCommand(t *testing.T) {
+	server, addr := newTestServer(t, &imapserver.Options{
+		Caps: imap.CapSet{imap.CapIMAP4rev1: {}},
+	})
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("net.Dial() = %v", err)
+	}
+	defer conn.Close()
+	br := bufio.NewReader(conn)
+
+	// exec 发送一条命令并返回带标签的响应行
+	exec := func(tag, cmd string) string {
+		if _, err := io.WriteString(conn, tag+" "+cmd+"\r\n"); err != nil {
+			t.Fatalf("写入命令失败: %v", err)
+		}
+		for {
+			line, err := br.ReadString('\n')
+			if err != nil {
+				t.Fatalf("读取响应失败: %v", err)
+			}
+			if strings.HasPrefix(line, tag+" ") {
+				return strings.TrimSuffix(line, "\r\n")
+			}
+		}
+	}
+
+	if _, err := br.ReadString('\n'); err != nil { // 读取欢迎信息
+		t.Fatalf("读取欢迎信息失败: %v", err)
+	}
+	if resp := exec("A1", "LOGIN "+testUsername+" "+testPassword); !strings.HasPrefix(resp, "A1 OK") {
+		t.Fatalf("LOGIN 响应 = %q", resp)
+	}
+	if resp := exec("A2", "SELECT INBOX"); !strings.HasPrefix(resp, "A2 OK") {
+		t.Fatalf("SELECT 响应 = %q", resp)
+	}
+	for _, cmd := range []string{"MOVE 1 INBOX", "UID MOVE 1 INBOX", "NAMESPACE", "UID EXPUNGE 1", `APPEND INBOX CATENATE (URL "/INBOX/;UID=1")`} {
+		if resp := exec("A3", cmd); !strings.HasPrefix(resp, "A3 BAD") {
+			t.Errorf("%v 响应 = %q, want BAD", cmd, resp)
+		}
+	}
+	// 连接应仍然可用
+	if resp := exec("A4", "NOOP"); !strings.HasPrefix(resp, "A4 OK") {
+		t.Errorf("NOOP 响应 = %q", resp)
+	}
+}
+
+// pipeListener 是基于 net.Pipe 的监听器：服务器的写入在客户端读取之前会一直阻塞。
+type pipeListener struct {
+	conns     chan net.Conn
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newPipeListener() *pipeListener {
+	return &pipeListener{conns: make(chan net.Conn), done: make(chan struct{})}
+}
+
+func (ln *pipeListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-ln.conns:
+		return conn, nil
+	case <-ln.done:
+		return nil, net.ErrClosed
+	}
+}
+
+func (ln *pipeListener) Close() error {
+	ln.closeOnce.Do(func() { close(ln.done) })
+	return nil
+}
+
+func (ln *pipeListener) Addr() net.Addr {
+	return pipeAddr{}
+}
+
+// Dial 创建一个新的连接并交给服务器。
+func (ln *pipeListener) Dial() net.Conn {
+	clientConn, serverConn := net.Pipe()
+	ln.conns <- serverConn
+	return clientConn
+}
+
+type pipeAddr struct{}
+
+func (pipeAddr) Network() string { return "pipe" }
+func (pipeAddr) String() string  { return "pipe" }
+
+// TestServer_idleQueueOverflow 测试从不读取的 IDLE 客户端在更新队列超过上限后被 BYE 断开。
+func TestServer_idleQueueOverflow(t *testing.T) {
+	server, addr := newTestServer(t, &imapserver.Options{})
+	defer server.Close()
+
+	ln := newPipeListener()
+	go server.Serve(ln)
+
+	client, err := imapclient.DialInsecure(addr, nil)
+	if err != nil {
+		t.Fatalf("DialInsecure() = %v", err)
+	}
+	defer client.Close()
+	if err := client.Login(testUsername, testPassword).Wait(); err != nil {
+		t.Fatalf("Login().Wait() = %v", err)
+	}
+	const rawMessage = "Subject: overflow\r\n\r\nHello\r\n"
+	appendCmd := client.Append("INBOX", int64(len(rawMessage)), nil)
+	appendCmd.Write([]byte(rawMessage))
+	appendCmd.Close()
+	if _, err := appendCmd.Wait(); err != nil {
+		t.Fatalf("Append().Wait() = %v", err)
+	}
+	if _, err := client.Select("INBOX", nil).Wait(); err != nil {
+		t.Fatalf("Select().Wait() = %v", err)
+	}
+
+	conn := ln.Dial()
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	br := bufio.NewReader(conn)
+	exec := func(tag, cmd string) string {
+		if _, err := io.WriteString(conn, tag+" "+cmd+"\r\n"); err != nil {
+			t.Fatalf("写入命令失败: %v", err)
+		}
+		for {
+			line, err := br.ReadString('\n')
+			if err != nil {
+				t.Fatalf("读取响应失败: %v", err)
+			}
+			if strings.HasPrefix(line, tag+" ") || strings.HasPrefix(line, "+ ") {
+				return strings.TrimSuffix(line, "\r\n")
+			}
+		}
+	}
+	if _, err := br.ReadString('\n'); err != nil { // 读取欢迎信息
+		t.Fatalf("读取欢迎信息失败: %v", err)
+	}
+	if resp := exec("A1", "LOGIN "+testUsername+" "+testPassword); !strings.HasPrefix(resp, "A1 OK") {
+		t.Fatalf("LOGIN 响应 = %q", resp)
+	}
+	if resp := exec("A2", "SELECT INBOX"); !strings.HasPrefix(resp, "A2 OK") {
+		t.Fatalf("SELECT 响应 = %q", resp)
+	}
+	if resp := exec("A3", "IDLE"); !strings.HasPrefix(resp, "+ ") {
+		t.Fatalf("IDLE 响应 = %q", resp)
+	}
+
+	// IDLE 客户端不再读取，另一个会话不断添加并移走邮件。这些更新无法合并
+	if err := client.Create("Archive", nil).Wait(); err != nil {
+		t.Fatalf("Create().Wait() = %v", err)
+	}
+	const cycles = 3000
+	for i := 0; i < cycles; i++ {
+		appendCmd := client.Append("INBOX", int64(len(rawMessage)), nil)
+		appendCmd.Write([]byte(rawMessage))
+		appendCmd.Close()
+		appendData, err := appendCmd.Wait()
+		if err != nil {
+			t.Fatalf("Append().Wait() = %v", err)
+		}
+		if _, err := client.Move(imap.UIDSetNum(appendData.UID), "Archive").Wait(); err != nil {
+			t.Fatalf("Move().Wait() = %v", err)
+		}
+	}
+
+	// 服务器应发送 BYE 并关闭连接，而不是保留所有更新
+	var lines []string
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			break
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 || !strings.HasPrefix(lines[len(lines)-1], "* BYE [UNAVAILABLE]") {
+		t.Fatalf("最后的响应 = %q, want * BYE [UNAVAILABLE]", lines)
+	}
+	if len(lines) >= cycles {
+		t.Errorf("收到 %v 行响应，want 队列已被丢弃", len(lines))
+	}
+}
+
+// TestServer_largeMailboxUpdates 测试对超过队列基础上限的邮件执行 STORE 和 EXPUNGE
+// 不会断开发出命令的会话或同一邮箱上的其他会话。
+func TestServer_largeMailboxUpdates(t *testing.T) {
+	server, addr := newTestServer(t, &imapserver.Options{})
+	defer server.Close()
+
+	const numMessages = 5000
+
+	var numFetch, numExpunge int32
+	other, err := imapclient.DialInsecure(addr, &imapclient.Options{
+		UnilateralDataHandler: &imapclient.UnilateralDataHandler{
+			Expunge: func(seqNum uint32) {
+				atomic.AddInt32(&numExpunge, 1)
+			},
+			Fetch: func(msg *imapclient.FetchMessageData) {
+				msg.Collect()
+				atomic.AddInt32(&numFetch, 1)
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("DialInsecure() = %v", err)
+	}
+	defer other.Close()
+
+	client, err := imapclient.DialInsecure(addr, nil)
+	if err != nil {
+		t.Fatalf("DialInsecure() = %v", err)
+	}
+	defer client.Close()
+
+	for _, c := range []*imapclient.Client{client, other} {
+		if err := c.Login(testUsername, testPassword).Wait(); err != nil {
+			t.Fatalf("Login().Wait() = %v", err)
+		}
+	}
+	const rawMessage = "Subject: large\r\n\r\nHello\r\n"
+	var appendCmds []*imapclient.AppendCommand
+	for i := 0; i < numMessages; i++ {
+		appendCmd := client.Append("INBOX", int64(len(rawMessage)), nil)
+		appendCmd.Write([]byte(rawMessage))
+		appendCmd.Close()
+		appendCmds = append(appendCmds, appendCmd)
+	}
+	for _, appendCmd := range appendCmds {
+		if _, err := appendCmd.Wait(); err != nil {
+			t.Fatalf("Append().Wait() = %v", err)
+		}
+	}
+	for _, c := range []*imapclient.Client{client, other} {
+		if _, err := c.Select("INBOX", nil).Wait(); err != nil {
+			t.Fatalf("Select().Wait() = %v", err)
+		}
+	}
+
+	storeFlags := imap.StoreFlags{Op: imap.StoreFlagsAdd, Silent: true, Flags: []imap.Flag{imap.FlagFlagged}}
+	if err := client.Store(imap.SeqSet{{Start: 1, Stop: 0}}, &storeFlags, nil).Close(); err != nil {
+		t.Fatalf("Store(\\Flagged).Close() = %v", err)
+	}
+	if err := other.Noop().Wait(); err != nil {
+		t.Fatalf("Noop().Wait() = %v", err)
+	}
+	// FETCH 处理函数在单独的 goroutine 中运行
+	deadline := time.Now().Add(5 * time.Second)
+	for atomic.LoadInt32(&numFetch) < numMessages && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := atomic.LoadInt32(&numFetch); n != numMessages {
+		t.Fatalf("其他会话收到 %v 个 FETCH 更新，want %v", n, numMessages)
+	}
+
+	// 先标记再删除：标志更新被删除合并，其他会话只收到 EXPUNGE
+	atomic.StoreInt32(&numFetch, 0)
+	storeFlags.Flags = []imap.Flag{imap.FlagDeleted}
+	if err := client.Store(imap.SeqSet{{Start: 1, Stop: 0}}, &storeFlags, nil).Close(); err != nil {
+		t.Fatalf("Store(\\Deleted).Close() = %v", err)
+	}
+	seqNums, err := client.Expunge().Collect()
+	if err != nil {
+		t.Fatalf("Expunge().Collect() = %v", err)
+	}
+	if len(seqNums) != numMessages {
+		t.Errorf("Expunge() 返回 %v 个序号，want %v", len(seqNums), numMessages)
+	}
+	if err := client.Noop().Wait(); err != nil {
+		t.Fatalf("发出命令的会话 Noop().Wait() = %v", err)
+	}
+	if err := other.Noop().Wait(); err != nil {
+		t.Fatalf("其他会话 Noop().Wait() = %v", err)
+	}
+	if n := atomic.LoadInt32(&numExpunge); n != numMessages {
+		t.Errorf("其他会话收到 %v 个 EXPUNGE 更新，want %v", n, numMessages)
+	}
+	if n := atomic.LoadInt32(&numFetch); n != 0 {
+		t.Errorf("其他会话收到 %v 个 FETCH 更新，want 0", n)
+	}
+}
+
+// flakyPollSession 在 fail 非零时让 Poll 返回错误，模拟推送更新瞬时失败。
+type flakyPollSession struct {
+	imapserver.Session
+	fail *int32
+}
+
+func (sess *flakyPollSession) Poll(w *imapserver.UpdateWriter, allowExpunge bool) error {
+	if atomic.LoadInt32(sess.fail) != 0 {
+		return errors.New("推送更新失败")
+	}
+	return sess.Session.Poll(w, allowExpunge)
+}
+
+// testLogger 记录服务器打印的日志。
+type testLogger struct {
+	mutex sync.Mutex
+	lines []string
+}
+
+func (l *testLogger) Printf(format string, args ...interface{}) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.lines = append(l.lines, fmt.Sprintf(format, args...))
+}
+
+// TestServer_pollError 测试命令成功后推送更新失败不会丢失命令的 OK 响应。
+func TestServer_pollError(t *testing.T) {
+	memServer := imapmemserver.New()
+	user := imapmemserver.NewUser(testUsername, testPassword)
+	user.Create("INBOX", nil)
+	memServer.AddUser(user)
+
+	var fail int32
+	logger := &testLogger{}
+	server := imapserver.New(&imapserver.Options{
+		NewSession: func(conn *imapserver.Conn) (imapserver.Session, *imapserver.GreetingData, error) {
+			return &flakyPollSession{Session: memServer.NewSession(), fail: &fail}, nil, nil
+		},
+		Caps:         imap.CapSet{imap.CapIMAP4rev1: {}},
+		InsecureAuth: true,
+		Logger:       logger,
+	})
+	ln, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("net.Listen() = %v", err)
+	}
+	go server.Serve(ln)
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("net.Dial() = %v", err)
+	}
+	defer conn.Close()
+	br := bufio.NewReader(conn)
+	exec := func(tag, cmd string) string {
+		if _, err := io.WriteString(conn, tag+" "+cmd+"\r\n"); err != nil {
+			t.Fatalf("写入命令失败: %v", err)
+		}
+		for {
+			line, err := br.ReadString('\n')
+			if err != nil {
+				t.Fatalf("读取响应失败: %v", err)
+			}
+			if strings.HasPrefix(line, tag+" ") {
+				return strings.TrimSuffix(line, "\r\n")
+			}
+		}
+	}
+
+	if _, err := br.ReadString('\n'); err != nil { // 读取欢迎信息
+		t.Fatalf("读取欢迎信息失败: %v", err)
+	}
+	if resp := exec("A1", "LOGIN "+testUsername+" "+testPassword); !strings.HasPrefix(resp, "A1 OK") {
+		t.Fatalf("LOGIN 响应 = %q", resp)
+	}
+	if resp := exec("A2", "SELECT INBOX"); !strings.HasPrefix(resp, "A2 OK") {
+		t.Fatalf("SELECT 响应 = %q", resp)
+	}
+
+	atomic.StoreInt32(&fail, 1)
+	if resp := exec("A3", "NOOP"); !strings.HasPrefix(resp, "A3 OK") {
+		t.Errorf("NOOP 响应 = %q, want OK", resp)
+	}
+	if resp := exec("A4", "CREATE Archive"); !strings.HasPrefix(resp, "A4 OK") {
+		t.Errorf("CREATE 响应 = %q, want OK", resp)
+	}
+	atomic.StoreInt32(&fail, 0)
+
+	// 连接仍然可用
+	if resp := exec("A5", "NOOP"); !strings.HasPrefix(resp, "A5 OK") {
+		t.Errorf("NOOP 响应 = %q, want OK", resp)
+	}
+
+	logger.mutex.Lock()
+	defer logger.mutex.Unlock()
+	if len(logger.lines) != 2 {
+		t.Errorf("日志 = %q, want 2 lines", logger.lines)
+	}
+}
+
+// TestServer_storeNonexistent 测试 STORE 不存在的消息时静默忽略并返回 OK。
+func TestServer_storeNonexistent(t *testing.T) {
+	server, addr := newTestServer(t, &imapserver.Options{})
+	defer server.Close()
+
+	client, err := imapclient.DialInsecure(addr, nil)
+	if err != nil {
+		t.Fatalf("DialInsecure() = %v", err)
+	}
+	defer client.Close()
+	if err := client.Login(testUsername, testPassword).Wait(); err != nil {
+		t.Fatalf("Login().Wait() = %v", err)
+	}
+	const rawMessage = "Subject: store\r\n\r\nHello\r\n"
+	appendCmd := client.Append("INBOX", int64(len(rawMessage)), nil)
+	appendCmd.Write([]byte(rawMessage))
+	appendCmd.Close()
+	if _, err := appendCmd.Wait(); err != nil {
+		t.Fatalf("Append().Wait() = %v", err)
+	}
+	if _, err := client.Select("INBOX", nil).Wait(); err != nil {
+		t.Fatalf("Select().Wait() = %v", err)
+	}
+
+	storeFlags := imap.StoreFlags{Op: imap.StoreFlagsAdd, Flags: []imap.Flag{imap.FlagSeen}}
+	for _, numSet := range []imap.NumSet{imap.SeqSetNum(999), imap.UIDSetNum(999)} {
+		msgs, err := client.Store(numSet, &storeFlags, nil).Collect()
+		if err != nil {
+			t.Errorf("Store(%v) = %v, want OK", numSet, err)
+		} else if len(msgs) != 0 {
+			t.Errorf("Store(%v) 返回了 %v 条消息，want 0", numSet, len(msgs))
+		}
+	}
+
+	// 已有消息不受影响
+	msgs, err := client.Fetch(imap.SeqSetNum(1), &imap.FetchOptions{Flags: true}).Collect()
+	if err != nil {
+		t.Fatalf("Fetch().Collect() = %v", err)
+	} else if len(msgs) != 1 {
+		t.Fatalf("len(msgs) = %v, want 1", len(msgs))
+	}
+	for _, flag := range msgs[0].Flags {
+		if flag == imap.FlagSeen {
+			t.Errorf("消息 1 被错误地设置了 \\Seen 标志")
+		}
+	}
+}
+
+// TestServer_setQuotaPermission 测试未获得管理员权限的用户不能通过 SETQUOTA 修改配额。
+func TestServer_setQuotaPermission(t *testing.T) {
+	server, addr := newTestServer(t, &imapserver.Options{
+		Caps: imap.CapSet{
+			imap.CapIMAP4rev1:   {},
+			imap.CapIMAP4rev2:   {},
+			imap.CapQuota:       {},
+			imap.CapQuotaSet:    {},
+			"QUOTA=RES-MESSAGE": {},
+		},
+	})
+	defer server.Close()
+
+	client, err := imapclient.DialInsecure(addr, nil)
+	if err != nil {
+		t.Fatalf("DialInsecure() = %v", err)
+	}
+	defer client.Close()
+	if err := client.Login(testUsername, testPassword).Wait(); err != nil {
+		t.Fatalf("Login().Wait() = %v", err)
+	}
+
+	limits := map[imap.QuotaResourceType]int64{imap.QuotaResourceMessage: 1}
+	if err := client.SetQuota("INBOX", limits).Wait(); imap.ErrorCode(err) != imap.ResponseCodeNoPerm {
+		t.Errorf("SetQuota() = %v, want NO [NOPERM]", err)
+	}
+	data, err := client.GetQuota("INBOX").Wait()
+	if err != nil {
+		t.Fatalf("GetQuota().Wait() = %v", err)
+	} else if len(data.Resources) != 0 {
+		t.Errorf("GetQuota() = %v, want 没有限制", data)
+	}
+}
+
+// TestServer_internalDateFunc 测试 APPEND 未指定日期时使用自定义的 INTERNALDATE 生成策略。
+func TestServer_internalDateFunc(t *testing.T) {
+	memServer := imapmemserver.New()
+	user := imapmemserver.NewUser(testUsername, testPassword)
+	user.Create("INBOX", nil)
+	user.SetInternalDateFunc(imapmemserver.ReceivedInternalDate)
+	memServer.AddUser(user)
+
+	server := imapserver.New(&imapserver.Options{
+		NewSession: func(conn *imapserver.Conn) (imapserver.Session, *imapserver.GreetingData, error) {
+			return memServer.NewSession(), nil, nil
+		},
+		Caps:         imap.CapSet{imap.CapIMAP4rev1: {}, imap.CapIMAP4rev2: {}},
+		InsecureAuth: true,
+	})
+	ln, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("net.Listen() = %v", err)
+	}
+	go server.Serve(ln)
+	defer server.Close()
+
+	client, err := imapclient.DialInsecure(ln.Addr().String(), nil)
+	if err != nil {
+		t.Fatalf("DialInsecure() = %v", err)
+	}
+	defer client.Close()
+	if err := client.Login(testUsername, testPassword).Wait(); err != nil {
+		t.Fatalf("Login().Wait() = %v", err)
+	}
+
+	received := time.Date(2023, time.March, 4, 10, 52, 37, 0, time.FixedZone("", 8*60*60))
+	explicit := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	messages := []struct {
+		raw     string
+		options *imap.AppendOptions
+	}{
+		{"Received: from a.example.org by b.example.org; Sat, 4 Mar 2023 10:52:37 +0800\r\n" +
+			"Received: from c.example.org by a.example.org; Sat, 4 Mar 2023 10:50:00 +0800\r\n" +
+			"Subject: received\r\n\r\nHello\r\n", nil},
+		{"Received: from a.example.org by b.example.org; Sat, 4 Mar 2023 10:52:37 +0800\r\n" +
+			"Subject: explicit\r\n\r\nHello\r\n", &imap.AppendOptions{Time: explicit}},
+		{"Subject: no received\r\n\r\nHello\r\n", nil},
+	}
+	before := time.Now().Add(-time.Second)
+	for _, m := range messages {
+		appendCmd := client.Append("INBOX", int64(len(m.raw)), m.options)
+		appendCmd.Write([]byte(m.raw))
+		appendCmd.Close()
+		if _, err := appendCmd.Wait(); err != nil {
+			t.Fatalf("Append().Wait() = %v", err)
+		}
+	}
+
+	if _, err := client.Select("INBOX", nil).Wait(); err != nil {
+		t.Fatalf("Select().Wait() = %v", err)
+	}
+	msgs, err := client.Fetch(imap.SeqSetNum(1, 2, 3), &imap.FetchOptions{InternalDate: true}).Collect()
+	if err != nil {
+		t.Fatalf("Fetch().Collect() = %v", err)
+	} else if len(msgs) != 3 {
+		t.Fatalf("len(msgs) = %v, want 3", len(msgs))
+	}
+	if got := msgs[0].InternalDate; !got.Equal(received) {
+		t.Errorf("INTERNALDATE = %v, want %v（来自 Received）", got, received)
+	}
+	if got := msgs[1].InternalDate; !got.Equal(explicit) {
+		t.Errorf("INTERNALDATE = %v, want %v（APPEND 指定）", got, explicit)
+	}
+	if got := msgs[2].InternalDate; got.Before(before) {
+		t.Errorf("INTERNALDATE = %v, want 当前时间", got)
+	}
+}
+
+// TestServer_mailboxAccess 测试只读和只追加邮箱的 SELECT、STORE、EXPUNGE、APPEND、COPY 和 MOVE。
+func TestServer_mailboxAccess(t *testing.T) {
+	memServer := imapmemserver.New()
+	user := imapmemserver.NewUser(testUsername, testPassword)
+	for _, name := range []string{"INBOX", "Archive", "Frozen"} {
+		user.Create(name, nil)
+	}
+	memServer.AddUser(user)
+
+	server := imapserver.New(&imapserver.Options{
+		NewSession: func(conn *imapserver.Conn) (imapserver.Session, *imapserver.GreetingData, error) {
+			return memServer.NewSession(), nil, nil
+		},
+		Caps:         imap.CapSet{imap.CapIMAP4rev1: {}, imap.CapIMAP4rev2: {}},
+		InsecureAuth: true,
+	})
+	ln, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("net.Listen() = %v", err)
+	}
+	go server.Serve(ln)
+	defer server.Close()
+
+	client, err := imapclient.DialInsecure(ln.Addr().String(), nil)
+	if err != nil {
+		t.Fatalf("DialInsecure() = %v", err)
+	}
+	defer client.Close()
+	if err := client.Login(testUsername, testPassword).Wait(); err != nil {
+		t.Fatalf("Login().Wait() = %v", err)
+	}
+
+	const raw = "Subject: access\r\n\r\nHello\r\n"
+	appendMessage := func(mailbox string) error {
+		appendCmd := client.Append(mailbox, int64(len(raw)), nil)
+		appendCmd.Write([]byte(raw))
+		appendCmd.Close()
+		_, err := appendCmd.Wait()
+		return err
+	}
+	for _, name := range []string{"INBOX", "Archive", "Frozen"} {
+		if err := appendMessage(name); err != nil {
+			t.Fatalf("Append(%v) = %v", name, err)
+		}
+	}
+	if err := user.SetMailboxAccess("Archive", imapmemserver.MailboxAccessAppendOnly); err != nil {
+		t.Fatalf("SetMailboxAccess(Archive) = %v", err)
+	}
+	if err := user.SetMailboxAccess("Frozen", imapmemserver.MailboxAccessReadOnly); err != nil {
+		t.Fatalf("SetMailboxAccess(Frozen) = %v", err)
+	}
+
+	// 只追加邮箱接受新邮件，只读邮箱拒绝新邮件
+	if data, err := client.Select("INBOX", nil).Wait(); err != nil {
+		t.Fatalf("Select(INBOX) = %v", err)
+	} else if data.ReadOnly {
+		t.Errorf("Select(INBOX).ReadOnly = true, want false")
+	}
+	if err := appendMessage("Archive"); err != nil {
+		t.Errorf("Append(Archive) = %v", err)
+	}
+	if err := appendMessage("Frozen"); err == nil {
+		t.Errorf("Append(Frozen) = nil, want error")
+	}
+	if _, err := client.Copy(imap.SeqSetNum(1), "Archive").Wait(); err != nil {
+		t.Errorf("Copy(Archive) = %v", err)
+	}
+	if _, err := client.Copy(imap.SeqSetNum(1), "Frozen").Wait(); err == nil {
+		t.Errorf("Copy(Frozen) = nil, want error")
+	}
+
+	// SELECT 只读和只追加的邮箱返回 READ-ONLY，已有邮件不能修改
+	for _, name := range []string{"Archive", "Frozen"} {
+		data, err := client.Select(name, nil).Wait()
+		if err != nil {
+			t.Fatalf("Select(%v) = %v", name, err)
+		} else if !data.ReadOnly || len(data.PermanentFlags) != 0 {
+			t.Errorf("Select(%v) = ReadOnly %v, PermanentFlags %v, want READ-ONLY without permanent flags", name, data.ReadOnly, data.PermanentFlags)
+		}
+
+		storeFlags := imap.StoreFlags{Op: imap.StoreFlagsAdd, Flags: []imap.Flag{imap.FlagDeleted}}
+		if err := client.Store(imap.SeqSetNum(1), &storeFlags, nil).Close(); err == nil {
+			t.Errorf("Store() in %v = nil, want error", name)
+		}
+		if err := client.Expunge().Close(); err == nil {
+			t.Errorf("Expunge() in %v = nil, want error", name)
+		}
+		if _, err := client.Move(imap.SeqSetNum(1), "INBOX").Wait(); err == nil {
+			t.Errorf("Move() from %v = nil, want error", name)
+		}
+	}
+
+	status, err := client.Status("Archive", &imap.StatusOptions{NumMessages: true}).Wait()
+	if err != nil {
+		t.Fatalf("Status(Archive) = %v", err)
+	} else if status.NumMessages == nil || *status.NumMessages != 3 {
+		t.Errorf("Status(Archive).NumMessages = %v, want 3", status.NumMessages)
+	}
+}
+
+// TestServer_maxConnections 测试达到连接数上限时拒绝新连接。
+func TestServer_maxConnections(t *testing.T) {
+	var limitAddr int32
+	server, addr := newTestServer(t, &imapserver.Options{
+		MaxConnections: 1,
+		ConnLimitFunc: func(addr net.Addr) bool {
+			return atomic.LoadInt32(&limitAddr) != 0
+		},
+	})
+	defer server.Close()
+
+	// readGreeting 建立连接并返回第一行响应。
+	readGreeting := func() string {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatalf("net.Dial() = %v", err)
+		}
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+		line, err := bufio.NewReader(conn).ReadString('\n')
+		if err != nil {
+			t.Fatalf("读取欢迎信息失败: %v", err)
+		}
+		return line
+	}
+
+	client, err := imapclient.DialInsecure(addr, nil)
+	if err != nil {
+		t.Fatalf("DialInsecure() = %v", err)
+	}
+	if err := client.WaitGreeting(); err != nil {
+		t.Fatalf("WaitGreeting() = %v", err)
+	}
+	if line := readGreeting(); !strings.HasPrefix(line, "* BYE [LIMIT]") {
+		t.Errorf("欢迎信息 = %q, want * BYE [LIMIT]", line)
+	}
+
+	// 关闭第一个连接后可以再次连接
+	client.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		line := readGreeting()
+		if strings.HasPrefix(line, "* OK") {
+			break
+		} else if time.Now().After(deadline) {
+			t.Fatalf("欢迎信息 = %q, want * OK", line)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// ConnLimitFunc 可以拒绝特定地址的连接
+	atomic.StoreInt32(&limitAddr, 1)
+	if line := readGreeting(); !strings.HasPrefix(line, "* BYE [LIMIT]") {
+		t.Errorf("欢迎信息 = %q, want * BYE [LIMIT]", line)
+	}
+}
+
+// TestServer_uidValidityFunc 测试自定义 UIDVALIDITY 生成器，以及重建邮箱后 UIDVALIDITY 变化。
+func TestServer_uidValidityFunc(t *testing.T) {
+	memServer := imapmemserver.New()
+	user := imapmemserver.NewUser(testUsername, testPassword)
+	user.SetUIDValidityFunc(func(mailbox string) uint32 {
+		return 1000 // 固定值，重建时必须仍然变化
+	})
+	user.Create("INBOX", nil)
+	memServer.AddUser(user)
+
+	server := imapserver.New(&imapserver.Options{
+		NewSession: func(conn *imapserver.Conn) (imapserver.Session, *imapserver.GreetingData, error) {
+			return memServer.NewSession(), nil, nil
+		},
+		Caps:         imap.CapSet{imap.CapIMAP4rev1: {}, imap.CapIMAP4rev2: {}},
+		InsecureAuth: true,
+	})
+	ln, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("net.Listen() = %v", err)
+	}
+	go server.Serve(ln)
+	defer server.Close()
+
+	client, err := imapclient.DialInsecure(ln.Addr().String(), nil)
+	if err != nil {
+		t.Fatalf("DialInsecure() = %v", err)
+	}
+	defer client.Close()
+	if err := client.Login(testUsername, testPassword).Wait(); err != nil {
+		t.Fatalf("Login().Wait() = %v", err)
+	}
+
+	uidValidity := func(mailbox string) uint32 {
+		data, err := client.Status(mailbox, &imap.StatusOptions{UIDValidity: true}).Wait()
+		if err != nil {
+			t.Fatalf("Status(%q).Wait() = %v", mailbox, err)
+		}
+		return data.UIDValidity
+	}
+
+	if v := uidValidity("INBOX"); v != 1000 {
+		t.Errorf("INBOX UIDVALIDITY = %v, want 1000", v)
+	}
+	if err := client.Create("Archive", nil).Wait(); err != nil {
+		t.Fatalf("Create().Wait() = %v", err)
+	}
+	before := uidValidity("Archive")
+	if err := client.Delete("Archive").Wait(); err != nil {
+		t.Fatalf("Delete().Wait() = %v", err)
+	}
+	if err := client.Create("Archive", nil).Wait(); err != nil {
+		t.Fatalf("Create().Wait() = %v", err)
+	}
+	if after := uidValidity("Archive"); after == before {
+		t.Errorf("重建后 UIDVALIDITY = %v, want 不同于 %v", after, before)
+	}
+}
+
+// TestServer_rateLimit 测试 ReadLimit 和 WriteLimit 对字面量传输的限速。
+func TestServer_rateLimit(t *testing.T) {
+	const limit = 200000 // 字节/秒
+	server, addr := newTestServer(t, &imapserver.Options{
+		ReadLimit:  limit,
+		WriteLimit: limit,
+	})
+	defer server.Close()
+
+	client, err := imapclient.DialInsecure(addr, nil)
+	if err != nil {
+		t.Fatalf("DialInsecure() = %v", err)
+	}
+	defer client.Close()
+	if err := client.Login(testUsername, testPassword).Wait(); err != nil {
+		t.Fatalf("Login().Wait() = %v", err)
+	}
+
+	body := strings.Repeat("0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcd\r\n", 1600)
+	rawMessage := "Subject: rate limit\r\n\r\n" + body
+	minDuration := time.Duration(len(rawMessage)) * time.Second / limit * 3 / 4
+
+	start := time.Now()
+	appendCmd := client.Append("INBOX", int64(len(rawMessage)), nil)
+	appendCmd.Write([]byte(rawMessage))
+	appendCmd.Close()
+	if _, err := appendCmd.Wait(); err != nil {
+		t.Fatalf("Append().Wait() = %v", err)
+	}
+	if d := time.Since(start); d < minDuration {
+		t.Errorf("APPEND 耗时 %v, want >= %v", d, minDuration)
+	}
+
+	if _, err := client.Select("INBOX", nil).Wait(); err != nil {
+		t.Fatalf("Select().Wait() = %v", err)
+	}
+
+	start = time.Now()
+	bodySection := &imap.FetchItemBodySection{}
+	msgs, err := client.Fetch(imap.SeqSetNum(1), &imap.FetchOptions{
+		BodySection: []*imap.FetchItemBodySection{bodySection},
+	}).Collect()
+	if err != nil {
+		t.Fatalf("Fetch().Collect() = %v", err)
+	} else if len(msgs) != 1 {
+		t.Fatalf("len(msgs) = %v, want 1", len(msgs))
+	}
+	if d := time.Since(start); d < minDuration {
+		t.Errorf("FETCH 耗时 %v, want >= %v", d, minDuration)
+	}
+	if got := string(msgs[0].FindBodySection(imap.PartSpecifierNone, nil)); got != rawMessage {
+		t.Errorf("FETCH 返回的消息与 APPEND 的内容不一致（长度 %v, want %v）", len(got), len(rawMessage))
+	}
+}
+
+// ctxSession 实现 SessionContext，记录服务器传入的上下文。
+type ctxSession struct {
+	imapserver.Session
+	fetchCtx    chan context.Context
+	searchStart chan struct{}
+	searchErr   chan error
+
+	// 不为 nil 时 FetchContext 不断写入响应，直到 ctx 被取消
+	fetchLoopErr chan error
+}
+
+var _ imapserver.SessionContext = (*ctxSession)(nil)
+
+func (sess *ctxSession) SelectContext(ctx context.Context, mailbox string, options *imap.SelectOptions) (*imap.SelectData, error) {
+	return sess.Select(mailbox, options)
+}
+
+func (sess *ctxSession) ListContext(ctx context.Context, w *imapserver.ListWriter, ref string, patterns []string, options *imap.ListOptions) error {
+	return sess.List(w, ref, patterns, options)
+}
+
+func (sess *ctxSession) StatusContext(ctx context.Context, mailbox string, options *imap.StatusOptions) (*imap.StatusData, error) {
+	return sess.Status(mailbox, options)
+}
+
+// SearchContext 阻塞直到 ctx 被取消。
+func (sess *ctxSession) SearchContext(ctx context.Context, kind imapserver.NumKind, criteria *imap.SearchCriteria, options *imap.SearchOptions) (*imap.SearchData, error) {
+	close(sess.searchStart)
+	<-ctx.Done()
+	sess.searchErr <- ctx.Err()
+	return nil, ctx.Err()
+}
+
+func (sess *ctxSession) FetchContext(ctx context.Context, w *imapserver.FetchWriter, numSet imap.NumSet, options *imap.FetchOptions) error {
+	if sess.fetchLoopErr != nil {
+		for ctx.Err() == nil {
+			resp := w.CreateMessage(1)
+			resp.WriteFlags(nil)
+			if err := resp.Close(); err != nil {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(5 * time.Second):
+		}
+		sess.fetchLoopErr <- ctx.Err()
+		return ctx.Err()
+	}
+	sess.fetchCtx <- ctx
+	return sess.Fetch(w, numSet, options)
+}
+
+func (sess *ctxSession) StoreContext(ctx context.Context, w *imapserver.FetchWriter, numSet imap.NumSet, flags *imap.StoreFlags, options *imap.StoreOptions) error {
+	return sess.Store(w, numSet, flags, options)
+}
+
+func (sess *ctxSession) CopyContext(ctx context.Context, numSet imap.NumSet, dest string) (*imap.CopyData, error) {
+	return sess.Copy(numSet, dest)
+}
+
+func (sess *ctxSession) AppendContext(ctx context.Context, mailbox string, r imap.LiteralReader, options *imap.AppendOptions) (*imap.AppendData, error) {
+	return sess.Append(mailbox, r, options)
+}
+
+func (sess *ctxSession) ExpungeContext(ctx context.Context, w *imapserver.ExpungeWriter, uids *imap.UIDSet) error {
+	return sess.Expunge(w, uids)
+}
+
+func (sess *ctxSession) PollContext(ctx context.Context, w *imapserver.UpdateWriter, allowExpunge bool) error {
+	return sess.Poll(w, allowExpunge)
+}
+
+func (sess *ctxSession) IdleContext(ctx context.Context, w *imapserver.UpdateWriter, stop <-chan struct{}) error {
+	return sess.Idle(w, stop)
+}
+
+// TestServer_sessionContext 测试传给 SessionContext 的上下文在命令完成和连接关闭时被取消。
+func TestServer_sessionContext(t *testing.T) {
+	memServer := imapmemserver.New()
+	user := imapmemserver.NewUser(testUsername, testPassword)
+	user.Create("INBOX", nil)
+	memServer.AddUser(user)
+
+	sess := &ctxSession{
+		fetchCtx:    make(chan context.Context, 1),
+		searchStart: make(chan struct{}),
+		searchErr:   make(chan error, 1),
+	}
+	server := imapserver.New(&imapserver.Options{
+		NewSession: func(conn *imapserver.Conn) (imapserver.Session, *imapserver.GreetingData, error) {
+			sess.Session = memServer.NewSession()
+			return sess, nil, nil
+		},
+		Caps:         imap.CapSet{imap.CapIMAP4rev1: {}},
+		InsecureAuth: true,
+	})
+	ln, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("net.Listen() = %v", err)
+	}
+	go server.Serve(ln)
+	defer server.Close()
+
+	client, err := imapclient.DialInsecure(ln.Addr().String(), nil)
+	if err != nil {
+		t.Fatalf("DialInsecure() = %v", err)
+	}
+	defer client.Close()
+	if err := client.Login(testUsername, testPassword).Wait(); err != nil {
+		t.Fatalf("Login().Wait() = %v", err)
+	}
+	if _, err := client.Select("INBOX", nil).Wait(); err != nil {
+		t.Fatalf("Select().Wait() = %v", err)
+	}
+
+	// 命令完成后上下文被取消
+	if err := client.Fetch(imap.SeqSetNum(1), &imap.FetchOptions{Flags: true}).Close(); err != nil {
+		t.Fatalf("Fetch().Close() = %v", err)
+	}
+	if ctx := <-sess.fetchCtx; ctx.Err() != context.Canceled {
+		t.Errorf("FETCH 完成后 ctx.Err() = %v, want %v", ctx.Err(), context.Canceled)
+	}
+
+	// 连接关闭时正在执行的命令的上下文被取消
+	searchCmd := client.Search(&imap.SearchCriteria{}, nil)
+	<-sess.searchStart
+	server.Close()
+	select {
+	case err := <-sess.searchErr:
+		if err != context.Canceled {
+			t.Errorf("SEARCH ctx.Err() = %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("连接关闭后 SEARCH 的上下文未被取消")
+	}
+	if _, err := searchCmd.Wait(); err == nil {
+		t.Errorf("Search().Wait() = nil, want error")
+	}
+}
+
+// TestServer_sessionContextDisconnect 测试客户端在命令执行期间断开连接时，
+// 服务器写入响应失败后取消命令的上下文。
+func TestServer_sessionContextDisconnect(t *testing.T) {
+	memServer := imapmemserver.New()
+	user := imapmemserver.NewUser(testUsername, testPassword)
+	user.Create("INBOX", nil)
+	memServer.AddUser(user)
+
+	sess := &ctxSession{fetchLoopErr: make(chan error, 1)}
+	server := imapserver.New(&imapserver.Options{
+		NewSession: func(conn *imapserver.Conn) (imapserver.Session, *imapserver.GreetingData, error) {
+			sess.Session = memServer.NewSession()
+			return sess, nil, nil
+		},
+		Caps:         imap.CapSet{imap.CapIMAP4rev1: {}},
+		InsecureAuth: true,
+	})
+	defer server.Close()
+	ln := newPipeListener()
+	go server.Serve(ln)
+
+	conn := ln.Dial()
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	br := bufio.NewReader(conn)
+	exec := func(tag, cmd string) string {
+		if _, err := io.WriteString(conn, tag+" "+cmd+"\r\n"); err != nil {
+			t.Fatalf("写入命令失败: %v", err)
+		}
+		for {
+			line, err := br.ReadString('\n')
+			if err != nil {
+				t.Fatalf("读取响应失败: %v", err)
+			}
+			if strings.HasPrefix(line, tag+" ") || strings.HasPrefix(line, "* 1 FETCH") {
+				return strings.TrimSuffix(line, "\r\n")
+			}
+		}
+	}
+	if _, err := br.ReadString('\n'); err != nil { // 读取欢迎信息
+		t.Fatalf("读取欢迎信息失败: %v", err)
+	}
+	if resp := exec("A1", "LOGIN "+testUsername+" "+testPassword); !strings.HasPrefix(resp, "A1 OK") {
+		t.Fatalf("LOGIN 响应 = %q", resp)
+	}
+	if resp := exec("A2", "SELECT INBOX"); !strings.HasPrefix(resp, "A2 OK") {
+		t.Fatalf("SELECT 响应 = %q", resp)
+	}
+	if resp := exec("A3", "FETCH 1 FLAGS"); !strings.HasPrefix(resp, "* 1 FETCH") {
+		t.Fatalf("FETCH 响应 = %q", resp)
+	}
+	conn.Close()
+
+	if err := <-sess.fetchLoopErr; err != context.Canceled {
+		t.Errorf("客户端断开后 FETCH ctx.Err() = %v, want %v", err, context.Canceled)
+	}
+}
+
+// TestServer_sessionContextClientClose 测试会话方法阻塞期间客户端断开连接会取消 ctx。
+func TestServer_sessionContextClientClose(t *testing.T) {
+	memServer := imapmemserver.New()
+	user := imapmemserver.NewUser(testUsername, testPassword)
+	user.Create("INBOX", nil)
+	memServer.AddUser(user)
+
+	sess := &ctxSession{searchStart: make(chan struct{}), searchErr: make(chan error, 1)}
+	server := imapserver.New(&imapserver.Options{
+		NewSession: func(conn *imapserver.Conn) (imapserver.Session, *imapserver.GreetingData, error) {
+			sess.Session = memServer.NewSession()
+			return sess, nil, nil
+		},
+		Caps:         imap.CapSet{imap.CapIMAP4rev1: {}},
+		InsecureAuth: true,
+	})
+	defer server.Close()
+	ln := newPipeListener()
+	go server.Serve(ln)
+
+	conn := ln.Dial()
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	br := bufio.NewReader(conn)
+	exec := func(tag, cmd string) string {
+		if _, err := io.WriteString(conn, tag+" "+cmd+"\r\n"); err != nil {
+			t.Fatalf("写入命令失败: %v", err)
+		}
+		for {
+			line, err := br.ReadString('\n')
+			if err != nil {
+				t.Fatalf("读取响应失败: %v", err)
+			}
+			if strings.HasPrefix(line, tag+" ") {
+				return strings.TrimSuffix(line, "\r\n")
+			}
+		}
+	}
+	if _, err := br.ReadString('\n'); err != nil { // 读取欢迎信息
+		t.Fatalf("读取欢迎信息失败: %v", err)
+	}
+	if resp := exec("A1", "LOGIN "+testUsername+" "+testPassword); !strings.HasPrefix(resp, "A1 OK") {
+		t.Fatalf("LOGIN 响应 = %q", resp)
+	}
+	if resp := exec("A2", "SELECT INBOX"); !strings.HasPrefix(resp, "A2 OK") {
+		t.Fatalf("SELECT 响应 = %q", resp)
+	}
+	if _, err := io.WriteString(conn, "A3 SEARCH ALL\r\n"); err != nil {
+		t.Fatalf("写入命令失败: %v", err)
+	}
+	<-sess.searchStart
+	conn.Close() // SEARCH 不写入任何响应，只能通过读取连接发现断开
+
+	select {
+	case err := <-sess.searchErr:
+		if err != context.Canceled {
+			t.Errorf("客户端断开后 SEARCH ctx.Err() = %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("客户端断开后 SEARCH ctx 未被取消")
+	}
+}
+
+// TestServer_appendLimit 测试 Options.AppendLimit 的广告和强制，以及内存服务器的每邮箱限制。
+func TestServer_appendLimit(t *testing.T) {
+	limit := uint32(64)
+	server, addr := newTestServer(t, &imapserver.Options{
+		AppendLimit: &limit,
+		Caps:        imap.CapSet{imap.CapIMAP4rev1: {}, imap.CapIMAP4rev2: {}, imap.CapCatenate: {}},
+	})
+	defer server.Close()
+
+	client, err := imapclient.DialInsecure(addr, nil)
+	if err != nil {
+		t.Fatalf("DialInsecure() = %v", err)
+	}
+	defer client.Close()
+	if err := client.Login(testUsername, testPassword).Wait(); err != nil {
+		t.Fatalf("Login().Wait() = %v", err)
+	}
+	if got, ok := client.Caps().AppendLimit(); !ok || got == nil || *got != limit {
+		t.Errorf("Caps().AppendLimit() = %v, %v, want %v", got, ok, limit)
+	}
+
+	appendMessage := func(client *imapclient.Client, mailbox, rawMessage string) error {
+		appendCmd := client.Append(mailbox, int64(len(rawMessage)), nil)
+		appendCmd.Write([]byte(rawMessage))
+		appendCmd.Close()
+		_, err := appendCmd.Wait()
+		return err
+	}
+	isTooBig := func(err error) bool {
+		var imapErr *imap.Error
+		return errors.As(err, &imapErr) && imapErr.Code == imap.ResponseCodeTooBig
+	}
+
+	if err := appendMessage(client, "INBOX", "Subject: small\r\n\r\nHi\r\n"); err != nil {
+		t.Errorf("Append(小邮件) = %v", err)
+	}
+	if err := appendMessage(client, "INBOX", "Subject: big\r\n\r\n"+strings.Repeat("x", 100)+"\r\n"); !isTooBig(err) {
+		t.Errorf("Append(大邮件) = %v, want NO [TOOBIG]", err)
+	}
+	// 被拒绝的非同步字面量已被丢弃，连接仍然可用
+	if err := client.Noop().Wait(); err != nil {
+		t.Errorf("Noop().Wait() = %v", err)
+	}
+
+	// CATENATE 的每个部分都不超过限制，但累计大小超过限制
+	for _, parts := range [][]imap.CatenatePart{
+		{{Text: []byte(strings.Repeat("x", 40))}, {Text: []byte(strings.Repeat("y", 40))}},
+		{{Text: []byte(strings.Repeat("x", 50))}, {URL: "/INBOX/;UID=1"}},
+	} {
+		if _, err := client.Catenate("INBOX", parts, nil).Wait(); !isTooBig(err) {
+			t.Errorf("Catenate(大邮件) = %v, want NO [TOOBIG]", err)
+		}
+	}
+
+	// 内存服务器的每邮箱限制
+	memServer := imapmemserver.New()
+	user := imapmemserver.NewUser(testUsername, testPassword)
+	user.Create("INBOX", nil)
+	user.Create("Archive", nil)
+	mboxLimit := uint32(32)
+	if err := user.SetAppendLimit("INBOX", &mboxLimit); err != nil {
+		t.Fatalf("SetAppendLimit() = %v", err)
+	}
+	memServer.AddUser(user)
+
+	mboxServer := imapserver.New(&imapserver.Options{
+		NewSession: func(conn *imapserver.Conn) (imapserver.Session, *imapserver.GreetingData, error) {
+			return memServer.NewSession(), nil, nil
+		},
+		Caps:         imap.CapSet{imap.CapIMAP4rev1: {}, imap.CapIMAP4rev2: {}, imap.CapAppendLimit: {}},
+		InsecureAuth: true,
+	})
+	defer mboxServer.Close()
+	ln, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("net.Listen() = %v", err)
+	}
+	go mboxServer.Serve(ln)
+
+	client2, err := imapclient.DialInsecure(ln.Addr().String(), nil)
+	if err != nil {
+		t.Fatalf("DialInsecure() = %v", err)
+	}
+	defer client2.Close()
+	if err := client2.Login(testUsername, testPassword).Wait(); err != nil {
+		t.Fatalf("Login().Wait() = %v", err)
+	}
+	if got, ok := client2.Caps().AppendLimit(); !ok || got != nil {
+		t.Errorf("Caps().AppendLimit() = %v, %v, want nil, true", got, ok)
+	}
+
+	for _, tc := range []struct {
+		mailbox string
+		want    uint32
+	}{
+		{"INBOX", mboxLimit},
+		{"Archive", ^uint32(0)}, // NIL 表示不限制
+	} {
+		data, err := client2.Status(tc.mailbox, &imap.StatusOptions{AppendLimit: true}).Wait()
+		if err != nil {
+			t.Fatalf("Status(%v) = %v", tc.mailbox, err)
+		} else if data.AppendLimit == nil || *data.AppendLimit != tc.want {
+			t.Errorf("Status(%v).AppendLimit = %v, want %v", tc.mailbox, data.AppendLimit, tc.want)
+		}
+	}
+
+	rawMessage := "Subject: medium\r\n\r\n" + strings.Repeat("x", 20) + "\r\n"
+	if err := appendMessage(client2, "INBOX", rawMessage); !isTooBig(err) {
+		t.Errorf("Append(INBOX) = %v, want NO [TOOBIG]", err)
+	}
+	if err := appendMessage(client2, "Archive", rawMessage); err != nil {
+		t.Errorf("Append(Archive) = %v", err)
+	}
+}
+
+// TestServer_maxCommandSize 测试超出 MaxCommandSize 的命令被拒绝，且连接可以继续使用。
+func TestServer_maxCommandSize(t *testing.T) {
+	const maxSize = 1024
+	server, addr := newTestServer(t, &imapserver.Options{MaxCommandSize: maxSize})
+	defer server.Close()
+
+	client, err := imapclient.DialInsecure(addr, nil)
+	if err != nil {
+		t.Fatalf("DialInsecure() = %v", err)
+	}
+	defer client.Close()
+	if err := client.Login(testUsername, testPassword).Wait(); err != nil {
+		t.Fatalf("Login().Wait() = %v", err)
+	}
+
+	appendMessage := func(size int) error {
+		rawMessage := "Subject: test\r\n\r\n" + strings.Repeat("x", size) + "\r\n"
+		appendCmd := client.Append("INBOX", int64(len(rawMessage)), nil)
+		appendCmd.Write([]byte(rawMessage))
+		appendCmd.Close()
+		_, err := appendCmd.Wait()
+		return err
+	}
+	checkTooBig := func(name string, err error) {
+		var imapErr *imap.Error
+		if !errors.As(err, &imapErr) || imapErr.Code != imap.ResponseCodeTooBig {
+			t.Errorf("%v = %v, want NO [TOOBIG]", name, err)
+		}
+		if err := client.Noop().Wait(); err != nil {
+			t.Fatalf("%v 之后 Noop().Wait() = %v", name, err)
+		}
+	}
+
+	if err := appendMessage(100); err != nil {
+		t.Fatalf("Append(小邮件) = %v", err)
+	}
+	checkTooBig("Append(非同步字面量)", appendMessage(2000))
+	checkTooBig("Append(同步字面量)", appendMessage(5000))
+
+	if _, err := client.Select("INBOX", nil).Wait(); err != nil {
+		t.Fatalf("Select().Wait() = %v", err)
+	}
+	var uidSet imap.UIDSet
+	for uid := imap.UID(1); uid < 1000; uid += 2 {
+		uidSet.AddNum(uid)
+	}
+	_, err = client.UIDSearch(&imap.SearchCriteria{UID: []imap.UIDSet{uidSet}}, nil).Wait()
+	checkTooBig("UIDSearch(长命令行)", err)
+
+	// 只有第一封邮件被追加
+	data, err := client.Status("INBOX", &imap.StatusOptions{NumMessages: true}).Wait()
+	if err != nil {
+		t.Fatalf("Status().Wait() = %v", err)
+	} else if *data.NumMessages != 1 {
+		t.Errorf("NumMessages = %v, want 1", *data.NumMessages)
+	}
+}
+
+// TestServer_maxLiteralSize 测试缓冲字面量的大小限制。
+func TestServer_maxLiteralSize(t *testing.T) {
+	for _, tc := range []struct {
+		name           string
+		maxLiteralSize int64
+		tooBig         bool
+	}{
+		{"默认", 0, false},
+		{"限制", 2048, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			server, addr := newTestServer(t, &imapserver.Options{MaxLiteralSize: tc.maxLiteralSize})
+			defer server.Close()
+
+			client, err := imapclient.DialInsecure(addr, nil)
+			if err != nil {
+				t.Fatalf("DialInsecure() = %v", err)
+			}
+			defer client.Close()
+			if err := client.Login(testUsername, testPassword).Wait(); err != nil {
+				t.Fatalf("Login().Wait() = %v", err)
+			}
+			if _, err := client.Select("INBOX", nil).Wait(); err != nil {
+				t.Fatalf("Select().Wait() = %v", err)
+			}
+
+			for _, s := range []string{
+				strings.Repeat("a", 5000),         // 同步字面量
+				"a\n" + strings.Repeat("a", 3000), // 非同步字面量（LITERAL-）
+			} {
+				criteria := imap.SearchCriteria{Body: []string{s}}
+				_, err := client.Search(&criteria, nil).Wait()
+				var imapErr *imap.Error
+				if tc.tooBig && (!errors.As(err, &imapErr) || imapErr.Code != imap.ResponseCodeTooBig) {
+					t.Errorf("Search(%v 字节) = %v, want NO [TOOBIG]", len(s), err)
+				} else if !tc.tooBig && err != nil {
+					t.Errorf("Search(%v 字节) = %v", len(s), err)
+				}
+				if err := client.Noop().Wait(); err != nil {
+					t.Fatalf("Noop().Wait() = %v", err)
+				}
+			}
+		})
+	}
+}
+
+// TestServer_persistentStore 测试 NewWithStore 创建的服务器重启后恢复邮件、
+// 标志、UIDVALIDITY 和 UIDNEXT。
+func TestServer_persistentStore(t *testing.T) {
+	dir := t.TempDir()
+
+	// start 使用 dir 中的数据启动一个服务器并登录
+	start := func() (*imapclient.Client, func()) {
+		memServer, err := imapmemserver.NewWithStore(dir)
+		if err != nil {
+			t.Fatalf("NewWithStore() = %v", err)
+		}
+		user := imapmemserver.NewUser(testUsername, testPassword)
+		user.Create("INBOX", nil)
+		if err := memServer.AddUser(user); err != nil {
+			t.Fatalf("AddUser() = %v", err)
+		}
+
+		server := imapserver.New(&imapserver.Options{
+			NewSession: func(conn *imapserver.Conn) (imapserver.Session, *imapserver.GreetingData, error) {
+				return memServer.NewSession(), nil, nil
+			},
+			Caps:         imap.CapSet{imap.CapIMAP4rev1: {}, imap.CapIMAP4rev2: {}},
+			InsecureAuth: true,
+		})
+		ln, err := net.Listen("tcp", "localhost:0")
+		if err != nil {
+			t.Fatalf("net.Listen() = %v", err)
+		}
+		go server.Serve(ln)
+
+		client, err := imapclient.DialInsecure(ln.Addr().String(), nil)
+		if err != nil {
+			t.Fatalf("DialInsecure() = %v", err)
+		}
+		if err := client.Login(testUsername, testPassword).Wait(); err != nil {
+			t.Fatalf("Login().Wait() = %v", err)
+		}
+		return client, func() {
+			client.Close()
+			server.Close()
+		}
+	}
+
+	appendMsg := func(client *imapclient.Client, mailbox, body string) {
+		raw := "Subject: " + body + "\r\n\r\n" + body
+		appendCmd := client.Append(mailbox, int64(len(raw)), nil)
+		appendCmd.Write([]byte(raw))
+		appendCmd.Close()
+		if _, err := appendCmd.Wait(); err != nil {
+			t.Fatalf("AppendCommand.Wait() = %v", err)
+		}
+	}
+
+	client, stop := start()
+	defer func() { stop() }() // stop 会在服务器重启时更新
+	for _, body := range []string{"one", "two", "three"} {
+		appendMsg(client, "INBOX", body)
+	}
+	if err := client.Create("Archive", nil).Wait(); err != nil {
+		t.Fatalf("Create().Wait() = %v", err)
+	}
+	if _, err := client.Select("INBOX", nil).Wait(); err != nil {
+		t.Fatalf("Select().Wait() = %v", err)
+	}
+	storeFlags := imap.StoreFlags{Op: imap.StoreFlagsAdd, Flags: []imap.Flag{imap.FlagSeen}, Silent: true}
+	if err := client.Store(imap.SeqSetNum(3), &storeFlags, nil).Close(); err != nil {
+		t.Fatalf("Store().Close() = %v", err)
+	}
+	storeFlags.Flags = []imap.Flag{imap.FlagDeleted}
+	if err := client.Store(imap.SeqSetNum(1), &storeFlags, nil).Close(); err != nil {
+		t.Fatalf("Store().Close() = %v", err)
+	}
+	if err := client.Expunge().Close(); err != nil {
+		t.Fatalf("Expunge().Close() = %v", err)
+	}
+	before, err := client.Status("Archive", &imap.StatusOptions{UIDValidity: true}).Wait()
+	if err != nil {
+		t.Fatalf("Status().Wait() = %v", err)
+	}
+	stop()
+
+	client, stop = start()
+
+	data, err := client.Select("INBOX", nil).Wait()
+	if err != nil {
+		t.Fatalf("Select().Wait() = %v", err)
+	}
+	if data.NumMessages != 2 || data.UIDNext != 4 {
+		t.Errorf("重启后 NumMessages = %v, UIDNext = %v, want 2, 4", data.NumMessages, data.UIDNext)
+	}
+
+	msgs, err := client.Fetch(imap.SeqSetNum(1, 2), &imap.FetchOptions{
+		UID:         true,
+		Flags:       true,
+		BodySection: []*imap.FetchItemBodySection{{Specifier: imap.PartSpecifierText, Peek: true}},
+	}).Collect()
+	if err != nil {
+		t.Fatalf("Fetch().Collect() = %v", err)
+	}
+	if len(msgs) != 2 {
+		t.Fatalf("len(msgs) = %v, want 2", len(msgs))
+	}
+	want := []struct {
+		uid  imap.UID
+		body string
+		seen bool
+	}{{2, "two", false}, {3, "three", true}}
+	for i, msg := range msgs {
+		text := string(msg.FindBodySection(imap.PartSpecifierText, nil))
+		seen := len(msg.Flags) == 1 && msg.Flags[0] == imap.FlagSeen
+		if msg.UID != want[i].uid || text != want[i].body || seen != want[i].seen {
+			t.Errorf("邮件 %v: UID = %v, 正文 = %q, 标志 = %v", i+1, msg.UID, text, msg.Flags)
+		}
+	}
+
+	after, err := client.Status("Archive", &imap.StatusOptions{UIDValidity: true}).Wait()
+	if err != nil {
+		t.Fatalf("Status().Wait() = %v", err)
+	}
+	if after.UIDValidity != before.UIDValidity {
+		t.Errorf("重启后 Archive UIDVALIDITY = %v, want %v", after.UIDValidity, before.UIDValidity)
+	}
+
+	// 非 PEEK 的 FETCH 设置的 \Seen 标志和 HIGHESTMODSEQ 在重启后保留
+	if err := client.Fetch(imap.SeqSetNum(1), &imap.FetchOptions{
+		BodySection: []*imap.FetchItemBodySection{{}},
+	}).Close(); err != nil {
+		t.Fatalf("Fetch(BODY[]).Close() = %v", err)
+	}
+	seenStatus, err := client.Status("INBOX", &imap.StatusOptions{HighestModSeq: true}).Wait()
+	if err != nil {
+		t.Fatalf("Status().Wait() = %v", err)
+	}
+	stop()
+	client, stop = start()
+	if _, err := client.Select("INBOX", nil).Wait(); err != nil {
+		t.Fatalf("Select().Wait() = %v", err)
+	}
+	msgs, err = client.Fetch(imap.SeqSetNum(1), &imap.FetchOptions{Flags: true}).Collect()
+	if err != nil {
+		t.Fatalf("Fetch().Collect() = %v", err)
+	} else if len(msgs) != 1 {
+		t.Fatalf("len(msgs) = %v, want 1", len(msgs))
+	} else if flags := msgs[0].Flags; len(flags) != 1 || flags[0] != imap.FlagSeen {
+		t.Errorf("重启后邮件 1 的标志 = %v, want [\\Seen]", flags)
+	}
+	status, err := client.Status("INBOX", &imap.StatusOptions{HighestModSeq: true}).Wait()
+	if err != nil {
+		t.Fatalf("Status().Wait() = %v", err)
+	} else if status.HighestModSeq != seenStatus.HighestModSeq {
+		t.Errorf("重启后 HIGHESTMODSEQ = %v, want %v", status.HighestModSeq, seenStatus.HighestModSeq)
+	}
+
+	// 删除并重建的邮箱必须使用不同的 UIDVALIDITY
+	if err := client.Delete("Archive").Wait(); err != nil {
+		t.Fatalf("Delete().Wait() = %v", err)
+	}
+	stop()
+	client, stop = start()
+	if err := client.Create("Archive", nil).Wait(); err != nil {
+		t.Fatalf("Create().Wait() = %v", err)
+	}
+	recreated, err := client.Status("Archive", &imap.StatusOptions{UIDValidity: true}).Wait()
+	if err != nil {
+		t.Fatalf("Status().Wait() = %v", err)
+	}
+	if recreated.UIDValidity == before.UIDValidity {
+		t.Errorf("重建后 UIDVALIDITY = %v, want 不同于 %v", recreated.UIDValidity, before.UIDValidity)
+	}
+}
+
+// TestServer_persistentStoreError 测试写入磁盘失败时 STORE 和 APPEND 不会修改邮箱，
+// 也不会留下无用的邮件文件。
+func TestServer_persistentStoreError(t *testing.T) {
+	dir := t.TempDir()
+	memServer, err := imapmemserver.NewWithStore(dir)
+	if err != nil {
+		t.Fatalf("NewWithStore() = %v", err)
+	}
+	user := imapmemserver.NewUser(testUsername, testPassword)
+	user.Create("INBOX", nil)
+	if err := memServer.AddUser(user); err != nil {
+		t.Fatalf("AddUser() = %v", err)
+	}
+	server := imapserver.New(&imapserver.Options{
+		NewSession: func(conn *imapserver.Conn) (imapserver.Session, *imapserver.GreetingData, error) {
+			return memServer.NewSession(), nil, nil
+		},
+		Caps:         imap.CapSet{imap.CapIMAP4rev1: {}, imap.CapIMAP4rev2: {}},
+		InsecureAuth: true,
+	})
+	defer server.Close()
+	ln, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("net.Listen() = %v", err)
+	}
+	go server.Serve(ln)
+
+	client, err := imapclient.DialInsecure(ln.Addr().String(), nil)
+	if err != nil {
+		t.Fatalf("DialInsecure() = %v", err)
+	}
+	defer client.Close()
+	if err := client.Login(testUsername, testPassword).Wait(); err != nil {
+		t.Fatalf("Login().Wait() = %v", err)
+	}
+	appendMsg := func() (*imap.AppendData, error) {
+		const raw = "Subject: store\r\n\r\nHello\r\n"
+		appendCmd := client.Append("INBOX", int64(len(raw)), nil)
+		appendCmd.Write([]byte(raw))
+		appendCmd.Close()
+		return appendCmd.Wait()
+	}
+	if _, err := appendMsg(); err != nil {
+		t.Fatalf("Append().Wait() = %v", err)
+	}
+	if _, err := client.Select("INBOX", nil).Wait(); err != nil {
+		t.Fatalf("Select().Wait() = %v", err)
+	}
+
+	// 索引的临时文件路径被目录占用，写入索引会失败
+	indexes, err := filepath.Glob(filepath.Join(dir, "*", "*", "index.json"))
+	if err != nil || len(indexes) != 1 {
+		t.Fatalf("Glob(index.json) = %v, %v", indexes, err)
+	}
+	mboxDir := filepath.Dir(indexes[0])
+	if err := os.Mkdir(indexes[0]+".tmp", 0700); err != nil {
+		t.Fatalf("Mkdir() = %v", err)
+	}
+
+	storeFlags := imap.StoreFlags{Op: imap.StoreFlagsAdd, Flags: []imap.Flag{imap.FlagFlagged}, Silent: true}
+	if err := client.Store(imap.SeqSetNum(1), &storeFlags, nil).Close(); err == nil {
+		t.Errorf("Store().Close() = nil, want error")
+	}
+	msgs, err := client.Fetch(imap.SeqSetNum(1), &imap.FetchOptions{Flags: true}).Collect()
+	if err != nil {
+		t.Fatalf("Fetch().Collect() = %v", err)
+	} else if len(msgs) != 1 {
+		t.Fatalf("len(msgs) = %v, want 1", len(msgs))
+	}
+	for _, flag := range msgs[0].Flags {
+		if flag == imap.FlagFlagged {
+			t.Errorf("写入失败后标志 = %v, want 没有 \\Flagged", msgs[0].Flags)
+		}
+	}
+
+	if _, err := appendMsg(); err == nil {
+		t.Errorf("Append().Wait() = nil, want error")
+	}
+	if files, _ := os.ReadDir(filepath.Join(mboxDir, "cur")); len(files) != 1 {
+		t.Errorf("cur 中有 %v 个文件，want 1", len(files))
+	}
+
+	// 恢复后 UID 保持连续
+	if err := os.Remove(indexes[0] + ".tmp"); err != nil {
+		t.Fatalf("Remove() = %v", err)
+	}
+	if data, err := appendMsg(); err != nil {
+		t.Fatalf("Append().Wait() = %v", err)
+	} else if data.UID != 2 {
+		t.Errorf("Append() UID = %v, want 2", data.UID)
+	}
+}
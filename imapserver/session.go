@@ -2,6 +2,8 @@
 package imapserver
 
 import (
+	"context"
+	"crypto/x509"
 	"fmt"
 
 	"github.com/emersion/go-sasl"
@@ -22,6 +24,11 @@ var ErrAuthFailed = errAuthFailed
 // GreetingData 是与 IMAP 问候相关的数据。
 type GreetingData struct {
 	PreAuth bool // 是否预先认证
+
+	// InsecureHiddenCaps 列出连接未加密时不宣告的能力，例如 "AUTH=PLAIN"。
+	// 它们不会出现在欢迎信息和 CAPABILITY 响应中，直到连接通过 STARTTLS
+	// 加密。这只影响能力的宣告，不会拒绝对应的命令。
+	InsecureHiddenCaps []imap.Cap
 }
 
 // NumKind 描述一个数字应如何被解释：可以是序列号或 UID。
@@ -94,6 +101,184 @@ type SessionMove interface {
 	Move(w *MoveWriter, numSet imap.NumSet, dest string) error // 移动邮件
 }
 
+// SessionMultiAppend 是一个支持 MULTIAPPEND 的 IMAP 会话。
+//
+// 当服务器启用 MULTIAPPEND 能力时，所有 APPEND 命令都将通过 MultiAppend 处理。
+type SessionMultiAppend interface {
+	Session
+
+	// 认证状态
+	MultiAppend(mailbox string) (MultiAppender, error) // 开始批量追加邮件
+}
+
+// MultiAppender 在一个 APPEND 命令中批量追加邮件，参见 RFC 3502。
+//
+// 服务器对每封邮件调用 Append，最后调用 Commit。如果命令失败，服务器将调用
+// Abort 代替 Commit，此时不应追加任何邮件。
+type MultiAppender interface {
+	Append(r imap.LiteralReader, options *imap.AppendOptions) error // 追加一封邮件
+	Commit() (*imap.AppendData, error)                              // 提交所有邮件
+	Abort()                                                         // 放弃所有邮件
+}
+
+// SessionCatenate 是一个支持 CATENATE 的 IMAP 会话。
+type SessionCatenate interface {
+	Session
+
+	// 认证状态
+	FetchURL(url *CatenateURL) ([]byte, error) // 获取 URL 引用的邮件内容
+}
+
+// SessionQResync 是一个支持 QRESYNC 的 IMAP 会话，参见 RFC 7162。
+//
+// 声明 QRESYNC 的服务器必须通过 MailboxTracker.QueueExpungeUID 报告删除的邮件，
+// 以便向启用了 QRESYNC 的客户端发送 VANISHED 响应。
+type SessionQResync interface {
+	Session
+
+	// 选择状态
+	//
+	// Vanished 返回 uids 中自修改序列号 modSeq 以来被删除的邮件 UID。
+	// 如果会话不记录邮件被删除时的修改序列号，可以返回更早删除的 UID。
+	Vanished(uids imap.UIDSet, modSeq uint64) (imap.UIDSet, error)
+}
+
+// SessionACL 是一个支持 ACL 的 IMAP 会话。
+type SessionACL interface {
+	Session
+
+	// 认证状态
+	SetACL(mailbox string, ri imap.RightsIdentifier, rm imap.RightModification, rs imap.RightSet) error // 修改标识符的权限
+	DeleteACL(mailbox string, ri imap.RightsIdentifier) error                                           // 删除标识符的所有权限
+	GetACL(mailbox string) (map[imap.RightsIdentifier]imap.RightSet, error)                             // 获取邮箱的访问控制列表
+	// ListRights 返回总是授予标识符的权限，以及可以授予的权限组
+	ListRights(mailbox string, ri imap.RightsIdentifier) (required imap.RightSet, optional []imap.RightSet, err error)
+	MyRights(mailbox string) (imap.RightSet, error) // 获取当前用户的权限
+}
+
+// SessionQuota 是一个支持 QUOTA 的 IMAP 会话，参见 RFC 9208。
+type SessionQuota interface {
+	Session
+
+	// 认证状态
+	GetQuota(root string) (*imap.QuotaData, error) // 获取配额根的资源使用情况和限制
+	GetQuotaRoot(mailbox string) ([]string, error) // 获取邮箱所属的配额根
+	// SetQuota 设置配额根的资源限制，limits 中没有的资源不再受限制。要求 QUOTASET
+	SetQuota(root string, limits map[imap.QuotaResourceType]int64) error
+}
+
+// SessionMetadata 是一个支持 METADATA 的 IMAP 会话，参见 RFC 5464。
+//
+// mailbox 为空字符串表示服务器元数据。条目名称已被转换为小写。
+type SessionMetadata interface {
+	Session
+
+	// 认证状态
+	// GetMetadata 返回 entries 中的条目，以及 options.Depth 指定深度内的子条目。
+	// 不存在的条目的值为 nil。options.MaxSize 由服务器处理，会话可以忽略
+	GetMetadata(mailbox string, entries []string, options *imap.GetMetadataOptions) (map[string]*[]byte, error)
+	// SetMetadata 设置条目的值，值为 nil 表示删除该条目
+	SetMetadata(mailbox string, entries map[string]*[]byte) error
+}
+
+// SessionObjectID 是一个支持 OBJECTID 的 IMAP 会话（RFC 8474）。
+//
+// 除 MailboxID 外，会话还必须处理对象 ID 相关的选项：Status 在 StatusOptions.MailboxID
+// 时填写 StatusData.MailboxID，Fetch 通过 FetchResponseWriter.WriteEmailID 和
+// WriteThreadID 返回 EMAILID 和 THREADID，Search 支持 EmailID 和 ThreadID 搜索条件。
+type SessionObjectID interface {
+	Session
+
+	// 认证状态
+	MailboxID(mailbox string) (string, error) // 获取邮箱的稳定 ID，邮箱重命名后保持不变
+}
+
+// SessionContext 是一个支持 context.Context 的 IMAP 会话。
+//
+// 如果会话实现了此接口，服务器将调用带 Context 后缀的方法代替 Session 中的同名方法。
+// 传入的 ctx 在命令完成、服务器关闭连接或连接读写出错时被取消，后端（如数据库）可以借此
+// 及时中止耗时的查询。执行 SELECT、LIST、STATUS、SEARCH、FETCH、STORE、COPY、MOVE 和
+// EXPUNGE 期间，服务器在后台读取连接，客户端断开连接时立即取消 ctx；APPEND 在读取字面量
+// 出错时取消，IDLE 在等待 DONE 时读取失败时取消。客户端在命令完成之前发送了下一个命令时，
+// 服务器无法再发现断开连接，直到写出响应失败。
+//
+// PollContext 的 ctx 属于触发轮询的命令，IdleContext 的 ctx 在 IDLE 命令结束时被取消。
+type SessionContext interface {
+	Session
+
+	// 认证状态
+	SelectContext(ctx context.Context, mailbox string, options *imap.SelectOptions) (*imap.SelectData, error)
+	ListContext(ctx context.Context, w *ListWriter, ref string, patterns []string, options *imap.ListOptions) error
+	StatusContext(ctx context.Context, mailbox string, options *imap.StatusOptions) (*imap.StatusData, error)
+	AppendContext(ctx context.Context, mailbox string, r imap.LiteralReader, options *imap.AppendOptions) (*imap.AppendData, error)
+
+	// 选择状态
+	ExpungeContext(ctx context.Context, w *ExpungeWriter, uids *imap.UIDSet) error
+	SearchContext(ctx context.Context, kind NumKind, criteria *imap.SearchCriteria, options *imap.SearchOptions) (*imap.SearchData, error)
+	FetchContext(ctx context.Context, w *FetchWriter, numSet imap.NumSet, options *imap.FetchOptions) error
+	StoreContext(ctx context.Context, w *FetchWriter, numSet imap.NumSet, flags *imap.StoreFlags, options *imap.StoreOptions) error
+	CopyContext(ctx context.Context, numSet imap.NumSet, dest string) (*imap.CopyData, error)
+
+	// 更新
+	PollContext(ctx context.Context, w *UpdateWriter, allowExpunge bool) error
+	IdleContext(ctx context.Context, w *UpdateWriter, stop <-chan struct{}) error
+}
+
+// SessionMoveContext 是一个支持 context.Context 的 SessionMove，参见 SessionContext。
+type SessionMoveContext interface {
+	SessionMove
+
+	// 选择状态
+	MoveContext(ctx context.Context, w *MoveWriter, numSet imap.NumSet, dest string) error
+}
+
+// SessionMultiAppendContext 是一个支持 context.Context 的 SessionMultiAppend，参见 SessionContext。
+//
+// ctx 在整个 APPEND 命令完成后才被取消，因此返回的 MultiAppender 可以继续使用它。
+type SessionMultiAppendContext interface {
+	SessionMultiAppend
+
+	// 认证状态
+	MultiAppendContext(ctx context.Context, mailbox string) (MultiAppender, error)
+}
+
+// SessionSort 是一个支持 SORT 的 IMAP 会话，参见 RFC 5256。
+type SessionSort interface {
+	Session
+
+	// 选择状态
+	//
+	// Sort 返回符合 searchCriteria 的邮件的序列号或 UID（取决于 kind），
+	// 按 sortCriteria 排序。排序键相同的邮件按序列号排列。
+	Sort(kind NumKind, sortCriteria []imap.SortCriterion, searchCriteria *imap.SearchCriteria) ([]uint32, error)
+}
+
+// SessionThread 是一个支持 THREAD 的 IMAP 会话，参见 RFC 5256。
+//
+// 服务器通过 Options.Caps 中的 "THREAD=<算法>" 声明支持的线程算法，
+// 只有声明过的算法才会传给 Thread。
+type SessionThread interface {
+	Session
+
+	// 选择状态
+	//
+	// Thread 使用 algorithm 将符合 searchCriteria 的邮件组织成线程，
+	// 线程中的邮件由序列号或 UID（取决于 kind）表示。
+	Thread(kind NumKind, algorithm imap.ThreadAlgorithm, searchCriteria *imap.SearchCriteria) ([]imap.ThreadData, error)
+}
+
+// SessionMultiSearch 是一个支持 MULTISEARCH 的 IMAP 会话，参见 RFC 7377。
+type SessionMultiSearch interface {
+	Session
+
+	// 认证状态
+	//
+	// MultiSearch 在 sources 指定的邮箱中搜索符合 criteria 的邮件，每个邮箱返回一个结果，
+	// 结果中的编号为 UID。不存在或无权访问的邮箱应被跳过。
+	// MultiSearchFilterSelected 表示当前选择的邮箱（如果有的话）
+	MultiSearch(sources []imap.MultiSearchSource, criteria *imap.SearchCriteria, options *imap.SearchOptions) ([]imap.MultiSearchData, error)
+}
+
 // SessionIMAP4rev2 是一个支持 IMAP4rev2 的 IMAP 会话。
 type SessionIMAP4rev2 interface {
 	Session
@@ -108,6 +293,17 @@ type SessionSASL interface {
 	Authenticate(mech string) (sasl.Server, error) // 执行认证
 }
 
+// SessionExternal 是一个支持使用 TLS 客户端证书登录的 IMAP 会话。
+//
+// 客户端提供了经 Options.ClientCAs 验证的证书时，服务器会声明 AUTH=EXTERNAL，
+// 并在客户端使用 SASL EXTERNAL 机制时调用 AuthenticateExternal。
+type SessionExternal interface {
+	Session
+
+	// 未认证状态
+	AuthenticateExternal(cert *x509.Certificate) error // 使用已验证的客户端证书登录
+}
+
 // SessionUnauthenticate 是一个支持 UNAUTHENTICATE 的 IMAP 会话。
 type SessionUnauthenticate interface {
 	Session
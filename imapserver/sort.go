@@ -0,0 +1,91 @@
+package imapserver
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/luhaoyun888/go-imap-cn"
+	"github.com/luhaoyun888/go-imap-cn/internal/imapwire"
+)
+
+// handleSort 处理 SORT 命令，参见 RFC 5256。
+func (c *Conn) handleSort(dec *imapwire.Decoder, numKind NumKind) error {
+	var sortCriteria []imap.SortCriterion
+	if !dec.ExpectSP() {
+		return dec.Err()
+	}
+	err := dec.ExpectList(func() error {
+		criterion, err := readSortCriterion(dec)
+		if err != nil {
+			return err
+		}
+		sortCriteria = append(sortCriteria, *criterion)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var charset string
+	if !dec.ExpectSP() || !dec.ExpectAString(&charset) || !dec.ExpectSP() {
+		return dec.Err()
+	}
+	if err := checkSearchCharset(charset); err != nil {
+		return err
+	}
+
+	var criteria imap.SearchCriteria
+	for {
+		if err := readSearchKey(&criteria, dec); err != nil {
+			return fmt.Errorf("在 search-key 中: %w", err)
+		}
+		if !dec.SP() {
+			break
+		}
+	}
+	if !dec.ExpectCRLF() {
+		return dec.Err()
+	}
+
+	if err := c.checkState(imap.ConnStateSelected); err != nil {
+		return err
+	}
+	session, ok := c.session.(SessionSort)
+	if !ok {
+		return newClientBugError("不支持 SORT")
+	}
+	nums, err := session.Sort(numKind, sortCriteria, &criteria)
+	if err != nil {
+		return err
+	}
+
+	enc := newResponseEncoder(c)
+	defer enc.end()
+	enc.Atom("*").SP().Atom("SORT")
+	for _, num := range nums {
+		enc.SP().Number(num)
+	}
+	return enc.CRLF()
+}
+
+// readSortCriterion 读取一个排序条件，可以带有 REVERSE 前缀。
+func readSortCriterion(dec *imapwire.Decoder) (*imap.SortCriterion, error) {
+	var criterion imap.SortCriterion
+	var key string
+	if !dec.ExpectAtom(&key) {
+		return nil, dec.Err()
+	}
+	if strings.EqualFold(key, "REVERSE") {
+		criterion.Reverse = true
+		if !dec.ExpectSP() || !dec.ExpectAtom(&key) {
+			return nil, dec.Err()
+		}
+	}
+	switch k := imap.SortKey(strings.ToUpper(key)); k {
+	case imap.SortKeyArrival, imap.SortKeyCc, imap.SortKeyDate, imap.SortKeyFrom, imap.SortKeySize, imap.SortKeySubject, imap.SortKeyTo:
+		criterion.Key = k
+	default:
+		return nil, newClientBugError("未知的排序关键字")
+	}
+	return &criterion, nil
+}
@@ -53,18 +53,18 @@ func (c *Conn) handleStartTLS(tag string, dec *imapwire.Decoder) error {
 		panic(err) // 不可达
 	}
 
-	var cleartextConn net.Conn
+	transport := c.transportReadWriter(c.conn) // 速率限制位于 TLS 之下
+	r := io.Reader(transport)
 	if buf.Len() > 0 { // 如果缓冲区有数据
-		r := io.MultiReader(&buf, c.conn)       // 将缓冲数据与当前连接合并
-		cleartextConn = startTLSConn{c.conn, r} // 创建新的连接
-	} else {
-		cleartextConn = c.conn // 使用当前连接
+		r = io.MultiReader(&buf, transport) // 将缓冲数据与当前连接合并
 	}
+	cleartextConn := startTLSConn{c.conn, r, transport} // 创建新的连接
 
-	tlsConn := tls.Server(cleartextConn, c.server.options.TLSConfig) // 创建 TLS 连接
+	tlsConn := tls.Server(cleartextConn, c.server.options.tlsConfig()) // 创建 TLS 连接
 
 	c.mutex.Lock()
 	c.conn = tlsConn // 更新连接为 TLS 连接
+	c.connLimited = true
 	c.mutex.Unlock()
 
 	rw := c.server.options.wrapReadWriter(tlsConn) // 包装读写器
@@ -78,9 +78,15 @@ func (c *Conn) handleStartTLS(tag string, dec *imapwire.Decoder) error {
 type startTLSConn struct {
 	net.Conn           // 嵌入 net.Conn
 	r        io.Reader // 读取器
+	w        io.Writer // 写入器
 }
 
 // Read 从读取器中读取数据。
 func (conn startTLSConn) Read(b []byte) (int, error) {
 	return conn.r.Read(b) // 调用读取器的 Read 方法
 }
+
+// Write 向写入器中写入数据。
+func (conn startTLSConn) Write(b []byte) (int, error) {
+	return conn.w.Write(b)
+}
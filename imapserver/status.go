@@ -16,16 +16,9 @@ func (c *Conn) handleStatus(dec *imapwire.Decoder) error {
 	}
 
 	var options imap.StatusOptions
-	recent := false
 	// 解析状态项
 	err := dec.ExpectList(func() error {
-		isRecent, err := readStatusItem(dec, &options) // 读取状态项
-		if err != nil {
-			return err // 返回读取错误
-		} else if isRecent {
-			recent = true // 如果状态项是 RECENT，设置标志
-		}
-		return nil
+		return readStatusItem(dec, &options) // 读取状态项
 	})
 	if err != nil {
 		return err // 返回解析错误
@@ -38,17 +31,22 @@ func (c *Conn) handleStatus(dec *imapwire.Decoder) error {
 	if err := c.checkState(imap.ConnStateAuthenticated); err != nil { // 检查连接状态是否为已认证
 		return err
 	}
+	if options.MailboxID {
+		if err := c.checkCap(imap.CapObjectID); err != nil {
+			return err
+		}
+	}
 
-	data, err := c.session.Status(mailbox, &options) // 调用会话的 Status 方法
+	data, err := c.sessionStatus(mailbox, &options) // 调用会话的 Status 方法
 	if err != nil {
 		return err // 返回状态查询错误
 	}
 
-	return c.writeStatus(data, &options, recent) // 写入状态响应
+	return c.writeStatus(data, &options) // 写入状态响应
 }
 
 // writeStatus 将状态数据写入响应中。
-func (c *Conn) writeStatus(data *imap.StatusData, options *imap.StatusOptions, recent bool) error {
+func (c *Conn) writeStatus(data *imap.StatusData, options *imap.StatusOptions) error {
 	enc := newResponseEncoder(c) // 创建响应编码器
 	defer enc.end()              // 确保在函数结束时释放编码器
 
@@ -84,8 +82,19 @@ func (c *Conn) writeStatus(data *imap.StatusData, options *imap.StatusOptions, r
 	if options.DeletedStorage {
 		listEnc.Item().Atom("DELETED-STORAGE").SP().Number64(*data.DeletedStorage) // 写入已删除存储
 	}
-	if recent {
-		listEnc.Item().Atom("RECENT").SP().Number(0) // 写入 RECENT 标志
+	if options.HighestModSeq {
+		listEnc.Item().Atom("HIGHESTMODSEQ").SP().ModSeq(data.HighestModSeq) // 写入最高的修改序列号
+	}
+	if options.NumRecent {
+		// 会话可能不支持 RECENT（IMAP4rev2 已将其废弃），此时返回 0
+		var num uint32
+		if data.NumRecent != nil {
+			num = *data.NumRecent
+		}
+		listEnc.Item().Atom("RECENT").SP().Number(num) // 写入最近邮件数量
+	}
+	if options.MailboxID && data.MailboxID != "" {
+		listEnc.Item().Atom("MAILBOXID").SP().Special('(').Atom(data.MailboxID).Special(')') // 写入邮箱对象 ID
 	}
 	listEnc.End() // 结束列表
 
@@ -93,10 +102,10 @@ func (c *Conn) writeStatus(data *imap.StatusData, options *imap.StatusOptions, r
 }
 
 // readStatusItem 读取状态项并更新选项。
-func readStatusItem(dec *imapwire.Decoder, options *imap.StatusOptions) (isRecent bool, err error) {
+func readStatusItem(dec *imapwire.Decoder, options *imap.StatusOptions) error {
 	var name string
 	if !dec.ExpectAtom(&name) { // 读取状态项名称
-		return false, dec.Err() // 返回解码错误
+		return dec.Err() // 返回解码错误
 	}
 	switch strings.ToUpper(name) { // 将名称转为大写以进行匹配
 	case "MESSAGES":
@@ -115,13 +124,17 @@ func readStatusItem(dec *imapwire.Decoder, options *imap.StatusOptions) (isRecen
 		options.AppendLimit = true // 设置追加限制标志
 	case "DELETED-STORAGE":
 		options.DeletedStorage = true // 设置已删除存储标志
+	case "HIGHESTMODSEQ":
+		options.HighestModSeq = true // 设置最高的修改序列号标志
 	case "RECENT":
-		isRecent = true // 设置 RECENT 标志
+		options.NumRecent = true // 设置最近邮件数量标志
+	case "MAILBOXID":
+		options.MailboxID = true // 设置邮箱对象 ID 标志
 	default:
-		return false, &imap.Error{
+		return &imap.Error{
 			Type: imap.StatusResponseTypeBad,
 			Text: "未知的 STATUS 数据项", // 返回未知状态项错误
 		}
 	}
-	return isRecent, nil // 返回读取结果
+	return nil
 }
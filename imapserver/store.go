@@ -9,19 +9,29 @@ import (
 )
 
 // handleStore 处理 STORE 命令。
-func (c *Conn) handleStore(dec *imapwire.Decoder, numKind NumKind) error {
+func (c *Conn) handleStore(tag string, dec *imapwire.Decoder, numKind NumKind) error {
 	var (
-		numSet imap.NumSet // 存储的消息集合
-		item   string      // 要修改的项目
+		numSet  imap.NumSet       // 存储的消息集合
+		item    string            // 要修改的项目
+		options imap.StoreOptions // 存储选项
 	)
 
 	// 检查命令格式，确保包括数字集合和项目名称
-	if !dec.ExpectSP() || !dec.ExpectNumSet(numKind.wire(), &numSet) || !dec.ExpectSP() || !dec.ExpectAtom(&item) || !dec.ExpectSP() {
+	if !dec.ExpectSP() || !dec.ExpectNumSet(numKind.wire(), &numSet) || !dec.ExpectSP() {
+		return dec.Err() // 返回解码错误
+	}
+	isList, err := readStoreModifiers(dec, &options) // 读取可选的存储修饰符
+	if err != nil {
+		return err
+	} else if isList && !dec.ExpectSP() {
+		return dec.Err()
+	}
+	if !dec.ExpectAtom(&item) || !dec.ExpectSP() {
 		return dec.Err() // 返回解码错误
 	}
 
 	var flags []imap.Flag // 存储标志
-	isList, err := dec.List(func() error {
+	isList, err = dec.List(func() error {
 		flag, err := internal.ExpectFlag(dec) // 读取标志
 		if err != nil {
 			return err // 返回读取错误
@@ -71,12 +81,66 @@ func (c *Conn) handleStore(dec *imapwire.Decoder, numKind NumKind) error {
 	if err := c.checkState(imap.ConnStateSelected); err != nil { // 检查连接状态是否为已选择
 		return err
 	}
+	if err := c.checkWritable(); err != nil { // 只读邮箱不能修改标志
+		return err
+	}
 
-	w := &FetchWriter{conn: c}     // 创建 FetchWriter
-	options := imap.StoreOptions{} // 创建存储选项
-	return c.session.Store(w, numSet, &imap.StoreFlags{
+	w := &FetchWriter{conn: c} // 创建 FetchWriter
+	err = c.sessionStore(w, numSet, &imap.StoreFlags{
 		Op:     op,
 		Silent: silent,
 		Flags:  flags,
 	}, &options) // 调用会话的 Store 方法
+	if err != nil {
+		return err
+	}
+
+	cmdName := "STORE"
+	if numKind == NumKindUID {
+		cmdName = "UID STORE"
+	}
+	if err := c.poll(cmdName); err != nil {
+		return err
+	}
+
+	return c.writeStoreOK(tag, w.modified) // 写入成功响应
+}
+
+// writeStoreOK 写入成功的 STORE 响应。
+//
+// 如果 modified 非空，则附带 MODIFIED 响应码，列出因 UNCHANGEDSINCE 条件
+// 未满足而未被修改的消息。
+func (c *Conn) writeStoreOK(tag string, modified imap.NumSet) error {
+	enc := newResponseEncoder(c) // 创建响应编码器
+	defer enc.end()
+
+	if tag == "" {
+		tag = "*"
+	}
+
+	enc.Atom(tag).SP().Atom("OK").SP()
+	if modified != nil && !isNumSetEmpty(modified) {
+		enc.Special('[').Atom("MODIFIED").SP().NumSet(modified).Special(']').SP()
+	}
+	enc.Text("STORE 完成")
+	return enc.CRLF()
+}
+
+// readStoreModifiers 读取 STORE 命令的可选修饰符列表，例如 UNCHANGEDSINCE。
+func readStoreModifiers(dec *imapwire.Decoder, options *imap.StoreOptions) (isList bool, err error) {
+	return dec.List(func() error {
+		var name string
+		if !dec.ExpectAtom(&name) || !dec.ExpectSP() {
+			return dec.Err()
+		}
+		switch strings.ToUpper(name) {
+		case "UNCHANGEDSINCE":
+			if !dec.ExpectModSeq(&options.UnchangedSince) {
+				return dec.Err()
+			}
+		default:
+			return newClientBugError("未知的 STORE 修饰符")
+		}
+		return nil
+	})
 }
@@ -0,0 +1,79 @@
+package imapserver
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/luhaoyun888/go-imap-cn"
+	"github.com/luhaoyun888/go-imap-cn/internal/imapwire"
+)
+
+// handleThread 处理 THREAD 命令，参见 RFC 5256。
+func (c *Conn) handleThread(dec *imapwire.Decoder, numKind NumKind) error {
+	var algStr, charset string
+	if !dec.ExpectSP() || !dec.ExpectAtom(&algStr) || !dec.ExpectSP() || !dec.ExpectAString(&charset) || !dec.ExpectSP() {
+		return dec.Err()
+	}
+	if err := checkSearchCharset(charset); err != nil {
+		return err
+	}
+
+	var criteria imap.SearchCriteria
+	for {
+		if err := readSearchKey(&criteria, dec); err != nil {
+			return fmt.Errorf("在 search-key 中: %w", err)
+		}
+		if !dec.SP() {
+			break
+		}
+	}
+	if !dec.ExpectCRLF() {
+		return dec.Err()
+	}
+
+	if err := c.checkState(imap.ConnStateSelected); err != nil {
+		return err
+	}
+
+	alg := imap.ThreadAlgorithm(strings.ToUpper(algStr))
+	if !c.server.options.caps().Has(imap.Cap("THREAD=" + string(alg))) {
+		return newClientBugError("不支持的线程算法")
+	}
+	session, ok := c.session.(SessionThread)
+	if !ok {
+		return newClientBugError("不支持 THREAD")
+	}
+	threads, err := session.Thread(numKind, alg, &criteria)
+	if err != nil {
+		return err
+	}
+
+	enc := newResponseEncoder(c)
+	defer enc.end()
+	enc.Atom("*").SP().Atom("THREAD")
+	if len(threads) > 0 {
+		enc.SP()
+	}
+	for i := range threads {
+		writeThread(enc.Encoder, &threads[i])
+	}
+	return enc.CRLF()
+}
+
+// writeThread 写入一个线程列表，格式参见 RFC 5256 第 4 节中的 thread-list。
+func writeThread(enc *imapwire.Encoder, thread *imap.ThreadData) {
+	enc.Special('(')
+	for i, num := range thread.Chain {
+		if i > 0 {
+			enc.SP()
+		}
+		enc.Number(num)
+	}
+	if len(thread.Chain) > 0 && len(thread.SubThreads) > 0 {
+		enc.SP()
+	}
+	for i := range thread.SubThreads {
+		writeThread(enc, &thread.SubThreads[i]) // 子线程之间没有空格
+	}
+	enc.Special(')')
+}
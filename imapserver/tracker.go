@@ -1,12 +1,24 @@
 package imapserver
 
 import (
+	"errors"
 	"fmt"
 	"sync"
 
 	"github.com/luhaoyun888/go-imap-cn"
 )
 
+// maxSessionTrackerQueue 是每个会话待处理更新队列长度的基础上限。
+//
+// 客户端不读取服务器推送时（例如 IDLE 期间从不读取），队列会不断增长。
+// 同一封邮件的标志更新和连续的 EXISTS 更新会被合并，因此单个命令（例如对整个邮箱执行
+// STORE 或 EXPUNGE）产生的更新数量不超过邮箱大小。实际上限为此值加上客户端视图和
+// 邮箱中邮件数量之和的两倍，超过后会话的队列被丢弃，并在下次轮询时以 BYE 断开连接。
+const maxSessionTrackerQueue = 4096
+
+// errSessionTrackerOverflow 表示会话待处理的更新超过了上限。
+var errSessionTrackerOverflow = errors.New("imapserver: 待处理的邮箱更新超过上限")
+
 // MailboxTracker 用于跟踪邮箱的状态。
 //
 // 一个邮箱可以有多个会话监听更新。每个会话都有自己对邮箱的视图，
@@ -31,6 +43,7 @@ func NewMailboxTracker(numMessages uint32) *MailboxTracker {
 func (t *MailboxTracker) NewSession() *SessionTracker {
 	st := &SessionTracker{mailbox: t} // 创建新的会话跟踪器
 	t.mutex.Lock()
+	st.numMessages = t.numMessages
 	t.sessions[st] = struct{}{} // 将新会话添加到会话列表
 	t.mutex.Unlock()
 	return st
@@ -50,12 +63,16 @@ func (t *MailboxTracker) queueUpdate(update *trackerUpdate, source *SessionTrack
 		panic(fmt.Errorf("imapserver: 不能将邮箱邮件数量从 %v 减少到 %v", t.numMessages, update.numMessages))
 	}
 
+	if update.numMessages != 0 {
+		update.prevNumMessages = t.numMessages // 记录更新前的邮件数量
+	}
+
 	// 将更新通知给所有会话
 	for st := range t.sessions {
 		if source != nil && st == source {
 			continue // 跳过源会话
 		}
-		st.queueUpdate(update)
+		st.queueUpdate(update, t.numMessages)
 	}
 
 	// 更新邮箱邮件数量
@@ -69,15 +86,21 @@ func (t *MailboxTracker) queueUpdate(update *trackerUpdate, source *SessionTrack
 
 // QueueExpunge 将新的 EXPUNGE 更新排入队列。
 func (t *MailboxTracker) QueueExpunge(seqNum uint32) {
+	t.QueueExpungeUID(seqNum, 0)
+}
+
+// QueueExpungeUID 将新的 EXPUNGE 更新排入队列，并附带被删除邮件的 UID。
+//
+// 对于启用了 QRESYNC 的客户端，该更新将以 VANISHED 响应发送。
+func (t *MailboxTracker) QueueExpungeUID(seqNum uint32, uid imap.UID) {
 	if seqNum == 0 {
 		panic("imapserver: 无效的删除邮件序号")
 	}
-	t.queueUpdate(&trackerUpdate{expunge: seqNum}, nil)
+	t.queueUpdate(&trackerUpdate{expunge: seqNum, expungeUID: uid}, nil)
 }
 
 // QueueNumMessages 将新的 EXISTS 更新排入队列。
 func (t *MailboxTracker) QueueNumMessages(n uint32) {
-	// TODO: 合并连续的 NumMessages 更新
 	t.queueUpdate(&trackerUpdate{numMessages: n}, nil)
 }
 
@@ -102,10 +125,17 @@ func (t *MailboxTracker) QueueMessageFlags(seqNum uint32, uid imap.UID, flags []
 
 // trackerUpdate 结构体用于跟踪邮箱的更新。
 type trackerUpdate struct {
-	expunge      uint32              // 要删除的邮件序号
-	numMessages  uint32              // 当前邮件数量
-	mailboxFlags []imap.Flag         // 邮箱标志
-	fetch        *trackerUpdateFetch // FETCH 更新
+	expunge         uint32              // 要删除的邮件序号
+	expungeUID      imap.UID            // 要删除的邮件 UID，未知时为零
+	numMessages     uint32              // 当前邮件数量
+	prevNumMessages uint32              // 此更新之前的邮件数量
+	mailboxFlags    []imap.Flag         // 邮箱标志
+	fetch           *trackerUpdateFetch // FETCH 更新
+}
+
+// isMerged 报告更新是否已被合并到队列中的其他更新里，不需要再发送。
+func (update *trackerUpdate) isMerged() bool {
+	return update.expunge == 0 && update.numMessages == 0 && update.mailboxFlags == nil && update.fetch == nil
 }
 
 // trackerUpdateFetch 结构体用于跟踪邮件获取更新。
@@ -119,9 +149,13 @@ type trackerUpdateFetch struct {
 type SessionTracker struct {
 	mailbox *MailboxTracker // 关联的邮箱跟踪器
 
-	mutex   sync.Mutex      // 互斥锁，用于保护会话状态的并发访问
-	queue   []trackerUpdate // 待处理的更新队列
-	updates chan<- struct{} // 更新通知通道
+	mutex       sync.Mutex       // 互斥锁，用于保护会话状态的并发访问
+	queue       []trackerUpdate  // 待处理的更新队列
+	numQueued   int              // 队列中未被合并掉的更新数量
+	fetchByUID  map[imap.UID]int // 待处理的 FETCH 更新在队列中的位置，按 UID 索引
+	numMessages uint32           // 客户端视图中的邮件数量
+	overflow    bool             // 队列是否超过上限，超过后会话需要断开
+	updates     chan<- struct{}  // 更新通知通道
 }
 
 // Close 注销会话。
@@ -132,11 +166,21 @@ func (t *SessionTracker) Close() {
 	t.mailbox = nil // 清空邮箱引用
 }
 
-// queueUpdate 将更新排入会话的队列。
-func (t *SessionTracker) queueUpdate(update *trackerUpdate) {
+// queueUpdate 将更新排入会话的队列。mailboxNumMessages 是更新之前邮箱中的邮件数量。
+func (t *SessionTracker) queueUpdate(update *trackerUpdate, mailboxNumMessages uint32) {
 	var updates chan<- struct{}
 	t.mutex.Lock()
-	t.queue = append(t.queue, *update) // 将更新添加到队列
+	if !t.overflow {
+		t.mergeUpdateLocked(update)
+		limit := maxSessionTrackerQueue + 2*(int(t.numMessages)+int(mailboxNumMessages))
+		if t.numQueued > limit {
+			// 客户端没有及时读取更新：丢弃队列以限制内存占用，下次轮询时断开连接
+			t.overflow = true
+			t.queue = nil
+			t.numQueued = 0
+			t.fetchByUID = nil
+		}
+	}
 	updates = t.updates
 	t.mutex.Unlock()
 
@@ -150,10 +194,60 @@ func (t *SessionTracker) queueUpdate(update *trackerUpdate) {
 	}
 }
 
+// mergeUpdateLocked 将更新添加到队列，并与队列中已有的更新合并。
+func (t *SessionTracker) mergeUpdateLocked(update *trackerUpdate) {
+	var last *trackerUpdate
+	if len(t.queue) > 0 {
+		last = &t.queue[len(t.queue)-1]
+	}
+
+	switch {
+	case update.numMessages != 0 && last != nil && last.numMessages != 0:
+		// 连续的 EXISTS 更新只需发送最新的数量，保留最早的 prevNumMessages
+		last.numMessages = update.numMessages
+		return
+	case update.mailboxFlags != nil && last != nil && last.mailboxFlags != nil:
+		last.mailboxFlags = update.mailboxFlags
+		return
+	case update.fetch != nil && update.fetch.uid != 0:
+		// 同一封邮件只发送最新的标志。保留原有的序号：它相对于队列中的位置仍然正确
+		if i, ok := t.fetchByUID[update.fetch.uid]; ok {
+			t.queue[i].fetch = &trackerUpdateFetch{
+				seqNum: t.queue[i].fetch.seqNum,
+				uid:    update.fetch.uid,
+				flags:  update.fetch.flags,
+			}
+			return
+		}
+		if t.fetchByUID == nil {
+			t.fetchByUID = make(map[imap.UID]int)
+		}
+		t.fetchByUID[update.fetch.uid] = len(t.queue)
+	case update.expunge != 0 && update.expungeUID != 0:
+		// 邮件已被删除，不再需要发送它的标志
+		if i, ok := t.fetchByUID[update.expungeUID]; ok {
+			t.queue[i] = trackerUpdate{}
+			delete(t.fetchByUID, update.expungeUID)
+			t.numQueued--
+		}
+	}
+	t.queue = append(t.queue, *update) // 将更新添加到队列
+	t.numQueued++
+}
+
 // Poll 从会话中取消排队的邮箱更新。
+//
+// 如果待处理的更新超过了上限，Poll 会发送 BYE 并关闭连接。
 func (t *SessionTracker) Poll(w *UpdateWriter, allowExpunge bool) error {
 	var updates []trackerUpdate
 	t.mutex.Lock()
+	if t.overflow {
+		t.mutex.Unlock()
+		if err := w.conn.bye(imap.ResponseCodeUnavailable, "待处理的邮箱更新过多"); err != nil {
+			return err
+		}
+		return errSessionTrackerOverflow
+	}
 	if allowExpunge {
 		updates = t.queue // 允许删除
 		t.queue = nil     // 清空队列
@@ -172,6 +266,27 @@ func (t *SessionTracker) Poll(w *UpdateWriter, allowExpunge bool) error {
 			t.queue = nil
 		}
 	}
+	t.numQueued = 0
+	t.fetchByUID = nil
+	for i, update := range t.queue {
+		if update.fetch != nil && update.fetch.uid != 0 {
+			if t.fetchByUID == nil {
+				t.fetchByUID = make(map[imap.UID]int)
+			}
+			t.fetchByUID[update.fetch.uid] = i
+		}
+		if !update.isMerged() {
+			t.numQueued++
+		}
+	}
+	for _, update := range updates {
+		switch {
+		case update.expunge != 0:
+			t.numMessages--
+		case update.numMessages != 0:
+			t.numMessages = update.numMessages
+		}
+	}
 	t.mutex.Unlock()
 
 	// 写入更新到更新写入器
@@ -179,13 +294,15 @@ func (t *SessionTracker) Poll(w *UpdateWriter, allowExpunge bool) error {
 		var err error
 		switch {
 		case update.expunge != 0:
-			err = w.WriteExpunge(update.expunge) // 写入删除更新
+			err = w.writeExpungeUID(update.expunge, update.expungeUID) // 写入删除更新
 		case update.numMessages != 0:
 			err = w.WriteNumMessages(update.numMessages) // 写入邮件数量更新
 		case update.mailboxFlags != nil:
 			err = w.WriteMailboxFlags(update.mailboxFlags) // 写入邮箱标志更新
 		case update.fetch != nil:
 			err = w.WriteMessageFlags(update.fetch.seqNum, update.fetch.uid, update.fetch.flags) // 写入消息标志更新
+		case update.isMerged():
+			// 已被合并到其他更新中
 		default:
 			panic(fmt.Errorf("imapserver: 未知的跟踪更新 %#v", update))
 		}
@@ -277,9 +394,8 @@ func (t *SessionTracker) EncodeSeqNum(seqNum uint32) uint32 {
 
 	for i := len(t.queue) - 1; i >= 0; i-- {
 		update := t.queue[i]
-		// TODO: 这不处理递增大于1的情况
-		if update.numMessages != 0 && seqNum == update.numMessages {
-			return 0 // 如果邮件数量更新与当前序列号相等，返回零
+		if update.numMessages != 0 && seqNum > update.prevNumMessages {
+			return 0 // 该邮件由客户端尚未收到的 EXISTS 更新新增
 		}
 		if update.expunge != 0 && seqNum >= update.expunge {
 			seqNum++ // 增加序列号
@@ -72,6 +72,51 @@ var sessionTrackerSeqNumTests = []struct {
 		clientSeqNum: 42,
 		serverSeqNum: 42,
 	},
+	{
+		name:         "添加多个_第一个",
+		pending:      []trackerUpdate{{numMessages: 45}},
+		clientSeqNum: 0,
+		serverSeqNum: 43,
+	},
+	{
+		name:         "添加多个_最后",
+		pending:      []trackerUpdate{{numMessages: 45}},
+		clientSeqNum: 0,
+		serverSeqNum: 45,
+	},
+	{
+		name:         "添加多个_小于",
+		pending:      []trackerUpdate{{numMessages: 45}},
+		clientSeqNum: 42,
+		serverSeqNum: 42,
+	},
+	{
+		name: "删除_添加多个",
+		pending: []trackerUpdate{
+			{expunge: 10},
+			{numMessages: 44},
+		},
+		clientSeqNum: 0,
+		serverSeqNum: 42,
+	},
+	{
+		name: "删除_添加多个",
+		pending: []trackerUpdate{
+			{expunge: 10},
+			{numMessages: 44},
+		},
+		clientSeqNum: 42,
+		serverSeqNum: 41,
+	},
+	{
+		name: "添加多个_删除",
+		pending: []trackerUpdate{
+			{numMessages: 45},
+			{expunge: 10},
+		},
+		clientSeqNum: 0,
+		serverSeqNum: 42,
+	},
 	{
 		name: "删除_添加",
 		pending: []trackerUpdate{
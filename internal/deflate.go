@@ -0,0 +1,36 @@
+package internal
+
+import (
+	"compress/flate"
+	"io"
+)
+
+// NewDeflateReadWriter wraps a stream with DEFLATE compression, as described
+// in RFC 4978.
+//
+// The compressor is flushed after each write, so that the other side can
+// decode each command or response as soon as it's sent.
+func NewDeflateReadWriter(r io.Reader, w io.Writer) io.ReadWriter {
+	fw, err := flate.NewWriter(w, flate.DefaultCompression)
+	if err != nil {
+		panic(err) // unreachable: the compression level is valid
+	}
+	return &deflateReadWriter{r: flate.NewReader(r), w: fw}
+}
+
+type deflateReadWriter struct {
+	r io.ReadCloser
+	w *flate.Writer
+}
+
+func (rw *deflateReadWriter) Read(b []byte) (int, error) {
+	return rw.r.Read(b)
+}
+
+func (rw *deflateReadWriter) Write(b []byte) (int, error) {
+	n, err := rw.w.Write(b)
+	if err != nil {
+		return n, err
+	}
+	return n, rw.w.Flush()
+}
@@ -2,6 +2,7 @@ package imapwire
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"io"
 	"strconv"
@@ -16,6 +17,10 @@ import (
 // This limits the max list nesting depth to prevent stack overflow.
 const maxListDepth = 1000
 
+// ErrTooBig is returned by the decoder when more than MaxSize bytes would be
+// decoded.
+var ErrTooBig = errors.New("imapwire: too much data")
+
 // IsAtomChar returns true if ch is an ATOM-CHAR.
 func IsAtomChar(ch byte) bool {
 	switch ch {
@@ -52,7 +57,13 @@ type Decoder struct {
 	// CheckBufferedLiteralFunc is called when a literal is about to be decoded
 	// and needs to be fully buffered in memory.
 	CheckBufferedLiteralFunc func(size int64, nonSync bool) error
+	// MaxSize is the maximum number of bytes which can be decoded, including
+	// literals. Zero means no limit. Once the limit is reached, decoding fails
+	// with ErrTooBig.
+	MaxSize int64
 
+	n         int64 // number of bytes decoded so far, including literals
+	skip      int64 // size of a rejected non-synchronizing literal
 	r         *bufio.Reader
 	side      ConnSide
 	err       error
@@ -99,6 +110,10 @@ func (dec *Decoder) readByte() (byte, bool) {
 		}
 		return b, dec.returnErr(err)
 	}
+	dec.n++
+	if dec.MaxSize > 0 && dec.n > dec.MaxSize {
+		return b, dec.returnErr(ErrTooBig)
+	}
 	return b, true
 }
 
@@ -263,10 +278,92 @@ func (dec *Decoder) DiscardLine() {
 	dec.CRLF()
 }
 
+// DiscardCommand discards the rest of a command after the decoder has failed
+// with ErrTooBig. MaxSize is ignored and the decoder error is cleared.
+//
+// Non-synchronizing literals are skipped. A synchronizing literal ends the
+// command, since the client waits for a continuation request before sending
+// it.
+func (dec *Decoder) DiscardCommand() error {
+	dec.MaxSize = 0
+	dec.err = nil
+	if dec.literal {
+		return fmt.Errorf("imapwire: cannot discard command while a literal is open")
+	}
+
+	skip := dec.skip
+	dec.skip = 0
+	lineDone := dec.crlf
+	for {
+		if skip > 0 {
+			if _, err := io.CopyN(io.Discard, dec.r, skip); err != nil {
+				return err
+			}
+			skip = 0
+			lineDone = false // the command continues after the literal
+		}
+		if lineDone {
+			dec.crlf = true
+			return nil
+		}
+
+		// Only keep the end of the line to look for a literal header
+		var tail []byte
+		for {
+			b, err := dec.r.ReadSlice('\n')
+			if len(b) > 32 {
+				tail = append(tail[:0], b[len(b)-32:]...)
+			} else {
+				tail = append(tail, b...)
+				if len(tail) > 32 {
+					tail = tail[len(tail)-32:]
+				}
+			}
+			if err == bufio.ErrBufferFull {
+				continue
+			} else if err != nil {
+				return err
+			}
+			break
+		}
+
+		size, nonSync, ok := parseLiteralHeaderSuffix(tail)
+		if ok && nonSync {
+			skip = size
+		} else {
+			lineDone = true
+		}
+	}
+}
+
+// parseLiteralHeaderSuffix checks whether a line ends with a literal header.
+func parseLiteralHeaderSuffix(line []byte) (size int64, nonSync, ok bool) {
+	s := strings.TrimSuffix(strings.TrimSuffix(string(line), "\n"), "\r")
+	if !strings.HasSuffix(s, "}") {
+		return 0, false, false
+	}
+	i := strings.LastIndexByte(s, '{')
+	if i < 0 {
+		return 0, false, false
+	}
+	s = s[i+1 : len(s)-1]
+	if strings.HasSuffix(s, "+") {
+		nonSync = true
+		s = strings.TrimSuffix(s, "+")
+	}
+	size, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || size < 0 {
+		return 0, false, false
+	}
+	return size, nonSync, true
+}
+
 func (dec *Decoder) DiscardValue() bool {
 	var s string
 	if dec.String(&s) {
 		return true
+	} else if dec.err != nil {
+		return false
 	}
 
 	isList, err := dec.List(func() error {
@@ -403,6 +500,8 @@ func (dec *Decoder) ExpectAString(ptr *string) bool {
 	}
 	if dec.Literal(ptr) {
 		return true
+	} else if dec.err != nil {
+		return false // the literal has been rejected
 	}
 	// We cannot do dec.Atom(ptr) here because sometimes mailbox names are unquoted,
 	// and they can contain special characters like `]`.
@@ -413,6 +512,34 @@ func (dec *Decoder) String(ptr *string) bool {
 	return dec.Quoted(ptr) || dec.Literal(ptr)
 }
 
+// StringBytes decodes a quoted string, a literal or a literal8 as raw bytes.
+// Unlike String, the literal data is never converted to a Go string, so binary
+// data (including NUL bytes) is preserved.
+func (dec *Decoder) StringBytes(ptr *[]byte) bool {
+	var s string
+	if dec.Quoted(&s) {
+		*ptr = []byte(s)
+		return true
+	}
+	literal8 := dec.Special('~')
+	lit, nonSync, ok := dec.LiteralReader()
+	if !ok {
+		if literal8 {
+			return dec.Expect(false, "literal8")
+		}
+		return false
+	}
+	if !dec.checkBufferedLiteral(lit, nonSync) {
+		return false
+	}
+	b, err := io.ReadAll(lit)
+	if err != nil {
+		return dec.returnErr(err)
+	}
+	*ptr = b
+	return true
+}
+
 func (dec *Decoder) ExpectString(ptr *string) bool {
 	return dec.Expect(dec.String(ptr), "string")
 }
@@ -563,15 +690,9 @@ func isNumSetChar(ch byte) bool {
 
 func (dec *Decoder) Literal(ptr *string) bool {
 	lit, nonSync, ok := dec.LiteralReader()
-	if !ok {
+	if !ok || !dec.checkBufferedLiteral(lit, nonSync) {
 		return false
 	}
-	if dec.CheckBufferedLiteralFunc != nil {
-		if err := dec.CheckBufferedLiteralFunc(lit.Size(), nonSync); err != nil {
-			lit.cancel()
-			return false
-		}
-	}
 	var sb strings.Builder
 	_, err := io.Copy(&sb, lit)
 	if err == nil {
@@ -580,6 +701,25 @@ func (dec *Decoder) Literal(ptr *string) bool {
 	return dec.returnErr(err)
 }
 
+// checkBufferedLiteral calls CheckBufferedLiteralFunc before a literal is
+// read into memory. If the literal is rejected, it's discarded.
+func (dec *Decoder) checkBufferedLiteral(lit *LiteralReader, nonSync bool) bool {
+	if dec.CheckBufferedLiteralFunc == nil {
+		return true
+	}
+	if err := dec.CheckBufferedLiteralFunc(lit.Size(), nonSync); err != nil {
+		if nonSync {
+			// The client sends the literal anyway, skip it
+			if _, discardErr := io.Copy(io.Discard, lit); discardErr != nil {
+				return dec.returnErr(discardErr)
+			}
+		}
+		lit.cancel()
+		return dec.returnErr(err)
+	}
+	return true
+}
+
 func (dec *Decoder) LiteralReader() (lit *LiteralReader, nonSync, ok bool) {
 	if !dec.Special('{') {
 		return nil, false, false
@@ -594,6 +734,13 @@ func (dec *Decoder) LiteralReader() (lit *LiteralReader, nonSync, ok bool) {
 	if !dec.ExpectSpecial('}') || !dec.ExpectCRLF() {
 		return nil, false, false
 	}
+	if dec.MaxSize > 0 && dec.n+size > dec.MaxSize {
+		if nonSync {
+			dec.skip = size // the client sends the literal anyway
+		}
+		return nil, false, dec.returnErr(ErrTooBig)
+	}
+	dec.n += size
 	dec.literal = true
 	lit = &LiteralReader{
 		dec:  dec,
@@ -631,6 +778,9 @@ func (lit *LiteralReader) Size() int64 {
 func (lit *LiteralReader) Read(b []byte) (int, error) {
 	n, err := lit.r.Read(b)
 	if err == io.EOF {
+		if lit.dec != nil {
+			lit.dec.crlf = false // the line continues after the literal
+		}
 		lit.cancel()
 	}
 	return n, err
@@ -31,6 +31,11 @@ type Encoder struct {
 	// NewContinuationRequest creates a new continuation request. This is only
 	// meaningful for clients.
 	NewContinuationRequest func() *ContinuationRequest
+	// SetRedact is called with true before sensitive data such as credentials
+	// is written, and with false afterwards. The buffered data is flushed
+	// around each call, so that the underlying writer sees the sensitive data
+	// on its own.
+	SetRedact func(redact bool)
 
 	w       *bufio.Writer
 	side    ConnSide
@@ -63,6 +68,33 @@ func (enc *Encoder) writeString(s string) *Encoder {
 	return enc
 }
 
+// Sensitive writes sensitive data such as credentials with f.
+//
+// If SetRedact is set, the buffered data is flushed before and after f is
+// called, and SetRedact is notified.
+func (enc *Encoder) Sensitive(f func(enc *Encoder)) *Encoder {
+	if enc.SetRedact == nil {
+		f(enc)
+		return enc
+	}
+
+	enc.flushBuffered()
+	enc.SetRedact(true)
+	f(enc)
+	enc.flushBuffered()
+	enc.SetRedact(false)
+	return enc
+}
+
+func (enc *Encoder) flushBuffered() {
+	if enc.err != nil {
+		return
+	}
+	if err := enc.w.Flush(); err != nil {
+		enc.err = err
+	}
+}
+
 // CRLF writes a "\r\n" sequence and flushes the buffered writer.
 func (enc *Encoder) CRLF() error {
 	enc.writeString("\r\n")
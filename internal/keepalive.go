@@ -0,0 +1,31 @@
+package internal
+
+import (
+	"crypto/tls"
+	"net"
+	"time"
+)
+
+// SetTCPKeepAlive configures TCP keep-alive on the TCP connection underlying
+// conn, unwrapping TLS connections. A positive period enables keep-alive with
+// that period, a negative period disables it, and zero leaves the connection
+// untouched. Connections which aren't backed by TCP are ignored.
+func SetTCPKeepAlive(conn net.Conn, period time.Duration) error {
+	if period == 0 {
+		return nil
+	}
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		conn = tlsConn.NetConn()
+	}
+	tcpConn, ok := conn.(*net.TCPConn)
+	if !ok {
+		return nil
+	}
+	if period < 0 {
+		return tcpConn.SetKeepAlive(false)
+	}
+	if err := tcpConn.SetKeepAlive(true); err != nil {
+		return err
+	}
+	return tcpConn.SetKeepAlivePeriod(period)
+}
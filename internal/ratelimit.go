@@ -0,0 +1,102 @@
+package internal
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// RateLimiter is a token bucket limiting throughput to a number of bytes per
+// second.
+//
+// The bucket holds at most a tenth of a second worth of tokens, so that
+// transfers are slowed down smoothly instead of in bursts.
+type RateLimiter struct {
+	rate  float64 // bytes per second
+	burst int
+
+	mutex  sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter creates a new rate limiter allowing rate bytes per second.
+func NewRateLimiter(rate int) *RateLimiter {
+	burst := rate / 10
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		rate:   float64(rate),
+		burst:  burst,
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// wait consumes n tokens, blocking until the bucket is no longer in debt.
+func (l *RateLimiter) wait(n int) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > float64(l.burst) {
+		l.tokens = float64(l.burst)
+	}
+	l.last = now
+
+	l.tokens -= float64(n)
+	if l.tokens < 0 {
+		time.Sleep(time.Duration(-l.tokens / l.rate * float64(time.Second)))
+	}
+}
+
+// NewRateLimitedReader wraps a reader with a rate limiter. Reads are never
+// throttled by failing: the reader blocks until enough tokens are available.
+func NewRateLimitedReader(r io.Reader, l *RateLimiter) io.Reader {
+	return &rateLimitedReader{r: r, l: l}
+}
+
+type rateLimitedReader struct {
+	r io.Reader
+	l *RateLimiter
+}
+
+func (r *rateLimitedReader) Read(b []byte) (int, error) {
+	if len(b) > r.l.burst {
+		b = b[:r.l.burst]
+	}
+	n, err := r.r.Read(b)
+	r.l.wait(n)
+	return n, err
+}
+
+// NewRateLimitedWriter wraps a writer with a rate limiter. Large writes are
+// split into chunks no larger than the bucket size.
+func NewRateLimitedWriter(w io.Writer, l *RateLimiter) io.Writer {
+	return &rateLimitedWriter{w: w, l: l}
+}
+
+type rateLimitedWriter struct {
+	w io.Writer
+	l *RateLimiter
+}
+
+func (w *rateLimitedWriter) Write(b []byte) (int, error) {
+	var written int
+	for len(b) > 0 {
+		chunk := b
+		if len(chunk) > w.l.burst {
+			chunk = chunk[:w.l.burst]
+		}
+		w.l.wait(len(chunk))
+		n, err := w.w.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		b = b[n:]
+	}
+	return written, nil
+}
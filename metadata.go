@@ -0,0 +1,34 @@
+package imap
+
+import (
+	"fmt"
+)
+
+// GetMetadataDepth 表示获取元数据的深度。
+type GetMetadataDepth int
+
+const (
+	GetMetadataDepthZero     GetMetadataDepth = 0  // 只获取指定的条目
+	GetMetadataDepthOne      GetMetadataDepth = 1  // 同时获取条目的直接子条目
+	GetMetadataDepthInfinity GetMetadataDepth = -1 // 同时获取条目的所有子条目
+)
+
+// String 返回 GetMetadataDepth 的字符串表示。
+func (depth GetMetadataDepth) String() string {
+	switch depth {
+	case GetMetadataDepthZero:
+		return "0"
+	case GetMetadataDepthOne:
+		return "1"
+	case GetMetadataDepthInfinity:
+		return "infinity"
+	default:
+		panic(fmt.Errorf("imap: 未知的 GETMETADATA 深度 %d", depth))
+	}
+}
+
+// GetMetadataOptions 包含 GETMETADATA 命令的选项，参见 RFC 5464。
+type GetMetadataOptions struct {
+	MaxSize *uint32          // 只返回大小不超过 MaxSize 字节的值
+	Depth   GetMetadataDepth // 获取深度
+}
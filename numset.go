@@ -31,6 +31,17 @@ func SeqSetNum(nums ...uint32) SeqSet {
 	return s
 }
 
+// ParseSeqSet 解析序列号集合的 IMAP 表示，例如 "1:5,8,10:*"。
+//
+// 集合由逗号分隔的序列号或范围组成，"*" 表示邮箱中最后一封邮件。输入无效时返回错误。
+func ParseSeqSet(s string) (SeqSet, error) {
+	numSet, err := imapnum.ParseSet(s)
+	if err != nil {
+		return nil, err
+	}
+	return *(*SeqSet)(unsafe.Pointer(&numSet)), nil
+}
+
 // numSetPtr 返回指向 imapnum.Set 的指针。
 func (s *SeqSet) numSetPtr() *imapnum.Set {
 	return (*imapnum.Set)(unsafe.Pointer(s))
@@ -91,6 +102,21 @@ func UIDSetNum(uids ...UID) UIDSet {
 	return s
 }
 
+// ParseUIDSet 解析 UID 集合的 IMAP 表示，例如 "1:5,8,10:*"。
+//
+// 语法与 ParseSeqSet 相同，"*" 表示邮箱中最大的 UID。"$" 表示 SEARCHRES 标记，
+// 返回 SearchRes()。输入无效时返回错误。
+func ParseUIDSet(s string) (UIDSet, error) {
+	if s == "$" {
+		return SearchRes(), nil
+	}
+	numSet, err := imapnum.ParseSet(s)
+	if err != nil {
+		return nil, err
+	}
+	return *(*UIDSet)(unsafe.Pointer(&numSet)), nil
+}
+
 // numSetPtr 返回指向 imapnum.Set 的指针。
 func (s *UIDSet) numSetPtr() *imapnum.Set {
 	return (*imapnum.Set)(unsafe.Pointer(s))
@@ -0,0 +1,84 @@
+package imap_test
+
+import (
+	"testing"
+
+	"github.com/luhaoyun888/go-imap-cn"
+)
+
+// numSetTests 是 ParseSeqSet 和 ParseUIDSet 共用的测试用例，want 是 String() 的结果。
+var numSetTests = []struct {
+	in, want string
+}{
+	{"1", "1"},
+	{"*", "*"},
+	{"1:5,8,10:*", "1:5,8,10:*"},
+	{"5:1", "1:5"},
+	{"*:3", "3:*"},
+	{"1,2,3,7", "1:3,7"},
+	{"8,1:5", "1:5,8"},
+	{"4294967295", "4294967295"},
+}
+
+var badNumSets = []string{"", "0", "1:", ":1", ",", "1,,2", "a", " 1", "1:0", "01", "4294967296", "1:5,"}
+
+func TestParseSeqSet(t *testing.T) {
+	for _, tc := range numSetTests {
+		seqSet, err := imap.ParseSeqSet(tc.in)
+		if err != nil {
+			t.Errorf("ParseSeqSet(%q) = %v", tc.in, err)
+			continue
+		}
+		if got := seqSet.String(); got != tc.want {
+			t.Errorf("ParseSeqSet(%q).String() = %q, want %q", tc.in, got, tc.want)
+		}
+		// String() 的结果能被重新解析为相同的集合
+		again, err := imap.ParseSeqSet(seqSet.String())
+		if err != nil || again.String() != tc.want {
+			t.Errorf("ParseSeqSet(%q) = %v, %v, want %q", seqSet.String(), again, err, tc.want)
+		}
+	}
+	for _, s := range badNumSets {
+		if seqSet, err := imap.ParseSeqSet(s); err == nil {
+			t.Errorf("ParseSeqSet(%q) = %v, want error", s, seqSet)
+		}
+	}
+
+	seqSet, err := imap.ParseSeqSet("2:4,10:*")
+	if err != nil {
+		t.Fatalf("ParseSeqSet() = %v", err)
+	}
+	if !seqSet.Contains(3) || seqSet.Contains(5) || !seqSet.Dynamic() {
+		t.Errorf("ParseSeqSet(%q) = %v, want 2:4,10:*", "2:4,10:*", seqSet)
+	}
+}
+
+func TestParseUIDSet(t *testing.T) {
+	for _, tc := range numSetTests {
+		uidSet, err := imap.ParseUIDSet(tc.in)
+		if err != nil {
+			t.Errorf("ParseUIDSet(%q) = %v", tc.in, err)
+			continue
+		}
+		if got := uidSet.String(); got != tc.want {
+			t.Errorf("ParseUIDSet(%q).String() = %q, want %q", tc.in, got, tc.want)
+		}
+		again, err := imap.ParseUIDSet(uidSet.String())
+		if err != nil || again.String() != tc.want {
+			t.Errorf("ParseUIDSet(%q) = %v, %v, want %q", uidSet.String(), again, err, tc.want)
+		}
+	}
+	for _, s := range badNumSets {
+		if uidSet, err := imap.ParseUIDSet(s); err == nil {
+			t.Errorf("ParseUIDSet(%q) = %v, want error", s, uidSet)
+		}
+	}
+
+	uidSet, err := imap.ParseUIDSet("$")
+	if err != nil {
+		t.Fatalf("ParseUIDSet(%q) = %v", "$", err)
+	}
+	if !imap.IsSearchRes(uidSet) || uidSet.String() != "$" {
+		t.Errorf("ParseUIDSet(%q) = %v, want SearchRes()", "$", uidSet)
+	}
+}
@@ -11,3 +11,17 @@ const (
 	QuotaResourceMailbox           QuotaResourceType = "MAILBOX"            // 邮箱资源类型
 	QuotaResourceAnnotationStorage QuotaResourceType = "ANNOTATION-STORAGE" // 注释存储资源类型
 )
+
+// QuotaData 是 QUOTA 响应返回的数据。
+type QuotaData struct {
+	Root      string                                  // 配额根
+	Resources map[QuotaResourceType]QuotaResourceData // 资源数据
+}
+
+// QuotaResourceData 包含配额资源的使用情况和限制。
+//
+// STORAGE 资源以 1024 字节为单位。
+type QuotaResourceData struct {
+	Usage int64 // 使用量
+	Limit int64 // 限制量
+}
@@ -1,6 +1,7 @@
 package imap
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 )
@@ -44,11 +45,24 @@ const (
 	ResponseCodeUnknownCTE           ResponseCode = "UNKNOWN-CTE"          // 未知内容传输编码
 
 	// METADATA
+	ResponseCodeMetadata  ResponseCode = "METADATA"  // 元数据相关的响应，后跟 LONGENTRIES、MAXSIZE、TOOMANY 或 NOPRIVATE
 	ResponseCodeTooMany   ResponseCode = "TOOMANY"   // 太多
 	ResponseCodeNoPrivate ResponseCode = "NOPRIVATE" // 无法访问私人元数据
 
 	// APPENDLIMIT
 	ResponseCodeTooBig ResponseCode = "TOOBIG" // 太大
+
+	// CATENATE
+	ResponseCodeBadURL ResponseCode = "BADURL" // URL 无效或无法解析
+
+	// CONDSTORE
+	ResponseCodeModified ResponseCode = "MODIFIED" // 自指定修改序列号以来已被修改
+
+	// COMPRESS
+	ResponseCodeCompressionActive ResponseCode = "COMPRESSIONACTIVE" // 压缩已启用
+
+	// LOGIN-REFERRALS、MAILBOX-REFERRALS
+	ResponseCodeReferral ResponseCode = "REFERRAL" // 重定向到其他服务器
 )
 
 // StatusResponse 是一种通用状态响应。
@@ -58,6 +72,10 @@ type StatusResponse struct {
 	Type StatusResponseType // 状态响应类型
 	Code ResponseCode       // 响应代码
 	Text string             // 额外信息
+
+	// Referrals 是 REFERRAL 响应代码中的 IMAP URL 列表，客户端可以据此连接到
+	// 其他服务器，参见 RFC 2193 和 RFC 2221。
+	Referrals []string
 }
 
 // Error 是由状态响应引起的 IMAP 错误。
@@ -79,3 +97,40 @@ func (err *Error) Error() string {
 	fmt.Fprintf(&sb, " %v", text) // 输出额外信息
 	return sb.String()
 }
+
+// IsNo 判断 err 是否为 NO 状态响应引起的 IMAP 错误，例如命令因邮箱不存在而失败。
+func IsNo(err error) bool {
+	return errorType(err) == StatusResponseTypeNo
+}
+
+// IsBad 判断 err 是否为 BAD 状态响应引起的 IMAP 错误，例如命令语法错误。
+func IsBad(err error) bool {
+	return errorType(err) == StatusResponseTypeBad
+}
+
+// ErrorCode 返回 IMAP 错误的响应代码，例如：
+//
+//	switch imap.ErrorCode(err) {
+//	case imap.ResponseCodeNonExistent:
+//		// 邮箱不存在
+//	case imap.ResponseCodeAlreadyExists:
+//		// 邮箱已存在
+//	}
+//
+// 如果 err 不是 IMAP 错误或者没有响应代码，返回空字符串。
+func ErrorCode(err error) ResponseCode {
+	var imapErr *Error
+	if !errors.As(err, &imapErr) {
+		return ""
+	}
+	return imapErr.Code
+}
+
+// errorType 返回 IMAP 错误的状态响应类型。如果 err 不是 IMAP 错误，返回空字符串。
+func errorType(err error) StatusResponseType {
+	var imapErr *Error
+	if !errors.As(err, &imapErr) {
+		return ""
+	}
+	return imapErr.Type
+}
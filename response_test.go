@@ -0,0 +1,40 @@
+package imap_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/luhaoyun888/go-imap-cn"
+)
+
+func TestErrorHelpers(t *testing.T) {
+	noErr := &imap.Error{Type: imap.StatusResponseTypeNo, Code: imap.ResponseCodeNonExistent, Text: "邮箱不存在"}
+	badErr := &imap.Error{Type: imap.StatusResponseTypeBad, Text: "语法错误"}
+
+	tests := []struct {
+		err   error
+		no    bool
+		bad   bool
+		code  imap.ResponseCode
+		label string
+	}{
+		{noErr, true, false, imap.ResponseCodeNonExistent, "NO [NONEXISTENT]"},
+		{fmt.Errorf("选择邮箱失败: %w", noErr), true, false, imap.ResponseCodeNonExistent, "wrapped NO"},
+		{badErr, false, true, "", "BAD"},
+		{&imap.Error{Type: imap.StatusResponseTypeNo, Code: imap.ResponseCodePrivacyRequired}, true, false, imap.ResponseCodePrivacyRequired, "NO [PRIVACYREQUIRED]"},
+		{errors.New("连接已关闭"), false, false, "", "non-IMAP error"},
+		{nil, false, false, "", "nil"},
+	}
+	for _, tc := range tests {
+		if got := imap.IsNo(tc.err); got != tc.no {
+			t.Errorf("IsNo(%v) = %v, want %v", tc.label, got, tc.no)
+		}
+		if got := imap.IsBad(tc.err); got != tc.bad {
+			t.Errorf("IsBad(%v) = %v, want %v", tc.label, got, tc.bad)
+		}
+		if got := imap.ErrorCode(tc.err); got != tc.code {
+			t.Errorf("ErrorCode(%v) = %q, want %q", tc.label, got, tc.code)
+		}
+	}
+}
@@ -16,6 +16,36 @@ type SearchOptions struct {
 	ReturnSave bool // 保存搜索结果
 }
 
+// MultiSearchFilter 是 ESEARCH 命令的邮箱过滤器（RFC 7377）。
+type MultiSearchFilter string
+
+const (
+	MultiSearchFilterSelected   MultiSearchFilter = "selected"    // 当前选择的邮箱
+	MultiSearchFilterInboxes    MultiSearchFilter = "inboxes"     // 收件箱
+	MultiSearchFilterPersonal   MultiSearchFilter = "personal"    // 个人命名空间中的所有邮箱
+	MultiSearchFilterSubscribed MultiSearchFilter = "subscribed"  // 已订阅的邮箱
+	MultiSearchFilterSubtree    MultiSearchFilter = "subtree"     // 指定的邮箱及其所有子邮箱
+	MultiSearchFilterSubtreeOne MultiSearchFilter = "subtree-one" // 指定的邮箱及其直接子邮箱
+	MultiSearchFilterMailboxes  MultiSearchFilter = "mailboxes"   // 指定的邮箱
+)
+
+// MultiSearchSource 是 ESEARCH 命令 IN 源选项中的一个过滤器。
+type MultiSearchSource struct {
+	Filter MultiSearchFilter
+	// 仅用于 MultiSearchFilterSubtree、MultiSearchFilterSubtreeOne 和
+	// MultiSearchFilterMailboxes，至少包含一个邮箱
+	Mailboxes []string
+}
+
+// MultiSearchData 是 ESEARCH 命令在一个邮箱中的搜索结果。
+//
+// 结果中的编号始终为 UID。
+type MultiSearchData struct {
+	Mailbox     string // 邮箱名称
+	UIDValidity uint32 // 邮箱的 UIDVALIDITY
+	SearchData         // 搜索结果
+}
+
 // SearchCriteria 表示 SEARCH 命令的搜索条件。
 //
 // 当多个字段被填充时，结果是符合所有条件消息的交集（"与" 操作）。
@@ -52,10 +82,17 @@ type SearchCriteria struct {
 	Larger  int64 // 大于某个大小
 	Smaller int64 // 小于某个大小
 
+	// 按内部日期的相对时间搜索，单位为秒（需要 WITHIN 扩展）
+	Younger int64 // 内部日期在最近 n 秒之内
+	Older   int64 // 内部日期早于 n 秒之前
+
 	Not []SearchCriteria    // 否定的搜索条件
 	Or  [][2]SearchCriteria // "或" 条件组合
 
 	ModSeq *SearchCriteriaModSeq // 条件存储功能（需要 CONDSTORE 扩展）
+
+	EmailID  []string // 邮件对象 ID（需要 OBJECTID 扩展）
+	ThreadID []string // 会话对象 ID（需要 OBJECTID 扩展）
 }
 
 // And 方法用于合并两个搜索条件的交集。
@@ -86,8 +123,46 @@ func (criteria *SearchCriteria) And(other *SearchCriteria) {
 		criteria.Smaller = other.Smaller
 	}
 
+	// 合并 Younger 和 Older 条件
+	if other.Younger != 0 && (criteria.Younger == 0 || other.Younger < criteria.Younger) {
+		criteria.Younger = other.Younger
+	}
+	if other.Older > criteria.Older {
+		criteria.Older = other.Older
+	}
+
+	// 合并 ModSeq 条件，取较大的修改序列号
+	if other.ModSeq != nil && (criteria.ModSeq == nil || other.ModSeq.ModSeq > criteria.ModSeq.ModSeq) {
+		criteria.ModSeq = other.ModSeq
+	}
+
 	criteria.Not = append(criteria.Not, other.Not...)
 	criteria.Or = append(criteria.Or, other.Or...)
+
+	criteria.EmailID = append(criteria.EmailID, other.EmailID...)
+	criteria.ThreadID = append(criteria.ThreadID, other.ThreadID...)
+}
+
+// SinceDays 方法设置 Since 条件，匹配内部日期不早于 n 天前（按本地日期）的邮件。
+//
+// SEARCH 的日期只精确到天，例如 SinceDays(7) 在 10 月 18 日会生成 SINCE 11-Oct-YYYY，
+// n 为 0 时只匹配今天的邮件。
+//
+// 参数：
+// - n: 天数。
+func (criteria *SearchCriteria) SinceDays(n int) {
+	now := time.Now()
+	criteria.Since = time.Date(now.Year(), now.Month(), now.Day()-n, 0, 0, 0, 0, now.Location())
+}
+
+// LastHours 方法设置 Younger 条件，匹配内部日期在最近 n 小时之内的邮件（需要 WITHIN 扩展）。
+//
+// 与 Since 不同，YOUNGER 由服务器按当前时间计算，精确到秒。
+//
+// 参数：
+// - n: 小时数。
+func (criteria *SearchCriteria) LastHours(n int) {
+	criteria.Younger = int64(n) * 60 * 60
 }
 
 // intersectSince 方法用于返回两个日期中较晚的日期。
@@ -153,9 +228,9 @@ type SearchCriteriaModSeq struct {
 type SearchCriteriaMetadataType string
 
 const (
-	SearchCriteriaMetadataAll     SearchCriteriaMetadataType = "所有"
-	SearchCriteriaMetadataPrivate SearchCriteriaMetadataType = "私人"
-	SearchCriteriaMetadataShared  SearchCriteriaMetadataType = "共享"
+	SearchCriteriaMetadataAll     SearchCriteriaMetadataType = "all"
+	SearchCriteriaMetadataPrivate SearchCriteriaMetadataType = "priv"
+	SearchCriteriaMetadataShared  SearchCriteriaMetadataType = "shared"
 )
 
 // SearchData 表示 SEARCH 命令返回的数据。
@@ -2,8 +2,23 @@ package imap
 
 // SelectOptions 包含 SELECT 或 EXAMINE 命令的选项。
 type SelectOptions struct {
-	ReadOnly  bool // 是否以只读模式选择邮箱
-	CondStore bool // 是否使用条件存储，要求支持 CONDSTORE
+	ReadOnly  bool            // 是否以只读模式选择邮箱
+	CondStore bool            // 是否使用条件存储，要求支持 CONDSTORE
+	QResync   *QResyncOptions // 快速重新同步参数，要求已启用 QRESYNC
+}
+
+// QResyncOptions 包含 SELECT 和 EXAMINE 命令的 QRESYNC 参数，参见 RFC 7162 第 3.2.5 节。
+type QResyncOptions struct {
+	UIDValidity uint32           // 客户端缓存的 UIDVALIDITY
+	ModSeq      uint64           // 客户端已知的最后一个修改序列号
+	KnownUIDs   UIDSet           // 客户端已知的 UID（可选），为空表示所有 UID
+	SeqMatch    *QResyncSeqMatch // 消息序号与 UID 的对应关系（可选）
+}
+
+// QResyncSeqMatch 是 QRESYNC 参数中消息序号与 UID 的对应关系，帮助服务器确定已删除的邮件。
+type QResyncSeqMatch struct {
+	SeqNums SeqSet // 消息序号
+	UIDs    UIDSet // 对应的 UID
 }
 
 // SelectData 是 SELECT 命令返回的数据。
@@ -22,4 +37,13 @@ type SelectData struct {
 	List *ListData // 返回列表数据，要求支持 IMAP4rev2
 
 	HighestModSeq uint64 // 最高的修改序列号，要求支持 CONDSTORE
+
+	// 带有 \Recent 标志的邮件数量，仅用于 IMAP4rev1（IMAP4rev2 已废弃）
+	NumRecent uint32
+
+	// 自 QResyncOptions.ModSeq 以来被删除的邮件 UID（VANISHED (EARLIER)），要求支持 QRESYNC
+	Vanished UIDSet
+
+	// 邮箱只能以只读方式访问（READ-ONLY），即使使用 SELECT 选择
+	ReadOnly bool
 }
@@ -0,0 +1,20 @@
+package imap
+
+// SortKey 表示排序关键字，参见 RFC 5256。
+type SortKey string
+
+const (
+	SortKeyArrival SortKey = "ARRIVAL" // 按到达时间排序
+	SortKeyCc      SortKey = "CC"      // 按抄送人排序
+	SortKeyDate    SortKey = "DATE"    // 按日期排序
+	SortKeyFrom    SortKey = "FROM"    // 按发件人排序
+	SortKeySize    SortKey = "SIZE"    // 按大小排序
+	SortKeySubject SortKey = "SUBJECT" // 按主题排序
+	SortKeyTo      SortKey = "TO"      // 按收件人排序
+)
+
+// SortCriterion 表示排序标准。
+type SortCriterion struct {
+	Key     SortKey // 排序关键字
+	Reverse bool    // 是否反向排序
+}
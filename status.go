@@ -8,10 +8,12 @@ type StatusOptions struct {
 	NumUnseen   bool // 是否返回未读邮件数量
 	NumDeleted  bool // 是否返回已删除邮件数量，要求 IMAP4rev2 或 QUOTA
 	Size        bool // 是否返回邮箱大小，要求 IMAP4rev2 或 STATUS=SIZE
+	NumRecent   bool // 是否返回最近邮件数量，仅适用于 IMAP4rev1，IMAP4rev2 已将其废弃
 
 	AppendLimit    bool // 是否返回附加限制，要求 APPENDLIMIT
 	DeletedStorage bool // 是否返回已删除邮件的存储量，要求 QUOTA=RES-STORAGE
 	HighestModSeq  bool // 是否返回最高的修改序列号，要求 CONDSTORE
+	MailboxID      bool // 是否返回邮箱的对象 ID，要求 OBJECTID
 }
 
 // StatusData 是 STATUS 命令返回的数据。
@@ -26,8 +28,10 @@ type StatusData struct {
 	NumUnseen   *uint32 // 未读邮件数量
 	NumDeleted  *uint32 // 已删除邮件数量
 	Size        *int64  // 邮箱大小
+	NumRecent   *uint32 // 带有 \Recent 标志的邮件数量
 
 	AppendLimit    *uint32 // 附加限制
 	DeletedStorage *int64  // 已删除邮件的存储量
 	HighestModSeq  uint64  // 最高的修改序列号
+	MailboxID      string  // 邮箱的对象 ID
 }
@@ -20,3 +20,12 @@ type StoreFlags struct {
 	Silent bool         // 是否静默操作
 	Flags  []Flag       // 要修改的标志
 }
+
+// StoreGmailLabels 修改消息的 Gmail 标签。
+//
+// 要求支持 X-GM-EXT-1。
+type StoreGmailLabels struct {
+	Op     StoreFlagsOp // 操作类型
+	Silent bool         // 是否静默操作
+	Labels []string     // 要修改的标签，系统标签以反斜杠开头，例如 \Inbox
+}
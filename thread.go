@@ -7,3 +7,12 @@ const (
 	ThreadOrderedSubject ThreadAlgorithm = "ORDEREDSUBJECT" // 有序主题算法
 	ThreadReferences     ThreadAlgorithm = "REFERENCES"     // 引用算法
 )
+
+// ThreadData 表示 THREAD 响应中的一个线程。
+//
+// Chain 中的每封邮件都是前一封邮件的唯一子邮件，SubThreads 是 Chain 中
+// 最后一封邮件的子线程。Chain 为空时表示线程的根邮件不存在。
+type ThreadData struct {
+	Chain      []uint32     // 线程链
+	SubThreads []ThreadData // 子线程
+}